@@ -1,12 +1,19 @@
 #!/bin/bash
 
-platforms=("linux/amd64" "linux/arm" "linux/arm64" "darwin/amd64")
+platforms=("linux/amd64" "linux/arm" "linux/arm64" "darwin/amd64" "darwin/arm64" "freebsd/amd64"
+    "windows/amd64" "windows/arm64")
 
 for platform in "${platforms[@]}"
 do
     platform_split=(${platform//\// })
     GOOS=${platform_split[0]}
     GOARCH=${platform_split[1]}
-    env GOOS=$GOOS GOARCH=$GOARCH go build -ldflags "-X github.com/arken/ait/apis/github.clientID=$1 -X github.com/arken/ait/cli.appVersion=$2" -o ait-$2-${GOOS}-${GOARCH} .
+    # ARMv6 builds run on every Raspberry Pi. The names must match
+    # platform.ReleaseAsset, which ait update downloads.
+    output=ait-$2-${GOOS}-${GOARCH}
+    if [ "$GOOS" = "windows" ]; then
+        output=$output.exe
+    fi
+    env CGO_ENABLED=0 GOOS=$GOOS GOARCH=$GOARCH GOARM=6 go build -tags "$BUILD_TAGS" -ldflags "-X github.com/arken/ait/apis/github.clientID=$1 -X github.com/arken/ait/cli.appVersion=$2" -o $output .
 
-done
\ No newline at end of file
+done
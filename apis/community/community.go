@@ -0,0 +1,69 @@
+package community
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/arken/ait/utils"
+)
+
+// Stats are the anonymized seeding statistics of one installation. ID is a
+// random identifier that isn't tied to the node's peer identity, and datasets
+// are only counted, never named.
+type Stats struct {
+	ID         string    `json:"id"`
+	Uploaded   int64     `json:"uploaded"`
+	Downloaded int64     `json:"downloaded"`
+	Datasets   int       `json:"datasets"`
+	Reported   time.Time `json:"reported"`
+}
+
+// Standing is a single row of the community leaderboard.
+type Standing struct {
+	Rank     int    `json:"rank"`
+	ID       string `json:"id"`
+	Uploaded int64  `json:"uploaded"`
+	Datasets int    `json:"datasets"`
+}
+
+// client is shared by requests to the community endpoint.
+var client = utils.NewClient(20 * time.Second)
+
+// Submit sends the installation's statistics to the endpoint. The totals are
+// cumulative, so the endpoint replaces any earlier report with the same ID.
+func Submit(endpoint string, stats Stats) error {
+	body, err := json.Marshal(stats)
+	if err != nil {
+		return err
+	}
+	resp, err := client.Post(strings.TrimSuffix(endpoint, "/")+"/stats",
+		"application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated &&
+		resp.StatusCode != http.StatusNoContent {
+		return fmt.Errorf("the community endpoint rejected the statistics: %v", resp.Status)
+	}
+	return nil
+}
+
+// Leaderboard fetches the top contributors of the cluster from the endpoint.
+func Leaderboard(endpoint string, limit int) (standings []Standing, err error) {
+	resp, err := client.Get(fmt.Sprintf("%v/leaderboard?limit=%d",
+		strings.TrimSuffix(endpoint, "/"), limit))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("could not fetch the leaderboard: %v", resp.Status)
+	}
+	err = json.NewDecoder(resp.Body).Decode(&standings)
+	return standings, err
+}
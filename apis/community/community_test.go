@@ -0,0 +1,44 @@
+package community
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSubmitAndLeaderboard(t *testing.T) {
+	var received Stats
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v1/stats":
+			assert.Equal(t, http.MethodPost, r.Method)
+			assert.NoError(t, json.NewDecoder(r.Body).Decode(&received))
+			w.WriteHeader(http.StatusNoContent)
+		case "/v1/leaderboard":
+			assert.Equal(t, "2", r.URL.Query().Get("limit"))
+			json.NewEncoder(w).Encode([]Standing{
+				{Rank: 1, ID: "abc", Uploaded: 2048, Datasets: 3},
+				{Rank: 2, ID: "def", Uploaded: 1024, Datasets: 1},
+			})
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	err := Submit(server.URL+"/v1/", Stats{ID: "abc", Uploaded: 2048, Datasets: 3})
+	assert.NoError(t, err)
+	assert.Equal(t, "abc", received.ID)
+	assert.Equal(t, int64(2048), received.Uploaded)
+
+	standings, err := Leaderboard(server.URL+"/v1", 2)
+	assert.NoError(t, err)
+	assert.Len(t, standings, 2)
+	assert.Equal(t, "def", standings[1].ID)
+
+	_, err = Leaderboard(server.URL+"/missing", 2)
+	assert.Error(t, err)
+}
@@ -0,0 +1,103 @@
+package discovery
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/arken/ait/config"
+	"github.com/arken/ait/utils"
+)
+
+// wellKnownPath is where an organization's website serves its discovery
+// document.
+const wellKnownPath = "/.well-known/arken.json"
+
+// repoFile is the discovery document's name when it's kept in a GitHub
+// repository.
+const repoFile = "arken.json"
+
+// Document lists an organization's official keyset repositories.
+type Document struct {
+	Organization string       `json:"organization"`
+	Repositories []Repository `json:"repositories"`
+	// Defaults are default flags for ait commands the organization
+	// recommends, keyed by command.
+	Defaults map[string]string `json:"defaults,omitempty"`
+	// EmailDomains are the domains the organization expects the email
+	// addresses of its members at, suggested when they enter theirs.
+	EmailDomains []string `json:"email_domains,omitempty"`
+}
+
+// Repository is an official keyset repository.
+type Repository struct {
+	// Name is the alias the repository is saved under.
+	Name        string   `json:"name"`
+	URL         string   `json:"url"`
+	Description string   `json:"description,omitempty"`
+	Categories  []string `json:"categories,omitempty"`
+	// Policy summarizes or links to what the repository accepts.
+	Policy string `json:"policy,omitempty"`
+}
+
+// Locate returns the URL of the discovery document of source: an
+// organization's domain, a GitHub repository holding arken.json or the URL of
+// the document itself.
+func Locate(source string) (string, error) {
+	source = strings.TrimSuffix(strings.TrimSpace(source), "/")
+	if source == "" {
+		return "", errors.New("no organization given")
+	}
+	if !strings.Contains(source, "://") {
+		source = "https://" + source
+	}
+	u, err := url.Parse(source)
+	if err != nil || u.Host == "" {
+		return "", fmt.Errorf("%q isn't a domain or URL", source)
+	}
+	switch {
+	case strings.HasSuffix(u.Path, ".json"):
+		return u.String(), nil
+	case u.Host == "github.com":
+		parts := strings.Split(strings.Trim(u.Path, "/"), "/")
+		if len(parts) != 2 {
+			return "", fmt.Errorf("%v isn't a GitHub repository", source)
+		}
+		return fmt.Sprintf("https://raw.githubusercontent.com/%v/%v/HEAD/%v", parts[0],
+			strings.TrimSuffix(parts[1], ".git"), repoFile), nil
+	case u.Path == "":
+		return u.Scheme + "://" + u.Host + wellKnownPath, nil
+	default:
+		return "", fmt.Errorf("unable to find a discovery document at %v, give its URL", source)
+	}
+}
+
+// Fetch downloads and checks the discovery document of source.
+func Fetch(source string) (*Document, error) {
+	location, err := Locate(source)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := utils.PinnedClient(config.Global.Trust.Hosts).Get(location)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%v returned %v", location, resp.Status)
+	}
+	doc := &Document{}
+	if err = json.NewDecoder(resp.Body).Decode(doc); err != nil {
+		return nil, fmt.Errorf("%v isn't a valid discovery document: %v", location, err)
+	}
+	for i, repo := range doc.Repositories {
+		if repo.Name == "" || repo.URL == "" {
+			return nil, fmt.Errorf("repository %d of %v has no name or URL", i+1, location)
+		}
+		doc.Repositories[i].Name = strings.Join(strings.Fields(repo.Name), "-")
+	}
+	return doc, nil
+}
@@ -0,0 +1,55 @@
+package discovery
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLocate(t *testing.T) {
+	for source, expected := range map[string]string{
+		"arken.io":                              "https://arken.io/.well-known/arken.json",
+		"https://arken.io/":                     "https://arken.io/.well-known/arken.json",
+		"https://github.com/arken/official.git": "https://raw.githubusercontent.com/arken/official/HEAD/arken.json",
+		"github.com/arken/official":             "https://raw.githubusercontent.com/arken/official/HEAD/arken.json",
+		"https://arken.io/keysets/index.json":   "https://arken.io/keysets/index.json",
+	} {
+		location, err := Locate(source)
+		assert.NoError(t, err, source)
+		assert.Equal(t, expected, location, source)
+	}
+	for _, bad := range []string{"", "https://github.com/arken", "https://arken.io/keysets"} {
+		_, err := Locate(bad)
+		assert.Error(t, err, bad)
+	}
+}
+
+func TestFetch(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/arken.json":
+			w.Write([]byte(`{"organization":"Arken","repositories":[{"name":"core keysets",
+				"url":"https://github.com/arken/core-keyset","categories":["genomics"],
+				"policy":"Pull requests are reviewed weekly."}],"defaults":{"submit":"--pull-request"}}`))
+		case "/broken.json":
+			w.Write([]byte(`{"repositories":[{"name":"core"}]}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	doc, err := Fetch(server.URL + "/arken.json")
+	assert.NoError(t, err)
+	assert.Equal(t, "Arken", doc.Organization)
+	assert.Equal(t, []Repository{{Name: "core-keysets", URL: "https://github.com/arken/core-keyset",
+		Categories: []string{"genomics"}, Policy: "Pull requests are reviewed weekly."}}, doc.Repositories)
+	assert.Equal(t, map[string]string{"submit": "--pull-request"}, doc.Defaults)
+
+	_, err = Fetch(server.URL + "/broken.json")
+	assert.Error(t, err)
+	_, err = Fetch(server.URL + "/missing.json")
+	assert.Error(t, err)
+}
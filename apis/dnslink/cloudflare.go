@@ -0,0 +1,120 @@
+package dnslink
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/arken/ait/config"
+	"github.com/arken/ait/utils"
+)
+
+const cloudflareAPI = "https://api.cloudflare.com/client/v4"
+
+// cloudflare updates records through the Cloudflare v4 API using a scoped
+// API token with DNS edit permission.
+type cloudflare struct {
+	token string
+	zone  string
+}
+
+// cfRecord is a DNS record as represented by the Cloudflare API.
+type cfRecord struct {
+	ID      string `json:"id,omitempty"`
+	Type    string `json:"type"`
+	Name    string `json:"name"`
+	Content string `json:"content"`
+	TTL     int    `json:"ttl"`
+}
+
+// cfResponse is the envelope wrapping every Cloudflare API response.
+type cfResponse struct {
+	Success bool            `json:"success"`
+	Errors  []cfError       `json:"errors"`
+	Result  json.RawMessage `json:"result"`
+}
+
+type cfError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+// SetTXT creates the TXT record at name or replaces the existing one.
+func (cf *cloudflare) SetTXT(name, value string) error {
+	if cf.zone == "" {
+		zone, err := cf.findZone(name)
+		if err != nil {
+			return err
+		}
+		cf.zone = zone
+	}
+	var existing []cfRecord
+	err := cf.do("GET", "/zones/"+cf.zone+"/dns_records?type=TXT&name="+name, nil, &existing)
+	if err != nil {
+		return err
+	}
+	record := cfRecord{Type: "TXT", Name: name, Content: value, TTL: 120}
+	if len(existing) > 0 {
+		return cf.do("PUT", "/zones/"+cf.zone+"/dns_records/"+existing[0].ID, record, nil)
+	}
+	return cf.do("POST", "/zones/"+cf.zone+"/dns_records", record, nil)
+}
+
+// findZone looks up the zone identifier for the closest parent domain of name
+// managed by the account.
+func (cf *cloudflare) findZone(name string) (string, error) {
+	labels := strings.Split(name, ".")
+	for i := 0; i < len(labels)-1; i++ {
+		var zones []struct {
+			ID string `json:"id"`
+		}
+		err := cf.do("GET", "/zones?name="+strings.Join(labels[i:], "."), nil, &zones)
+		if err != nil {
+			return "", err
+		}
+		if len(zones) > 0 {
+			return zones[0].ID, nil
+		}
+	}
+	return "", fmt.Errorf("no Cloudflare zone found for %v", name)
+}
+
+// do sends a request to the Cloudflare API and decodes the result into out.
+func (cf *cloudflare) do(method, endpoint string, body, out interface{}) error {
+	var payload bytes.Buffer
+	if body != nil {
+		if err := json.NewEncoder(&payload).Encode(body); err != nil {
+			return err
+		}
+	}
+	req, err := http.NewRequest(method, cloudflareAPI+endpoint, &payload)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+cf.token)
+	req.Header.Set("Content-Type", "application/json")
+	client := utils.PinnedClient(config.Global.Trust.Hosts)
+	client.Timeout = 20 * time.Second
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	result := cfResponse{}
+	if err = json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return fmt.Errorf("unexpected response from Cloudflare: %v", err)
+	}
+	if !result.Success {
+		if len(result.Errors) > 0 {
+			return fmt.Errorf("cloudflare: %v", result.Errors[0].Message)
+		}
+		return fmt.Errorf("cloudflare: request failed with status %v", resp.Status)
+	}
+	if out != nil {
+		return json.Unmarshal(result.Result, out)
+	}
+	return nil
+}
@@ -0,0 +1,48 @@
+package dnslink
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/arken/ait/config"
+)
+
+// Provider is a DNS hosting service able to update a domain's DNSLink record.
+type Provider interface {
+	// SetTXT creates or replaces the TXT record at name with value.
+	SetTXT(name, value string) error
+}
+
+// Enabled returns true if a DNSLink domain has been configured.
+func Enabled() bool {
+	return config.Global.DNSLink.Domain != ""
+}
+
+// newProvider returns the DNS provider named in the config.
+func newProvider() (Provider, error) {
+	conf := config.Global.DNSLink
+	if token, ok := os.LookupEnv("AIT_DNSLINK_TOKEN"); ok {
+		conf.Token = token
+	}
+	if conf.Token == "" {
+		return nil, fmt.Errorf("no DNSLink API token configured for %v", conf.Domain)
+	}
+	switch strings.ToLower(conf.Provider) {
+	case "cloudflare", "":
+		return &cloudflare{token: conf.Token, zone: conf.Zone}, nil
+	default:
+		return nil, fmt.Errorf("unsupported DNSLink provider %q", conf.Provider)
+	}
+}
+
+// Update points the DNSLink record of the configured domain at the given
+// content path, ie "/ipfs/<cid>" or "/ipns/<name>".
+func Update(path string) error {
+	provider, err := newProvider()
+	if err != nil {
+		return err
+	}
+	domain := strings.TrimSuffix(config.Global.DNSLink.Domain, ".")
+	return provider.SetTXT("_dnslink."+domain, "dnslink="+path)
+}
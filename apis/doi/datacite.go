@@ -0,0 +1,97 @@
+package doi
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/arken/ait/config"
+	"github.com/arken/ait/utils"
+)
+
+const dataciteAPI = "https://api.datacite.org"
+
+// datacite mints DOIs through the DataCite REST API with the credentials of
+// a DataCite repository.
+type datacite struct {
+	server   string
+	prefix   string
+	username string
+	password string
+}
+
+// dataciteAttributes are the DataCite metadata schema fields AIT fills in.
+type dataciteAttributes struct {
+	Event           string              `json:"event"`
+	Prefix          string              `json:"prefix"`
+	Titles          []map[string]string `json:"titles"`
+	Creators        []map[string]string `json:"creators"`
+	Publisher       string              `json:"publisher"`
+	PublicationYear int                 `json:"publicationYear"`
+	Types           map[string]string   `json:"types"`
+	Descriptions    []map[string]string `json:"descriptions,omitempty"`
+	URL             string              `json:"url"`
+	Identifiers     []map[string]string `json:"alternateIdentifiers,omitempty"`
+}
+
+// Mint creates and publishes a findable DOI for the dataset.
+func (dc *datacite) Mint(m Metadata) (string, error) {
+	attrs := dataciteAttributes{
+		Event:           "publish",
+		Prefix:          dc.prefix,
+		Titles:          []map[string]string{{"title": m.Title}},
+		Publisher:       m.Publisher,
+		PublicationYear: m.Published.Year(),
+		Types:           map[string]string{"resourceTypeGeneral": "Dataset"},
+		URL:             m.URL,
+	}
+	for _, creator := range m.Creators {
+		attrs.Creators = append(attrs.Creators, map[string]string{"name": creator})
+	}
+	if m.Description != "" {
+		attrs.Descriptions = []map[string]string{{
+			"description":     m.Description,
+			"descriptionType": "Abstract",
+		}}
+	}
+	if m.Root != "" {
+		attrs.Identifiers = []map[string]string{{
+			"alternateIdentifier":     m.Root,
+			"alternateIdentifierType": "IPFS CID",
+		}}
+	}
+	body := map[string]interface{}{
+		"data": map[string]interface{}{"type": "dois", "attributes": attrs},
+	}
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return "", err
+	}
+	req, err := http.NewRequest("POST", dc.server+"/dois", bytes.NewReader(payload))
+	if err != nil {
+		return "", err
+	}
+	req.SetBasicAuth(dc.username, dc.password)
+	req.Header.Set("Content-Type", "application/vnd.api+json")
+	client := utils.PinnedClient(config.Global.Trust.Hosts)
+	client.Timeout = 30 * time.Second
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusCreated {
+		return "", fmt.Errorf("DataCite rejected the registration: %v", resp.Status)
+	}
+	result := struct {
+		Data struct {
+			ID string `json:"id"`
+		} `json:"data"`
+	}{}
+	if err = json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return "", err
+	}
+	return result.Data.ID, nil
+}
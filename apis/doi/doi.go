@@ -0,0 +1,90 @@
+package doi
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/arken/ait/config"
+)
+
+// Metadata describes a dataset being registered.
+type Metadata struct {
+	Title       string
+	Description string
+	Creators    []string
+	Publisher   string
+	Published   time.Time
+	// URL is where the dataset can be accessed, ie its gateway link.
+	URL string
+	// Root is the CID of the dataset's root directory.
+	Root string
+	// KeysetName and Keyset are the keyset file listing the dataset's files,
+	// uploaded to registries that require a file.
+	KeysetName string
+	Keyset     []byte
+}
+
+// Registry is a DOI registration agency able to mint a DOI for a dataset.
+type Registry interface {
+	// Mint registers the dataset and returns its DOI, ie "10.5281/zenodo.123".
+	Mint(m Metadata) (string, error)
+}
+
+// Enabled returns true if a DOI registry has been configured.
+func Enabled() bool {
+	return config.Global.DOI.Registry != ""
+}
+
+// newRegistry returns the registry named in the config.
+func newRegistry() (Registry, error) {
+	conf := config.Global.DOI
+	if token, ok := os.LookupEnv("AIT_DOI_TOKEN"); ok {
+		conf.Token = token
+	}
+	if conf.Token == "" {
+		return nil, fmt.Errorf("no %v API token configured", conf.Registry)
+	}
+	switch strings.ToLower(conf.Registry) {
+	case "datacite":
+		if conf.Prefix == "" || conf.Username == "" {
+			return nil, fmt.Errorf("DataCite needs a DOI prefix and repository ID")
+		}
+		return &datacite{
+			server:   serverOr(conf.Server, dataciteAPI),
+			prefix:   conf.Prefix,
+			username: conf.Username,
+			password: conf.Token,
+		}, nil
+	case "zenodo":
+		return &zenodo{server: serverOr(conf.Server, zenodoAPI), token: conf.Token}, nil
+	default:
+		return nil, fmt.Errorf("unsupported DOI registry %q", conf.Registry)
+	}
+}
+
+// serverOr returns server without a trailing slash, or fallback if it's empty.
+func serverOr(server, fallback string) string {
+	if server == "" {
+		return fallback
+	}
+	return strings.TrimSuffix(server, "/")
+}
+
+// Mint registers the dataset with the configured registry and returns its DOI.
+func Mint(m Metadata) (string, error) {
+	registry, err := newRegistry()
+	if err != nil {
+		return "", err
+	}
+	if m.Publisher == "" {
+		m.Publisher = config.Global.DOI.Publisher
+	}
+	return registry.Mint(m)
+}
+
+// URL returns the resolver link of a DOI.
+func URL(doi string) string {
+	return "https://doi.org/" + doi
+}
@@ -0,0 +1,48 @@
+package doi
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDataciteMint(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, pass, ok := r.BasicAuth()
+		assert.True(t, ok)
+		assert.Equal(t, "ARKEN.LAB", user)
+		assert.Equal(t, "secret", pass)
+		body := struct {
+			Data struct {
+				Attributes dataciteAttributes `json:"attributes"`
+			} `json:"data"`
+		}{}
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		attrs := body.Data.Attributes
+		assert.Equal(t, "10.1234", attrs.Prefix)
+		assert.Equal(t, "Engine notes", attrs.Titles[0]["title"])
+		assert.Equal(t, "Ada Lovelace", attrs.Creators[0]["name"])
+		assert.Equal(t, 2021, attrs.PublicationYear)
+		assert.Equal(t, "bafyroot", attrs.Identifiers[0]["alternateIdentifier"])
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"data":{"id":"10.1234/abcd-efgh"}}`))
+	}))
+	defer server.Close()
+
+	dc := &datacite{server: server.URL, prefix: "10.1234", username: "ARKEN.LAB", password: "secret"}
+	id, err := dc.Mint(Metadata{
+		Title:     "Engine notes",
+		Creators:  []string{"Ada Lovelace"},
+		Publisher: "Arken",
+		Published: time.Date(2021, 3, 1, 0, 0, 0, 0, time.UTC),
+		URL:       "https://ipfs.io/ipfs/bafyroot/",
+		Root:      "bafyroot",
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "10.1234/abcd-efgh", id)
+	assert.Equal(t, "https://doi.org/10.1234/abcd-efgh", URL(id))
+}
@@ -0,0 +1,107 @@
+package doi
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/arken/ait/config"
+	"github.com/arken/ait/utils"
+)
+
+const zenodoAPI = "https://zenodo.org/api"
+
+// zenodo mints DOIs by publishing a Zenodo deposition with the keyset as its
+// only file, since Zenodo doesn't publish depositions without files.
+type zenodo struct {
+	server string
+	token  string
+}
+
+// zenodoDeposition is the part of a Zenodo deposition AIT reads.
+type zenodoDeposition struct {
+	ID    int    `json:"id"`
+	DOI   string `json:"doi"`
+	Links struct {
+		Bucket  string `json:"bucket"`
+		Publish string `json:"publish"`
+	} `json:"links"`
+}
+
+// Mint creates a deposition for the dataset, uploads its keyset and publishes
+// it.
+func (z *zenodo) Mint(m Metadata) (string, error) {
+	metadata := map[string]interface{}{
+		"upload_type":      "dataset",
+		"title":            m.Title,
+		"description":      m.Description,
+		"publication_date": m.Published.Format("2006-01-02"),
+	}
+	if m.Description == "" {
+		metadata["description"] = m.Title
+	}
+	creators := []map[string]string{}
+	for _, creator := range m.Creators {
+		creators = append(creators, map[string]string{"name": creator})
+	}
+	metadata["creators"] = creators
+	if m.URL != "" {
+		metadata["related_identifiers"] = []map[string]string{{
+			"identifier": m.URL,
+			"relation":   "isIdenticalTo",
+		}}
+	}
+	if m.Root != "" {
+		metadata["notes"] = "IPFS CID: " + m.Root
+	}
+	body, err := json.Marshal(map[string]interface{}{"metadata": metadata})
+	if err != nil {
+		return "", err
+	}
+	deposition := zenodoDeposition{}
+	err = z.do("POST", z.server+"/deposit/depositions", "application/json",
+		bytes.NewReader(body), &deposition)
+	if err != nil {
+		return "", err
+	}
+	err = z.do("PUT", deposition.Links.Bucket+"/"+m.KeysetName, "application/octet-stream",
+		bytes.NewReader(m.Keyset), nil)
+	if err != nil {
+		return "", err
+	}
+	published := zenodoDeposition{}
+	if err = z.do("POST", deposition.Links.Publish, "", nil, &published); err != nil {
+		return "", err
+	}
+	return published.DOI, nil
+}
+
+// do sends an authenticated request to Zenodo and decodes the response into
+// out.
+func (z *zenodo) do(method, url, contentType string, body io.Reader, out interface{}) error {
+	req, err := http.NewRequest(method, url, body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+z.token)
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	client := utils.PinnedClient(config.Global.Trust.Hosts)
+	client.Timeout = 2 * time.Minute
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("zenodo: %v %v", resp.Status, url)
+	}
+	if out != nil {
+		return json.NewDecoder(resp.Body).Decode(out)
+	}
+	return nil
+}
@@ -0,0 +1,92 @@
+package email
+
+import (
+	"bytes"
+	"fmt"
+	"net"
+	"net/smtp"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/arken/ait/config"
+)
+
+// Patch is a keyset submission formatted like the output of git format-patch
+// so mailing list archives and their tooling can apply it.
+type Patch struct {
+	From    string
+	To      string
+	Subject string
+	Message string
+	Path    string
+	Keyset  []byte
+	Date    time.Time
+}
+
+// Bytes renders the patch as an RFC 5322 email. The keyset is added as a new
+// file at Path.
+func (p *Patch) Bytes() []byte {
+	lines := strings.Split(strings.TrimSuffix(string(p.Keyset), "\n"), "\n")
+	if len(p.Keyset) == 0 {
+		lines = nil
+	}
+	buf := new(bytes.Buffer)
+	fmt.Fprintf(buf, "From: %v\r\n", p.From)
+	fmt.Fprintf(buf, "To: %v\r\n", p.To)
+	fmt.Fprintf(buf, "Subject: [PATCH] %v\r\n", p.Subject)
+	fmt.Fprintf(buf, "Date: %v\r\n", p.Date.Format(time.RFC1123Z))
+	buf.WriteString("MIME-Version: 1.0\r\n")
+	buf.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+	buf.WriteString("Content-Transfer-Encoding: 8bit\r\n\r\n")
+	buf.WriteString(strings.ReplaceAll(strings.TrimSpace(p.Message), "\n", "\r\n"))
+	buf.WriteString("\r\n---\r\n")
+	fmt.Fprintf(buf, " %v | %d +\r\n", p.Path, len(lines))
+	fmt.Fprintf(buf, " 1 file changed, %d insertions(+)\r\n", len(lines))
+	fmt.Fprintf(buf, " create mode 100644 %v\r\n\r\n", p.Path)
+	fmt.Fprintf(buf, "diff --git a/%v b/%v\r\n", p.Path, p.Path)
+	buf.WriteString("new file mode 100644\r\n")
+	buf.WriteString("--- /dev/null\r\n")
+	fmt.Fprintf(buf, "+++ b/%v\r\n", p.Path)
+	fmt.Fprintf(buf, "@@ -0,0 +1,%d @@\r\n", len(lines))
+	for _, line := range lines {
+		fmt.Fprintf(buf, "+%v\r\n", line)
+	}
+	buf.WriteString("-- \r\nait\r\n")
+	return buf.Bytes()
+}
+
+// Password returns the SMTP password given by the AIT_SMTP_PASSWORD
+// environment variable, or else the one of the SMTP section of the ait config.
+func Password() string {
+	if env, ok := os.LookupEnv("AIT_SMTP_PASSWORD"); ok {
+		return env
+	}
+	return config.Global.SMTP.Password
+}
+
+// Send delivers the patch through the SMTP server configured in the SMTP
+// section of the ait config, signing in with password if it has a Username.
+func Send(p *Patch, password string) error {
+	conf := config.Global.SMTP
+	if conf.Host == "" {
+		return fmt.Errorf("no SMTP server configured, set Host in the SMTP section of %v", config.Path)
+	}
+	var auth smtp.Auth
+	if conf.Username != "" {
+		host, _, err := net.SplitHostPort(conf.Host)
+		if err != nil {
+			return fmt.Errorf("SMTP Host must be in the form host:port: %v", err)
+		}
+		auth = smtp.PlainAuth("", conf.Username, password, host)
+	}
+	return smtp.SendMail(conf.Host, auth, envelopeAddress(p.From), []string{envelopeAddress(p.To)}, p.Bytes())
+}
+
+// envelopeAddress extracts the bare address from "Name <address>".
+func envelopeAddress(addr string) string {
+	if start := strings.LastIndex(addr, "<"); start >= 0 {
+		return strings.TrimSuffix(addr[start+1:], ">")
+	}
+	return addr
+}
@@ -0,0 +1,28 @@
+package email
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPatchBytes(t *testing.T) {
+	p := &Patch{
+		From:    "Ada Lovelace <ada@example.org>",
+		To:      "archive@lists.example.org",
+		Subject: "Add engine notes",
+		Message: "Scanned notes on the analytical engine.",
+		Path:    "library/notes.ks",
+		Keyset:  []byte("bafyone  notes.pdf\nbafytwo  diagrams.pdf\n"),
+		Date:    time.Date(2021, 3, 1, 12, 0, 0, 0, time.UTC),
+	}
+	msg := string(p.Bytes())
+	assert.True(t, strings.HasPrefix(msg, "From: Ada Lovelace <ada@example.org>\r\n"))
+	assert.Contains(t, msg, "Subject: [PATCH] Add engine notes\r\n")
+	assert.Contains(t, msg, " library/notes.ks | 2 +\r\n")
+	assert.Contains(t, msg, "@@ -0,0 +1,2 @@\r\n+bafyone  notes.pdf\r\n+bafytwo  diagrams.pdf\r\n")
+	assert.Equal(t, "ada@example.org", envelopeAddress(p.From))
+	assert.Equal(t, "archive@lists.example.org", envelopeAddress(p.To))
+}
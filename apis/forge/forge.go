@@ -0,0 +1,225 @@
+// Package forge submits keysets to repositories hosted on GitLab and Gitea,
+// for organizations that keep their keyset repositories on their own forge.
+// Repositories on GitHub are submitted to through the github package.
+package forge
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/arken/ait/config"
+	"github.com/arken/ait/utils"
+)
+
+// The forges a repository can be hosted on.
+const (
+	GitHub = "github"
+	GitLab = "gitlab"
+	Gitea  = "gitea"
+)
+
+// Provider is a forge hosting a keyset repository.
+type Provider interface {
+	// CheckAccess returns whether the user can push to the repository.
+	CheckAccess() (bool, error)
+	// Fork forks the repository to the user's account, or reuses the fork
+	// a previous submission made.
+	Fork() error
+	// Exists returns whether the file at repoPath is in the repository.
+	Exists(repoPath string) (bool, error)
+	// CommitFile commits the file at localPath to repoPath and returns the
+	// SHA of the commit. For a pull request it's committed to a branch of the
+	// fork named after repoPath, otherwise to the repository's default
+	// branch.
+	CommitFile(localPath, repoPath, message string, isPR bool) (string, error)
+	// OpenPR opens a pull request from the branch of the fork the last file
+	// was committed to, or updates the one already open from it, and returns
+	// its URL and number.
+	OpenPR(title, body string) (string, int, error)
+	// EditPR replaces the description of the pull request with the given
+	// number.
+	EditPR(number int, body string) error
+}
+
+// Detect returns the forge hosting the repository at rawURL: the one given,
+// the one saved with its remote alias, or the one its host suggests. Hosts
+// that don't suggest one are GitHub.
+func Detect(rawURL, given string) (string, error) {
+	if given == "" {
+		given = config.RemoteOptionsOf(rawURL).Provider
+	}
+	switch strings.ToLower(given) {
+	case GitHub, GitLab, Gitea:
+		return strings.ToLower(given), nil
+	case "":
+	default:
+		return "", fmt.Errorf("unknown provider %q, expected github, gitlab or gitea", given)
+	}
+	host := repoHost(rawURL)
+	switch {
+	case host == "gitlab.com" || strings.HasPrefix(host, "gitlab."):
+		return GitLab, nil
+	case host == "gitea.com" || host == "codeberg.org" || strings.HasPrefix(host, "gitea."):
+		return Gitea, nil
+	}
+	return GitHub, nil
+}
+
+// New returns the provider of the given forge for the repository at rawURL,
+// authenticated with the token saved for its host in Git.Tokens. AIT_GIT_TOKEN
+// is only used for hosts without one, so it's never sent to a forge another
+// token was saved for.
+func New(kind, rawURL string) (Provider, error) {
+	token := tokenFor(rawURL)
+	if token == "" {
+		return nil, fmt.Errorf("no access token for %v, add one to Tokens in the [Git] section "+
+			"of the config or set AIT_GIT_TOKEN", repoHost(rawURL))
+	}
+	return NewWithToken(context.Background(), kind, rawURL, token)
+}
+
+// tokenFor returns the token the repository at rawURL is accessed with: the
+// one saved for its host, or the one given by AIT_GIT_TOKEN.
+func tokenFor(rawURL string) string {
+	if token := config.Global.Git.Tokens[repoHost(rawURL)]; token != "" {
+		return token
+	}
+	return os.Getenv("AIT_GIT_TOKEN")
+}
+
+// NewWithToken returns the provider of the given forge for the repository at
+// rawURL, authenticated with token. Its requests stop once ctx is done.
+func NewWithToken(ctx context.Context, kind, rawURL, token string) (Provider, error) {
+	base, owner, name, err := parseRepoURL(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	// Submissions go to the branch saved with the remote alias, if any,
+	// rather than the default branch of the repository.
+	branch := config.RemoteOptionsOf(rawURL).Branch
+	switch kind {
+	case GitLab:
+		g, err := newGitLab(&client{ctx: ctx, base: base + "/api/v4", header: "PRIVATE-TOKEN", token: token}, owner, name)
+		if err != nil {
+			return nil, err
+		}
+		if branch != "" {
+			g.upstream.DefaultBranch = branch
+		}
+		return g, nil
+	case Gitea:
+		g, err := newGitea(&client{ctx: ctx, base: base + "/api/v1", header: "Authorization", token: "token " + token}, owner, name)
+		if err != nil {
+			return nil, err
+		}
+		if branch != "" {
+			g.upstream.DefaultBranch = branch
+		}
+		return g, nil
+	}
+	return nil, fmt.Errorf("%v repositories aren't submitted to through a provider", kind)
+}
+
+// repoHost returns the lowercase host of the repository at rawURL, for both
+// https://host/owner/name and git@host:owner/name URLs.
+func repoHost(rawURL string) string {
+	if u, err := url.Parse(rawURL); err == nil && u.Host != "" {
+		return strings.ToLower(u.Hostname())
+	}
+	host := rawURL[strings.Index(rawURL, "@")+1:]
+	if i := strings.Index(host, ":"); i >= 0 {
+		host = host[:i]
+	}
+	return strings.ToLower(host)
+}
+
+// BaseURL returns the base URL of the forge hosting the repository at rawURL,
+// ie https://gitlab.example.org.
+func BaseURL(rawURL string) (string, error) {
+	base, _, _, err := parseRepoURL(rawURL)
+	return base, err
+}
+
+// parseRepoURL returns the base URL of the forge hosting the repository at
+// rawURL, its owner and its name. The owner can hold subgroups, ie
+// "lab/surveys" on GitLab.
+func parseRepoURL(rawURL string) (base, owner, name string, err error) {
+	path := ""
+	if u, parseErr := url.Parse(rawURL); parseErr == nil && u.Host != "" {
+		if u.Scheme == "ssh" {
+			u.Scheme = "https"
+		}
+		base = u.Scheme + "://" + u.Host
+		path = u.Path
+	} else if i := strings.Index(rawURL, ":"); i >= 0 && strings.Contains(rawURL[:i], "@") {
+		base = "https://" + repoHost(rawURL)
+		path = rawURL[i+1:]
+	}
+	path = strings.TrimSuffix(strings.Trim(path, "/"), ".git")
+	slash := strings.LastIndex(path, "/")
+	if base == "" || slash <= 0 || slash == len(path)-1 {
+		return "", "", "", fmt.Errorf("%v isn't the URL of a repository, ie https://gitlab.com/owner/name", rawURL)
+	}
+	return base, path[:slash], path[slash+1:], nil
+}
+
+// errNotFound is returned by client.do for 404 responses.
+var errNotFound = errors.New("not found")
+
+// client sends requests to the REST API of a forge, until ctx is done.
+type client struct {
+	ctx    context.Context
+	base   string
+	header string
+	token  string
+}
+
+// do sends a request to the API and decodes the response into out. Responses
+// with a 404 status return errNotFound.
+func (c *client) do(method, endpoint string, body, out interface{}) error {
+	var payload bytes.Buffer
+	if body != nil {
+		if err := json.NewEncoder(&payload).Encode(body); err != nil {
+			return err
+		}
+	}
+	req, err := http.NewRequestWithContext(c.ctx, method, c.base+endpoint, &payload)
+	if err != nil {
+		return err
+	}
+	req.Header.Set(c.header, c.token)
+	req.Header.Set("Content-Type", "application/json")
+	httpClient := utils.WithRetries(utils.PinnedClient(config.Global.Trust.Hosts))
+	httpClient.Timeout = 30 * time.Second
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return errNotFound
+	}
+	if resp.StatusCode >= 300 {
+		message, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("%v %v failed with status %v: %s", method, endpoint, resp.Status,
+			bytes.TrimSpace(message))
+	}
+	if out != nil {
+		return json.NewDecoder(resp.Body).Decode(out)
+	}
+	return nil
+}
+
+// forkTimeout is how long a new fork is waited for, forges may create them in
+// the background.
+const forkTimeout = 60 * time.Second
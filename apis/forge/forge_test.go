@@ -0,0 +1,59 @@
+package forge
+
+import (
+	"os"
+	"testing"
+
+	"github.com/arken/ait/config"
+)
+
+func TestDetect(t *testing.T) {
+	for url, expected := range map[string]string{
+		"https://github.com/arken/core-keyset":   GitHub,
+		"https://gitlab.com/lab/surveys/keysets": GitLab,
+		"git@gitlab.mylab.org:lab/keysets.git":   GitLab,
+		"https://codeberg.org/arken/keysets":     Gitea,
+		"https://gitea.mylab.org/arken/keysets":  Gitea,
+		"https://git.mylab.org/arken/keysets":    GitHub,
+	} {
+		if kind, err := Detect(url, ""); err != nil || kind != expected {
+			t.Errorf("expected %v for %v, got %v, %v", expected, url, kind, err)
+		}
+	}
+	if kind, err := Detect("https://git.mylab.org/arken/keysets", "Gitea"); err != nil || kind != Gitea {
+		t.Error("the given provider should be used, got", kind, err)
+	}
+	if _, err := Detect("https://git.mylab.org/arken/keysets", "bitbucket"); err == nil {
+		t.Error("an unknown provider should be refused")
+	}
+}
+
+func TestParseRepoURL(t *testing.T) {
+	for url, expected := range map[string][3]string{
+		"https://gitlab.com/lab/surveys/keysets":    {"https://gitlab.com", "lab/surveys", "keysets"},
+		"https://gitea.mylab.org/arken/keysets.git": {"https://gitea.mylab.org", "arken", "keysets"},
+		"git@gitlab.mylab.org:lab/keysets.git":      {"https://gitlab.mylab.org", "lab", "keysets"},
+		"ssh://git@codeberg.org/arken/keysets":      {"https://codeberg.org", "arken", "keysets"},
+	} {
+		base, owner, name, err := parseRepoURL(url)
+		if err != nil || [3]string{base, owner, name} != expected {
+			t.Errorf("expected %v for %v, got %v %v %v, %v", expected, url, base, owner, name, err)
+		}
+	}
+	if _, _, _, err := parseRepoURL("https://gitlab.com/keysets"); err == nil {
+		t.Error("a URL without an owner should be refused")
+	}
+}
+
+func TestTokenFor(t *testing.T) {
+	defer func(tokens map[string]string) { config.Global.Git.Tokens = tokens }(config.Global.Git.Tokens)
+	config.Global.Git.Tokens = map[string]string{"gitlab.mylab.org": "glpat-saved"}
+	os.Setenv("AIT_GIT_TOKEN", "from-env")
+	defer os.Unsetenv("AIT_GIT_TOKEN")
+	if token := tokenFor("https://gitlab.mylab.org/lab/keysets"); token != "glpat-saved" {
+		t.Errorf("expected the token saved for the host, got %q", token)
+	}
+	if token := tokenFor("https://gitea.other.org/lab/keysets"); token != "from-env" {
+		t.Errorf("expected AIT_GIT_TOKEN for a host without a token, got %q", token)
+	}
+}
@@ -0,0 +1,238 @@
+package forge
+
+import (
+	"encoding/base64"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"strings"
+
+	aitgh "github.com/arken/ait/apis/github"
+)
+
+// gitea submits to a repository through the Gitea v1 API, which Forgejo and
+// Codeberg serve as well.
+type gitea struct {
+	api      *client
+	user     string
+	upstream *gtRepo
+	fork     *gtRepo
+	// branch is the branch of the fork the last file was committed to.
+	branch string
+}
+
+// gtRepo is a repository as represented by the Gitea API.
+type gtRepo struct {
+	Name          string  `json:"name"`
+	FullName      string  `json:"full_name"`
+	DefaultBranch string  `json:"default_branch"`
+	HTMLURL       string  `json:"html_url"`
+	Fork          bool    `json:"fork"`
+	Parent        *gtRepo `json:"parent"`
+	Owner         struct {
+		Login string `json:"login"`
+	} `json:"owner"`
+	Permissions struct {
+		Admin bool `json:"admin"`
+		Push  bool `json:"push"`
+	} `json:"permissions"`
+}
+
+// gtPull is a pull request as represented by the Gitea API.
+type gtPull struct {
+	Number  int    `json:"number"`
+	HTMLURL string `json:"html_url"`
+	Head    struct {
+		Ref  string `json:"ref"`
+		Repo *struct {
+			FullName string `json:"full_name"`
+		} `json:"repo"`
+	} `json:"head"`
+}
+
+// newGitea looks up the repository owner/name and the authenticated user.
+func newGitea(api *client, owner, name string) (*gitea, error) {
+	g := &gitea{api: api, upstream: &gtRepo{}}
+	err := api.do("GET", "/repos/"+url.PathEscape(owner)+"/"+url.PathEscape(name), nil, g.upstream)
+	if err == errNotFound {
+		return nil, fmt.Errorf("the Gitea repository %v/%v doesn't exist or isn't visible to you", owner, name)
+	} else if err != nil {
+		return nil, err
+	}
+	var user struct {
+		Login string `json:"login"`
+	}
+	if err = api.do("GET", "/user", nil, &user); err != nil {
+		return nil, err
+	}
+	g.user = user.Login
+	return g, nil
+}
+
+func (g *gitea) CheckAccess() (bool, error) {
+	return g.upstream.Permissions.Push || g.upstream.Permissions.Admin, nil
+}
+
+func (g *gitea) Fork() error {
+	fork := &gtRepo{}
+	err := g.api.do("GET", g.repoPath(g.user, g.upstream.Name), nil, fork)
+	switch {
+	case err == errNotFound:
+		fmt.Printf("Forking %v to your account...\n", g.upstream.FullName)
+		if err = g.api.do("POST", g.repoPath(g.upstream.Owner.Login, g.upstream.Name)+"/forks",
+			struct{}{}, fork); err != nil {
+			return err
+		}
+	case err != nil:
+		return err
+	case !fork.Fork || fork.Parent == nil || !strings.EqualFold(fork.Parent.FullName, g.upstream.FullName):
+		return fmt.Errorf("your account already has a repository named %v that isn't a fork of %v",
+			g.upstream.Name, g.upstream.FullName)
+	}
+	fmt.Println("Using your fork at", fork.HTMLURL)
+	g.fork = fork
+	return nil
+}
+
+// repoPath returns the API path of the repository owner/name.
+func (g *gitea) repoPath(owner, name string) string {
+	return "/repos/" + url.PathEscape(owner) + "/" + url.PathEscape(name)
+}
+
+func (g *gitea) Exists(repoPath string) (bool, error) {
+	sha, err := g.fileSHA(g.upstream, repoPath, g.upstream.DefaultBranch)
+	return sha != "", err
+}
+
+// fileSHA returns the blob SHA of the file at repoPath on the branch of the
+// repository, or "" if it isn't there.
+func (g *gitea) fileSHA(repo *gtRepo, repoPath, branch string) (string, error) {
+	var file struct {
+		SHA string `json:"sha"`
+	}
+	err := g.api.do("GET", g.repoPath(repo.Owner.Login, repo.Name)+"/contents/"+escapePath(repoPath)+
+		"?ref="+url.QueryEscape(branch), nil, &file)
+	if err == errNotFound {
+		return "", nil
+	}
+	return file.SHA, err
+}
+
+func (g *gitea) CommitFile(localPath, repoPath, message string, isPR bool) (string, error) {
+	content, err := ioutil.ReadFile(localPath)
+	if err != nil {
+		return "", err
+	}
+	request := map[string]string{
+		"message": message,
+		"content": base64.StdEncoding.EncodeToString(content),
+	}
+	repo, branch := g.upstream, g.upstream.DefaultBranch
+	if isPR {
+		repo = g.fork
+		g.branch = aitgh.PullRequestBranch(repoPath)
+		if branch, err = g.prepareBranch(); err != nil {
+			return "", err
+		}
+		if branch != g.branch {
+			request["new_branch"] = g.branch
+		}
+	}
+	request["branch"] = branch
+	sha, err := g.fileSHA(repo, repoPath, branch)
+	if err != nil {
+		return "", err
+	}
+	method := "POST"
+	if sha != "" {
+		method = "PUT"
+		request["sha"] = sha
+	}
+	var done struct {
+		Commit struct {
+			SHA string `json:"sha"`
+		} `json:"commit"`
+	}
+	err = g.api.do(method, g.repoPath(repo.Owner.Login, repo.Name)+"/contents/"+escapePath(repoPath), request, &done)
+	return done.Commit.SHA, err
+}
+
+// prepareBranch returns the branch of the fork the pull request branch is
+// committed on top of. While a pull request from it is open it's the branch
+// itself, so committing to it updates that pull request. Otherwise the branch
+// is started over from the fork's default branch, brought up to date with the
+// upstream's first.
+func (g *gitea) prepareBranch() (string, error) {
+	branches := g.repoPath(g.fork.Owner.Login, g.fork.Name) + "/branches/"
+	err := g.api.do("GET", branches+url.PathEscape(g.branch), nil, nil)
+	if err == nil {
+		open, err := g.openPull()
+		if err != nil {
+			return "", err
+		}
+		if open != nil {
+			fmt.Printf("Your pull request %v is still open, it will be updated.\n", open.HTMLURL)
+			return g.branch, nil
+		}
+		if err = g.api.do("DELETE", branches+url.PathEscape(g.branch), nil, nil); err != nil {
+			return "", err
+		}
+	} else if err != errNotFound {
+		return "", err
+	}
+	// Older Gitea versions can't sync forks, their default branch is used
+	// as it is.
+	_ = g.api.do("POST", g.repoPath(g.fork.Owner.Login, g.fork.Name)+"/merge-upstream",
+		map[string]string{"branch": g.fork.DefaultBranch}, nil)
+	return g.fork.DefaultBranch, nil
+}
+
+// openPull returns the open pull request from the pull request branch of the
+// fork, or nil if there's none.
+func (g *gitea) openPull() (*gtPull, error) {
+	var open []gtPull
+	err := g.api.do("GET", g.repoPath(g.upstream.Owner.Login, g.upstream.Name)+"/pulls?state=open&limit=50",
+		nil, &open)
+	if err != nil {
+		return nil, err
+	}
+	for i, pull := range open {
+		if pull.Head.Ref == g.branch && pull.Head.Repo != nil &&
+			strings.EqualFold(pull.Head.Repo.FullName, g.fork.FullName) {
+			return &open[i], nil
+		}
+	}
+	return nil, nil
+}
+
+func (g *gitea) OpenPR(title, body string) (string, int, error) {
+	open, err := g.openPull()
+	if err != nil {
+		return "", 0, err
+	}
+	pulls := g.repoPath(g.upstream.Owner.Login, g.upstream.Name) + "/pulls"
+	request := map[string]string{"title": title, "body": body}
+	if open != nil {
+		err = g.api.do("PATCH", fmt.Sprintf("%v/%d", pulls, open.Number), request, nil)
+		return open.HTMLURL, open.Number, err
+	}
+	request["head"] = g.fork.Owner.Login + ":" + g.branch
+	request["base"] = g.upstream.DefaultBranch
+	created := gtPull{}
+	err = g.api.do("POST", pulls, request, &created)
+	return created.HTMLURL, created.Number, err
+}
+
+func (g *gitea) EditPR(number int, body string) error {
+	return g.api.do("PATCH", fmt.Sprintf("%v/pulls/%d", g.repoPath(g.upstream.Owner.Login, g.upstream.Name), number),
+		map[string]string{"body": body}, nil)
+}
+
+// escapePath escapes each segment of the path of a file in a repository.
+func escapePath(path string) string {
+	segments := strings.Split(path, "/")
+	for i, segment := range segments {
+		segments[i] = url.PathEscape(segment)
+	}
+	return strings.Join(segments, "/")
+}
@@ -0,0 +1,247 @@
+package forge
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"time"
+
+	aitgh "github.com/arken/ait/apis/github"
+)
+
+// gitlabDeveloper is the access level from which members of a GitLab project
+// can push to it.
+const gitlabDeveloper = 30
+
+// gitlab submits to a repository through the GitLab v4 API.
+type gitlab struct {
+	api      *client
+	user     string
+	upstream *glProject
+	fork     *glProject
+	// branch is the branch of the fork the last file was committed to.
+	branch string
+}
+
+// glProject is a project as represented by the GitLab API.
+type glProject struct {
+	ID                int    `json:"id"`
+	PathWithNamespace string `json:"path_with_namespace"`
+	DefaultBranch     string `json:"default_branch"`
+	WebURL            string `json:"web_url"`
+	ImportStatus      string `json:"import_status"`
+	ForkedFrom        *struct {
+		ID int `json:"id"`
+	} `json:"forked_from_project"`
+	Permissions struct {
+		Project *glAccess `json:"project_access"`
+		Group   *glAccess `json:"group_access"`
+	} `json:"permissions"`
+}
+
+type glAccess struct {
+	Level int `json:"access_level"`
+}
+
+// glMergeRequest is a merge request as represented by the GitLab API.
+type glMergeRequest struct {
+	IID    int    `json:"iid"`
+	WebURL string `json:"web_url"`
+}
+
+// newGitLab looks up the project owner/name and the authenticated user.
+func newGitLab(api *client, owner, name string) (*gitlab, error) {
+	g := &gitlab{api: api, upstream: &glProject{}}
+	err := api.do("GET", "/projects/"+url.PathEscape(owner+"/"+name), nil, g.upstream)
+	if err == errNotFound {
+		return nil, fmt.Errorf("the GitLab project %v/%v doesn't exist or isn't visible to you", owner, name)
+	} else if err != nil {
+		return nil, err
+	}
+	var user struct {
+		Username string `json:"username"`
+	}
+	if err = api.do("GET", "/user", nil, &user); err != nil {
+		return nil, err
+	}
+	g.user = user.Username
+	return g, nil
+}
+
+func (g *gitlab) CheckAccess() (bool, error) {
+	for _, access := range []*glAccess{g.upstream.Permissions.Project, g.upstream.Permissions.Group} {
+		if access != nil && access.Level >= gitlabDeveloper {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (g *gitlab) Fork() error {
+	name := g.projectName()
+	fork := &glProject{}
+	err := g.api.do("GET", "/projects/"+url.PathEscape(g.user+"/"+name), nil, fork)
+	switch {
+	case err == errNotFound:
+		fmt.Printf("Forking %v to your account...\n", g.upstream.PathWithNamespace)
+		if err = g.api.do("POST", fmt.Sprintf("/projects/%d/fork", g.upstream.ID), struct{}{}, fork); err != nil {
+			return err
+		}
+		for start := time.Now(); fork.ImportStatus != "finished" && fork.ImportStatus != "none"; {
+			if time.Since(start) > forkTimeout {
+				return fmt.Errorf("your fork %v wasn't ready after %v, submit again in a moment", fork.WebURL, forkTimeout)
+			}
+			select {
+			case <-g.api.ctx.Done():
+				return g.api.ctx.Err()
+			case <-time.After(2 * time.Second):
+			}
+			if err = g.api.do("GET", fmt.Sprintf("/projects/%d", fork.ID), nil, fork); err != nil {
+				return err
+			}
+		}
+	case err != nil:
+		return err
+	case fork.ForkedFrom == nil || fork.ForkedFrom.ID != g.upstream.ID:
+		return fmt.Errorf("your account already has a project named %v that isn't a fork of %v",
+			name, g.upstream.PathWithNamespace)
+	}
+	fmt.Println("Using your fork at", fork.WebURL)
+	g.fork = fork
+	return nil
+}
+
+// projectName returns the name of the upstream project, without its
+// namespace.
+func (g *gitlab) projectName() string {
+	path := g.upstream.PathWithNamespace
+	for i := len(path) - 1; i >= 0; i-- {
+		if path[i] == '/' {
+			return path[i+1:]
+		}
+	}
+	return path
+}
+
+func (g *gitlab) Exists(repoPath string) (bool, error) {
+	return g.fileExists(g.upstream.ID, repoPath, g.upstream.DefaultBranch)
+}
+
+// fileExists returns whether the file at repoPath is on the branch of the
+// project.
+func (g *gitlab) fileExists(project int, repoPath, branch string) (bool, error) {
+	err := g.api.do("HEAD", fmt.Sprintf("/projects/%d/repository/files/%v?ref=%v",
+		project, url.PathEscape(repoPath), url.QueryEscape(branch)), nil, nil)
+	if err == errNotFound {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+func (g *gitlab) CommitFile(localPath, repoPath, message string, isPR bool) (string, error) {
+	content, err := ioutil.ReadFile(localPath)
+	if err != nil {
+		return "", err
+	}
+	commit := map[string]interface{}{"commit_message": message}
+	project, branch := g.upstream.ID, g.upstream.DefaultBranch
+	var exists bool
+	if isPR {
+		project, branch = g.fork.ID, aitgh.PullRequestBranch(repoPath)
+		g.branch = branch
+		exists, err = g.prepareBranch(repoPath)
+	} else {
+		exists, err = g.fileExists(project, repoPath, branch)
+	}
+	if err != nil {
+		return "", err
+	}
+	action := "create"
+	if exists {
+		action = "update"
+	}
+	commit["branch"] = branch
+	commit["actions"] = []map[string]string{{"action": action, "file_path": repoPath, "content": string(content)}}
+	var done struct {
+		ID string `json:"id"`
+	}
+	err = g.api.do("POST", fmt.Sprintf("/projects/%d/repository/commits", project), commit, &done)
+	return done.ID, err
+}
+
+// prepareBranch makes sure the pull request branch of the fork can be
+// committed to, and returns whether the file at repoPath is on it. The branch
+// is kept while a merge request from it is open, so committing to it updates
+// that merge request, otherwise it's started over from the head of the
+// upstream's default branch.
+func (g *gitlab) prepareBranch(repoPath string) (bool, error) {
+	branches := fmt.Sprintf("/projects/%d/repository/branches", g.fork.ID)
+	err := g.api.do("GET", branches+"/"+url.PathEscape(g.branch), nil, nil)
+	if err == nil {
+		open, err := g.openMergeRequest()
+		if err != nil {
+			return false, err
+		}
+		if open != nil {
+			fmt.Printf("Your merge request %v is still open, it will be updated.\n", open.WebURL)
+			return g.fileExists(g.fork.ID, repoPath, g.branch)
+		}
+		if err = g.api.do("DELETE", branches+"/"+url.PathEscape(g.branch), nil, nil); err != nil {
+			return false, err
+		}
+	} else if err != errNotFound {
+		return false, err
+	}
+	// The branch is created from the upstream rather than the fork, whose
+	// default branch may be behind.
+	var head struct {
+		Commit struct {
+			ID string `json:"id"`
+		} `json:"commit"`
+	}
+	err = g.api.do("GET", fmt.Sprintf("/projects/%d/repository/branches/%v", g.upstream.ID,
+		url.PathEscape(g.upstream.DefaultBranch)), nil, &head)
+	if err != nil {
+		return false, err
+	}
+	err = g.api.do("POST", branches, map[string]string{"branch": g.branch, "ref": head.Commit.ID}, nil)
+	if err != nil {
+		return false, fmt.Errorf("unable to prepare the branch %v in your fork: %v", g.branch, err)
+	}
+	return g.Exists(repoPath)
+}
+
+// openMergeRequest returns the open merge request from the pull request
+// branch of the fork, or nil if there's none.
+func (g *gitlab) openMergeRequest() (*glMergeRequest, error) {
+	var open []glMergeRequest
+	err := g.api.do("GET", fmt.Sprintf("/projects/%d/merge_requests?state=opened&source_branch=%v&author_username=%v",
+		g.upstream.ID, url.QueryEscape(g.branch), url.QueryEscape(g.user)), nil, &open)
+	if err != nil || len(open) == 0 {
+		return nil, err
+	}
+	return &open[0], nil
+}
+
+func (g *gitlab) OpenPR(title, body string) (string, int, error) {
+	open, err := g.openMergeRequest()
+	if err != nil {
+		return "", 0, err
+	}
+	request := map[string]interface{}{"title": title, "description": body}
+	if open != nil {
+		err = g.api.do("PUT", fmt.Sprintf("/projects/%d/merge_requests/%d", g.upstream.ID, open.IID), request, nil)
+		return open.WebURL, open.IID, err
+	}
+	request["source_branch"] = g.branch
+	request["target_branch"] = g.upstream.DefaultBranch
+	request["target_project_id"] = g.upstream.ID
+	created := glMergeRequest{}
+	err = g.api.do("POST", fmt.Sprintf("/projects/%d/merge_requests", g.fork.ID), request, &created)
+	return created.WebURL, created.IID, err
+}
+
+func (g *gitlab) EditPR(number int, body string) error {
+	return g.api.do("PUT", fmt.Sprintf("/projects/%d/merge_requests/%d", g.upstream.ID, number),
+		map[string]string{"description": body}, nil)
+}
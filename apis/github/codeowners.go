@@ -0,0 +1,143 @@
+package github
+
+import (
+	"bufio"
+	"fmt"
+	"io/ioutil"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/google/go-github/v32/github"
+)
+
+// codeownersPaths are the locations GitHub reads a CODEOWNERS file from, in
+// order of precedence.
+var codeownersPaths = []string{".github/CODEOWNERS", "CODEOWNERS", "docs/CODEOWNERS"}
+
+// ownerRule is a single line of a CODEOWNERS file.
+type ownerRule struct {
+	pattern *regexp.Regexp
+	owners  []string
+}
+
+// parseCodeowners reads the rules of a CODEOWNERS file, skipping comments and
+// lines it can't make sense of.
+func parseCodeowners(data string) (rules []ownerRule) {
+	scanner := bufio.NewScanner(strings.NewReader(data))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if i := strings.Index(line, "#"); i >= 0 {
+			line = line[:i]
+		}
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		pattern, err := codeownersPattern(fields[0])
+		if err != nil {
+			continue
+		}
+		rules = append(rules, ownerRule{pattern: pattern, owners: fields[1:]})
+	}
+	return rules
+}
+
+// codeownersPattern converts a gitignore style CODEOWNERS pattern to a
+// regular expression matching repository paths.
+func codeownersPattern(pattern string) (*regexp.Regexp, error) {
+	anchored := strings.HasPrefix(pattern, "/") || strings.Contains(strings.TrimSuffix(pattern, "/"), "/")
+	pattern = strings.TrimPrefix(pattern, "/")
+	var expr strings.Builder
+	if anchored {
+		expr.WriteString("^")
+	} else {
+		expr.WriteString("(^|/)")
+	}
+	for i := 0; i < len(pattern); i++ {
+		switch {
+		case strings.HasPrefix(pattern[i:], "**/"):
+			expr.WriteString("(.*/)?")
+			i += 2
+		case strings.HasPrefix(pattern[i:], "**"):
+			expr.WriteString(".*")
+			i++
+		case pattern[i] == '*':
+			expr.WriteString("[^/]*")
+		case pattern[i] == '?':
+			expr.WriteString("[^/]")
+		default:
+			expr.WriteString(regexp.QuoteMeta(string(pattern[i])))
+		}
+	}
+	// A pattern matches the path itself or anything beneath it.
+	if !strings.HasSuffix(pattern, "/") {
+		expr.WriteString("(/|$)")
+	}
+	return regexp.Compile(expr.String())
+}
+
+// ownersFor returns the owners of path. As on GitHub, the last matching rule
+// takes precedence.
+func ownersFor(rules []ownerRule, path string) []string {
+	path = strings.TrimPrefix(path, "/")
+	for i := len(rules) - 1; i >= 0; i-- {
+		if rules[i].pattern.MatchString(path) {
+			return rules[i].owners
+		}
+	}
+	return nil
+}
+
+// fetchCodeowners downloads the upstream repository's CODEOWNERS file, if it
+// has one.
+func fetchCodeowners() (string, bool) {
+	opts := &github.RepositoryContentGetOptions{}
+	for _, path := range codeownersPaths {
+		reader, err := client.Repositories.DownloadContents(cache.ctx, cache.upstream.owner,
+			cache.upstream.name, path, opts)
+		if err != nil {
+			continue
+		}
+		data, err := ioutil.ReadAll(reader)
+		reader.Close()
+		if err == nil {
+			return string(data), true
+		}
+	}
+	return "", false
+}
+
+// requestCodeownerReviews asks the owners of the keyset path to review the
+// pull request. Owners may be users (@user) or teams (@org/team); owners
+// listed by email can't be requested through the API and are skipped.
+func requestCodeownerReviews(number int, path string) {
+	data, ok := fetchCodeowners()
+	if !ok {
+		return
+	}
+	var users, teams []string
+	for _, owner := range ownersFor(parseCodeowners(data), path) {
+		if !strings.HasPrefix(owner, "@") {
+			continue
+		}
+		owner = strings.TrimPrefix(owner, "@")
+		if i := strings.Index(owner, "/"); i >= 0 {
+			teams = append(teams, owner[i+1:])
+		} else if !strings.EqualFold(owner, cache.user.GetLogin()) {
+			users = append(users, owner)
+		}
+	}
+	if len(users) == 0 && len(teams) == 0 {
+		return
+	}
+	ctx, cancel := callContext(8 * time.Second)
+	defer cancel()
+	_, _, err := client.PullRequests.RequestReviewers(ctx, cache.upstream.owner,
+		cache.upstream.name, number, github.ReviewersRequest{Reviewers: users, TeamReviewers: teams})
+	if err != nil {
+		fmt.Println("Unable to request reviews from the code owners:", err)
+		return
+	}
+	fmt.Println("Requested reviews from the code owners:", strings.Join(append(users, teams...), ", "))
+}
@@ -0,0 +1,23 @@
+package github
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOwnersFor(t *testing.T) {
+	rules := parseCodeowners(`# Keyset maintainers
+*                   @arken/maintainers
+*.ks                @keyset-bot
+/science/           @biologist   # all science keysets
+library/**/rare.ks  @librarian user@example.com
+`)
+	assert.Equal(t, []string{"@arken/maintainers"}, ownersFor(rules, "README.md"))
+	assert.Equal(t, []string{"@keyset-bot"}, ownersFor(rules, "library/fiction.ks"))
+	assert.Equal(t, []string{"@biologist"}, ownersFor(rules, "science/biology/datasets.ks"))
+	assert.Equal(t, []string{"@keyset-bot"}, ownersFor(rules, "other/science/data.ks"))
+	assert.Equal(t, []string{"@librarian", "user@example.com"}, ownersFor(rules, "library/a/b/rare.ks"))
+	assert.Equal(t, []string{"@librarian", "user@example.com"}, ownersFor(rules, "/library/rare.ks"))
+	assert.Nil(t, ownersFor(parseCodeowners(""), "any.ks"))
+}
@@ -1,6 +1,7 @@
 package github
 
 import (
+	"errors"
 	"io/ioutil"
 	"os"
 	"path/filepath"
@@ -11,10 +12,13 @@ import (
 )
 
 // CreateFile attempts to upload the file at localPath to the current repo at
-// the path repoPath.
-func CreateFile(localPath, repoPath, commit string, isPR bool) {
+// the path repoPath. It returns the SHA of the resulting commit.
+func CreateFile(localPath, repoPath, commit string, isPR bool) (string, error) {
+	defer utils.TimePhase("push")()
 	file, err := ioutil.ReadFile(localPath)
-	utils.CheckError(err)
+	if err != nil {
+		return "", err
+	}
 	opts := &github.RepositoryContentFileOptions{
 		Message: github.String(commit),
 		Content: file,
@@ -24,86 +28,162 @@ func CreateFile(localPath, repoPath, commit string, isPR bool) {
 		owner = *cache.user.Login
 		// if it's a PR, the repo belongs to our user and not what we pulled out
 		// of the original URL.
+		opts.Branch = prBranch()
+	}
+	resp, _, err := client.Repositories.CreateFile(cache.ctx, owner, cache.upstream.name,
+		repoPath, opts)
+	if err != nil {
+		return "", err
+	}
+	return resp.GetSHA(), nil
+}
+
+// CreateBranchFile uploads the file at localPath to the given branch of the
+// forked repo at the path repoPath. It returns the SHA of the resulting commit.
+func CreateBranchFile(localPath, repoPath, commit, branch string) (string, error) {
+	defer utils.TimePhase("push")()
+	file, err := ioutil.ReadFile(localPath)
+	if err != nil {
+		return "", err
+	}
+	opts := &github.RepositoryContentFileOptions{
+		Message: github.String(commit),
+		Content: file,
+		Branch:  github.String(branch),
 	}
-	_, _, err = client.Repositories.CreateFile(cache.ctx, owner, cache.upstream.name,
+	resp, _, err := client.Repositories.CreateFile(cache.ctx, cache.fork.owner, cache.fork.name,
 		repoPath, opts)
-	utils.CheckError(err)
+	if err != nil {
+		return "", err
+	}
+	return resp.GetSHA(), nil
 }
 
 // UpdateFile attempts to upload the file at localPath to the current repo at
-// the path repoPath. The file is expected to exist in the repo.
-func UpdateFile(localPath, repoPath, commit string, isPR bool) {
+// the path repoPath. The file is expected to exist in the repo. It returns the
+// SHA of the resulting commit.
+func UpdateFile(localPath, repoPath, commit string, isPR bool) (string, error) {
+	defer utils.TimePhase("push")()
 	file, err := ioutil.ReadFile(localPath)
-	utils.CheckError(err)
+	if err != nil {
+		return "", err
+	}
+	sha, err := fileSHA(repoPath, isPR)
+	if err != nil {
+		return "", err
+	}
 	opts := &github.RepositoryContentFileOptions{
 		Message: github.String(commit),
 		Content: file,
-		SHA:     github.String(getFileSHA(repoPath, isPR)),
+		SHA:     github.String(sha),
 	}
 	owner := cache.upstream.owner
 	if isPR {
 		owner = *cache.user.Login
+		opts.Branch = prBranch()
 	}
-	_, _, err = client.Repositories.UpdateFile(cache.ctx, owner, cache.upstream.name,
+	resp, _, err := client.Repositories.UpdateFile(cache.ctx, owner, cache.upstream.name,
 		repoPath, opts)
-	utils.CheckError(err)
+	if err != nil {
+		return "", err
+	}
+	return resp.GetSHA(), nil
 }
 
 // ReplaceFile attempts to upload the file at localPath to the current repo at
 // the path repoPath. The file is expected to exist in the repo. It deletes the
-// old version and uploads the new one.
-func ReplaceFile(localPath, repoPath, commit string, isPR bool) {
+// old version and uploads the new one. It returns the SHA of the commit adding
+// the new version.
+func ReplaceFile(localPath, repoPath, commit string, isPR bool) (string, error) {
+	defer utils.TimePhase("push")()
 	file, err := ioutil.ReadFile(localPath)
-	utils.CheckError(err)
+	if err != nil {
+		return "", err
+	}
+	sha, err := fileSHA(repoPath, isPR)
+	if err != nil {
+		return "", err
+	}
 	opts := &github.RepositoryContentFileOptions{
 		Message: github.String(commit),
 		Content: file,
-		SHA:     github.String(getFileSHA(repoPath, isPR)),
+		SHA:     github.String(sha),
 	}
 	owner := cache.upstream.owner
 	if isPR {
 		owner = *cache.user.Login
+		opts.Branch = prBranch()
 	}
 	_, _, err = client.Repositories.DeleteFile(cache.ctx, owner, cache.upstream.name,
 		repoPath, opts)
-	utils.CheckError(err)
+	if err != nil {
+		return "", err
+	}
 	opts.SHA = nil
-	_, _, err = client.Repositories.CreateFile(cache.ctx, owner, cache.upstream.name,
+	resp, _, err := client.Repositories.CreateFile(cache.ctx, owner, cache.upstream.name,
 		repoPath, opts)
+	if err != nil {
+		return "", err
+	}
+	return resp.GetSHA(), nil
+}
+
+// CommitFile uploads the file at localPath to the current repo at the path
+// repoPath, replacing the file already there if any, and returns the SHA of
+// the resulting commit.
+func CommitFile(localPath, repoPath, commit string, isPR bool) (string, error) {
+	exists, err := KeysetExists(repoPath, isPR)
+	if err != nil {
+		return "", err
+	}
+	if exists {
+		return ReplaceFile(localPath, repoPath, commit, isPR)
+	}
+	return CreateFile(localPath, repoPath, commit, isPR)
 }
 
 // getFileSHA returns the sha of a file in the current repo. Returns "" if the
 // file doesn't exist. path should be the path to the file in the repo, not
 // locally
 func getFileSHA(path string, isPR bool) string {
+	sha, err := fileSHA(path, isPR)
+	if err != nil {
+		utils.FatalPrintln(err)
+	}
+	return sha
+}
+
+// fileSHA is getFileSHA returning the error the repo couldn't be read with.
+func fileSHA(path string, isPR bool) (string, error) {
 	owner := cache.upstream.owner
+	opts := &github.RepositoryContentGetOptions{}
 	if isPR {
 		owner = *cache.user.Login
+		opts.Ref = cache.branch
 	}
 	sha, ok := cache.shas[path]
 	if ok && sha != "" {
-		return sha
+		return sha, nil
 	}
 	dir := filepath.Dir(path)
 	base := filepath.Base(path)
-	opts := &github.RepositoryContentGetOptions{}
 	_, contents, resp, err := client.Repositories.GetContents(cache.ctx, owner,
 		cache.upstream.name, dir, opts)
 	if err != nil {
 		if resp != nil && (resp.Response.StatusCode == 404 || resp.Response.StatusCode == 403) {
-			return ""
+			return "", nil
 		}
-		utils.FatalPrintln(err)
+		return "", err
 	}
 	for _, file := range contents {
 		// fetch the metadata of all the files in the directory the keyset file
 		// is supposed to go into.
 		if *file.Name == base {
 			cache.shas[path] = *file.SHA
-			return *file.SHA
+			return *file.SHA, nil
 		}
 	}
-	return "" //if the file didn't exist return empty string
+	return "", nil //if the file didn't exist return empty string
 }
 
 // KeysetExistsInRepo returns true if the file at path exists in repo, false
@@ -112,9 +192,19 @@ func KeysetExistsInRepo(path string, isPR bool) bool {
 	return getFileSHA(path, isPR) != ""
 }
 
+// KeysetExists is KeysetExistsInRepo returning the error the repo couldn't be
+// read with.
+func KeysetExists(path string, isPR bool) (bool, error) {
+	sha, err := fileSHA(path, isPR)
+	return sha != "", err
+}
+
 // DownloadRepoAppTemplate looks for a file called "application.md" in the root
 // of the repo and downloads it if such a file exists.
 func DownloadRepoAppTemplate() (string, error) {
+	if cache.upstream == nil {
+		return "", errors.New("not submitting to a GitHub repository")
+	}
 	path := filepath.Join(".ait", cache.upstream.name+"_application.md")
 	return path, DownloadFile("application.md", path)
 }
@@ -135,9 +225,10 @@ func DownloadFile(repoPath, localPath string) error {
 	if err != nil {
 		return err //probably means the file didn't exist in the fork
 	}
+	defer reader.Close()
 	data, err := ioutil.ReadAll(reader)
-	utils.CheckError(err)
-	err = ioutil.WriteFile(localPath, data, 0644)
-	reader.Close()
-	return err
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(localPath, data, 0644)
 }
@@ -1,12 +1,13 @@
 package github
 
 import (
-	"bufio"
 	"context"
+	"errors"
 	"fmt"
 	"net/http"
 	"os"
 	"strings"
+	"time"
 
 	"github.com/arken/ait/config"
 	"github.com/arken/ait/utils"
@@ -24,6 +25,15 @@ type Info struct {
 	shas     map[string]string
 	isPR     bool
 	ctx      context.Context
+	// triedGit is set once git's credential helpers have been asked for a
+	// token, and fromGit while the token is the one they gave.
+	triedGit bool
+	fromGit  bool
+	// fromEnv is set while the token is the one given by AIT_GIT_TOKEN.
+	fromEnv bool
+	// branch is the branch of the fork pull request commits are pushed to,
+	// empty for its default branch.
+	branch string
 }
 
 // Repository defines a respository response from Github.
@@ -40,36 +50,93 @@ var (
 )
 
 // Init sets up the github portion of AIT with the context it needs going
-// forward, including the url and client id.
-func Init(URL string, isPR bool) bool {
+// forward, including the url and client id. It returns whether the user can
+// push to the repository, always true for pull requests.
+func Init(URL string, isPR bool) (bool, error) {
 	cache = Info{
 		upstream: &Repository{
 			url:   URL,
 			owner: utils.GetRepoOwner(URL),
 			name:  utils.GetRepoName(URL),
 		},
-		token:    config.Global.Git.PAT,
+		token:    config.GitHubToken(),
 		clientID: clientID,
 		shas:     make(map[string]string),
 		isPR:     isPR,
 		ctx:      context.Background(),
 	}
-	client = github.NewClient(&http.Client{}) // basic client for setting up app
+	if token, ok := os.LookupEnv("AIT_GIT_TOKEN"); ok && token != "" {
+		cache.token, cache.fromEnv = token, true
+	}
+	// basic client for setting up app
+	client = github.NewClient(httpClient())
 	if !repoExists() {
-		utils.FatalPrintf(
-			`Could not stat the repository %v. 
+		return false, fmt.Errorf(`could not stat the repository %v
 Make sure that there are no typos in the URL, the repository is public, and this
-computer has an internet connection.
-`, cache.upstream.url)
+computer has an internet connection`, cache.upstream.url)
 	}
 	for correctUser := false; !correctUser; {
 		collectToken()
 		correctUser = promptIsCorrectUser()
 	}
 	if isPR {
-		return true
+		return true, nil
 	}
-	return hasWritePermission()
+	return writePermission()
+}
+
+// InitWithToken is Init for programs embedding ait: the repository at URL is
+// reached with the given token, without signing in or asking anything, and
+// problems are returned. It returns whether the user can push to the
+// repository, always true for pull requests.
+func InitWithToken(ctx context.Context, URL, token string, isPR bool) (bool, error) {
+	if token == "" {
+		return false, errors.New("a GitHub token is needed")
+	}
+	cache = Info{
+		upstream: &Repository{
+			url:   URL,
+			owner: utils.GetRepoOwner(URL),
+			name:  utils.GetRepoName(URL),
+		},
+		token:    token,
+		clientID: clientID,
+		shas:     make(map[string]string),
+		isPR:     isPR,
+		ctx:      ctx,
+		triedGit: true,
+	}
+	client = github.NewClient(httpClient())
+	if !repoExists() {
+		return false, fmt.Errorf("could not stat the repository %v", URL)
+	}
+	// The token is set, so this only authenticates the client.
+	collectToken()
+	user, _, err := client.Users.Get(ctx, "")
+	if err != nil {
+		return false, fmt.Errorf("unable to authenticate with the token: %v", err)
+	}
+	cache.user = user
+	if isPR {
+		return true, nil
+	}
+	return writePermission()
+}
+
+// callContext returns the context an API call is made with, done after timeout
+// or once the context given to InitWithToken is.
+func callContext(timeout time.Duration) (context.Context, context.CancelFunc) {
+	ctx := cache.ctx
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	return context.WithTimeout(ctx, timeout)
+}
+
+// httpClient returns the client the API is reached with, sending requests that
+// failed for a transient reason, ie a 502, again.
+func httpClient() *http.Client {
+	return utils.WithRetries(utils.PinnedClient(config.Global.Trust.Hosts))
 }
 
 // promptIsCorrectUser asks the user if the user we authenticated is correct.
@@ -87,10 +154,11 @@ func promptIsCorrectUser() bool {
 	}
 	fmt.Println("Successfully authenticated as user", *user.Login)
 	cache.user = user
+	if utils.NonInteractive {
+		return true
+	}
 	fmt.Printf("Is this correct? ([y]/n) ")
-	reader := bufio.NewReader(os.Stdin)
-	input, _ := reader.ReadString('\n')
-	input = strings.ToLower(strings.TrimSpace(input))
+	input := strings.ToLower(utils.ReadAnswer())
 	if input == "n" {
 		cache.token = ""
 		SaveToken() //clear the token from config
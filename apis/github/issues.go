@@ -0,0 +1,31 @@
+package github
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/google/go-github/v32/github"
+)
+
+// IssuesEnabled returns whether the upstream repo accepts issues, which some
+// keyset repositories use for submissions instead of pull requests.
+func IssuesEnabled() bool {
+	repo, _, err := client.Repositories.Get(
+		cache.ctx, cache.upstream.owner, cache.upstream.name)
+	return err == nil && repo.GetHasIssues()
+}
+
+// CreateIssue opens an issue on the upstream repo and returns its URL and
+// number.
+func CreateIssue(title, body string) (string, int, error) {
+	fmt.Println("Attempting to create the issue...")
+	ctx, cancel := callContext(8 * time.Second)
+	defer cancel()
+	issue, _, err := client.Issues.Create(ctx, cache.upstream.owner, cache.upstream.name,
+		&github.IssueRequest{Title: github.String(title), Body: github.String(body)})
+	if err != nil {
+		return "", 0, err
+	}
+	fmt.Println("\nYour new issue can be found at:", issue.GetHTMLURL())
+	return issue.GetHTMLURL(), issue.GetNumber(), nil
+}
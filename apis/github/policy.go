@@ -0,0 +1,220 @@
+package github
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"regexp"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/arken/ait/utils"
+
+	"github.com/BurntSushi/toml"
+	"github.com/google/go-github/v32/github"
+)
+
+// policyPath is where an upstream repository states what it expects of
+// submissions.
+const policyPath = "ait-policy.toml"
+
+// Policy is an upstream repository's submission policy.
+type Policy struct {
+	// Changelog, when set, asks for a line describing each new dataset to be
+	// appended to a file in the same commit as its keyset.
+	Changelog *ChangelogPolicy
+}
+
+// ChangelogPolicy describes the changelog entry of each submission.
+type ChangelogPolicy struct {
+	// File is the path of the changelog in the repository.
+	File string
+	// Template is a text/template rendering a ChangelogEntry to a line.
+	Template string
+	// Pattern is a regular expression the rendered line must match, and
+	// MaxLength the longest it may be. Both are optional.
+	Pattern   string
+	MaxLength int
+}
+
+// ChangelogEntry is what a changelog template can describe a submission with.
+type ChangelogEntry struct {
+	Title       string
+	Description string
+	Path        string
+	Category    string
+	Author      string
+	Date        string
+	Files       int
+	Size        string
+}
+
+// parsePolicy reads and validates a policy file.
+func parsePolicy(data string) (*Policy, error) {
+	policy := &Policy{}
+	if _, err := toml.Decode(data, policy); err != nil {
+		return nil, err
+	}
+	if c := policy.Changelog; c != nil {
+		if c.File == "" || c.Template == "" {
+			return nil, errors.New("the changelog needs a File and a Template")
+		}
+		if _, err := template.New("changelog").Parse(c.Template); err != nil {
+			return nil, err
+		}
+		if _, err := regexp.Compile(c.Pattern); err != nil {
+			return nil, err
+		}
+	}
+	return policy, nil
+}
+
+// FetchPolicy downloads and validates the upstream repository's submission
+// policy. It returns nil if the repository doesn't have one, and the error
+// the policy couldn't be read with otherwise, so a policy isn't skipped
+// because GitHub couldn't be reached.
+func FetchPolicy() (*Policy, error) {
+	file, _, resp, err := client.Repositories.GetContents(cache.ctx, cache.upstream.owner,
+		cache.upstream.name, policyPath, &github.RepositoryContentGetOptions{})
+	if resp != nil && resp.Response.StatusCode == 404 {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("unable to read %v: %v", policyPath, err)
+	}
+	if file == nil {
+		return nil, fmt.Errorf("%v isn't a file", policyPath)
+	}
+	data, err := file.GetContent()
+	if err != nil {
+		return nil, err
+	}
+	return parsePolicy(data)
+}
+
+// Render renders the changelog line of a submission and checks it against the
+// policy.
+func (c *ChangelogPolicy) Render(entry ChangelogEntry) (string, error) {
+	tmpl, err := template.New("changelog").Parse(c.Template)
+	if err != nil {
+		return "", err
+	}
+	buf := new(bytes.Buffer)
+	if err = tmpl.Execute(buf, entry); err != nil {
+		return "", err
+	}
+	line := strings.TrimSpace(buf.String())
+	if strings.Contains(line, "\n") {
+		return "", errors.New("the changelog entry spans several lines")
+	}
+	if c.MaxLength > 0 && len(line) > c.MaxLength {
+		return "", fmt.Errorf("the changelog entry is longer than %d characters", c.MaxLength)
+	}
+	if ok, _ := regexp.MatchString(c.Pattern, line); !ok {
+		return "", fmt.Errorf("the changelog entry %q doesn't match %q", line, c.Pattern)
+	}
+	return line, nil
+}
+
+// appendLine appends line to the contents of a file, ending the last line
+// first if needed.
+func appendLine(contents []byte, line string) []byte {
+	if len(contents) > 0 && !bytes.HasSuffix(contents, []byte("\n")) {
+		contents = append(contents, '\n')
+	}
+	return append(contents, line+"\n"...)
+}
+
+// CommitFiles uploads the local files, keyed by the path in the repository
+// each is uploaded to, in a single commit so that either all of them change or
+// none do. When changelog is set, line is appended to it in the same commit.
+// The commit goes to branch of the fork, or of the upstream if it isn't a PR;
+// an empty branch is the pull request branch, or the default branch. It returns the SHA of the commit.
+func CommitFiles(files map[string]string, commit string, changelog *ChangelogPolicy,
+	line string, isPR bool, branch string) (string, error) {
+	defer utils.TimePhase("push")()
+	contents := make(map[string][]byte, len(files)+1)
+	for repoPath, localPath := range files {
+		file, err := ioutil.ReadFile(localPath)
+		if err != nil {
+			return "", err
+		}
+		contents[repoPath] = file
+	}
+	owner := cache.upstream.owner
+	if isPR {
+		owner = *cache.user.Login
+		if branch == "" {
+			branch = cache.branch
+		}
+	}
+	if branch == "" {
+		branch = getDefaultBranch()
+	}
+	ctx, cancel := callContext(30 * time.Second)
+	defer cancel()
+	if changelog != nil {
+		var existing []byte
+		reader, err := client.Repositories.DownloadContents(ctx, owner, cache.upstream.name,
+			changelog.File, &github.RepositoryContentGetOptions{Ref: branch})
+		if err == nil {
+			existing, err = ioutil.ReadAll(reader)
+			reader.Close()
+			if err != nil {
+				return "", err
+			}
+		}
+		contents[changelog.File] = appendLine(existing, line)
+	}
+	return commitFiles(ctx, owner, branch, commit, contents)
+}
+
+// commitFiles commits files, by path, on top of branch of owner's copy of the
+// repository and returns the SHA of the commit.
+func commitFiles(ctx context.Context, owner, branch, message string,
+	files map[string][]byte) (string, error) {
+	name := cache.upstream.name
+	ref, _, err := client.Git.GetRef(ctx, owner, name, "heads/"+branch)
+	if err != nil {
+		return "", err
+	}
+	parent, _, err := client.Git.GetCommit(ctx, owner, name, ref.Object.GetSHA())
+	if err != nil {
+		return "", err
+	}
+	entries := make([]*github.TreeEntry, 0, len(files))
+	for path, contents := range files {
+		entries = append(entries, &github.TreeEntry{
+			Path:    github.String(path),
+			Mode:    github.String("100644"),
+			Type:    github.String("blob"),
+			Content: github.String(string(contents)),
+		})
+	}
+	tree, _, err := client.Git.CreateTree(ctx, owner, name, parent.Tree.GetSHA(), entries)
+	if err != nil {
+		return "", err
+	}
+	commit := &github.Commit{
+		Message: github.String(message),
+		Tree:    tree,
+		Parents: []*github.Commit{parent},
+	}
+	if SigningEnabled() {
+		if err = signCommit(commit); err != nil {
+			return "", err
+		}
+	}
+	done, _, err := client.Git.CreateCommit(ctx, owner, name, commit)
+	if err != nil {
+		return "", err
+	}
+	ref.Object.SHA = done.SHA
+	if _, _, err = client.Git.UpdateRef(ctx, owner, name, ref, false); err != nil {
+		return "", err
+	}
+	return done.GetSHA(), nil
+}
@@ -0,0 +1,73 @@
+package github
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/google/go-github/v32/github"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestChangelogPolicy(t *testing.T) {
+	policy, err := parsePolicy(`[Changelog]
+File = "CHANGELOG.md"
+Template = "- {{.Date}} [{{.Title}}]({{.Path}}): {{.Files}} files, {{.Size}}"
+Pattern = '^- \d{4}-\d{2}-\d{2} \['
+MaxLength = 80
+`)
+	assert.NoError(t, err)
+	entry := ChangelogEntry{Title: "Moon Maps", Path: "space/moon.ks", Date: "2026-10-15", Files: 12, Size: "3.5GB"}
+	line, err := policy.Changelog.Render(entry)
+	assert.NoError(t, err)
+	assert.Equal(t, "- 2026-10-15 [Moon Maps](space/moon.ks): 12 files, 3.5GB", line)
+
+	entry.Date = "today"
+	_, err = policy.Changelog.Render(entry)
+	assert.Error(t, err)
+	entry.Date, entry.Title = "2026-10-15", "A title far too long to fit within the eighty characters"
+	_, err = policy.Changelog.Render(entry)
+	assert.Error(t, err)
+
+	_, err = parsePolicy("[Changelog]\nFile = \"CHANGELOG.md\"\n")
+	assert.Error(t, err)
+	_, err = parsePolicy("[Changelog]\nFile = \"a\"\nTemplate = \"{{.Title\"\n")
+	assert.Error(t, err)
+
+	assert.Equal(t, "# Datasets\n- new\n", string(appendLine([]byte("# Datasets"), "- new")))
+	assert.Equal(t, "- new\n", string(appendLine(nil, "- new")))
+}
+
+func TestFetchPolicy(t *testing.T) {
+	status := http.StatusNotFound
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if status != http.StatusOK {
+			w.WriteHeader(status)
+			return
+		}
+		content := base64.StdEncoding.EncodeToString([]byte("[Changelog]\nFile = \"CHANGELOG.md\"\nTemplate = \"- {{.Title}}\"\n"))
+		fmt.Fprintf(w, `{"type": "file", "encoding": "base64", "content": %q}`, content)
+	}))
+	defer srv.Close()
+	defer func(c *github.Client, i Info) { client, cache = c, i }(client, cache)
+	client = github.NewClient(nil)
+	client.BaseURL, _ = url.Parse(srv.URL + "/")
+	cache = Info{upstream: &Repository{owner: "arken", name: "core-keyset"}, ctx: context.Background()}
+
+	policy, err := FetchPolicy()
+	assert.NoError(t, err)
+	assert.Nil(t, policy)
+
+	status = http.StatusBadGateway
+	_, err = FetchPolicy()
+	assert.Error(t, err, "a policy that can't be read shouldn't be taken as none")
+
+	status = http.StatusOK
+	policy, err = FetchPolicy()
+	assert.NoError(t, err)
+	assert.Equal(t, "CHANGELOG.md", policy.Changelog.File)
+}
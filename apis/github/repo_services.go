@@ -1,19 +1,46 @@
 package github
 
 import (
-	"context"
+	"errors"
 	"fmt"
+	"strings"
 	"time"
+	"unicode"
 
+	"github.com/arken/ait/config"
 	"github.com/arken/ait/utils"
 
 	"github.com/google/go-github/v32/github"
 )
 
-// CreateFork uses the github api to create a fork in the user's github account
-func CreateFork() {
+// forkTimeout is how long a new fork is waited for, GitHub creates them in the
+// background.
+const forkTimeout = 60 * time.Second
+
+// CreateFork uses the github api to create a fork in the user's github account,
+// or reuses the fork a previous submission made.
+func CreateFork() error {
 	owner, name := cache.upstream.owner, cache.upstream.name
-	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Second)
+	existing, resp, err := client.Repositories.Get(cache.ctx, *cache.user.Login, name)
+	if err == nil {
+		parent := existing.GetParent()
+		if !existing.GetFork() || !strings.EqualFold(parent.GetOwner().GetLogin(), owner) ||
+			!strings.EqualFold(parent.GetName(), name) {
+			return fmt.Errorf("your account already has a repository named \"%v\" that isn't a fork of %v's", name, owner)
+		}
+		fmt.Printf("Using your fork at %v\n", existing.GetHTMLURL())
+		cache.fork = &Repository{
+			url:   existing.GetHTMLURL(),
+			owner: *cache.user.Login,
+			name:  name,
+		}
+		return nil
+	}
+	if resp == nil || resp.StatusCode != 404 {
+		return fmt.Errorf("unable to look for your fork of %v's repo \"%v\": %v", owner, name, err)
+	}
+
+	ctx, cancel := callContext(20 * time.Second)
 	defer cancel()
 	fmt.Printf("Attempting to fork %v's repository \"%v\" to your account...\n", owner, name)
 	remoteRepo, response, err := client.Repositories.CreateFork(ctx, owner, name, nil)
@@ -27,46 +54,246 @@ func CreateFork() {
 	// 202 seems to be the most common non-error status code.
 	if remoteRepo == nil || status != 202 && status != 200 {
 		if response != nil && response.Response.StatusCode == 401 {
-			utils.FatalPrintln("Your personal access token didn't work!")
-			// This should never happen anymore since we get the PAT from GH
-		} else {
-			utils.FatalPrintf(
-				`Something went wrong when trying to fork %v's repo "%v":\n%v`,
-				owner, name, err)
+			return errors.New("GitHub refused your personal access token, unable to fork the repository")
 		}
+		return fmt.Errorf("something went wrong when trying to fork %v's repo \"%v\": %v",
+			owner, name, err)
 	}
-	fmt.Printf("Fork creation successful. See it at %v\n\n", remoteRepo.GetHTMLURL())
 	cache.fork = &Repository{
 		url:   remoteRepo.GetHTMLURL(),
 		owner: *cache.user.Login,
 		name:  name,
 	}
+	if err = waitForFork(); err != nil {
+		return err
+	}
+	fmt.Printf("Fork creation successful. See it at %v\n\n", remoteRepo.GetHTMLURL())
+	return nil
+}
+
+// waitForFork waits until the default branch of the new fork exists, so
+// commits can be pushed to it.
+func waitForFork() error {
+	branch := getDefaultBranch()
+	for start := time.Now(); time.Since(start) < forkTimeout; time.Sleep(2 * time.Second) {
+		if err := cache.ctx.Err(); err != nil {
+			return err
+		}
+		if _, _, err := client.Git.GetRef(cache.ctx, cache.fork.owner, cache.fork.name, "heads/"+branch); err == nil {
+			return nil
+		}
+	}
+	return fmt.Errorf("your fork %v wasn't ready after %v, submit again in a moment", cache.fork.url, forkTimeout)
+}
+
+// UsePullRequestBranch makes the keyset at path be pushed to a branch of the
+// fork named after it, rather than to the fork's default branch. If a previous
+// submission of the keyset left a pull request open from that branch, the
+// branch is kept so pushing to it updates that pull request. Otherwise it's
+// started over from the head of the upstream's default branch.
+func UsePullRequestBranch(path string) error {
+	branch := PullRequestBranch(path)
+	cache.branch = branch
+	if pr, err := openPullRequest(branch); err != nil {
+		return err
+	} else if pr != nil {
+		fmt.Printf("Your pull request %v is still open, it will be updated.\n", pr.GetHTMLURL())
+		return nil
+	}
+	ctx, cancel := callContext(8 * time.Second)
+	defer cancel()
+	base, _, err := client.Git.GetRef(ctx, cache.upstream.owner, cache.upstream.name,
+		"heads/"+getDefaultBranch())
+	if err != nil {
+		return err
+	}
+	ref := &github.Reference{
+		Ref:    github.String("refs/heads/" + branch),
+		Object: &github.GitObject{SHA: base.Object.SHA},
+	}
+	if _, _, err = client.Git.GetRef(ctx, cache.fork.owner, cache.fork.name, "heads/"+branch); err == nil {
+		_, _, err = client.Git.UpdateRef(ctx, cache.fork.owner, cache.fork.name, ref, true)
+	} else {
+		_, _, err = client.Git.CreateRef(ctx, cache.fork.owner, cache.fork.name, ref)
+	}
+	if err != nil {
+		return fmt.Errorf("unable to prepare the branch %v in your fork: %v", branch, err)
+	}
+	return nil
+}
+
+// ResumePullRequestBranch makes the keyset at path be pushed to the branch
+// UsePullRequestBranch prepared for it before, as it was left, ie to open the
+// pull request of a resumed submission whose commit was pushed already.
+func ResumePullRequestBranch(path string) {
+	cache.branch = PullRequestBranch(path)
+}
+
+// PullRequestBranch returns the name of the branch of the fork the keyset at
+// path is pushed to, ie "ait/data/survey" for data/survey.ks.
+func PullRequestBranch(path string) string {
+	name := strings.TrimSuffix(utils.SlashPath(path), ".ks")
+	name = strings.Map(func(r rune) rune {
+		if r == '/' || r == '-' || r == '_' || r == '.' || r < 128 && (unicode.IsLetter(r) || unicode.IsDigit(r)) {
+			return r
+		}
+		return '-'
+	}, name)
+	for strings.Contains(name, "..") {
+		name = strings.ReplaceAll(name, "..", ".")
+	}
+	return "ait/" + strings.Trim(name, "/.")
+}
+
+// openPullRequest returns the open pull request from the given branch of the
+// fork into the upstream repo, or nil if there's none.
+func openPullRequest(branch string) (*github.PullRequest, error) {
+	ctx, cancel := callContext(8 * time.Second)
+	defer cancel()
+	prs, _, err := client.PullRequests.List(ctx, cache.upstream.owner, cache.upstream.name,
+		&github.PullRequestListOptions{State: "open", Head: cache.fork.owner + ":" + branch})
+	if err != nil || len(prs) == 0 {
+		return nil, err
+	}
+	return prs[0], nil
+}
+
+// prBranch returns the branch of the fork commits for a pull request go to, or
+// nil for its default branch.
+func prBranch() *string {
+	if cache.branch == "" {
+		return nil
+	}
+	return github.String(cache.branch)
 }
 
 // CreatePullRequest creates a pull request from the forked repository to the
-// upstream repo.
-func CreatePullRequest(title, prBody string) {
+// upstream repo and returns its URL and number. Reviews are requested from the
+// code owners of the keyset at path, if the upstream repo has a CODEOWNERS file.
+func CreatePullRequest(title, prBody, path string) (string, int, error) {
 	branch := getDefaultBranch()
-	head := fmt.Sprintf("%v:%v", cache.fork.owner, branch)
+	if cache.branch != "" {
+		return createPullRequest(cache.branch, branch, title, prBody, path)
+	}
+	return createPullRequest(branch, branch, title, prBody, path)
+}
+
+// CreateBranchPullRequest creates a pull request from the given branch of the
+// forked repository to the upstream repo and returns its URL and number.
+func CreateBranchPullRequest(branch, title, prBody, path string) (string, int, error) {
+	return createPullRequest(branch, getDefaultBranch(), title, prBody, path)
+}
+
+// createPullRequest opens a pull request from the fork's head branch into the
+// upstream's base branch. If one is already open from it, ie from a previous
+// submission, its title and description are updated instead.
+func createPullRequest(head, base, title, prBody, path string) (string, int, error) {
+	open, err := openPullRequest(head)
+	if err != nil {
+		return "", 0, err
+	}
+	if open != nil {
+		ctx, cancel := callContext(8 * time.Second)
+		defer cancel()
+		_, _, err = client.PullRequests.Edit(ctx, cache.upstream.owner, cache.upstream.name,
+			open.GetNumber(), &github.PullRequest{Title: github.String(title), Body: github.String(prBody)})
+		if err != nil {
+			return "", 0, err
+		}
+		fmt.Println("\nYour pull request was updated, it can be found at:", open.GetHTMLURL())
+		return open.GetHTMLURL(), open.GetNumber(), nil
+	}
 	pr := &github.NewPullRequest{
 		Title:               github.String(title),
 		Body:                github.String(prBody),
-		Head:                github.String(head),
-		Base:                github.String(branch),
+		Head:                github.String(fmt.Sprintf("%v:%v", cache.fork.owner, head)),
+		Base:                github.String(base),
 		MaintainerCanModify: github.Bool(true),
 		Draft:               github.Bool(false),
 	}
 	fmt.Println("Attempting to create the pull request...")
-	ctx, cancel := context.WithTimeout(context.Background(), 8*time.Second)
+	ctx, cancel := callContext(8 * time.Second)
 	defer cancel()
 	donePR, _, err := client.PullRequests.Create(ctx, cache.upstream.owner,
 		cache.upstream.name, pr)
-	utils.CheckErrorWithCleanup(err, utils.SubmissionCleanup)
+	if err != nil {
+		return "", 0, err
+	}
+	requestCodeownerReviews(donePR.GetNumber(), path)
 	fmt.Println("\nYour new pull request can be found at:", donePR.GetHTMLURL())
+	return donePR.GetHTMLURL(), donePR.GetNumber(), nil
 }
 
-// getDefaultBranch returns the default branch in use in the current repo.
+// EditPullRequest replaces the description of the pull request with the
+// given number in the upstream repo.
+func EditPullRequest(number int, prBody string) error {
+	ctx, cancel := callContext(8 * time.Second)
+	defer cancel()
+	_, _, err := client.PullRequests.Edit(ctx, cache.upstream.owner,
+		cache.upstream.name, number, &github.PullRequest{Body: github.String(prBody)})
+	return err
+}
+
+// CreateBranch creates a branch in the forked repository starting at the head
+// of the upstream's default branch, so pull requests from it only contain
+// what is committed to it.
+func CreateBranch(branch string) error {
+	ctx, cancel := callContext(8 * time.Second)
+	defer cancel()
+	base, _, err := client.Git.GetRef(ctx, cache.upstream.owner, cache.upstream.name,
+		"heads/"+getDefaultBranch())
+	if err != nil {
+		return err
+	}
+	_, _, err = client.Git.CreateRef(ctx, cache.fork.owner, cache.fork.name, &github.Reference{
+		Ref:    github.String("refs/heads/" + branch),
+		Object: &github.GitObject{SHA: base.Object.SHA},
+	})
+	if err != nil {
+		return fmt.Errorf("unable to create the branch %v in your fork: %v", branch, err)
+	}
+	return nil
+}
+
+// PullRequestOpen returns whether the pull request with the given number in
+// the upstream repo is still open.
+func PullRequestOpen(number int) (bool, error) {
+	pr, _, err := client.PullRequests.Get(cache.ctx, cache.upstream.owner,
+		cache.upstream.name, number)
+	if err != nil {
+		return false, err
+	}
+	return pr.GetState() == "open", nil
+}
+
+// PullRequestMerged returns whether the pull request with the given number in
+// the repository at URL was merged. Unlike PullRequestOpen it doesn't need
+// Init, so it can be called from background checks without prompting.
+func PullRequestMerged(URL string, number int) (bool, error) {
+	ctx, cancel := callContext(8 * time.Second)
+	defer cancel()
+	c := github.NewClient(httpClient())
+	merged, _, err := c.PullRequests.IsMerged(ctx, utils.GetRepoOwner(URL),
+		utils.GetRepoName(URL), number)
+	return merged, err
+}
+
+// CommentPullRequest posts a comment on the pull request with the given number
+// in the upstream repo.
+func CommentPullRequest(number int, body string) error {
+	ctx, cancel := callContext(8 * time.Second)
+	defer cancel()
+	_, _, err := client.Issues.CreateComment(ctx, cache.upstream.owner,
+		cache.upstream.name, number, &github.IssueComment{Body: github.String(body)})
+	return err
+}
+
+// getDefaultBranch returns the default branch in use in the current repo, or
+// the branch saved with its remote alias.
 func getDefaultBranch() string {
+	if branch := config.RemoteOptionsOf(cache.upstream.url).Branch; branch != "" {
+		return branch
+	}
 	repo, _, err := client.Repositories.Get(
 		cache.ctx, cache.upstream.owner, cache.upstream.name)
 	if err != nil {
@@ -75,24 +302,23 @@ func getDefaultBranch() string {
 	return *repo.DefaultBranch
 }
 
-// hasWritePermission checks if the authenticated user has write permissions to
-// the repo at the upstream URL
-func hasWritePermission() bool {
+// writePermission checks if the authenticated user has write permissions to
+// the repo at the upstream URL, returning the error the permission couldn't be
+// checked with.
+func writePermission() (bool, error) {
 	perm, resp, err := client.Repositories.GetPermissionLevel(
 		cache.ctx, cache.upstream.owner, cache.upstream.name, *cache.user.Login,
 	)
-	if resp != nil && resp.Response.StatusCode != 200 {
-		if resp.Response.StatusCode == 403 {
-			return false
-		}
-		if resp.Response.StatusCode == 404 {
-			utils.FatalPrintln("The repository", cache.upstream.url,
-				"doesn't appear to exist.")
-		} else {
-			utils.FatalPrintln(err)
-		}
+	if resp != nil && resp.Response.StatusCode == 403 {
+		return false, nil
+	}
+	if resp != nil && resp.Response.StatusCode == 404 {
+		return false, fmt.Errorf("the repository %v doesn't appear to exist", cache.upstream.url)
+	}
+	if err != nil {
+		return false, err
 	}
-	return *perm.Permission == "admin" || *perm.Permission == "write"
+	return perm.GetPermission() == "admin" || perm.GetPermission() == "write", nil
 }
 
 // repoExists returns whether or not the given repository exists. It will
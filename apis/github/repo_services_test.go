@@ -0,0 +1,18 @@
+package github
+
+import "testing"
+
+func TestPullRequestBranch(t *testing.T) {
+	for path, expected := range map[string]string{
+		"survey.ks":              "ait/survey",
+		"data/2021/climate.ks":   "ait/data/2021/climate",
+		"data/my survey (v2).ks": "ait/data/my-survey--v2-",
+		"../sneaky..name.ks":     "ait/sneaky.name",
+		"données/été.ks":         "ait/donn-es/-t-",
+		"keysets/archive.tar.ks": "ait/keysets/archive.tar",
+	} {
+		if branch := PullRequestBranch(path); branch != expected {
+			t.Errorf("expected %v for %v, got %v", expected, path, branch)
+		}
+	}
+}
@@ -0,0 +1,50 @@
+package github
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/arken/ait/config"
+	"github.com/arken/ait/utils"
+
+	"github.com/google/go-github/v32/github"
+)
+
+// SigningEnabled returns whether keyset commits are signed, which only
+// commits made with CommitFiles are: the contents API commits as GitHub.
+func SigningEnabled() bool {
+	return config.Global.Git.SigningKey != ""
+}
+
+// signCommit signs commit with the signing key of the config. Its author is
+// set, with the date the signature covers.
+func signCommit(commit *github.Commit) error {
+	now := time.Now().Truncate(time.Second)
+	commit.Author = &github.CommitAuthor{
+		Name:  github.String(config.Global.Git.Name),
+		Email: github.String(config.Global.Git.Email),
+		Date:  &now,
+	}
+	signature, err := utils.Sign([]byte(commitPayload(commit)), config.Global.Git.SigningFormat,
+		config.Global.Git.SigningKey, utils.NamespaceGit)
+	if err != nil {
+		return err
+	}
+	commit.Verification = &github.SignatureVerification{Signature: github.String(signature)}
+	return nil
+}
+
+// commitPayload returns the commit object GitHub writes for commit, which
+// its signature is checked against. The committer is the author.
+func commitPayload(commit *github.Commit) string {
+	lines := []string{"tree " + commit.GetTree().GetSHA()}
+	for _, parent := range commit.Parents {
+		lines = append(lines, "parent "+parent.GetSHA())
+	}
+	author := commit.GetAuthor()
+	person := fmt.Sprintf("%v <%v> %d %v", author.GetName(), author.GetEmail(),
+		author.GetDate().Unix(), author.GetDate().Format("-0700"))
+	lines = append(lines, "author "+person, "committer "+person, "", commit.GetMessage())
+	return strings.Join(lines, "\n")
+}
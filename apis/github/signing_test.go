@@ -0,0 +1,24 @@
+package github
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/go-github/v32/github"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCommitPayload(t *testing.T) {
+	when := time.Date(2026, 10, 15, 9, 30, 0, 0, time.FixedZone("", -4*3600))
+	commit := &github.Commit{
+		Message: github.String("Add the 2026 survey"),
+		Tree:    &github.Tree{SHA: github.String("9c4bd2f")},
+		Parents: []*github.Commit{{SHA: github.String("f1e2d3c")}},
+		Author: &github.CommitAuthor{Name: github.String("Jane Doe"),
+			Email: github.String("jane@example.org"), Date: &when},
+	}
+	assert.Equal(t, "tree 9c4bd2f\nparent f1e2d3c\n"+
+		"author Jane Doe <jane@example.org> 1792071000 -0400\n"+
+		"committer Jane Doe <jane@example.org> 1792071000 -0400\n\nAdd the 2026 survey",
+		commitPayload(commit))
+}
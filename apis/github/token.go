@@ -1,6 +1,7 @@
 package github
 
 import (
+	"context"
 	"fmt"
 	"math"
 	"net/http"
@@ -29,11 +30,27 @@ func collectToken() {
 		tokenSource := oauth2.StaticTokenSource(
 			&oauth2.Token{AccessToken: cache.token},
 		)
-		client = github.NewClient(oauth2.NewClient(cache.ctx, tokenSource))
+		ctx := context.WithValue(cache.ctx, oauth2.HTTPClient,
+			httpClient())
+		client = github.NewClient(oauth2.NewClient(ctx, tokenSource))
 	}()
 	if cache.token != "" {
 		return
 	}
+	// Prefer the credential git already has for the repository, ie from a
+	// helper set up by "gh auth", unless the user turned it down.
+	if !cache.triedGit {
+		cache.triedGit = true
+		if _, password, ok := utils.GitCredential(cache.upstream.url); ok {
+			cache.token, cache.fromGit = password, true
+			return
+		}
+	}
+	cache.fromGit = false
+	if utils.NonInteractive {
+		utils.FatalPrintln("No GitHub token was found, and signing in can't be done in non-interactive mode.\n" +
+			"Set AIT_GIT_TOKEN, save a token in the config or set up a git credential helper.")
+	}
 	if cache.clientID == "" {
 		utils.FatalPrintln("Need a client ID in the environment if no token is provided!")
 	}
@@ -151,8 +168,46 @@ This code will expire in about %v minutes at %v.
 `, code, int(minutes), expireTime.Format("3:04 PM"))
 }
 
-// SaveToken saves the user's PAT to the global config and writes the file.
+// UsingGitCredential returns true if the token came from git's credential
+// helpers, which already store it.
+func UsingGitCredential() bool {
+	return cache.fromGit
+}
+
+// UsingEnvToken returns whether the token in use was given by AIT_GIT_TOKEN,
+// so it isn't offered to be saved.
+func UsingEnvToken() bool {
+	return cache.fromEnv && cache.token != ""
+}
+
+// SaveToken saves the user's PAT in the system keychain, or in the config
+// file if the keychain is unavailable.
 func SaveToken() {
-	config.Global.Git.PAT = cache.token
-	config.GenConf(config.Global)
+	config.SaveGitHubToken(cache.token)
+}
+
+// Login authenticates the user with GitHub through the device flow without a
+// repository, ie while setting up, and returns their GitHub login. The token
+// is available from Token afterwards.
+func Login() (string, error) {
+	cache = Info{
+		clientID: clientID,
+		shas:     make(map[string]string),
+		ctx:      context.Background(),
+		// There's no repository to ask git's credential helpers about.
+		triedGit: true,
+	}
+	client = github.NewClient(httpClient())
+	collectToken()
+	user, _, err := client.Users.Get(cache.ctx, "")
+	if err != nil {
+		return "", err
+	}
+	cache.user = user
+	return user.GetLogin(), nil
+}
+
+// Token returns the access token the user authenticated with.
+func Token() string {
+	return cache.token
 }
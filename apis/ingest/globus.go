@@ -0,0 +1,49 @@
+package ingest
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/arken/ait/config"
+)
+
+// globus fetches directories from a Globus collection with the Globus CLI,
+// which must be installed and logged in, into the local Globus Connect
+// Personal endpoint named in the config.
+type globus struct {
+	endpoint string
+	path     string
+}
+
+func (s *globus) Name() string {
+	return lastElement(s.path)
+}
+
+// Fetch submits a checksum verified transfer to dest and waits for it.
+func (s *globus) Fetch(dest string) error {
+	local := config.Global.Ingest.GlobusEndpoint
+	if local == "" {
+		return errors.New("set Ingest.GlobusEndpoint to the ID of this machine's Globus Connect Personal endpoint")
+	}
+	dest, err := filepath.Abs(dest)
+	if err != nil {
+		return err
+	}
+	out, err := output("globus", "transfer", "--recursive", "--sync-level", "checksum",
+		"--verify-checksum", "--label", "ait ingest "+s.Name(), "--jmespath", "task_id",
+		"--format", "unix", s.endpoint+":"+s.path, local+":"+filepath.ToSlash(dest))
+	if err != nil {
+		return fmt.Errorf("unable to start the Globus transfer: %v", err)
+	}
+	task := strings.TrimSpace(string(out))
+	if task == "" {
+		return errors.New("globus didn't report a transfer task")
+	}
+	fmt.Printf("Waiting for Globus transfer %v to finish...\n", task)
+	if err = run("globus", "task", "wait", "--polling-interval", "10", task); err != nil {
+		return fmt.Errorf("Globus transfer %v didn't succeed: %v", task, err)
+	}
+	return nil
+}
@@ -0,0 +1,58 @@
+package ingest
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// Source is a remote collection of research data that can be fetched into
+// the workspace.
+type Source interface {
+	// Fetch downloads the collection into the local directory dest.
+	Fetch(dest string) error
+	// Name is the name of the collection, used as its default directory.
+	Name() string
+}
+
+// Parse returns the source at location: "irods:/<zone>/<collection>" or
+// "globus://<endpoint ID>/<path>".
+func Parse(location string) (Source, error) {
+	switch {
+	case strings.HasPrefix(location, "irods:"):
+		collection := "/" + strings.TrimLeft(strings.TrimPrefix(location, "irods:"), "/")
+		if collection == "/" {
+			return nil, fmt.Errorf("no iRODS collection in %q", location)
+		}
+		return &irods{collection: collection}, nil
+	case strings.HasPrefix(location, "globus://"):
+		parts := strings.SplitN(strings.TrimPrefix(location, "globus://"), "/", 2)
+		if parts[0] == "" || len(parts) < 2 || parts[1] == "" {
+			return nil, fmt.Errorf("expected globus://<endpoint ID>/<path>, not %q", location)
+		}
+		return &globus{endpoint: parts[0], path: "/" + strings.TrimSuffix(parts[1], "/")}, nil
+	default:
+		return nil, fmt.Errorf("unsupported source %q, expected irods:/... or globus://...", location)
+	}
+}
+
+// run runs a command with its output shown to the user.
+var run = func(name string, args ...string) error {
+	cmd := exec.Command(name, args...)
+	cmd.Stdout, cmd.Stderr = os.Stdout, os.Stderr
+	return cmd.Run()
+}
+
+// output runs a command and returns what it printed.
+var output = func(name string, args ...string) ([]byte, error) {
+	cmd := exec.Command(name, args...)
+	cmd.Stderr = os.Stderr
+	return cmd.Output()
+}
+
+// lastElement returns the last element of a slash separated path.
+func lastElement(path string) string {
+	path = strings.TrimSuffix(path, "/")
+	return path[strings.LastIndex(path, "/")+1:]
+}
@@ -0,0 +1,50 @@
+package ingest
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/arken/ait/config"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParse(t *testing.T) {
+	src, err := Parse("irods:/tempZone/home/lab/run-42/")
+	assert.NoError(t, err)
+	assert.Equal(t, &irods{collection: "/tempZone/home/lab/run-42/"}, src)
+	assert.Equal(t, "run-42", src.Name())
+
+	src, err = Parse("globus://ddb59aef-6d04-11e5-ba46-22000b92c6ec/share/godata/")
+	assert.NoError(t, err)
+	assert.Equal(t, &globus{endpoint: "ddb59aef-6d04-11e5-ba46-22000b92c6ec", path: "/share/godata"}, src)
+	assert.Equal(t, "godata", src.Name())
+
+	for _, bad := range []string{"irods:/", "globus://endpoint", "globus:///path", "s3://bucket/key"} {
+		_, err = Parse(bad)
+		assert.Error(t, err, bad)
+	}
+}
+
+func TestGlobusFetch(t *testing.T) {
+	var calls [][]string
+	output = func(name string, args ...string) ([]byte, error) {
+		calls = append(calls, append([]string{name}, args...))
+		return []byte("task-1\n"), nil
+	}
+	run = func(name string, args ...string) error {
+		calls = append(calls, append([]string{name}, args...))
+		return nil
+	}
+	src := &globus{endpoint: "remote", path: "/share/godata"}
+	config.Global.Ingest.GlobusEndpoint = ""
+	assert.Error(t, src.Fetch("godata"))
+
+	config.Global.Ingest.GlobusEndpoint = "local"
+	assert.NoError(t, src.Fetch("godata"))
+	abs, _ := filepath.Abs("godata")
+	assert.Len(t, calls, 2)
+	assert.Contains(t, calls[0], "remote:/share/godata")
+	assert.Contains(t, calls[0], "local:"+filepath.ToSlash(abs))
+	assert.Equal(t, []string{"globus", "task", "wait", "--polling-interval", "10", "task-1"}, calls[1])
+}
@@ -0,0 +1,21 @@
+package ingest
+
+import "fmt"
+
+// irods fetches collections with the iRODS icommands, which must be installed
+// and authenticated with iinit.
+type irods struct {
+	collection string
+}
+
+func (s *irods) Name() string {
+	return lastElement(s.collection)
+}
+
+// Fetch downloads the collection recursively, verifying checksums.
+func (s *irods) Fetch(dest string) error {
+	if err := run("iget", "-r", "-K", "-f", s.collection, dest); err != nil {
+		return fmt.Errorf("iget %v failed: %v", s.collection, err)
+	}
+	return nil
+}
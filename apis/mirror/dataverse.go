@@ -0,0 +1,95 @@
+package mirror
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"path"
+)
+
+// dataverseDataset is the part of a Dataverse dataset AIT reads.
+type dataverseDataset struct {
+	Data struct {
+		PersistentURL string `json:"persistentUrl"`
+		LatestVersion struct {
+			License interface{} `json:"license"`
+			Files   []struct {
+				Directory string `json:"directoryLabel"`
+				DataFile  struct {
+					ID       int64  `json:"id"`
+					Name     string `json:"filename"`
+					Size     int64  `json:"filesize"`
+					MD5      string `json:"md5"`
+					Original string `json:"originalFileName"`
+				} `json:"dataFile"`
+			} `json:"files"`
+			MetadataBlocks struct {
+				Citation struct {
+					Fields []struct {
+						Type  string          `json:"typeName"`
+						Value json.RawMessage `json:"value"`
+					} `json:"fields"`
+				} `json:"citation"`
+			} `json:"metadataBlocks"`
+		} `json:"latestVersion"`
+	} `json:"data"`
+}
+
+// resolveDataverse fetches the latest version of a dataset from the Dataverse
+// installation at server.
+func resolveDataverse(server, doi string) (*Dataset, error) {
+	var resp dataverseDataset
+	err := getJSON(server+"/api/datasets/:persistentId/?persistentId="+url.QueryEscape("doi:"+doi), &resp)
+	if err != nil {
+		return nil, fmt.Errorf("unable to fetch %v from %v: %v", doi, server, err)
+	}
+	version := resp.Data.LatestVersion
+	dataset := &Dataset{
+		Identifier:  doi,
+		Repository:  "Dataverse",
+		LandingPage: resp.Data.PersistentURL,
+	}
+	switch license := version.License.(type) {
+	case string:
+		dataset.License = license
+	case map[string]interface{}:
+		dataset.License, _ = license["name"].(string)
+	}
+	for _, field := range version.MetadataBlocks.Citation.Fields {
+		switch field.Type {
+		case "title":
+			_ = json.Unmarshal(field.Value, &dataset.Title)
+		case "author":
+			var authors []struct {
+				Name struct {
+					Value string `json:"value"`
+				} `json:"authorName"`
+			}
+			_ = json.Unmarshal(field.Value, &authors)
+			for _, author := range authors {
+				dataset.Authors = append(dataset.Authors, author.Name.Value)
+			}
+		}
+	}
+	for _, file := range version.Files {
+		download := fmt.Sprintf("%v/api/access/datafile/%d", server, file.DataFile.ID)
+		name := file.DataFile.Name
+		// Tabular files are converted when they're uploaded, the published
+		// checksum is of the original.
+		if file.DataFile.Original != "" {
+			name = file.DataFile.Original
+			download += "?format=original"
+		}
+		name, err = cleanName(path.Join(file.Directory, name))
+		if err != nil {
+			return nil, err
+		}
+		dataset.Files = append(dataset.Files, File{
+			Name: name,
+			URL:  download,
+			Size: file.DataFile.Size,
+			MD5:  file.DataFile.MD5,
+		})
+	}
+	return dataset, nil
+}
@@ -0,0 +1,50 @@
+package mirror
+
+import "fmt"
+
+// figshareAPI serves figshare.com and the institutional figshare portals.
+var figshareAPI = "https://api.figshare.com/v2"
+
+// figshareArticle is the part of a figshare article AIT reads.
+type figshareArticle struct {
+	Title   string `json:"title"`
+	URL     string `json:"url_public_html"`
+	Authors []struct {
+		Name string `json:"full_name"`
+	} `json:"authors"`
+	License struct {
+		Name string `json:"name"`
+	} `json:"license"`
+	Files []struct {
+		Name string `json:"name"`
+		Size int64  `json:"size"`
+		URL  string `json:"download_url"`
+		MD5  string `json:"computed_md5"`
+	} `json:"files"`
+}
+
+// resolveFigshare fetches the public figshare article with the given ID.
+func resolveFigshare(id, doi string) (*Dataset, error) {
+	var article figshareArticle
+	if err := getJSON(fmt.Sprintf("%v/articles/%v", figshareAPI, id), &article); err != nil {
+		return nil, fmt.Errorf("unable to fetch figshare article %v: %v", id, err)
+	}
+	dataset := &Dataset{
+		Identifier:  doi,
+		Repository:  "figshare",
+		Title:       article.Title,
+		License:     article.License.Name,
+		LandingPage: article.URL,
+	}
+	for _, author := range article.Authors {
+		dataset.Authors = append(dataset.Authors, author.Name)
+	}
+	for _, file := range article.Files {
+		name, err := cleanName(file.Name)
+		if err != nil {
+			return nil, err
+		}
+		dataset.Files = append(dataset.Files, File{Name: name, URL: file.URL, Size: file.Size, MD5: file.MD5})
+	}
+	return dataset, nil
+}
@@ -0,0 +1,85 @@
+package mirror
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+
+	"github.com/arken/ait/config"
+	"github.com/arken/ait/utils"
+)
+
+// Validators identify the version of a file served over HTTP.
+type Validators struct {
+	ETag         string
+	LastModified string
+}
+
+// FetchChanged downloads url to dest unless the server reports it hasn't
+// changed since the version identified by v, or what it sends is the same as
+// dest. It returns the validators of the version now at dest and whether dest
+// changed.
+func FetchChanged(url, dest string, v Validators) (Validators, bool, error) {
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return v, false, err
+	}
+	if _, err := os.Stat(dest); err == nil {
+		if v.ETag != "" {
+			req.Header.Set("If-None-Match", v.ETag)
+		}
+		if v.LastModified != "" {
+			req.Header.Set("If-Modified-Since", v.LastModified)
+		}
+	}
+	resp, err := utils.PinnedClient(config.Global.Trust.Hosts).Do(req)
+	if err != nil {
+		return v, false, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotModified {
+		return v, false, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return v, false, fmt.Errorf("%v returned %v", url, resp.Status)
+	}
+	latest := Validators{ETag: resp.Header.Get("ETag"), LastModified: resp.Header.Get("Last-Modified")}
+
+	// The new version is downloaded beside dest so a failed download doesn't
+	// replace it.
+	part := dest + ".part"
+	out, err := os.Create(part)
+	if err != nil {
+		return v, false, err
+	}
+	sum := sha256.New()
+	_, err = io.Copy(io.MultiWriter(out, sum), resp.Body)
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(part)
+		return v, false, fmt.Errorf("unable to download %v: %v", url, err)
+	}
+	if old, err := fileSum(dest); err == nil && bytes.Equal(old, sum.Sum(nil)) {
+		return latest, false, os.Remove(part)
+	}
+	return latest, true, os.Rename(part, dest)
+}
+
+// fileSum returns the SHA-256 checksum of the file at path.
+func fileSum(path string) ([]byte, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	sum := sha256.New()
+	if _, err = io.Copy(sum, file); err != nil {
+		return nil, err
+	}
+	return sum.Sum(nil), nil
+}
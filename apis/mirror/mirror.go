@@ -0,0 +1,182 @@
+package mirror
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/arken/ait/config"
+	"github.com/arken/ait/utils"
+)
+
+// doiAPI looks up where DOIs point without following the redirects.
+var doiAPI = "https://doi.org/api/handles"
+
+var (
+	geoAccession    = regexp.MustCompile(`^GSE\d+$`)
+	genomeAccession = regexp.MustCompile(`^GC[AF]_\d+(\.\d+)?$`)
+	figshareDOI     = regexp.MustCompile(`(?i)figshare\.(\d+)`)
+	trailingNumber  = regexp.MustCompile(`/(\d+)(/\d+)?/?$`)
+)
+
+// Dataset is a published dataset and the files it's made of.
+type Dataset struct {
+	// Identifier is the DOI or accession the dataset was resolved from.
+	Identifier  string
+	Repository  string
+	Title       string
+	Authors     []string
+	License     string
+	LandingPage string
+	Files       []File
+}
+
+// File is a file of a dataset.
+type File struct {
+	// Name is the slash separated path of the file within the dataset.
+	Name string
+	URL  string
+	// Size and MD5 are left empty when the repository doesn't publish them.
+	Size int64
+	MD5  string
+}
+
+// Resolve finds the dataset with the given identifier: a figshare or Dataverse
+// DOI, a GEO series accession (GSE...) or a genome assembly accession (GCF_...
+// or GCA_...).
+func Resolve(id string) (*Dataset, error) {
+	id = strings.TrimSpace(id)
+	switch {
+	case geoAccession.MatchString(strings.ToUpper(id)):
+		return resolveGEO(strings.ToUpper(id))
+	case genomeAccession.MatchString(strings.ToUpper(id)):
+		return resolveGenome(strings.ToUpper(id))
+	}
+	doi := id
+	for _, prefix := range []string{"https://doi.org/", "http://doi.org/", "https://dx.doi.org/", "doi:"} {
+		if strings.HasPrefix(strings.ToLower(doi), prefix) {
+			doi = doi[len(prefix):]
+		}
+	}
+	if !strings.HasPrefix(doi, "10.") {
+		return nil, fmt.Errorf("%q isn't a DOI or a supported NCBI accession", id)
+	}
+	if match := figshareDOI.FindStringSubmatch(doi); match != nil {
+		return resolveFigshare(match[1], doi)
+	}
+	landing, err := lookupDOI(doi)
+	if err != nil {
+		return nil, err
+	}
+	u, err := url.Parse(landing)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case u.Query().Get("persistentId") != "":
+		return resolveDataverse(u.Scheme+"://"+u.Host, doi)
+	case strings.Contains(u.Host, "figshare"):
+		match := trailingNumber.FindStringSubmatch(u.Path)
+		if match == nil {
+			return nil, fmt.Errorf("unable to find the figshare article of %v", landing)
+		}
+		return resolveFigshare(match[1], doi)
+	default:
+		return nil, fmt.Errorf("%v is hosted at %v, which isn't a supported repository", doi, u.Host)
+	}
+}
+
+// lookupDOI returns the URL the DOI resolves to.
+func lookupDOI(doi string) (string, error) {
+	var handle struct {
+		Values []struct {
+			Type string `json:"type"`
+			Data struct {
+				Value interface{} `json:"value"`
+			} `json:"data"`
+		} `json:"values"`
+	}
+	if err := getJSON(doiAPI+"/"+doi, &handle); err != nil {
+		return "", fmt.Errorf("unable to resolve %v: %v", doi, err)
+	}
+	for _, value := range handle.Values {
+		if landing, ok := value.Data.Value.(string); ok && value.Type == "URL" {
+			return landing, nil
+		}
+	}
+	return "", fmt.Errorf("%v doesn't point to a URL", doi)
+}
+
+// Download fetches the file to dest, checking its MD5 if the repository
+// published one.
+func Download(file File, dest string) error {
+	resp, err := get(file.URL)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	out, err := os.Create(dest)
+	if err != nil {
+		return err
+	}
+	sum := md5.New()
+	_, err = io.Copy(io.MultiWriter(out, sum), resp.Body)
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("unable to download %v: %v", file.Name, err)
+	}
+	if actual := hex.EncodeToString(sum.Sum(nil)); file.MD5 != "" && !strings.EqualFold(actual, file.MD5) {
+		return fmt.Errorf("%v is corrupt, its MD5 is %v but %v was published", file.Name, actual, file.MD5)
+	}
+	return nil
+}
+
+// cleanName returns the slash separated path name, which must stay within the
+// dataset.
+func cleanName(name string) (string, error) {
+	slashed := strings.ReplaceAll(name, "\\", "/")
+	clean := path.Clean("/" + slashed)[1:]
+	for _, part := range strings.Split(slashed, "/") {
+		if part == ".." {
+			clean = ""
+		}
+	}
+	if clean == "" {
+		return "", fmt.Errorf("refusing to download a file named %q", name)
+	}
+	return clean, nil
+}
+
+// get requests url, failing on anything but a 200 response.
+func get(url string) (*http.Response, error) {
+	client := utils.PinnedClient(config.Global.Trust.Hosts)
+	resp, err := client.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("%v returned %v", url, resp.Status)
+	}
+	return resp, nil
+}
+
+// getJSON decodes the JSON response from url into out.
+func getJSON(url string, out interface{}) error {
+	resp, err := get(url)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	return json.NewDecoder(resp.Body).Decode(out)
+}
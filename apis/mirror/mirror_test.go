@@ -0,0 +1,165 @@
+package mirror
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestResolveFigshare(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/articles/1234567", r.URL.Path)
+		w.Write([]byte(`{"title":"Tide gauges","url_public_html":"https://figshare.com/articles/1234567",
+			"authors":[{"full_name":"Ada Lovelace"}],"license":{"name":"CC BY 4.0"},
+			"files":[{"name":"gauges.csv","size":42,"download_url":"https://ndownloader.figshare.com/files/1",
+			"computed_md5":"abc"}]}`))
+	}))
+	defer server.Close()
+	figshareAPI = server.URL
+
+	dataset, err := Resolve("https://doi.org/10.6084/m9.figshare.1234567.v2")
+	assert.NoError(t, err)
+	assert.Equal(t, "figshare", dataset.Repository)
+	assert.Equal(t, "10.6084/m9.figshare.1234567.v2", dataset.Identifier)
+	assert.Equal(t, "Tide gauges", dataset.Title)
+	assert.Equal(t, []string{"Ada Lovelace"}, dataset.Authors)
+	assert.Equal(t, "CC BY 4.0", dataset.License)
+	assert.Equal(t, []File{{Name: "gauges.csv", URL: "https://ndownloader.figshare.com/files/1", Size: 42, MD5: "abc"}},
+		dataset.Files)
+}
+
+func TestResolveDataverse(t *testing.T) {
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/handles/10.7910/DVN/ABC123":
+			w.Write([]byte(`{"values":[{"type":"URL","data":{"value":"` + server.URL +
+				`/citation?persistentId=doi:10.7910/DVN/ABC123"}}]}`))
+		case "/api/datasets/:persistentId/":
+			assert.Equal(t, "doi:10.7910/DVN/ABC123", r.URL.Query().Get("persistentId"))
+			w.Write([]byte(`{"data":{"persistentUrl":"https://doi.org/10.7910/DVN/ABC123","latestVersion":{
+				"license":{"name":"CC0 1.0"},
+				"files":[{"directoryLabel":"raw","dataFile":{"id":7,"filename":"survey.tab","filesize":10,
+					"md5":"def","originalFileName":"survey.csv"}},
+					{"dataFile":{"id":8,"filename":"README.txt","filesize":5,"md5":"123"}}],
+				"metadataBlocks":{"citation":{"fields":[{"typeName":"title","value":"Survey"},
+					{"typeName":"author","value":[{"authorName":{"value":"Lovelace, Ada"}}]}]}}}}}`))
+		default:
+			t.Errorf("unexpected request for %v", r.URL)
+		}
+	}))
+	defer server.Close()
+	doiAPI = server.URL + "/api/handles"
+
+	dataset, err := Resolve("doi:10.7910/DVN/ABC123")
+	assert.NoError(t, err)
+	assert.Equal(t, "Dataverse", dataset.Repository)
+	assert.Equal(t, "Survey", dataset.Title)
+	assert.Equal(t, []string{"Lovelace, Ada"}, dataset.Authors)
+	assert.Equal(t, "CC0 1.0", dataset.License)
+	assert.Equal(t, []File{
+		{Name: "raw/survey.csv", URL: server.URL + "/api/access/datafile/7?format=original", Size: 10, MD5: "def"},
+		{Name: "README.txt", URL: server.URL + "/api/access/datafile/8", Size: 5, MD5: "123"},
+	}, dataset.Files)
+}
+
+func TestResolveGEO(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/GSE12nnn/GSE12345/suppl/":
+			w.Write([]byte(`<a href="/geo/series/GSE12nnn/GSE12345/">Parent Directory</a>
+<a href="?C=N;O=D">Name</a>
+<a href="GSE12345_RAW.tar">GSE12345_RAW.tar</a>
+<a href="filelist.txt">filelist.txt</a>`))
+		case "/GSE12nnn/GSE12345/matrix/":
+			w.Write([]byte(`<a href="GSE12345_series_matrix.txt.gz">GSE12345_series_matrix.txt.gz</a>`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+	geoFTP = server.URL
+
+	dataset, err := Resolve("gse12345")
+	assert.NoError(t, err)
+	assert.Equal(t, "GSE12345", dataset.Identifier)
+	var names []string
+	for _, file := range dataset.Files {
+		names = append(names, file.Name)
+	}
+	assert.Equal(t, []string{"matrix/GSE12345_series_matrix.txt.gz", "suppl/GSE12345_RAW.tar", "suppl/filelist.txt"},
+		names)
+	assert.Equal(t, server.URL+"/GSE12nnn/GSE12345/suppl/GSE12345_RAW.tar", dataset.Files[1].URL)
+
+	_, err = Resolve("SRR000001")
+	assert.Error(t, err)
+}
+
+func TestDownload(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("hello\n"))
+	}))
+	defer server.Close()
+	dest := filepath.Join(t.TempDir(), "hello.txt")
+
+	assert.NoError(t, Download(File{Name: "hello.txt", URL: server.URL, MD5: "b1946ac92492d2347c6235b4d2611184"}, dest))
+	data, err := ioutil.ReadFile(dest)
+	assert.NoError(t, err)
+	assert.Equal(t, "hello\n", string(data))
+	assert.Error(t, Download(File{Name: "hello.txt", URL: server.URL, MD5: "00"}, dest))
+}
+
+func TestCleanName(t *testing.T) {
+	name, err := cleanName("/raw//a.csv")
+	assert.NoError(t, err)
+	assert.Equal(t, "raw/a.csv", name)
+	for _, bad := range []string{"", "../a.csv", "raw/../../a.csv", "..\\a.csv"} {
+		_, err = cleanName(bad)
+		assert.Error(t, err, bad)
+	}
+}
+
+func TestFetchChanged(t *testing.T) {
+	body := "v1"
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		etag := `"` + body + `"`
+		if r.Header.Get("If-None-Match") == etag {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", etag)
+		w.Write([]byte(body))
+	}))
+	defer server.Close()
+	dest := filepath.Join(t.TempDir(), "data.csv")
+
+	v, changed, err := FetchChanged(server.URL, dest, Validators{})
+	assert.NoError(t, err)
+	assert.True(t, changed)
+	assert.Equal(t, `"v1"`, v.ETag)
+
+	v, changed, err = FetchChanged(server.URL, dest, v)
+	assert.NoError(t, err)
+	assert.False(t, changed)
+
+	// Servers that ignore the validators don't cause a change either.
+	v, changed, err = FetchChanged(server.URL, dest, Validators{ETag: `"stale"`})
+	assert.NoError(t, err)
+	assert.False(t, changed)
+	assert.Equal(t, `"v1"`, v.ETag)
+
+	body = "v2"
+	v, changed, err = FetchChanged(server.URL, dest, v)
+	assert.NoError(t, err)
+	assert.True(t, changed)
+	assert.Equal(t, `"v2"`, v.ETag)
+	data, _ := ioutil.ReadFile(dest)
+	assert.Equal(t, "v2", string(data))
+	_, err = os.Stat(dest + ".part")
+	assert.True(t, os.IsNotExist(err))
+}
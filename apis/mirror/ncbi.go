@@ -0,0 +1,97 @@
+package mirror
+
+import (
+	"fmt"
+	"io/ioutil"
+	"regexp"
+	"strings"
+)
+
+var (
+	// geoFTP is NCBI's FTP site for GEO, which is also served over HTTPS.
+	geoFTP = "https://ftp.ncbi.nlm.nih.gov/geo/series"
+	// ncbiDatasetsAPI is the NCBI Datasets API, which packages genome
+	// assemblies.
+	ncbiDatasetsAPI = "https://api.ncbi.nlm.nih.gov/datasets/v2"
+
+	listingLink = regexp.MustCompile(`<a href="([^"?/][^"]*)">`)
+)
+
+// geoDirs are the directories of a GEO series that are mirrored.
+var geoDirs = []string{"matrix", "soft", "miniml", "suppl"}
+
+// resolveGEO lists the files of a GEO series.
+func resolveGEO(acc string) (*Dataset, error) {
+	// Series are grouped by their accession with the last three digits
+	// replaced, ie GSE12345 is in GSE12nnn.
+	group := acc[:len(acc)-3] + "nnn"
+	if len(acc) < 6 {
+		group = "GSEnnn"
+	}
+	dataset := &Dataset{
+		Identifier:  acc,
+		Repository:  "NCBI GEO",
+		Title:       "GEO series " + acc,
+		LandingPage: "https://www.ncbi.nlm.nih.gov/geo/query/acc.cgi?acc=" + acc,
+	}
+	for _, dir := range geoDirs {
+		base := fmt.Sprintf("%v/%v/%v/%v/", geoFTP, group, acc, dir)
+		resp, err := get(base)
+		if err != nil {
+			// Not every series has every directory.
+			continue
+		}
+		listing, err := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		for _, match := range listingLink.FindAllStringSubmatch(string(listing), -1) {
+			if strings.HasSuffix(match[1], "/") {
+				continue
+			}
+			name, err := cleanName(dir + "/" + match[1])
+			if err != nil {
+				return nil, err
+			}
+			dataset.Files = append(dataset.Files, File{Name: name, URL: base + match[1]})
+		}
+	}
+	if len(dataset.Files) == 0 {
+		return nil, fmt.Errorf("no files were found for %v", acc)
+	}
+	return dataset, nil
+}
+
+// resolveGenome describes the NCBI Datasets package of a genome assembly.
+func resolveGenome(acc string) (*Dataset, error) {
+	var report struct {
+		Reports []struct {
+			Info struct {
+				Name string `json:"assembly_name"`
+			} `json:"assembly_info"`
+			Organism struct {
+				Name string `json:"organism_name"`
+			} `json:"organism"`
+		} `json:"reports"`
+	}
+	err := getJSON(fmt.Sprintf("%v/genome/accession/%v/dataset_report", ncbiDatasetsAPI, acc), &report)
+	if err != nil {
+		return nil, fmt.Errorf("unable to fetch the report of %v: %v", acc, err)
+	}
+	if len(report.Reports) == 0 {
+		return nil, fmt.Errorf("no genome assembly %v was found", acc)
+	}
+	info := report.Reports[0]
+	return &Dataset{
+		Identifier:  acc,
+		Repository:  "NCBI Datasets",
+		Title:       fmt.Sprintf("%v genome assembly %v", info.Organism.Name, info.Info.Name),
+		LandingPage: "https://www.ncbi.nlm.nih.gov/datasets/genome/" + acc,
+		Files: []File{{
+			Name: acc + ".zip",
+			URL: fmt.Sprintf("%v/genome/accession/%v/download?include_annotation_type="+
+				"GENOME_FASTA,GENOME_GFF,RNA_FASTA,PROT_FASTA", ncbiDatasetsAPI, acc),
+		}},
+	}, nil
+}
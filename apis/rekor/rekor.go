@@ -0,0 +1,104 @@
+package rekor
+
+import (
+	"bytes"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/arken/ait/utils"
+
+	crypto "github.com/libp2p/go-libp2p-core/crypto"
+)
+
+// Entry identifies a record appended to a Rekor transparency log.
+type Entry struct {
+	UUID     string `json:"uuid"`
+	LogIndex int64  `json:"logIndex"`
+	URL      string `json:"url"`
+}
+
+// rekordRequest is the body of a request to add a "rekord" entry, which
+// stores a signed artifact along with the key that signed it.
+type rekordRequest struct {
+	APIVersion string     `json:"apiVersion"`
+	Kind       string     `json:"kind"`
+	Spec       rekordSpec `json:"spec"`
+}
+
+type rekordSpec struct {
+	Data struct {
+		Content []byte `json:"content"`
+	} `json:"data"`
+	Signature struct {
+		Format    string `json:"format"`
+		Content   []byte `json:"content"`
+		PublicKey struct {
+			Content []byte `json:"content"`
+		} `json:"publicKey"`
+	} `json:"signature"`
+}
+
+// Upload appends data and its signature to the Rekor log at server. pubKey is
+// a libp2p marshaled public key, as returned by ipfs.Sign.
+func Upload(server string, data, sig, pubKey []byte) (entry Entry, err error) {
+	keyPEM, err := publicKeyPEM(pubKey)
+	if err != nil {
+		return entry, err
+	}
+	req := rekordRequest{APIVersion: "0.0.1", Kind: "rekord"}
+	req.Spec.Data.Content = data
+	req.Spec.Signature.Format = "x509"
+	req.Spec.Signature.Content = sig
+	req.Spec.Signature.PublicKey.Content = keyPEM
+	body, err := json.Marshal(req)
+	if err != nil {
+		return entry, err
+	}
+	server = strings.TrimSuffix(server, "/")
+	client := utils.NewClient(20 * time.Second)
+	resp, err := client.Post(server+"/api/v1/log/entries", "application/json", bytes.NewReader(body))
+	if err != nil {
+		return entry, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusCreated {
+		return entry, fmt.Errorf("transparency log rejected the receipt: %v", resp.Status)
+	}
+	result := make(map[string]struct {
+		LogIndex int64 `json:"logIndex"`
+	})
+	if err = json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return entry, err
+	}
+	for uuid, e := range result {
+		return Entry{
+			UUID:     uuid,
+			LogIndex: e.LogIndex,
+			URL:      server + "/api/v1/log/entries/" + uuid,
+		}, nil
+	}
+	return entry, fmt.Errorf("transparency log returned no entry")
+}
+
+// publicKeyPEM converts a libp2p public key to the PEM encoded PKIX form
+// Rekor expects.
+func publicKeyPEM(pubKey []byte) ([]byte, error) {
+	pub, err := crypto.UnmarshalPublicKey(pubKey)
+	if err != nil {
+		return nil, err
+	}
+	std, err := crypto.PubKeyToStdKey(pub)
+	if err != nil {
+		return nil, err
+	}
+	der, err := x509.MarshalPKIXPublicKey(std)
+	if err != nil {
+		return nil, err
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}), nil
+}
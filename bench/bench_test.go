@@ -0,0 +1,167 @@
+package bench
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/arken/ait/config"
+	"github.com/arken/ait/ipfs"
+	"github.com/arken/ait/keysets"
+	"github.com/arken/ait/utils"
+)
+
+func TestGenerateIsReproducible(t *testing.T) {
+	spec := Spec{Seed: 7, Files: 50, MinSize: 0, MaxSize: 4096, Dirs: 3, Duplicates: 0.2}
+	first, err := Generate(t.TempDir(), spec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := Generate(t.TempDir(), spec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(first.Paths, second.Paths) || first.Size != second.Size {
+		t.Fatal("the same spec generated different trees")
+	}
+	for _, path := range first.Paths {
+		a, _ := ioutil.ReadFile(filepath.Join(first.Root, path))
+		b, _ := ioutil.ReadFile(filepath.Join(second.Root, path))
+		if string(a) != string(b) {
+			t.Fatalf("the contents of %v differ", path)
+		}
+	}
+	if !reflect.DeepEqual(Keyset(7, 100, 0.2), Keyset(7, 100, 0.2)) {
+		t.Fatal("the same seed generated different keysets")
+	}
+}
+
+var nodeOnce sync.Once
+
+// startNode starts the IPFS subsystem once for every benchmark, with its
+// repository in a temporary directory.
+func startNode(b *testing.B) {
+	nodeOnce.Do(func() {
+		dir, err := ioutil.TempDir("", "ait-bench")
+		if err != nil {
+			b.Fatal(err)
+		}
+		if err = config.Load("", false); err != nil {
+			b.Fatal(err)
+		}
+		config.Global.IPFS.Path = filepath.Join(dir, "ipfs")
+		ipfs.Init(false)
+	})
+}
+
+// workspace generates the tree of spec in a temporary AIT workspace with all
+// of its files staged, and changes to it until the benchmark ends.
+func workspace(b *testing.B, spec Spec) *Tree {
+	b.Helper()
+	tree, err := Generate(b.TempDir(), spec)
+	if err != nil {
+		b.Fatal(err)
+	}
+	if err = os.MkdirAll(filepath.Join(tree.Root, ".ait"), os.ModePerm); err != nil {
+		b.Fatal(err)
+	}
+	staged := strings.Join(tree.Paths, "\n") + "\n"
+	if err = ioutil.WriteFile(filepath.Join(tree.Root, utils.AddedFilesPath), []byte(staged), 0644); err != nil {
+		b.Fatal(err)
+	}
+	wd, _ := os.Getwd()
+	if err = os.Chdir(tree.Root); err != nil {
+		b.Fatal(err)
+	}
+	b.Cleanup(func() { os.Chdir(wd) })
+	return tree
+}
+
+// benchmarkAdd adds every file of the tree to the embedded node through the
+// workspace link, as "ait upload" does.
+func benchmarkAdd(b *testing.B, spec Spec) {
+	startNode(b)
+	tree := workspace(b, spec)
+	link, err := ipfs.LinkWorkdir()
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.SetBytes(tree.Size)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for _, path := range tree.Paths {
+			if _, err = ipfs.Add(filepath.Join(link, path), false); err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+}
+
+func BenchmarkAddSmallFiles(b *testing.B) { benchmarkAdd(b, SmallFiles) }
+func BenchmarkAddLargeFiles(b *testing.B) { benchmarkAdd(b, LargeFiles) }
+func BenchmarkAddMixed(b *testing.B)      { benchmarkAdd(b, Mixed) }
+
+// benchmarkGenerate generates the keyset of the staged tree, hashing every
+// file as "ait submit" does.
+func benchmarkGenerate(b *testing.B, spec Spec) {
+	startNode(b)
+	tree := workspace(b, spec)
+	ksPath := filepath.Join(b.TempDir(), "bench.ks")
+	b.SetBytes(tree.Size)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := keysets.Generate(ksPath, true); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkGenerateSmallFiles(b *testing.B) { benchmarkGenerate(b, SmallFiles) }
+func BenchmarkGenerateLargeFiles(b *testing.B) { benchmarkGenerate(b, LargeFiles) }
+func BenchmarkGenerateMixed(b *testing.B)      { benchmarkGenerate(b, Mixed) }
+
+// BenchmarkDedupe merges a keyset into another sharing a fifth of its CIDs,
+// skipping the entries it already has.
+func BenchmarkDedupe(b *testing.B) {
+	dir := b.TempDir()
+	existing := Keyset(1, 100000, 0.05)
+	incoming := append(Keyset(2, 80000, 0.05), existing[:20000]...)
+	from, ksPath := filepath.Join(dir, "from.ks"), filepath.Join(dir, "dataset.ks")
+	if err := WriteKeyset(from, incoming); err != nil {
+		b.Fatal(err)
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		b.StopTimer()
+		if err := WriteKeyset(ksPath, existing); err != nil {
+			b.Fatal(err)
+		}
+		b.StartTimer()
+		if err := keysets.Merge(ksPath, from); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// BenchmarkSearch looks files up in a keyset repository holding a million
+// entries, as "ait pull" does.
+func BenchmarkSearch(b *testing.B) {
+	dir := b.TempDir()
+	for shard := 0; shard < 4; shard++ {
+		entries := Keyset(int64(shard), 250000, 0)
+		if err := WriteKeyset(filepath.Join(dir, fmt.Sprintf("part%d", shard), "dataset.ks"), entries); err != nil {
+			b.Fatal(err)
+		}
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := keysets.Search(dir, "dataset/file00012*.dat"); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
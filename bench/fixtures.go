@@ -0,0 +1,136 @@
+// Package bench generates reproducible synthetic datasets, so the benchmarks
+// of adding, deduplicating, generating and searching keysets measure the same
+// work from one release to the next.
+package bench
+
+import (
+	"fmt"
+	"io"
+	"math/rand"
+	"os"
+	"path/filepath"
+
+	"github.com/arken/ait/utils"
+)
+
+// Spec describes a synthetic tree of files.
+type Spec struct {
+	// Seed makes the tree reproducible: a spec always generates the same
+	// names and contents.
+	Seed  int64
+	Files int
+	// Sizes are drawn uniformly between MinSize and MaxSize bytes.
+	MinSize int64
+	MaxSize int64
+	// Dirs is how many directories the files are spread over.
+	Dirs int
+	// Duplicates is the fraction of files that are copies of an earlier one.
+	Duplicates float64
+}
+
+var (
+	// SmallFiles is many files of about a kilobyte.
+	SmallFiles = Spec{Seed: 1, Files: 10000, MinSize: 512, MaxSize: 2048, Dirs: 100}
+	// LargeFiles is a few files of tens of megabytes.
+	LargeFiles = Spec{Seed: 2, Files: 4, MinSize: 32 << 20, MaxSize: 64 << 20, Dirs: 1}
+	// Mixed is files of every size up to 4 MB, some of them duplicated.
+	Mixed = Spec{Seed: 3, Files: 1000, MinSize: 1, MaxSize: 4 << 20, Dirs: 20, Duplicates: 0.1}
+)
+
+// Tree is a generated tree of files.
+type Tree struct {
+	Root string
+	// Paths are relative to Root, in the order they were generated.
+	Paths []string
+	// Size is the total size of the files in bytes.
+	Size int64
+}
+
+// Generate writes the tree described by spec under root.
+func Generate(root string, spec Spec) (*Tree, error) {
+	rng := rand.New(rand.NewSource(spec.Seed))
+	tree := &Tree{Root: root}
+	// Each file's contents come from its own seed, so duplicates can be
+	// written again without keeping earlier files in memory.
+	seeds := make([]int64, 0, spec.Files)
+	sizes := make([]int64, 0, spec.Files)
+	dirs := spec.Dirs
+	if dirs < 1 {
+		dirs = 1
+	}
+	for i := 0; i < spec.Files; i++ {
+		seed, size := rng.Int63(), spec.MinSize
+		if spec.MaxSize > spec.MinSize {
+			size += rng.Int63n(spec.MaxSize - spec.MinSize + 1)
+		}
+		if i > 0 && rng.Float64() < spec.Duplicates {
+			original := rng.Intn(i)
+			seed, size = seeds[original], sizes[original]
+		}
+		seeds, sizes = append(seeds, seed), append(sizes, size)
+
+		path := filepath.Join(fmt.Sprintf("dir%04d", i%dirs), fmt.Sprintf("file%07d.dat", i))
+		if err := writeRandom(filepath.Join(root, path), seed, size); err != nil {
+			return nil, err
+		}
+		tree.Paths = append(tree.Paths, path)
+		tree.Size += size
+	}
+	return tree, nil
+}
+
+// writeRandom writes size bytes generated from seed to path.
+func writeRandom(path string, seed, size int64) error {
+	if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+		return err
+	}
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	_, err = io.CopyN(file, rand.New(rand.NewSource(seed)), size)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// cidAlphabet is the base32 alphabet of CIDv1 strings.
+const cidAlphabet = "abcdefghijklmnopqrstuvwxyz234567"
+
+// Keyset returns n synthetic keyset entries generated from seed, a fraction
+// of which repeat the CID of an earlier entry under another name.
+func Keyset(seed int64, n int, duplicates float64) []utils.KeysetEntry {
+	rng := rand.New(rand.NewSource(seed))
+	entries := make([]utils.KeysetEntry, 0, n)
+	for i := 0; i < n; i++ {
+		cid := make([]byte, 52)
+		for j := range cid {
+			cid[j] = cidAlphabet[rng.Intn(len(cidAlphabet))]
+		}
+		entry := utils.KeysetEntry{CID: "bafkrei" + string(cid), Name: fmt.Sprintf("file%07d.dat", i)}
+		if i > 0 && rng.Float64() < duplicates {
+			entry.CID = entries[rng.Intn(i)].CID
+		}
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+// WriteKeyset writes the entries to a keyset file at path.
+func WriteKeyset(path string, entries []utils.KeysetEntry) error {
+	if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+		return err
+	}
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		if _, err = fmt.Fprintf(file, "%v  %v\n", entry.CID, entry.Name); err != nil {
+			file.Close()
+			return err
+		}
+	}
+	return file.Close()
+}
@@ -0,0 +1,85 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/arken/ait/config"
+	"github.com/arken/ait/utils"
+
+	"github.com/DataDrake/cli-ng/v2/cmd"
+)
+
+// commandNames maps the name and alias of each registered command to its
+// name.
+var commandNames = map[string]string{"help": "help"}
+
+// registered are the registered commands, in the order they were registered.
+var registered []*cmd.Sub
+
+// commandAliases are the names commands can also be run by, beyond the alias
+// they're registered with.
+var commandAliases = map[string]string{"remove": "unstage", "rm": "unstage"}
+
+// valueFlags are the global flags that take the next arg as their value.
+var valueFlags = []string{"--profile", "--progress-socket", config.HomeFlag}
+
+// expandArgs applies the configured command aliases and default flags to the
+// command line, without the program name. An alias can't replace a built-in
+// command, and its expansion is not expanded again. "%date" is replaced by
+// today's date in both.
+func expandArgs(args []string) []string {
+	i := commandIndex(args)
+	if i < 0 {
+		return args
+	}
+	command := []string{args[i]}
+	if name, ok := commandAliases[args[i]]; ok {
+		command = []string{name}
+	} else if line, ok := config.Global.Aliases[args[i]]; ok {
+		if _, builtin := commandNames[args[i]]; builtin {
+			fmt.Fprintf(os.Stderr, "Ignoring the alias %q, it is the name of a command.\n", args[i])
+		} else if command = splitConfigured(line); len(command) == 0 {
+			utils.FatalPrintf("The alias %q has no command.\n", args[i])
+		}
+	}
+	if name, ok := commandNames[command[0]]; ok {
+		if line, ok := config.Global.Defaults[name]; ok {
+			defaults := splitConfigured(line)
+			command = append(command[:1], append(defaults, command[1:]...)...)
+		}
+	}
+	expanded := append([]string{}, args[:i]...)
+	expanded = append(expanded, command...)
+	return append(expanded, args[i+1:]...)
+}
+
+// commandIndex returns the index of the command in args, skipping the global
+// flags before it, or -1 if there is none.
+func commandIndex(args []string) int {
+	for i := 0; i < len(args); i++ {
+		if !strings.HasPrefix(args[i], "-") {
+			return i
+		}
+		if utils.IndexOf(valueFlags, args[i]) >= 0 {
+			i++
+		}
+	}
+	return -1
+}
+
+// splitConfigured splits an alias or default flags from the config into args.
+func splitConfigured(line string) []string {
+	args, err := utils.SplitArgs(expandDate(line))
+	if err != nil {
+		utils.FatalPrintf("Unable to read %q from the config: %v\n", line, err)
+	}
+	return args
+}
+
+// expandDate replaces "%date" in s by today's date.
+func expandDate(s string) string {
+	return strings.ReplaceAll(s, "%date", time.Now().Format("2006-01-02"))
+}
@@ -0,0 +1,214 @@
+package cli
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/arken/ait/config"
+	"github.com/arken/ait/display"
+	"github.com/arken/ait/ipfs"
+	"github.com/arken/ait/notify"
+	"github.com/arken/ait/utils"
+)
+
+// watchReplication checks the datasets submitted from the workspace every
+// AtRiskPeriod until ctx is canceled, and alerts the user when one that had
+// been safely replicated drops below the replication target. It is meant for
+// long running nodes.
+func watchReplication(ctx context.Context) {
+	period, err := time.ParseDuration(config.Global.Notify.AtRiskPeriod)
+	if err != nil || period <= 0 {
+		if config.Global.Notify.AtRiskPeriod != "" {
+			fmt.Printf("\n[Ignoring AtRiskPeriod setting %q: not a valid duration]\n",
+				config.Global.Notify.AtRiskPeriod)
+		}
+		return
+	}
+	ticker := time.NewTicker(period)
+	defer ticker.Stop()
+	for {
+		checkAtRisk()
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// checkAtRisk updates the replication state of each submission in the history
+// and alerts the user about the ones that regressed.
+func checkAtRisk() {
+	history, err := utils.ReadHistory()
+	if err != nil {
+		fmt.Printf("\n[Unable to read the submission history: %v]\n", err)
+		return
+	}
+	for _, s := range history {
+		if len(s.Entries) == 0 {
+			continue
+		}
+		_, replicated := replicationCounts(s)
+		s.RecordReplication(replicated)
+		if replicated == len(s.Entries) {
+			s.Safe, s.AtRisk = true, false
+		} else if s.Safe && !s.AtRisk {
+			s.AtRisk = true
+			alertAtRisk(s, replicated)
+		}
+		if releaseSubmission(s) {
+			fmt.Printf("\n[%v is merged and replicated, it is no longer protected from garbage collection.]\n", s.Path)
+		}
+		if err := s.Save(); err != nil {
+			fmt.Printf("\n[Unable to record the replication of %v: %v]\n", s.Path, err)
+		}
+	}
+}
+
+// alertAtRisk tells the user that a submission is no longer safely
+// replicated, on the console and through the configured channels.
+func alertAtRisk(s *utils.Submission, replicated int) {
+	title := "Dataset at risk: " + s.Path
+	message := fmt.Sprintf("Only %d of %d file(s) submitted to %v on %v are provided by at least "+
+		"%d peers. Consider seeding them from more machines.", replicated, len(s.Entries),
+		s.Remote, s.Time.Format("Jan 2 2006"), replicationTarget())
+	fmt.Printf("\n[%v. %v]\n", title, message)
+	if err := notify.Send(title, message); err != nil {
+		fmt.Printf("[Unable to send the alert: %v]\n", err)
+	}
+}
+
+// AtRiskFile is a file the at-risk report found under-replicated.
+type AtRiskFile struct {
+	CID       string   `json:"cid"`
+	Names     []string `json:"names"`
+	Source    string   `json:"source"`
+	Providers int      `json:"providers"`
+}
+
+// reportAtRisk looks the providers of every file of the keysets up, or of
+// every file the user submitted if none are given, and reports those provided
+// by fewer peers than ipfs.AtRiskThreshhold. The report is also written as CSV
+// to output if it's set.
+func reportAtRisk(args []string, output string) {
+	var cids, sources []string
+	names := make(map[string][]string)
+	seen := make(map[string]bool)
+	add := func(cid, name, source string) {
+		if !seen[cid] {
+			seen[cid] = true
+			cids, sources = append(cids, cid), append(sources, source)
+		}
+		for _, known := range names[cid] {
+			if known == name {
+				return
+			}
+		}
+		names[cid] = append(names[cid], name)
+	}
+	if len(args) == 0 {
+		registry, err := utils.ReadRegistry()
+		utils.CheckError(err)
+		for cid, entries := range registry {
+			for _, entry := range entries {
+				add(cid, entry.Name, entry.Remote)
+			}
+		}
+	} else {
+		for _, arg := range args {
+			entries, source := loadKeyset(arg)
+			for _, entry := range entries {
+				add(entry.CID, entry.Name, source)
+			}
+		}
+	}
+	if len(cids) == 0 {
+		utils.FatalPrintln("There are no files to check: nothing was submitted from this machine " +
+			"yet, give the keyset files or repositories to check instead.")
+	}
+
+	prettyIPFSInit()
+	threshold := ipfs.AtRiskThreshhold
+	fmt.Printf("Looking up the providers of %d file(s)...\n", len(cids))
+	bar := display.NewProgress("Looking up", int64(len(cids)), false)
+	providers := make([]int, len(cids))
+	jobs := make(chan int)
+	wg := sync.WaitGroup{}
+	for w := 0; w < genNumWorkers(); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				if count, err := ipfs.FindProvs(cids[i], threshold); err == nil {
+					providers[i] = count
+				}
+				bar.Add(1)
+			}
+		}()
+	}
+	for i := range cids {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	var atRisk []AtRiskFile
+	for i, cid := range cids {
+		if providers[i] < threshold {
+			atRisk = append(atRisk, AtRiskFile{CID: cid, Names: names[cid], Source: sources[i],
+				Providers: providers[i]})
+		}
+	}
+	sort.SliceStable(atRisk, func(i, j int) bool {
+		if atRisk[i].Providers != atRisk[j].Providers {
+			return atRisk[i].Providers < atRisk[j].Providers
+		}
+		if atRisk[i].Source != atRisk[j].Source {
+			return atRisk[i].Source < atRisk[j].Source
+		}
+		return atRisk[i].CID < atRisk[j].CID
+	})
+	if output != "" {
+		utils.CheckError(writeAtRiskCSV(output, atRisk))
+	}
+	display.Out.Result(atRisk, func(w io.Writer) {
+		if len(atRisk) == 0 {
+			fmt.Fprintf(w, "All %d file(s) are provided by at least %d peers.\n", len(cids), threshold)
+			return
+		}
+		fmt.Fprintf(w, "%d of %d file(s) are provided by fewer than %d peers:\n", len(atRisk), len(cids), threshold)
+		for _, f := range atRisk {
+			fmt.Fprintf(w, "\t%d  %v  %v (%v)\n", f.Providers, f.CID, strings.Join(f.Names, ", "), f.Source)
+		}
+	})
+	if output != "" {
+		fmt.Println("Wrote the report to", output)
+	}
+}
+
+// writeAtRiskCSV writes the under-replicated files to path as CSV.
+func writeAtRiskCSV(path string, atRisk []AtRiskFile) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	w := csv.NewWriter(file)
+	_ = w.Write([]string{"cid", "names", "source", "providers"})
+	for _, f := range atRisk {
+		_ = w.Write([]string{f.CID, strings.Join(f.Names, ";"), f.Source, strconv.Itoa(f.Providers)})
+	}
+	w.Flush()
+	if err = w.Error(); err != nil {
+		return err
+	}
+	return file.Close()
+}
@@ -0,0 +1,120 @@
+package cli
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/arken/ait/config"
+	"github.com/arken/ait/utils"
+
+	"github.com/DataDrake/cli-ng/v2/cmd"
+)
+
+// Backup packages ait's state into a single file so it can be moved to
+// another workstation.
+var Backup = cmd.Sub{
+	Name:  "backup",
+	Short: "Save ait's config, staged files and keys to a file.",
+	Args:  &BackupArgs{},
+	Flags: &BackupFlags{},
+	Run:   BackupRun,
+}
+
+// BackupArgs handles the specific arguments for the backup command.
+type BackupArgs struct {
+	File string `desc:"The backup file to write"`
+}
+
+// BackupFlags handles the specific flags for the backup command.
+type BackupFlags struct {
+	NoKeys bool `short:"n" long:"no-keys" desc:"Leave the IPFS identity and IPNS keys out of the backup"`
+}
+
+// Restore unpacks a file created by ait backup.
+var Restore = cmd.Sub{
+	Name:  "restore",
+	Short: "Restore ait's state from a file created by ait backup.",
+	Args:  &RestoreArgs{},
+	Flags: &RestoreFlags{},
+	Run:   RestoreRun,
+}
+
+// RestoreArgs handles the specific arguments for the restore command.
+type RestoreArgs struct {
+	File string `desc:"The backup file to restore"`
+}
+
+// RestoreFlags handles the specific flags for the restore command.
+type RestoreFlags struct {
+	Force bool `short:"f" long:"force" desc:"Overwrite existing files"`
+}
+
+// backupEntries lists the state that makes up a backup. The IPFS repository
+// contributes its identity and keystore but never its blocks, which can be
+// re-added from the original files.
+func backupEntries(noKeys bool) []utils.ArchiveEntry {
+	home := filepath.Dir(config.Path)
+	entries := []utils.ArchiveEntry{
+		{Path: config.Path, Name: "home/ait.config"},
+		{Path: filepath.Join(home, "application.md"), Name: "home/application.md"},
+	}
+	if !noKeys {
+		for _, name := range []string{"config", "datastore_spec", "version", "keystore"} {
+			entries = append(entries, utils.ArchiveEntry{
+				Path: filepath.Join(config.Global.IPFS.Path, name),
+				Name: "ipfs/" + name,
+			})
+		}
+	}
+	if utils.IsAITRepo() {
+		for _, name := range []string{"added_files", "commit", "profile", "archive", "handoff", "history"} {
+			entries = append(entries, utils.ArchiveEntry{
+				Path: filepath.Join(".ait", name),
+				Name: "workspace/" + name,
+			})
+		}
+		// The CIDs of the files and the settings the workspace overrides.
+		for _, path := range []string{utils.HashCachePath, config.WorkspaceConfigPath} {
+			rel, err := filepath.Rel(".ait", path)
+			if err != nil {
+				continue
+			}
+			entries = append(entries, utils.ArchiveEntry{
+				Path: path,
+				Name: "workspace/" + filepath.ToSlash(rel),
+			})
+		}
+	}
+	return entries
+}
+
+// BackupRun writes the backup file.
+func BackupRun(_ *cmd.Root, c *cmd.Sub) {
+	out := c.Args.(*BackupArgs).File
+	noKeys := c.Flags.(*BackupFlags).NoKeys
+	utils.CheckError(utils.CreateArchive(out, backupEntries(noKeys)))
+	fmt.Println("Backup written to", out)
+	if !noKeys {
+		fmt.Println("The backup contains your private IPFS keys. Keep it somewhere safe!")
+	}
+	if !utils.IsAITRepo() {
+		fmt.Println("Not in an AIT repository, no staged files were included.")
+	}
+}
+
+// RestoreRun unpacks the backup into ~/.ait, the IPFS repository and, for
+// workspace state, the current directory.
+func RestoreRun(_ *cmd.Root, c *cmd.Sub) {
+	in := c.Args.(*RestoreArgs).File
+	roots := map[string]string{
+		"home":      filepath.Dir(config.Path),
+		"ipfs":      config.Global.IPFS.Path,
+		"workspace": ".ait",
+	}
+	restored, err := utils.ExtractArchive(in, roots, c.Flags.(*RestoreFlags).Force)
+	if err != nil {
+		utils.FatalPrintln("Unable to restore the backup:", err,
+			"\nUse --force to overwrite existing files.")
+	}
+	fmt.Println(len(restored), "file(s) restored from", in)
+}
@@ -0,0 +1,117 @@
+package cli
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/arken/ait/apis/forge"
+	"github.com/arken/ait/display"
+	"github.com/arken/ait/keysets"
+	"github.com/arken/ait/utils"
+)
+
+// batchKeysetPath is where the keyset of a batch submission is generated, out
+// of the keysets directory each submission cleans up.
+var batchKeysetPath = filepath.Join(".ait", "batch.ks")
+
+// batchSubmitting is set while the staged files are submitted to several
+// remotes, each with the application given once.
+var batchSubmitting bool
+
+// BatchResult is how the submission to one of the remotes of a batch went.
+type BatchResult struct {
+	Remote    string `json:"remote"`
+	Submitted bool   `json:"submitted"`
+	Error     string `json:"error,omitempty"`
+}
+
+// readRemotesFile returns the remotes listed in the file at path, one per
+// line. Blank lines and lines starting with # are skipped.
+func readRemotesFile(path string) ([]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	var remotes []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line != "" && !strings.HasPrefix(line, "#") {
+			remotes = append(remotes, line)
+		}
+	}
+	return remotes, scanner.Err()
+}
+
+// submitBatch submits the staged files to every remote in turn, generating
+// the keyset and asking for the application once. A remote that fails doesn't
+// stop the others, how each went is reported at the end.
+func submitBatch(urls []string, isPR, isIssue bool, flags *SubmitFlags) {
+	if flags.SplitFiles > 0 || flags.SplitSize != "" {
+		utils.FatalPrintln("Split submissions go to a single remote, they can't be made to several.")
+	}
+	display.ShowApplication()
+	app := display.ReadApplication()
+	if !app.IsValid() {
+		fmt.Printf("Exiting Submission because the application has no %v.\n", app.Missing())
+		utils.FatalPrintln("Submission aborted.")
+	}
+	if err := preSubmitHook(app, urls...); err != nil {
+		exitSubmission(withCleanup(err))
+	}
+	cleanup := func() { _ = os.Remove(batchKeysetPath) }
+	_, err := checkGenerated(keysets.Generate(batchKeysetPath, true), batchKeysetPath, flags.Strict, cleanup)
+	if err != nil {
+		exitSubmission(err)
+	}
+	defer cleanup()
+	// Each remote is submitted the keyset as a queued submission would be,
+	// merged with the one it holds already unless it's overwritten.
+	queuedKeyset, batchSubmitting = batchKeysetPath, true
+	defer func() { queuedKeyset, batchSubmitting = "", false }()
+
+	results := make([]BatchResult, 0, len(urls))
+	for i, url := range urls {
+		fmt.Printf("\nSubmitting to %v (%d of %d)...\n", url, i+1, len(urls))
+		result := BatchResult{Remote: url}
+		kind, err := forge.Detect(url, flags.Provider)
+		if err == nil {
+			err = display.WriteApplication(app)
+		}
+		if err == nil {
+			result.Submitted, err = submitTo(url, kind, isPR, isIssue, flags)
+		}
+		if err != nil {
+			result.Submitted, result.Error = false, err.Error()
+		} else if !result.Submitted {
+			result.Error = "aborted"
+		}
+		results = append(results, result)
+	}
+
+	failed := 0
+	for _, result := range results {
+		if !result.Submitted {
+			failed++
+		}
+	}
+	display.Out.Result(results, func(w io.Writer) {
+		fmt.Fprintf(w, "\nSubmitted to %d of %d remote(s):\n", len(results)-failed, len(results))
+		for _, result := range results {
+			if result.Submitted {
+				fmt.Fprintf(w, "\t%v: submitted\n", result.Remote)
+			} else {
+				fmt.Fprintf(w, "\t%v: failed, %v\n", result.Remote, result.Error)
+			}
+		}
+	})
+	if failed > 0 {
+		cleanup()
+		utils.FatalPrintf("The submission to %d remote(s) failed, submit to them again once fixed.\n", failed)
+	}
+}
@@ -0,0 +1,173 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/arken/ait/config"
+	"github.com/arken/ait/platform"
+	"github.com/arken/ait/utils"
+
+	"github.com/BurntSushi/toml"
+	"github.com/DataDrake/cli-ng/v2/cmd"
+	goipfs "github.com/ipfs/go-ipfs"
+)
+
+// BugReport bundles what's needed to diagnose a problem into a tarball.
+var BugReport = cmd.Sub{
+	Name:  "bugreport",
+	Short: "Bundle logs, the sanitized config and the last failure for a bug report.",
+	Flags: &BugReportFlags{},
+	Run:   BugReportRun,
+}
+
+// BugReportFlags handles the specific flags for the bugreport command.
+type BugReportFlags struct {
+	Output string `short:"o" long:"output" desc:"Where to write the tarball, ait-bugreport-<date>.tar.gz by default"`
+}
+
+// bugReportLogLines is how many of the most recent lines of the alert log are
+// included.
+const bugReportLogLines = 500
+
+// failure is the context of the last command that failed, kept for bug
+// reports.
+type failure struct {
+	Time    time.Time `json:"time"`
+	Command []string  `json:"command"`
+	Workdir string    `json:"workdir"`
+	Profile string    `json:"profile,omitempty"`
+	Message string    `json:"message"`
+}
+
+// failurePath is where the last failure is recorded.
+func failurePath() string {
+	return filepath.Join(filepath.Dir(config.Path), "last-failure.json")
+}
+
+// recordFailure records the context of the command failing with msg.
+func recordFailure(msg string) {
+	wd, _ := os.Getwd()
+	data, err := json.MarshalIndent(failure{
+		Time:    time.Now(),
+		Command: os.Args,
+		Workdir: wd,
+		Profile: config.ActiveProfile,
+		Message: msg,
+	}, "", "  ")
+	if err == nil {
+		_ = ioutil.WriteFile(failurePath(), data, 0600)
+	}
+}
+
+// BugReportRun writes the bug report tarball. Every file in it is scrubbed of
+// the secrets in the config, tokens and credentials in URLs.
+func BugReportRun(_ *cmd.Root, c *cmd.Sub) {
+	flags := c.Flags.(*BugReportFlags)
+	out := flags.Output
+	if out == "" {
+		out = fmt.Sprintf("ait-bugreport-%v.tar.gz", time.Now().Format("20060102-150405"))
+	}
+	dir, err := ioutil.TempDir("", "ait-bugreport")
+	utils.CheckError(err)
+	defer os.RemoveAll(dir)
+
+	conf := config.Global
+	secrets := []string{conf.Git.PAT, config.GitHubToken(), conf.DNSLink.Token, conf.SMTP.Password, conf.DOI.Token,
+		conf.Community.ID}
+	files := map[string]string{
+		"versions.txt":      bugReportVersions(),
+		"config.toml":       sanitizedConfig(conf),
+		"workspace.txt":     bugReportWorkspace(),
+		"last-failure.json": readOrNote(failurePath(), "No failure has been recorded."),
+	}
+	if conf.Notify.Log != "" {
+		files["alerts.log"] = tailLines(readOrNote(conf.Notify.Log, "The alert log is empty."),
+			bugReportLogLines)
+	}
+	for name, contents := range files {
+		err = ioutil.WriteFile(filepath.Join(dir, name), []byte(utils.Redact(contents, secrets...)), 0600)
+		utils.CheckError(err)
+	}
+	utils.CheckError(utils.CreateArchive(out, []utils.ArchiveEntry{{Path: dir, Name: "ait-bugreport"}}))
+	fmt.Printf("Wrote %v. Secrets were removed, but please look through it before attaching it to an issue.\n", out)
+}
+
+// bugReportVersions describes the versions of ait and what it runs on.
+func bugReportVersions() string {
+	return fmt.Sprintf("ait %v\nconfig %v\ngo-ipfs %v\n%v %v\n%v\n",
+		appVersion, config.Global.General.Version, goipfs.CurrentVersionNumber,
+		runtime.Version(), platform.Name(), strings.Join(platform.Features(), "\n"))
+}
+
+// sanitizedConfig encodes conf with its secrets replaced.
+func sanitizedConfig(conf config.Config) string {
+	for _, secret := range []*string{&conf.Git.PAT, &conf.DNSLink.Token, &conf.SMTP.Password,
+		&conf.DOI.Token, &conf.DOI.Username, &conf.SMTP.Username, &conf.Community.ID} {
+		if *secret != "" {
+			*secret = utils.Redacted
+		}
+	}
+	if conf.Notify.Webhook != "" {
+		// Webhook URLs usually embed their credentials in the path.
+		conf.Notify.Webhook = utils.Redacted
+	}
+	buf := new(bytes.Buffer)
+	if err := toml.NewEncoder(buf).Encode(conf); err != nil {
+		return err.Error()
+	}
+	return buf.String()
+}
+
+// bugReportWorkspace summarizes the current workspace without naming its
+// files.
+func bugReportWorkspace() string {
+	if !utils.IsAITRepo() {
+		return "Not run from an ait workspace.\n"
+	}
+	var b strings.Builder
+	var size int64
+	staged := readStagedFiles()
+	for _, file := range staged {
+		size += file.Size
+	}
+	fmt.Fprintf(&b, "%d file(s) staged, %v\n", len(staged), utils.FormatByteSize(size))
+	history, err := utils.ReadHistory()
+	if err != nil {
+		fmt.Fprintf(&b, "Unable to read the submission history: %v\n", err)
+	}
+	for _, s := range history {
+		fmt.Fprintf(&b, "Submission %v at %v to %v: %d entries, safe %v, at risk %v\n",
+			s.ID, s.Time.Format(time.RFC3339), s.Remote, len(s.Entries), s.Safe, s.AtRisk)
+	}
+	return b.String()
+}
+
+// readOrNote returns the contents of the file at path, or note if it can't be
+// read.
+func readOrNote(path, note string) string {
+	data, err := ioutil.ReadFile(path)
+	if err != nil || len(data) == 0 {
+		return note + "\n"
+	}
+	return string(data)
+}
+
+// tailLines returns the last n lines of text.
+func tailLines(text string, n int) string {
+	lines := strings.SplitAfter(text, "\n")
+	if len(lines) > 0 && lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	if len(lines) > n {
+		lines = lines[len(lines)-n:]
+	}
+	return strings.Join(lines, "")
+}
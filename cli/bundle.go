@@ -0,0 +1,181 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/arken/ait/config"
+	"github.com/arken/ait/ipfs"
+	"github.com/arken/ait/types"
+	"github.com/arken/ait/utils"
+
+	"github.com/DataDrake/cli-ng/v2/cmd"
+)
+
+// Bundle lets someone without submission credentials prepare the staged files
+// of a dataset as a signed bundle for somebody else to review and submit.
+var Bundle = cmd.Sub{
+	Name:  "bundle",
+	Short: "Prepare the staged files as a signed bundle for review.",
+	Args:  &BundleArgs{},
+	Run:   BundleRun,
+}
+
+// BundleArgs handles the specific arguments for the bundle command.
+type BundleArgs struct {
+	File string `desc:"The bundle file to write"`
+}
+
+// ReviewBundle checks a bundle prepared with ait bundle and stages its files
+// so they can be submitted.
+var ReviewBundle = cmd.Sub{
+	Name:  "review-bundle",
+	Alias: "rb",
+	Short: "Review a signed bundle and stage it for submission.",
+	Args:  &ReviewBundleArgs{},
+	Flags: &ReviewBundleFlags{},
+	Run:   ReviewBundleRun,
+}
+
+// ReviewBundleArgs handles the specific arguments for the review-bundle command.
+type ReviewBundleArgs struct {
+	File string `desc:"The bundle file to review"`
+}
+
+// ReviewBundleFlags handles the specific flags for the review-bundle command.
+type ReviewBundleFlags struct {
+	Signer string `short:"s" long:"signer" desc:"Accept the bundle if it's signed by this IPFS peer ID, besides those of Trust.Signers"`
+	Yes    bool   `short:"y" long:"yes" desc:"Stage the bundle without asking for confirmation"`
+}
+
+// stagingBundle is a signed bundle file. The signature covers the raw
+// payload so it can be verified before the payload is decoded.
+type stagingBundle struct {
+	Payload   json.RawMessage `json:"payload"`
+	Signer    string          `json:"signer"`
+	PublicKey []byte          `json:"publicKey"`
+	Signature []byte          `json:"signature"`
+}
+
+// bundlePayload is what the preparer vouches for.
+type bundlePayload struct {
+	Created     time.Time            `json:"created"`
+	Files       []utils.HandoffEntry `json:"files"`
+	Application string               `json:"application,omitempty"`
+}
+
+// BundleRun hashes the staged files and writes them, together with any
+// application filled in so far, to a bundle signed with this machine's IPFS
+// identity.
+func BundleRun(_ *cmd.Root, c *cmd.Sub) {
+	out := c.Args.(*BundleArgs).File
+	contents, err := utils.ReadStagedSorted()
+	utils.CheckError(err)
+	if contents.Size() == 0 {
+		utils.FatalPrintln("No files are currently staged, nothing to bundle.")
+	}
+	prettyIPFSInit()
+	payload := bundlePayload{Created: time.Now(), Files: hashStaged(contents)}
+	if app, err := ioutil.ReadFile(filepath.Join(".ait", "commit")); err == nil {
+		payload.Application = string(app)
+	}
+	data, err := json.Marshal(payload)
+	utils.CheckError(err)
+	sig, pubKey, id, err := ipfs.Sign(data)
+	utils.CheckError(err)
+	bundle, err := json.MarshalIndent(stagingBundle{
+		Payload:   data,
+		Signer:    id,
+		PublicKey: pubKey,
+		Signature: sig,
+	}, "", "  ")
+	utils.CheckError(err)
+	utils.CheckError(ioutil.WriteFile(out, bundle, 0644))
+	fmt.Printf("Bundle of %d file(s) written to %v and signed by %v\n", len(payload.Files), out, id)
+	fmt.Println("Send it to whoever submits for your team so they can run \"ait review-bundle\".")
+}
+
+// ReviewBundleRun verifies the bundle's signature, shows its contents, and
+// stages it once the reviewer approves.
+func ReviewBundleRun(_ *cmd.Root, c *cmd.Sub) {
+	in := c.Args.(*ReviewBundleArgs).File
+	flags := c.Flags.(*ReviewBundleFlags)
+	data, err := ioutil.ReadFile(in)
+	utils.CheckError(err)
+	bundle := stagingBundle{}
+	if err = json.Unmarshal(data, &bundle); err != nil {
+		utils.FatalPrintln(in, "is not an ait bundle:", err)
+	}
+	signer, err := ipfs.Verify(bundle.Payload, bundle.Signature, bundle.PublicKey)
+	if err != nil || signer != bundle.Signer {
+		utils.FatalPrintln("The bundle's signature is invalid, it may have been tampered with.")
+	}
+	// The bundle carries the key it's signed with, so the signature only
+	// shows who prepared it once the signer is known to the reviewer.
+	trusted := config.Global.Trust.Signers
+	if flags.Signer != "" {
+		trusted = []string{flags.Signer}
+	}
+	switch {
+	case len(trusted) == 0:
+		utils.FatalPrintf("The bundle was signed by %v, but no signer is trusted. Check with whoever "+
+			"prepared it that this is their peer ID (\"ait id\"), then pass it with --signer or add it "+
+			"to Trust.Signers in the config.\n", signer)
+	case utils.IndexOf(trusted, signer) < 0:
+		utils.FatalPrintf("The bundle was signed by %v, not by %v.\n", signer, strings.Join(trusted, " or "))
+	}
+	payload := bundlePayload{}
+	utils.CheckError(json.Unmarshal(bundle.Payload, &payload))
+
+	fmt.Printf("Bundle signed by %v on %v\n", signer, payload.Created.Format("Jan 2 2006 3:04 PM"))
+	fmt.Println(len(payload.Files), "file(s):")
+	for _, entry := range payload.Files {
+		fmt.Printf("\t %v  %v (%v)\n", entry.CID, entry.Path, utils.FormatByteSize(entry.Size))
+	}
+	if payload.Application != "" {
+		fmt.Println("The bundle includes a filled in application.")
+	}
+	staged, err := utils.ReadStaged()
+	utils.CheckError(err)
+	hasApp := payload.Application != "" && utils.FileExists(filepath.Join(".ait", "commit"))
+	if !flags.Yes && !promptStageBundle(staged.Size(), hasApp) {
+		fmt.Println("Bundle rejected, nothing was staged.")
+		return
+	}
+
+	contents := types.NewSortedStringSet()
+	for _, entry := range payload.Files {
+		contents.Add(entry.Path)
+	}
+	// The files staged before are kept in the trash to be restored.
+	utils.CheckError(utils.WriteUnstaged(contents, "ait review-bundle "+in))
+	utils.CheckError(utils.WriteHandoff(payload.Files))
+	if payload.Application != "" {
+		err = ioutil.WriteFile(filepath.Join(".ait", "commit"), []byte(payload.Application), 0644)
+		utils.CheckError(err)
+	}
+	fmt.Println("Bundle staged. Run \"ait submit <remote>\" to submit it.")
+	if staged.Size() > 0 {
+		fmt.Printf("The %d file(s) staged before were unstaged, \"ait unstage --undo\" stages them again.\n",
+			staged.Size())
+	}
+}
+
+// promptStageBundle asks the reviewer to approve the bundle, warning that the
+// files already staged and the application filled in are replaced.
+func promptStageBundle(staged int, hasApp bool) bool {
+	switch {
+	case staged > 0 && hasApp:
+		fmt.Printf("This replaces the %d file(s) currently staged and the application filled in.\n", staged)
+	case staged > 0:
+		fmt.Printf("This replaces the %d file(s) currently staged.\n", staged)
+	case hasApp:
+		fmt.Println("This replaces the application filled in.")
+	}
+	fmt.Print("Stage these files for submission? (y/[n]) ")
+	return strings.ToLower(utils.ReadAnswer()) == "y"
+}
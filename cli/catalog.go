@@ -0,0 +1,111 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/arken/ait/utils"
+
+	"github.com/DataDrake/cli-ng/v2/cmd"
+)
+
+// Catalog imports a lab's existing data catalog and reconciles it with the
+// staged files.
+var Catalog = cmd.Sub{
+	Name:  "catalog",
+	Short: "Import a data catalog (CSV of path, sha256 and metadata) and check the staged files against it.",
+	Args:  &CatalogArgs{},
+	Run:   CatalogRun,
+}
+
+// CatalogArgs handles the specific arguments for the catalog command.
+type CatalogArgs struct {
+	// CSV is optional, the imported catalog is checked again without it.
+	CSV []string
+}
+
+// catalogListed is how many paths of each kind are listed before the rest are
+// only counted.
+const catalogListed = 10
+
+// CatalogRun merges the given CSV files into the workspace's catalog, then
+// checks the checksum of every staged file the catalog lists. The IDs and
+// metadata of files that match are attached to submissions.
+func CatalogRun(_ *cmd.Root, c *cmd.Sub) {
+	args := c.Args.(*CatalogArgs)
+	catalog, err := utils.ReadCatalog()
+	utils.CheckError(err)
+	for _, path := range args.CSV {
+		file, err := os.Open(path)
+		utils.CheckError(err)
+		records, err := utils.ParseCatalogCSV(file)
+		file.Close()
+		if err != nil {
+			utils.FatalPrintf("Unable to import %v: %v\n", path, err)
+		}
+		for _, record := range records {
+			catalog[record.Path] = record
+		}
+		fmt.Printf("Imported %d record(s) from %v.\n", len(records), path)
+	}
+	if len(catalog) == 0 {
+		utils.FatalPrintln("No catalog has been imported, expected a CSV file with path and sha256 columns.")
+	}
+
+	var staged []string
+	for _, file := range readStagedFiles() {
+		staged = append(staged, file.Path)
+	}
+	fmt.Println("Checking the staged files against the catalog...")
+	report, err := utils.ReconcileCatalog(catalog, staged, utils.FileSHA256)
+	utils.CheckError(err)
+	utils.CheckError(utils.WriteCatalog(catalog))
+
+	fmt.Printf("%d staged file(s) match the catalog.\n", len(report.Matched))
+	if len(report.Mismatched) > 0 {
+		fmt.Printf("%d staged file(s) DON'T match their catalog checksum:\n", len(report.Mismatched))
+		for _, m := range report.Mismatched {
+			fmt.Printf("    %v\n        catalog %v\n        on disk %v\n", m.Path, m.Expected, m.Actual)
+		}
+	}
+	printCatalogPaths("staged file(s) aren't in the catalog", report.Uncataloged)
+	printCatalogPaths("cataloged file(s) aren't staged", report.Unstaged)
+	if len(report.Mismatched) > 0 {
+		utils.FatalPrintln("Unstage the mismatched files or correct the catalog before submitting.")
+	}
+}
+
+// printCatalogPaths prints the first few paths under a heading.
+func printCatalogPaths(heading string, paths []string) {
+	if len(paths) == 0 {
+		return
+	}
+	fmt.Printf("%d %v:\n", len(paths), heading)
+	for i, path := range paths {
+		if i == catalogListed {
+			fmt.Printf("    ...and %d more\n", len(paths)-catalogListed)
+			break
+		}
+		fmt.Println("   ", path)
+	}
+}
+
+// catalogIDs returns the catalog IDs of the staged files verified against the
+// catalog, by path.
+func catalogIDs() map[string]string {
+	catalog, err := utils.ReadCatalog()
+	if err != nil {
+		fmt.Println("Unable to read the data catalog:", err)
+		return nil
+	}
+	ids := make(map[string]string)
+	for _, file := range readStagedFiles() {
+		if record, ok := catalog[file.Path]; ok && record.Verified && record.ID != "" {
+			ids[file.Path] = record.ID
+		}
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+	return ids
+}
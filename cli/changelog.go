@@ -0,0 +1,44 @@
+package cli
+
+import (
+	"fmt"
+	"time"
+
+	aitgh "github.com/arken/ait/apis/github"
+	"github.com/arken/ait/config"
+	"github.com/arken/ait/types"
+	"github.com/arken/ait/utils"
+)
+
+// changelogLine returns the upstream policy's changelog and the line
+// describing the staged files in it, or nil if the policy doesn't ask for
+// one. An error is returned if the policy is invalid or the line doesn't
+// follow it, so the submission stops before anything is committed.
+func changelogLine(app *types.ApplicationContents, repoPath string) (*aitgh.ChangelogPolicy, string, error) {
+	policy, err := aitgh.FetchPolicy()
+	if err != nil {
+		return nil, "", fmt.Errorf("unable to use the repository's submission policy: %v", err)
+	}
+	if policy == nil || policy.Changelog == nil {
+		return nil, "", nil
+	}
+	var size int64
+	staged := readStagedFiles()
+	for _, file := range staged {
+		size += file.Size
+	}
+	line, err := policy.Changelog.Render(aitgh.ChangelogEntry{
+		Title:       app.Title,
+		Description: app.Commit,
+		Path:        repoPath,
+		Category:    app.Category,
+		Author:      config.Global.Git.Name,
+		Date:        time.Now().Format("2006-01-02"),
+		Files:       len(staged),
+		Size:        utils.FormatByteSize(size),
+	})
+	if err != nil {
+		return nil, "", fmt.Errorf("the changelog entry doesn't follow the repository's submission policy: %v", err)
+	}
+	return policy.Changelog, line, nil
+}
@@ -0,0 +1,57 @@
+package cli
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/arken/ait/config"
+	"github.com/arken/ait/utils"
+
+	"github.com/DataDrake/cli-ng/v2/cmd"
+)
+
+// Clean removes archived keysets and cloned keyset sources that fall outside
+// of the configured retention.
+var Clean = cmd.Sub{
+	Name:  "clean",
+	Short: "Remove old generated keysets and cloned keyset sources.",
+	Args:  &CleanArgs{},
+	Flags: &CleanFlags{},
+	Run:   CleanRun,
+}
+
+// CleanArgs handles the specific arguments for the clean command.
+type CleanArgs struct {
+}
+
+// CleanFlags handles the specific flags for the clean command.
+type CleanFlags struct {
+	All bool `short:"a" long:"all" desc:"Remove everything, ignoring the retention setting"`
+}
+
+// CleanRun prunes the keyset archive of the current workspace and the cloned
+// keyset sources down to General.Retention entries each.
+func CleanRun(_ *cmd.Root, c *cmd.Sub) {
+	keep := config.Global.General.Retention
+	if c.Flags.(*CleanFlags).All {
+		keep = 0
+	}
+	total := 0
+	if utils.IsAITRepo() {
+		removed, err := utils.PruneDir(utils.KeysetArchivePath, keep)
+		utils.CheckError(err)
+		total += len(removed)
+	}
+	sources := filepath.Join(filepath.Dir(config.Path), "sources")
+	removed, err := utils.PruneDir(sources, keep)
+	utils.CheckError(err)
+	for _, name := range removed {
+		fmt.Println("Removed cloned keyset", name)
+	}
+	total += len(removed)
+	if total == 0 {
+		fmt.Println("Nothing to clean.")
+		return
+	}
+	fmt.Printf("Removed %d item(s), keeping the %d most recent of each.\n", total, keep)
+}
@@ -0,0 +1,338 @@
+package cli
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/arken/ait/config"
+	"github.com/arken/ait/utils"
+
+	"github.com/DataDrake/cli-ng/v2/cmd"
+)
+
+// Completion prints the shell completion script of ait.
+var Completion = cmd.Sub{
+	Name:  "completion",
+	Short: "Print the completion script of bash, zsh or fish for ait's commands, flags and remotes.",
+	Args:  &CompletionArgs{},
+	Run:   CompletionRun,
+}
+
+// CompletionArgs handles the specific arguments for the completion command.
+type CompletionArgs struct {
+	Shell string `desc:"bash, zsh or fish"`
+}
+
+// remoteCommands are the commands taking a remote alias, completed along with
+// the files.
+var remoteCommands = []string{"coverage", "index", "keyset", "log", "pull", "submit", "update", "validate"}
+
+// completedCommand is a command as the completion scripts know it.
+type completedCommand struct {
+	names   []string
+	short   string
+	flags   []completedFlag
+	remotes bool
+}
+
+// completedFlag is a flag as the completion scripts know it.
+type completedFlag struct {
+	short, long, desc string
+	// value is set for flags taking a value.
+	value bool
+}
+
+// CompletionRun prints the completion script of the shell. "remotes" prints
+// the remote aliases instead, one per line, which the scripts run to complete
+// them as they're saved.
+func CompletionRun(_ *cmd.Root, c *cmd.Sub) {
+	shell := c.Args.(*CompletionArgs).Shell
+	commands := completedCommands()
+	global := completedFlags(&GlobalFlags{})
+	switch shell {
+	case "bash":
+		fmt.Print(bashCompletion(commands, global))
+	case "zsh":
+		fmt.Print(zshCompletion(commands, global))
+	case "fish":
+		fmt.Print(fishCompletion(commands, global))
+	case "remotes":
+		for _, alias := range remoteAliases() {
+			fmt.Println(alias)
+		}
+	default:
+		utils.FatalPrintf("Unknown shell %q, expected bash, zsh or fish.\n", shell)
+	}
+}
+
+// completedCommands returns the visible commands, with the command aliases of
+// the config, sorted by name.
+func completedCommands() []completedCommand {
+	commands := []completedCommand{{names: []string{"help"}, short: cmd.Help.Short}}
+	for _, sub := range registered {
+		if sub.Hidden {
+			continue
+		}
+		command := completedCommand{names: []string{sub.Name}, short: sub.Short,
+			flags: completedFlags(sub.Flags), remotes: utils.IndexOf(remoteCommands, sub.Name) >= 0}
+		if sub.Alias != "" {
+			command.names = append(command.names, sub.Alias)
+		}
+		var aliases []string
+		for alias, name := range commandAliases {
+			if name == sub.Name {
+				aliases = append(aliases, alias)
+			}
+		}
+		sort.Strings(aliases)
+		command.names = append(command.names, aliases...)
+		commands = append(commands, command)
+	}
+	for alias, line := range config.Global.Aliases {
+		if _, builtin := commandNames[alias]; !builtin {
+			commands = append(commands, completedCommand{names: []string{alias}, short: "Alias for " + line})
+		}
+	}
+	sort.Slice(commands, func(i, j int) bool { return commands[i].names[0] < commands[j].names[0] })
+	return commands
+}
+
+// completedFlags returns the flags of the flags struct of a command, read from
+// their tags as cli-ng does.
+func completedFlags(flags interface{}) []completedFlag {
+	if flags == nil {
+		return nil
+	}
+	t := reflect.TypeOf(flags)
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	var list []completedFlag
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		flag := completedFlag{short: field.Tag.Get("short"), long: field.Tag.Get("long"),
+			desc: field.Tag.Get("desc"), value: field.Type.Kind() != reflect.Bool}
+		if flag.short != "" || flag.long != "" {
+			list = append(list, flag)
+		}
+	}
+	return list
+}
+
+// options returns the options of the flags, ie "-e --extensions", those
+// taking a value only with value.
+func options(flags []completedFlag, value bool) []string {
+	var opts []string
+	for _, flag := range flags {
+		if value && !flag.value {
+			continue
+		}
+		if flag.short != "" {
+			opts = append(opts, "-"+flag.short)
+		}
+		if flag.long != "" {
+			opts = append(opts, "--"+flag.long)
+		}
+	}
+	return opts
+}
+
+// bashCompletion returns the bash completion script.
+func bashCompletion(commands []completedCommand, global []completedFlag) string {
+	var names []string
+	for _, command := range commands {
+		names = append(names, command.names...)
+	}
+	b := new(strings.Builder)
+	fmt.Fprintf(b, `# bash completion for ait, generated by "ait completion bash".
+
+_ait() {
+    local cur="${COMP_WORDS[COMP_CWORD]}" command="" i
+    for ((i = 1; i < COMP_CWORD; i++)); do
+        case "${COMP_WORDS[i]}" in
+            %v) ((i++)) ;;
+            -*) ;;
+            *) command="${COMP_WORDS[i]}"; break ;;
+        esac
+    done
+    local global=%q
+    if [[ -z "$command" ]]; then
+        if [[ "$cur" == -* ]]; then
+            COMPREPLY=($(compgen -W "$global" -- "$cur"))
+        else
+            COMPREPLY=($(compgen -W %q -- "$cur"))
+        fi
+        return
+    fi
+    local flags="" remotes=""
+    case "$command" in
+`, strings.Join(options(global, true), "|"), strings.Join(options(global, false), " "), strings.Join(names, " "))
+	for _, command := range commands {
+		fmt.Fprintf(b, "        %v) flags=%q", strings.Join(command.names, "|"), strings.Join(options(command.flags, false), " "))
+		if command.remotes {
+			b.WriteString(" remotes=1")
+		}
+		b.WriteString(" ;;\n")
+	}
+	fmt.Fprintf(b, `    esac
+    if [[ "$cur" == -* ]]; then
+        COMPREPLY=($(compgen -W "$flags $global" -- "$cur"))
+    elif [[ "$command" == help ]]; then
+        COMPREPLY=($(compgen -W %q -- "$cur"))
+    else
+        if [[ -n "$remotes" ]]; then
+            COMPREPLY=($(compgen -W "$(ait completion remotes 2>/dev/null)" -- "$cur"))
+        fi
+        COMPREPLY+=($(compgen -f -- "$cur"))
+    fi
+}
+
+complete -o filenames -F _ait ait
+`, strings.Join(names, " "))
+	return b.String()
+}
+
+// shQuote quotes s in single quotes for bash and zsh.
+func shQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// zshSpecs returns the _arguments specs of the flags, ie
+// '(-e --extensions)'{-e,--extensions}'[Stage files with these extensions]:value: '.
+func zshSpecs(flags []completedFlag) []string {
+	// Brackets and colons end the parts of a spec.
+	escape := strings.NewReplacer("[", `\[`, "]", `\]`, ":", `\:`)
+	var specs []string
+	for _, flag := range flags {
+		spec := "--" + flag.long
+		switch {
+		case flag.long == "":
+			spec = "-" + flag.short
+		case flag.short != "":
+			spec = fmt.Sprintf("'(-%v --%v)'{-%v,--%v}", flag.short, flag.long, flag.short, flag.long)
+		}
+		rest := "[" + escape.Replace(flag.desc) + "]"
+		if flag.value {
+			rest += ":value: "
+		}
+		specs = append(specs, spec+shQuote(rest))
+	}
+	return specs
+}
+
+// zshCompletion returns the zsh completion script.
+func zshCompletion(commands []completedCommand, global []completedFlag) string {
+	b := new(strings.Builder)
+	b.WriteString(`#compdef ait
+# zsh completion for ait, generated by "ait completion zsh".
+
+_ait_remotes() {
+    local -a remotes
+    remotes=(${(f)"$(ait completion remotes 2>/dev/null)"})
+    _describe -t remotes 'remote' remotes
+}
+
+_ait_remote_or_file() {
+    _ait_remotes
+    _files
+}
+
+_ait() {
+    local curcontext="$curcontext" state line
+    local -a commands global
+    commands=(
+`)
+	for _, command := range commands {
+		for _, name := range command.names {
+			fmt.Fprintf(b, "        %v\n", shQuote(strings.ReplaceAll(name, ":", `\:`)+":"+command.short))
+		}
+	}
+	b.WriteString("    )\n    global=(\n")
+	for _, spec := range zshSpecs(global) {
+		fmt.Fprintf(b, "        %v\n", spec)
+	}
+	b.WriteString(`    )
+    _arguments -C $global '1: :->command' '*:: :->args'
+    case $state in
+        command)
+            _describe -t commands 'ait command' commands
+            ;;
+        args)
+            case $words[1] in
+                help)
+                    _describe -t commands 'ait command' commands
+                    ;;
+`)
+	for _, command := range commands {
+		if command.names[0] == "help" {
+			continue
+		}
+		args := "'*:file:_files'"
+		if command.remotes {
+			args = "'*:remote or file:_ait_remote_or_file'"
+		}
+		fmt.Fprintf(b, "                %v)\n                    _arguments %v\n                    ;;\n",
+			strings.Join(command.names, "|"), strings.Join(append(zshSpecs(command.flags), args), " "))
+	}
+	b.WriteString(`            esac
+            ;;
+    esac
+}
+
+_ait "$@"
+`)
+	return b.String()
+}
+
+// fishQuote quotes s for fish.
+func fishQuote(s string) string {
+	return "'" + strings.NewReplacer(`\`, `\\`, "'", `\'`).Replace(s) + "'"
+}
+
+// fishCompletion returns the fish completion script.
+func fishCompletion(commands []completedCommand, global []completedFlag) string {
+	var names []string
+	for _, command := range commands {
+		names = append(names, command.names...)
+	}
+	b := new(strings.Builder)
+	b.WriteString(`# fish completion for ait, generated by "ait completion fish".
+
+function __ait_remotes
+    ait completion remotes 2>/dev/null
+end
+
+`)
+	writeFlags := func(condition string, flags []completedFlag) {
+		for _, flag := range flags {
+			fmt.Fprintf(b, "complete -c ait%v", condition)
+			if flag.short != "" {
+				fmt.Fprintf(b, " -s %v", flag.short)
+			}
+			if flag.long != "" {
+				fmt.Fprintf(b, " -l %v", flag.long)
+			}
+			if flag.value {
+				b.WriteString(" -r")
+			}
+			fmt.Fprintf(b, " -d %v\n", fishQuote(flag.desc))
+		}
+	}
+	writeFlags("", global)
+	for _, command := range commands {
+		fmt.Fprintf(b, "complete -c ait -n __fish_use_subcommand -f -a %v -d %v\n",
+			fishQuote(command.names[0]), fishQuote(command.short))
+	}
+	for _, command := range commands {
+		condition := " -n " + fishQuote("__fish_seen_subcommand_from "+strings.Join(command.names, " "))
+		writeFlags(condition, command.flags)
+		if command.remotes {
+			fmt.Fprintf(b, "complete -c ait%v -a '(__ait_remotes)' -d remote\n", condition)
+		}
+	}
+	fmt.Fprintf(b, "complete -c ait -n %v -f -a %v\n", fishQuote("__fish_seen_subcommand_from help"),
+		fishQuote(strings.Join(names, " ")))
+	return b.String()
+}
@@ -0,0 +1,107 @@
+package cli
+
+import (
+	"io/ioutil"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// completionTestFlags has descriptions the scripts must escape.
+type completionTestFlags struct {
+	Extensions string `short:"e" long:"extensions" desc:"Stage the files with these extensions, ie \"csv:tsv\""`
+	Quiet      bool   `long:"quiet" desc:"Don't print [anything]"`
+	Yes        bool   `short:"y" desc:"Answer yes: it's [y]"`
+}
+
+// completionTestCommands returns commands whose descriptions need escaping.
+func completionTestCommands() ([]completedCommand, []completedFlag) {
+	commands := []completedCommand{
+		{names: []string{"help"}, short: "Get help"},
+		{names: []string{"stage", "st"}, short: "Stage files: the user's [data]",
+			flags: completedFlags(&completionTestFlags{})},
+		{names: []string{"submit", "sm"}, short: "Submit the keyset", remotes: true},
+	}
+	global := completedFlags(&GlobalFlags{})
+	return commands, global
+}
+
+func TestCompletedFlags(t *testing.T) {
+	flags := completedFlags(&completionTestFlags{})
+	if len(flags) != 3 {
+		t.Fatalf("expected 3 flags, got %+v", flags)
+	}
+	if flags[0].short != "e" || flags[0].long != "extensions" || !flags[0].value {
+		t.Errorf("wrong --extensions flag: %+v", flags[0])
+	}
+	if flags[1].value || flags[2].long != "" {
+		t.Errorf("wrong --quiet or -y flag: %+v", flags[1:])
+	}
+	if completedFlags(nil) != nil {
+		t.Error("expected a command without flags to have none")
+	}
+}
+
+func TestBashCompletion(t *testing.T) {
+	script := bashCompletion(completionTestCommands())
+	for _, expected := range []string{
+		`stage|st) flags="-e --extensions --quiet -y"`,
+		`submit|sm) flags="" remotes=1`,
+		`--profile|--progress-socket`,
+		`complete -o filenames -F _ait ait`,
+	} {
+		if !strings.Contains(script, expected) {
+			t.Errorf("expected the bash script to contain %q:\n%v", expected, script)
+		}
+	}
+	checkSyntax(t, "bash", script)
+}
+
+func TestZshCompletion(t *testing.T) {
+	script := zshCompletion(completionTestCommands())
+	for _, expected := range []string{
+		`'stage:Stage files: the user'\''s [data]'`,
+		`'(-e --extensions)'{-e,--extensions}'[Stage the files with these extensions, ie "csv\:tsv"]:value: '`,
+		`--quiet'[Don'\''t print \[anything\]]'`,
+		`-y'[Answer yes\: it'\''s \[y\]]'`,
+		`'*:remote or file:_ait_remote_or_file'`,
+	} {
+		if !strings.Contains(script, expected) {
+			t.Errorf("expected the zsh script to contain %q:\n%v", expected, script)
+		}
+	}
+	checkSyntax(t, "zsh", script)
+}
+
+func TestFishCompletion(t *testing.T) {
+	script := fishCompletion(completionTestCommands())
+	for _, expected := range []string{
+		`-a 'stage' -d 'Stage files: the user\'s [data]'`,
+		`-s e -l extensions -r -d 'Stage the files with these extensions, ie "csv:tsv"'`,
+		`-l quiet -d 'Don\'t print [anything]'`,
+		`-n '__fish_seen_subcommand_from submit sm' -a '(__ait_remotes)'`,
+	} {
+		if !strings.Contains(script, expected) {
+			t.Errorf("expected the fish script to contain %q:\n%v", expected, script)
+		}
+	}
+	checkSyntax(t, "fish", script)
+}
+
+// checkSyntax parses script with shell, if it's installed, without running
+// it.
+func checkSyntax(t *testing.T, shell, script string) {
+	path, err := exec.LookPath(shell)
+	if err != nil {
+		t.Logf("%v isn't installed, the syntax of the script wasn't checked", shell)
+		return
+	}
+	file := filepath.Join(t.TempDir(), "ait."+shell)
+	if err = ioutil.WriteFile(file, []byte(script), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if out, err := exec.Command(path, "-n", file).CombinedOutput(); err != nil {
+		t.Errorf("%v -n failed: %v\n%s", shell, err, out)
+	}
+}
@@ -0,0 +1,94 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/arken/ait/config"
+	"github.com/arken/ait/display"
+	"github.com/arken/ait/utils"
+
+	"github.com/DataDrake/cli-ng/v2/cmd"
+)
+
+// Config reads and writes single settings, of the config file or of the
+// workspace's overrides.
+var Config = cmd.Sub{
+	Name:  "config",
+	Short: "Get, set or list settings, for this workspace or globally.",
+	Args:  &ConfigArgs{},
+	Flags: &ConfigFlags{},
+	Run:   ConfigRun,
+}
+
+// ConfigArgs handles the specific arguments for the config command.
+type ConfigArgs struct {
+	Args []string `zero:"yes" desc:"get <key>, set <key> <value>, unset <key> or list"`
+}
+
+// ConfigFlags handles the specific flags for the config command.
+type ConfigFlags struct {
+	Global bool `short:"g" long:"global" desc:"Use the config file instead of the workspace's overrides"`
+}
+
+const configUsage = `	ait config get <key>            # Show a setting, ie git.name
+	ait config set <key> <value>    # Set a setting for this workspace, or everywhere with --global
+	ait config unset <key>          # Remove a setting set by the workspace, or reset it with --global
+	ait config list                 # Show every setting`
+
+// ConfigRun dispatches to the requested config operation.
+func ConfigRun(_ *cmd.Root, c *cmd.Sub) {
+	args := c.Args.(*ConfigArgs).Args
+	flags := c.Flags.(*ConfigFlags)
+	if len(args) == 0 {
+		args = []string{"list"}
+	}
+	switch {
+	case args[0] == "get" && len(args) == 2:
+		conf := configLayer(flags.Global)
+		value, err := config.GetKey(conf, args[1])
+		utils.CheckError(err)
+		display.Out.Result(config.KeyValue{Key: args[1], Value: value}, func(w io.Writer) {
+			fmt.Fprintln(w, value)
+		})
+	case args[0] == "set" && len(args) == 3:
+		utils.CheckError(config.SaveKey(args[1], args[2], flags.Global))
+		fmt.Printf("Set %v to %q in %v.\n", args[1], args[2], configFile(flags.Global))
+	case args[0] == "unset" && len(args) == 2:
+		utils.CheckError(config.RemoveKey(args[1], flags.Global))
+		fmt.Printf("Removed %v from %v.\n", args[1], configFile(flags.Global))
+	case args[0] == "list" && len(args) == 1:
+		list := config.ListKeys(configLayer(flags.Global))
+		display.Out.Result(list, func(w io.Writer) {
+			for _, kv := range list {
+				fmt.Fprintf(w, "%v = %v", kv.Key, kv.Value)
+				if !flags.Global && config.IsWorkspaceKey(kv.Key) {
+					fmt.Fprintf(w, "    (%v)", config.WorkspaceConfigPath)
+				}
+				fmt.Fprintln(w)
+			}
+		})
+	default:
+		utils.FatalPrintln("Unknown config operation \"" + strings.Join(args, " ") + "\":\n" + configUsage)
+	}
+}
+
+// configLayer returns the settings of the config file with global, and the
+// ones in effect otherwise.
+func configLayer(global bool) config.Config {
+	if !global {
+		return config.Global
+	}
+	conf, err := config.LoadConf(false)
+	utils.CheckError(err)
+	return conf
+}
+
+// configFile returns the file config writes to.
+func configFile(global bool) string {
+	if global {
+		return config.Path
+	}
+	return config.WorkspaceConfigPath
+}
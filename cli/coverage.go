@@ -0,0 +1,153 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/arken/ait/config"
+	"github.com/arken/ait/display"
+	"github.com/arken/ait/ipfs"
+	"github.com/arken/ait/keysets"
+	"github.com/arken/ait/utils"
+
+	"github.com/DataDrake/cli-ng/v2/cmd"
+	"github.com/libp2p/go-libp2p-core/peer"
+)
+
+// Coverage checks how much of a keyset another keeper provides.
+var Coverage = cmd.Sub{
+	Name:  "coverage",
+	Short: "Check which files of a keyset a peer provides.",
+	Args:  &CoverageArgs{},
+	Flags: &CoverageFlags{},
+	Run:   CoverageRun,
+}
+
+// CoverageArgs handles the specific arguments for the coverage command.
+type CoverageArgs struct {
+	Keyset string `desc:"A keyset file, or a keyset repository to check every keyset of"`
+	Peer   string `desc:"The peer ID of the keeper"`
+}
+
+// CoverageFlags handles the specific flags for the coverage command.
+type CoverageFlags struct {
+	Missing bool   `short:"m" long:"missing" desc:"List the files the peer doesn't provide"`
+	Timeout string `short:"t" long:"timeout" desc:"How long to look for the peer among the providers of each file, 10s by default"`
+}
+
+// coverageTimeout is how long the providers of each file are looked through
+// by default.
+const coverageTimeout = 10 * time.Second
+
+// CoverageRun looks the peer up among the providers of every CID of the
+// keyset and reports the share it provides. Nothing is pinned or fetched.
+func CoverageRun(_ *cmd.Root, c *cmd.Sub) {
+	args := c.Args.(*CoverageArgs)
+	flags := c.Flags.(*CoverageFlags)
+	id, err := peer.Decode(args.Peer)
+	if err != nil {
+		utils.FatalPrintf("%q isn't a peer ID: %v\n", args.Peer, err)
+	}
+	timeout := coverageTimeout
+	if flags.Timeout != "" {
+		timeout, err = time.ParseDuration(flags.Timeout)
+		utils.CheckError(err)
+	}
+	entries, source := loadKeyset(args.Keyset)
+	cids := uniqueCIDs(entries)
+	if len(cids) == 0 {
+		utils.FatalPrintln(source + " has no entries.")
+	}
+
+	ipfs.Init(false)
+	fmt.Printf("Checking which of the %d file(s) of %v %v provides:\n", len(cids), source, id)
+	bar := display.NewProgress("Checking", int64(len(cids)), false)
+	provided := make([]bool, len(cids))
+	jobs := make(chan int)
+	wg := sync.WaitGroup{}
+	for w := 0; w < genNumWorkers(); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				provided[i], _ = ipfs.ProvidedBy(cids[i], id, timeout)
+				bar.Add(1)
+			}
+		}()
+	}
+	for i := range cids {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	count := 0
+	for _, ok := range provided {
+		if ok {
+			count++
+		}
+	}
+	fmt.Printf("%v provides %d of %d file(s), %.1f%% of %v.\n",
+		id, count, len(cids), 100*float64(count)/float64(len(cids)), source)
+	if flags.Missing && count < len(cids) {
+		fmt.Println("Not provided:")
+		names := namesByCID(entries)
+		for i, cid := range cids {
+			if !provided[i] {
+				fmt.Printf("\t%v  %v\n", cid, strings.Join(names[cid], ", "))
+			}
+		}
+	}
+}
+
+// loadKeyset reads the entries of the keyset file at arg, or of every keyset
+// of the keyset repository it names, and returns them with a description of
+// where they came from.
+func loadKeyset(arg string) ([]utils.KeysetEntry, string) {
+	if info, err := os.Stat(arg); err == nil && !info.IsDir() {
+		entries, err := utils.ReadKeysetEntries(arg)
+		utils.CheckError(err)
+		return entries, arg
+	}
+	url := config.GetRemote(arg)
+	repoPath := config.SourcePath(url)
+	_, err := keysets.Clone(url, repoPath)
+	utils.CheckError(err)
+	var entries []utils.KeysetEntry
+	err = filepath.Walk(repoPath, func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() || filepath.Ext(path) != ".ks" {
+			return err
+		}
+		found, err := utils.ReadKeysetEntries(path)
+		entries = append(entries, found...)
+		return err
+	})
+	utils.CheckError(err)
+	return entries, url
+}
+
+// uniqueCIDs returns the CIDs of the entries, once each, in order.
+func uniqueCIDs(entries []utils.KeysetEntry) []string {
+	seen := make(map[string]bool, len(entries))
+	var cids []string
+	for _, entry := range entries {
+		if !seen[entry.CID] {
+			seen[entry.CID] = true
+			cids = append(cids, entry.CID)
+		}
+	}
+	return cids
+}
+
+// namesByCID maps each CID of the entries to the names it's listed under.
+func namesByCID(entries []utils.KeysetEntry) map[string][]string {
+	names := make(map[string][]string, len(entries))
+	for _, entry := range entries {
+		names[entry.CID] = append(names[entry.CID], entry.Name)
+	}
+	return names
+}
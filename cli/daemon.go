@@ -0,0 +1,218 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sort"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/arken/ait/config"
+	"github.com/arken/ait/ipfs"
+	"github.com/arken/ait/utils"
+
+	"github.com/DataDrake/cli-ng/v2/cmd"
+)
+
+// Daemon keeps the node running so submitted files stay provided after the
+// commands that submitted them exit.
+var Daemon = cmd.Sub{
+	Name:  "daemon",
+	Short: "Keep the IPFS node running and reproviding every submitted file until stopped.",
+	Args:  &DaemonArgs{},
+	Flags: &DaemonFlags{},
+	Run:   DaemonRun,
+}
+
+// DaemonArgs handles the specific arguments for the daemon command.
+type DaemonArgs struct {
+}
+
+// DaemonFlags handles the specific flags for the daemon command.
+type DaemonFlags struct {
+	Address string `short:"a" long:"address" desc:"Address to serve the status on, localhost:8422 by default, or \"off\""`
+}
+
+// daemonShutdownTimeout is how long requests to the status endpoint are given
+// to finish when the daemon stops.
+const daemonShutdownTimeout = 5 * time.Second
+
+// daemon tracks what the running node provides, for its status endpoint.
+type daemon struct {
+	mu         sync.Mutex
+	started    time.Time
+	id         string
+	cids       []string
+	workspaces int
+	announced  int
+}
+
+// DaemonStatus is what the status endpoint of the daemon answers.
+type DaemonStatus struct {
+	PeerID     string    `json:"peer_id"`
+	Started    time.Time `json:"started"`
+	Uptime     string    `json:"uptime"`
+	Peers      int       `json:"peers"`
+	Workspaces int       `json:"workspaces"`
+	Submitted  int       `json:"submitted_cids"`
+	Announced  int       `json:"announced_since_start"`
+}
+
+// DaemonRun runs the node until it receives SIGINT or SIGTERM, then closes
+// the IPFS repository so other commands can open it again.
+func DaemonRun(_ *cmd.Root, c *cmd.Sub) {
+	flags := c.Flags.(*DaemonFlags)
+	address := flags.Address
+	if address == "" {
+		address = "localhost:8422"
+	}
+	interval, err := time.ParseDuration(config.Global.IPFS.ReprovideInterval)
+	if err != nil || interval <= 0 {
+		utils.FatalPrintf("The daemon needs IPFS.ReprovideInterval to be a duration like 12h, not %q.\n",
+			config.Global.IPFS.ReprovideInterval)
+	}
+
+	doneChan := make(chan int, 1)
+	wg := sync.WaitGroup{}
+	wg.Add(1)
+	go utils.SpinnerWait(doneChan, "Initializing IPFS...", &wg)
+	ipfs.Init(true)
+	doneChan <- 0
+	wg.Wait()
+	fmt.Print("\rInitializing IPFS: Done!")
+	fmt.Println()
+	close(doneChan)
+
+	d := &daemon{started: time.Now(), id: ipfs.GetID()}
+	d.refresh()
+	ctx, stop := context.WithCancel(context.Background())
+	go d.announce(ctx)
+	go ipfs.ScheduleReprovides(ctx, interval, d.priority)
+	go func() {
+		if err := ipfs.EnforceStorage(ctx); err != nil {
+			fmt.Printf("\n[Unable to garbage collect the IPFS repository: %v]\n", err)
+		}
+	}()
+	go scrubPeriodically(ctx)
+
+	var server *http.Server
+	if address != "off" {
+		mux := http.NewServeMux()
+		mux.HandleFunc("/status", d.status)
+		listener, err := net.Listen("tcp", address)
+		utils.CheckError(err)
+		server = &http.Server{Handler: mux}
+		go func() {
+			if err := server.Serve(listener); err != nil && err != http.ErrServerClosed {
+				fmt.Printf("\n[The status endpoint stopped: %v]\n", err)
+			}
+		}()
+		fmt.Printf("Serving the status at http://%v/status\n", address)
+	}
+	d.mu.Lock()
+	fmt.Printf("Providing the %d submitted file(s) of %d workspace(s) as %v, stop with Ctrl+C or SIGTERM.\n",
+		len(d.cids), d.workspaces, d.id)
+	d.mu.Unlock()
+
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, os.Interrupt, syscall.SIGTERM)
+	<-sig
+	signal.Stop(sig)
+	fmt.Println("\nStopping the daemon...")
+	stop()
+	if server != nil {
+		shutdown, cancel := context.WithTimeout(context.Background(), daemonShutdownTimeout)
+		_ = server.Shutdown(shutdown)
+		cancel()
+	}
+	if err := ipfs.Close(); err != nil {
+		utils.FatalPrintln("Unable to close the IPFS repository cleanly:", err)
+	}
+	fmt.Println("Stopped, the IPFS repository is closed.")
+}
+
+// refresh reads the CIDs submitted from every workspace the IPFS repository
+// knows of, newest submissions first, so new submissions are picked up by the
+// next round of reprovides.
+func (d *daemon) refresh() {
+	workspaces, err := ipfs.ReadWorkspaces()
+	if err != nil {
+		fmt.Printf("\n[Unable to read the workspaces: %v]\n", err)
+		return
+	}
+	var submissions []*utils.Submission
+	count := 0
+	for _, w := range workspaces {
+		history, err := utils.ReadHistoryIn(filepath.Join(w.Path, utils.HistoryPath))
+		if err != nil || len(history) == 0 {
+			continue
+		}
+		count++
+		submissions = append(submissions, history...)
+	}
+	sort.SliceStable(submissions, func(i, j int) bool { return submissions[i].Time.After(submissions[j].Time) })
+	seen := make(map[string]bool)
+	var cids []string
+	for _, s := range submissions {
+		for _, entry := range s.Entries {
+			if !seen[entry.CID] {
+				seen[entry.CID] = true
+				cids = append(cids, entry.CID)
+			}
+		}
+	}
+	d.mu.Lock()
+	d.cids, d.workspaces = cids, count
+	d.mu.Unlock()
+}
+
+// priority returns the submitted CIDs, which are reprovided before the rest
+// of the pinset each round.
+func (d *daemon) priority() []string {
+	d.refresh()
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.cids
+}
+
+// announce provides every submitted CID once when the daemon starts, rather
+// than waiting for their turn in the first round of reprovides.
+func (d *daemon) announce(ctx context.Context) {
+	d.mu.Lock()
+	cids := d.cids
+	d.mu.Unlock()
+	for _, cid := range cids {
+		if ctx.Err() != nil {
+			return
+		}
+		if err := ipfs.Provide(cid); err == nil {
+			d.mu.Lock()
+			d.announced++
+			d.mu.Unlock()
+		}
+	}
+}
+
+// status answers the state of the daemon as JSON.
+func (d *daemon) status(w http.ResponseWriter, _ *http.Request) {
+	d.mu.Lock()
+	status := DaemonStatus{
+		PeerID:     d.id,
+		Started:    d.started,
+		Uptime:     time.Since(d.started).Round(time.Second).String(),
+		Workspaces: d.workspaces,
+		Submitted:  len(d.cids),
+		Announced:  d.announced,
+	}
+	d.mu.Unlock()
+	status.Peers = ipfs.PeerCount()
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(status)
+}
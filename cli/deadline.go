@@ -0,0 +1,65 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/arken/ait/keysets"
+	"github.com/arken/ait/utils"
+)
+
+// submitDeadline is when the submission must stop by, set with --deadline.
+// The zero time is no deadline.
+var submitDeadline time.Time
+
+// deadlineExitCode is the exit status of a submission stopped at its deadline,
+// so job scripts can tell it apart from a failure.
+const deadlineExitCode = 2
+
+// errPastDeadline is returned by the steps of a submission stopped at its
+// deadline, once what remains to do was reported.
+var errPastDeadline = errors.New("the deadline passed")
+
+// pastDeadline returns whether the submission's deadline has passed.
+func pastDeadline() bool {
+	return !submitDeadline.IsZero() && time.Now().After(submitDeadline)
+}
+
+// stopHashingAtDeadline reports how far hashing the staged files got before
+// the deadline. The application and the CIDs of the hashed files are kept, so
+// submitting again picks up where it stopped.
+func stopHashingAtDeadline(err *keysets.DeadlineError) {
+	_ = os.RemoveAll(utils.KeysetsDir())
+	fmt.Printf(`The deadline passed while hashing the staged files, nothing was submitted:
+	Files hashed: %d of %d, their CIDs are kept
+	Files left to hash: %d
+Run "ait submit" again to hash the rest and submit, the application is kept.
+`, err.Added, err.Added+err.Remaining, err.Remaining)
+}
+
+// queueAtDeadline queues the submission of the keyset at ksPath when the
+// deadline passed before it was pushed, reports what remains and returns
+// errPastDeadline.
+func queueAtDeadline(q *utils.QueuedSubmission, ksPath string) error {
+	err := os.MkdirAll(utils.QueuePath, os.ModePerm)
+	if err == nil {
+		err = utils.CopyFile(ksPath, q.KeysetPath())
+	}
+	if err == nil {
+		err = q.Save()
+	}
+	utils.SubmissionCleanup()
+	if err != nil {
+		return fmt.Errorf("unable to queue the submission at the deadline: %v", err)
+	}
+	// The queue finishes the submission, not its journal.
+	finishJournal()
+	fmt.Printf(`The deadline passed before the keyset was pushed, the submission is only partly done:
+	Files hashed: done, "ait upload" announces those that weren't announced yet
+	Keyset committed to %v: queued as %v
+Run "ait queue flush" to finish it.
+`, q.Remote, q.ID)
+	return errPastDeadline
+}
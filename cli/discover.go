@@ -0,0 +1,112 @@
+package cli
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/arken/ait/apis/discovery"
+	"github.com/arken/ait/config"
+	"github.com/arken/ait/utils"
+)
+
+// discoverRemotes lists the official keyset repositories in the discovery
+// document of source and saves the ones the user picks as remotes, along with
+// the organization's default flags if the user wants them.
+func discoverRemotes(source string) {
+	doc, err := discovery.Fetch(source)
+	utils.CheckError(err)
+	if len(doc.Repositories) == 0 {
+		utils.FatalPrintln("The organization doesn't list any keyset repositories.")
+	}
+	name := doc.Organization
+	if name == "" {
+		name = source
+	}
+	fmt.Printf("Official keyset repositories of %v:\n", name)
+	for i, repo := range doc.Repositories {
+		fmt.Printf("  [%d] %v  %v\n", i+1, repo.Name, repo.URL)
+		if repo.Description != "" {
+			fmt.Printf("      %v\n", repo.Description)
+		}
+		if len(repo.Categories) > 0 {
+			fmt.Printf("      Categories: %v\n", strings.Join(repo.Categories, ", "))
+		}
+		if repo.Policy != "" {
+			fmt.Printf("      Policy: %v\n", repo.Policy)
+		}
+	}
+	fmt.Print("Save which as remotes? (ie 1,3 or all, nothing for none) ")
+	for _, i := range parseSelection(utils.ReadAnswer(), len(doc.Repositories)) {
+		repo := doc.Repositories[i]
+		validateURL(repo.URL)
+		addRemote(repo.Name, repo.URL, config.RemoteOptions{})
+	}
+
+	if len(doc.EmailDomains) > 0 {
+		saveEmailDomains(name, doc.EmailDomains)
+	}
+
+	if len(doc.Defaults) == 0 {
+		return
+	}
+	fmt.Println("The organization recommends these default flags:")
+	for command, flags := range doc.Defaults {
+		fmt.Printf("  ait %v %v\n", command, flags)
+	}
+	fmt.Print("Use them for the commands that don't have defaults yet? (y/n) ")
+	if !strings.EqualFold(utils.ReadAnswer(), "y") {
+		return
+	}
+	if config.Global.Defaults == nil {
+		config.Global.Defaults = make(map[string]string)
+	}
+	for command, flags := range doc.Defaults {
+		if existing, ok := config.Global.Defaults[command]; ok {
+			fmt.Printf("Kept your defaults for %v: %v\n", command, existing)
+			continue
+		}
+		config.Global.Defaults[command] = flags
+	}
+}
+
+// saveEmailDomains keeps the domains the organization expects the email
+// addresses of its members at, to suggest them when the identity is entered.
+func saveEmailDomains(organization string, domains []string) {
+	git := &config.Global.Git
+	for _, domain := range domains {
+		if !utils.InEmailDomains("@"+domain, git.EmailDomains) {
+			git.EmailDomains = append(git.EmailDomains, domain)
+		}
+	}
+	fmt.Printf("%v expects the email addresses of its members at %v.\n", organization,
+		strings.Join(domains, " or "))
+	if git.Email != "" && !utils.InEmailDomains(git.Email, domains) {
+		fmt.Printf("Yours is %v, \"ait identity email <address>\" corrects it.\n", git.Email)
+	}
+}
+
+// parseSelection returns the indexes of the items picked from a list of n by
+// their comma separated numbers, or "all". Invalid numbers are skipped.
+func parseSelection(input string, n int) (picked []int) {
+	input = strings.TrimSpace(input)
+	if strings.EqualFold(input, "all") {
+		for i := 0; i < n; i++ {
+			picked = append(picked, i)
+		}
+		return picked
+	}
+	for _, field := range strings.Split(input, ",") {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+		i, err := strconv.Atoi(field)
+		if err != nil || i < 1 || i > n {
+			fmt.Printf("Skipping %q, it isn't one of the listed numbers.\n", field)
+			continue
+		}
+		picked = append(picked, i-1)
+	}
+	return picked
+}
@@ -0,0 +1,132 @@
+package cli
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/arken/ait/config"
+	"github.com/arken/ait/ipfs"
+	"github.com/arken/ait/utils"
+
+	"github.com/DataDrake/cli-ng/v2/cmd"
+)
+
+// Doctor checks that ait is set up to work, without starting the node or
+// changing anything, and says how to fix what isn't.
+var Doctor = cmd.Sub{
+	Name:  "doctor",
+	Short: "Check the config, IPFS repository, ports, git identity and GitHub token.",
+	Run:   DoctorRun,
+}
+
+// DoctorRun runs every check and prints a pass/fail matrix with the fixes of
+// the failed checks. It exits with an error if any check failed.
+func DoctorRun(_ *cmd.Root, _ *cmd.Sub) {
+	checks := []ipfs.Check{checkConfig()}
+	checks = append(checks, ipfs.CheckRepo()...)
+	checks = append(checks, checkIdentity()...)
+	checks = append(checks, checkToken())
+
+	if failed := printChecks(checks); failed > 0 {
+		utils.FatalPrintf("\n%d of %d checks failed.\n", failed, len(checks))
+	}
+	fmt.Println("\nEverything looks fine.")
+}
+
+// checkConfig checks that the settings in effect, with the workspace's
+// overrides and the environment, are valid.
+func checkConfig() ipfs.Check {
+	check := ipfs.Check{Name: "Config (" + config.Path + ")"}
+	if _, err := os.Stat(config.WorkspaceConfigPath); err == nil {
+		check.Name = fmt.Sprintf("Config (%v, %v)", config.Path, config.WorkspaceConfigPath)
+	}
+	if err := config.Validate(config.Global); err != nil {
+		check.Detail = err.Error()
+		check.Fix = "correct the setting with \"ait config set <key> <value>\", or check the AIT_* " +
+			"environment variables"
+		return check
+	}
+	check.OK, check.Detail = true, "valid"
+	return check
+}
+
+// checkIdentity checks that the name and email submissions are made under
+// are set.
+func checkIdentity() []ipfs.Check {
+	var checks []ipfs.Check
+	for _, setting := range []struct{ key, value, example string }{
+		{"git.name", config.Global.Git.Name, "\"Your Name\""},
+		{"git.email", config.Global.Git.Email, "you@example.org"},
+	} {
+		check := ipfs.Check{Name: "Git identity (" + setting.key + ")", OK: setting.value != "",
+			Detail: setting.value}
+		if !check.OK {
+			check.Detail = "not set"
+			check.Fix = fmt.Sprintf("ait config set --global %v %v", setting.key, setting.example)
+		}
+		checks = append(checks, check)
+	}
+	return checks
+}
+
+// tokenFix is how to replace a GitHub token that doesn't work.
+const tokenFix = "create a token with the public_repo scope and give it through AIT_GIT_TOKEN, or " +
+	"replace the github-token entry of ait in the system keychain"
+
+// checkToken checks that the GitHub token, if there's one, is still valid and
+// has the scopes submissions need.
+func checkToken() ipfs.Check {
+	check := ipfs.Check{Name: "GitHub token"}
+	token, source := os.Getenv("AIT_GIT_TOKEN"), "AIT_GIT_TOKEN"
+	if token == "" {
+		token, source = config.GitHubToken(), "saved"
+	}
+	if token == "" {
+		check.OK, check.Detail = true, "none saved, ait asks for one when submitting"
+		return check
+	}
+	client := utils.PinnedClient(config.Global.Trust.Hosts)
+	client.Timeout = netcheckTimeout
+	req, err := http.NewRequest(http.MethodGet, "https://api.github.com/user", nil)
+	if err != nil {
+		check.Detail = err.Error()
+		return check
+	}
+	req.Header.Set("Authorization", "token "+token)
+	resp, err := client.Do(req)
+	if err != nil {
+		check.Detail = err.Error()
+		check.Fix = "run \"ait netcheck\" to find out why GitHub can't be reached"
+		return check
+	}
+	resp.Body.Close()
+	if resp.StatusCode == http.StatusUnauthorized {
+		check.Detail = source + " token rejected: " + resp.Status
+		check.Fix = tokenFix
+		return check
+	}
+	if resp.StatusCode != http.StatusOK {
+		check.Detail = resp.Status
+		return check
+	}
+	// Fine-grained tokens don't report scopes, their permissions are per
+	// repository.
+	scopes, ok := resp.Header["X-Oauth-Scopes"]
+	if !ok {
+		check.OK = true
+		check.Detail = source + " token valid, its repository permissions can't be checked"
+		return check
+	}
+	list := strings.Join(scopes, ",")
+	for _, scope := range strings.Split(list, ",") {
+		if scope = strings.TrimSpace(scope); scope == "repo" || scope == "public_repo" {
+			check.OK, check.Detail = true, fmt.Sprintf("%v token valid, scopes: %v", source, list)
+			return check
+		}
+	}
+	check.Detail = fmt.Sprintf("%v token lacks the public_repo scope, scopes: %v", source, list)
+	check.Fix = tokenFix
+	return check
+}
@@ -0,0 +1,39 @@
+package cli
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/arken/ait/apis/doi"
+	"github.com/arken/ait/config"
+	"github.com/arken/ait/types"
+	"github.com/arken/ait/utils"
+)
+
+// mintDOI registers the submitted dataset with the configured DOI registry
+// and records the DOI in the submission. It should run after the gateway
+// links are added so the DOI can resolve to the dataset. Failures are only
+// reported, a DOI can be minted by hand later.
+func mintDOI(s *utils.Submission, app *types.ApplicationContents, keyset []byte) {
+	landing := s.Remote
+	if len(s.Links) > 0 {
+		landing = s.Links[0].URL
+	}
+	fmt.Printf("Registering the dataset with %v...\n", config.Global.DOI.Registry)
+	id, err := doi.Mint(doi.Metadata{
+		Title:       app.Title,
+		Description: app.Commit,
+		Creators:    []string{config.Global.Git.Name},
+		Published:   s.Time,
+		URL:         landing,
+		Root:        s.Root,
+		KeysetName:  filepath.Base(s.Path),
+		Keyset:      keyset,
+	})
+	if err != nil {
+		fmt.Println("Unable to mint a DOI for the dataset:", err)
+		return
+	}
+	s.DOI = id
+	fmt.Println("Minted DOI", doi.URL(id))
+}
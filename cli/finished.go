@@ -0,0 +1,59 @@
+package cli
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/arken/ait/config"
+	"github.com/arken/ait/notify"
+	"github.com/arken/ait/utils"
+)
+
+// longOperations names the commands that can take hours unattended, which
+// notify the desktop when they finish or fail.
+var longOperations = map[string]string{
+	"stage":  "Staging",
+	"submit": "Submission",
+	"pull":   "Pull",
+	"upload": "Upload",
+}
+
+// watchOperation arranges for the desktop to be notified when the named
+// command fails, and returns a function to call once it succeeds. Nothing is
+// shown unless enabled, or for operations quicker than OperationsAfter.
+func watchOperation(name string) func() {
+	operation, ok := longOperations[name]
+	if !ok || !config.Global.Notify.Operations {
+		return func() {}
+	}
+	started := time.Now()
+	before := utils.BeforeFatal
+	utils.BeforeFatal = func(msg string) {
+		if before != nil {
+			before(msg)
+		}
+		operationFinished(operation, started, msg)
+	}
+	return func() {
+		operationFinished(operation, started, "")
+	}
+}
+
+// operationFinished shows a desktop notification that the operation succeeded,
+// or failed with msg.
+func operationFinished(operation string, started time.Time, msg string) {
+	elapsed := time.Since(started)
+	after, err := time.ParseDuration(config.Global.Notify.OperationsAfter)
+	if err == nil && elapsed < after {
+		return
+	}
+	title := operation + " finished"
+	message := fmt.Sprintf("%v completed after %v.", operation, elapsed.Round(time.Second))
+	if msg != "" {
+		title = operation + " failed"
+		message = fmt.Sprintf("%v failed after %v: %v", operation, elapsed.Round(time.Second), msg)
+	}
+	if err := notify.Desktop(title, message); err != nil {
+		fmt.Println(err)
+	}
+}
@@ -0,0 +1,167 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/arken/ait/apis/forge"
+	aitgh "github.com/arken/ait/apis/github"
+	"github.com/arken/ait/display"
+	"github.com/arken/ait/types"
+	"github.com/arken/ait/utils"
+)
+
+// submitForge makes the submission to a repository on GitLab or Gitea,
+// committing the keyset to it or proposing it in a pull request from the
+// user's fork, returning false if it was aborted. It's journaled, queued at
+// the deadline and split like submissions to GitHub.
+func submitForge(url, kind string, isPR, isIssue bool, flags *SubmitFlags) (bool, error) {
+	if isIssue || flags.Also != "" {
+		return false, fmt.Errorf("keysets are submitted to %v repositories in a commit or a pull request, "+
+			"without --issue or --also", kind)
+	}
+	provider, err := forge.New(kind, url)
+	if err != nil {
+		return false, err
+	}
+	canPush, err := provider.CheckAccess()
+	if err != nil {
+		return false, err
+	}
+	if !canPush && !isPR {
+		if isPR, err = promptDoPullRequest(url); err != nil {
+			return false, err
+		} else if !isPR {
+			fmt.Println("Submission aborted.")
+			return false, nil
+		}
+	}
+	if isPR {
+		fmt.Println("You chose to submit via pull request.")
+		if err = provider.Fork(); err != nil {
+			return false, err
+		}
+	}
+	parts, err := splitStaged(flags)
+	if err != nil {
+		return false, err
+	}
+	if parts != nil && !isPR {
+		return false, errors.New("only pull request submissions can be split")
+	}
+	// A resumed submission keeps the application it was journaled with, and
+	// a batch submission the one given once for every remote.
+	if submitJournal == nil && !batchSubmitting {
+		display.ShowApplication()
+	}
+	app := display.ReadApplication()
+	if !app.IsValid() {
+		fmt.Printf("Exiting Submission because the application has no %v.\n", app.Missing())
+		fmt.Println("Submission aborted.")
+		return false, nil
+	}
+	// Resumed, flushed and batch submissions ran the hook when they started.
+	if submitJournal == nil && queuedKeyset == "" {
+		if err = preSubmitHook(app, url); err != nil {
+			return false, withCleanup(err)
+		}
+	}
+	if parts != nil {
+		if err = submitParts(url, forgeParts{provider}, app, parts, flags); err != nil {
+			return false, err
+		}
+		return true, nil
+	}
+
+	var exists bool
+	if submitJournal != nil {
+		exists = submitJournal.Exists
+	} else if app, exists, err = resolveForgeConflict(provider, kind, app, flags); err != nil {
+		return false, err
+	}
+	// Forges only replace keysets, they're never amended.
+	ksPath, committed, err := prepareKeyset(url, isPR, false, flags, app, true, exists)
+	if err != nil {
+		return false, err
+	}
+	spooled := spoolGitStep(url, isPR, false, flags, app, ksPath)
+	var commit string
+	if stepDone(utils.StepCommitted) {
+		commit = submitJournal.CommitSHA
+	} else {
+		if aitgh.SigningEnabled() {
+			fmt.Printf("Commits on %v aren't signed, only those on GitHub are.\n", kind)
+		}
+		var files map[string]string
+		if files, err = keysetFiles(committed, app.FullPath()); err == nil {
+			commit, err = provider.CommitFile(files[app.FullPath()], app.FullPath(), app.Commit, isPR)
+		}
+		// The forges commit a file at a time, the signature follows the keyset.
+		for repoPath, localPath := range files {
+			if err == nil && repoPath != app.FullPath() {
+				_, err = provider.CommitFile(localPath, repoPath, app.Commit, isPR)
+			}
+		}
+	}
+	if err = spooled(err); err != nil {
+		return false, withCleanup(err)
+	}
+	if submitJournal != nil {
+		submitJournal.CommitSHA = commit
+		recordStep(utils.StepCommitted)
+	}
+	submission, keyset, err := recordSubmission(url, ksPath, commit, app, flags)
+	if err != nil {
+		return false, err
+	}
+	if isPR && stepDone(utils.StepPullRequest) {
+		submission.PullRequest, submission.PRNumber = submitJournal.PRURL, submitJournal.PRNumber
+	} else if isPR {
+		body := app.PRBody
+		if body == "" {
+			body = app.Commit
+		}
+		if submission.PullRequest, submission.PRNumber, err = provider.OpenPR(app.Title, body); err != nil {
+			return false, err
+		}
+		if submitJournal != nil {
+			submitJournal.PRURL, submitJournal.PRNumber = submission.PullRequest, submission.PRNumber
+			recordStep(utils.StepPullRequest)
+		}
+		fmt.Println("\nYour pull request can be found at:", submission.PullRequest)
+	}
+	completeSubmission(submission, keyset)
+	return true, nil
+}
+
+// resolveForgeConflict asks what to do when a file is already at the path of
+// the application's keyset in the repository: replace it, or rename the
+// keyset. It returns the application, renamed or not, and whether the file at
+// its path exists.
+func resolveForgeConflict(provider forge.Provider, kind string, app *types.ApplicationContents,
+	flags *SubmitFlags) (*types.ApplicationContents, bool, error) {
+	for {
+		exists, err := provider.Exists(app.FullPath())
+		if err != nil {
+			return app, false, err
+		}
+		if !exists || flags.KsMode == "overwrite" {
+			return app, exists, nil
+		}
+		if flags.KsMode == "amend" || utils.NonInteractive {
+			return app, true, fmt.Errorf("a file already exists at %v in the repo, on %v it can only be replaced "+
+				"with --ks-mode overwrite", app.FullPath(), kind)
+		}
+		fmt.Printf("A file already exists at %v in the repo.\n"+
+			"Do you want to overwrite it (o), rename yours (r), or abort (any other key)? ", app.FullPath())
+		input := strings.ToLower(utils.ReadAnswer())
+		if input == "o" {
+			return app, true, nil
+		} else if input != "r" {
+			return app, true, errAborted
+		}
+		display.ShowApplication()
+		app = display.ReadApplication()
+	}
+}
@@ -0,0 +1,62 @@
+package cli
+
+import (
+	"strings"
+
+	"github.com/arken/ait/config"
+	"github.com/arken/ait/display"
+	"github.com/arken/ait/keysets"
+	"github.com/arken/ait/types"
+	"github.com/arken/ait/utils"
+)
+
+// keysetWriter returns the writer of the named keyset format, the one of the
+// config if format is empty.
+func keysetWriter(format string) keysets.Writer {
+	if format == "" {
+		format = config.Global.General.KeysetFormat
+	}
+	w, err := keysets.FormatWriter(format)
+	utils.CheckError(err)
+	return w
+}
+
+// useSubmitFormat makes the keysets submitted be written in the named format,
+// the one of the config if format is empty. Applications name keysets with
+// its extension.
+func useSubmitFormat(format string) {
+	if format == "car" {
+		utils.FatalPrintln("CAR manifests are written with \"ait keyset export\", " +
+			"keysets are submitted as ksv, json or csv.")
+	}
+	display.KeysetExtension = keysetWriter(format).Extension()
+}
+
+// writerOf returns the writer of the format a keyset named name is submitted
+// in, told by its extension.
+func writerOf(name string) keysets.Writer {
+	for _, w := range keysets.Formats {
+		if strings.HasSuffix(name, w.Extension()) {
+			return w
+		}
+	}
+	return keysets.Formats[keysets.DefaultFormat]
+}
+
+// isDefaultFormat returns whether the keyset of the application is submitted
+// as repositories read keysets, which is the only one that can be amended.
+func isDefaultFormat(app *types.ApplicationContents) bool {
+	return writerOf(app.KsName) == keysets.Formats[keysets.DefaultFormat]
+}
+
+// exportSubmitted writes the keyset generated at ksPath in the format of the
+// application's keyset, and returns the path of the file to commit. Keysets
+// submitted as repositories read them are committed as generated.
+func exportSubmitted(ksPath string, app *types.ApplicationContents) (string, error) {
+	if isDefaultFormat(app) {
+		return ksPath, nil
+	}
+	w := writerOf(app.KsName)
+	dest := keysets.WithExtension(ksPath, w)
+	return dest, keysets.Export(ksPath, dest, w)
+}
@@ -0,0 +1,151 @@
+package cli
+
+import (
+	"fmt"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/arken/ait/config"
+	"github.com/arken/ait/ipfs"
+	"github.com/arken/ait/types"
+	"github.com/arken/ait/utils"
+)
+
+// maxPrintedLinks is the number of file links printed after a submission,
+// the rest are only in the history and --json output.
+const maxPrintedLinks = 10
+
+// addDatasetRoot builds a directory of the submitted files so the dataset can
+// be referenced by a single CID, and returns the path of each file in it. With
+// roCrate the directory is an RO-Crate of the staged files, otherwise it holds
+// each file under its keyset name, and files sharing a name after the first
+// are left out. The IPFS subsystem must be initialized. Failures are only
+// reported.
+func addDatasetRoot(s *utils.Submission, app *types.ApplicationContents, roCrate bool) map[string]string {
+	if config.Global.General.Gateway == "" && !roCrate {
+		return nil
+	}
+	named := make(map[string]string)
+	var err error
+	if roCrate {
+		named, err = roCrateEntries(app)
+		if err != nil {
+			fmt.Println("Unable to package the dataset as an RO-Crate:", err)
+			named = make(map[string]string)
+		}
+	}
+	if len(named) == 0 {
+		roCrate = false
+		for _, entry := range s.Entries {
+			if _, taken := named[entry.Name]; !taken {
+				named[entry.Name] = entry.CID
+			}
+		}
+	}
+	// Files added through the workspace link are read through it.
+	_, err = ipfs.LinkWorkdir()
+	if err == nil {
+		s.Root, err = ipfs.BuildRoot(named)
+	}
+	if err == nil {
+		err = ipfs.Provide(s.Root)
+	}
+	if err != nil {
+		fmt.Println("Unable to create a root for the dataset:", err)
+		return nil
+	}
+	s.ROCrate = roCrate
+	return named
+}
+
+// roCrateEntries describes the staged files in an RO-Crate metadata file,
+// adds it, and returns the path -> CID of every file in the crate.
+func roCrateEntries(app *types.ApplicationContents) (map[string]string, error) {
+	contents, err := utils.ReadStaged()
+	if err != nil {
+		return nil, err
+	}
+	files := hashStaged(contents)
+	catalog, err := utils.ReadCatalog()
+	if err != nil {
+		return nil, err
+	}
+	metadata, err := utils.ReadEntryMetadata()
+	if err != nil {
+		return nil, err
+	}
+	crate, err := utils.ROCrateMetadata(utils.ROCrateDataset{
+		Name:        app.Title,
+		Description: app.Commit,
+		Author:      config.Global.Git.Name,
+		Email:       config.Global.Git.Email,
+		Published:   time.Now(),
+		Catalog:     catalog,
+		Metadata:    metadata,
+	}, files)
+	if err != nil {
+		return nil, err
+	}
+	metadataCID, err := ipfs.AddBytes(crate)
+	if err != nil {
+		return nil, err
+	}
+	entries := map[string]string{utils.ROCrateMetadataFile: metadataCID}
+	for _, f := range files {
+		entries[filepath.ToSlash(filepath.Clean(f.Path))] = f.CID
+	}
+	return entries, nil
+}
+
+// addGatewayLinks records public gateway URLs for the dataset root and each
+// submitted file. Files in the root (path -> CID) are linked through it, the
+// rest by CID.
+func addGatewayLinks(s *utils.Submission, inRoot map[string]string) {
+	base := strings.TrimSuffix(config.Global.General.Gateway, "/")
+	if base == "" {
+		return
+	}
+	paths := make(map[string]string, len(inRoot))
+	for p, cid := range inRoot {
+		if existing, ok := paths[cid]; !ok || p < existing {
+			paths[cid] = p
+		}
+	}
+	if s.Root != "" {
+		s.Links = append(s.Links, utils.GatewayLink{URL: base + "/ipfs/" + s.Root + "/"})
+	}
+	for _, entry := range s.Entries {
+		u := fmt.Sprintf("%v/ipfs/%v?filename=%v", base, entry.CID, url.QueryEscape(entry.Name))
+		if p, ok := paths[entry.CID]; ok && s.Root != "" {
+			segments := strings.Split(p, "/")
+			for i := range segments {
+				segments[i] = url.PathEscape(segments[i])
+			}
+			u = base + "/ipfs/" + s.Root + "/" + strings.Join(segments, "/")
+		}
+		s.Links = append(s.Links, utils.GatewayLink{Name: entry.Name, URL: u})
+	}
+}
+
+// printGatewayLinks lists the dataset's gateway URLs.
+func printGatewayLinks(s *utils.Submission) {
+	if len(s.Links) == 0 {
+		return
+	}
+	fmt.Println("Your files can be linked through the public gateway:")
+	files := s.Links
+	if s.Root != "" {
+		fmt.Println("\tDataset:", s.Links[0].URL)
+		files = s.Links[1:]
+	}
+	for i, link := range files {
+		if i == maxPrintedLinks {
+			fmt.Printf("\t...and %d more, listed in %v.\n", len(files)-i,
+				filepath.Join(utils.HistoryPath, s.ID+".json"))
+			break
+		}
+		fmt.Printf("\t%v: %v\n", link.Name, link.URL)
+	}
+}
@@ -0,0 +1,172 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/arken/ait/display"
+	"github.com/arken/ait/ipfs"
+	"github.com/arken/ait/types"
+	"github.com/arken/ait/utils"
+
+	"github.com/DataDrake/cli-ng/v2/cmd"
+	humanize "github.com/dustin/go-humanize"
+)
+
+// GC frees the space of the IPFS repository nothing AIT tracks still uses.
+var GC = cmd.Sub{
+	Name:  "gc",
+	Short: "Unpin and remove the files no staged file or submitted keyset entry references.",
+	Args:  &GCArgs{},
+	Flags: &GCFlags{},
+	Run:   GCRun,
+}
+
+// GCArgs handles the specific arguments for the gc command.
+type GCArgs struct {
+}
+
+// GCFlags handles the specific flags for the gc command.
+type GCFlags struct {
+	DryRun bool `short:"n" long:"dry-run" desc:"List what would be unpinned without changing anything"`
+	Yes    bool `short:"y" long:"yes" desc:"Don't ask for confirmation"`
+}
+
+// GCRun unpins the CIDs that aren't referenced by the staged files or the
+// submissions of any workspace using the IPFS repository, then garbage
+// collects every unpinned block.
+func GCRun(_ *cmd.Root, c *cmd.Sub) {
+	flags := c.Flags.(*GCFlags)
+	prettyIPFSInit()
+	referenced, workspaces := referencedCIDs()
+	pins, err := ipfs.RecursivePins()
+	utils.CheckError(err)
+	unreferenced := unreferencedPins(pins, referenced)
+	fmt.Printf("%d of %d pinned CID(s) aren't referenced by the staged files or submissions of %d workspace(s).\n",
+		len(unreferenced), len(pins), workspaces)
+	if flags.DryRun {
+		for _, pin := range unreferenced {
+			fmt.Println("\t" + pin)
+		}
+		return
+	}
+	if len(unreferenced) > 0 && !flags.Yes && !promptGC(len(unreferenced)) {
+		fmt.Println("Nothing was changed.")
+		return
+	}
+
+	unpinned, kept := 0, 0
+	bar := display.NewProgress("Unpinning", int64(len(unreferenced)), false)
+	for _, pin := range unreferenced {
+		if err := ipfs.Unpin(pin); err == nil {
+			unpinned++
+		} else if owners, _ := ipfs.Protected(pin); len(owners) > 0 {
+			kept++
+		} else {
+			fmt.Printf("Unable to unpin %v: %v\n", pin, err)
+		}
+		bar.Add(1)
+	}
+	fmt.Printf("Unpinned %d CID(s).\n", unpinned)
+	if kept > 0 {
+		fmt.Printf("%d CID(s) are protected until their submission is merged and replicated.\n", kept)
+	}
+
+	before, _, _ := ipfs.StorageUsage()
+	fmt.Println("Garbage collecting...")
+	utils.CheckError(ipfs.CollectGarbage())
+	after, _, _ := ipfs.StorageUsage()
+	if after < before {
+		fmt.Printf("Freed %v, the IPFS repository now uses %v.\n",
+			humanize.Bytes(before-after), humanize.Bytes(after))
+	} else {
+		fmt.Printf("The IPFS repository uses %v.\n", humanize.Bytes(after))
+	}
+}
+
+// unreferencedPins returns the pins that aren't referenced, in their order.
+func unreferencedPins(pins []string, referenced map[string]bool) []string {
+	var unreferenced []string
+	for _, pin := range pins {
+		if !referenced[ipfs.NormalizeCID(pin)] {
+			unreferenced = append(unreferenced, pin)
+		}
+	}
+	return unreferenced
+}
+
+// referencedCIDs returns the CIDs gc keeps, and the number of workspaces they
+// were gathered from: the entries and dataset roots of every submission and
+// the staged files of every workspace the IPFS repository knows of, the files
+// a replication plan assigned to this node, and those ait pinned to publish,
+// upload or refetch them. Staged files are hashed,
+// those that didn't change since they were last hashed are taken from the
+// hash cache.
+func referencedCIDs() (map[string]bool, int) {
+	referenced := make(map[string]bool)
+	keep := func(hash string) {
+		if hash != "" {
+			referenced[ipfs.NormalizeCID(hash)] = true
+		}
+	}
+	known, err := ipfs.ReadWorkspaces()
+	utils.CheckError(err)
+	// The files of a workspace are hashed through its link, which the
+	// current workspace may not have yet.
+	links := make(map[string]string)
+	for _, w := range known {
+		links[w.Path] = w.LinkPath()
+	}
+	if wd, err := os.Getwd(); err == nil && utils.IsAITRepo() {
+		link, err := ipfs.LinkWorkdir()
+		utils.CheckError(err)
+		links[wd] = link
+	}
+
+	for dir, link := range links {
+		history, err := utils.ReadHistoryIn(filepath.Join(dir, utils.HistoryPath))
+		utils.CheckError(err)
+		for _, s := range history {
+			for _, entry := range s.Entries {
+				keep(entry.CID)
+			}
+			keep(s.Root)
+		}
+		staged := types.NewBasicStringSet()
+		if file, err := os.Open(filepath.Join(dir, utils.AddedFilesPath)); err == nil {
+			utils.FillSet(staged, file)
+			file.Close()
+		}
+		err = staged.ForEach(func(path string) error {
+			cid, err := ipfs.HashCached(filepath.Join(link, path))
+			if err != nil && !os.IsNotExist(err) {
+				return fmt.Errorf("unable to hash the staged file %v of %v: %v", path, dir, err)
+			}
+			keep(cid)
+			return nil
+		})
+		utils.CheckError(err)
+	}
+
+	planned, err := ipfs.ReadPlanPins()
+	utils.CheckError(err)
+	for _, cid := range planned {
+		keep(cid)
+	}
+	pinned, err := ipfs.ReadPins()
+	utils.CheckError(err)
+	for cid := range pinned {
+		keep(cid)
+	}
+	return referenced, len(links)
+}
+
+// promptGC asks the user to confirm unpinning the unreferenced CIDs.
+func promptGC(unreferenced int) bool {
+	fmt.Printf("This unpins the %d CID(s) and removes every unpinned block from the IPFS "+
+		"repository.\nFiles ait pinned itself, ie published with \"ait key publish\" or uploaded, "+
+		"are kept, only those pinned by other means are unpinned. Continue? (y/[n]) ", unreferenced)
+	return strings.ToLower(utils.ReadAnswer()) == "y"
+}
@@ -0,0 +1,25 @@
+package cli
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/arken/ait/ipfs"
+)
+
+func TestUnreferencedPins(t *testing.T) {
+	v0 := "QmYwAPJzv5CZsnA625s3Xf2nemtYgPpHdWEz79ojWnPbdG"
+	pins := []string{"bafyplanned", v0, "bafystray", "bafypublished"}
+	referenced := map[string]bool{
+		"bafyplanned":         true,
+		"bafypublished":       true,
+		ipfs.NormalizeCID(v0): true,
+	}
+	// Pins are compared by their CIDv1, however they were listed.
+	if unreferenced := unreferencedPins(pins, referenced); !reflect.DeepEqual(unreferenced, []string{"bafystray"}) {
+		t.Errorf("unreferencedPins() = %v, expected only bafystray", unreferenced)
+	}
+	if unreferenced := unreferencedPins(pins, nil); !reflect.DeepEqual(unreferenced, pins) {
+		t.Errorf("unreferencedPins() = %v, expected every pin without references", unreferenced)
+	}
+}
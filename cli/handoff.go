@@ -0,0 +1,153 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+
+	"github.com/arken/ait/ipfs"
+	"github.com/arken/ait/types"
+	"github.com/arken/ait/utils"
+
+	"github.com/DataDrake/cli-ng/v2/cmd"
+)
+
+// Handoff passes the staged files of this workspace to a colleague so they
+// can continue the submission from their machine without re-hashing.
+var Handoff = cmd.Sub{
+	Name:  "handoff",
+	Alias: "ho",
+	Short: "Hand your staged files off to another machine.",
+	Args:  &HandoffArgs{},
+	Run:   HandoffRun,
+}
+
+// HandoffArgs handles the specific arguments for the handoff command.
+type HandoffArgs struct {
+	Action string   `desc:"The operation to perform: send, receive"`
+	Args   []string `zero:"yes" desc:"The code printed by send, for receive"`
+}
+
+const handoffUsage = `	ait handoff send             # Publish the staged files and print a one-time code
+	ait handoff receive <code>   # Stage the files handed off with the code`
+
+// handoffBundle is the encrypted object published by ait handoff send.
+type handoffBundle struct {
+	Files []utils.HandoffEntry `json:"files"`
+}
+
+// HandoffRun dispatches to the requested handoff operation.
+func HandoffRun(_ *cmd.Root, c *cmd.Sub) {
+	args := c.Args.(*HandoffArgs)
+	switch args.Action {
+	case "send":
+		handoffSend()
+	case "receive":
+		if len(args.Args) < 1 {
+			utils.FatalPrintln("Expected the code printed by \"ait handoff send\":\n" + handoffUsage)
+		}
+		handoffReceive(args.Args[0])
+	default:
+		utils.FatalPrintln("Unknown handoff operation \"" + args.Action + "\":\n" + handoffUsage)
+	}
+}
+
+// handoffSend hashes the staged files, publishes them as an encrypted bundle
+// and serves it until the user interrupts the command. The bundle is unpinned
+// afterwards so the code can't be reused.
+func handoffSend() {
+	contents, err := utils.ReadStagedSorted()
+	utils.CheckError(err)
+	if contents.Size() == 0 {
+		utils.FatalPrintln("No files are currently staged, nothing to hand off.")
+	}
+	prettyIPFSInit()
+	bundle := handoffBundle{Files: hashStaged(contents)}
+
+	data, err := json.Marshal(bundle)
+	utils.CheckError(err)
+	sealed, secret, err := utils.Seal(data)
+	utils.CheckError(err)
+	hash, err := ipfs.AddBytes(sealed)
+	utils.CheckError(err)
+	utils.CheckError(ipfs.Provide(hash))
+
+	fmt.Printf("Handing off %d staged file(s). On the other machine, inside a copy of this dataset, run:\n", len(bundle.Files))
+	fmt.Printf("\n\tait handoff receive %v.%v\n\n", hash, secret)
+	fmt.Println("Keep this command running until they're done, then press Ctrl-C.")
+
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, os.Interrupt, syscall.SIGTERM)
+	<-sig
+	utils.CheckError(ipfs.Unpin(hash))
+	fmt.Println("\nHandoff closed.")
+}
+
+// hashStaged returns the CID and size of each staged file, reusing the CIDs
+// of files received through a handoff. The IPFS subsystem must be initialized.
+func hashStaged(contents types.StringSet) (entries []utils.HandoffEntry) {
+	link, err := ipfs.LinkWorkdir()
+	utils.CheckError(err)
+	received, err := utils.ReadHandoff()
+	utils.CheckError(err)
+	err = contents.ForEach(func(path string) error {
+		size, err := utils.GetFileSize(path)
+		if err != nil {
+			return err
+		}
+		cid, ok := utils.HandoffCID(received, path)
+		if !ok {
+			cid, err = ipfs.HashCached(filepath.Join(link, path))
+			if err != nil {
+				return err
+			}
+		}
+		entries = append(entries, utils.HandoffEntry{Path: path, CID: cid, Size: size})
+		return nil
+	})
+	utils.CheckError(err)
+	return entries
+}
+
+// handoffReceive fetches and decrypts a bundle and stages its files along with
+// their known CIDs.
+func handoffReceive(code string) {
+	parts := strings.SplitN(strings.TrimSpace(code), ".", 2)
+	if len(parts) != 2 {
+		utils.FatalPrintln("Invalid handoff code \"" + code + "\".")
+	}
+	prettyIPFSInit()
+	fmt.Println("Fetching handoff...")
+	sealed, err := ipfs.Cat(parts[0])
+	utils.CheckError(err)
+	data, err := utils.Unseal(sealed, parts[1])
+	utils.CheckError(err)
+	bundle := handoffBundle{}
+	utils.CheckError(json.Unmarshal(data, &bundle))
+
+	var staged []utils.HandoffEntry
+	for _, entry := range bundle.Files {
+		size, err := utils.GetFileSize(entry.Path)
+		if err != nil {
+			fmt.Printf("Skipping %v, it doesn't exist here.\n", entry.Path)
+			continue
+		}
+		if size != entry.Size {
+			fmt.Printf("Skipping %v, it differs from the handed off file.\n", entry.Path)
+			continue
+		}
+		staged = append(staged, entry)
+	}
+	utils.CheckError(utils.UpdateStaged(func(contents *types.ThreadSafeStringSet) error {
+		for _, entry := range staged {
+			contents.Add(entry.Path)
+		}
+		return nil
+	}))
+	utils.CheckError(utils.WriteHandoff(staged))
+	fmt.Printf("%d of %d handed off file(s) staged.\n", len(staged), len(bundle.Files))
+}
@@ -0,0 +1,53 @@
+package cli
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/arken/ait/types"
+	"github.com/arken/ait/utils"
+)
+
+// sortedPaths returns the paths of the set in order.
+func sortedPaths(set types.StringSet) []string {
+	var paths []string
+	_ = set.ForEach(func(path string) error {
+		paths = append(paths, path)
+		return nil
+	})
+	sort.Strings(paths)
+	return paths
+}
+
+// preAddHook runs the pre-add hook with the files about to be staged.
+func preAddHook(contents types.StringSet) error {
+	if contents.Size() == 0 {
+		return nil
+	}
+	return utils.RunHook(utils.HookPreAdd, sortedPaths(contents), "")
+}
+
+// preSubmitHook runs the pre-submit hook with the staged files and the
+// keyset path of the application submitted to the remotes at urls, returning
+// the error the submission is aborted with if it fails. It runs before the keyset is generated, so the
+// metadata the hook attaches to files is submitted with them.
+func preSubmitHook(app *types.ApplicationContents, urls ...string) error {
+	return utils.RunHook(utils.HookPreSubmit, stagedList(), app.FullPath(),
+		"AIT_REMOTE="+strings.Join(urls, " "))
+}
+
+// postSubmitHook runs the post-submit hook with the staged files and the
+// keyset path of the submission s. The submission succeeded already, so a
+// failing hook is only reported.
+func postSubmitHook(s *utils.Submission) {
+	err := utils.RunHook(utils.HookPostSubmit, stagedList(), s.Path,
+		"AIT_REMOTE="+s.Remote,
+		"AIT_SUBMISSION="+s.ID,
+		"AIT_COMMIT="+s.Commit,
+		"AIT_PULL_REQUEST="+s.PullRequest,
+		"AIT_ISSUE="+s.Issue)
+	if err != nil {
+		fmt.Println(err)
+	}
+}
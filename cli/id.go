@@ -0,0 +1,123 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+	"time"
+
+	"github.com/arken/ait/display"
+	"github.com/arken/ait/ipfs"
+	"github.com/arken/ait/utils"
+
+	"github.com/DataDrake/cli-ng/v2/cmd"
+)
+
+// ID shows the peer ID of the node and moves its identity between machines,
+// as operators allow Arken peers by their ID.
+var ID = cmd.Sub{
+	Name:  "id",
+	Short: "Show the peer ID of your node, export, import or rotate its identity.",
+	Args:  &IDArgs{},
+	Flags: &IDFlags{},
+	Run:   IDRun,
+}
+
+// IDArgs handles the specific arguments for the id command.
+type IDArgs struct {
+	Args []string `zero:"yes" desc:"export [file], import <file> or rotate"`
+}
+
+// IDFlags handles the specific flags for the id command.
+type IDFlags struct {
+	Yes bool `short:"y" long:"yes" desc:"Replace the identity without asking for confirmation"`
+}
+
+const idUsage = `	ait id                  # Show the peer ID of your node
+	ait id export [file]    # Write the identity to a file (default identity.key)
+	ait id import <file>    # Make an exported identity the one of this node
+	ait id rotate           # Give the node a new identity`
+
+// IDOutput is what the id command prints with --json.
+type IDOutput struct {
+	PeerID string `json:"peer_id"`
+	// Previous is the peer ID replaced by import or rotate, and KeptAs the
+	// name of the IPNS key it's kept under.
+	Previous string `json:"previous,omitempty"`
+	KeptAs   string `json:"kept_as,omitempty"`
+}
+
+// IDRun dispatches to the requested id operation.
+func IDRun(_ *cmd.Root, c *cmd.Sub) {
+	args := c.Args.(*IDArgs).Args
+	flags := c.Flags.(*IDFlags)
+	if len(args) == 0 {
+		id, err := ipfs.NodeID()
+		utils.CheckError(err)
+		display.Out.Result(IDOutput{PeerID: id}, func(w io.Writer) {
+			fmt.Fprintln(w, id)
+		})
+		return
+	}
+	switch {
+	case args[0] == "export" && len(args) <= 2:
+		out := "identity.key"
+		if len(args) == 2 {
+			out = args[1]
+		}
+		data, err := ipfs.ExportIdentity()
+		utils.CheckError(err)
+		utils.CheckError(ioutil.WriteFile(out, data, 0600))
+		fmt.Printf("Identity exported to %v. Keep this file private, anyone holding it can "+
+			"act as your node!\n", out)
+	case args[0] == "import" && len(args) == 2:
+		data, err := ioutil.ReadFile(args[1])
+		utils.CheckError(err)
+		confirmIDReplaced(flags.Yes, "import the identity of "+args[1])
+		keepAs := replacedIdentityName()
+		previous, id, err := ipfs.ImportIdentity(data, keepAs)
+		utils.CheckError(err)
+		printIDReplaced(IDOutput{PeerID: id, Previous: previous, KeptAs: keepAs})
+	case args[0] == "rotate" && len(args) == 1:
+		confirmIDReplaced(flags.Yes, "give your node a new identity")
+		keepAs := replacedIdentityName()
+		previous, id, err := ipfs.RotateIdentity(keepAs)
+		utils.CheckError(err)
+		printIDReplaced(IDOutput{PeerID: id, Previous: previous, KeptAs: keepAs})
+	default:
+		utils.FatalPrintln("Unknown id operation \"" + strings.Join(args, " ") + "\":\n" + idUsage)
+	}
+}
+
+// confirmIDReplaced asks before the node's identity is replaced, unless yes
+// is set.
+func confirmIDReplaced(yes bool, action string) {
+	if yes {
+		return
+	}
+	if utils.NonInteractive {
+		utils.FatalPrintln("Replacing the identity of the node needs confirmation, use --yes.")
+	}
+	fmt.Printf("This will %v. Peers and allow-lists that know your node by its current "+
+		"peer ID won't recognize it anymore. Continue? (y/[n]) ", action)
+	if strings.ToLower(utils.ReadAnswer()) != "y" {
+		utils.FatalPrintln("The identity was left as it is.")
+	}
+}
+
+// replacedIdentityName returns the name the replaced identity is kept under
+// in the keystore.
+func replacedIdentityName() string {
+	return "identity-" + time.Now().Format("20060102-150405")
+}
+
+// printIDReplaced reports the new identity and where the previous one went.
+func printIDReplaced(out IDOutput) {
+	display.Out.Result(out, func(w io.Writer) {
+		fmt.Fprintf(w, "Your node is now %v.\n", out.PeerID)
+		fmt.Fprintf(w, "It was %v, whose key is kept as \"%v\" in \"ait key list\" and can be "+
+			"exported with \"ait key export %v\".\n", out.Previous, out.KeptAs, out.KeptAs)
+		fmt.Fprintln(w, "Ask the operators of allow-lists your node is on to add the new peer ID.")
+	})
+}
@@ -0,0 +1,102 @@
+package cli
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/arken/ait/config"
+	"github.com/arken/ait/utils"
+
+	"github.com/DataDrake/cli-ng/v2/cmd"
+)
+
+// Identity shows or corrects the name and email submissions are made under.
+var Identity = cmd.Sub{
+	Name:  "identity",
+	Short: "Show or correct the name and email your submissions are made under.",
+	Args:  &IdentityArgs{},
+	Run:   IdentityRun,
+}
+
+// IdentityArgs handles the specific arguments for the identity command.
+type IdentityArgs struct {
+	Args []string `zero:"yes" desc:"set, name <name> or email <address>"`
+}
+
+const identityUsage = `	ait identity                  # Show your name and email
+	ait identity set              # Enter them again
+	ait identity name <name>      # Correct your name
+	ait identity email <address>  # Correct your email`
+
+// IdentityRun shows the saved identity, or replaces it once the new one is
+// valid.
+func IdentityRun(_ *cmd.Root, c *cmd.Sub) {
+	args := c.Args.(*IdentityArgs).Args
+	git := &config.Global.Git
+	if len(args) == 0 {
+		if git.Name == "" && git.Email == "" {
+			fmt.Println("No identity is saved yet, \"ait identity set\" saves one.")
+			return
+		}
+		fmt.Printf("Name:   %v\nEmail:  %v\n", git.Name, git.Email)
+		if len(git.EmailDomains) > 0 {
+			fmt.Printf("Expected email domains:  %v\n", strings.Join(git.EmailDomains, ", "))
+		}
+		return
+	}
+	switch {
+	case args[0] == "set" && len(args) == 1:
+		git.Name, git.Email = askIdentity(git.Name, git.Email, git.EmailDomains)
+	case args[0] == "name" && len(args) > 1:
+		name, err := utils.CleanName(strings.Join(args[1:], " "))
+		utils.CheckError(err)
+		git.Name = name
+	case args[0] == "email" && len(args) == 2:
+		email, err := utils.CleanEmail(args[1], git.EmailDomains)
+		utils.CheckError(err)
+		noteEmailDomain(email, git.EmailDomains)
+		git.Email = email
+	default:
+		utils.FatalPrintln("Unknown arguments:\n" + identityUsage)
+	}
+	config.GenConf(config.Global)
+	fmt.Printf("Your submissions will be made as %v <%v>.\n", git.Name, git.Email)
+}
+
+// askIdentity asks for a name and email, suggesting the ones given, until
+// both are valid. An email without a domain is completed with the domain the
+// discovered organization expects, if there's one.
+func askIdentity(name, email string, domains []string) (string, string) {
+	for {
+		cleaned, err := utils.CleanName(ask("Your name", name))
+		if err == nil {
+			name = cleaned
+			break
+		}
+		fmt.Printf("That name can't be used, %v.\n", err)
+	}
+	question := "Your email"
+	if len(domains) > 0 {
+		question += " (at " + strings.Join(domains, " or ") + ")"
+	}
+	for {
+		cleaned, err := utils.CleanEmail(ask(question, email), domains)
+		if err == nil {
+			email = cleaned
+			break
+		}
+		fmt.Printf("That email can't be used, %v.\n", err)
+	}
+	noteEmailDomain(email, domains)
+	return name, email
+}
+
+// noteEmailDomain warns when the email isn't at any of the domains the
+// discovered organizations expect. It's still used, the user may belong to
+// several.
+func noteEmailDomain(email string, domains []string) {
+	if len(domains) > 0 && !utils.InEmailDomains(email, domains) {
+		fmt.Printf("Note that %v isn't at %v, which the organization expects.\n",
+			email, strings.Join(domains, " or "))
+	}
+}
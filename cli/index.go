@@ -0,0 +1,103 @@
+package cli
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	aitgh "github.com/arken/ait/apis/github"
+	"github.com/arken/ait/config"
+	"github.com/arken/ait/ipfs"
+	"github.com/arken/ait/keysets"
+	"github.com/arken/ait/utils"
+
+	"github.com/DataDrake/cli-ng/v2/cmd"
+)
+
+// Index regenerates the table of contents of a keyset repository for its
+// maintainers.
+var Index = cmd.Sub{
+	Name:  "index",
+	Short: "Regenerate the index of every keyset in a keyset repository.",
+	Args:  &IndexArgs{},
+	Flags: &IndexFlags{},
+	Run:   IndexRun,
+}
+
+// IndexArgs handles the specific arguments for the index command.
+type IndexArgs struct {
+	Keyset string
+}
+
+// IndexFlags handles the specific flags for the index command.
+type IndexFlags struct {
+	File      string `short:"f" long:"file" desc:"Path of the index in the repository, INDEX.md by default"`
+	Print     bool   `short:"p" long:"print" desc:"Print the index instead of committing it"`
+	SkipSizes bool   `short:"s" long:"skip-sizes" desc:"Don't look up the size of each entry on the network"`
+}
+
+// indexSizeTimeout is how long the size of a single entry is looked up for.
+const indexSizeTimeout = 10 * time.Second
+
+// IndexRun summarizes every keyset of the repository and commits the result
+// to it, if it changed.
+func IndexRun(_ *cmd.Root, c *cmd.Sub) {
+	args := c.Args.(*IndexArgs)
+	flags := c.Flags.(*IndexFlags)
+	if flags.File == "" {
+		flags.File = "INDEX.md"
+	}
+	url := config.GetRemote(args.Keyset)
+	repoPath := config.SourcePath(url)
+	repo, err := keysets.Clone(url, repoPath)
+	utils.CheckError(err)
+
+	sizeOf := func(cid string) (int64, error) {
+		return 0, errors.New("sizes are not looked up")
+	}
+	if !flags.SkipSizes {
+		ipfs.Init(false)
+		sizeOf = func(cid string) (int64, error) {
+			return ipfs.Size(cid, indexSizeTimeout)
+		}
+	}
+	fmt.Println("Summarizing the keysets of", url)
+	index, err := keysets.BuildIndex(repo, sizeOf)
+	utils.CheckError(err)
+	contents := []byte(keysets.FormatIndex(index))
+	if flags.Print {
+		fmt.Print(string(contents))
+		return
+	}
+	if existing, err := ioutil.ReadFile(filepath.Join(repoPath, flags.File)); err == nil &&
+		bytes.Equal(existing, contents) {
+		fmt.Println("The index is already up to date.")
+		return
+	}
+
+	canPush, err := aitgh.Init(config.GetPushRemote(args.Keyset), false)
+	utils.CheckError(err)
+	if !canPush {
+		utils.FatalPrintf("Only maintainers with write access to %v can update its index.\n", url)
+	}
+	// The index is uploaded from a temporary file so the local copy of the
+	// repository stays clean for the next pull.
+	tmp, err := ioutil.TempFile("", "ait-index-*.md")
+	utils.CheckError(err)
+	defer os.Remove(tmp.Name())
+	_, err = tmp.Write(contents)
+	tmp.Close()
+	utils.CheckError(err)
+	message := fmt.Sprintf("Update the keyset index (%d keysets)", len(index))
+	if aitgh.KeysetExistsInRepo(flags.File, false) {
+		_, err = aitgh.UpdateFile(tmp.Name(), flags.File, message, false)
+	} else {
+		_, err = aitgh.CreateFile(tmp.Name(), flags.File, message, false)
+	}
+	utils.CheckError(err)
+	fmt.Printf("Committed the index of %d keysets to %v.\n", len(index), flags.File)
+}
@@ -0,0 +1,55 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/arken/ait/apis/ingest"
+	"github.com/arken/ait/utils"
+
+	"github.com/DataDrake/cli-ng/v2/cmd"
+)
+
+// Ingest pulls data from institutional storage into the workspace and stages
+// it.
+var Ingest = cmd.Sub{
+	Name:  "ingest",
+	Short: "Fetch an iRODS collection or Globus directory into the workspace and stage it.",
+	Args:  &IngestArgs{},
+	Run:   IngestRun,
+}
+
+// IngestArgs handles the specific arguments for the ingest command.
+type IngestArgs struct {
+	// Source is irods:/<zone>/<collection> or globus://<endpoint ID>/<path>.
+	Source string
+	// Dest is optional, the source's name in the workspace by default.
+	Dest []string
+}
+
+// IngestRun fetches the source into a directory of the workspace and stages
+// everything in it.
+func IngestRun(_ *cmd.Root, c *cmd.Sub) {
+	args := c.Args.(*IngestArgs)
+	src, err := ingest.Parse(args.Source)
+	utils.CheckError(err)
+	dest := src.Name()
+	if len(args.Dest) > 1 {
+		utils.FatalPrintln("Expected one destination directory.")
+	} else if len(args.Dest) == 1 {
+		dest = args.Dest[0]
+	}
+	dest = filepath.Clean(dest)
+	withinRepo, err := utils.IsWithinRepo(dest)
+	utils.CheckError(err)
+	if !withinRepo {
+		utils.FatalPrintln("The destination must be inside this AIT repo.")
+	}
+	utils.CheckError(os.MkdirAll(dest, os.ModePerm))
+
+	fmt.Printf("Fetching %v into %v...\n", args.Source, dest)
+	utils.CheckError(src.Fetch(dest))
+
+	fmt.Println(stagePath(dest), "file(s) added")
+}
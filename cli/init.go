@@ -2,10 +2,16 @@ package cli
 
 import (
 	"fmt"
+	"io/ioutil"
 	"os"
+	"path/filepath"
 
+	"github.com/arken/ait/config"
+	"github.com/arken/ait/display"
+	"github.com/arken/ait/types"
 	"github.com/arken/ait/utils"
 
+	"github.com/BurntSushi/toml"
 	"github.com/DataDrake/cli-ng/v2/cmd"
 )
 
@@ -15,6 +21,7 @@ var Init = cmd.Sub{
 	Alias: "i",
 	Short: "Initialize a dataset's local configuration.",
 	Args:  &InitArgs{},
+	Flags: &InitFlags{},
 	Run:   InitRun,
 }
 
@@ -22,21 +29,114 @@ var Init = cmd.Sub{
 type InitArgs struct {
 }
 
+// InitFlags handles the specific flags for the init command.
+type InitFlags struct {
+	Template string `long:"template" desc:"Keyset repository whose .ait-template directory sets up the workspace"`
+	Nested   bool   `long:"nested" desc:"Create the workspace even inside another one"`
+}
+
+// initTemplateDir is the directory of a keyset repository holding what
+// workspaces initialized from it start with.
+const initTemplateDir = ".ait-template"
+
+// InitTemplate is the template.toml of a keyset repository's .ait-template
+// directory.
+type InitTemplate struct {
+	// Alias is the remote alias the workspace gets for the repository.
+	Alias    string
+	Category string
+	Filename string
+	Title    string
+	Commit   string
+}
+
 // InitRun creates a new ait repo simply by creating a folder called .ait in the working dir.
-func InitRun(_ *cmd.Root, _ *cmd.Sub) {
+// Like git, a directory inside another workspace isn't made a workspace of its
+// own unless asked to, and a workspace is initialized again only to apply a
+// template to it.
+func InitRun(_ *cmd.Root, c *cmd.Sub) {
+	flags := c.Flags.(*InitFlags)
+	wd, err := os.Getwd()
+	utils.CheckError(err)
+	stateDir := filepath.Dir(config.Path)
+	if filepath.Join(wd, ".ait") == stateDir {
+		utils.FatalPrintf("%v is where ait keeps its own state, initialize the repo in another directory.\n",
+			stateDir)
+	}
+	root, inWorkspace := utils.FindWorkspace(wd, stateDir)
 	info, err := os.Stat(".ait")
-	if os.IsNotExist(err) {
-		err := os.Mkdir(".ait", os.ModePerm)
-		utils.CheckError(err)
-	} else if info.IsDir() {
+	exists := err == nil && info.IsDir()
+	switch {
+	case exists && flags.Template == "":
 		utils.FatalPrintln("a directory called \".ait\" already exists here, " +
 			"suggesting that this is already an ait repo")
-	} else {
-		utils.FatalPrintln("a file called \".ait\" already exists in this " +
-			"this directory and it is not itself a directory. Please move or " +
-			"rename this file")
+	case exists:
+		fmt.Printf("Applying the template to the ait repo at %v\n", wd)
+	case inWorkspace && !flags.Nested:
+		utils.FatalPrintf("%v is inside the ait repo at %v, which already tracks its files. "+
+			"Run ait from there, or use --nested to make a separate repo here anyway.\n", wd, root)
+	default:
+		if err == nil {
+			utils.FatalPrintln("a file called \".ait\" already exists in this " +
+				"this directory and it is not itself a directory. Please move or " +
+				"rename this file")
+		}
+		utils.CheckError(os.Mkdir(".ait", os.ModePerm))
+		fmt.Printf("New ait repo initiated at %v\n", wd)
 	}
-	wd, err := os.Getwd()
+	// The staging file marks the directory as a workspace, even before
+	// anything is staged.
+	if !utils.FileExists(utils.AddedFilesPath) {
+		utils.CheckError(utils.WriteStaged(types.NewThreadSafeStringSet()))
+	}
+	if flags.Template != "" {
+		applyInitTemplate(flags.Template)
+	}
+}
+
+// applyInitTemplate sets the workspace up from the .ait-template directory of
+// the keyset repository at remote: a remote alias for the repository and an
+// application template from its template.toml and application.md, and the
+// ignore rules of its aitignore.
+func applyInitTemplate(remote string) {
+	url, repoPath := clonePullSource(remote)
+	dir := filepath.Join(repoPath, initTemplateDir)
+	if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+		utils.FatalPrintf("%v has no %v directory to set the workspace up from.\n", url, initTemplateDir)
+	}
+	tmpl := &InitTemplate{}
+	if path := filepath.Join(dir, "template.toml"); utils.FileExists(path) {
+		if _, err := toml.DecodeFile(path, tmpl); err != nil {
+			utils.FatalPrintf("The template.toml of %v is malformed: %v\n", url, err)
+		}
+	}
+	if tmpl.Alias != "" {
+		utils.CheckError(config.SaveWorkspaceRemote(tmpl.Alias, url))
+		fmt.Printf("Added the remote alias %q for %v to this repo.\n", tmpl.Alias, url)
+	}
+
+	app := filepath.Join(dir, "application.md")
+	if !utils.FileExists(app) {
+		app = filepath.Join(filepath.Dir(config.Path), "application.md")
+	}
+	template, err := ioutil.ReadFile(app)
 	utils.CheckError(err)
-	fmt.Printf("New ait repo initiated at %v\n", wd)
+	filled := display.FillApplicationTemplate(template, &types.ApplicationContents{
+		Category: tmpl.Category,
+		KsName:   tmpl.Filename,
+		Title:    tmpl.Title,
+		Commit:   tmpl.Commit,
+	})
+	utils.CheckError(ioutil.WriteFile(display.WorkspaceApplicationPath, filled, 0644))
+	fmt.Println("Submissions from this repo start from the template's application.")
+
+	if ignore := filepath.Join(dir, "aitignore"); utils.FileExists(ignore) {
+		if utils.FileExists(utils.IgnoreFileName) {
+			fmt.Printf("Kept the existing %v, the template's ignore rules weren't applied.\n",
+				utils.IgnoreFileName)
+		} else {
+			utils.CheckError(utils.CopyFile(ignore, utils.IgnoreFileName))
+			fmt.Printf("Wrote the template's ignore rules to %v.\n", utils.IgnoreFileName)
+		}
+	}
 }
@@ -0,0 +1,276 @@
+package cli
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/arken/ait/config"
+	"github.com/arken/ait/ipfs"
+	"github.com/arken/ait/utils"
+
+	"github.com/DataDrake/cli-ng/v2/cmd"
+)
+
+// IPFS groups the commands used to inspect and maintain AIT's embedded node.
+var IPFS = cmd.Sub{
+	Name:  "ipfs",
+	Alias: "node",
+	Short: "Inspect and maintain AIT's embedded IPFS node.",
+	Args:  &IPFSArgs{},
+	Flags: &IPFSFlags{},
+	Run:   IPFSRun,
+}
+
+// IPFSArgs handles the specific arguments for the ipfs command.
+type IPFSArgs struct {
+	Action string   `desc:"The operation to perform: stat, fsck, profile"`
+	Args   []string `zero:"yes" desc:"Arguments for the operation"`
+}
+
+// IPFSFlags handles the specific flags for the ipfs command.
+type IPFSFlags struct {
+	Repair bool `short:"r" long:"repair" desc:"Have fsck repair the problems it finds"`
+}
+
+const ipfsUsage = `	ait ipfs stat            # Show storage limits, the last scrub and relayed traffic
+	ait ipfs fsck [--repair] # Verify the repository and optionally repair it
+	ait ipfs profile add <name> [path] # Create a profile with its own repository
+	ait ipfs profile remove <name>     # Forget a profile (its repository is kept on disk)
+	ait ipfs profile list              # List profiles and their repositories
+	ait ipfs profile use <name>        # Make this workspace use the profile ("default" to reset)`
+
+// IPFSRun dispatches to the requested ipfs operation.
+func IPFSRun(_ *cmd.Root, c *cmd.Sub) {
+	args := c.Args.(*IPFSArgs)
+	switch args.Action {
+	case "stat":
+		ipfsStat()
+	case "fsck":
+		ipfsFsck(c.Flags.(*IPFSFlags).Repair)
+	case "profile":
+		ipfsProfile(args.Args)
+	default:
+		utils.FatalPrintln("Unknown ipfs operation \"" + args.Action + "\":\n" + ipfsUsage)
+	}
+}
+
+// ipfsStat prints the storage settings and the relay usage recorded by
+// previous online sessions.
+func ipfsStat() {
+	stats, err := ipfs.ReadRelayStats()
+	utils.CheckError(err)
+	if config.ActiveProfile != "" {
+		fmt.Println("Profile:", config.ActiveProfile)
+	}
+	fmt.Println("Repository:", config.Global.IPFS.Path)
+	fmt.Printf("Storage limit: %v (garbage collected at %d%%)\n",
+		config.Global.IPFS.StorageMax, config.Global.IPFS.StorageGCWatermark)
+	scrubbed, err := ipfs.ReadScrubStats()
+	utils.CheckError(err)
+	if scrubbed.Last.IsZero() {
+		fmt.Println("Last scrub: never")
+	} else {
+		fmt.Printf("Last scrub: %v (%d block(s) checked, %d problem(s), %d unrepaired)\n",
+			scrubbed.Last.Format("Jan 2 2006 3:04 PM"), scrubbed.BlocksChecked,
+			scrubbed.Problems, scrubbed.Unrepaired)
+	}
+	protected, err := ipfs.ReadProtected()
+	utils.CheckError(err)
+	fmt.Printf("Protected from garbage collection: %d file(s) of unmerged or unreplicated submissions\n",
+		len(protected))
+	if stats.LastRelayed.IsZero() {
+		fmt.Println("This node has not routed any traffic through the Arken relay.")
+		return
+	}
+	fmt.Println("Relay usage:")
+	fmt.Printf("\tLast relayed session: %v\n", stats.LastRelayed.Format("Jan 2 2006 3:04 PM"))
+	fmt.Printf("\tLast session:         %v in / %v out\n",
+		utils.FormatByteSize(stats.SessionIn), utils.FormatByteSize(stats.SessionOut))
+	fmt.Printf("\tAll time:             %v in / %v out\n",
+		utils.FormatByteSize(stats.TotalIn), utils.FormatByteSize(stats.TotalOut))
+	fmt.Printf("\tWarning threshold:    %v\n", config.Global.IPFS.RelayWarn)
+	if stats.Throttled {
+		fmt.Println("\tThe last session was throttled after crossing the threshold.")
+	}
+}
+
+// ipfsFsck checks the repository for corrupt or missing blocks and broken
+// filestore references. With repair, corrupt blocks are dropped and missing
+// data is restored from the staged files of the current AIT repo when
+// possible, otherwise it is fetched from the network.
+func ipfsFsck(repair bool) {
+	prettyIPFSInit()
+	fmt.Println("Checking the IPFS repository, this may take a while...")
+	report, err := ipfs.Fsck()
+	utils.CheckError(err)
+	fmt.Println("Checked", report.BlocksChecked, "block(s).")
+	if report.IsHealthy() {
+		fmt.Println("The IPFS repository is healthy.")
+		return
+	}
+	printFsckReport(report)
+	if !repair {
+		utils.FatalPrintln("\nRun \"ait ipfs fsck --repair\" to attempt to fix these problems.")
+	}
+
+	fmt.Println("\nRepairing the IPFS repository...")
+	failed, err := repairRepository(report)
+	utils.CheckError(err)
+	if failed > 0 {
+		utils.FatalPrintln(failed, "pinned file(s) could not be repaired.")
+	}
+	fmt.Println("Repair complete.")
+}
+
+// repairRepository drops the corrupt blocks found by ipfs.Fsck and restores
+// missing data from the staged files of the current AIT repo when possible,
+// otherwise from the network. It returns the number of pinned files that
+// could not be repaired.
+func repairRepository(report ipfs.FsckReport) (failed int, err error) {
+	for _, hash := range report.CorruptBlocks {
+		if err := ipfs.RemoveBlock(hash); err != nil {
+			fmt.Printf("\tUnable to drop corrupt block %v: %v\n", hash, err)
+		}
+	}
+	// Dropping corrupt blocks may leave more pinned data incomplete.
+	report, err = ipfs.Fsck()
+	if err != nil {
+		return failed, err
+	}
+	staged := stagedCIDs()
+	for _, path := range report.BrokenRefs {
+		fmt.Printf("\tFilestore reference to %v is broken.\n", path)
+	}
+	for root := range report.MissingBlocks {
+		if path, ok := staged[root]; ok {
+			if _, err := ipfs.Add(path, false); err == nil {
+				fmt.Printf("\tRe-added %v from %v\n", root, path)
+				continue
+			}
+		}
+		if err := ipfs.Refetch(root, 5*time.Minute); err != nil {
+			fmt.Printf("\t%v\n", err)
+			failed++
+			continue
+		}
+		fmt.Printf("\tFetched %v from the network\n", root)
+	}
+	return failed, nil
+}
+
+// printFsckReport lists the problems found by ipfs.Fsck.
+func printFsckReport(report ipfs.FsckReport) {
+	if len(report.CorruptBlocks) > 0 {
+		fmt.Println(len(report.CorruptBlocks), "corrupt block(s):")
+		for _, hash := range report.CorruptBlocks {
+			fmt.Println("\t", hash)
+		}
+	}
+	if len(report.MissingBlocks) > 0 {
+		fmt.Println(len(report.MissingBlocks), "pinned file(s) with missing blocks:")
+		for root, missing := range report.MissingBlocks {
+			fmt.Printf("\t %v (%d block(s) missing)\n", root, len(missing))
+		}
+	}
+	if len(report.BrokenRefs) > 0 {
+		fmt.Println(len(report.BrokenRefs), "broken filestore reference(s):")
+		for hash, path := range report.BrokenRefs {
+			fmt.Printf("\t %v -> %v\n", hash, path)
+		}
+	}
+}
+
+// stagedCIDs hashes the files staged in the current AIT repo and returns a
+// map of CID -> path (through the workspace link) so missing data can be
+// re-added from the original files. An empty map is returned outside of a repo.
+func stagedCIDs() map[string]string {
+	result := make(map[string]string)
+	if !utils.IsAITRepo() {
+		return result
+	}
+	link, err := ipfs.LinkWorkdir()
+	if err != nil {
+		return result
+	}
+	contents, err := utils.ReadStaged()
+	if err != nil {
+		return result
+	}
+	_ = contents.ForEach(func(path string) error {
+		linkPath := filepath.Join(link, path)
+		if cid, err := ipfs.Add(linkPath, true); err == nil {
+			result[cid] = linkPath
+		}
+		return nil
+	})
+	return result
+}
+
+// ipfsProfile manages the named IPFS repositories stored in the config.
+func ipfsProfile(args []string) {
+	if len(args) == 0 {
+		utils.FatalPrintln("Expected a profile operation:\n" + ipfsUsage)
+	}
+	if len(args) < 2 && args[0] != "list" {
+		utils.FatalPrintln("Expected a profile name:\n" + ipfsUsage)
+	}
+	switch args[0] {
+	case "add":
+		name := args[1]
+		utils.CheckError(config.ValidateProfileName(name))
+		if _, ok := config.Global.IPFS.Profiles[name]; ok {
+			utils.FatalPrintf("A profile named \"%v\" already exists.\n", name)
+		}
+		path := ""
+		if len(args) > 2 {
+			abs, err := filepath.Abs(args[2])
+			utils.CheckError(err)
+			path = abs
+		}
+		if config.Global.IPFS.Profiles == nil {
+			config.Global.IPFS.Profiles = make(map[string]string)
+		}
+		config.Global.IPFS.Profiles[name] = path
+		config.GenConf(config.Global)
+		fmt.Printf("Profile \"%v\" will keep its IPFS repository at %v\n", name, config.ProfilePath(name))
+	case "remove":
+		name := args[1]
+		if _, ok := config.Global.IPFS.Profiles[name]; !ok {
+			utils.FatalPrintf("There is no profile named \"%v\". Nothing was done.\n", name)
+		}
+		path := config.ProfilePath(name)
+		delete(config.Global.IPFS.Profiles, name)
+		config.GenConf(config.Global)
+		fmt.Printf("Profile \"%v\" removed. Its repository at %v was left on disk.\n", name, path)
+	case "list":
+		names := make([]string, 0, len(config.Global.IPFS.Profiles))
+		for name := range config.Global.IPFS.Profiles {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		fmt.Printf("\t default  %v\n", config.ProfilePath("default"))
+		for _, name := range names {
+			fmt.Printf("\t %v  %v\n", name, config.ProfilePath(name))
+		}
+	case "use":
+		if !utils.IsAITRepo() {
+			utils.FatalPrintln("Profiles can only be assigned to an AIT repo. Run \"ait init\" first.")
+		}
+		name := args[1]
+		if name == "default" {
+			_ = os.Remove(config.WorkspaceProfilePath)
+			fmt.Println("This workspace now uses the default IPFS repository.")
+			return
+		}
+		utils.CheckError(config.UseProfile(name))
+		err := ioutil.WriteFile(config.WorkspaceProfilePath, []byte(name+"\n"), 0644)
+		utils.CheckError(err)
+		fmt.Printf("This workspace now uses the \"%v\" IPFS repository at %v\n", name, config.Global.IPFS.Path)
+	default:
+		utils.FatalPrintln("Unknown profile operation \"" + args[0] + "\":\n" + ipfsUsage)
+	}
+}
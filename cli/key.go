@@ -0,0 +1,105 @@
+package cli
+
+import (
+	"fmt"
+	"io/ioutil"
+
+	"github.com/arken/ait/ipfs"
+	"github.com/arken/ait/utils"
+
+	"github.com/DataDrake/cli-ng/v2/cmd"
+)
+
+// Key manages additional IPNS keys so each keyset can be published under its
+// own stable name.
+var Key = cmd.Sub{
+	Name:  "key",
+	Alias: "k",
+	Short: "Manage IPNS keys used to publish keysets.",
+	Args:  &KeyArgs{},
+	Run:   KeyRun,
+}
+
+// KeyArgs handles the specific arguments for the key command.
+type KeyArgs struct {
+	Action string   `desc:"The operation to perform: gen, list, export, import, rm, publish"`
+	Args   []string `zero:"yes" desc:"Arguments for the operation"`
+}
+
+const keyUsage = `	ait key gen <name>                  # Generate a new IPNS key
+	ait key list                        # List your IPNS keys and their names
+	ait key export <name> [file]        # Write the key to a file (default <name>.key)
+	ait key import <name> <file>        # Import a previously exported key
+	ait key rm <name>                   # Delete a key
+	ait key publish <name> <file|cid>   # Publish a keyset under the key's IPNS name`
+
+// KeyRun dispatches to the requested key operation.
+func KeyRun(_ *cmd.Root, c *cmd.Sub) {
+	args := c.Args.(*KeyArgs)
+	if args.Action != "list" && len(args.Args) < 1 {
+		utils.FatalPrintln("Expected a key name:\n" + keyUsage)
+	}
+	switch args.Action {
+	case "gen":
+		key, err := ipfs.GenerateKey(args.Args[0])
+		utils.CheckError(err)
+		fmt.Printf("Generated key \"%v\" with IPNS name /ipns/%v\n", key.Name, key.ID)
+	case "list":
+		keys, err := ipfs.ListKeys()
+		utils.CheckError(err)
+		if len(keys) == 0 {
+			fmt.Println("No IPNS keys have been generated.")
+			return
+		}
+		fmt.Println(len(keys), "IPNS key(s):")
+		for _, key := range keys {
+			fmt.Printf("\t %v  /ipns/%v\n", key.Name, key.ID)
+		}
+	case "export":
+		name := args.Args[0]
+		out := name + ".key"
+		if len(args.Args) > 1 {
+			out = args.Args[1]
+		}
+		data, err := ipfs.ExportKey(name)
+		utils.CheckError(err)
+		utils.CheckError(ioutil.WriteFile(out, data, 0600))
+		fmt.Printf("Key \"%v\" exported to %v. Keep this file private!\n", name, out)
+	case "import":
+		if len(args.Args) < 2 {
+			utils.FatalPrintln("Expected a key name and a file:\n" + keyUsage)
+		}
+		data, err := ioutil.ReadFile(args.Args[1])
+		utils.CheckError(err)
+		key, err := ipfs.ImportKey(args.Args[0], data)
+		utils.CheckError(err)
+		fmt.Printf("Imported key \"%v\" with IPNS name /ipns/%v\n", key.Name, key.ID)
+	case "rm":
+		utils.CheckError(ipfs.RemoveKey(args.Args[0]))
+		fmt.Printf("Key \"%v\" deleted.\n", args.Args[0])
+	case "publish":
+		if len(args.Args) < 2 {
+			utils.FatalPrintln("Expected a key name and a keyset file or CID:\n" + keyUsage)
+		}
+		keyPublish(args.Args[0], args.Args[1])
+	default:
+		utils.FatalPrintln("Unknown key operation \"" + args.Action + "\":\n" + keyUsage)
+	}
+}
+
+// keyPublish adds the given file (or uses the given CID) and publishes it
+// under the IPNS name of the key.
+func keyPublish(name, target string) {
+	prettyIPFSInit()
+	hash := target
+	if utils.FileExists(target) {
+		var err error
+		hash, err = ipfs.Add(target, false)
+		utils.CheckError(err)
+		utils.CheckError(ipfs.RecordPin(hash, ipfs.PinPublished))
+	}
+	fmt.Printf("Publishing %v under key \"%v\"...\n", hash, name)
+	ipnsName, err := ipfs.Publish(name, hash)
+	utils.CheckError(err)
+	fmt.Printf("Published to /ipns/%v\n", ipnsName)
+}
@@ -0,0 +1,319 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/arken/ait/config"
+	"github.com/arken/ait/keysets"
+	"github.com/arken/ait/utils"
+
+	"github.com/DataDrake/cli-ng/v2/cmd"
+)
+
+// Keyset works with keyset files locally, without a remote.
+var Keyset = cmd.Sub{
+	Name:  "keyset",
+	Short: "Generate, preview or export the keyset of the staged files, without submitting it.",
+	Args:  &KeysetArgs{},
+	Flags: &KeysetFlags{},
+	Run:   KeysetRun,
+}
+
+// KeysetArgs handles the specific arguments for the keyset command.
+type KeysetArgs struct {
+	Action string   `desc:"The operation to perform: generate, preview or export"`
+	Args   []string `zero:"yes" desc:"Arguments for the operation"`
+}
+
+// KeysetFlags handles the specific flags for the keyset command.
+type KeysetFlags struct {
+	Amend      bool   `short:"a" long:"amend" desc:"Add the staged files missing from the keyset at the path instead of replacing it"`
+	OnConflict string `long:"on-conflict" desc:"With --amend, keep ours, theirs or abort on staged files named like an entry with other contents"`
+	Strict     bool   `long:"strict" desc:"Exit with an error if a staged file couldn't be added, after writing the keyset"`
+	Path       string `long:"path" desc:"Path of the keyset in the repository to preview against, the one last submitted to it by default"`
+	Format     string `short:"f" long:"format" desc:"Write the keyset as ksv, json, csv or a car manifest, General.KeysetFormat by default"`
+}
+
+const keysetUsage = `	ait keyset generate          # Write the keyset of the staged files to stdout
+	ait keyset generate <path>   # Write it to a file
+	ait keyset generate -a <path>  # Add the staged files missing from the keyset at path
+	ait keyset preview           # Show the keyset a submission would commit
+	ait keyset preview <remote>  # And how it compares to the keyset in the repository
+	ait keyset export -f car <keyset> [path]  # Write a keyset file in another format`
+
+// KeysetRun dispatches to the requested keyset operation.
+func KeysetRun(_ *cmd.Root, c *cmd.Sub) {
+	args := c.Args.(*KeysetArgs)
+	flags := c.Flags.(*KeysetFlags)
+	switch {
+	case args.Action == "preview":
+		keysetPreview(args.Args, flags)
+		return
+	case args.Action == "export":
+		keysetExport(args.Args, flags)
+		return
+	case args.Action != "generate":
+		utils.FatalPrintf("Unknown action %q:\n%v\n", args.Action, keysetUsage)
+	case len(args.Args) > 1:
+		utils.FatalPrintln("Expected at most one path:\n" + keysetUsage)
+	case len(args.Args) == 0 && flags.Amend:
+		utils.FatalPrintln("--amend needs the path of the keyset to add to.")
+	}
+	w := keysetWriter(flags.Format)
+	if flags.Amend && w != keysets.Formats[keysets.DefaultFormat] {
+		utils.FatalPrintln("Only ksv keysets can be amended.")
+	}
+	useOnConflict(flags.OnConflict)
+	if s, _ := utils.GetFileSize(utils.AddedFilesPath); s == 0 {
+		utils.FatalPrintln("No files are currently staged, there's nothing to generate a keyset from.")
+	}
+	if len(args.Args) == 1 && w == keysets.Formats[keysets.DefaultFormat] {
+		prettyIPFSInit()
+		failed, err := checkGenerated(keysets.Generate(args.Args[0], !flags.Amend), args.Args[0], false, func() {})
+		utils.CheckError(err)
+		fmt.Printf("Wrote the keyset to %v.\n", args.Args[0])
+		if failed && flags.Strict {
+			utils.Exit(1)
+		}
+		return
+	}
+
+	// Keysets written to stdout or in another format are generated in a
+	// temporary directory first. Only the keyset is written to stdout, so it
+	// can be piped, everything else printed while generating it goes to
+	// stderr.
+	stdout := os.Stdout
+	os.Stdout = os.Stderr
+	dir, err := ioutil.TempDir("", "ait-keyset")
+	utils.CheckError(err)
+	defer os.RemoveAll(dir)
+	ksPath := filepath.Join(dir, "generated.ks")
+	prettyIPFSInit()
+	failed, err := checkGenerated(keysets.Generate(ksPath, true), ksPath, false, func() {})
+	utils.CheckError(err)
+	os.Stdout = stdout
+	if len(args.Args) == 1 {
+		// Keysets in other formats are generated as ksv first.
+		utils.CheckError(keysets.Export(ksPath, args.Args[0], w))
+		fmt.Printf("Wrote the keyset to %v.\n", args.Args[0])
+	} else {
+		utils.CheckError(writeKeyset(ksPath, w))
+	}
+	if failed && flags.Strict {
+		os.RemoveAll(dir)
+		utils.Exit(1)
+	}
+}
+
+// writeKeyset writes the keyset at ksPath to stdout with w.
+func writeKeyset(ksPath string, w keysets.Writer) error {
+	entries, err := keysets.ReadEntries(ksPath)
+	if err != nil {
+		return err
+	}
+	return w.Write(os.Stdout, entries)
+}
+
+// keysetExport writes a keyset file in another format, to stdout or the path
+// given after it.
+func keysetExport(args []string, flags *KeysetFlags) {
+	if len(args) == 0 || len(args) > 2 {
+		utils.FatalPrintln("Expected the keyset to export and at most one path:\n" + keysetUsage)
+	}
+	w := keysetWriter(flags.Format)
+	if len(args) == 1 {
+		utils.CheckError(writeKeyset(args[0], w))
+		return
+	}
+	utils.CheckError(keysets.Export(args[0], args[1], w))
+	fmt.Printf("Wrote %v to %v.\n", args[0], args[1])
+}
+
+// keysetPreview prints the keyset a submission of the staged files would
+// commit and, given a remote, how it compares to the keyset already in the
+// repository. Nothing is cloned, committed or pushed.
+func keysetPreview(args []string, flags *KeysetFlags) {
+	if len(args) > 1 {
+		utils.FatalPrintln("Expected at most one remote:\n" + keysetUsage)
+	}
+	if s, _ := utils.GetFileSize(utils.AddedFilesPath); s == 0 {
+		utils.FatalPrintln("No files are currently staged, there's nothing to preview.")
+	}
+	dir, err := ioutil.TempDir("", "ait-keyset")
+	utils.CheckError(err)
+	defer os.RemoveAll(dir)
+	ksPath := filepath.Join(dir, "preview.ks")
+	prettyIPFSInit()
+	_, err = checkGenerated(keysets.Generate(ksPath, true), ksPath, false, func() {})
+	utils.CheckError(err)
+	keyset, err := ioutil.ReadFile(ksPath)
+	utils.CheckError(err)
+	fmt.Println("The keyset a submission would commit:")
+	fmt.Println()
+	os.Stdout.Write(keyset)
+	fmt.Println()
+	if len(args) == 0 {
+		return
+	}
+
+	url := config.GetPushRemote(args[0])
+	path := flags.Path
+	if path == "" {
+		history, err := utils.ReadHistory()
+		utils.CheckError(err)
+		if path = lastSubmittedPath(history, url); path == "" {
+			fmt.Printf("Nothing was submitted to %v from this workspace, give --path to compare with one of its keysets.\n", url)
+			return
+		}
+	}
+	existing, source, err := upstreamKeyset(url, path)
+	if os.IsNotExist(err) {
+		fmt.Printf("%v isn't in %v, it would be created.\n", path, source)
+		return
+	}
+	utils.CheckError(err)
+	generated, err := utils.ParseKeysetEntries(bytes.NewReader(keyset))
+	utils.CheckError(err)
+	inExisting := make(map[string]bool, len(existing))
+	for _, entry := range existing {
+		inExisting[entry.CID] = true
+	}
+	inGenerated := make(map[string]bool, len(generated))
+	appended := 0
+	for _, entry := range generated {
+		inGenerated[entry.CID] = true
+		if !inExisting[entry.CID] {
+			appended++
+		}
+	}
+	removed := 0
+	for _, entry := range existing {
+		if !inGenerated[entry.CID] {
+			removed++
+		}
+	}
+	fmt.Printf("%v is already in %v with %d entries.\n", path, source, len(existing))
+	fmt.Printf("Appending to it (--ks-mode amend) adds %d entries, %d are already there.\n",
+		appended, len(generated)-appended)
+	fmt.Printf("Overwriting it (--ks-mode overwrite) replaces it, removing %d entries that aren't staged.\n", removed)
+}
+
+// upstreamKeyset reads the keyset at path in the repository at url, without
+// cloning it: from the copy pulled before if there's one, otherwise straight
+// from GitHub. It also returns where it was read from, and an error
+// satisfying os.IsNotExist if the keyset isn't there.
+func upstreamKeyset(url, path string) ([]utils.KeysetEntry, string, error) {
+	if source := config.SourcePath(url); utils.FileExists(source) {
+		entries, err := utils.ReadKeysetEntries(filepath.Join(source, filepath.FromSlash(path)))
+		return entries, url + " as last pulled", err
+	}
+	if !strings.HasPrefix(url, "https://github.com/") {
+		return nil, url, fmt.Errorf("%v can only be compared with once it's pulled, ie with \"ait index\"", url)
+	}
+	raw := fmt.Sprintf("https://raw.githubusercontent.com/%v/%v/HEAD/%v",
+		utils.GetRepoOwner(url), utils.GetRepoName(url), strings.TrimPrefix(utils.SlashPath(path), "/"))
+	resp, err := utils.PinnedClient(config.Global.Trust.Hosts).Get(raw)
+	if err != nil {
+		return nil, url, err
+	}
+	defer resp.Body.Close()
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		return nil, url, os.ErrNotExist
+	case resp.StatusCode != http.StatusOK:
+		return nil, url, fmt.Errorf("unable to read %v: %v", raw, resp.Status)
+	}
+	entries, err := utils.ParseKeysetEntries(resp.Body)
+	return entries, url, err
+}
+
+// useOnConflict makes amending keysets resolve conflicts as onConflict says,
+// asking about each one when it's empty, unless prompts are disabled.
+func useOnConflict(onConflict string) {
+	switch onConflict {
+	case "", keysets.ConflictOurs, keysets.ConflictTheirs, keysets.ConflictAbort:
+	default:
+		utils.FatalPrintf("--on-conflict must be ours, theirs or abort, not %q.\n", onConflict)
+	}
+	keysets.OnConflict = onConflict
+	keysets.ResolveConflict = nil
+	if !utils.NonInteractive {
+		keysets.ResolveConflict = promptConflict
+	}
+}
+
+// promptConflict asks whether to keep the staged file or the entry of the
+// keyset it's named like.
+func promptConflict(c keysets.Conflict) string {
+	fmt.Printf("%v is already in the keyset as %v, the staged file is %v.\n"+
+		"Replace it with yours (o), keep theirs (t), or abort (any other key)? ", c.Name, c.Theirs, c.Ours)
+	switch strings.ToLower(utils.ReadAnswer()) {
+	case "o":
+		return keysets.ConflictOurs
+	case "t":
+		return keysets.ConflictTheirs
+	}
+	return keysets.ConflictAbort
+}
+
+// addFailuresPath records the staged files the last keyset generated couldn't
+// add, as JSON.
+var addFailuresPath = filepath.Join(".ait", "add_failures.json")
+
+// checkGenerated handles the error of generating the keyset at ksPath and
+// returns whether some staged files couldn't be added. Those are listed and
+// recorded in addFailuresPath, and are only an error with strict or when no
+// file could be added at all. A submission whose deadline passed while hashing
+// stops there with errPastDeadline. Any other error is returned. cleanup is
+// called before returning an error.
+func checkGenerated(err error, ksPath string, strict bool, cleanup func()) (bool, error) {
+	_ = os.Remove(addFailuresPath)
+	var conflictErr *keysets.ConflictError
+	if errors.As(err, &conflictErr) {
+		fmt.Println("Staged files are named like entries of the keyset with other contents:")
+		for _, c := range conflictErr.Conflicts {
+			fmt.Printf("\t%v: yours is %v, the keyset's is %v\n", c.Name, c.Ours, c.Theirs)
+		}
+		cleanup()
+		return false, errors.New("the keyset was left as it is, rename your files or choose " +
+			"with --on-conflict ours or theirs")
+	}
+	var deadlineErr *keysets.DeadlineError
+	if errors.As(err, &deadlineErr) {
+		stopHashingAtDeadline(deadlineErr)
+		return false, errPastDeadline
+	}
+	var addErr *keysets.AddError
+	if !errors.As(err, &addErr) {
+		if err != nil {
+			cleanup()
+		}
+		return false, err
+	}
+	fmt.Printf("%d staged file(s) couldn't be added and were left out of the keyset:\n", len(addErr.Failures))
+	for _, failure := range addErr.Failures {
+		fmt.Printf("\t%v: %v\n", failure.Path, failure.Error)
+	}
+	if data, err := json.MarshalIndent(addErr.Failures, "", "  "); err == nil {
+		if ioutil.WriteFile(addFailuresPath, data, 0644) == nil {
+			fmt.Println("They are listed in", addFailuresPath)
+		}
+	}
+	if entries, _ := utils.ReadKeysetEntries(ksPath); len(entries) == 0 {
+		cleanup()
+		return true, errors.New("none of the staged files could be added")
+	}
+	if strict {
+		cleanup()
+		return true, errors.New("stopping, as --strict was given")
+	}
+	return true, nil
+}
@@ -0,0 +1,167 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/arken/ait/config"
+	"github.com/arken/ait/display"
+	"github.com/arken/ait/utils"
+
+	"github.com/DataDrake/cli-ng/v2/cmd"
+)
+
+// Log lists the submissions made from the workspace.
+var Log = cmd.Sub{
+	Name:  "log",
+	Short: "List the submissions made from this workspace, or show one of them.",
+	Args:  &LogArgs{},
+	Flags: &LogFlags{},
+	Run:   LogRun,
+}
+
+// LogArgs handles the specific arguments for the log command.
+type LogArgs struct {
+	Args []string `zero:"yes" desc:"show <id> to show a submission"`
+}
+
+// LogFlags handles the specific flags for the log command.
+type LogFlags struct {
+	Remote string `short:"r" long:"remote" desc:"Only list the submissions to this remote"`
+}
+
+const logUsage = `	ait log             # List the submissions, newest first
+	ait log show <id>   # Show where a submission went and the entries of its keyset`
+
+// LogRun lists the submissions of the history or shows one.
+func LogRun(_ *cmd.Root, c *cmd.Sub) {
+	args := c.Args.(*LogArgs).Args
+	flags := c.Flags.(*LogFlags)
+	history, err := utils.ReadHistory()
+	utils.CheckError(err)
+	switch {
+	case len(args) == 0:
+		listSubmissions(history, flags.Remote)
+	case args[0] == "show" && len(args) == 2:
+		showSubmission(findSubmission(history, args[1]))
+	default:
+		utils.FatalPrintln("Expected no arguments or show <id>:\n" + logUsage)
+	}
+}
+
+// listSubmissions prints the submissions to remote, or to every remote when
+// it's empty, newest first.
+func listSubmissions(history []*utils.Submission, remote string) {
+	if remote != "" {
+		remote = config.GetPushRemote(remote)
+	}
+	listed := submissionsTo(history, remote)
+	display.Out.Result(listed, func(w io.Writer) {
+		printSubmissions(w, listed)
+	})
+}
+
+// submissionsTo returns the submissions of the history to remote, or to every
+// remote when it's empty, newest first.
+func submissionsTo(history []*utils.Submission, remote string) []*utils.Submission {
+	var listed []*utils.Submission
+	for i := len(history) - 1; i >= 0; i-- {
+		if remote == "" || history[i].Remote == remote {
+			listed = append(listed, history[i])
+		}
+	}
+	return listed
+}
+
+// printSubmissions writes a line for each submission to w, with where it can
+// be followed below it.
+func printSubmissions(w io.Writer, listed []*utils.Submission) {
+	if len(listed) == 0 {
+		fmt.Fprintln(w, "Nothing was submitted from this workspace.")
+		return
+	}
+	for _, s := range listed {
+		fmt.Fprintf(w, "%v  %v  %v in %v, %d file(s)\n", s.ID, s.Time.Local().Format("Jan 2 2006 15:04"),
+			s.Path, s.Remote, len(s.Entries))
+		if where := submissionLink(s); where != "" {
+			fmt.Fprintf(w, "\t%v\n", where)
+		}
+	}
+}
+
+// submissionLink returns where the submission can be followed: its pull
+// request, issue or commit.
+func submissionLink(s *utils.Submission) string {
+	switch {
+	case s.PullRequest != "":
+		return s.PullRequest
+	case s.Issue != "":
+		return s.Issue
+	case s.Commit != "":
+		return "commit " + s.Commit
+	}
+	return ""
+}
+
+// findSubmission returns the submission of the history whose ID is id, or
+// the only one it starts.
+func findSubmission(history []*utils.Submission, id string) *utils.Submission {
+	found := matchSubmissions(history, id)
+	switch len(found) {
+	case 0:
+		utils.FatalPrintf("No submission %v is in the history, \"ait log\" lists them.\n", id)
+	case 1:
+	default:
+		utils.FatalPrintf("%d submissions start with %v, give more of the ID.\n", len(found), id)
+	}
+	return found[0]
+}
+
+// matchSubmissions returns the submission of the history whose ID is id or,
+// if none is, those whose ID starts with it.
+func matchSubmissions(history []*utils.Submission, id string) []*utils.Submission {
+	var found []*utils.Submission
+	for _, s := range history {
+		if s.ID == id {
+			return []*utils.Submission{s}
+		}
+		if strings.HasPrefix(s.ID, id) {
+			found = append(found, s)
+		}
+	}
+	return found
+}
+
+// showSubmission prints the details of a submission and the entries of its
+// keyset.
+func showSubmission(s *utils.Submission) {
+	display.Out.Result(s, func(w io.Writer) {
+		fmt.Fprintf(w, "Submission %v\n", s.ID)
+		fields := []struct{ label, value string }{
+			{"Time", s.Time.Local().Format("Jan 2 2006 15:04:05")},
+			{"Remote", s.Remote},
+			{"Keyset", s.Path},
+			{"Commit", s.Commit},
+			{"Pull request", s.PullRequest},
+			{"Issue", s.Issue},
+			{"DOI", s.DOI},
+			{"Root", s.Root},
+		}
+		for _, field := range fields {
+			if field.value != "" {
+				fmt.Fprintf(w, "\t%-14v%v\n", field.label+":", field.value)
+			}
+		}
+		switch {
+		case s.AtRisk:
+			fmt.Fprintf(w, "\t%-14v%v\n", "Replication:", "at risk, some entries fell below the target")
+		case s.Safe:
+			fmt.Fprintf(w, "\t%-14v%v\n", "Replication:", "every entry reached the target")
+		}
+		fmt.Fprintf(w, "\nEntries (%d):\n", len(s.Entries))
+		for _, entry := range s.Entries {
+			fmt.Fprintf(w, "\t%v  %v\n", entry.CID, entry.Name)
+		}
+	})
+}
@@ -0,0 +1,103 @@
+package cli
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/arken/ait/utils"
+)
+
+// logHistory is a history of three submissions, oldest first.
+func logHistory() []*utils.Submission {
+	at := time.Date(2021, 6, 1, 12, 0, 0, 0, time.UTC)
+	return []*utils.Submission{
+		{ID: "20210601-120000", Time: at, Remote: "https://github.com/arken/core-keyset", Path: "space/moon.ks",
+			PullRequest: "https://github.com/arken/core-keyset/pull/12",
+			Entries:     []utils.KeysetEntry{{CID: "bafymoon", Name: "moon.fits"}}},
+		{ID: "20210602-090000", Time: at.Add(21 * time.Hour), Remote: "https://github.com/lab/keyset", Path: "reads.ks",
+			Commit: "4f2a9c1"},
+		{ID: "20210602-093000", Time: at.Add(21*time.Hour + 30*time.Minute), Remote: "https://github.com/arken/core-keyset",
+			Path: "space/mars.ks", Issue: "https://github.com/arken/core-keyset/issues/3"},
+	}
+}
+
+func TestSubmissionsTo(t *testing.T) {
+	history := logHistory()
+	var ids []string
+	for _, s := range submissionsTo(history, "") {
+		ids = append(ids, s.ID)
+	}
+	if strings.Join(ids, " ") != "20210602-093000 20210602-090000 20210601-120000" {
+		t.Errorf("expected every submission newest first, got %v", ids)
+	}
+	ids = nil
+	for _, s := range submissionsTo(history, "https://github.com/arken/core-keyset") {
+		ids = append(ids, s.ID)
+	}
+	if strings.Join(ids, " ") != "20210602-093000 20210601-120000" {
+		t.Errorf("expected the submissions to the remote only, got %v", ids)
+	}
+	if listed := submissionsTo(history, "https://github.com/other/keyset"); len(listed) != 0 {
+		t.Errorf("expected no submission to another remote, got %v", listed)
+	}
+}
+
+func TestPrintSubmissions(t *testing.T) {
+	var out bytes.Buffer
+	printSubmissions(&out, submissionsTo(logHistory(), ""))
+	text := out.String()
+	for _, expected := range []string{
+		"space/mars.ks in https://github.com/arken/core-keyset, 0 file(s)\n\thttps://github.com/arken/core-keyset/issues/3\n",
+		"reads.ks in https://github.com/lab/keyset, 0 file(s)\n\tcommit 4f2a9c1\n",
+		"space/moon.ks in https://github.com/arken/core-keyset, 1 file(s)\n\thttps://github.com/arken/core-keyset/pull/12\n",
+	} {
+		if !strings.Contains(text, expected) {
+			t.Errorf("expected %q in the list, got:\n%v", expected, text)
+		}
+	}
+	out.Reset()
+	printSubmissions(&out, nil)
+	if out.String() != "Nothing was submitted from this workspace.\n" {
+		t.Errorf("wrong output without submissions: %q", out.String())
+	}
+}
+
+func TestFindSubmission(t *testing.T) {
+	history := logHistory()
+	if s := findSubmission(history, "20210601-120000"); s != history[0] {
+		t.Errorf("expected the submission with the exact ID, got %v", s.ID)
+	}
+	if s := findSubmission(history, "20210601"); s != history[0] {
+		t.Errorf("expected the only submission the prefix starts, got %v", s.ID)
+	}
+	if found := matchSubmissions(history, "20210602-09"); len(found) != 2 {
+		t.Errorf("expected both submissions of the prefix, got %d", len(found))
+	}
+
+	defer func(output, fatal func(string), before func()) {
+		utils.FatalOutput, utils.BeforeFatal, utils.BeforeExit = output, fatal, before
+	}(utils.FatalOutput, utils.BeforeFatal, utils.BeforeExit)
+	utils.BeforeFatal = nil
+	var msg string
+	utils.FatalOutput = func(m string) { msg = m }
+	utils.BeforeExit = func() { panic("exit") }
+	for id, expected := range map[string]string{
+		"20200101":    `No submission 20200101 is in the history, "ait log" lists them.`,
+		"20210602-09": "2 submissions start with 20210602-09, give more of the ID.",
+	} {
+		msg = ""
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("expected %v to fail", id)
+				}
+			}()
+			findSubmission(history, id)
+		}()
+		if msg != expected {
+			t.Errorf("expected %q for %v, got %q", expected, id, msg)
+		}
+	}
+}
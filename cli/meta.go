@@ -0,0 +1,149 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/arken/ait/display"
+	"github.com/arken/ait/utils"
+
+	"github.com/DataDrake/cli-ng/v2/cmd"
+)
+
+// Meta edits the metadata recorded with files in their keyset entries.
+var Meta = cmd.Sub{
+	Name:  "meta",
+	Short: "Set, unset or show the description, license, tags and other metadata of files.",
+	Args:  &MetaArgs{},
+	Run:   MetaRun,
+}
+
+// MetaArgs handles the specific arguments for the meta command.
+type MetaArgs struct {
+	Action string   `desc:"The operation to perform: set, unset or show"`
+	Args   []string `zero:"yes" desc:"The file or directory, then key=value pairs or keys"`
+}
+
+const metaUsage = `	ait meta set <path> key=value...   # Attach metadata to a file, or every file of a directory
+	ait meta set data tags=genomics,rna license=CC-BY-4.0
+	ait meta unset <path> key...       # Remove metadata from a file or directory
+	ait meta show [path]               # Show the metadata of a file, or of every path
+	ait meta show <keyset.ks>          # Show the metadata recorded in a keyset`
+
+// MetaRun dispatches to the requested metadata operation.
+func MetaRun(_ *cmd.Root, c *cmd.Sub) {
+	args := c.Args.(*MetaArgs)
+	switch args.Action {
+	case "set", "unset":
+		if len(args.Args) < 2 {
+			utils.FatalPrintf("Expected a path and what to %v:\n%v\n", args.Action, metaUsage)
+		}
+		path, err := utils.WorkspacePath(args.Args[0])
+		if err != nil {
+			utils.FatalPrintf("Will not attach metadata to files that are not in this AIT repo: %v\n", err)
+		}
+		if !utils.FileExists(path) {
+			utils.FatalPrintf("%v doesn't exist.\n", args.Args[0])
+		}
+		if args.Action == "set" {
+			fields := make(map[string]string)
+			for _, arg := range args.Args[1:] {
+				key, value, err := utils.ParseMetadataAssignment(arg)
+				utils.CheckError(err)
+				fields[key] = value
+			}
+			setFileMetadata([]string{path}, fields)
+		} else {
+			unsetFileMetadata(path, args.Args[1:])
+		}
+	case "show":
+		if len(args.Args) > 1 {
+			utils.FatalPrintln("Expected at most one path:\n" + metaUsage)
+		}
+		showMetadata(args.Args)
+	default:
+		utils.FatalPrintf("Unknown action %q:\n%v\n", args.Action, metaUsage)
+	}
+}
+
+// setFileMetadata attaches fields to the workspace paths. Empty values remove
+// the field.
+func setFileMetadata(paths []string, fields map[string]string) {
+	metadata, err := utils.ReadFileMetadata()
+	utils.CheckError(err)
+	for _, path := range paths {
+		if metadata[path] == nil {
+			metadata[path] = make(map[string]string)
+		}
+		for key, value := range fields {
+			if value == "" {
+				delete(metadata[path], key)
+			} else {
+				metadata[path][key] = value
+			}
+		}
+	}
+	utils.CheckError(utils.WriteFileMetadata(metadata))
+	fmt.Printf("Updated the metadata of %v path(s), it's recorded in the keyset the next time "+
+		"it's generated.\n", len(paths))
+}
+
+// unsetFileMetadata removes the keys from the metadata of the workspace path.
+func unsetFileMetadata(path string, keys []string) {
+	metadata, err := utils.ReadFileMetadata()
+	utils.CheckError(err)
+	for _, key := range keys {
+		if _, ok := metadata[path][key]; !ok {
+			fmt.Printf("%v has no %q set, skipping it.\n", path, key)
+		}
+		delete(metadata[path], key)
+	}
+	utils.CheckError(utils.WriteFileMetadata(metadata))
+}
+
+// showMetadata prints the metadata of a file with what it inherits from its
+// directories, of a keyset file's entries, or of every path metadata is
+// attached to.
+func showMetadata(args []string) {
+	var shown map[string]map[string]string
+	switch {
+	case len(args) == 1 && isKeysetFile(args[0]):
+		file, err := os.Open(args[0])
+		utils.CheckError(err)
+		shown, err = utils.ParseKeysetMetadata(file)
+		file.Close()
+		utils.CheckError(err)
+	case len(args) == 1:
+		path, err := utils.WorkspacePath(args[0])
+		utils.CheckError(err)
+		metadata, err := utils.ReadFileMetadata()
+		utils.CheckError(err)
+		shown = map[string]map[string]string{path: utils.MetadataFor(metadata, path)}
+	default:
+		var err error
+		shown, err = utils.ReadFileMetadata()
+		utils.CheckError(err)
+	}
+	display.Out.Result(shown, func(w io.Writer) {
+		names := make([]string, 0, len(shown))
+		for name, fields := range shown {
+			if len(fields) > 0 {
+				names = append(names, name)
+			}
+		}
+		if len(names) == 0 {
+			fmt.Fprintln(w, "No metadata is set.")
+			return
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			fmt.Fprintln(w, name)
+			for _, line := range utils.KeysetMetadataLines(shown[name]) {
+				fmt.Fprintln(w, "\t"+strings.TrimSpace(strings.TrimPrefix(line, "#")))
+			}
+		}
+	})
+}
@@ -0,0 +1,178 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/arken/ait/apis/mirror"
+	"github.com/arken/ait/display"
+	"github.com/arken/ait/types"
+	"github.com/arken/ait/utils"
+
+	"github.com/DataDrake/cli-ng/v2/cmd"
+)
+
+// Mirror downloads a published dataset into the workspace so it can be
+// archived on Arken.
+var Mirror = cmd.Sub{
+	Name:  "mirror",
+	Short: "Download a figshare, Dataverse or NCBI dataset, stage it and prepare its submission.",
+	Args:  &MirrorArgs{},
+	Run:   MirrorRun,
+}
+
+// MirrorArgs handles the specific arguments for the mirror command.
+type MirrorArgs struct {
+	// ID is a DOI, a GEO series accession or a genome assembly accession.
+	ID string
+	// Dest is optional, named after the identifier by default.
+	Dest []string
+}
+
+// provenancePath records where a mirrored dataset came from, it's staged with
+// the dataset.
+const provenancePath = "PROVENANCE.json"
+
+// provenance is the record of a mirrored dataset.
+type provenance struct {
+	Identifier  string           `json:"identifier"`
+	Repository  string           `json:"repository"`
+	Title       string           `json:"title"`
+	Authors     []string         `json:"authors,omitempty"`
+	License     string           `json:"license,omitempty"`
+	LandingPage string           `json:"landingPage,omitempty"`
+	Retrieved   time.Time        `json:"retrieved"`
+	Files       []provenanceFile `json:"files"`
+}
+
+// provenanceFile is a file of a mirrored dataset.
+type provenanceFile struct {
+	Name   string `json:"name"`
+	URL    string `json:"url"`
+	Size   int64  `json:"size"`
+	MD5    string `json:"md5,omitempty"`
+	SHA256 string `json:"sha256"`
+}
+
+var unsafeDirChars = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// MirrorRun resolves the dataset, downloads any of its files that aren't
+// already in the workspace and stages them with a record of their provenance.
+// The files are added to the workspace's catalog and, unless one is already
+// being written, an application is prepared for "ait submit".
+func MirrorRun(_ *cmd.Root, c *cmd.Sub) {
+	args := c.Args.(*MirrorArgs)
+	dataset, err := mirror.Resolve(args.ID)
+	utils.CheckError(err)
+	if len(dataset.Files) == 0 {
+		utils.FatalPrintf("%v has no files to mirror.\n", dataset.Identifier)
+	}
+	dest := unsafeDirChars.ReplaceAllString(dataset.Identifier, "-")
+	if len(args.Dest) > 1 {
+		utils.FatalPrintln("Expected one destination directory.")
+	} else if len(args.Dest) == 1 {
+		dest = args.Dest[0]
+	}
+	dest = filepath.Clean(dest)
+	withinRepo, err := utils.IsWithinRepo(dest)
+	utils.CheckError(err)
+	if !withinRepo {
+		utils.FatalPrintln("The destination must be inside this AIT repo.")
+	}
+
+	var total int64
+	for _, file := range dataset.Files {
+		total += file.Size
+	}
+	fmt.Printf("%v: %v\n%d file(s) from %v", dataset.Identifier, dataset.Title, len(dataset.Files),
+		dataset.Repository)
+	if total > 0 {
+		fmt.Printf(", %v", utils.FormatByteSize(total))
+	}
+	fmt.Println()
+
+	record := provenance{
+		Identifier:  dataset.Identifier,
+		Repository:  dataset.Repository,
+		Title:       dataset.Title,
+		Authors:     dataset.Authors,
+		License:     dataset.License,
+		LandingPage: dataset.LandingPage,
+		Retrieved:   time.Now().UTC(),
+	}
+	catalog, err := utils.ReadCatalog()
+	utils.CheckError(err)
+	for i, file := range dataset.Files {
+		path := filepath.Join(dest, filepath.FromSlash(file.Name))
+		utils.CheckError(os.MkdirAll(filepath.Dir(path), os.ModePerm))
+		if size, err := utils.GetFileSize(path); err == nil && file.Size > 0 && size == file.Size {
+			fmt.Printf("[%d/%d] %v is already downloaded\n", i+1, len(dataset.Files), file.Name)
+		} else {
+			fmt.Printf("[%d/%d] Downloading %v\n", i+1, len(dataset.Files), file.Name)
+			utils.CheckError(mirror.Download(file, path))
+		}
+		sum, err := utils.FileSHA256(path)
+		utils.CheckError(err)
+		size, err := utils.GetFileSize(path)
+		utils.CheckError(err)
+		record.Files = append(record.Files, provenanceFile{
+			Name:   file.Name,
+			URL:    file.URL,
+			Size:   size,
+			MD5:    file.MD5,
+			SHA256: sum,
+		})
+		metadata := map[string]string{"source": file.URL, "repository": dataset.Repository}
+		if dataset.License != "" {
+			metadata["license"] = dataset.License
+		}
+		catalog[path] = utils.CatalogRecord{
+			Path:     path,
+			SHA256:   sum,
+			ID:       dataset.Identifier + "/" + file.Name,
+			Metadata: metadata,
+			Verified: true,
+		}
+	}
+	data, err := json.MarshalIndent(record, "", "  ")
+	utils.CheckError(err)
+	utils.CheckError(ioutil.WriteFile(filepath.Join(dest, provenancePath), data, 0644))
+	utils.CheckError(utils.WriteCatalog(catalog))
+	fmt.Println(stagePath(dest), "file(s) added")
+
+	if app := display.ReadApplication(); app != nil && !app.IsEmpty() {
+		fmt.Println("An application is already being written, it was left as is.")
+		return
+	}
+	utils.CheckError(display.WriteApplication(mirrorApplication(dataset, dest)))
+	fmt.Println("Prepared the application, run \"ait submit\" to review and submit it.")
+}
+
+// mirrorApplication describes the mirror of dataset for its submission.
+func mirrorApplication(dataset *mirror.Dataset, dest string) *types.ApplicationContents {
+	var body strings.Builder
+	fmt.Fprintf(&body, "Mirror of %v (%v) from %v.\n\n", dataset.Title, dataset.Identifier, dataset.Repository)
+	if len(dataset.Authors) > 0 {
+		fmt.Fprintf(&body, "Authors: %v\n", strings.Join(dataset.Authors, "; "))
+	}
+	if dataset.License != "" {
+		fmt.Fprintf(&body, "License: %v\n", dataset.License)
+	}
+	if dataset.LandingPage != "" {
+		fmt.Fprintf(&body, "Source: %v\n", dataset.LandingPage)
+	}
+	fmt.Fprintf(&body, "Files: %d, checksums are recorded in %v\n", len(dataset.Files),
+		filepath.ToSlash(filepath.Join(dest, provenancePath)))
+	return &types.ApplicationContents{
+		KsName: filepath.Base(dest) + ".ks",
+		Title:  "Mirror " + dataset.Title,
+		Commit: fmt.Sprintf("Mirror %v from %v", dataset.Identifier, dataset.Repository),
+		PRBody: body.String(),
+	}
+}
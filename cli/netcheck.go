@@ -0,0 +1,102 @@
+package cli
+
+import (
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/arken/ait/config"
+	"github.com/arken/ait/ipfs"
+	"github.com/arken/ait/utils"
+
+	"github.com/DataDrake/cli-ng/v2/cmd"
+)
+
+// NetCheck tests connectivity to the Arken network and the services ait
+// submits through, without changing anything.
+var NetCheck = cmd.Sub{
+	Name:  "netcheck",
+	Short: "Check connectivity to the Arken network, GitHub and git remotes.",
+	Run:   NetCheckRun,
+}
+
+// netcheckTimeout bounds each check.
+const netcheckTimeout = 30 * time.Second
+
+// NetCheckRun runs every check and prints a pass/fail matrix. It exits with
+// an error if any check failed.
+func NetCheckRun(_ *cmd.Root, _ *cmd.Sub) {
+	var checks []ipfs.Check
+	for _, dial := range ipfs.DialArkenPeers(netcheckTimeout) {
+		check := ipfs.Check{Name: fmt.Sprintf("Arken %v (%v)", dial.Name, dial.Host), OK: dial.Err == nil}
+		if dial.Err != nil {
+			check.Detail = dial.Err.Error()
+		} else {
+			check.Detail = fmt.Sprintf("TCP connect in %v", dial.Latency.Round(time.Millisecond))
+		}
+		checks = append(checks, check)
+	}
+	checks = append(checks, checkHTTPS("GitHub API (HTTPS)", "https://api.github.com"))
+	checks = append(checks, checkHTTPS("GitHub (git over HTTPS)", "https://github.com/arken/ait.git/info/refs?service=git-upload-pack"))
+	checks = append(checks, checkTCP("GitHub (git over SSH)", "github.com:22"))
+
+	fmt.Printf("Starting the IPFS node, this takes up to %v...\n", netcheckTimeout)
+	ipfs.Init(false)
+	checks = append(checks, ipfs.CheckNode(netcheckTimeout)...)
+
+	if failed := printChecks(checks); failed > 0 {
+		utils.FatalPrintf("\n%d of %d checks failed.\n", failed, len(checks))
+	}
+	fmt.Println("\nAll checks passed.")
+}
+
+// printChecks prints checks as a pass/fail matrix, with what can be done
+// about the failed ones, and returns how many failed.
+func printChecks(checks []ipfs.Check) int {
+	width := 0
+	for _, check := range checks {
+		if len(check.Name) > width {
+			width = len(check.Name)
+		}
+	}
+	failed := 0
+	fmt.Println()
+	for _, check := range checks {
+		result := "PASS"
+		if !check.OK {
+			result = "FAIL"
+			failed++
+		}
+		fmt.Printf("%-*v  %v  %v\n", width, check.Name, result, check.Detail)
+		if !check.OK && check.Fix != "" {
+			fmt.Printf("%-*v        Fix: %v\n", width, "", check.Fix)
+		}
+	}
+	return failed
+}
+
+// checkHTTPS checks that url can be fetched, honoring pinned hosts.
+func checkHTTPS(name, url string) ipfs.Check {
+	client := utils.PinnedClient(config.Global.Trust.Hosts)
+	client.Timeout = netcheckTimeout
+	start := time.Now()
+	resp, err := client.Get(url)
+	if err != nil {
+		return ipfs.Check{Name: name, Detail: err.Error()}
+	}
+	resp.Body.Close()
+	return ipfs.Check{Name: name, OK: resp.StatusCode < 500,
+		Detail: fmt.Sprintf("%v in %v", resp.Status, time.Since(start).Round(time.Millisecond))}
+}
+
+// checkTCP checks that address accepts TCP connections.
+func checkTCP(name, address string) ipfs.Check {
+	start := time.Now()
+	conn, err := net.DialTimeout("tcp", address, netcheckTimeout)
+	if err != nil {
+		return ipfs.Check{Name: name, Detail: err.Error()}
+	}
+	conn.Close()
+	return ipfs.Check{Name: name, OK: true,
+		Detail: fmt.Sprintf("TCP connect in %v", time.Since(start).Round(time.Millisecond))}
+}
@@ -0,0 +1,327 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/arken/ait/display"
+	"github.com/arken/ait/ipfs"
+	"github.com/arken/ait/keysets"
+	"github.com/arken/ait/utils"
+
+	"github.com/DataDrake/cli-ng/v2/cmd"
+	"github.com/libp2p/go-libp2p-core/peer"
+)
+
+// Plan proposes how a team can share the replication of a keyset.
+var Plan = cmd.Sub{
+	Name:  "plan",
+	Short: "Plan which peers of a team pin which files of a keyset, and apply the plan.",
+	Args:  &PlanArgs{},
+	Flags: &PlanFlags{},
+	Run:   PlanRun,
+}
+
+// PlanArgs handles the specific arguments for the plan command.
+type PlanArgs struct {
+	Keyset string   `desc:"A keyset file or repository to plan every keyset of, or apply or watch"`
+	Peers  []string `desc:"The team's peers with the space each offers, as <peer ID>=<size>, ie 12D3Koo...=2TB, or the pin list to apply"`
+}
+
+// PlanFlags handles the specific flags for the plan command.
+type PlanFlags struct {
+	Replicas  int    `short:"r" long:"replicas" desc:"Number of peers that should pin each file, 3 by default"`
+	Out       string `short:"o" long:"out" desc:"Write the pin list of each peer to <peer ID>.txt in this directory"`
+	Providers bool   `short:"p" long:"providers" desc:"Prefer giving files to the peers that already provide them"`
+	Timeout   string `short:"t" long:"timeout" desc:"How long to look up the size of each file, 10s by default"`
+	Topic     string `long:"topic" desc:"Pubsub topic keepers report the progress of their pin lists on, ait-plan by default"`
+}
+
+const planUsage = `	ait plan <keyset> <peer ID>=<size>...   # Propose which peer pins which files
+	ait plan apply <pin list>             # Pin exactly the CIDs of this keeper's list
+	ait plan watch                        # Follow the keepers applying their lists`
+
+// defaultPlanTopic is the pubsub topic plan progress is reported on.
+const defaultPlanTopic = "ait-plan"
+
+// planReportPeriod is how often a keeper reports the progress of its list.
+const planReportPeriod = 30 * time.Second
+
+// PlanRun looks up the size of every file of the keyset and assigns each to
+// enough peers of the team within their budgets. Nothing is pinned, each peer
+// applies its list with "ait plan apply". "apply" and "watch" in place of the
+// keyset run the keeper and coordinator sides of a plan.
+func PlanRun(_ *cmd.Root, c *cmd.Sub) {
+	args := c.Args.(*PlanArgs)
+	flags := c.Flags.(*PlanFlags)
+	if flags.Topic == "" {
+		flags.Topic = defaultPlanTopic
+	}
+	switch args.Keyset {
+	case "apply":
+		if len(args.Peers) != 1 {
+			utils.FatalPrintln("Expected the pin list to apply:\n" + planUsage)
+		}
+		applyPinList(args.Peers[0], flags.Topic)
+		return
+	case "watch":
+		watchPlan(flags.Topic)
+		return
+	}
+	if len(args.Peers) == 0 {
+		utils.FatalPrintln("Give the team's peers as <peer ID>=<size>:\n" + planUsage)
+	}
+	peers := make([]keysets.PlanPeer, len(args.Peers))
+	ids := make([]peer.ID, len(args.Peers))
+	for i, arg := range args.Peers {
+		var err error
+		peers[i], ids[i], err = parsePlanPeer(arg)
+		utils.CheckError(err)
+	}
+	replicas := flags.Replicas
+	if replicas <= 0 {
+		replicas = replicationTarget()
+	}
+	if replicas > len(peers) {
+		utils.FatalPrintf("%d replicas need at least as many peers, only %d were given.\n", replicas, len(peers))
+	}
+	timeout := coverageTimeout
+	if flags.Timeout != "" {
+		var err error
+		timeout, err = time.ParseDuration(flags.Timeout)
+		utils.CheckError(err)
+	}
+	entries, source := loadKeyset(args.Keyset)
+	cids := uniqueCIDs(entries)
+	if len(cids) == 0 {
+		utils.FatalPrintln(source + " has no entries.")
+	}
+
+	ipfs.Init(false)
+	fmt.Printf("Looking up the %d file(s) of %v:\n", len(cids), source)
+	files := make([]keysets.PlanFile, len(cids))
+	unknown := 0
+	bar := display.NewProgress("Looking up", int64(len(cids)), false)
+	jobs := make(chan int)
+	lock := sync.Mutex{}
+	wg := sync.WaitGroup{}
+	for w := 0; w < genNumWorkers(); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				files[i].CID = cids[i]
+				size, err := ipfs.Size(cids[i], timeout)
+				if err != nil {
+					lock.Lock()
+					unknown++
+					lock.Unlock()
+				}
+				files[i].Size = size
+				if flags.Providers {
+					for p, id := range ids {
+						if ok, _ := ipfs.ProvidedBy(cids[i], id, timeout); ok {
+							files[i].Holders = append(files[i].Holders, p)
+						}
+					}
+				}
+				bar.Add(1)
+			}
+		}()
+	}
+	for i := range cids {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+	if unknown > 0 {
+		fmt.Printf("The size of %d file(s) couldn't be found, they are planned as empty.\n", unknown)
+	}
+
+	plan := keysets.MakePlan(files, peers, replicas)
+	fmt.Printf("Plan for %d replica(s) of each file:\n", replicas)
+	for p, pins := range plan.Pins {
+		fmt.Printf("\t%v  %d file(s), %v of %v\n", peers[p].ID, len(pins),
+			utils.FormatByteSize(plan.Used[p]), utils.FormatByteSize(peers[p].Budget))
+	}
+	if len(plan.Short) > 0 {
+		fmt.Printf("%d file(s) don't fit on %d peers within their budgets:\n", len(plan.Short), replicas)
+		names := namesByCID(entries)
+		for _, cid := range cids {
+			if placed, ok := plan.Short[cid]; ok {
+				fmt.Printf("\t%v  %v  %d replica(s)\n", cid, strings.Join(names[cid], ", "), placed)
+			}
+		}
+	}
+	if flags.Out != "" {
+		utils.CheckError(writePinLists(flags.Out, peers, plan))
+		fmt.Println("Wrote the pin list of each peer to", flags.Out)
+	}
+}
+
+// parsePlanPeer parses a "<peer ID>=<size>" argument.
+func parsePlanPeer(arg string) (keysets.PlanPeer, peer.ID, error) {
+	parts := strings.SplitN(arg, "=", 2)
+	if len(parts) != 2 {
+		return keysets.PlanPeer{}, "", fmt.Errorf("expected <peer ID>=<size>, got %q", arg)
+	}
+	id, err := peer.Decode(parts[0])
+	if err != nil {
+		return keysets.PlanPeer{}, "", fmt.Errorf("%q isn't a peer ID: %v", parts[0], err)
+	}
+	budget, err := utils.ParseByteSize(parts[1])
+	if err != nil {
+		return keysets.PlanPeer{}, "", err
+	}
+	return keysets.PlanPeer{ID: parts[0], Budget: budget}, id, nil
+}
+
+// writePinLists writes the CIDs each peer should pin to <peer ID>.txt in dir,
+// one per line.
+func writePinLists(dir string, peers []keysets.PlanPeer, plan *keysets.Plan) error {
+	if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+		return err
+	}
+	for p, pins := range plan.Pins {
+		var list strings.Builder
+		for _, cid := range pins {
+			list.WriteString(cid + "\n")
+		}
+		err := ioutil.WriteFile(filepath.Join(dir, peers[p].ID+".txt"), []byte(list.String()), 0644)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// planReport is the progress of a keeper applying its pin list, published on
+// the plan's topic as JSON.
+type planReport struct {
+	Peer   string    `json:"peer"`
+	List   string    `json:"list"`
+	Pinned int       `json:"pinned"`
+	Failed int       `json:"failed"`
+	Total  int       `json:"total"`
+	Done   bool      `json:"done"`
+	Time   time.Time `json:"time"`
+}
+
+// applyPinList pins exactly the CIDs of the pin list at path: those of the
+// previously applied list that it doesn't hold anymore are unpinned, unless a
+// submission protects them. Progress is published on topic as it goes.
+func applyPinList(path, topic string) {
+	data, err := ioutil.ReadFile(path)
+	utils.CheckError(err)
+	var cids []string
+	for _, line := range strings.Split(string(data), "\n") {
+		if line = strings.TrimSpace(line); line != "" && !strings.HasPrefix(line, "#") {
+			cids = append(cids, line)
+		}
+	}
+	ipfs.Init(false)
+	previous, err := ipfs.ReadPlanPins()
+	utils.CheckError(err)
+	for _, cid := range previous {
+		if utils.IndexOf(cids, cid) >= 0 {
+			continue
+		}
+		if err = ipfs.Unpin(cid); err != nil {
+			fmt.Printf("Keeping %v: %v\n", cid, err)
+		}
+	}
+	// Recorded first, so an interrupted run is completed by the next one
+	// rather than leaving pins behind.
+	utils.CheckError(ipfs.WritePlanPins(cids))
+
+	report := planReport{Peer: ipfs.GetID(), List: filepath.Base(path), Total: len(cids)}
+	lock := sync.Mutex{}
+	publish := func() {
+		lock.Lock()
+		report.Time = time.Now().UTC()
+		msg, _ := json.Marshal(report)
+		lock.Unlock()
+		if err := ipfs.PublishMessage(topic, msg); err != nil {
+			fmt.Printf("[Unable to report the progress on %v: %v]\n", topic, err)
+		}
+	}
+	stop := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(planReportPeriod)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				publish()
+			case <-stop:
+				return
+			}
+		}
+	}()
+
+	fmt.Printf("Pinning the %d CID(s) of %v:\n", len(cids), path)
+	bar := display.NewProgress("Pinning", int64(len(cids)), false)
+	jobs := make(chan string)
+	var failed []string
+	wg := sync.WaitGroup{}
+	for w := 0; w < genNumWorkers(); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for cid := range jobs {
+				err := ipfs.Pin(cid)
+				lock.Lock()
+				if err != nil {
+					report.Failed++
+					failed = append(failed, cid+": "+err.Error())
+				} else {
+					report.Pinned++
+				}
+				lock.Unlock()
+				bar.Add(1)
+			}
+		}()
+	}
+	for _, cid := range cids {
+		jobs <- cid
+	}
+	close(jobs)
+	wg.Wait()
+	close(stop)
+	report.Done = true
+	publish()
+
+	fmt.Printf("Pinned %d of %d CID(s).\n", report.Pinned, report.Total)
+	if len(failed) > 0 {
+		fmt.Println("Unable to pin:")
+		for _, line := range failed {
+			fmt.Println("\t" + line)
+		}
+	}
+}
+
+// watchPlan prints the progress keepers report on topic until interrupted.
+func watchPlan(topic string) {
+	ipfs.Init(false)
+	fmt.Printf("Following the keepers reporting on %v, press Ctrl+C to stop.\n", topic)
+	err := ipfs.Subscribe(context.Background(), topic, func(from string, data []byte) {
+		var report planReport
+		if json.Unmarshal(data, &report) != nil || report.Peer != from {
+			return
+		}
+		state := "in progress"
+		if report.Done {
+			state = "done"
+		}
+		fmt.Printf("%v  %v  %v: %d of %d pinned, %d failed, %v\n", report.Time.Local().Format("15:04:05"),
+			report.Peer, report.List, report.Pinned, report.Total, report.Failed, state)
+	})
+	utils.CheckError(err)
+}
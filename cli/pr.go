@@ -0,0 +1,151 @@
+package cli
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	aitgh "github.com/arken/ait/apis/github"
+	"github.com/arken/ait/config"
+	"github.com/arken/ait/ipfs"
+	"github.com/arken/ait/utils"
+
+	"github.com/DataDrake/cli-ng/v2/cmd"
+)
+
+// PR follows up on pull requests opened by ait submit.
+var PR = cmd.Sub{
+	Name:  "pr",
+	Short: "Follow up on pull requests made by your submissions.",
+	Args:  &PRArgs{},
+	Run:   PRRun,
+}
+
+// PRArgs handles the specific arguments for the pr command.
+type PRArgs struct {
+	Action string   `desc:"The operation to perform: update"`
+	Args   []string `zero:"yes" desc:"The pull request number, defaults to all open ones"`
+}
+
+const prUsage = `	ait pr update [number]   # Comment the replication of submitted files on the pull request`
+
+// replicationTarget returns the number of providers a file needs before it
+// is considered safely replicated, Notify.AtRiskThreshold in the config.
+func replicationTarget() int {
+	return config.Global.Notify.AtRiskThreshold
+}
+
+// maxReportRows keeps replication comments well under GitHub's size limit.
+const maxReportRows = 100
+
+// PRRun dispatches to the requested pr operation.
+func PRRun(_ *cmd.Root, c *cmd.Sub) {
+	args := c.Args.(*PRArgs)
+	switch args.Action {
+	case "update":
+		number := 0
+		if len(args.Args) > 0 {
+			var err error
+			number, err = strconv.Atoi(strings.TrimPrefix(args.Args[0], "#"))
+			if err != nil {
+				utils.FatalPrintln("Expected a pull request number:\n" + prUsage)
+			}
+		}
+		prUpdate(number)
+	default:
+		utils.FatalPrintln("Unknown pr operation \"" + args.Action + "\":\n" + prUsage)
+	}
+}
+
+// prUpdate posts a replication report on each open pull request recorded in
+// the history, or only on the given one.
+func prUpdate(number int) {
+	history, err := utils.ReadHistory()
+	utils.CheckError(err)
+	var pending []*utils.Submission
+	for _, s := range history {
+		if s.PRNumber == 0 || (number != 0 && s.PRNumber != number) {
+			continue
+		}
+		if number == 0 && s.Replicated {
+			continue
+		}
+		pending = append(pending, s)
+	}
+	if len(pending) == 0 {
+		fmt.Println("No pull requests are waiting on a replication report.")
+		return
+	}
+	prettyIPFSInit()
+	for _, s := range pending {
+		if _, err := aitgh.Init(s.Remote, true); err != nil {
+			fmt.Printf("Unable to reach %v for pull request #%d: %v\n", s.Remote, s.PRNumber, err)
+			continue
+		}
+		open, err := aitgh.PullRequestOpen(s.PRNumber)
+		if err != nil {
+			fmt.Printf("Unable to check pull request #%d: %v\n", s.PRNumber, err)
+			continue
+		}
+		if !open {
+			fmt.Printf("Pull request #%d is closed, skipping it.\n", s.PRNumber)
+			continue
+		}
+		body, complete := replicationComment(s)
+		if err = aitgh.CommentPullRequest(s.PRNumber, body); err != nil {
+			fmt.Printf("Unable to comment on pull request #%d: %v\n", s.PRNumber, err)
+			continue
+		}
+		fmt.Printf("Posted a replication report on %v\n", s.PullRequest)
+		if complete {
+			s.Replicated = true
+			utils.CheckError(s.Save())
+		}
+	}
+}
+
+// replicationComment builds a markdown table with the number of providers of
+// each submitted file and reports whether all of them reached the target.
+// The IPFS subsystem must be initialized.
+func replicationComment(s *utils.Submission) (string, bool) {
+	var rows strings.Builder
+	providers, replicated := replicationCounts(s)
+	for i, entry := range s.Entries {
+		if i < maxReportRows {
+			fmt.Fprintf(&rows, "| %v | `%v` | %d |\n", entry.Name, entry.CID, providers[i])
+		}
+	}
+	var body strings.Builder
+	body.WriteString("### Replication report\n\n")
+	complete := replicated == len(s.Entries)
+	if complete {
+		fmt.Fprintf(&body, "All %d file(s) are provided by at least %d peers on the Arken network "+
+			"and can be retrieved.\n\n", len(s.Entries), replicationTarget())
+	} else {
+		fmt.Fprintf(&body, "%d of %d file(s) are provided by at least %d peers on the Arken network.\n\n",
+			replicated, len(s.Entries), replicationTarget())
+	}
+	body.WriteString("| File | CID | Providers |\n| ---- | --- | --------- |\n")
+	body.WriteString(rows.String())
+	if len(s.Entries) > maxReportRows {
+		fmt.Fprintf(&body, "\n...and %d more file(s).\n", len(s.Entries)-maxReportRows)
+	}
+	return body.String(), complete
+}
+
+// replicationCounts returns the number of providers of each submitted file
+// and how many of them reached the target. The IPFS subsystem must be
+// initialized.
+func replicationCounts(s *utils.Submission) (providers []int, replicated int) {
+	providers = make([]int, len(s.Entries))
+	for i, entry := range s.Entries {
+		count, err := ipfs.FindProvs(entry.CID, 20)
+		if err == nil {
+			providers[i] = count
+		}
+		if providers[i] >= replicationTarget() {
+			replicated++
+		}
+	}
+	return providers, replicated
+}
@@ -0,0 +1,158 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/arken/ait/display"
+	"github.com/arken/ait/ipfs"
+	"github.com/arken/ait/utils"
+
+	"github.com/DataDrake/cli-ng/v2/cmd"
+)
+
+// Preview shows the beginning of a staged file as it was added to IPFS.
+var Preview = cmd.Sub{
+	Name:  "preview",
+	Short: "Show the beginning of a staged file as read back from IPFS.",
+	Args:  &PreviewArgs{},
+	Flags: &PreviewFlags{},
+	Run:   PreviewRun,
+}
+
+// PreviewArgs handles the specific arguments for the preview command.
+type PreviewArgs struct {
+	Path string `desc:"The staged file to preview"`
+}
+
+// PreviewFlags handles the specific flags for the preview command.
+type PreviewFlags struct {
+	Bytes int  `short:"n" long:"bytes" desc:"How many bytes of the file to read, 1024 by default"`
+	Rows  int  `short:"r" long:"rows" desc:"How many rows of a CSV or TSV file to show under its header, 10 by default"`
+	Hex   bool `short:"x" long:"hex" desc:"Show a hex dump, even of text"`
+}
+
+// previewBytes and previewRows are how much of a file is previewed by default.
+const (
+	previewBytes = 1024
+	previewRows  = 10
+)
+
+// PreviewRun adds the staged file to IPFS like a submission would, then reads
+// its first bytes back through its CID, so what is shown is what was hashed.
+// CSV and TSV files are shown as a table of their header and first rows, other
+// text as it is and anything else as a hex dump.
+func PreviewRun(_ *cmd.Root, c *cmd.Sub) {
+	args := c.Args.(*PreviewArgs)
+	flags := c.Flags.(*PreviewFlags)
+	if flags.Bytes <= 0 {
+		flags.Bytes = previewBytes
+	}
+	if flags.Rows <= 0 {
+		flags.Rows = previewRows
+	}
+	path, err := utils.WorkspacePath(args.Path)
+	utils.CheckError(err)
+	if utils.IndexOf(stagedList(), path) < 0 {
+		utils.FatalPrintf("%v isn't staged, only staged files can be previewed.\n", path)
+	}
+
+	ipfs.Init(false)
+	cid := previewCID(path)
+	data, err := ipfs.Head(cid, int64(flags.Bytes))
+	utils.CheckError(err)
+	fmt.Printf("%v  %v\n", cid, path)
+	if size, err := utils.GetFileSize(path); err == nil && size > int64(len(data)) {
+		fmt.Printf("The first %v of %v:\n", utils.FormatByteSize(int64(len(data))), utils.FormatByteSize(size))
+	}
+	fmt.Println()
+
+	switch ext := strings.ToLower(filepath.Ext(path)); {
+	case flags.Hex || !isText(data):
+		fmt.Print(hex.Dump(data))
+	case ext == ".csv" || ext == ".tsv":
+		if err = previewTable(data, ext == ".tsv", flags.Rows, len(data) == flags.Bytes); err != nil {
+			fmt.Printf("Unable to read it as a table (%v), showing it as text:\n\n", err)
+			fmt.Println(string(data))
+		}
+	default:
+		fmt.Println(string(data))
+	}
+}
+
+// previewCID returns the CID of the staged file at path, adding it to the
+// repository through the workspace's link unless it was handed off.
+func previewCID(path string) string {
+	handoff, err := utils.ReadHandoff()
+	utils.CheckError(err)
+	if cid, ok := utils.HandoffCID(handoff, path); ok {
+		return cid
+	}
+	if !utils.FileExists(path) {
+		utils.FatalPrintf("%v no longer exists.\n", path)
+	}
+	link, err := ipfs.LinkWorkdir()
+	utils.CheckError(err)
+	cid, err := ipfs.Add(filepath.Join(link, path), false)
+	utils.CheckError(err)
+	return cid
+}
+
+// isText returns whether data looks like text: valid UTF-8 without NUL bytes.
+// A rune cut at the end of data doesn't count against it.
+func isText(data []byte) bool {
+	if bytes.IndexByte(data, 0) >= 0 {
+		return false
+	}
+	for i := 0; i < utf8.UTFMax && len(data) > 0 && !utf8.Valid(data); i++ {
+		data = data[:len(data)-1]
+	}
+	return utf8.Valid(data)
+}
+
+// previewTable prints the header and first rows of CSV data as a table. When
+// truncated the last line is dropped, as it may have been cut.
+func previewTable(data []byte, tabs bool, rows int, truncated bool) error {
+	if truncated {
+		if end := bytes.LastIndexByte(data, '\n'); end >= 0 {
+			data = data[:end+1]
+		}
+	}
+	reader := csv.NewReader(bytes.NewReader(data))
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+	if tabs {
+		reader.Comma = '\t'
+	}
+	records, err := reader.ReadAll()
+	if err != nil {
+		return err
+	}
+	if len(records) == 0 {
+		return fmt.Errorf("there is no header")
+	}
+	table := display.Table{}
+	for _, name := range records[0] {
+		table.Columns = append(table.Columns, display.Column{Name: name, Kind: display.Text})
+	}
+	for _, record := range records[1:] {
+		if len(table.Rows) == rows {
+			break
+		}
+		row := make(display.Row, len(table.Columns))
+		for i := range row {
+			row[i] = ""
+			if i < len(record) {
+				row[i] = record[i]
+			}
+		}
+		table.Rows = append(table.Rows, row)
+	}
+	return table.Render(os.Stdout, 1, 0)
+}
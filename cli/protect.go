@@ -0,0 +1,50 @@
+package cli
+
+import (
+	"fmt"
+
+	aitgh "github.com/arken/ait/apis/github"
+	"github.com/arken/ait/ipfs"
+	"github.com/arken/ait/utils"
+)
+
+// protectSubmission protects the submitted entries from garbage collection
+// until releaseSubmission finds the submission merged and replicated. The
+// IPFS subsystem must be initialized.
+func protectSubmission(s *utils.Submission) {
+	cids := make([]string, 0, len(s.Entries))
+	for _, entry := range s.Entries {
+		cids = append(cids, entry.CID)
+	}
+	if err := ipfs.Protect(s.ID, cids); err != nil {
+		fmt.Println("Unable to protect the submitted files from garbage collection:", err)
+		return
+	}
+	s.Protected = true
+}
+
+// releaseSubmission lifts the protection of a submission once every entry
+// reached the replication target and, for a pull request, it was merged.
+// Submissions committed directly, or through an issue or email, only wait for
+// replication. It reports whether the protection was released.
+func releaseSubmission(s *utils.Submission) bool {
+	if !s.Protected || !s.Safe {
+		return false
+	}
+	if s.PRNumber != 0 {
+		merged, err := aitgh.PullRequestMerged(s.Remote, s.PRNumber)
+		if err != nil {
+			fmt.Printf("\n[Unable to check whether pull request #%d was merged: %v]\n", s.PRNumber, err)
+			return false
+		}
+		if !merged {
+			return false
+		}
+	}
+	if err := ipfs.Release(s.ID); err != nil {
+		fmt.Printf("\n[Unable to release the protection of %v: %v]\n", s.Path, err)
+		return false
+	}
+	s.Protected = false
+	return true
+}
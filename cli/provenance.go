@@ -0,0 +1,35 @@
+package cli
+
+import (
+	"fmt"
+	"io/ioutil"
+
+	"github.com/arken/ait/config"
+	"github.com/arken/ait/utils"
+)
+
+// keysetFiles returns the files committed for the keyset at committed, by
+// their path in the repository: the keyset at repoPath and, if the config
+// asks for it, its detached signature next to it.
+func keysetFiles(committed, repoPath string) (map[string]string, error) {
+	files := map[string]string{repoPath: committed}
+	if !config.Global.Git.SignKeysets {
+		return files, nil
+	}
+	contents, err := ioutil.ReadFile(committed)
+	if err != nil {
+		return nil, err
+	}
+	format := config.Global.Git.SigningFormat
+	signature, err := utils.Sign(contents, format, config.Global.Git.SigningKey, utils.NamespaceFile)
+	if err != nil {
+		return nil, err
+	}
+	ext := utils.SignatureExtension(format)
+	if err = ioutil.WriteFile(committed+ext, []byte(signature), 0644); err != nil {
+		return nil, err
+	}
+	files[repoPath+ext] = committed + ext
+	fmt.Printf("Signed the keyset, its signature is committed as %v.\n", repoPath+ext)
+	return files, nil
+}
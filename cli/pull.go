@@ -1,21 +1,22 @@
 package cli
 
 import (
-	"bufio"
 	"fmt"
+	"io"
 	"os"
-	"os/user"
 	"path/filepath"
 	"strconv"
 	"strings"
-	"sync"
+	"time"
 
 	"github.com/arken/ait/config"
+	"github.com/arken/ait/display"
 	"github.com/arken/ait/ipfs"
 	"github.com/arken/ait/keysets"
 	"github.com/arken/ait/utils"
 
 	"github.com/DataDrake/cli-ng/v2/cmd"
+	"github.com/go-git/go-git/v5"
 	files "github.com/ipfs/go-ipfs-files"
 )
 
@@ -23,51 +24,63 @@ import (
 var Pull = cmd.Sub{
 	Name:  "pull",
 	Alias: "pl",
-	Short: "Pull a file from the Arken Cluster.",
+	Short: "Pull files, or the whole contents of a keyset, from the Arken Cluster.",
 	Args:  &PullArgs{},
+	Flags: &PullFlags{},
 	Run:   PullRun,
 }
 
 // PullArgs handles the specific arguments for the pull command.
 type PullArgs struct {
 	Keyset    string
-	Filepaths []string
+	Filepaths []string `zero:"yes" desc:"<category>/<file> patterns to pull, or the destination of a keyset file"`
+}
+
+// PullFlags handles the specific flags for the pull command.
+type PullFlags struct {
+	Dest string `short:"d" long:"dest" desc:"Directory to restore a whole keyset in, the current one by default"`
 }
 
 // PullRun handles pulling and saving a file from the Arken cluster.
 func PullRun(r *cmd.Root, c *cmd.Sub) {
 	args := c.Args.(*PullArgs)
+	flags := c.Flags.(*PullFlags)
 	currentwd, err := os.Getwd()
 	if err != nil {
 		utils.FatalPrintln(err.Error())
 	}
-
-	user, err := user.Current()
-	if err != nil {
-		utils.FatalPrintln(err.Error())
+	if isKeysetFile(args.Keyset) || len(args.Filepaths) == 0 {
+		pullKeyset(args, flags)
+		return
+	}
+	if flags.Dest != "" {
+		utils.FatalPrintln("--dest only applies when a whole keyset is pulled.")
 	}
 
 	// Initialize the IPFS subsystem without confirming the node is
 	// reachable from the rest of the cluster.
 	ipfs.Init(false)
 
-	// Convert/Check URL against known alaises.
-	url := config.GetRemote(args.Keyset)
-	repoPath := filepath.Join(user.HomeDir, ".ait", "sources", utils.GetRepoName(url))
-
-	// Clone/Update the keyset locally
-	_, err = keysets.Clone(url, repoPath)
-	if err != nil {
-		utils.FatalPrintln(err.Error())
-	}
+	url, repoPath := clonePullSource(args.Keyset)
 
+	// Every file is looked up before any is written, so the progress of
+	// pulling many files can account for their number and total size.
+	var pulls []pulled
+	var total int64
 	for pathNum := range args.Filepaths {
-		results, err := keysets.Search(repoPath, args.Filepaths[pathNum])
+		results, err := keysets.Search(repoPath, utils.SlashPath(args.Filepaths[pathNum]))
 		if err != nil {
 			utils.FatalPrintln(err.Error())
 		}
 
 		for filename, cids := range results {
+			// Names come from the keyset, so one can't be allowed to
+			// write outside of the working directory.
+			dest, err := utils.SandboxPath(currentwd, filename)
+			if err != nil {
+				fmt.Printf("Skipping %v: %v\n", filename, err)
+				continue
+			}
 			i := 0
 			if len(cids) > 1 {
 				fmt.Printf("There is more than 1 file with the name: %s\n"+
@@ -78,38 +91,211 @@ func PullRun(r *cmd.Root, c *cmd.Sub) {
 					fmt.Printf("  | %d - %s", i, hash)
 				}
 
-				reader := bufio.NewReader(os.Stdin)
 				for {
-					text, err := reader.ReadString('\n')
+					text := utils.ReadRequiredAnswer()
 					if strings.ToLower(text) == "exit" {
 						return
 					}
-					i, err = strconv.Atoi(text)
-					if err == nil && i >= 0 && i < len(cids) {
+					n, err := strconv.Atoi(text)
+					if err == nil && n >= 0 && n < len(cids) {
+						i = n
 						break
 					}
 					fmt.Printf("Select a number between 0 - %d\n", len(cids)-1)
 				}
 			}
 
-			// Display Spinner when pulling a file.
-			doneChan := make(chan int, 1)
-			wg := sync.WaitGroup{}
-			wg.Add(1)
-
-			go utils.SpinnerWait(doneChan, "Pulling "+filename+"...", &wg)
 			file, err := ipfs.Pull(cids[i])
-			err = files.WriteTo(file, filepath.Join(currentwd, filename))
 			if err != nil {
-				panic(fmt.Errorf("Could not write out the fetched CID: %s", err))
+				utils.FatalPrintln(err.Error())
 			}
+			size, err := file.Size()
+			if err != nil {
+				utils.FatalPrintln(err.Error())
+			}
+			pulls = append(pulls, pulled{filename, dest, file, size})
+			total += size
+		}
+	}
 
-			doneChan <- 0
-			wg.Wait()
+	var overall *display.Progress
+	if len(pulls) > 1 {
+		overall = display.NewFileProgress("Pulling", int64(len(pulls)), total)
+	}
+	var quarantined []string
+	for _, p := range pulls {
+		err = writePulled(p.node, currentwd, p.path, p.name, p.size, overall)
+		if err != nil {
+			panic(fmt.Errorf("Could not write out the fetched CID: %s", err))
+		}
+		if err := ipfs.RecordTransfer(url, 0, p.size); err != nil {
+			fmt.Printf("[Unable to record transfer usage: %v]\n", err)
+		}
 
-			fmt.Println()
-			close(doneChan)
+		if dest, ok := scanPulled(p.path); !ok {
+			quarantined = append(quarantined, dest)
 		}
 	}
 
+	if len(quarantined) > 0 {
+		fmt.Printf("%v file(s) failed scanning and were quarantined:\n", len(quarantined))
+		for _, path := range quarantined {
+			fmt.Println("\t" + path)
+		}
+	}
+}
+
+// pulled is a file looked up on the network, ready to be written.
+type pulled struct {
+	name string
+	path string
+	node files.Node
+	size int64
+}
+
+// writePulled saves a fetched file to path, showing the download speed and
+// time remaining on its own bar, or on overall when many files are pulled.
+// Directories are written without progress, and kept within root.
+func writePulled(node files.Node, root, path, name string, size int64, overall *display.Progress) error {
+	defer utils.TimePhase("fetching")()
+	file, ok := node.(files.File)
+	if !ok {
+		err := writeTree(node, root, path)
+		if overall != nil {
+			overall.AddFile(size)
+		}
+		return err
+	}
+	out, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	bar := overall
+	if bar == nil {
+		bar = display.NewProgress("Pulling "+name, size, true)
+	}
+	_, err = io.Copy(io.MultiWriter(out, bar), file)
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	if overall != nil {
+		overall.AddFile(0)
+	}
+	return err
+}
+
+// writeTree writes a fetched directory to path like files.WriteTo, except
+// that its entries and symlinks can't lead out of root.
+func writeTree(node files.Node, root, path string) error {
+	switch node := node.(type) {
+	case *files.Symlink:
+		target := node.Target
+		if !filepath.IsAbs(target) {
+			target = filepath.Join(filepath.Dir(path), target)
+		}
+		if !utils.IsWithin(root, target) {
+			return fmt.Errorf("the symlink %v leads out of %v", path, root)
+		}
+		return os.Symlink(node.Target, path)
+	case files.File:
+		out, err := os.Create(path)
+		if err != nil {
+			return err
+		}
+		_, err = io.Copy(out, node)
+		if closeErr := out.Close(); err == nil {
+			err = closeErr
+		}
+		return err
+	case files.Directory:
+		if err := os.Mkdir(path, 0777); err != nil {
+			return err
+		}
+		entries := node.Entries()
+		for entries.Next() {
+			rel, err := filepath.Rel(root, filepath.Join(path, entries.Name()))
+			if err != nil {
+				return err
+			}
+			child, err := utils.SandboxPath(root, rel)
+			if err != nil {
+				return err
+			}
+			if err = writeTree(entries.Node(), root, child); err != nil {
+				return err
+			}
+		}
+		return entries.Err()
+	default:
+		return fmt.Errorf("file type %T at %q is not supported", node, path)
+	}
+}
+
+// scanPulled runs the configured scanner on a downloaded file and quarantines
+// it if the scanner rejects it. It returns where the file ended up and whether
+// it passed.
+func scanPulled(path string) (string, bool) {
+	command := config.Global.Scan.Command
+	if command == "" {
+		return path, true
+	}
+	output, err := utils.ScanFile(command, path)
+	if err == nil {
+		return path, true
+	}
+	fmt.Printf("Scanning %v failed: %v\n", filepath.Base(path), err)
+	if output != "" {
+		fmt.Println(output)
+	}
+	dest, err := utils.QuarantineFile(path, config.Global.Scan.Quarantine)
+	if err != nil {
+		utils.FatalPrintln("Could not quarantine " + path + ": " + err.Error())
+	}
+	return dest, false
+}
+
+// clonePullSource clones or updates the keyset repository at the remote or
+// URL given, checks its signature and returns its URL and local path.
+func clonePullSource(remote string) (string, string) {
+	// Convert/Check URL against known alaises.
+	url := config.GetRemote(remote)
+	repoPath := config.SourcePath(url)
+
+	// Clone/Update the keyset locally
+	repo, err := keysets.Clone(url, repoPath)
+	if err != nil {
+		utils.FatalPrintln(err.Error())
+	}
+	verifyKeyset(repo, url)
+	// Mark the source as recently used so "ait clean" keeps it.
+	now := time.Now()
+	_ = os.Chtimes(repoPath, now, now)
+	return url, repoPath
+}
+
+// verifyKeyset checks that the latest commit of a keyset repository is signed
+// by a trusted key before any data is downloaded from it, following the
+// configured trust policy.
+func verifyKeyset(repo *git.Repository, url string) {
+	policy := config.Global.Trust.Policy
+	if policy == keysets.VerifyOff {
+		return
+	}
+	keys := make([]string, 0, len(config.Global.Trust.Keys))
+	for _, key := range config.Global.Trust.Keys {
+		keys = append(keys, key)
+	}
+	// Without any trusted keys a warning would be printed on every pull.
+	if len(keys) == 0 && policy != keysets.VerifyRequire {
+		return
+	}
+	signer, err := keysets.VerifyHead(repo, keys)
+	if err == nil {
+		fmt.Printf("Keyset %v is signed by %v\n", url, signer)
+		return
+	}
+	if policy == keysets.VerifyRequire {
+		utils.FatalPrintln("Refusing to pull from " + url + ": " + err.Error())
+	}
+	fmt.Println("Warning: could not verify " + url + ": " + err.Error())
 }
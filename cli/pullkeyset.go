@@ -0,0 +1,224 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/arken/ait/display"
+	"github.com/arken/ait/ipfs"
+	"github.com/arken/ait/utils"
+
+	files "github.com/ipfs/go-ipfs-files"
+)
+
+// keysetPull is a file of a keyset restored by "ait pull".
+type keysetPull struct {
+	cid  string
+	name string
+	path string
+	node files.Node
+	size int64
+	// done is set when the file was pulled before and still matches.
+	done bool
+	err  error
+}
+
+// isKeysetFile returns whether the argument is a local keyset file rather
+// than a keyset repository.
+func isKeysetFile(arg string) bool {
+	info, err := os.Stat(arg)
+	return err == nil && !info.IsDir() && filepath.Ext(arg) == ".ks"
+}
+
+// pullKeyset restores every file of a keyset file, or of every keyset of a
+// repository, under the destination with their names from the keysets. The
+// files are fetched concurrently and each is hashed again once written, so a
+// file that doesn't match its CID isn't kept.
+func pullKeyset(args *PullArgs, flags *PullFlags) {
+	dest := flags.Dest
+	if isKeysetFile(args.Keyset) {
+		if len(args.Filepaths) > 1 || len(args.Filepaths) == 1 && dest != "" {
+			utils.FatalPrintln("A keyset file is pulled whole, give only the directory to restore it in.")
+		}
+		if len(args.Filepaths) == 1 {
+			dest = args.Filepaths[0]
+		}
+	}
+	if dest == "" {
+		dest = "."
+	}
+	root, err := filepath.Abs(dest)
+	utils.CheckError(err)
+	utils.CheckError(os.MkdirAll(root, os.ModePerm))
+
+	ipfs.Init(false)
+	source := args.Keyset
+	// Keyset files of a repository are restored in directories named after
+	// them, so files of the same name in different keysets don't collide.
+	ksFiles := map[string]string{args.Keyset: ""}
+	if !isKeysetFile(args.Keyset) {
+		var repoPath string
+		source, repoPath = clonePullSource(args.Keyset)
+		ksFiles, err = repoKeysets(repoPath)
+		utils.CheckError(err)
+	}
+	pulls := keysetPulls(ksFiles, root)
+	if len(pulls) == 0 {
+		utils.FatalPrintln("The keyset doesn't list any files.")
+	}
+
+	// Every file is looked up before any is written, so the progress can
+	// account for their total size.
+	forEachPull(pulls, func(p *keysetPull) {
+		if cid, err := ipfs.Hash(p.path, p.cid); err == nil && cid == p.cid {
+			p.done = true
+			return
+		}
+		if p.node, p.err = ipfs.Pull(p.cid); p.err == nil {
+			p.size, p.err = p.node.Size()
+		}
+	})
+	var total, count int64
+	for _, p := range pulls {
+		if !p.done && p.err == nil {
+			total += p.size
+			count++
+		}
+	}
+	bar := display.NewFileProgress("Pulling", count, total)
+	forEachPull(pulls, func(p *keysetPull) {
+		if p.done || p.err != nil {
+			return
+		}
+		defer p.node.Close()
+		if p.err = os.MkdirAll(filepath.Dir(p.path), os.ModePerm); p.err != nil {
+			return
+		}
+		p.err = writePulled(p.node, root, p.path, p.name, p.size, bar)
+		if _, isFile := p.node.(files.File); p.err == nil && isFile {
+			// Blocks are checked as they're fetched, the file is checked as
+			// it was written.
+			var cid string
+			if cid, p.err = ipfs.Hash(p.path, p.cid); p.err == nil && cid != p.cid {
+				p.err = fmt.Errorf("it was written as %v instead of %v", cid, p.cid)
+			}
+			if p.err != nil {
+				_ = os.Remove(p.path)
+			}
+		}
+	})
+
+	var failed, skipped, quarantined []string
+	pulled := 0
+	for _, p := range pulls {
+		switch {
+		case p.err != nil:
+			failed = append(failed, fmt.Sprintf("%v: %v", p.name, p.err))
+		case p.done:
+			skipped = append(skipped, p.name)
+		default:
+			pulled++
+			if err := ipfs.RecordTransfer(source, 0, p.size); err != nil {
+				fmt.Printf("[Unable to record transfer usage: %v]\n", err)
+			}
+			if dest, ok := scanPulled(p.path); !ok {
+				quarantined = append(quarantined, dest)
+			}
+		}
+	}
+	fmt.Printf("Pulled %d file(s) to %v", pulled, root)
+	if len(skipped) > 0 {
+		fmt.Printf(", %d were already there", len(skipped))
+	}
+	fmt.Println(".")
+	if len(quarantined) > 0 {
+		fmt.Printf("%v file(s) failed scanning and were quarantined:\n", len(quarantined))
+		for _, path := range quarantined {
+			fmt.Println("\t" + path)
+		}
+	}
+	if len(failed) > 0 {
+		utils.FatalPrintf("%d file(s) couldn't be pulled:\n\t%v\n", len(failed), strings.Join(failed, "\n\t"))
+	}
+}
+
+// repoKeysets returns the keyset files of the repository at repoPath, with
+// the directory each is restored in, ie "data/survey" for data/survey.ks.
+func repoKeysets(repoPath string) (map[string]string, error) {
+	ksFiles := make(map[string]string)
+	err := filepath.Walk(repoPath, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() && info.Name() == ".git" {
+			return filepath.SkipDir
+		}
+		if !info.IsDir() && filepath.Ext(path) == ".ks" {
+			rel, err := filepath.Rel(repoPath, path)
+			if err != nil {
+				return err
+			}
+			ksFiles[path] = strings.TrimSuffix(rel, ".ks")
+		}
+		return nil
+	})
+	return ksFiles, err
+}
+
+// keysetPulls lists the files of the keyset files to restore under root.
+// Names listed more than once are restored from their last CID, the most
+// recent, and names that would lead out of root are skipped.
+func keysetPulls(ksFiles map[string]string, root string) []*keysetPull {
+	var pulls []*keysetPull
+	seen := make(map[string]*keysetPull)
+	paths := make([]string, 0, len(ksFiles))
+	for ksPath := range ksFiles {
+		paths = append(paths, ksPath)
+	}
+	sort.Strings(paths)
+	for _, ksPath := range paths {
+		dir := ksFiles[ksPath]
+		entries, err := utils.ReadKeysetEntries(ksPath)
+		utils.CheckError(err)
+		for _, entry := range entries {
+			name := filepath.Join(dir, entry.Name)
+			path, err := utils.SandboxPath(root, name)
+			if err != nil {
+				fmt.Printf("Skipping %v: %v\n", name, err)
+				continue
+			}
+			if p, ok := seen[path]; ok {
+				p.cid = entry.CID
+				continue
+			}
+			p := &keysetPull{cid: entry.CID, name: name, path: path}
+			seen[path] = p
+			pulls = append(pulls, p)
+		}
+	}
+	return pulls
+}
+
+// forEachPull runs fn on every file concurrently.
+func forEachPull(pulls []*keysetPull, fn func(*keysetPull)) {
+	jobs := make(chan *keysetPull)
+	wg := sync.WaitGroup{}
+	for w := 0; w < genNumWorkers(); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for p := range jobs {
+				fn(p)
+			}
+		}()
+	}
+	for _, p := range pulls {
+		jobs <- p
+	}
+	close(jobs)
+	wg.Wait()
+}
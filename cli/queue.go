@@ -0,0 +1,249 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+
+	"github.com/arken/ait/apis/forge"
+	"github.com/arken/ait/display"
+	"github.com/arken/ait/keysets"
+	"github.com/arken/ait/types"
+	"github.com/arken/ait/utils"
+
+	"github.com/DataDrake/cli-ng/v2/cmd"
+)
+
+// Queue manages the submissions prepared while their remote couldn't be
+// reached.
+var Queue = cmd.Sub{
+	Name:  "queue",
+	Short: "List the submissions queued while offline, or flush them once their remote can be reached.",
+	Args:  &QueueArgs{},
+	Run:   QueueRun,
+}
+
+// QueueArgs handles the specific arguments for the queue command.
+type QueueArgs struct {
+	Args []string
+}
+
+const queueUsage = `	ait queue             # Lists the queued submissions
+	ait queue flush       # Submits the queued submissions, oldest first
+	ait queue drop <id>   # Deletes a queued submission`
+
+// queuedKeyset is the keyset of the queued submission being flushed, it's
+// submitted instead of generating one from the staged files.
+var queuedKeyset string
+
+// QueueRun lists, flushes or drops queued submissions.
+func QueueRun(_ *cmd.Root, c *cmd.Sub) {
+	args := c.Args.(*QueueArgs).Args
+	queue, err := utils.ReadQueue()
+	utils.CheckError(err)
+	if len(args) == 0 || args[0] == "list" {
+		listQueue(queue)
+		return
+	}
+	switch args[0] {
+	case "flush":
+		flushQueue(queue)
+	case "drop":
+		if len(args) < 2 {
+			utils.FatalPrintln("Expected the ID of the submission to drop:\n" + queueUsage)
+		}
+		for _, q := range queue {
+			if q.ID == args[1] {
+				utils.CheckError(q.Remove())
+				fmt.Printf("Dropped the submission %v.\n", q.ID)
+				return
+			}
+		}
+		utils.FatalPrintf("No submission %v is queued.\n", args[1])
+	default:
+		utils.FatalPrintf("Unknown action %q:\n%v\n", args[0], queueUsage)
+	}
+}
+
+// listQueue prints the queued submissions.
+func listQueue(queue []*utils.QueuedSubmission) {
+	if len(queue) == 0 {
+		fmt.Println("No submissions are queued.")
+		return
+	}
+	for _, q := range queue {
+		entries, _ := utils.ReadKeysetEntries(q.KeysetPath())
+		fmt.Printf("%v  %v to %v (%d file(s), queued %v)\n", q.ID, q.Title, q.Remote, len(entries),
+			q.Time.Format("Jan 2 15:04"))
+	}
+}
+
+// flushQueue submits the queued submissions, oldest first. It stops at the
+// first that fails or whose remote still can't be reached, leaving it and the
+// rest queued.
+func flushQueue(queue []*utils.QueuedSubmission) {
+	if len(queue) == 0 {
+		fmt.Println("No submissions are queued.")
+		return
+	}
+	prettyIPFSInit()
+	for _, q := range queue {
+		kind, err := forge.Detect(q.Remote, q.Provider)
+		utils.CheckError(err)
+		if !remoteReachable(q.Remote, kind) {
+			utils.FatalPrintf("%v still can't be reached, the submission %v and those after it stay queued.\n",
+				q.Remote, q.ID)
+		}
+		fmt.Printf("Submitting %v to %v...\n", q.Title, q.Remote)
+		utils.CheckError(display.WriteApplication(&types.ApplicationContents{
+			Title:    q.Title,
+			Commit:   q.Commit,
+			PRBody:   q.PRBody,
+			Category: q.Category,
+			KsName:   q.Filename,
+		}))
+		// The keyset is committed in the format its name was given with.
+		display.KeysetExtension = path.Ext(q.Filename)
+		queuedKeyset = q.KeysetPath()
+		submitted, err := submitOnline(q.Remote, kind, q.PullRequest, q.Issue,
+			&SubmitFlags{ROCrate: q.ROCrate, Also: q.Also, Provider: q.Provider})
+		queuedKeyset = ""
+		if err != nil {
+			exitSubmission(fmt.Errorf("%w, the submission %v stays queued", err, q.ID))
+		} else if !submitted {
+			utils.FatalPrintf("The submission %v stays queued.\n", q.ID)
+		}
+		utils.CheckError(q.Remove())
+	}
+}
+
+// queueSubmission prepares the submission and queues it to be flushed once
+// the remote at url can be reached, returning false if it was aborted.
+func queueSubmission(url string, isPR, isIssue bool, flags *SubmitFlags) (bool, error) {
+	if flags.SplitFiles > 0 || flags.SplitSize != "" {
+		return false, fmt.Errorf("%v can't be reached and split submissions can't be queued, "+
+			"try again once you're online", url)
+	}
+	fmt.Printf("%v can't be reached, the files will be announced and the submission queued.\n", url)
+	display.ShowApplication()
+	app := display.ReadApplication()
+	if !app.IsValid() {
+		fmt.Printf("Exiting Submission because the application has no %v.\n", app.Missing())
+		fmt.Println("Submission aborted.")
+		return false, nil
+	}
+	if !batchSubmitting {
+		if err := preSubmitHook(app, url); err != nil {
+			return false, withCleanup(err)
+		}
+	}
+	q := newQueued(url, isPR, isIssue, flags, app)
+	_, err := checkGenerated(keysets.Generate(q.KeysetPath(), true), q.KeysetPath(), flags.Strict, func() {
+		_ = os.Remove(q.KeysetPath())
+	})
+	if err != nil {
+		return false, err
+	}
+	announceStaged(q.KeysetPath())
+	if err = q.Save(); err != nil {
+		return false, err
+	}
+	utils.SubmissionCleanup()
+	fmt.Printf("Queued the submission as %v, run \"ait queue flush\" once you're back online.\n", q.ID)
+	return true, nil
+}
+
+// newQueued returns a queued submission of the application to url, flushed
+// with the same flags.
+func newQueued(url string, isPR, isIssue bool, flags *SubmitFlags,
+	app *types.ApplicationContents) *utils.QueuedSubmission {
+	q := utils.NewQueuedSubmission(url)
+	q.PullRequest, q.Issue, q.ROCrate, q.Also = isPR, isIssue, flags.ROCrate, flags.Also
+	q.Provider = flags.Provider
+	q.Title, q.Commit, q.PRBody = app.Title, app.Commit, app.PRBody
+	q.Category, q.Filename = app.Category, app.KsName
+	return q
+}
+
+// spoolGitStep keeps a copy of the keyset at ksPath in the queue while it is
+// pushed. If the push fails because the remote stopped answering, the submission
+// is queued from that copy rather than lost: its files have been announced
+// already, only the git step is left for "ait queue flush". The returned
+// function is called with the error the push ended with, and returns it.
+func spoolGitStep(url string, isPR, isIssue bool, flags *SubmitFlags,
+	app *types.ApplicationContents, ksPath string) func(error) error {
+	pushed := func(err error) error { return err }
+	if queuedKeyset != "" {
+		// Flushing the queue, the submission is queued already.
+		return pushed
+	}
+	q := newQueued(url, isPR, isIssue, flags, app)
+	err := os.MkdirAll(utils.QueuePath, os.ModePerm)
+	if err == nil {
+		err = utils.CopyFile(ksPath, q.KeysetPath())
+	}
+	if err != nil {
+		fmt.Println("Unable to keep a copy of the keyset, it won't be queued if the remote stops answering:", err)
+		return pushed
+	}
+	return func(pushErr error) error {
+		if pushErr == nil {
+			_ = os.Remove(q.KeysetPath())
+		} else if kind, err := forge.Detect(url, flags.Provider); err != nil || remoteReachable(url, kind) {
+			_ = os.Remove(q.KeysetPath())
+		} else if err := q.Save(); err != nil {
+			fmt.Println("Unable to queue the submission:", err)
+		} else {
+			// The queue finishes the submission, not its journal.
+			if submitJournal != nil {
+				_ = submitJournal.Remove()
+			}
+			fmt.Printf(`The remote stopped answering during the submission, which is only partly done:
+	Files hashed, pinned and announced to the Arken network: done
+	Keyset committed to %v: queued as %v
+Run "ait queue flush" once it's back to finish it.
+`, url, q.ID)
+		}
+		return pushErr
+	}
+}
+
+// generateKeyset writes the keyset of the submission to ksPath, from the
+// staged files or the queued submission being flushed. Unless overwrite is
+// set, its entries are added to the keyset already at ksPath.
+func generateKeyset(ksPath string, overwrite bool) error {
+	if stepDone(utils.StepGenerated) {
+		// The journaled keyset was merged already when it was generated.
+		if err := os.MkdirAll(filepath.Dir(ksPath), os.ModePerm); err != nil {
+			return err
+		}
+		return utils.CopyFile(submitJournal.KeysetPath(), ksPath)
+	}
+	if queuedKeyset == "" {
+		return keysets.Generate(ksPath, overwrite)
+	}
+	if overwrite {
+		if err := os.MkdirAll(filepath.Dir(ksPath), os.ModePerm); err != nil {
+			return err
+		}
+		return utils.CopyFile(queuedKeyset, ksPath)
+	}
+	return keysets.Merge(ksPath, queuedKeyset)
+}
+
+// githubReachable checks that GitHub's API answers.
+func githubReachable() bool {
+	return checkHTTPS("GitHub API", "https://api.github.com").OK
+}
+
+// remoteReachable checks that the forge of the given kind hosting the remote
+// at url answers.
+func remoteReachable(url, kind string) bool {
+	if kind == forge.GitHub {
+		return githubReachable()
+	}
+	base, err := forge.BaseURL(url)
+	utils.CheckError(err)
+	return checkHTTPS(kind, base).OK
+}
@@ -0,0 +1,68 @@
+package cli
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/arken/ait/apis/rekor"
+	"github.com/arken/ait/config"
+	"github.com/arken/ait/ipfs"
+	"github.com/arken/ait/utils"
+)
+
+// receiptStatement is what a submitter signs and appends to the transparency
+// log for each submission.
+type receiptStatement struct {
+	KeysetSHA256 string    `json:"keysetSha256"`
+	Remote       string    `json:"remote"`
+	Path         string    `json:"path"`
+	Commit       string    `json:"commit,omitempty"`
+	Root         string    `json:"root,omitempty"`
+	DOI          string    `json:"doi,omitempty"`
+	Submitter    string    `json:"submitter"`
+	Time         time.Time `json:"time"`
+}
+
+// publishReceipt signs a statement of the submission with the IPFS identity
+// and appends it to the configured transparency log. The IPFS subsystem must
+// be initialized. The submission has already succeeded, so failures are only
+// reported.
+func publishReceipt(s *utils.Submission, keyset []byte) {
+	sum := sha256.Sum256(keyset)
+	id := ipfs.GetID()
+	statement, err := json.Marshal(receiptStatement{
+		KeysetSHA256: hex.EncodeToString(sum[:]),
+		Remote:       s.Remote,
+		Path:         s.Path,
+		Commit:       s.Commit,
+		Root:         s.Root,
+		DOI:          s.DOI,
+		Submitter:    id,
+		Time:         s.Time,
+	})
+	if err != nil {
+		fmt.Println("Unable to create a submission receipt:", err)
+		return
+	}
+	sig, pubKey, _, err := ipfs.Sign(statement)
+	if err != nil {
+		fmt.Println("Unable to sign a submission receipt:", err)
+		return
+	}
+	entry, err := rekor.Upload(config.Global.General.TransparencyLog, statement, sig, pubKey)
+	if err != nil {
+		fmt.Println("Unable to publish the submission receipt:", err)
+		return
+	}
+	s.Receipt = &utils.Receipt{
+		Statement: statement,
+		Signature: sig,
+		Submitter: id,
+		LogIndex:  entry.LogIndex,
+		LogEntry:  entry.URL,
+	}
+	fmt.Printf("Submission receipt recorded in the transparency log at index %d\n", entry.LogIndex)
+}
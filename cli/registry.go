@@ -0,0 +1,167 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/arken/ait/ipfs"
+	"github.com/arken/ait/types"
+	"github.com/arken/ait/utils"
+
+	"github.com/DataDrake/cli-ng/v2/cmd"
+)
+
+// Registry looks files up in the registry of every CID submitted from any
+// workspace.
+var Registry = cmd.Sub{
+	Name:  "registry",
+	Short: "Check whether files or CIDs were submitted before, from any workspace.",
+	Args:  &RegistryArgs{},
+	Run:   RegistryRun,
+}
+
+// RegistryArgs handles the specific arguments for the registry command.
+type RegistryArgs struct {
+	Action string   `desc:"The operation to perform: check or rebuild"`
+	Args   []string `zero:"yes" desc:"The files, directories or CIDs to check"`
+}
+
+const registryUsage = `	ait registry check <paths or CIDs>...  # Check whether they were submitted before
+	ait registry rebuild                   # Rebuild the registry from the history of every workspace`
+
+// RegistryRun checks files or CIDs against the registry, or rebuilds it.
+func RegistryRun(_ *cmd.Root, c *cmd.Sub) {
+	args := c.Args.(*RegistryArgs)
+	switch args.Action {
+	case "check":
+		if len(args.Args) == 0 {
+			utils.FatalPrintln("Expected the files, directories or CIDs to check:\n" + registryUsage)
+		}
+		checkRegistry(args.Args)
+	case "rebuild":
+		rebuildRegistry()
+	default:
+		utils.FatalPrintf("Unknown action %q:\n%v\n", args.Action, registryUsage)
+	}
+}
+
+// checkRegistry prints where each of the files or CIDs was submitted before.
+// Files are only hashed, nothing is added to the repository.
+func checkRegistry(args []string) {
+	registry, err := utils.ReadRegistry()
+	utils.CheckError(err)
+	type checked struct{ cid, label string }
+	var items []checked
+	walked := types.NewThreadSafeStringSet()
+	for _, arg := range args {
+		if utils.FileExists(arg) {
+			path, err := utils.WorkspacePath(arg)
+			if err != nil {
+				fmt.Printf("Only files under the working directory can be checked, skipping %v\n", arg)
+				continue
+			}
+			addPath(path, walked)
+		} else {
+			items = append(items, checked{cid: arg})
+		}
+	}
+	if walked.Size() > 0 {
+		prettyIPFSInit()
+		link, err := ipfs.LinkWorkdir()
+		utils.CheckError(err)
+		var paths []string
+		_ = walked.ForEach(func(path string) error {
+			paths = append(paths, path)
+			return nil
+		})
+		sort.Strings(paths)
+		for _, path := range paths {
+			cid, err := ipfs.HashCached(filepath.Join(link, path))
+			if err != nil {
+				fmt.Printf("Unable to hash %v: %v\n", path, err)
+				continue
+			}
+			items = append(items, checked{cid: cid, label: path})
+		}
+	}
+
+	found := 0
+	for _, item := range items {
+		label := item.cid
+		if item.label != "" {
+			label = item.label + " (" + item.cid + ")"
+		}
+		entries := registry[item.cid]
+		if len(entries) == 0 {
+			fmt.Printf("%v: not submitted before\n", label)
+			continue
+		}
+		found++
+		fmt.Printf("%v: submitted %d time(s)\n", label, len(entries))
+		for _, entry := range entries {
+			fmt.Printf("\t%v  as %v in %v of %v, from %v\n", entry.Time.Format("Jan 2 2006"),
+				entry.Name, entry.Keyset, entry.Remote, entry.Workspace)
+		}
+	}
+	fmt.Printf("%d of %d were submitted before.\n", found, len(items))
+}
+
+// rebuildRegistry replaces the registry with the submissions recorded in the
+// history of every workspace the IPFS repository knows of, and of this one.
+func rebuildRegistry() {
+	workspaces, err := ipfs.ReadWorkspaces()
+	utils.CheckError(err)
+	seen := make(map[string]bool)
+	var dirs []string
+	if wd, err := os.Getwd(); err == nil && utils.IsAITRepo() {
+		seen[wd] = true
+		dirs = append(dirs, wd)
+	}
+	for _, w := range workspaces {
+		if !seen[w.Path] && utils.FileExists(filepath.Join(w.Path, utils.HistoryPath)) {
+			seen[w.Path] = true
+			dirs = append(dirs, w.Path)
+		}
+	}
+	sort.Strings(dirs)
+	count, err := utils.RebuildRegistry(dirs)
+	utils.CheckError(err)
+	fmt.Printf("Rebuilt the registry from %d submission(s) of %d workspace(s).\n", count, len(dirs))
+}
+
+// registerSubmission adds the submission to the registry. The submission has
+// already succeeded, so failures are only reported.
+func registerSubmission(s *utils.Submission) {
+	wd, err := os.Getwd()
+	if err == nil {
+		err = utils.RegisterSubmission(s, wd)
+	}
+	if err != nil {
+		fmt.Println("Unable to add the submission to the registry:", err)
+	}
+}
+
+// noteSubmittedBefore tells how many files of the keyset at ksPath were
+// submitted before, from any workspace.
+func noteSubmittedBefore(ksPath string) {
+	registry, err := utils.ReadRegistry()
+	if err != nil || len(registry) == 0 {
+		return
+	}
+	entries, err := utils.ReadKeysetEntries(ksPath)
+	if err != nil {
+		return
+	}
+	before := 0
+	for _, cid := range uniqueCIDs(entries) {
+		if len(registry[cid]) > 0 {
+			before++
+		}
+	}
+	if before > 0 {
+		fmt.Printf("%d of the %d file(s) were submitted before, \"ait registry check\" tells where.\n",
+			before, len(entries))
+	}
+}
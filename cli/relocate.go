@@ -0,0 +1,45 @@
+package cli
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/arken/ait/ipfs"
+	"github.com/arken/ait/utils"
+
+	"github.com/DataDrake/cli-ng/v2/cmd"
+)
+
+// Relocate points the node at data that moved instead of adding it again.
+var Relocate = cmd.Sub{
+	Name:  "relocate",
+	Short: "Point the node at workspaces that moved, ie to a new mount point.",
+	Args:  &RelocateArgs{},
+	Run:   RelocateRun,
+}
+
+// RelocateArgs handles the specific arguments for the relocate command.
+type RelocateArgs struct {
+	Old string
+	New string
+}
+
+// RelocateRun moves the references of every workspace at or under the old
+// path to the same place under the new one.
+func RelocateRun(_ *cmd.Root, c *cmd.Sub) {
+	args := c.Args.(*RelocateArgs)
+	oldPath, err := filepath.Abs(args.Old)
+	utils.CheckError(err)
+	newPath, err := filepath.Abs(args.New)
+	utils.CheckError(err)
+	moved, err := ipfs.RelocateWorkspaces(oldPath, newPath)
+	for _, w := range moved {
+		fmt.Println("Relocated", w.Path)
+	}
+	utils.CheckError(err)
+	if len(moved) == 0 {
+		fmt.Println("No workspaces with referenced files are under", oldPath)
+		return
+	}
+	fmt.Printf("%d workspace(s) relocated, their files will be provided from the new location.\n", len(moved))
+}
@@ -1,12 +1,11 @@
 package cli
 
 import (
-	"bufio"
 	"fmt"
 	"math"
-	"os"
 	"strings"
 
+	"github.com/arken/ait/apis/forge"
 	"github.com/arken/ait/config"
 	"github.com/arken/ait/utils"
 
@@ -34,19 +33,47 @@ type RemoteFlags struct {
 	IsRm    bool `short:"d" long:"delete" desc:"Unstage a remote alias"`
 	IsRmAll bool `short:"D" long:"delete-all" desc:"Unstage all remote aliases"`
 	IsList  bool `short:"l" long:"list" desc:"List your saved aliases"`
+	// IsDiscover can also be given as "ait remote discover".
+	IsDiscover bool `short:"s" long:"discover" desc:"Browse and save an organization's official keyset repositories"`
+	// Branch and Provider are saved with an added alias.
+	Branch   string `short:"b" long:"branch" desc:"Branch to submit to and clone from with --add, the repository's default branch otherwise"`
+	Provider string `long:"provider" desc:"Forge hosting the remote added with --add: github, gitlab or gitea, detected from its host otherwise"`
 }
 
-const usageEx = `	ait remote --add/-a MyAlias https://github.com/example-user/example-repo.git  # Saves an alias/URL pair for use later
-	ait remote --delete/-d MyAlias      # Removes an alias/URL pair
-	ait remote --delete-all/-D MyAlias  # Removes all alias/URL pairs
-	ait remote --list/-l                # See all your saved alias/URL pairs`
+const usageEx = `	ait remote add MyAlias https://github.com/example-user/example-repo.git  # Saves an alias/URL pair for use later
+	ait remote add MyAlias <url> --branch keysets --provider gitea  # Also saves the branch and forge of the remote
+	ait remote remove MyAlias           # Removes an alias/URL pair
+	ait remote --delete-all/-D          # Removes all alias/URL pairs
+	ait remote list                     # See all your saved alias/URL pairs
+	ait remote discover example.org     # Browse and save an organization's official keyset repositories
+The operations can also be given as flags: --add/-a, --delete/-d, --list/-l and --discover/-s.`
+
+// remoteActions are the operations of "ait remote" given as its first
+// argument rather than as a flag.
+var remoteActions = map[string]func(*RemoteFlags){
+	"add":      func(f *RemoteFlags) { f.IsAdd = true },
+	"list":     func(f *RemoteFlags) { f.IsList = true },
+	"ls":       func(f *RemoteFlags) { f.IsList = true },
+	"remove":   func(f *RemoteFlags) { f.IsRm = true },
+	"rm":       func(f *RemoteFlags) { f.IsRm = true },
+	"discover": func(f *RemoteFlags) { f.IsDiscover = true },
+}
 
 // RemoteRun handles managing aliases for GitHub remotes.
 func RemoteRun(_ *cmd.Root, c *cmd.Sub) {
 	flags := c.Flags.(*RemoteFlags)
-	validateFlags(flags.IsAdd, flags.IsRm, flags.IsList, flags.IsRmAll)
-	// ^ makes sure exactly one flag is present
 	args := c.Args.(*RemoteArgs).Args
+	if len(args) > 0 {
+		if action, ok := remoteActions[args[0]]; ok {
+			action(flags)
+			args = args[1:]
+		}
+	}
+	if (flags.Branch != "" || flags.Provider != "") && !flags.IsAdd {
+		utils.FatalPrintln("--branch and --provider are saved with the alias given to add.")
+	}
+	validateFlags(flags.IsAdd, flags.IsRm, flags.IsList, flags.IsRmAll, flags.IsDiscover)
+	// ^ makes sure exactly one flag is present
 	if len(args) < 2 && flags.IsAdd {
 		utils.FatalPrintln(`Expected an alias and a URL to add:
 	ait remote --add MyAlias https://github.com/example-user/example-repo.git`)
@@ -61,21 +88,34 @@ func RemoteRun(_ *cmd.Root, c *cmd.Sub) {
 			utils.FatalPrintln(`It appears that your alias is a URL. The alias should come first:
 	ait remote --add MyAlias https://github.com/example-user/example-repo.git`)
 		}
-		validateURL(url)
-		addRemote(alias, url)
+		options := config.RemoteOptions{Branch: flags.Branch, Provider: strings.ToLower(flags.Provider)}
+		if _, err := forge.Detect(url, options.Provider); err != nil {
+			utils.FatalPrintln(err)
+		}
+		if options.Provider == "" || options.Provider == forge.GitHub {
+			validateURL(url)
+		}
+		addRemote(alias, url, options)
 	} else if flags.IsRm {
 		alias := args[0]
 		deleteRemote(alias)
 	} else if flags.IsList {
 		listRemotes()
+	} else if flags.IsDiscover {
+		if len(args) < 1 {
+			utils.FatalPrintln(`Expected the organization's domain, repository or discovery document:
+	ait remote discover example.org`)
+		}
+		discoverRemotes(args[0])
 	} else { //remove all
 		deleteAllRemotes()
 	}
 	config.GenConf(config.Global)
 }
 
-// addRemote adds the given alias and url to the map config.Global.Git.Remotes.
-func addRemote(alias, url string) {
+// addRemote adds the given alias and url to the map config.Global.Git.Remotes,
+// along with its options if any are set.
+func addRemote(alias, url string, options config.RemoteOptions) {
 	if config.Global.Git.Remotes == nil {
 		config.Global.Git.Remotes = make(map[string]string)
 	}
@@ -84,15 +124,20 @@ func addRemote(alias, url string) {
 		fmt.Printf(`The alias "%v" is already mapped to %v.
 Would you like to proceed regardless (y) or abort (any other key)? `,
 			alias, oldVal)
-		reader := bufio.NewReader(os.Stdin)
-		choice, _ := reader.ReadString('\n')
-		if !strings.EqualFold(strings.TrimSpace(choice), "y") {
+		if !strings.EqualFold(utils.ReadAnswer(), "y") {
 			utils.FatalPrintln("Aborting.")
 		}
 	}
 	config.Global.Git.Remotes[alias] = url
+	delete(config.Global.Git.RemoteOptions, alias)
+	if options != (config.RemoteOptions{}) {
+		if config.Global.Git.RemoteOptions == nil {
+			config.Global.Git.RemoteOptions = make(map[string]config.RemoteOptions)
+		}
+		config.Global.Git.RemoteOptions[alias] = options
+	}
 	config.GenConf(config.Global)
-	fmt.Printf("Alias \"%v\" successfully mapped to %v.\n", alias, url)
+	fmt.Printf("Alias \"%v\" successfully mapped to %v%v.\n", alias, url, describeRemoteOptions(options))
 }
 
 // deleteRemote tries to delete the given alias and url from the the map
@@ -109,6 +154,7 @@ func deleteRemote(alias string) {
 			"There are no saved remotes that go by the alias \"%v\". Nothing was done.\n", alias)
 	} else {
 		delete(remotes, alias)
+		delete(config.Global.Git.RemoteOptions, alias)
 		fmt.Printf(
 			"Alias \"%v\" which mapped to %v has been deleted.\n", alias, oldVal)
 	}
@@ -123,6 +169,7 @@ func deleteAllRemotes() {
 	}
 	oLen := len(remotes)
 	config.Global.Git.Remotes = make(map[string]string)
+	config.Global.Git.RemoteOptions = nil
 	fmt.Println(oLen, "alias(es) removed.")
 }
 
@@ -143,9 +190,31 @@ func listRemotes() {
 			for i := 0; i < spaces; i++ {
 				fmt.Print(" ")
 			}
-			fmt.Println(url)
+			fmt.Println(url + describeRemoteOptions(config.Global.Git.RemoteOptions[alias]))
 		}
 	}
+	if workspace := config.WorkspaceRemotes(); len(workspace) > 0 {
+		fmt.Println(len(workspace), "alias(es) of this workspace, used before the saved ones:")
+		for alias, url := range workspace {
+			fmt.Printf("\t%q = %v\n", alias, url)
+		}
+	}
+}
+
+// describeRemoteOptions returns the options of a remote alias as they're
+// listed after its URL, or nothing if it has none.
+func describeRemoteOptions(options config.RemoteOptions) string {
+	var parts []string
+	if options.Branch != "" {
+		parts = append(parts, "branch "+options.Branch)
+	}
+	if options.Provider != "" {
+		parts = append(parts, "on "+options.Provider)
+	}
+	if len(parts) == 0 {
+		return ""
+	}
+	return " (" + strings.Join(parts, ", ") + ")"
 }
 
 // validateURL uses utils.IsGithubRemote to detect obvious problems with the
@@ -153,6 +222,9 @@ func listRemotes() {
 // regardless, and if yes the program continues as expected. If not, the program
 // is terminated immediately.
 func validateURL(url string) {
+	if strings.HasPrefix(url, "mailto:") && strings.Contains(url, "@") {
+		return // Mailing list remote.
+	}
 	ok, msg := utils.IsGithubRemote(url)
 	if !ok {
 		if len(msg) > 0 {
@@ -163,9 +235,7 @@ func validateURL(url string) {
 				"provided URL \"%v\".", url)
 		}
 		fmt.Print("\nWould you like to proceed regardless (y) or abort (any other key)? ")
-		reader := bufio.NewReader(os.Stdin)
-		choice, _ := reader.ReadString('\n')
-		if !strings.EqualFold(strings.TrimSpace(choice), "y") {
+		if !strings.EqualFold(utils.ReadAnswer(), "y") {
 			utils.FatalPrintln("Aborting.")
 		}
 	}
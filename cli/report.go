@@ -0,0 +1,179 @@
+package cli
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/arken/ait/apis/community"
+	"github.com/arken/ait/config"
+	"github.com/arken/ait/ipfs"
+	"github.com/arken/ait/utils"
+
+	"github.com/DataDrake/cli-ng/v2/cmd"
+)
+
+// Report shows what this machine has contributed to the cluster.
+var Report = cmd.Sub{
+	Name:  "report",
+	Short: "Report on this machine's contribution to the Arken Cluster.",
+	Args:  &ReportArgs{},
+	Flags: &ReportFlags{},
+	Run:   ReportRun,
+}
+
+// ReportArgs handles the specific arguments for the report command.
+type ReportArgs struct {
+	Keysets []string `zero:"yes" desc:"Keyset files or repositories the --at-risk report checks, every file you submitted by default"`
+}
+
+// ReportFlags handles the specific flags for the report command.
+type ReportFlags struct {
+	Transfer    bool   `short:"t" long:"transfer" desc:"Show the data uploaded and downloaded per dataset"`
+	Days        int    `short:"d" long:"days" desc:"Number of recent days to total separately (default 30)"`
+	Share       bool   `short:"s" long:"share" desc:"Send anonymized seeding statistics to the community endpoint"`
+	Leaderboard bool   `short:"l" long:"leaderboard" desc:"Show the cluster-wide contribution leaderboard"`
+	AtRisk      bool   `short:"a" long:"at-risk" desc:"List the files of the keysets provided by fewer peers than Notify.AtRiskThreshold"`
+	Output      string `short:"o" long:"output" desc:"Also write the --at-risk report as CSV to the file"`
+}
+
+const reportUsage = `	ait report --transfer/-t [--days/-d 30]  # Data uploaded and downloaded per dataset
+	ait report --share/-s                    # Share anonymized seeding statistics (opt-in)
+	ait report --leaderboard/-l              # Show the community contribution leaderboard
+	ait report --at-risk/-a [keysets...]     # List the under-replicated files of keysets or your submissions`
+
+// leaderboardSize is the number of contributors shown by --leaderboard.
+const leaderboardSize = 20
+
+// ReportRun prints the requested report.
+func ReportRun(_ *cmd.Root, c *cmd.Sub) {
+	flags := c.Flags.(*ReportFlags)
+	if !flags.Transfer && !flags.Share && !flags.Leaderboard && !flags.AtRisk {
+		utils.FatalPrintln("Expected a report to show:\n" + reportUsage)
+	}
+	if flags.Transfer {
+		days := flags.Days
+		if days <= 0 {
+			days = 30
+		}
+		reportTransfer(days)
+	}
+	if flags.Share {
+		optedIn := config.Global.Community.Share && config.Global.Community.ID != ""
+		if !optedIn && !promptShareStats() {
+			utils.FatalPrintln("Aborting.")
+		}
+		utils.CheckError(shareStats())
+		fmt.Println("Shared your seeding statistics as", config.Global.Community.ID[:8]+".")
+	}
+	if flags.Leaderboard {
+		reportLeaderboard()
+	}
+	if flags.AtRisk {
+		reportAtRisk(c.Args.(*ReportArgs).Keysets, flags.Output)
+	}
+}
+
+// reportTransfer prints the recorded transfer totals of each dataset, most
+// uploaded first, along with the totals of the last few days.
+func reportTransfer(days int) {
+	stats, err := ipfs.ReadTransfers()
+	utils.CheckError(err)
+	if len(stats) == 0 {
+		fmt.Println("No transfers have been recorded yet. Seed a dataset with \"ait upload\"" +
+			" or pull files with \"ait pull\" first.")
+		return
+	}
+	datasets := make([]string, 0, len(stats))
+	for dataset := range stats {
+		datasets = append(datasets, dataset)
+	}
+	sort.Slice(datasets, func(i, j int) bool {
+		return stats[datasets[i]].Uploaded > stats[datasets[j]].Uploaded
+	})
+
+	now := time.Now()
+	since := time.Date(now.Year(), now.Month(), now.Day()-days+1, 0, 0, 0, 0, time.Local)
+	var totalUp, totalDown, recentUp, recentDown int64
+	fmt.Printf("Transfers for %v dataset(s):\n", len(datasets))
+	for _, dataset := range datasets {
+		t := stats[dataset]
+		up, down := t.Since(since)
+		fmt.Printf("\t%v\n", dataset)
+		fmt.Printf("\t\tAll time:         %v up / %v down\n",
+			utils.FormatByteSize(t.Uploaded), utils.FormatByteSize(t.Downloaded))
+		fmt.Printf("\t\tLast %-3d day(s): %v up / %v down\n", days,
+			utils.FormatByteSize(up), utils.FormatByteSize(down))
+		fmt.Printf("\t\tLast active:      %v\n", t.LastActive.Format("Jan 2 2006 3:04 PM"))
+		totalUp, totalDown = totalUp+t.Uploaded, totalDown+t.Downloaded
+		recentUp, recentDown = recentUp+up, recentDown+down
+	}
+	fmt.Printf("Total: %v up / %v down (%v up / %v down in the last %v day(s))\n",
+		utils.FormatByteSize(totalUp), utils.FormatByteSize(totalDown),
+		utils.FormatByteSize(recentUp), utils.FormatByteSize(recentDown), days)
+}
+
+// promptShareStats asks the user to opt in to sharing statistics and saves
+// their choice along with a new anonymous identifier.
+func promptShareStats() bool {
+	fmt.Printf(`Sharing sends the total amount of data you've uploaded and downloaded and the
+number of datasets you seed to %v after each upload.
+Datasets aren't named and you're identified by a random ID, not your node.
+Share your seeding statistics? (y/[n]) `, config.Global.Community.Endpoint)
+	if !strings.EqualFold(utils.ReadAnswer(), "y") {
+		return false
+	}
+	if config.Global.Community.ID == "" {
+		raw := make([]byte, 16)
+		_, err := rand.Read(raw)
+		utils.CheckError(err)
+		config.Global.Community.ID = hex.EncodeToString(raw)
+	}
+	config.Global.Community.Share = true
+	config.GenConf(config.Global)
+	return true
+}
+
+// shareStats sends the totals of the recorded transfers to the community
+// endpoint under the anonymous identifier.
+func shareStats() error {
+	transfers, err := ipfs.ReadTransfers()
+	if err != nil {
+		return err
+	}
+	stats := community.Stats{ID: config.Global.Community.ID, Reported: time.Now()}
+	for _, t := range transfers {
+		stats.Uploaded += t.Uploaded
+		stats.Downloaded += t.Downloaded
+		if t.Uploaded > 0 {
+			stats.Datasets++
+		}
+	}
+	return community.Submit(config.Global.Community.Endpoint, stats)
+}
+
+// reportLeaderboard prints the top contributors of the cluster.
+func reportLeaderboard() {
+	standings, err := community.Leaderboard(config.Global.Community.Endpoint, leaderboardSize)
+	utils.CheckError(err)
+	if len(standings) == 0 {
+		fmt.Println("No contributions have been shared yet.")
+		return
+	}
+	fmt.Println("Top contributors to the Arken Cluster:")
+	for _, s := range standings {
+		you := ""
+		if s.ID == config.Global.Community.ID {
+			you = " (you)"
+		}
+		id := s.ID
+		if len(id) > 8 {
+			id = id[:8]
+		}
+		fmt.Printf("\t%3d. %v  %v uploaded, %v dataset(s)%v\n",
+			s.Rank, id, utils.FormatByteSize(s.Uploaded), s.Datasets, you)
+	}
+}
@@ -0,0 +1,130 @@
+package cli
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+
+	"github.com/arken/ait/config"
+	"github.com/arken/ait/ipfs"
+	"github.com/arken/ait/keysets"
+	"github.com/arken/ait/types"
+	"github.com/arken/ait/utils"
+
+	"github.com/DataDrake/cli-ng/v2/cmd"
+)
+
+// Reproduce regenerates a submitted keyset from the original files and
+// compares the two, to audit that the archived files are the ones submitted.
+var Reproduce = cmd.Sub{
+	Name:  "reproduce",
+	Short: "Regenerate a submitted keyset from the original files and compare them byte-for-byte.",
+	Args:  &ReproduceArgs{},
+	Flags: &ReproduceFlags{},
+	Run:   ReproduceRun,
+}
+
+// ReproduceArgs handles the specific arguments for the reproduce command.
+type ReproduceArgs struct {
+	Keyset string   `desc:"The submitted keyset file"`
+	Paths  []string `zero:"yes" desc:"The files and directories it was generated from, the working directory by default"`
+}
+
+// ReproduceFlags handles the specific flags for the reproduce command.
+type ReproduceFlags struct {
+	Output string `short:"o" long:"output" desc:"Also write the reproduced keyset to the given path"`
+}
+
+// ReproduceRun hashes the files at the paths, relative to the working
+// directory as they were to the workspace they were staged from, into a
+// keyset like submitting them did, and compares it to the submitted keyset.
+// Any divergence is reported and exits with an error.
+func ReproduceRun(_ *cmd.Root, c *cmd.Sub) {
+	args := c.Args.(*ReproduceArgs)
+	flags := c.Flags.(*ReproduceFlags)
+	submitted, err := ioutil.ReadFile(args.Keyset)
+	utils.CheckError(err)
+	if len(args.Paths) == 0 {
+		args.Paths = []string{"."}
+	}
+	walked := types.NewThreadSafeStringSet()
+	for _, userPath := range args.Paths {
+		path, err := utils.WorkspacePath(userPath)
+		if err != nil {
+			fmt.Printf("Only files under the working directory can be reproduced, skipping %v\n", userPath)
+			continue
+		}
+		addPath(path, walked)
+	}
+	// The keysets themselves may be in the directory the files are in.
+	skip := types.NewBasicStringSet()
+	for _, p := range []string{args.Keyset, flags.Output} {
+		if path, err := utils.WorkspacePath(p); err == nil && p != "" {
+			skip.Add(path)
+		}
+	}
+	var paths []string
+	_ = walked.ForEach(func(path string) error {
+		if !skip.Contains(path) {
+			paths = append(paths, path)
+		}
+		return nil
+	})
+	if len(paths) == 0 {
+		utils.FatalPrintln("No files were found to reproduce the keyset from.")
+	}
+
+	prettyIPFSInit()
+	fmt.Printf("Hashing %d file(s) with the %q layout, inlining %v:\n",
+		len(paths), ipfsLayout(), config.Global.IPFS.Inline)
+	reproduced, failures, err := keysets.Reproduce(paths)
+	utils.CheckError(err)
+	for _, failure := range failures {
+		fmt.Printf("\tUnable to hash %v: %v\n", failure.Path, failure.Error)
+	}
+	if flags.Output != "" {
+		utils.CheckError(ioutil.WriteFile(flags.Output, reproduced, 0644))
+		fmt.Println("Wrote the reproduced keyset to", flags.Output)
+	}
+
+	d, err := keysets.Compare(submitted, reproduced)
+	utils.CheckError(err)
+	fmt.Printf("Submitted:  sha256 %v  %v\n", keysetSum(submitted), args.Keyset)
+	fmt.Printf("Reproduced: sha256 %v\n", keysetSum(reproduced))
+	if d.Identical() {
+		fmt.Println("The keyset was reproduced byte-for-byte.")
+		return
+	}
+	fmt.Printf("The keysets diverge from line %d:\n", d.Line)
+	if d.Reordered() {
+		fmt.Println("\tThey list the same files under the same CIDs, in another order, ie because the keyset was amended.")
+	}
+	for _, entry := range d.Changed {
+		fmt.Printf("\tchanged   %v: submitted %v, reproduced %v\n", entry.Name, entry.Submitted, entry.Reproduced)
+	}
+	for _, entry := range d.Missing {
+		fmt.Printf("\tmissing   %v  %v\n", entry.CID, entry.Name)
+	}
+	for _, entry := range d.Extra {
+		fmt.Printf("\tnot submitted  %v  %v\n", entry.CID, entry.Name)
+	}
+	if len(d.Changed) > 0 {
+		fmt.Println("CIDs depend on the IPFS Layout and Inline settings, they must be those the keyset was submitted with.")
+	}
+	utils.Exit(1)
+}
+
+// ipfsLayout returns the DAG layout files are added with.
+func ipfsLayout() string {
+	if config.Global.IPFS.Layout == "" {
+		return ipfs.LayoutBalanced
+	}
+	return config.Global.IPFS.Layout
+}
+
+// keysetSum returns the hex SHA-256 of a keyset, as recorded in receipts.
+func keysetSum(keyset []byte) string {
+	sum := sha256.Sum256(keyset)
+	return hex.EncodeToString(sum[:])
+}
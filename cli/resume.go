@@ -0,0 +1,154 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/arken/ait/apis/forge"
+	"github.com/arken/ait/display"
+	"github.com/arken/ait/types"
+	"github.com/arken/ait/utils"
+)
+
+// submitJournal is the journal of the submission being made or resumed, nil
+// when it isn't journaled, ie while flushing the queue.
+var submitJournal *utils.SubmissionJournal
+
+// journalBefore is the hook utils.BeforeFatal held before the journal was
+// watched.
+var journalBefore func(msg string)
+
+// startJournal journals the submission about to be generated, unless it's
+// resumed from a journal already or flushed from the queue, which keeps its
+// own copy of the keyset.
+func startJournal(url string, isPR, isIssue bool, flags *SubmitFlags,
+	app *types.ApplicationContents, overwrite, exists bool) {
+	if submitJournal != nil || queuedKeyset != "" {
+		return
+	}
+	j := utils.NewJournal(url)
+	j.PullRequest, j.Issue, j.ROCrate, j.Also = isPR, isIssue, flags.ROCrate, flags.Also
+	j.Provider = flags.Provider
+	j.Title, j.Commit, j.PRBody = app.Title, app.Commit, app.PRBody
+	j.Category, j.Filename = app.Category, app.KsName
+	j.Overwrite, j.Exists = overwrite, exists
+	watchJournal(j)
+}
+
+// watchJournal makes j the journal of the submission, and tells how to resume
+// it if the submission fails after some of its steps completed.
+func watchJournal(j *utils.SubmissionJournal) {
+	submitJournal = j
+	journalBefore = utils.BeforeFatal
+	before := journalBefore
+	utils.BeforeFatal = func(msg string) {
+		printResumeHint(j)
+		if before != nil {
+			before(msg)
+		}
+	}
+}
+
+// printResumeHint tells how to resume the submission journaled in j, if some
+// of its steps completed. The journal is gone if the submission was queued
+// instead.
+func printResumeHint(j *utils.SubmissionJournal) {
+	if len(j.Steps) > 0 && utils.FileExists(j.Dir()) {
+		fmt.Printf("The submission stopped after the step %q, run \"ait submit --resume\" "+
+			"to pick it up from there.\n", j.LastStep())
+	}
+}
+
+// abandonJournal tells how to resume the submission that failed, and stops
+// journaling it so the next one, ie to another remote of a batch submission,
+// gets its own journal.
+func abandonJournal() {
+	if submitJournal == nil {
+		return
+	}
+	printResumeHint(submitJournal)
+	utils.BeforeFatal = journalBefore
+	submitJournal, journalBefore = nil, nil
+}
+
+// journalKeyset keeps a copy of the keyset generated at ksPath with the
+// journal, so resuming doesn't hash the staged files again.
+func journalKeyset(ksPath string) {
+	if submitJournal == nil || submitJournal.Done(utils.StepGenerated) {
+		return
+	}
+	err := os.MkdirAll(submitJournal.Dir(), os.ModePerm)
+	if err == nil {
+		err = utils.CopyFile(ksPath, submitJournal.KeysetPath())
+	}
+	if err != nil {
+		fmt.Println("Unable to journal the keyset, the submission can't be resumed if it fails:", err)
+		return
+	}
+	recordStep(utils.StepGenerated)
+}
+
+// recordStep records that the step of the journaled submission completed.
+func recordStep(step string) {
+	if submitJournal == nil || submitJournal.Done(step) {
+		return
+	}
+	if err := submitJournal.Record(step); err != nil {
+		fmt.Println("Unable to journal the submission, it can't be resumed if it fails:", err)
+	}
+}
+
+// stepDone returns whether the step of the journaled submission completed
+// before it was resumed.
+func stepDone(step string) bool {
+	return submitJournal != nil && submitJournal.Done(step)
+}
+
+// finishJournal removes the journal of the submission once it completed.
+func finishJournal() {
+	if submitJournal == nil {
+		return
+	}
+	if err := submitJournal.Remove(); err != nil {
+		fmt.Println("Unable to remove the journal of the submission:", err)
+	}
+	utils.BeforeFatal = journalBefore
+	submitJournal, journalBefore = nil, nil
+}
+
+// resumeSubmission picks up the most recent submission that didn't complete
+// from the last step its journal recorded.
+func resumeSubmission(flags *SubmitFlags) {
+	j, err := utils.LatestJournal()
+	utils.CheckError(err)
+	if j == nil {
+		utils.FatalPrintln("No submission was left unfinished, there's nothing to resume.")
+	}
+	if !j.Done(utils.StepGenerated) || !utils.FileExists(j.KeysetPath()) {
+		utils.FatalPrintf("The keyset of the submission %v wasn't kept, it can't be resumed. "+
+			"Remove %v and submit again.\n", j.ID, j.Dir())
+	}
+	fmt.Printf("Resuming the submission of %v to %v, done so far: %v.\n",
+		j.Title, j.Remote, strings.Join(j.Steps, ", "))
+	kind, err := forge.Detect(j.Remote, j.Provider)
+	utils.CheckError(err)
+	if !remoteReachable(j.Remote, kind) {
+		utils.FatalPrintf("%v still can't be reached, resume the submission once it can.\n", j.Remote)
+	}
+	display.KeysetExtension = path.Ext(j.Filename)
+	utils.CheckError(display.WriteApplication(&types.ApplicationContents{
+		Title:    j.Title,
+		Commit:   j.Commit,
+		PRBody:   j.PRBody,
+		Category: j.Category,
+		KsName:   j.Filename,
+	}))
+	flags.ROCrate, flags.Also, flags.Provider = j.ROCrate, j.Also, j.Provider
+	prettyIPFSInit()
+	watchJournal(j)
+	if _, err = submitOnline(j.Remote, kind, j.PullRequest, j.Issue, flags); err != nil {
+		exitSubmission(err)
+	}
+}
@@ -1,45 +1,224 @@
 package cli
 
 import (
+	"fmt"
 	"os"
+	"time"
 
+	"github.com/arken/ait/config"
+	"github.com/arken/ait/display"
+	"github.com/arken/ait/ipfs"
 	"github.com/arken/ait/utils"
 
 	"github.com/DataDrake/cli-ng/v2/cmd"
 )
 
 //GlobalFlags contains the flags for commands.
-type GlobalFlags struct{}
+type GlobalFlags struct {
+	AutoMigrate    bool   `long:"auto-migrate" desc:"Migrate the IPFS repository without prompting if it is out of date"`
+	NoMigrate      bool   `long:"no-migrate" desc:"Never migrate the IPFS repository, failing instead"`
+	Profile        string `long:"profile" desc:"Use the IPFS repository of the named profile"`
+	ProgressJSON   bool   `long:"progress-json" desc:"Write progress events to stderr as newline delimited JSON"`
+	ProgressSocket string `long:"progress-socket" desc:"Write progress events as newline delimited JSON to a unix socket"`
+	Timings        bool   `long:"timings" desc:"Print where the command spent its time when it finishes"`
+	Workdir        string `short:"C" long:"workdir" desc:"Run as if ait was started in the given workspace directory"`
+	Home           string `long:"home" desc:"Keep the config, IPFS repository and cloned sources in this directory instead of ~/.ait"`
+	JSON           bool   `long:"json" desc:"Write the output as newline delimited JSON records instead of text"`
+	Rehash         bool   `long:"rehash" desc:"Hash every file again rather than reusing the CIDs of the unchanged ones"`
+}
 
 // Root is the main command.
 var Root *cmd.Root
 
-// init creates the command interface and registers the possible commands.
+// repoFreeCommands are the commands (and aliases) that can be run outside of
+// an AIT repo, matched against the command of the command line only.
+var repoFreeCommands = []string{"help", "init", "i", "pull", "remote", "update", "ipfs", "node",
+	"key", "k", "clean", "backup", "restore", "trust", "report", "relocate", "index", "setup",
+	"netcheck", "bugreport", "coverage", "plan", "reproduce", "template", "registry", "identity", "daemon",
+	"gc", "validate", "id", "config", "completion", "doctor"}
+
+// init loads the config, creates the command interface and registers the
+// possible commands.
 func init() {
-	isHelp := len(os.Args) < 2 || utils.IndexOf(os.Args, "help") > 0
-	isInit := utils.IndexOf(os.Args, "init") > 0 || utils.IndexOf(os.Args, "i") > 0
-	isPull := utils.IndexOf(os.Args, "pull") > 0
-	isRemote := utils.IndexOf(os.Args, "remote") > 0
-	isUpdate := utils.IndexOf(os.Args, "update") > 0
-	isTesting := utils.IndexOf(os.Args, "-test.v") > 0 //Don't force init when testing
-	if !utils.IsAITRepo() && !isInit && !isTesting && !isPull && !isRemote && !isHelp && !isUpdate {
-		utils.FatalPrintln(`This is not an AIT repository! Please run
-	ait init
-Before issuing any other commands.`)
-	}
+	// The command line isn't parsed yet, so --home is looked for in it.
+	home, err := config.StateDir(os.Args[1:])
+	utils.CheckError(err)
+	utils.CheckError(config.Load(home, true))
 	Root = &cmd.Root{
 		Name:  "ait",
 		Short: "Arken Import Tool",
 		Flags: &GlobalFlags{},
 	}
+	// Identify ait to the servers it makes requests to.
+	utils.UserAgent = utils.FormatUserAgent(appVersion, config.Global.General.Contact)
+	if config.Global.Network.Proxy != "" {
+		_, err := utils.ParseProxy(config.Global.Network.Proxy)
+		utils.CheckError(err)
+		utils.Proxy = config.Global.Network.Proxy
+	}
 	cmd.Register(&cmd.Help)
-	cmd.Register(&Stage)
-	cmd.Register(&AddRemote)
-	cmd.Register(&Init)
-	cmd.Register(&Unstage)
-	cmd.Register(&Status)
-	cmd.Register(&Submit)
-	cmd.Register(&Upload)
-	cmd.Register(&Pull)
-	cmd.Register(&Update)
+	register(&Stage)
+	register(&AddRemote)
+	register(&Init)
+	register(&Unstage)
+	register(&Status)
+	register(&Submit)
+	register(&Upload)
+	register(&Pull)
+	register(&Update)
+	register(&IPFS)
+	register(&Key)
+	register(&Clean)
+	register(&Backup)
+	register(&Restore)
+	register(&Handoff)
+	register(&Bundle)
+	register(&ReviewBundle)
+	register(&PR)
+	register(&Trust)
+	register(&Report)
+	register(&Web)
+	register(&Relocate)
+	register(&Index)
+	register(&Setup)
+	register(&NetCheck)
+	register(&BugReport)
+	register(&Catalog)
+	register(&Ingest)
+	register(&Mirror)
+	register(&Watch)
+	register(&Queue)
+	register(&Keyset)
+	register(&Coverage)
+	register(&Plan)
+	register(&UnpinWorkspace)
+	register(&GC)
+	register(&Preview)
+	register(&MergeStaging)
+	register(&Reproduce)
+	register(&Template)
+	register(&Registry)
+	register(&Identity)
+	register(&Daemon)
+	register(&Verify)
+	register(&Meta)
+	register(&Log)
+	register(&UI)
+	register(&Validate)
+	register(&ID)
+	register(&Config)
+	register(&Completion)
+	register(&Doctor)
+	// The workspace is changed to, and aliases and default flags from the
+	// config are applied, before anything looks at the command line.
+	os.Args = append(os.Args[:1], expandArgs(changeWorkdir(os.Args[1:]))...)
+
+	// Only the command is looked at, the same words can be arguments of
+	// other commands, ie "ait stage config".
+	i := commandIndex(os.Args[1:])
+	isHelp := i < 0
+	isRepoFree := !isHelp && utils.IndexOf(repoFreeCommands, os.Args[i+1]) >= 0
+	isTesting := utils.IndexOf(os.Args, "-test.v") > 0 //Don't force init when testing
+	if !utils.IsAITRepo() && !isTesting && !isHelp && !isRepoFree {
+		utils.FatalPrintln(`This is not an AIT repository! Please run
+	ait init
+Before issuing any other commands.`)
+	}
+	if utils.IsAITRepo() {
+		recovered, err := utils.RecoverStaged()
+		utils.CheckError(err)
+		if recovered {
+			fmt.Println("Recovered the staged files from an interrupted operation.")
+		}
+	}
+}
+
+// register adds the subcommand to the interface, making sure the global flags
+// are applied before the subcommand runs.
+func register(sub *cmd.Sub) {
+	registered = append(registered, sub)
+	commandNames[sub.Name] = sub.Name
+	if sub.Alias != "" {
+		commandNames[sub.Alias] = sub.Name
+	}
+	run := sub.Run
+	sub.Run = func(r *cmd.Root, c *cmd.Sub) {
+		applyGlobalFlags(r.Flags.(*GlobalFlags))
+		if r.Flags.(*GlobalFlags).JSON {
+			emitJSON(sub.Name)
+		}
+		utils.BeforeFatal = recordFailure
+		done := watchOperation(sub.Name)
+		timed := printTimings()
+		run(r, c)
+		done()
+		timed()
+		display.Out.Release()
+	}
+	cmd.Register(sub)
+}
+
+// applyGlobalFlags overrides the loaded configuration with the global flags
+// given on the command line.
+func applyGlobalFlags(flags *GlobalFlags) {
+	utils.ShowUploads = display.UploadTransport
+	if flags.AutoMigrate && flags.NoMigrate {
+		utils.FatalPrintln("--auto-migrate and --no-migrate cannot be used together.")
+	}
+	if flags.AutoMigrate {
+		config.Global.IPFS.Migrate = ipfs.MigrateAuto
+	} else if flags.NoMigrate {
+		config.Global.IPFS.Migrate = ipfs.MigrateNever
+	}
+	if flags.Profile != "" {
+		utils.CheckError(config.UseProfile(flags.Profile))
+	}
+	if flags.ProgressJSON {
+		display.StreamEvents(os.Stderr)
+	}
+	if flags.ProgressSocket != "" {
+		utils.CheckError(display.StreamEventsTo(flags.ProgressSocket))
+	}
+	if flags.Timings {
+		utils.EnableTimings()
+	}
+	utils.Rehash = flags.Rehash
+}
+
+// emitJSON makes the command write its output as JSON records: what it prints
+// becomes message records, its results result records and a fatal error an
+// error record.
+func emitJSON(command string) {
+	display.Out.EnableJSON(command)
+	utils.CheckError(display.Out.Capture())
+	utils.FatalOutput = display.Out.Error
+	utils.BeforeExit = display.Out.Release
+}
+
+// deprecatedJSON makes the command write its output as JSON records when set,
+// the flag of the command given being the deprecated form of the global
+// --json.
+func deprecatedJSON(command, flag string, set bool) {
+	if !set || display.Out.JSON() {
+		return
+	}
+	fmt.Fprintf(os.Stderr, "%v %v is deprecated, use \"ait --json %v\" instead.\n", command, flag, command)
+	emitJSON(command)
+}
+
+// printTimings arranges for the breakdown of where the command spent its time
+// to be printed to stderr if it fails, and returns the function printing it
+// once it succeeds. Nothing is printed without --timings.
+func printTimings() func() {
+	started := time.Now()
+	before := utils.BeforeFatal
+	utils.BeforeFatal = func(msg string) {
+		if before != nil {
+			before(msg)
+		}
+		utils.WriteTimings(os.Stderr, time.Since(started))
+	}
+	return func() {
+		utils.WriteTimings(os.Stderr, time.Since(started))
+	}
 }
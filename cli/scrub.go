@@ -0,0 +1,74 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/arken/ait/config"
+	"github.com/arken/ait/ipfs"
+)
+
+// scrubCheckInterval is how often a long running node checks whether a scrub
+// is due.
+const scrubCheckInterval = time.Hour
+
+// scrubPeriodically re-verifies the repository every ScrubPeriod until ctx is
+// canceled, repairing corrupt or missing blocks from the staged files or the
+// network. The time of the last scrub is persisted so that short sessions
+// don't scrub every time they start. It is meant for long running nodes.
+func scrubPeriodically(ctx context.Context) {
+	// An external daemon's repository isn't ait's to scrub.
+	if config.Global.IPFS.ScrubPeriod == "" || ipfs.BackendName() != "embedded" {
+		return
+	}
+	period, err := time.ParseDuration(config.Global.IPFS.ScrubPeriod)
+	if err != nil || period <= 0 {
+		fmt.Printf("\n[Ignoring ScrubPeriod setting %q: not a valid duration]\n",
+			config.Global.IPFS.ScrubPeriod)
+		return
+	}
+	ticker := time.NewTicker(scrubCheckInterval)
+	defer ticker.Stop()
+	for {
+		stats, err := ipfs.ReadScrubStats()
+		if err == nil && time.Since(stats.Last) >= period {
+			scrub()
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// scrub checks every block of the repository once, repairs what it can and
+// records the result.
+func scrub() {
+	report, err := ipfs.Fsck()
+	if err != nil {
+		fmt.Printf("\n[Unable to scrub the IPFS repository: %v]\n", err)
+		return
+	}
+	stats := ipfs.ScrubStats{
+		Last:          time.Now(),
+		BlocksChecked: report.BlocksChecked,
+		Problems:      report.Problems(),
+	}
+	if !report.IsHealthy() {
+		fmt.Printf("\n[Scrub found %d problem(s) in the IPFS repository, repairing:]\n", stats.Problems)
+		printFsckReport(report)
+		stats.Unrepaired, err = repairRepository(report)
+		if err != nil {
+			fmt.Printf("[Unable to repair the IPFS repository: %v]\n", err)
+			stats.Unrepaired = stats.Problems
+		} else if stats.Unrepaired > 0 {
+			fmt.Printf("[%d pinned file(s) could not be repaired, run \"ait ipfs fsck --repair\".]\n",
+				stats.Unrepaired)
+		}
+	}
+	if err := ipfs.WriteScrubStats(stats); err != nil {
+		fmt.Printf("[Unable to record the scrub: %v]\n", err)
+	}
+}
@@ -0,0 +1,71 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/arken/ait/ipfs"
+	"github.com/arken/ait/utils"
+)
+
+// seedAnnouncePeriod is how often the submitted files are announced again
+// while seeding after a submission.
+const seedAnnouncePeriod = time.Hour
+
+// seedAfterSubmit keeps the node running for duration after a submission, so
+// peers can fetch the submitted files from it, announcing them every
+// seedAnnouncePeriod. Once the duration is up, or on Ctrl-C, it prints how
+// replicated each submission made since started is.
+func seedAfterSubmit(duration time.Duration, started time.Time) {
+	history, err := utils.ReadHistory()
+	utils.CheckError(err)
+	var submitted []*utils.Submission
+	for _, s := range history {
+		if !s.Time.Before(started) {
+			submitted = append(submitted, s)
+		}
+	}
+	if len(submitted) == 0 {
+		return
+	}
+
+	until := time.Now().Add(duration)
+	fmt.Printf("Seeding the submitted files until %v, press Ctrl-C to stop early.\n",
+		until.Format("Jan 2 15:04"))
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(sig)
+	ticker := time.NewTicker(seedAnnouncePeriod)
+	defer ticker.Stop()
+	timer := time.NewTimer(duration)
+	defer timer.Stop()
+seeding:
+	for {
+		select {
+		case <-ticker.C:
+			for _, s := range submitted {
+				for _, entry := range s.Entries {
+					_ = ipfs.Provide(entry.CID)
+				}
+			}
+		case <-sig:
+			fmt.Println("\nStopped seeding early.")
+			break seeding
+		case <-timer.C:
+			break seeding
+		}
+	}
+
+	fmt.Println("Replication of the submitted files:")
+	for _, s := range submitted {
+		_, replicated := replicationCounts(s)
+		fmt.Printf("\t%v  %d of %d file(s) provided by at least %d peers\n",
+			s.Path, replicated, len(s.Entries), replicationTarget())
+		if replicated < len(s.Entries) {
+			fmt.Println("\t\tRun \"ait upload\" to keep seeding them until they are replicated.")
+		}
+	}
+}
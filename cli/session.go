@@ -0,0 +1,76 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"time"
+
+	"github.com/arken/ait/utils"
+)
+
+// stagedList returns the staged files, sorted.
+func stagedList() []string {
+	contents, err := utils.ReadStagedSorted()
+	if err != nil {
+		return nil
+	}
+	staged := make([]string, 0, contents.Size())
+	contents.ForEach(func(path string) error {
+		staged = append(staged, path)
+		return nil
+	})
+	return staged
+}
+
+// recordSubmitSession starts recording the submission made with args and
+// flags, and returns a function saving it to flags.Record, and as the
+// template flags.SaveTemplate, once done.
+func recordSubmitSession(args []string, flags *SubmitFlags) func() {
+	recorded := *flags
+	recorded.Record, recorded.Template, recorded.SaveTemplate = "", "", ""
+	data, err := json.Marshal(recorded)
+	utils.CheckError(err)
+	session := &utils.Session{
+		Time:    time.Now(),
+		Command: "submit",
+		Args:    args,
+		Flags:   data,
+		Staged:  stagedList(),
+	}
+	utils.RecordSession(session)
+	return func() {
+		if flags.SaveTemplate != "" {
+			saveSubmitTemplate(flags.SaveTemplate, args, session)
+		}
+		if flags.Record == "" {
+			return
+		}
+		if err := session.Save(flags.Record); err != nil {
+			fmt.Println("Unable to save the recorded session:", err)
+			return
+		}
+		fmt.Printf("Recorded the session to %v, \"ait submit --replay %v\" runs it again.\n",
+			flags.Record, flags.Record)
+	}
+}
+
+// replaySubmitSession loads the session recorded at flags.Replay in place of
+// the flags and returns its arguments. The same files must be staged.
+func replaySubmitSession(flags *SubmitFlags) []string {
+	path := flags.Replay
+	session, err := utils.ReadSession(path)
+	utils.CheckError(err)
+	if session.Command != "submit" {
+		utils.FatalPrintf("%v recorded \"ait %v\", not a submission.\n", path, session.Command)
+	}
+	if !reflect.DeepEqual(stagedList(), session.Staged) {
+		utils.FatalPrintf("The staged files differ from the %d recorded in %v, stage the same files "+
+			"to replay the submission.\n", len(session.Staged), path)
+	}
+	utils.CheckError(json.Unmarshal(session.Flags, flags))
+	flags.Replay, flags.Record = path, ""
+	utils.ReplaySession(session)
+	fmt.Printf("Replaying the submission recorded on %v.\n", session.Time.Format("Jan 2 2006 15:04"))
+	return session.Args
+}
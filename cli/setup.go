@@ -0,0 +1,143 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	aitgh "github.com/arken/ait/apis/github"
+	"github.com/arken/ait/config"
+	"github.com/arken/ait/ipfs"
+	"github.com/arken/ait/utils"
+
+	"github.com/DataDrake/cli-ng/v2/cmd"
+)
+
+// Setup walks new users through configuring ait.
+var Setup = cmd.Sub{
+	Name:  "setup",
+	Short: "Walk through configuring ait for the first time.",
+	Run:   SetupRun,
+}
+
+// setupTimeout is how long each connectivity check waits.
+const setupTimeout = 10 * time.Second
+
+// SetupRun asks for the settings a new user needs, checks that the Arken
+// network and GitHub can be reached, and writes the config once it's valid.
+func SetupRun(_ *cmd.Root, _ *cmd.Sub) {
+	if config.ActiveProfile != "" {
+		utils.FatalPrintln("ait setup configures the default IPFS repository, run it without --profile.")
+	}
+	conf := config.Global
+	fmt.Println("This will walk you through setting up ait. Press enter to keep the value in brackets.")
+
+	fmt.Println("\n[1/5] Identity, used for the commits of your submissions")
+	conf.Git.Name, conf.Git.Email = askIdentity(conf.Git.Name, conf.Git.Email, conf.Git.EmailDomains)
+
+	fmt.Println("\n[2/5] GitHub, to submit keysets to repositories hosted there")
+	if config.GitHubToken() != "" {
+		// A token kept in the config is moved to the keychain if one became
+		// available.
+		if moved, err := config.MigrateGitHubToken(); err != nil {
+			fmt.Println("Unable to move the token to the system keychain:", err)
+		} else if moved {
+			fmt.Printf("Moved the GitHub token from %v to the system keychain.\n", config.Path)
+		}
+		conf.Git.PAT = config.Global.Git.PAT
+		fmt.Println("An access token is already saved.")
+	} else if strings.ToLower(ask("Sign in to GitHub now? (y/n)", "y")) == "y" {
+		login, err := aitgh.Login()
+		if err != nil {
+			fmt.Println("Unable to sign in, you will be asked again when you submit:", err)
+		} else {
+			fmt.Println("Signed in as", login)
+			if strings.ToLower(ask("Save the token in the system keychain for future submissions? (y/n)", "y")) == "y" {
+				config.SaveGitHubToken(aitgh.Token())
+				// The token is only kept in the config without a keychain.
+				conf.Git.PAT = config.Global.Git.PAT
+			}
+		}
+	}
+
+	fmt.Println("\n[3/5] Storage, where the files you seed are tracked")
+	for {
+		path := utils.ExpandHome(ask("IPFS repository location", conf.IPFS.Path))
+		abs, err := filepath.Abs(path)
+		if err == nil {
+			err = os.MkdirAll(filepath.Dir(abs), os.ModePerm)
+		}
+		if err == nil {
+			conf.IPFS.Path = abs
+			break
+		}
+		fmt.Println("Unable to use that location:", err)
+	}
+
+	fmt.Println("\n[4/5] Storage budget, the most the IPFS repository may grow to")
+	for {
+		budget := ask("Budget (ie 500GB or 2TB)", conf.IPFS.StorageMax)
+		size, err := utils.ParseByteSize(budget)
+		if err == nil && size > 0 {
+			conf.IPFS.StorageMax = budget
+			break
+		}
+		fmt.Println("Please enter a size such as 500GB.")
+	}
+
+	fmt.Println("\n[5/5] Connectivity")
+	reachable := true
+	for _, dial := range ipfs.DialArkenPeers(setupTimeout) {
+		if dial.Err != nil {
+			fmt.Printf("  Unable to reach %v: %v\n", dial.Host, dial.Err)
+			reachable = false
+		} else {
+			fmt.Printf("  Reached %v\n", dial.Host)
+		}
+	}
+	client := utils.PinnedClient(conf.Trust.Hosts)
+	client.Timeout = setupTimeout
+	if resp, err := client.Get("https://api.github.com"); err != nil {
+		fmt.Println("  Unable to reach GitHub:", err)
+		reachable = false
+	} else {
+		resp.Body.Close()
+		fmt.Println("  Reached GitHub")
+	}
+	if !reachable {
+		fmt.Println("Some services couldn't be reached. Check your firewall allows outgoing " +
+			"connections on ports 443 and 4001, ait will still be set up.")
+	}
+
+	if err := config.Validate(conf); err != nil {
+		utils.FatalPrintln("The configuration isn't valid:", err)
+	}
+	config.Global = conf
+	config.GenConf(conf)
+	fmt.Printf("\nSaved your configuration to %v. Run \"ait init\" in a dataset's directory to start.\n",
+		config.Path)
+}
+
+// ask prints the question with its default answer and returns the answer, or
+// the default if nothing was entered. The command is aborted when input ends.
+func ask(question, def string) string {
+	if def != "" {
+		fmt.Printf("%v [%v]: ", question, def)
+	} else {
+		fmt.Printf("%v: ", question)
+	}
+	var input string
+	if def == "" {
+		input = utils.ReadRequiredAnswer()
+	} else if answer, ok := utils.TryReadAnswer(); ok {
+		input = answer
+	} else {
+		utils.FatalPrintln("\nAborted, nothing was saved.")
+	}
+	if input != "" {
+		return input
+	}
+	return def
+}
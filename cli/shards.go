@@ -0,0 +1,138 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+
+	"github.com/arken/ait/display"
+	"github.com/arken/ait/ipfs"
+	"github.com/arken/ait/types"
+	"github.com/arken/ait/utils"
+
+	"github.com/DataDrake/cli-ng/v2/cmd"
+)
+
+// MergeStaging stages the files hashed by the parts of a partitioned staging.
+var MergeStaging = cmd.Sub{
+	Name:  "merge-staging",
+	Short: "Stage the files every part of \"ait stage --partition\" hashed.",
+	Flags: &MergeStagingFlags{},
+	Run:   MergeStagingRun,
+}
+
+// MergeStagingFlags handles the specific flags for the merge-staging command.
+type MergeStagingFlags struct {
+	Partial bool `long:"partial" desc:"Merge the parts that are done even if others are missing"`
+}
+
+// stagePartition walks paths like "ait stage", keeps the files of the given
+// part and hashes them into a staging shard. The staged files aren't touched,
+// so the parts can run at once, ie as the jobs of a cluster job array.
+func stagePartition(paths []string, exts *types.BasicStringSet, partition string) {
+	part, of, err := utils.ParsePartition(partition)
+	utils.CheckError(err)
+	walked := types.NewThreadSafeStringSet()
+	for _, userPath := range paths {
+		path, err := utils.WorkspacePath(userPath)
+		if err != nil {
+			fmt.Printf("Will not stage files that are not in this AIT repo, skipping %v\n", userPath)
+			continue
+		}
+		addPath(path, walked)
+	}
+	if exts.Size() > 0 {
+		addExtension(walked, exts)
+	}
+	var files []string
+	var total int64
+	_ = walked.ForEach(func(path string) error {
+		if utils.InPartition(path, part, of) {
+			files = append(files, path)
+			size, _ := utils.GetFileSize(path)
+			total += size
+		}
+		return nil
+	})
+	sort.Strings(files)
+	fmt.Printf("Part %d of %d holds %d of the %d file(s) found.\n", part, of, len(files), walked.Size())
+
+	prettyIPFSInit()
+	link, err := ipfs.LinkWorkdir()
+	utils.CheckError(err)
+	shard := utils.StagingShard{Part: part, Of: of}
+	var failed []string
+	bar := display.NewFileProgress("Hashing", int64(len(files)), total)
+	jobs := make(chan string)
+	lock := sync.Mutex{}
+	wg := sync.WaitGroup{}
+	for w := 0; w < genNumWorkers(); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for path := range jobs {
+				size, err := utils.GetFileSize(path)
+				var cid string
+				if err == nil {
+					cid, err = ipfs.HashCached(filepath.Join(link, path))
+				}
+				lock.Lock()
+				if err != nil {
+					failed = append(failed, path+": "+err.Error())
+				} else {
+					shard.Entries = append(shard.Entries, utils.HandoffEntry{Path: path, CID: cid, Size: size})
+				}
+				lock.Unlock()
+				bar.AddFile(size)
+			}
+		}()
+	}
+	for _, path := range files {
+		jobs <- path
+	}
+	close(jobs)
+	wg.Wait()
+
+	if len(failed) > 0 {
+		fmt.Println("Unable to hash, these files are left out of the shard:")
+		for _, line := range failed {
+			fmt.Println("\t" + line)
+		}
+	}
+	shardFile, err := utils.WriteStagingShard(shard)
+	utils.CheckError(err)
+	fmt.Printf("Hashed %d file(s) into %v, run \"ait merge-staging\" once every part is done.\n",
+		len(shard.Entries), shardFile)
+}
+
+// MergeStagingRun stages the files of every staging shard along with their
+// CIDs, so they aren't hashed again, and removes the shards.
+func MergeStagingRun(_ *cmd.Root, c *cmd.Sub) {
+	flags := c.Flags.(*MergeStagingFlags)
+	shards, err := utils.ReadStagingShards()
+	utils.CheckError(err)
+	if len(shards) == 0 {
+		utils.FatalPrintln("There are no staging shards to merge, stage files with \"ait stage --partition i/N\" first.")
+	}
+	entries, missing, err := utils.MergeStagingShards(shards)
+	utils.CheckError(err)
+	if len(missing) > 0 && !flags.Partial {
+		utils.FatalPrintf("Part(s) %v of %d are missing, wait for them or use --partial.\n",
+			missing, shards[0].Of)
+	}
+
+	utils.CheckError(utils.WriteHandoff(entries))
+	added := 0
+	utils.CheckError(utils.UpdateStaged(func(contents *types.ThreadSafeStringSet) error {
+		before := contents.Size()
+		for _, entry := range entries {
+			contents.Add(entry.Path)
+		}
+		added = contents.Size() - before
+		return nil
+	}))
+	utils.CheckError(os.RemoveAll(utils.StagingShardsPath))
+	fmt.Printf("Merged %d part(s), %d file(s) added.\n", len(shards), added)
+}
@@ -0,0 +1,44 @@
+package cli
+
+import (
+	"path/filepath"
+
+	"github.com/arken/ait/config"
+	"github.com/arken/ait/utils"
+)
+
+const (
+	// keysetLineSize is the size of a keyset line without the file's name: a
+	// CIDv1, the delimiter and a newline.
+	keysetLineSize = 64
+	// filestoreBlockSize is the size of the blocks files are split in when
+	// they are added, and filestoreEntrySize about what the IPFS repository
+	// stores for each of them, since the data itself stays in the workspace.
+	filestoreBlockSize = 256 << 10
+	filestoreEntrySize = 256
+)
+
+// checkSubmitSpace estimates the space the generated keyset and announcing the
+// staged files take, and exits with the shortfall before anything is submitted
+// if either disk would end up with less than the configured free space.
+func checkSubmitSpace() {
+	staged, err := utils.ReadStaged()
+	if err != nil {
+		return
+	}
+
+	var keyset, repo int64
+	staged.ForEach(func(path string) error {
+		size, _ := utils.GetFileSize(path)
+		keyset += keysetLineSize + int64(len(filepath.Base(path)))
+		repo += (size/filestoreBlockSize + 1) * filestoreEntrySize
+		return nil
+	})
+	if err = utils.CheckFreeSpace(utils.KeysetsDir(), keyset, "the generated keyset"); err != nil {
+		utils.FatalPrintln(err.Error() + "\nSet TempDir in the [General] section of " + config.Path +
+			" to generate keysets on another disk.")
+	}
+	if err = utils.CheckFreeSpace(config.Global.IPFS.Path, repo, "the IPFS repository"); err != nil {
+		utils.FatalPrintln(err.Error())
+	}
+}
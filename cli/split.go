@@ -0,0 +1,248 @@
+package cli
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path"
+	"strings"
+
+	"github.com/arken/ait/apis/forge"
+	aitgh "github.com/arken/ait/apis/github"
+	"github.com/arken/ait/config"
+	"github.com/arken/ait/keysets"
+	"github.com/arken/ait/types"
+	"github.com/arken/ait/utils"
+)
+
+// splitStaged partitions the staged files according to the --split-files and
+// --split-size flags. It returns nil when the submission isn't split.
+func splitStaged(flags *SubmitFlags) ([][]string, error) {
+	if flags.SplitFiles <= 0 && flags.SplitSize == "" {
+		return nil, nil
+	}
+	maxSize, err := utils.ParseByteSize(flags.SplitSize)
+	if err != nil {
+		return nil, err
+	}
+	var paths []string
+	sizes := make(map[string]int64)
+	for _, file := range readStagedFiles() {
+		paths = append(paths, file.Path)
+		sizes[file.Path] = file.Size
+	}
+	parts := utils.SplitFiles(paths, func(p string) int64 { return sizes[p] },
+		flags.SplitFiles, maxSize)
+	if len(parts) < 2 {
+		return nil, nil
+	}
+	return parts, nil
+}
+
+// partPath returns where part n of the keyset at keysetPath is committed, ie
+// "library/data-part2.ks".
+func partPath(keysetPath string, n int) string {
+	ext := path.Ext(keysetPath)
+	return fmt.Sprintf("%v-part%d%v", strings.TrimSuffix(keysetPath, ext), n, ext)
+}
+
+// partBranch returns the branch of the fork part n is proposed from.
+func partBranch(keysetPath string, n int) string {
+	name := strings.TrimSuffix(path.Base(keysetPath), path.Ext(keysetPath))
+	return fmt.Sprintf("ait/%v-part%d", name, n)
+}
+
+// partBody is the description of the pull request for part n of the
+// submission, linking the pull requests of the other parts opened so far.
+func partBody(app *types.ApplicationContents, n int, prs []string, total int) string {
+	var body strings.Builder
+	if app.PRBody != "" {
+		body.WriteString(app.PRBody)
+	} else {
+		body.WriteString(app.Commit)
+	}
+	fmt.Fprintf(&body, "\n\nThis submission is split into %d pull requests, this is part %d.\n\n", total, n)
+	for i := 1; i <= total; i++ {
+		switch {
+		case i == n:
+			fmt.Fprintf(&body, "- Part %d: this pull request\n", i)
+		case i <= len(prs):
+			fmt.Fprintf(&body, "- Part %d: %v\n", i, prs[i-1])
+		default:
+			fmt.Fprintf(&body, "- Part %d: to follow\n", i)
+		}
+	}
+	return body.String()
+}
+
+// partRemote is the repository the parts of a split submission are proposed
+// to, each from its own branch of the fork.
+type partRemote interface {
+	// exists returns whether the file at repoPath is in the repository.
+	exists(repoPath string) (bool, error)
+	// commit commits files, from their path in the repository to the local
+	// one, to branch and returns the SHA of the commit. The changelog line is
+	// added when changelog is set.
+	commit(files map[string]string, repoPath, message string, changelog *aitgh.ChangelogPolicy, line,
+		branch string) (string, error)
+	// openPR opens the pull request from branch and returns its URL and
+	// number.
+	openPR(branch, title, body, repoPath string) (string, int, error)
+	// editPR replaces the description of the pull request number.
+	editPR(number int, body string) error
+}
+
+// githubParts proposes the parts to the GitHub repository aitgh was set up
+// with.
+type githubParts struct{}
+
+func (githubParts) exists(repoPath string) (bool, error) {
+	return aitgh.KeysetExists(repoPath, false)
+}
+
+func (githubParts) commit(files map[string]string, repoPath, message string, changelog *aitgh.ChangelogPolicy,
+	line, branch string) (string, error) {
+	if err := aitgh.CreateBranch(branch); err != nil {
+		return "", err
+	}
+	if changelog != nil || aitgh.SigningEnabled() {
+		return aitgh.CommitFiles(files, message, changelog, line, true, branch)
+	}
+	return aitgh.CreateBranchFile(files[repoPath], repoPath, message, branch)
+}
+
+func (githubParts) openPR(branch, title, body, repoPath string) (string, int, error) {
+	return aitgh.CreateBranchPullRequest(branch, title, body, repoPath)
+}
+
+func (githubParts) editPR(number int, body string) error {
+	return aitgh.EditPullRequest(number, body)
+}
+
+// forgeParts proposes the parts to a repository on GitLab or Gitea, which
+// commit a file at a time to a branch named after it.
+type forgeParts struct {
+	provider forge.Provider
+}
+
+func (f forgeParts) exists(repoPath string) (bool, error) {
+	return f.provider.Exists(repoPath)
+}
+
+func (f forgeParts) commit(files map[string]string, repoPath, message string, _ *aitgh.ChangelogPolicy, _,
+	_ string) (string, error) {
+	commit, err := f.provider.CommitFile(files[repoPath], repoPath, message, true)
+	if err != nil {
+		return "", err
+	}
+	for path, localPath := range files {
+		if path != repoPath {
+			if _, err = f.provider.CommitFile(localPath, path, message, true); err != nil {
+				return "", err
+			}
+		}
+	}
+	return commit, nil
+}
+
+func (f forgeParts) openPR(_, title, body, _ string) (string, int, error) {
+	return f.provider.OpenPR(title, body)
+}
+
+func (f forgeParts) editPR(number int, body string) error {
+	return f.provider.EditPR(number, body)
+}
+
+// submitParts submits each part of the staged files as its own keyset through
+// a pull request to remote from its own branch of the fork, one after another.
+// Once all are open, every description is updated to link the others. The
+// keyset of each part is generated from its files, the staged files are left
+// as they are. Parts submitted before one fails stay open.
+func submitParts(url string, remote partRemote, app *types.ApplicationContents, parts [][]string,
+	flags *SubmitFlags) error {
+	for i := range parts {
+		if exists, err := remote.exists(partPath(app.FullPath(), i+1)); err != nil {
+			return err
+		} else if exists {
+			return fmt.Errorf("%v already exists in the repo, choose another keyset name",
+				partPath(app.FullPath(), i+1))
+		}
+	}
+	// The changelog describes the whole dataset, with the first part.
+	var changelog *aitgh.ChangelogPolicy
+	var line string
+	if _, ok := remote.(githubParts); ok {
+		var err error
+		if changelog, line, err = changelogLine(app, partPath(app.FullPath(), 1)); err != nil {
+			return withCleanup(err)
+		}
+	}
+	fmt.Printf("Splitting the submission into %d pull requests.\n", len(parts))
+	ksPath := utils.GeneratedKeysetPath()
+	var prs []string
+	var numbers []int
+	for i, part := range parts {
+		n := i + 1
+		fmt.Printf("\nSubmitting part %d of %d (%d files):\n", n, len(parts), len(part))
+		_, err := checkGenerated(keysets.GenerateFiles(ksPath, part), ksPath, flags.Strict, utils.SubmissionCleanup)
+		if err != nil {
+			return err
+		}
+		committed, err := exportSubmitted(ksPath, app)
+		if err != nil {
+			return withCleanup(err)
+		}
+		repoPath, branch := partPath(app.FullPath(), n), partBranch(app.FullPath(), n)
+		message := fmt.Sprintf("%v (part %d of %d)", app.Commit, n, len(parts))
+		var partChangelog *aitgh.ChangelogPolicy
+		if n == 1 {
+			partChangelog = changelog
+		}
+		files, err := keysetFiles(committed, repoPath)
+		if err != nil {
+			return withCleanup(err)
+		}
+		commit, err := remote.commit(files, repoPath, message, partChangelog, line, branch)
+		if err != nil {
+			return withCleanup(err)
+		}
+		announceStaged(ksPath)
+		entries, err := utils.ReadKeysetEntries(ksPath)
+		if err != nil {
+			return withCleanup(err)
+		}
+		keyset, err := ioutil.ReadFile(ksPath)
+		if err != nil {
+			return withCleanup(err)
+		}
+		submission := utils.NewSubmission(url, repoPath, entries)
+		submission.Commit = commit
+		submission.Catalog = catalogIDs()
+		submission.Metadata = entryMetadata(entries)
+		utils.SubmissionCleanup()
+		addGatewayLinks(submission, addDatasetRoot(submission, app, flags.ROCrate))
+		submission.PullRequest, submission.PRNumber, err = remote.openPR(branch,
+			fmt.Sprintf("%v (part %d of %d)", app.Title, n, len(parts)),
+			partBody(app, n, prs, len(parts)), repoPath)
+		if err != nil {
+			return fmt.Errorf("unable to create the pull request: %v", err)
+		}
+		prs = append(prs, submission.PullRequest)
+		numbers = append(numbers, submission.PRNumber)
+		if config.Global.General.TransparencyLog != "" {
+			publishReceipt(submission, keyset)
+		}
+		if err = submission.Save(); err != nil {
+			fmt.Println("Unable to record the submission in the history:", err)
+		}
+		registerSubmission(submission)
+		postSubmitHook(submission)
+		printSubmission(submission)
+	}
+	for i, number := range numbers {
+		if err := remote.editPR(number, partBody(app, i+1, prs, len(parts))); err != nil {
+			fmt.Printf("Unable to link the other parts from %v: %v\n", prs[i], err)
+		}
+	}
+	fmt.Println("Submission successful!")
+	return nil
+}
@@ -2,13 +2,12 @@ package cli
 
 import (
 	"fmt"
-	"io/ioutil"
+	"io"
 	"os"
-	"path/filepath"
 	"runtime"
 	"strings"
-	"sync"
 
+	"github.com/arken/ait/display"
 	"github.com/arken/ait/types"
 	"github.com/arken/ait/utils"
 
@@ -32,9 +31,20 @@ type StageArgs struct {
 
 // StageFlags handles the specific flags for the add command.
 type StageFlags struct {
-	Extensions string `short:"e" long:"extension" desc:"Stage all files with the given file extension. For multiple extensions, separate each with a comma"`
+	Extensions  string `short:"e" long:"extension" desc:"Stage all files with the given file extension. For multiple extensions, separate each with a comma"`
+	Partition   string `long:"partition" desc:"Only hash the i-th of N parts of the files, ie 3/8, into a staging shard for ait merge-staging"`
+	NoIgnore    bool   `long:"no-ignore" desc:"Stage the files .aitignore files exclude too"`
+	Tag         string `long:"tag" desc:"Tag the given files, recorded in their keyset entries. For multiple tags, separate each with a comma"`
+	License     string `long:"license" desc:"License of the given files, ie CC-BY-4.0, recorded in their keyset entries"`
+	Description string `long:"description" desc:"Description of the given files, recorded in their keyset entries"`
+	CidVersion  string `long:"cid-version" desc:"CID version the given files are added to IPFS with, 0 or 1 (the default)"`
+	Chunker     string `long:"chunker" desc:"Chunker the given files are split with, ie size-1048576, rabin-<min>-<avg>-<max> or buzhash"`
+	RawLeaves   string `long:"raw-leaves" desc:"Whether the given files are added with raw leaves, true (the default) or false"`
 }
 
+// noIgnore makes directory walks stage the files .aitignore files exclude.
+var noIgnore bool
+
 // StageRun Similar to "git add", this function adds files that match a given list of
 // file matching patterns (can include *, ? wildcards) to a file. Currently this
 // file is in .ait/added_files, and it contains paths relative to the program's
@@ -44,46 +54,133 @@ type StageFlags struct {
 func StageRun(_ *cmd.Root, c *cmd.Sub) {
 	runtime.GOMAXPROCS(512) //TODO: assign this number meaningfully
 	args, exts := parseAddArgs(c)
+	flags := c.Flags.(*StageFlags)
+	noIgnore = flags.NoIgnore
+	params, err := utils.ParseAddParams(flags.CidVersion, flags.Chunker, flags.RawLeaves)
+	if err != nil {
+		utils.FatalPrintln(err)
+	}
+	defer stageMetadata(args, flags)
+	if partition := flags.Partition; partition != "" {
+		// The files of the shard are hashed with their parameters.
+		if !params.IsZero() {
+			stageAddParams(args, false, nil, params)
+		}
+		stagePartition(args, exts, partition)
+		return
+	}
+	// The paths are walked without holding the lock on the staged files, and
+	// only merged into them once done.
 	contents := types.NewThreadSafeStringSet()
-	file := utils.BasicFileOpen(utils.AddedFilesPath, os.O_CREATE|os.O_RDONLY, 0644)
-	utils.FillSet(contents, file)
-	origLen := contents.Size()
-	file.Close()
+	walked := utils.TimePhase("walking")
 	for _, userPath := range args {
-		userPath = filepath.Clean(userPath)
-		withinRepo, err := utils.IsWithinRepo(userPath)
-		utils.CheckError(err)
-		if withinRepo {
-			addPath(userPath, contents)
-		} else {
+		path, err := utils.WorkspacePath(userPath)
+		if err != nil {
 			fmt.Printf("Will not stage files that are not in this AIT repo,"+
-				" skipping %v", userPath)
+				" skipping %v\n", userPath)
+			continue
 		}
+		addPath(path, contents)
 	}
 	if exts.Size() > 0 {
 		addExtension(contents, exts)
 	}
-	//completely truncate the file to avoid duplicated filenames
-	file = utils.BasicFileOpen(utils.AddedFilesPath, os.O_TRUNC|os.O_WRONLY, 0644)
-	defer file.Close()
-	//dump the set, which has to have unique values, into the file.
-	err := utils.DumpSet(contents, file)
+	walked()
+	added := mergeStaged(contents)
+	if !params.IsZero() {
+		stageAddParams(args, exts.Size() > 0, contents, params)
+	}
+	display.Out.Result(map[string]int{"added": added}, func(w io.Writer) {
+		fmt.Fprintln(w, added, "file(s) added")
+	})
+}
+
+// stageMetadata attaches the metadata given with flags to the staged paths.
+// Directories get it as a whole, so files staged in them later have it too.
+func stageMetadata(args []string, flags *StageFlags) {
+	fields := make(map[string]string)
+	for key, value := range map[string]string{utils.TagsKey: flags.Tag, "license": flags.License,
+		"description": flags.Description} {
+		if value != "" {
+			_, fields[key], _ = utils.ParseMetadataAssignment(key + "=" + value)
+		}
+	}
+	if len(fields) == 0 {
+		return
+	}
+	var paths []string
+	for _, arg := range args {
+		if path, err := utils.WorkspacePath(arg); err == nil && utils.FileExists(path) {
+			paths = append(paths, path)
+		}
+	}
+	if len(paths) == 0 {
+		fmt.Println("Metadata is attached to the paths given, not to files staged by extension.")
+		return
+	}
+	setFileMetadata(paths, fields)
+}
+
+// stageAddParams records the parameters the staged files are added to IPFS
+// with. Like metadata, directories get them as a whole, while files staged by
+// extension get them one by one.
+func stageAddParams(args []string, byExtension bool, contents *types.ThreadSafeStringSet, params utils.AddParams) {
+	var paths []string
+	for _, arg := range args {
+		if path, err := utils.WorkspacePath(arg); err == nil && utils.FileExists(path) {
+			paths = append(paths, path)
+		}
+	}
+	if byExtension {
+		_ = contents.ForEach(func(path string) error {
+			paths = append(paths, path)
+			return nil
+		})
+	}
+	recorded, err := utils.ReadAddParams(utils.AddParamsPath)
 	utils.CheckError(err)
-	fmt.Println(contents.Size()-origLen, "file(s) added")
+	for _, path := range paths {
+		recorded[path] = params
+	}
+	utils.CheckError(utils.WriteAddParams(recorded))
+	fmt.Printf("%v path(s) are added to IPFS with %v.\n", len(paths), params)
+}
+
+// stagePath stages the given path of the workspace along with the files that
+// are already staged, returning how many files were added.
+func stagePath(path string) int {
+	contents := types.NewThreadSafeStringSet()
+	addPath(path, contents)
+	return mergeStaged(contents)
+}
+
+// mergeStaged adds the files of contents to the staged files, returning how
+// many weren't staged yet.
+func mergeStaged(contents *types.ThreadSafeStringSet) int {
+	utils.CheckError(preAddHook(contents))
+	added := 0
+	utils.CheckError(utils.UpdateStaged(func(staged *types.ThreadSafeStringSet) error {
+		origLen := staged.Size()
+		_ = contents.ForEach(func(path string) error {
+			staged.Add(path)
+			return nil
+		})
+		added = staged.Size() - origLen
+		return nil
+	}))
+	return added
 }
 
 // addPath attempts to add the given path to the current collection of added
 // files. No attempt will be made if the file doesn't exist or it is already
-// in the collection.
+// in the collection. The files of directories .aitignore files exclude are
+// skipped, unless noIgnore is set, but paths given themselves are added.
 func addPath(userPath string, contents *types.ThreadSafeStringSet) {
 	info, statErr := os.Stat(userPath)
 	if !os.IsNotExist(statErr) && info != nil && !contents.Contains(userPath) {
 		// if file exists and isn't already in the set
 		if info.IsDir() {
-			wg := sync.WaitGroup{}
-			wg.Add(1)
-			go processDir(userPath, contents, &wg)
-			wg.Wait()
+			printWalkErrors(utils.WalkFiles(userPath, nil, walkIgnore(), contents))
 		} else {
 			contents.Add(userPath)
 		}
@@ -92,61 +189,24 @@ func addPath(userPath string, contents *types.ThreadSafeStringSet) {
 	}
 }
 
-// processDir walks through the directory at dir and sends the path of all
-// regular files back to the main thread via c. If another directory is found,
-// another goproc is called to processDir that directory.
-func processDir(dir string, contents *types.ThreadSafeStringSet, wg *sync.WaitGroup) {
-	defer wg.Done()
-	if dir == ".ait" {
-		return
-	}
-	files, err := ioutil.ReadDir(dir)
-	if err != nil {
-		fmt.Println("A thread encountered an error:", err)
-		return
-	}
-	for _, info := range files {
-		path := filepath.Join(dir, info.Name())
-		if info.IsDir() {
-			wg.Add(1)
-			go processDir(path, contents, wg)
-		} else {
-			contents.Add(path)
-		}
+// walkIgnore returns what directory walks skip, nil when noIgnore is set.
+func walkIgnore() *utils.Ignore {
+	if noIgnore {
+		return nil
 	}
+	return utils.NewIgnore()
 }
 
 // addExtension attempts to add ALL files within the current wd that have the
 // extension(s) contained in exts.
 func addExtension(contents *types.ThreadSafeStringSet, exts *types.BasicStringSet) {
-	wg := sync.WaitGroup{}
-	wg.Add(1)
-	go processDirExt(".", exts, contents, &wg)
-	wg.Wait()
+	printWalkErrors(utils.WalkFiles(".", exts, walkIgnore(), contents))
 }
 
-// processDirExt walks through the directory at dir and sends the path of all
-// regular files that have the desired file extensions back to the main thread
-// via c. If another directory is found, another goproc is called to
-// processDirExt that directory.
-func processDirExt(dir string, exts *types.BasicStringSet, contents *types.ThreadSafeStringSet, wg *sync.WaitGroup) {
-	defer wg.Done()
-	if dir == ".ait" {
-		return
-	}
-	files, err := ioutil.ReadDir(dir)
-	if err != nil {
+// printWalkErrors reports the directories a walk couldn't read.
+func printWalkErrors(errs []error) {
+	for _, err := range errs {
 		fmt.Println("A thread encountered an error:", err)
-		return
-	}
-	for _, info := range files {
-		path := filepath.Join(dir, info.Name())
-		if info.IsDir() {
-			wg.Add(1)
-			go processDirExt(path, exts, contents, wg)
-		} else if exts.Contains(filepath.Ext(info.Name())) {
-			contents.Add(path)
-		}
 	}
 }
 
@@ -174,7 +234,7 @@ func parseAddArgs(c *cmd.Sub) ([]string, *types.BasicStringSet) {
 	}
 	if exts.Size() == 0 && len(args) == 0 {
 		fmt.Println("No files were given to stage, please provide arguments")
-		os.Exit(0)
+		utils.Exit(0)
 	}
 	return args, exts
 }
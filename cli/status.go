@@ -2,9 +2,12 @@ package cli
 
 import (
 	"fmt"
+	"io"
 	"os"
+	"path/filepath"
 
-	"github.com/arken/ait/types"
+	"github.com/arken/ait/display"
+	"github.com/arken/ait/ipfs"
 	"github.com/arken/ait/utils"
 
 	"github.com/DataDrake/cli-ng/v2/cmd"
@@ -16,6 +19,7 @@ var Status = cmd.Sub{
 	Alias: "s",
 	Short: "View what files are currently staged for submission.",
 	Args:  &StatusArgs{},
+	Flags: &StatusFlags{},
 	Run:   StatusRun,
 }
 
@@ -23,21 +27,130 @@ var Status = cmd.Sub{
 type StatusArgs struct {
 }
 
+// StatusFlags handles the specific flags for the status command.
+type StatusFlags struct {
+	Table    bool   `short:"t" long:"table" desc:"Show the staged files as a table of their name, size, CID and when they were staged"`
+	Sort     string `long:"sort" desc:"Sort the table by name, size, cid or added, prefixed with - for descending order"`
+	Filter   string `long:"filter" desc:"Only show the files matching comma separated expressions, ie \"size>1MB,name=*.pdf\""`
+	Page     int    `long:"page" desc:"Show this page of the table"`
+	PageSize int    `long:"page-size" desc:"Number of files on each page of the table, 100 by default"`
+	All      bool   `long:"all" desc:"Show every file in the table instead of a page"`
+	Hash     bool   `long:"hash" desc:"Hash the files without a known CID to fill in the CID column of the table"`
+	Online   bool   `long:"online" desc:"Count the peers of the Arken network providing each file, hashing the files without a known CID"`
+}
+
+// defaultPageSize is how many files a page of the table holds by default.
+const defaultPageSize = 100
+
 // StatusRun executes the status function.
-func StatusRun(*cmd.Root, *cmd.Sub) {
-	file, err := os.OpenFile(utils.AddedFilesPath, os.O_RDONLY, 0644)
-	if err == nil {
-		defer file.Close()
-	}
-	lines := types.NewSortedStringSet()
-	utils.FillSet(lines, file)
-	if lines.Size() > 0 {
-		fmt.Println(lines.Size(), "file(s) currently staged for submission:")
-		_ = lines.ForEach(func(line string) error {
-			fmt.Println("\t", line)
-			return nil
-		})
-	} else {
+func StatusRun(_ *cmd.Root, c *cmd.Sub) {
+	flags := c.Flags.(*StatusFlags)
+	if flags.Table || flags.Sort != "" || flags.Filter != "" || flags.Page != 0 || flags.Hash || flags.Online {
+		statusTable(flags)
+		return
+	}
+	lines, err := utils.ReadStagedSorted()
+	utils.CheckError(err)
+	staged := make([]string, 0, lines.Size())
+	_ = lines.ForEach(func(line string) error {
+		staged = append(staged, line)
+		return nil
+	})
+	display.Out.Result(map[string][]string{"staged": staged}, func(w io.Writer) {
+		if len(staged) == 0 {
+			fmt.Fprintln(w, "No files are currently staged for submission.")
+			return
+		}
+		fmt.Fprintln(w, len(staged), "file(s) currently staged for submission:")
+		for _, line := range staged {
+			fmt.Fprintln(w, "\t", line)
+		}
+	})
+}
+
+// statusTable prints the staged files as a table, sorted, filtered and paged
+// as the flags ask.
+func statusTable(flags *StatusFlags) {
+	staged := readStagedFiles()
+	if len(staged) == 0 {
 		fmt.Println("No files are currently staged for submission.")
+		return
+	}
+	times, err := utils.ReadStagedTimes()
+	utils.CheckError(err)
+	handoff, err := utils.ReadHandoff()
+	utils.CheckError(err)
+	// Counting the providers of a file needs its CID.
+	hash := flags.Hash || flags.Online
+	if hash {
+		prettyIPFSInit()
+	}
+	link, err := ipfs.LinkWorkdir()
+	utils.CheckError(err)
+
+	table := &display.Table{Columns: []display.Column{
+		{Name: "Name", Kind: display.Text},
+		{Name: "Size", Kind: display.Size},
+		{Name: "CID", Kind: display.Text},
+		{Name: "Added", Kind: display.Time},
+	}}
+	if flags.Online {
+		table.Columns = append(table.Columns, display.Column{Name: "Peers", Kind: display.Count})
+	}
+	for _, file := range staged {
+		path := filepath.Join(link, file.Path)
+		cid, ok := utils.HandoffCID(handoff, file.Path)
+		if !ok {
+			cid, ok = ipfs.CachedCID(path)
+		}
+		if !ok && hash {
+			cid, err = ipfs.HashCached(path)
+			utils.CheckError(err)
+		} else if !ok {
+			cid = "-"
+		}
+		row := display.Row{file.Path, file.Size, cid, times[file.Path]}
+		if flags.Online {
+			row = append(row, providerCount(cid))
+		}
+		table.Rows = append(table.Rows, row)
+	}
+	if flags.Filter != "" {
+		utils.CheckError(table.Filter(flags.Filter))
+	}
+	if flags.Sort != "" {
+		utils.CheckError(table.Sort(flags.Sort))
+	}
+
+	size := flags.PageSize
+	if size <= 0 {
+		size = defaultPageSize
+	}
+	if flags.All {
+		size = 0
+	}
+	page := flags.Page
+	if page == 0 {
+		page = 1
+	}
+	if flags.Filter != "" {
+		fmt.Printf("%d of the %d staged file(s) match the filter:\n", len(table.Rows), len(staged))
+	} else {
+		fmt.Println(len(staged), "file(s) currently staged for submission:")
+	}
+	utils.CheckError(table.Render(os.Stdout, page, size))
+}
+
+// providerCount returns how many peers of the Arken network provide cid, or -1
+// if it isn't known or they can't be looked up. The IPFS subsystem must be
+// initialized.
+func providerCount(cid string) int {
+	if cid == "-" {
+		return -1
+	}
+	count, err := ipfs.FindProvs(cid, 20)
+	if err != nil {
+		return -1
 	}
+	return count
 }
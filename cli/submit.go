@@ -1,19 +1,28 @@
 package cli
 
 import (
-	"bufio"
+	"errors"
 	"fmt"
+	"io"
+	"io/ioutil"
 	"os"
-	"path/filepath"
+	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
+	"time"
 
+	"github.com/arken/ait/apis/dnslink"
+	"github.com/arken/ait/apis/doi"
+	"github.com/arken/ait/apis/email"
+	"github.com/arken/ait/apis/forge"
 	"github.com/arken/ait/ipfs"
 	//vv to differentiate between go-github and our github package
 	aitgh "github.com/arken/ait/apis/github"
 	"github.com/arken/ait/config"
 	"github.com/arken/ait/display"
 	"github.com/arken/ait/keysets"
+	"github.com/arken/ait/types"
 	"github.com/arken/ait/utils"
 
 	"github.com/DataDrake/cli-ng/v2/cmd"
@@ -36,162 +45,819 @@ type SubmitArgs struct {
 
 // SubmitFlags handles the specific flags for the submit command.
 type SubmitFlags struct {
-	IsPR bool `short:"p" long:"pull-request" desc:"Jump straight into submitting a pull request"`
+	IsPR    bool `short:"p" long:"pull-request" desc:"Jump straight into submitting a pull request"`
+	IsIssue bool `short:"i" long:"issue" desc:"Submit the keyset in an issue, for repositories that accept submissions that way"`
+	ROCrate bool `short:"r" long:"ro-crate" desc:"Package the submitted files and their metadata as an RO-Crate"`
+	// JSON is kept for the scripts written before the global --json, which
+	// takes precedence over --json but not over -j.
+	JSON bool `short:"j" long:"json" desc:"Deprecated, use the global --json instead"`
+	// SplitFiles and SplitSize split large pull request submissions into
+	// several keysets, each proposed in its own pull request.
+	SplitFiles int    `long:"split-files" desc:"Split the submission into pull requests of at most this many files"`
+	SplitSize  string `long:"split-size" desc:"Split the submission into pull requests of at most this size, ie 500GB"`
+	// Also are files committed along with the keyset in the same commit.
+	Also string `long:"also" desc:"Commit these files with the keyset, as comma separated local[=repo path] files"`
+	// SeedDuration keeps the process seeding the submitted files for a
+	// while, ie for contributors who won't run ait upload.
+	SeedDuration string `long:"seed-duration" desc:"Keep seeding the submitted files for this long, ie 72h, then print their replication"`
+	// Record and Replay save the answers given to every prompt with the
+	// flags and application, and submit again from them.
+	Record string `long:"record" desc:"Record the flags, application and prompt answers of this submission to a file"`
+	Replay string `long:"replay" desc:"Submit again as recorded in a file by --record, without asking anything"`
+	// Template and SaveTemplate submit recurring datasets with the remote,
+	// flags and application saved under a name.
+	Template     string `short:"t" long:"template" desc:"Submit with the remote, flags and application of a saved template"`
+	SaveTemplate string `long:"save-template" desc:"Save the remote, flags and application of this submission as a template"`
+	// Strict aborts the submission when a staged file can't be added, rather
+	// than submitting the others.
+	Strict bool `long:"strict" desc:"Abort the submission if a staged file couldn't be added"`
+	// Deadline stops the submission cleanly, keeping what's done, when it
+	// runs past a batch window.
+	Deadline string `long:"deadline" desc:"Stop hashing, announcing, pushing and seeding after this long, ie 2h, keeping what remains to resume"`
+	// Yes and KsMode run the submission without prompting, ie in CI
+	// pipelines, where it fails instead of asking.
+	Yes    bool   `short:"y" long:"yes" desc:"Never prompt, taking every choice from the flags, environment and config, and fail if one is missing"`
+	KsMode string `long:"ks-mode" desc:"What to do when the keyset already exists in the repository: overwrite or amend"`
+	// OnConflict resolves the staged files named like entries of the
+	// keyset being amended, with other contents.
+	OnConflict string `long:"on-conflict" desc:"When amending, keep ours, theirs or abort on staged files named like an entry with other contents"`
+	// Provider is the forge hosting the repository, for self-hosted ones
+	// that can't be told from their host.
+	Provider string `long:"provider" desc:"The forge hosting the repository: github, gitlab or gitea, detected from its host by default"`
+	// Resume picks up the last submission that didn't complete from its
+	// journal.
+	Resume bool `long:"resume" desc:"Resume the last submission that didn't complete, from the last step it completed"`
+	// RemotesFile lists more remotes to submit to, one per line.
+	RemotesFile string `long:"remotes-file" desc:"Also submit to every remote listed in the file, one per line"`
+	// Flush submits the submissions queued while GitHub couldn't be reached.
+	Flush bool `long:"flush" desc:"Submit the submissions queued while offline, like ait queue flush"`
+	// Format is the format the keyset is committed in, for repositories
+	// read by other tools.
+	Format string `long:"format" desc:"Commit the keyset as ksv, json or csv, General.KeysetFormat by default"`
+	// MessageFile, Title and Body give the application without opening the
+	// editor, like git commit -F and -m.
+	MessageFile string `short:"F" long:"message-file" desc:"Take the application from a file (- for the standard input), filled template or title line and message"`
+	Title       string `long:"title" desc:"The title of the submission, instead of writing it in the editor"`
+	Body        string `long:"body" desc:"The commit message describing the submission, instead of writing it in the editor"`
 }
 
+// nonInteractiveEnv runs every submission without prompting when set to a
+// true value, like --yes.
+const nonInteractiveEnv = "AIT_NON_INTERACTIVE"
+
+// maxIssueKeyset is the largest keyset included verbatim in an issue, GitHub
+// limits issue bodies to 65536 characters.
+const maxIssueKeyset = 60000
+
 // SubmitRun authenticates the user through our OAuth app and uses that to
 // upload a keyset file generated locally, or makes a pull request if necessary.
+// When the remote can't be reached the submission is queued instead.
 func SubmitRun(_ *cmd.Root, c *cmd.Sub) {
-	url, isPR := parseSubmitArgs(c)
-	prettyIPFSInit()
-	hasWritePerm := aitgh.Init(url, isPR)
-	if config.Global.Git.PAT == "" {
+	flags := c.Flags.(*SubmitFlags)
+	deprecatedJSON(c.Name, "-j", flags.JSON)
+	if flags.Resume {
+		resumeSubmission(flags)
+		return
+	}
+	if flags.Flush {
+		queue, err := utils.ReadQueue()
+		utils.CheckError(err)
+		flushQueue(queue)
+		return
+	}
+	urls, isPR, isIssue, saveSession := parseSubmitArgs(c)
+	useSubmitFormat(flags.Format)
+	var seed time.Duration
+	if flags.SeedDuration != "" {
+		var err error
+		seed, err = time.ParseDuration(flags.SeedDuration)
+		if err != nil || seed <= 0 {
+			utils.FatalPrintf("--seed-duration %q isn't a duration like 72h.\n", flags.SeedDuration)
+		}
+	}
+	started := time.Now()
+	if flags.Deadline != "" {
+		deadline, err := time.ParseDuration(flags.Deadline)
+		if err != nil || deadline <= 0 {
+			utils.FatalPrintf("--deadline %q isn't a duration like 2h.\n", flags.Deadline)
+		}
+		submitDeadline = started.Add(deadline)
+		keysets.Deadline = submitDeadline
+	}
+	if len(urls) > 1 {
+		prettyIPFSInit()
+		checkSubmitSpace()
+		submitBatch(urls, isPR, isIssue, flags)
+	} else {
+		kind, err := forge.Detect(urls[0], flags.Provider)
+		utils.CheckError(err)
+		prettyIPFSInit()
+		checkSubmitSpace()
+		if _, err = submitTo(urls[0], kind, isPR, isIssue, flags); err != nil {
+			exitSubmission(err)
+		}
+	}
+	saveSession()
+	if seed > 0 && !submitDeadline.IsZero() && time.Until(submitDeadline) < seed {
+		seed = time.Until(submitDeadline)
+		fmt.Println("Seeding stops at the deadline rather than after --seed-duration.")
+	}
+	if seed > 0 {
+		seedAfterSubmit(seed, started)
+	}
+}
+
+// errAborted is returned by submissions the user chose not to go on with.
+var errAborted = errors.New("submission aborted")
+
+// submitTo makes the submission to the remote at url, hosted by the forge of
+// the given kind, returning false if it was aborted. It's queued when the
+// forge can't be reached.
+func submitTo(url, kind string, isPR, isIssue bool, flags *SubmitFlags) (bool, error) {
+	if strings.HasPrefix(url, "mailto:") {
+		return submitEmail(strings.TrimPrefix(url, "mailto:"), flags)
+	} else if !remoteReachable(url, kind) {
+		return queueSubmission(url, isPR, isIssue, flags)
+	}
+	return submitOnline(url, kind, isPR, isIssue, flags)
+}
+
+// submitOnline makes the submission to the remote at url, on GitHub or the
+// forge of the given kind, returning false if it was aborted.
+func submitOnline(url, kind string, isPR, isIssue bool, flags *SubmitFlags) (bool, error) {
+	if kind != forge.GitHub {
+		return submitForge(url, kind, isPR, isIssue, flags)
+	}
+	return submit(url, isPR, isIssue, flags)
+}
+
+// exitSubmission exits with the error the submission failed with, once how
+// to resume it was told, with deadlineExitCode if it stopped at its deadline.
+func exitSubmission(err error) {
+	abandonJournal()
+	if errors.Is(err, errPastDeadline) {
+		utils.Exit(deadlineExitCode)
+	}
+	utils.FatalPrintln(err)
+}
+
+// withCleanup cleans up after the submission if err isn't nil, and returns
+// err.
+func withCleanup(err error) error {
+	if err != nil {
+		utils.SubmissionCleanup()
+	}
+	return err
+}
+
+// submit makes the submission to the GitHub repository at url, returning false
+// if it was aborted.
+func submit(url string, isPR, isIssue bool, flags *SubmitFlags) (bool, error) {
+	hasWritePerm, err := aitgh.Init(url, isPR || isIssue)
+	if err != nil {
+		return false, err
+	}
+	if config.GitHubToken() == "" && !aitgh.UsingGitCredential() && !aitgh.UsingEnvToken() &&
+		!utils.NonInteractive {
 		promptSaveToken()
 	}
 	if config.Global.Git.Name == "" || config.Global.Git.Email == "" {
-		promptNameEmail()
+		if err = promptNameEmail(); err != nil {
+			return false, err
+		}
 	}
-	if !hasWritePerm && !isPR {
+	if !hasWritePerm && !isPR && !isIssue {
 		// Offer the user the option to change to a pull request.
-		isPR = promptDoPullRequest(url)
-		if !isPR {
+		if isPR, err = promptDoPullRequest(url); err != nil {
+			return false, err
+		} else if !isPR {
 			fmt.Println("Exiting Submission and will not continue as pull request...")
 			fmt.Println("Submission aborted.")
-			return
+			return false, nil
 		}
 	}
 	if isPR {
 		fmt.Println("You chose to submit via pull request.")
-		aitgh.CreateFork()
+		if err = aitgh.CreateFork(); err != nil {
+			fmt.Println(err)
+			isPR = false
+			if isIssue = promptSubmitIssue(); !isIssue {
+				return false, errAborted
+			}
+		}
+	}
+	parts, err := splitStaged(flags)
+	if err != nil {
+		return false, err
+	}
+	if parts != nil && !isPR {
+		return false, errors.New("only pull request submissions can be split")
+	}
+	also, err := utils.ParseFileMappings(flags.Also)
+	if err != nil {
+		return false, err
+	}
+	if len(also) > 0 && isIssue {
+		return false, errors.New("the files given with --also can't be submitted in an issue")
+	}
+	// A resumed submission keeps the application it was journaled with, and
+	// a batch submission the one given once for every remote.
+	if submitJournal == nil && !batchSubmitting {
+		display.ShowApplication()
 	}
-	display.ShowApplication()
 	overwrite := true
 	app := display.ReadApplication()
 	if !app.IsValid() {
-		fmt.Println("Exiting Submission because of an empty commit message.")
+		fmt.Printf("Exiting Submission because the application has no %v.\n", app.Missing())
 		fmt.Println("Submission aborted.")
-		return
+		return false, nil
+	}
+	// Resumed, flushed and batch submissions ran the hook when they started.
+	if submitJournal == nil && queuedKeyset == "" {
+		if err = preSubmitHook(app, url); err != nil {
+			return false, withCleanup(err)
+		}
+	}
+	if parts != nil {
+		if err = submitParts(url, githubParts{}, app, parts, flags); err != nil {
+			return false, err
+		}
+		return true, nil
 	}
 
-	fileExists := aitgh.KeysetExistsInRepo(app.FullPath(), isPR)
-	for fileExists {
+	if _, ok := also[app.FullPath()]; ok {
+		return false, fmt.Errorf("%v is the keyset itself, it can't also be given with --also", app.FullPath())
+	}
+	if isPR && stepDone(utils.StepCommitted) {
+		// The branch holds the commit already, it's not started over.
+		aitgh.ResumePullRequestBranch(app.FullPath())
+	} else if isPR {
+		if err = aitgh.UsePullRequestBranch(app.FullPath()); err != nil {
+			return false, err
+		}
+	}
+	var fileExists bool
+	if submitJournal != nil {
+		overwrite, fileExists = submitJournal.Overwrite, submitJournal.Exists
+	} else if fileExists, err = aitgh.KeysetExists(app.FullPath(), isPR); err != nil {
+		return false, err
+	}
+	for fileExists && submitJournal == nil {
 		var resolved bool
-		overwrite, resolved = promptOverwriteConflict(app.FullPath())
-		if resolved {
+		if overwrite, resolved, err = promptOverwriteConflict(app.FullPath(), flags.KsMode); err != nil {
+			return false, err
+		} else if resolved {
 			break
 		}
 		app = display.ReadApplication()
-		fileExists = aitgh.KeysetExistsInRepo(app.FullPath(), false)
+		if fileExists, err = aitgh.KeysetExists(app.FullPath(), false); err != nil {
+			return false, err
+		}
 	}
-	ksPath := filepath.Join(".ait", "keysets", "generated.ks")
-	utils.CheckError(keysets.Generate(ksPath, overwrite))
-	if !fileExists {
-		aitgh.CreateFile(ksPath, app.FullPath(), app.Commit, isPR)
-	} else {
-		if overwrite {
-			aitgh.ReplaceFile(ksPath, app.FullPath(), app.Commit, isPR)
-		} else {
-			aitgh.UpdateFile(ksPath, app.FullPath(), app.Commit, isPR)
+	if !overwrite && !isDefaultFormat(app) {
+		return false, fmt.Errorf("only ksv keysets can be amended, %v can only be overwritten", app.FullPath())
+	}
+	// Issue submissions attach the keyset instead of committing it.
+	var commit string
+	var changelog *aitgh.ChangelogPolicy
+	var line string
+	if !isIssue {
+		if changelog, line, err = changelogLine(app, app.FullPath()); err != nil {
+			return false, withCleanup(err)
+		}
+	}
+	ksPath, committed, err := prepareKeyset(url, isPR, isIssue, flags, app, overwrite, fileExists)
+	if err != nil {
+		return false, err
+	}
+	spooled := spoolGitStep(url, isPR, isIssue, flags, app, ksPath)
+	if stepDone(utils.StepCommitted) {
+		commit = submitJournal.CommitSHA
+	} else if changelog != nil || len(also) > 0 || aitgh.SigningEnabled() {
+		// Everything is committed at once, so that the keyset and the files
+		// that go with it are never out of step. Only such commits can be
+		// signed.
+		var files map[string]string
+		if files, err = keysetFiles(committed, app.FullPath()); err == nil {
+			for repoPath, localPath := range also {
+				files[repoPath] = localPath
+			}
+			commit, err = aitgh.CommitFiles(files, app.Commit, changelog, line, isPR, "")
+		}
+	} else if !isIssue && !fileExists {
+		commit, err = aitgh.CreateFile(committed, app.FullPath(), app.Commit, isPR)
+	} else if !isIssue && overwrite {
+		commit, err = aitgh.ReplaceFile(committed, app.FullPath(), app.Commit, isPR)
+	} else if !isIssue {
+		commit, err = aitgh.UpdateFile(committed, app.FullPath(), app.Commit, isPR)
+	}
+	if err = spooled(err); err != nil {
+		return false, withCleanup(err)
+	}
+	if submitJournal != nil && !isIssue {
+		submitJournal.CommitSHA = commit
+		recordStep(utils.StepCommitted)
+	}
+	submission, keyset, err := recordSubmission(url, ksPath, commit, app, flags)
+	if err != nil {
+		return false, err
+	}
+	if isPR && stepDone(utils.StepPullRequest) {
+		submission.PullRequest, submission.PRNumber = submitJournal.PRURL, submitJournal.PRNumber
+	} else if isPR {
+		submission.PullRequest, submission.PRNumber, err = aitgh.CreatePullRequest(
+			app.Title, app.PRBody, app.FullPath())
+		if err != nil {
+			fmt.Println("Unable to create the pull request:", err)
+			if isIssue = promptSubmitIssue(); !isIssue {
+				return false, errAborted
+			}
+		} else if submitJournal != nil {
+			submitJournal.PRURL, submitJournal.PRNumber = submission.PullRequest, submission.PRNumber
+			recordStep(utils.StepPullRequest)
 		}
 	}
+	if isIssue {
+		var body string
+		if body, err = issueBody(app, keyset); err == nil {
+			submission.Issue, _, err = aitgh.CreateIssue(app.Title, body)
+		}
+		if err != nil {
+			return false, err
+		}
+	}
+	completeSubmission(submission, keyset)
+	return true, nil
+}
+
+// prepareKeyset journals the submission and generates its keyset, exported in
+// the format it's committed in, then announces its files. Past the deadline
+// the submission is queued rather than pushed, and errPastDeadline returned.
+// It returns the path of the keyset and of the file committed, and cleans up
+// after the submission if it fails.
+func prepareKeyset(url string, isPR, isIssue bool, flags *SubmitFlags, app *types.ApplicationContents,
+	overwrite, exists bool) (ksPath, committed string, err error) {
+	startJournal(url, isPR, isIssue, flags, app, overwrite, exists)
+	ksPath = utils.GeneratedKeysetPath()
+	_, err = checkGenerated(generateKeyset(ksPath, overwrite), ksPath, flags.Strict, utils.SubmissionCleanup)
+	if err != nil {
+		return "", "", err
+	}
+	journalKeyset(ksPath)
+	noteSubmittedBefore(ksPath)
+	if committed, err = exportSubmitted(ksPath, app); err != nil {
+		return "", "", withCleanup(err)
+	}
+	// The files are announced before the git step, so they are available
+	// however it goes.
+	if !stepDone(utils.StepAnnounced) && !stagedAnnounced {
+		announceStaged(ksPath)
+		recordStep(utils.StepAnnounced)
+	}
+	if pastDeadline() && queuedKeyset == "" {
+		return "", "", queueAtDeadline(newQueued(url, isPR, isIssue, flags, app), ksPath)
+	}
+	return ksPath, committed, nil
+}
+
+// recordSubmission returns the submission of the keyset at ksPath to url,
+// committed in commit, along with the keyset. Its DNSLink is published and its
+// DOI minted, which is added to the description of its pull request.
+func recordSubmission(url, ksPath, commit string, app *types.ApplicationContents,
+	flags *SubmitFlags) (*utils.Submission, []byte, error) {
+	if dnslink.Enabled() {
+		publishDNSLink(ksPath)
+	}
+	entries, err := utils.ReadKeysetEntries(ksPath)
+	if err != nil {
+		return nil, nil, withCleanup(err)
+	}
+	keyset, err := ioutil.ReadFile(ksPath)
+	if err != nil {
+		return nil, nil, withCleanup(err)
+	}
+	submission := utils.NewSubmission(url, app.FullPath(), entries)
+	submission.Commit = commit
+	submission.Catalog = catalogIDs()
+	submission.Metadata = entryMetadata(entries)
 	utils.SubmissionCleanup()
-	if isPR {
-		aitgh.CreatePullRequest(app.Title, app.PRBody)
+	addGatewayLinks(submission, addDatasetRoot(submission, app, flags.ROCrate))
+	if submitJournal != nil && submitJournal.DOI != "" {
+		submission.DOI = submitJournal.DOI
+	} else if doi.Enabled() {
+		mintDOI(submission, app, keyset)
+		if submitJournal != nil && submission.DOI != "" {
+			submitJournal.DOI = submission.DOI
+			_ = submitJournal.Save()
+		}
 	}
+	if submission.DOI != "" {
+		if app.PRBody == "" {
+			app.PRBody = app.Commit
+		}
+		app.PRBody += "\n\nDOI: " + doi.URL(submission.DOI)
+	}
+	return submission, keyset, nil
+}
+
+// completeSubmission publishes the receipt of the submission, keeps its files
+// pinned, records it in the history and the registry, and runs the post-submit
+// hook before removing its journal.
+func completeSubmission(submission *utils.Submission, keyset []byte) {
+	if config.Global.General.TransparencyLog != "" {
+		publishReceipt(submission, keyset)
+	}
+	protectSubmission(submission)
+	if err := submission.Save(); err != nil {
+		fmt.Println("Unable to record the submission in the history:", err)
+	}
+	registerSubmission(submission)
+	postSubmitHook(submission)
+	finishJournal()
 	fmt.Println("Submission successful!")
+	printSubmission(submission)
+}
+
+// submitEmail mails the keyset as a patch to a mailing list, for communities
+// that keep their archive through a list instead of a GitHub repository. It
+// returns false if the submission was aborted.
+func submitEmail(to string, flags *SubmitFlags) (bool, error) {
+	if config.Global.Git.Name == "" || config.Global.Git.Email == "" {
+		if err := promptNameEmail(); err != nil {
+			return false, err
+		}
+	}
+	display.ShowApplication()
+	app := display.ReadApplication()
+	if !app.IsValid() {
+		fmt.Printf("Exiting Submission because the application has no %v.\n", app.Missing())
+		fmt.Println("Submission aborted.")
+		return false, nil
+	}
+	if !batchSubmitting {
+		if err := preSubmitHook(app, "mailto:"+to); err != nil {
+			return false, withCleanup(err)
+		}
+	}
+	ksPath := utils.GeneratedKeysetPath()
+	_, err := checkGenerated(keysets.Generate(ksPath, true), ksPath, flags.Strict, utils.SubmissionCleanup)
+	if err != nil {
+		return false, err
+	}
+	committed, err := exportSubmitted(ksPath, app)
+	if err != nil {
+		return false, withCleanup(err)
+	}
+	keyset, err := ioutil.ReadFile(committed)
+	if err != nil {
+		return false, withCleanup(err)
+	}
+	entries, err := utils.ReadKeysetEntries(ksPath)
+	if err != nil {
+		return false, withCleanup(err)
+	}
+	patch := &email.Patch{
+		From:    fmt.Sprintf("%v <%v>", config.Global.Git.Name, config.Global.Git.Email),
+		To:      to,
+		Subject: app.Title,
+		Message: app.Commit,
+		Path:    app.FullPath(),
+		Keyset:  keyset,
+		Date:    time.Now(),
+	}
+	password := email.Password()
+	if password == "" && config.Global.SMTP.Username != "" {
+		fmt.Printf("SMTP password of %v: ", config.Global.SMTP.Username)
+		if password, err = utils.ReadSecret(); err != nil {
+			return false, withCleanup(err)
+		}
+	}
+	fmt.Printf("Mailing the keyset to %v...\n", to)
+	if err = email.Send(patch, password); err != nil {
+		return false, withCleanup(err)
+	}
+	announceStaged(ksPath)
+	submission := utils.NewSubmission("mailto:"+to, app.FullPath(), entries)
+	submission.Catalog = catalogIDs()
+	submission.Metadata = entryMetadata(entries)
+	utils.SubmissionCleanup()
+	addGatewayLinks(submission, addDatasetRoot(submission, app, flags.ROCrate))
+	if doi.Enabled() {
+		mintDOI(submission, app, keyset)
+	}
+	if config.Global.General.TransparencyLog != "" {
+		publishReceipt(submission, keyset)
+	}
+	protectSubmission(submission)
+	if err = submission.Save(); err != nil {
+		fmt.Println("Unable to record the submission in the history:", err)
+	}
+	registerSubmission(submission)
+	postSubmitHook(submission)
+	fmt.Println("Submission successful!")
+	printSubmission(submission)
+	return true, nil
+}
+
+// printSubmission prints the gateway links of a completed submission, or its
+// whole record as a JSON result.
+func printSubmission(s *utils.Submission) {
+	display.Out.Result(s, func(io.Writer) {
+		printGatewayLinks(s)
+	})
+}
+
+// entryMetadata returns the fields the metadata command collected for the
+// submitted entries, by path.
+func entryMetadata(entries []utils.KeysetEntry) map[string]map[string]string {
+	metadata, err := utils.ReadEntryMetadata()
+	if err != nil {
+		fmt.Println("Unable to read the metadata of the staged files:", err)
+		return nil
+	}
+	return utils.SubmittedMetadata(metadata, entries)
+}
+
+// promptSubmitIssue offers to submit the keyset through an issue when the
+// upstream repository accepts them.
+func promptSubmitIssue() bool {
+	if !aitgh.IssuesEnabled() {
+		fmt.Println("The repository does not accept issues either.")
+		return false
+	}
+	if utils.NonInteractive {
+		fmt.Println("The keyset isn't submitted in an issue instead in non-interactive mode, use --issue for that.")
+		return false
+	}
+	fmt.Print("Do you want to submit the keyset in an issue instead? (y/[n]) ")
+	return strings.ToLower(utils.ReadAnswer()) == "y"
+}
+
+// issueBody describes the submission and includes the keyset, or its CID if
+// it is too large to fit in an issue.
+func issueBody(app *types.ApplicationContents, keyset []byte) (string, error) {
+	var body strings.Builder
+	if app.PRBody != "" {
+		body.WriteString(app.PRBody)
+	} else {
+		body.WriteString(app.Commit)
+	}
+	fmt.Fprintf(&body, "\n\nKeyset file `%v`:\n\n", app.FullPath())
+	if len(keyset) > maxIssueKeyset {
+		hash, err := ipfs.AddBytes(keyset)
+		if err != nil {
+			return "", err
+		}
+		fmt.Fprintf(&body, "The keyset is too large to include here, it is available on "+
+			"the Arken network as `/ipfs/%v`.\n", hash)
+	} else {
+		fmt.Fprintf(&body, "```\n%s```\n", keyset)
+	}
+	return body.String(), nil
+}
+
+// stagedAnnounced is set once the staged files were announced, they aren't
+// again for the other remotes of a batch submission.
+var stagedAnnounced bool
+
+// announceStaged announces the staged files the node holds to the DHT,
+// reporting progress and whether the Arken bootstrapper has received the
+// announcements. Their CIDs are read from the keyset generated at ksPath
+// rather than hashing the files again. The files the node doesn't hold yet
+// are announced by "ait upload", which adds them.
+func announceStaged(ksPath string) {
+	entries, err := utils.ReadKeysetEntries(ksPath)
+	if err != nil {
+		fmt.Println("Unable to announce staged files:", err)
+		return
+	}
+
+	fmt.Println("Announcing Staged Files to the Arken Network:")
+	hashes := make(chan string, len(entries))
+	for _, entry := range entries {
+		hashes <- entry.CID
+	}
+	close(hashes)
+	bar := display.NewProgress("Announcing", int64(len(entries)), false)
+
+	var announced, missing, failed, late int32
+	wg := sync.WaitGroup{}
+	for i := 0; i < genNumWorkers(); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for hash := range hashes {
+				switch {
+				case pastDeadline():
+					atomic.AddInt32(&late, 1)
+				case !ipfs.Holds(hash):
+					atomic.AddInt32(&missing, 1)
+				case ipfs.Provide(hash) != nil:
+					atomic.AddInt32(&failed, 1)
+				default:
+					atomic.AddInt32(&announced, 1)
+				}
+				bar.Add(1)
+			}
+		}()
+	}
+	wg.Wait()
+	stagedAnnounced = true
+
+	fmt.Printf("%d of %d file(s) announced to the DHT", announced, len(entries))
+	if missing > 0 {
+		fmt.Printf(", %d aren't on your node yet and will be once \"ait upload\" adds them", missing)
+	}
+	if failed > 0 {
+		fmt.Printf(", %d failed and will be retried by \"ait upload\"", failed)
+	}
+	if late > 0 {
+		fmt.Printf(", %d weren't announced before the deadline and will be by \"ait upload\"", late)
+	}
+	fmt.Println(".")
+	if announced > 0 && ipfs.BootstrapperConnected() {
+		fmt.Println("The Arken bootstrapper has acknowledged your files.")
+	} else {
+		fmt.Println("The Arken bootstrapper has not acknowledged your files yet, " +
+			"\"ait upload\" will announce them again.")
+	}
+}
+
+// publishDNSLink adds the submitted keyset to IPFS and points the configured
+// DNSLink domain at it. The submission has already succeeded at this point so
+// failures are only reported.
+func publishDNSLink(ksPath string) {
+	domain := config.Global.DNSLink.Domain
+	hash, err := ipfs.Add(ksPath, false)
+	if err != nil {
+		fmt.Printf("Unable to add the keyset to IPFS, DNSLink for %v not updated: %v\n", domain, err)
+		return
+	}
+	path := "/ipfs/" + hash
+	if key := config.Global.DNSLink.Key; key != "" {
+		name, err := ipfs.Publish(key, hash)
+		if err != nil {
+			fmt.Printf("Unable to publish the keyset under key \"%v\", DNSLink for %v not updated: %v\n",
+				key, domain, err)
+			return
+		}
+		path = "/ipns/" + name
+	}
+	if err = dnslink.Update(path); err != nil {
+		fmt.Printf("Unable to update DNSLink for %v: %v\n", domain, err)
+		return
+	}
+	fmt.Printf("DNSLink for %v now points to %v\n", domain, path)
 }
 
 // promptDoPullRequest asks the user if they want to switch over to submitting
 // a pull request instead of pushing directly to their repo.
-func promptDoPullRequest(url string) bool {
+func promptDoPullRequest(url string) (bool, error) {
+	if utils.NonInteractive {
+		return false, fmt.Errorf("you don't appear to have write permissions for %v, submit with --pull-request", url)
+	}
 	fmt.Printf(
 		`You don't appear to have write permissions for 
 %v.
 Do you want to submit a pull request to the repository instead?
 This is the only way to continue the submission. (y/[n]) `, url)
-	reader := bufio.NewReader(os.Stdin)
-	input, _ := reader.ReadString('\n')
-	input = strings.ToLower(strings.TrimSpace(input))
+	input := strings.ToLower(utils.ReadAnswer())
 
-	return input == "y"
+	return input == "y", nil
 }
 
 // promptOverwriteConflict asks the user what to do in the event that a keyset
-// the user is trying to submit a keyset that already exists
-func promptOverwriteConflict(path string) (bool, bool) {
-	fmt.Printf(
-		`A file already exists at %v in the repo. 
+// the user is trying to submit a keyset that already exists, unless ksMode
+// already tells. It returns whether the keyset is overwritten, and whether
+// the conflict was resolved rather than the keyset renamed.
+func promptOverwriteConflict(path, ksMode string) (bool, bool, error) {
+	var input string
+	switch {
+	case ksMode == "overwrite":
+		input = "o"
+	case ksMode == "amend":
+		input = "a"
+	case utils.NonInteractive:
+		return false, false, fmt.Errorf("a file already exists at %v in the repo, choose what to do with it "+
+			"with --ks-mode overwrite or amend", path)
+	default:
+		fmt.Printf(
+			`A file already exists at %v in the repo. 
 Do you want to overwrite it (o), append to it (a), rename yours (r), 
 or abort (any other key)?`, path)
-	reader := bufio.NewReader(os.Stdin)
-	input, _ := reader.ReadString('\n')
-	input = strings.ToLower(strings.TrimSpace(input))
+		input = strings.ToLower(utils.ReadAnswer())
+	}
 	if input == "o" {
-		return true, true
+		return true, true, nil
 	} else if input == "a" {
-		localPath := filepath.Join(".ait", "keysets", "generated.ks")
-		utils.CheckError(aitgh.DownloadFile(path, localPath))
-		return false, true
-	} else if input == "r" {
-		display.ShowApplication()
-	} else {
-		utils.FatalPrintln("Submission aborted.")
+		localPath := utils.GeneratedKeysetPath()
+		return false, true, aitgh.DownloadFile(path, localPath)
+	} else if input != "r" {
+		return false, false, errAborted
 	}
-	return true, false
+	display.ShowApplication()
+	return true, false, nil
 }
 
 // promptNameEmail asks the user to enter their name and email for git purposes.
 // this is saved into the file at ~/.ait/ait.config
-func promptNameEmail() {
-	reader := bufio.NewReader(os.Stdin)
-	fmt.Print("We don't appear to have an identity saved for you.\n" +
-		"Please enter your name (spaces are ok): ")
-	input, _ := reader.ReadString('\n')
-	config.Global.Git.Name = strings.TrimSpace(input)
-	fmt.Print("Please enter your email: ")
-	input, _ = reader.ReadString('\n')
-	config.Global.Git.Email = strings.TrimSpace(input)
+func promptNameEmail() error {
+	if utils.NonInteractive {
+		return errors.New("your name and email aren't configured, and can't be asked for in non-interactive mode\n" +
+			"Set AIT_GIT_NAME and AIT_GIT_EMAIL, or save them with \"ait identity set\"")
+	}
+	fmt.Println("We don't appear to have an identity saved for you, it's used for the commits " +
+		"of your submissions.\n\"ait identity\" corrects it later.")
+	git := &config.Global.Git
+	git.Name, git.Email = askIdentity(git.Name, git.Email, git.EmailDomains)
 	config.GenConf(config.Global)
+	return nil
 }
 
 // promptSaveToken asks the user if they want to save their token for the next
 // submission.
 func promptSaveToken() {
-	fmt.Print("\nWould you like to save your access token for future submissions? (y/[n]) ")
-	reader := bufio.NewReader(os.Stdin)
-	input, _ := reader.ReadString('\n')
-	input = strings.ToLower(strings.TrimSpace(input))
+	fmt.Print("\nWould you like to save your access token in the system keychain for future submissions? (y/[n]) ")
+	input := strings.ToLower(utils.ReadAnswer())
 	if input == "y" {
-		fmt.Print(`Please note that the token will be stored in plain text. It can be utilized by a 
-savvy attacker to modify your GitHub account and take actions on your behalf.
-Saving the token is not recommended if you share this computer with other people.
-Are you sure you want to save it? (y/[n]) `)
-		input, _ = reader.ReadString('\n')
-		input = strings.ToLower(strings.TrimSpace(input))
-		if input == "y" {
-			aitgh.SaveToken()
-		}
+		aitgh.SaveToken()
 	}
 }
 
 // parseSubmitArgs simply does some of the sanitization and extraction required to
 // get the desired data structures out of the cmd.Sub object, then returns said
-// useful data structures.
-func parseSubmitArgs(c *cmd.Sub) (string, bool) {
+// useful data structures, and the function saving the session being recorded.
+func parseSubmitArgs(c *cmd.Sub) ([]string, bool, bool, func()) {
 	args := c.Args.(*SubmitArgs).Args
+	flags := c.Flags.(*SubmitFlags)
+	if flags.Record != "" && flags.Replay != "" {
+		utils.FatalPrintln("--record and --replay cannot be used together.")
+	}
+	if flags.SaveTemplate != "" {
+		utils.CheckError(utils.CheckTemplateName(flags.SaveTemplate))
+	}
+	switch flags.KsMode {
+	case "", "overwrite", "amend":
+	default:
+		utils.FatalPrintf("--ks-mode must be overwrite or amend, not %q.\n", flags.KsMode)
+	}
+	useOnConflict(flags.OnConflict)
+	if err := display.GiveApplication(flags.MessageFile, flags.Title, flags.Body); err != nil {
+		utils.FatalPrintln("Unable to use the given application:", err)
+	}
+	if env, err := strconv.ParseBool(os.Getenv(nonInteractiveEnv)); flags.Yes || (err == nil && env) {
+		utils.NonInteractive = true
+	}
+	if flags.Template != "" {
+		if flags.Replay != "" {
+			utils.FatalPrintln("--template and --replay cannot be used together.")
+		}
+		args = applySubmitTemplate(args, flags)
+	}
+	saveSession := func() {}
+	if flags.Replay != "" {
+		args = replaySubmitSession(flags)
+	} else if flags.Record != "" || flags.SaveTemplate != "" {
+		saveSession = recordSubmitSession(args, flags)
+	}
+	if flags.RemotesFile != "" {
+		listed, err := readRemotesFile(flags.RemotesFile)
+		utils.CheckError(err)
+		args = append(args, listed...)
+	}
 	if len(args) < 1 {
 		utils.FatalPrintln("Not enough arguments, expected repository url")
 	}
-	url := config.GetRemote(args[0])
-	if url != args[0] {
-		fmt.Printf("Submitting to the remote at %v\n", url)
+	urls := make([]string, 0, len(args))
+	for _, arg := range args {
+		url := config.GetPushRemote(arg)
+		if url != arg {
+			fmt.Printf("Submitting to the remote at %v\n", url)
+		}
+		urls = append(urls, url)
+	}
+	mailto := false
+	for _, url := range urls {
+		mailto = mailto || strings.HasPrefix(url, "mailto:")
 	}
 	if s, _ := utils.GetFileSize(utils.AddedFilesPath); s == 0 {
 		utils.FatalPrintln(`No files are currently added, nothing to submit. Use
     ait add <files>...
 to add files for submission.`)
 	}
-	return url, c.Flags.(*SubmitFlags).IsPR
+	if flags.IsPR && flags.IsIssue {
+		utils.FatalPrintln("--pull-request and --issue cannot be used together.")
+	}
+	if flags.Also != "" {
+		if flags.IsIssue || mailto || flags.SplitFiles > 0 || flags.SplitSize != "" {
+			utils.FatalPrintln("--also needs the keyset to be committed, it can't be used with issues, " +
+				"mailing lists or split submissions.")
+		}
+		if _, err := utils.ParseFileMappings(flags.Also); err != nil {
+			utils.FatalPrintln("Unable to use --also:", err)
+		}
+	}
+	return urls, flags.IsPR, flags.IsIssue, saveSession
 }
 
 // prettyIPFSInit spins a routine to show a spinner while IPFS initializes
@@ -0,0 +1,119 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/arken/ait/display"
+	"github.com/arken/ait/ipfs"
+	"github.com/arken/ait/types"
+	"github.com/arken/ait/utils"
+
+	"github.com/DataDrake/cli-ng/v2/cmd"
+)
+
+// UnpinWorkspace tears down the workspace of a finished project.
+var UnpinWorkspace = cmd.Sub{
+	Name:  "unpin-workspace",
+	Short: "Unpin everything the workspace submitted and clear its staged files, once a project ends.",
+	Args:  &UnpinWorkspaceArgs{},
+	Flags: &UnpinWorkspaceFlags{},
+	Run:   UnpinWorkspaceRun,
+}
+
+// UnpinWorkspaceArgs handles the specific arguments for the unpin-workspace
+// command.
+type UnpinWorkspaceArgs struct {
+}
+
+// UnpinWorkspaceFlags handles the specific flags for the unpin-workspace
+// command.
+type UnpinWorkspaceFlags struct {
+	GC    bool `short:"g" long:"gc" desc:"Garbage collect the unpinned blocks right away"`
+	Force bool `short:"f" long:"force" desc:"Also unpin submissions still protected until merged and replicated"`
+	Yes   bool `short:"y" long:"yes" desc:"Don't ask for confirmation"`
+}
+
+// UnpinWorkspaceRun unpins the files and dataset roots of every submission in
+// the workspace's history and clears the staged files, along with the CIDs the
+// hash cache and handoffs recorded for them. The history itself, the global
+// config and the IPFS repository's other pins are left alone.
+func UnpinWorkspaceRun(_ *cmd.Root, c *cmd.Sub) {
+	flags := c.Flags.(*UnpinWorkspaceFlags)
+	history, err := utils.ReadHistory()
+	utils.CheckError(err)
+	var cids []string
+	seen := make(map[string]bool)
+	for _, s := range history {
+		for _, entry := range s.Entries {
+			if !seen[entry.CID] {
+				seen[entry.CID] = true
+				cids = append(cids, entry.CID)
+			}
+		}
+		if s.Root != "" && !seen[s.Root] {
+			seen[s.Root] = true
+			cids = append(cids, s.Root)
+		}
+	}
+	if !flags.Yes && !promptUnpinWorkspace(len(history), len(cids)) {
+		fmt.Println("Nothing was changed.")
+		return
+	}
+
+	ipfs.Init(false)
+	if flags.Force {
+		for _, s := range history {
+			if !s.Protected {
+				continue
+			}
+			if err = ipfs.Release(s.ID); err != nil {
+				utils.FatalPrintf("Unable to release the protection of %v: %v\n", s.Path, err)
+			}
+			s.Protected = false
+			if err = s.Save(); err != nil {
+				fmt.Printf("Unable to record that %v is no longer protected: %v\n", s.Path, err)
+			}
+		}
+	}
+	unpinned, kept := 0, 0
+	bar := display.NewProgress("Unpinning", int64(len(cids)), false)
+	for _, cid := range cids {
+		if err := ipfs.Unpin(cid); err == nil {
+			unpinned++
+		} else if owners, _ := ipfs.Protected(cid); len(owners) > 0 {
+			kept++
+		}
+		bar.Add(1)
+	}
+	fmt.Printf("Unpinned %d of %d CID(s) submitted from this workspace.\n", unpinned, len(cids))
+	if kept > 0 {
+		fmt.Printf("%d CID(s) are protected until their submission is merged and replicated, "+
+			"use --force to unpin them too.\n", kept)
+	}
+
+	utils.CheckError(utils.WriteUnstaged(types.NewBasicStringSet(), "ait unpin-workspace"))
+	for _, path := range []string{utils.HashCachePath, utils.HandoffPath} {
+		if err = os.Remove(path); err != nil && !os.IsNotExist(err) {
+			utils.FatalPrintf("Unable to remove %v: %v\n", path, err)
+		}
+	}
+	// The cache directory only goes if nothing else was left in it.
+	_ = os.Remove(filepath.Dir(utils.HashCachePath))
+	fmt.Println("Cleared the staged files and the CIDs recorded for them.")
+	if flags.GC {
+		fmt.Println("Garbage collecting...")
+		utils.CheckError(ipfs.CollectGarbage())
+		fmt.Println("Removed the unpinned blocks from the IPFS repository.")
+	}
+}
+
+// promptUnpinWorkspace asks the user to confirm the teardown.
+func promptUnpinWorkspace(submissions, cids int) bool {
+	fmt.Printf("This unpins the %d CID(s) of the %d submission(s) made from this workspace "+
+		"and clears its staged files.\nOther keepers still hold the data, but this node will "+
+		"stop providing it. Continue? (y/[n]) ", cids, submissions)
+	return strings.ToLower(utils.ReadAnswer()) == "y"
+}
@@ -0,0 +1,115 @@
+package cli
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/arken/ait/config"
+	"github.com/arken/ait/utils"
+
+	"github.com/DataDrake/cli-ng/v2/cmd"
+)
+
+// Template manages the saved submission templates.
+var Template = cmd.Sub{
+	Name:  "template",
+	Short: "List, show or remove the templates saved with \"ait submit --save-template\".",
+	Args:  &TemplateArgs{},
+	Run:   TemplateRun,
+}
+
+// TemplateArgs handles the specific arguments for the template command.
+type TemplateArgs struct {
+	Args []string `zero:"yes" desc:"list, show <name> or remove <name>"`
+}
+
+const templateUsage = `	ait template               # List the saved templates
+	ait template show <name>   # Show the remote, flags and application of a template
+	ait template remove <name> # Remove a template`
+
+// templatesDir returns the directory templates are saved in, next to the
+// config.
+func templatesDir() string {
+	return filepath.Join(filepath.Dir(config.Path), "templates")
+}
+
+// TemplateRun lists, shows or removes submission templates.
+func TemplateRun(_ *cmd.Root, c *cmd.Sub) {
+	args := c.Args.(*TemplateArgs).Args
+	if len(args) == 0 || (len(args) == 1 && args[0] == "list") {
+		listTemplates()
+		return
+	}
+	if len(args) != 2 {
+		utils.FatalPrintln("Expected an action and a template name:\n" + templateUsage)
+	}
+	t, err := utils.ReadTemplate(templatesDir(), args[1])
+	utils.CheckError(err)
+	switch args[0] {
+	case "show":
+		fmt.Printf("Template:  %v\nSaved:     %v\nRemote:    %v\nFlags:     %s\n",
+			t.Name, t.Saved.Format("Jan 2 2006 15:04"), t.Remote, t.Flags)
+		if t.Application != "" {
+			fmt.Printf("Application:\n%v\n", strings.TrimSpace(t.Application))
+		}
+	case "remove", "rm":
+		utils.CheckError(os.Remove(filepath.Join(templatesDir(), t.Name+".json")))
+		fmt.Printf("Removed the template %q.\n", t.Name)
+	default:
+		utils.FatalPrintf("Unknown action %q:\n%v\n", args[0], templateUsage)
+	}
+}
+
+// listTemplates prints the saved templates and where they submit to.
+func listTemplates() {
+	templates, err := utils.ListTemplates(templatesDir())
+	utils.CheckError(err)
+	if len(templates) == 0 {
+		fmt.Println(`No templates are saved, "ait submit --save-template <name>" saves one.`)
+		return
+	}
+	for _, t := range templates {
+		fmt.Printf("%-24v %v  (saved %v)\n", t.Name, t.Remote, t.Saved.Format("Jan 2 2006"))
+	}
+}
+
+// applySubmitTemplate fills the flags of a submission from the template named
+// by flags.Template and returns its arguments: those given, or the template's
+// remote. Its application is the one opened for editing, unless one is
+// already being written. "%date" is replaced by today's date in it.
+func applySubmitTemplate(args []string, flags *SubmitFlags) []string {
+	t, err := utils.ReadTemplate(templatesDir(), flags.Template)
+	utils.CheckError(err)
+	utils.CheckError(utils.MergeFlags(t.Flags, flags))
+	if len(args) == 0 && t.Remote != "" {
+		args = []string{t.Remote}
+	}
+	appPath := filepath.Join(".ait", "commit")
+	if s, _ := utils.GetFileSize(appPath); s == 0 && t.Application != "" {
+		utils.CheckError(ioutil.WriteFile(appPath, []byte(expandDate(t.Application)), 0644))
+	}
+	fmt.Printf("Submitting with the template %q saved on %v.\n", t.Name, t.Saved.Format("Jan 2 2006"))
+	return args
+}
+
+// saveSubmitTemplate saves the remote, flags and application of the recorded
+// submission as the template called name.
+func saveSubmitTemplate(name string, args []string, session *utils.Session) {
+	t := &utils.SubmitTemplate{
+		Name:        name,
+		Saved:       session.Time,
+		Flags:       session.Flags,
+		Application: session.Application,
+	}
+	if len(args) > 0 {
+		t.Remote = args[0]
+	}
+	if err := t.Save(templatesDir()); err != nil {
+		fmt.Println("Unable to save the template:", err)
+		return
+	}
+	fmt.Printf("Saved the template %q, \"ait submit --template %v\" submits with it.\n", name, name)
+}
@@ -0,0 +1,57 @@
+package cli
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/arken/ait/utils"
+)
+
+// restoreUnstaged stages the unstaged files within paths again, or every file
+// of the latest unstaging when no paths are given. The files were walked
+// when they were first staged, so nothing is walked or hashed again.
+func restoreUnstaged(paths []string, list bool) {
+	if !utils.IsAITRepo() {
+		utils.FatalPrintln("This is not an AIT repository, there are no unstaged files to restore.")
+	}
+	records, err := utils.ReadTrash()
+	utils.CheckError(err)
+	if len(records) == 0 {
+		utils.FatalPrintln("No files were unstaged recently, nothing was done.")
+	}
+	if list {
+		for _, r := range records {
+			fmt.Printf("%v  %d file(s)  %v\n", r.Time.Local().Format("2006-01-02 15:04"), len(r.Files), r.Reason)
+		}
+		return
+	}
+
+	var match func(string) bool
+	if len(paths) == 0 {
+		latest := records[len(records)-1]
+		fmt.Printf("Restoring the %d file(s) unstaged by %q at %v.\n", len(latest.Files),
+			latest.Reason, latest.Time.Local().Format("2006-01-02 15:04"))
+		records = records[len(records)-1:]
+		match = func(string) bool { return true }
+	} else {
+		roots := make([]string, len(paths))
+		for i, p := range paths {
+			roots[i], err = utils.WorkspacePath(p)
+			utils.CheckError(err)
+		}
+		match = func(path string) bool {
+			for _, root := range roots {
+				if utils.IsWithin(root, filepath.Clean(path)) {
+					return true
+				}
+			}
+			return false
+		}
+	}
+	restored, missing, err := utils.RestoreTrash(records, match)
+	utils.CheckError(err)
+	for _, path := range missing {
+		fmt.Printf("%v no longer exists, it wasn't staged again.\n", path)
+	}
+	fmt.Println(len(restored), "file(s) staged again")
+}
@@ -0,0 +1,222 @@
+package cli
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"sort"
+	"strings"
+
+	"github.com/arken/ait/config"
+	"github.com/arken/ait/keysets"
+	"github.com/arken/ait/utils"
+
+	"github.com/BurntSushi/toml"
+	"github.com/DataDrake/cli-ng/v2/cmd"
+)
+
+// Trust manages the maintainer keys keyset repositories are verified against
+// and the certificate pins of provider hosts.
+var Trust = cmd.Sub{
+	Name:  "trust",
+	Short: "Manage trusted maintainer keys and pinned hosts.",
+	Args:  &TrustArgs{},
+	Run:   TrustRun,
+}
+
+// TrustArgs handles the specific arguments for the trust command.
+type TrustArgs struct {
+	Action string   `desc:"The operation to perform: add, remove, list, export, import"`
+	Args   []string `zero:"yes" desc:"Arguments for the operation"`
+}
+
+const trustUsage = `	ait trust add key <name> <file>     # Trust the armored PGP public key in file
+	ait trust add host <host> [pin]     # Pin a provider host to its current (or the given) key
+	ait trust remove <name|host>        # Stop trusting a key or unpin a host
+	ait trust list                      # List trusted keys and pinned hosts
+	ait trust export [file]             # Write keys and pins to a file (default trust.toml)
+	ait trust import <file>             # Add the keys and pins from an exported file`
+
+// trustFile is the format of exported trust settings. The policy is left out
+// so that importing a team's keys doesn't change how strict this machine is.
+type trustFile struct {
+	Keys  map[string]string
+	Hosts map[string][]string
+}
+
+// TrustRun dispatches to the requested trust operation.
+func TrustRun(_ *cmd.Root, c *cmd.Sub) {
+	args := c.Args.(*TrustArgs)
+	switch args.Action {
+	case "add":
+		if len(args.Args) < 2 {
+			utils.FatalPrintln("Expected what to trust:\n" + trustUsage)
+		}
+		switch args.Args[0] {
+		case "key":
+			if len(args.Args) < 3 {
+				utils.FatalPrintln("Expected a name and a key file:\n" + trustUsage)
+			}
+			trustAddKey(args.Args[1], args.Args[2])
+		case "host":
+			trustAddHost(strings.ToLower(args.Args[1]), args.Args[2:])
+		default:
+			utils.FatalPrintln("Can only trust a \"key\" or a \"host\":\n" + trustUsage)
+		}
+	case "remove", "rm":
+		if len(args.Args) < 1 {
+			utils.FatalPrintln("Expected a key name or host:\n" + trustUsage)
+		}
+		trustRemove(args.Args[0])
+	case "list":
+		trustList()
+	case "export":
+		out := "trust.toml"
+		if len(args.Args) > 0 {
+			out = args.Args[0]
+		}
+		trustExport(out)
+	case "import":
+		if len(args.Args) < 1 {
+			utils.FatalPrintln("Expected a file to import:\n" + trustUsage)
+		}
+		trustImport(args.Args[0])
+	default:
+		utils.FatalPrintln("Unknown trust operation \"" + args.Action + "\":\n" + trustUsage)
+	}
+}
+
+// trustAddKey checks that file holds a PGP public key and trusts it under name.
+func trustAddKey(name, file string) {
+	data, err := ioutil.ReadFile(file)
+	utils.CheckError(err)
+	identity, err := keysets.KeyIdentity(string(data))
+	if err != nil {
+		utils.FatalPrintln("Could not read a public key from " + file + ": " + err.Error())
+	}
+	if config.Global.Trust.Keys == nil {
+		config.Global.Trust.Keys = make(map[string]string)
+	}
+	config.Global.Trust.Keys[name] = string(data)
+	config.GenConf(config.Global)
+	fmt.Printf("Now trusting keysets signed by \"%v\" (%v).\n", name, identity)
+}
+
+// trustAddHost pins host to the given pins, or to the certificate chain it
+// currently presents after the user confirms it.
+func trustAddHost(host string, pins []string) {
+	if len(pins) == 0 {
+		chain, err := utils.FetchPins(host)
+		if err != nil {
+			utils.FatalPrintln("Could not connect to " + host + ": " + err.Error())
+		}
+		fmt.Println(host, "presents the following certificates:")
+		for _, cert := range chain {
+			pin := utils.CertificatePin(cert)
+			fmt.Printf("\t%v  %v\n", pin, cert.Subject.CommonName)
+			pins = append(pins, pin)
+		}
+		fmt.Print("Pin these keys? ([y]/n) ")
+		if strings.EqualFold(utils.ReadAnswer(), "n") {
+			utils.FatalPrintln("Aborting.")
+		}
+	}
+	if config.Global.Trust.Hosts == nil {
+		config.Global.Trust.Hosts = make(map[string][]string)
+	}
+	config.Global.Trust.Hosts[host] = pins
+	config.GenConf(config.Global)
+	fmt.Printf("Pinned %v to %v key(s).\n", host, len(pins))
+}
+
+// trustRemove removes a trusted key or a pinned host by name.
+func trustRemove(name string) {
+	if _, ok := config.Global.Trust.Keys[name]; ok {
+		delete(config.Global.Trust.Keys, name)
+		fmt.Printf("No longer trusting the key \"%v\".\n", name)
+	} else if _, ok := config.Global.Trust.Hosts[strings.ToLower(name)]; ok {
+		delete(config.Global.Trust.Hosts, strings.ToLower(name))
+		fmt.Printf("Unpinned %v.\n", name)
+	} else {
+		fmt.Printf("There is no trusted key or pinned host named \"%v\". Nothing was done.\n", name)
+		return
+	}
+	config.GenConf(config.Global)
+}
+
+// trustList prints the trusted keys, pinned hosts and the verification policy.
+func trustList() {
+	trust := config.Global.Trust
+	fmt.Printf("Keyset verification policy: %v\n", trust.Policy)
+	if len(trust.Keys) == 0 {
+		fmt.Println("No trusted keys.")
+	} else {
+		fmt.Println(len(trust.Keys), "trusted key(s):")
+		names := make([]string, 0, len(trust.Keys))
+		for name := range trust.Keys {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			identity, err := keysets.KeyIdentity(trust.Keys[name])
+			if err != nil {
+				identity = "unreadable key: " + err.Error()
+			}
+			fmt.Printf("\t %v  %v\n", name, identity)
+		}
+	}
+	if len(trust.Hosts) == 0 {
+		fmt.Println("No pinned hosts.")
+		return
+	}
+	fmt.Println(len(trust.Hosts), "pinned host(s):")
+	hosts := make([]string, 0, len(trust.Hosts))
+	for host := range trust.Hosts {
+		hosts = append(hosts, host)
+	}
+	sort.Strings(hosts)
+	for _, host := range hosts {
+		fmt.Printf("\t %v  %v\n", host, strings.Join(trust.Hosts[host], ", "))
+	}
+}
+
+// trustExport writes the trusted keys and pinned hosts to out for sharing.
+func trustExport(out string) {
+	buf := new(bytes.Buffer)
+	err := toml.NewEncoder(buf).Encode(trustFile{
+		Keys:  config.Global.Trust.Keys,
+		Hosts: config.Global.Trust.Hosts,
+	})
+	utils.CheckError(err)
+	utils.CheckError(ioutil.WriteFile(out, buf.Bytes(), 0644))
+	fmt.Printf("Exported %v key(s) and %v pinned host(s) to %v.\n",
+		len(config.Global.Trust.Keys), len(config.Global.Trust.Hosts), out)
+}
+
+// trustImport merges the keys and pins of an exported file into the config,
+// replacing entries with the same name.
+func trustImport(file string) {
+	imported := trustFile{}
+	_, err := toml.DecodeFile(file, &imported)
+	utils.CheckError(err)
+	if config.Global.Trust.Keys == nil {
+		config.Global.Trust.Keys = make(map[string]string)
+	}
+	if config.Global.Trust.Hosts == nil {
+		config.Global.Trust.Hosts = make(map[string][]string)
+	}
+	for name, key := range imported.Keys {
+		if _, err := keysets.KeyIdentity(key); err != nil {
+			fmt.Printf("Skipping the key \"%v\": %v\n", name, err)
+			delete(imported.Keys, name)
+			continue
+		}
+		config.Global.Trust.Keys[name] = key
+	}
+	for host, pins := range imported.Hosts {
+		config.Global.Trust.Hosts[strings.ToLower(host)] = pins
+	}
+	config.GenConf(config.Global)
+	fmt.Printf("Imported %v key(s) and %v pinned host(s) from %v.\n",
+		len(imported.Keys), len(imported.Hosts), file)
+}
@@ -0,0 +1,77 @@
+package cli
+
+import (
+	"io/ioutil"
+	"sort"
+
+	"github.com/arken/ait/config"
+	"github.com/arken/ait/display"
+	"github.com/arken/ait/utils"
+
+	"github.com/DataDrake/cli-ng/v2/cmd"
+)
+
+// UI is an interactive alternative to staging and submitting with flags.
+var UI = cmd.Sub{
+	Name:  "ui",
+	Short: "Browse the workspace, stage files and submit them in an interactive terminal UI.",
+	Run:   UIRun,
+}
+
+// UIRun shows the workbench until it's quit.
+func UIRun(_ *cmd.Root, _ *cmd.Sub) {
+	// Hooks printing would draw over the workbench.
+	utils.HookOutput = ioutil.Discard
+	wb := display.NewWorkbench(remoteAliases(), display.ReadApplication())
+	wb.Staged = func() ([]string, error) {
+		var paths []string
+		for _, file := range readStagedFiles() {
+			paths = append(paths, file.Path)
+		}
+		return paths, nil
+	}
+	wb.Stage = func(path string) (int, error) {
+		return stagePaths([]string{path})
+	}
+	wb.Unstage = func(path string) (int, error) {
+		return unstagePaths("ait ui", []string{path})
+	}
+	// Submissions run as a separate "ait submit", as they do from the web
+	// dashboard, reporting their progress as events the workbench shows.
+	wb.Submit = func(form display.SubmitForm, output *display.WorkbenchOutput) error {
+		child, err := submitCommand(webSubmitRequest{
+			Remote:      form.Remote,
+			Category:    form.Category,
+			Filename:    form.Filename,
+			Title:       form.Title,
+			Commit:      form.Commit,
+			PRBody:      form.PRBody,
+			PullRequest: form.PullRequest,
+			Issue:       form.Issue,
+		}, "--progress-json")
+		if err != nil {
+			return err
+		}
+		// Nothing can answer a prompt, the choices are those of the form.
+		child.Args = append(child.Args, "--yes")
+		child.Stdout, child.Stderr = output, output
+		return child.Run()
+	}
+	utils.CheckError(wb.Run())
+}
+
+// remoteAliases returns the remote aliases of the workspace and the saved
+// ones, in order.
+func remoteAliases() []string {
+	var aliases []string
+	for alias := range config.WorkspaceRemotes() {
+		aliases = append(aliases, alias)
+	}
+	for alias := range config.Global.Git.Remotes {
+		if _, ok := config.WorkspaceRemotes()[alias]; !ok {
+			aliases = append(aliases, alias)
+		}
+	}
+	sort.Strings(aliases)
+	return aliases
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 
 	"github.com/arken/ait/types"
 	"github.com/arken/ait/utils"
@@ -36,43 +37,65 @@ type UnstageArgs struct {
 type UnstageFlags struct {
 	All        bool   `long:"all" desc:"unstage all currently staged files"`
 	Extensions string `short:"e" long:"extension" desc:"Unstage all files with the given file extension. For multiple extensions, separate each with a comma"`
+	Undo       bool   `long:"undo" desc:"Stage the files of the latest unstaging again, or the unstaged files within the given paths"`
+	Trash      bool   `long:"trash" desc:"List the recent unstagings --undo can revert"`
 }
 
 // UnstageRun executes the remove function.
 func UnstageRun(_ *cmd.Root, c *cmd.Sub) {
+	if flags, ok := c.Flags.(*UnstageFlags); ok && (flags.Undo || flags.Trash) {
+		var paths []string
+		if c.Args != nil {
+			paths = c.Args.(*UnstageArgs).Paths
+		}
+		restoreUnstaged(paths, flags.Trash)
+		return
+	}
 	args, exts, rmAll := parseUnstageArgs(c)
 	size, _ := utils.GetFileSize(utils.AddedFilesPath)
 	if !utils.FileExists(utils.AddedFilesPath) || size == 0 {
 		utils.FatalPrintln("No files currently staged, nothing was done")
 	} else if rmAll || (len(args) > 0 && args[0] == ".") {
-		file := utils.BasicFileOpen(utils.AddedFilesPath, os.O_TRUNC|os.O_WRONLY, 0644)
-		file.Close()
-		fmt.Println("All files unstaged")
+		utils.CheckError(utils.WriteUnstaged(types.NewBasicStringSet(), "ait unstage --all"))
+		fmt.Println("All files unstaged, \"ait unstage --undo\" stages them again")
 		return
 	}
-	contents := types.NewBasicStringSet()
-	file := utils.BasicFileOpen(utils.AddedFilesPath, os.O_RDONLY, 0644)
-	utils.FillSet(contents, file)
-	file.Close()
 	numRMd := 0
 	if exts.Size() > 0 && len(args) == 0 {
 		args = append(args, ".")
 	}
 	for _, userPath := range args {
-		userPath = filepath.Clean(userPath)
-		_ = contents.ForEach(func(addedPath string) error {
-			if utils.IsInSubDir(addedPath, userPath) || exts.Contains(filepath.Ext(addedPath)) {
+		if _, err := filepath.Match(userPath, ""); err != nil {
+			utils.FatalPrintf("Invalid pattern %q: %v\n", userPath, err)
+		}
+	}
+	var notFound []string
+	err := utils.UpdateUnstaged("ait unstage "+strings.Join(args, " "), func(contents *types.ThreadSafeStringSet) error {
+		for _, userPath := range args {
+			var matches []string
+			_ = contents.ForEach(func(addedPath string) error {
+				if utils.MatchesStaged(userPath, addedPath) || exts.Contains(filepath.Ext(addedPath)) {
+					matches = append(matches, addedPath)
+				}
+				return nil
+			})
+			for _, addedPath := range matches {
 				contents.Delete(addedPath)
-				numRMd++
 			}
-			return nil
-		})
-	}
-	file = utils.BasicFileOpen(utils.AddedFilesPath, os.O_WRONLY|os.O_TRUNC, 0644)
-	err := utils.DumpSet(contents, file)
-	file.Close()
+			if len(matches) == 0 {
+				notFound = append(notFound, userPath)
+			} else if len(args) > 1 {
+				fmt.Printf("\t%v: %d file(s)\n", userPath, len(matches))
+			}
+			numRMd += len(matches)
+		}
+		return nil
+	})
 	utils.CheckError(err)
 	fmt.Println(numRMd, "file(s) unstaged")
+	if len(notFound) > 0 {
+		fmt.Println("Nothing staged matched:", strings.Join(notFound, ", "))
+	}
 }
 
 // parseUnstageArgs simply does some of the sanitization and extraction required to
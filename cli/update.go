@@ -1,15 +1,17 @@
 package cli
 
 import (
-	"bufio"
 	"fmt"
-	"net/http"
 	"os"
-	"runtime"
+	"path/filepath"
 	"strings"
 	"sync"
 
 	"github.com/DataDrake/cli-ng/v2/cmd"
+	aitgh "github.com/arken/ait/apis/github"
+	"github.com/arken/ait/config"
+	"github.com/arken/ait/keysets"
+	"github.com/arken/ait/platform"
 	"github.com/arken/ait/utils"
 	"github.com/inconshreveable/go-update"
 	"github.com/tcnksm/go-latest"
@@ -18,11 +20,12 @@ import (
 var appVersion string
 
 // Update checks for a new version of the AIT program and updates itself
-// if a newer version is found and the user agrees to update.
+// if a newer version is found and the user agrees to update. Given a keyset
+// repository it updates the keyset submitted to it instead.
 var Update = cmd.Sub{
 	Name:  "update",
 	Alias: "upd",
-	Short: "Update AIT to the lastest version.",
+	Short: "Update AIT to the lastest version, or a submitted keyset to the staged files.",
 	Args:  &UpdateArgs{},
 	Flags: &UpdateFlags{},
 	Run:   UpdateRun,
@@ -30,15 +33,25 @@ var Update = cmd.Sub{
 
 // UpdateArgs handles the specific arguments for the update command.
 type UpdateArgs struct {
+	Args []string `zero:"yes" desc:"Repository url of a submitted keyset to update to the staged files"`
 }
 
 // UpdateFlags handles the specific flags for the update command.
 type UpdateFlags struct {
-	Yes bool `short:"y" long:"yes" desc:"If a newer version is found update without prompting the user."`
+	Yes  bool   `short:"y" long:"yes" desc:"Update without prompting the user."`
+	Path string `long:"path" desc:"Path of the keyset in the repository, the one last submitted to it by default"`
+	IsPR bool   `short:"p" long:"pull-request" desc:"Update the keyset through a pull request"`
 }
 
 // UpdateRun handles the checking and self updating of the AIT program.
 func UpdateRun(r *cmd.Root, c *cmd.Sub) {
+	if args := c.Args.(*UpdateArgs).Args; len(args) > 0 {
+		if len(args) > 1 {
+			utils.FatalPrintln("Expected a single repository url.")
+		}
+		updateKeyset(args[0], c.Flags.(*UpdateFlags))
+		return
+	}
 	fmt.Printf("Current Version: %s\n", appVersion)
 
 	flags := c.Flags.(*UpdateFlags)
@@ -53,14 +66,11 @@ func UpdateRun(r *cmd.Root, c *cmd.Sub) {
 	if res.Outdated {
 		if !flags.Yes {
 			fmt.Println("Would you like to update AIT to the newest version? ([y]/n)")
-			reader := bufio.NewReader(os.Stdin)
-			input, _ := reader.ReadString('\n')
-			input = strings.ToLower(strings.TrimSpace(input))
-			if input == "n" {
+			if strings.ToLower(utils.ReadAnswer()) == "n" {
 				return
 			}
 		}
-		url := "https://github.com/arken/ait/releases/download/v" + res.Current + "/ait-v" + res.Current + "-" + runtime.GOOS + "-" + runtime.GOARCH
+		url := "https://github.com/arken/ait/releases/download/v" + res.Current + "/" + platform.ReleaseAsset("v"+res.Current)
 
 		doneChan := make(chan int, 1)
 		wg := sync.WaitGroup{}
@@ -69,7 +79,7 @@ func UpdateRun(r *cmd.Root, c *cmd.Sub) {
 		// Display Spinner on Update.
 		go utils.SpinnerWait(doneChan, "Updating AIT...", &wg)
 
-		resp, err := http.Get(url)
+		resp, err := utils.NewClient(0).Get(url)
 		utils.CheckError(err)
 
 		defer resp.Body.Close()
@@ -84,3 +94,118 @@ func UpdateRun(r *cmd.Root, c *cmd.Sub) {
 		fmt.Println("Already Up-To-Date!")
 	}
 }
+
+// updateKeyset updates the keyset this workspace submitted to the repository
+// at url to match the staged files. Files submitted before that aren't staged
+// anymore are removed from it, newly staged files are added, and the entries
+// other contributors added are kept.
+func updateKeyset(arg string, flags *UpdateFlags) {
+	if !utils.IsAITRepo() {
+		utils.FatalPrintln("Updating a keyset compares it to the staged files, run it in an AIT repository.")
+	}
+	if s, _ := utils.GetFileSize(utils.AddedFilesPath); s == 0 {
+		utils.FatalPrintln("No files are currently staged, stage the files the keyset should list.")
+	}
+	if flags.Yes {
+		utils.NonInteractive = true
+	}
+	url := config.GetPushRemote(arg)
+	history, err := utils.ReadHistory()
+	utils.CheckError(err)
+	path := flags.Path
+	if path == "" {
+		path = lastSubmittedPath(history, url)
+		if path == "" {
+			utils.FatalPrintf("Nothing was submitted to %v from this workspace, give the keyset with --path.\n", url)
+		}
+	}
+	submitted := make(map[string]bool)
+	for _, s := range history {
+		if s.Remote == url && s.Path == path {
+			for _, entry := range s.Entries {
+				submitted[entry.CID] = true
+			}
+		}
+	}
+
+	repoPath := config.SourcePath(url)
+	_, err = keysets.Clone(url, repoPath)
+	utils.CheckError(err)
+	existing := filepath.Join(repoPath, filepath.FromSlash(path))
+	if !utils.FileExists(existing) {
+		utils.FatalPrintf("%v isn't in %v, submit it with \"ait submit\" instead.\n", path, url)
+	}
+
+	prettyIPFSInit()
+	ksPath := utils.GeneratedKeysetPath()
+	_, err = checkGenerated(keysets.Generate(ksPath, true), ksPath, false, utils.SubmissionCleanup)
+	utils.CheckError(err)
+	updatedPath := ksPath + ".updated"
+	updated, err := os.Create(updatedPath)
+	utils.CheckErrorWithCleanup(err, utils.SubmissionCleanup)
+	result, err := keysets.Update(existing, ksPath, submitted, updated)
+	updated.Close()
+	utils.CheckErrorWithCleanup(err, utils.SubmissionCleanup)
+	if !result.Changed() {
+		utils.SubmissionCleanup()
+		fmt.Printf("%v already lists the staged files, there's nothing to update.\n", path)
+		return
+	}
+	fmt.Printf("Updating %v: adding %d and removing %d file(s), keeping %d from other contributors.\n",
+		path, result.Added, result.Removed, result.Kept)
+	if !flags.Yes {
+		fmt.Print("Continue? ([y]/n) ")
+		if strings.ToLower(utils.ReadAnswer()) == "n" {
+			utils.SubmissionCleanup()
+			fmt.Println("Update aborted.")
+			return
+		}
+	}
+
+	isPR := flags.IsPR
+	canPush, err := aitgh.Init(url, isPR)
+	utils.CheckErrorWithCleanup(err, utils.SubmissionCleanup)
+	if !canPush && !isPR {
+		isPR, err = promptDoPullRequest(url)
+		utils.CheckErrorWithCleanup(err, utils.SubmissionCleanup)
+		if !isPR {
+			utils.SubmissionCleanup()
+			utils.FatalPrintln("Update aborted.")
+		}
+	}
+	if isPR {
+		utils.CheckErrorWithCleanup(aitgh.CreateFork(), utils.SubmissionCleanup)
+		utils.CheckErrorWithCleanup(aitgh.UsePullRequestBranch(path), utils.SubmissionCleanup)
+	}
+	message := fmt.Sprintf("Update %v: add %d and remove %d file(s)", path, result.Added, result.Removed)
+	announceStaged(ksPath)
+	commit, err := aitgh.UpdateFile(updatedPath, path, message, isPR)
+	utils.CheckErrorWithCleanup(err, utils.SubmissionCleanup)
+	entries, err := utils.ReadKeysetEntries(ksPath)
+	utils.CheckErrorWithCleanup(err, utils.SubmissionCleanup)
+	submission := utils.NewSubmission(url, path, entries)
+	submission.Commit = commit
+	utils.SubmissionCleanup()
+	if isPR {
+		submission.PullRequest, submission.PRNumber, err = aitgh.CreatePullRequest(message, message, path)
+		utils.CheckError(err)
+	}
+	if err = submission.Save(); err != nil {
+		fmt.Println("Unable to record the update in the history:", err)
+	}
+	registerSubmission(submission)
+	postSubmitHook(submission)
+	fmt.Println("Update successful!")
+	printSubmission(submission)
+}
+
+// lastSubmittedPath returns the path of the keyset last committed to the
+// repository at url from this workspace, or "" if there's none.
+func lastSubmittedPath(history []*utils.Submission, url string) string {
+	for i := len(history) - 1; i >= 0; i-- {
+		if history[i].Remote == url && history[i].Issue == "" && history[i].Path != "" {
+			return history[i].Path
+		}
+	}
+	return ""
+}
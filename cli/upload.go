@@ -1,21 +1,19 @@
 package cli
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"path/filepath"
-	"runtime"
-	"strings"
 	"sync"
 	"time"
 
 	"github.com/arken/ait/config"
+	"github.com/arken/ait/display"
 	"github.com/arken/ait/ipfs"
-	"github.com/arken/ait/types"
 	"github.com/arken/ait/utils"
 
 	"github.com/DataDrake/cli-ng/v2/cmd"
-	"github.com/schollz/progressbar/v3"
 )
 
 // Upload begins seeding your files to the Arken Cluster once your
@@ -41,29 +39,18 @@ type UploadFlags struct {
 // UploadRun handles the uploading and display of the upload command.
 func UploadRun(r *cmd.Root, c *cmd.Sub) {
 	flags := c.Flags.(*UploadFlags)
-	contents := types.NewBasicStringSet()
-	file := utils.BasicFileOpen(utils.AddedFilesPath, os.O_CREATE|os.O_RDONLY, 0644)
-	utils.FillSet(contents, file)
-	file.Close()
+	contents, err := utils.ReadStaged()
+	utils.CheckError(err)
 
-	// In order to not copy files to ~/.ait/ipfs/ we need to create a workdir symlink
-	// in .ait
 	wd, err := os.Getwd()
 	if err != nil {
 		utils.FatalWithCleanup(utils.SubmissionCleanup, err.Error())
 	}
-	link := filepath.Join(filepath.Dir(config.Global.IPFS.Path), "workdir")
-	err = os.Symlink(wd, link)
+	// Files are referenced through the workspace's link instead of being
+	// copied into ~/.ait/ipfs.
+	link, err := ipfs.LinkWorkdir()
 	if err != nil {
-		if strings.HasSuffix(err.Error(), "file exists") {
-			os.Remove(link)
-			err = os.Symlink(wd, link)
-			if err != nil {
-				utils.FatalWithCleanup(utils.SubmissionCleanup, err.Error())
-			}
-		} else {
-			utils.FatalWithCleanup(utils.SubmissionCleanup, err.Error())
-		}
+		utils.FatalWithCleanup(utils.SubmissionCleanup, err.Error())
 	}
 
 	workers := genNumWorkers()
@@ -82,12 +69,36 @@ func UploadRun(r *cmd.Root, c *cmd.Sub) {
 	fmt.Println()
 	close(doneChan)
 
+	// Notice data of other workspaces that moved before it stops being
+	// provided.
+	checkWorkspaces()
+
+	// Attribute what is sent to peers to this workspace's dataset.
+	go ipfs.MonitorUploads(context.Background(), uploadDataset(wd))
+
+	// Keep the repository within its configured limits while seeding.
+	go func() {
+		if err := ipfs.EnforceStorage(context.Background()); err != nil {
+			fmt.Printf("\n[Unable to garbage collect the IPFS repository: %v]\n", err)
+		}
+	}()
+
+	// Catch blocks that rot on disk while the data is being seeded.
+	go scrubPeriodically(context.Background())
+	// Alert if previously submitted datasets lose their replicas.
+	go watchReplication(context.Background())
+	// Announce the pinned data again in batches over ReprovideInterval.
+	if interval, err := time.ParseDuration(config.Global.IPFS.ReprovideInterval); err == nil && interval > 0 {
+		go ipfs.ScheduleReprovides(context.Background(), interval, priorityReprovides)
+	}
+
 	input := make(chan string, contents.Size())
 
 	go func() {
 		contents.ForEach(func(path string) error {
 			cid, err := ipfs.Add(filepath.Join(link, path), false)
 			utils.CheckError(err)
+			utils.CheckError(ipfs.RecordPin(cid, ipfs.PinUploaded))
 
 			input <- cid
 			return nil
@@ -95,11 +106,10 @@ func UploadRun(r *cmd.Root, c *cmd.Sub) {
 	}()
 
 	fmt.Println("Uploading Files to Cluster")
-	ipfsBar := progressbar.Default(int64(contents.Size()))
-	ipfsBar.RenderBlank()
+	ipfsBar := display.NewProgress("Replicated", int64(contents.Size()), false)
 
 	for i := 0; i < workers; i++ {
-		go func(bar *progressbar.ProgressBar, input chan string) {
+		go func(bar *display.Progress, input chan string) {
 			for cid := range input {
 				replications, err := ipfs.FindProvs(cid, 20)
 				if flags.Debug {
@@ -108,7 +118,7 @@ func UploadRun(r *cmd.Root, c *cmd.Sub) {
 				if replications > 2 {
 					bar.Add(1)
 				} else {
-					bar.Add(0)
+					bar.Refresh()
 					input <- cid
 				}
 				if replications == 0 {
@@ -120,22 +130,57 @@ func UploadRun(r *cmd.Root, c *cmd.Sub) {
 	}
 
 	for {
-		if ipfsBar.State().CurrentPercent == float64(1) {
+		if ipfsBar.Finished() {
 			close(input)
-			err = os.Remove(link)
-			utils.CheckError(err)
+			if config.Global.Community.Share {
+				if err := shareStats(); err != nil {
+					fmt.Printf("[Unable to share seeding statistics: %v]\n", err)
+				}
+			}
 			break
 		}
-		ipfsBar.Add(0)
+		ipfsBar.Refresh()
 		time.Sleep(1000 * time.Millisecond)
 	}
 }
 
-// Generate the number of worker processes to optimize efficiency.
-// Subtract 2 from the number of cores because of the main thread and the GetAll function.
+// uploadDataset names the dataset the workspace seeds, which is the remote it
+// was last submitted to or the name of the workspace if it wasn't.
+func uploadDataset(wd string) string {
+	history, err := utils.ReadHistory()
+	if err != nil || len(history) == 0 {
+		return filepath.Base(wd)
+	}
+	return history[len(history)-1].Remote
+}
+
+// Generate the number of worker processes to optimize efficiency, General.Workers
+// if it's set.
 func genNumWorkers() int {
-	if runtime.NumCPU() > 2 {
-		return runtime.NumCPU() - 1
+	return utils.NumWorkers()
+}
+
+// recentSubmission is how long the files of a submission are reprovided
+// before the rest of the pinset.
+const recentSubmission = 7 * 24 * time.Hour
+
+// priorityReprovides returns the CIDs of the workspace's at-risk submissions,
+// then of those made within recentSubmission, newest first.
+func priorityReprovides() []string {
+	history, err := utils.ReadHistory()
+	if err != nil {
+		return nil
+	}
+	var atRisk, recent []string
+	for i := len(history) - 1; i >= 0; i-- {
+		s := history[i]
+		for _, entry := range s.Entries {
+			if s.AtRisk {
+				atRisk = append(atRisk, entry.CID)
+			} else if time.Since(s.Time) < recentSubmission {
+				recent = append(recent, entry.CID)
+			}
+		}
 	}
-	return 1
+	return append(atRisk, recent...)
 }
@@ -0,0 +1,102 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/arken/ait/config"
+	"github.com/arken/ait/display"
+	"github.com/arken/ait/ipfs"
+	"github.com/arken/ait/keysets"
+	"github.com/arken/ait/utils"
+
+	"github.com/DataDrake/cli-ng/v2/cmd"
+)
+
+// Validate checks keysets before they're merged, for the maintainers of
+// keyset repositories reviewing submissions.
+var Validate = cmd.Sub{
+	Name:  "validate",
+	Short: "Check the entries of a keyset file or of every keyset in a keyset repository.",
+	Args:  &ValidateArgs{},
+	Flags: &ValidateFlags{},
+	Run:   ValidateRun,
+}
+
+// ValidateArgs handles the specific arguments for the validate command.
+type ValidateArgs struct {
+	Keyset string `desc:"A keyset file, a keyset repository checked out or a remote to clone"`
+}
+
+// ValidateFlags handles the specific flags for the validate command.
+type ValidateFlags struct {
+	Online  bool   `short:"o" long:"online" desc:"Check that every CID has at least one provider"`
+	MaxSize string `short:"m" long:"max-size" desc:"Largest size allowed for a file, ie 10GB, looked up on the network"`
+	AllowV0 bool   `long:"allow-v0" desc:"Accept CIDv0 entries, only CIDv1 ones are by default"`
+}
+
+// ValidateRun checks every entry of the keysets given, printing each problem
+// found as path:line: message, and exits with status 1 if there's any.
+func ValidateRun(_ *cmd.Root, c *cmd.Sub) {
+	args := c.Args.(*ValidateArgs)
+	flags := c.Flags.(*ValidateFlags)
+	maxSize, err := utils.ParseByteSize(flags.MaxSize)
+	if err != nil {
+		utils.FatalPrintln("Invalid --max-size:", err)
+	}
+	dir, paths := validatedKeysets(args.Keyset)
+
+	opts := keysets.ValidateOptions{AllowV0: flags.AllowV0, MaxSize: maxSize}
+	if flags.Online || maxSize > 0 {
+		prettyIPFSInit()
+	}
+	if maxSize > 0 {
+		opts.SizeOf = func(cid string) (int64, error) {
+			return ipfs.Size(cid, indexSizeTimeout)
+		}
+	}
+	if flags.Online {
+		opts.Providers = func(cid string) (int, error) {
+			return ipfs.FindProvs(cid, 1)
+		}
+	}
+	validation, err := keysets.Validate(dir, paths, opts)
+	utils.CheckError(err)
+	display.Out.Result(validation, func(w io.Writer) {
+		for _, problem := range validation.Problems {
+			fmt.Fprintln(w, problem)
+		}
+		if len(validation.Problems) == 0 {
+			fmt.Fprintf(w, "The %d entries of %d keyset(s) are valid.\n", validation.Entries, validation.Keysets)
+		} else {
+			fmt.Fprintf(w, "Found %d problem(s) in the %d entries of %d keyset(s).\n",
+				len(validation.Problems), validation.Entries, validation.Keysets)
+		}
+	})
+	if len(validation.Problems) > 0 {
+		utils.Exit(1)
+	}
+}
+
+// validatedKeysets returns the keysets to validate, relative to the directory
+// returned: the keyset file given, those of the keyset repository checked out
+// at the path given, or those of the remote given, cloned.
+func validatedKeysets(keyset string) (dir string, paths []string) {
+	if info, err := os.Stat(keyset); err == nil && !info.IsDir() {
+		return "", []string{keyset}
+	} else if err == nil {
+		dir = keyset
+	} else {
+		url := config.GetRemote(keyset)
+		dir = config.SourcePath(url)
+		_, err := keysets.Clone(url, dir)
+		utils.CheckError(err)
+	}
+	paths, err := keysets.FindKeysetFiles(dir)
+	utils.CheckError(err)
+	if len(paths) == 0 {
+		utils.FatalPrintf("No keyset files were found in %v.\n", keyset)
+	}
+	return dir, paths
+}
@@ -0,0 +1,250 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+
+	"github.com/arken/ait/display"
+	"github.com/arken/ait/ipfs"
+	"github.com/arken/ait/types"
+	"github.com/arken/ait/utils"
+
+	"github.com/DataDrake/cli-ng/v2/cmd"
+)
+
+// Verify hashes staged files again to find those that changed since their CID
+// was recorded.
+var Verify = cmd.Sub{
+	Name:  "verify",
+	Short: "Check that staged files still match the CIDs recorded for them, or those of a keyset.",
+	Args:  &VerifyArgs{},
+	Flags: &VerifyFlags{},
+	Run:   VerifyRun,
+}
+
+// VerifyFlags handles the specific flags for the verify command.
+type VerifyFlags struct {
+	// JSON is kept for the scripts written before the global --json.
+	JSON bool `long:"json" desc:"Deprecated, use the global --json instead"`
+}
+
+// VerifyArgs handles the specific arguments for the verify command.
+type VerifyArgs struct {
+	Keyset []string `zero:"yes" desc:"A keyset file to check the staged files against"`
+}
+
+// The statuses of a verified file.
+const (
+	verifyOK       = "ok"
+	verifyModified = "modified"
+	verifyMissing  = "missing"
+	verifyError    = "error"
+)
+
+// VerifyResult is the outcome of checking a file against its expected CID.
+type VerifyResult struct {
+	Path     string `json:"path,omitempty"`
+	Name     string `json:"name"`
+	Expected string `json:"expected"`
+	Actual   string `json:"actual,omitempty"`
+	Status   string `json:"status"`
+	Error    string `json:"error,omitempty"`
+}
+
+// VerifyReport is what the verify command prints with --json.
+type VerifyReport struct {
+	Checked int `json:"checked"`
+	Failed  int `json:"failed"`
+	// Unrecorded is the number of staged files no CID was recorded for, so
+	// they couldn't be checked without a keyset.
+	Unrecorded int            `json:"unrecorded,omitempty"`
+	Files      []VerifyResult `json:"files"`
+}
+
+// VerifyRun hashes the staged files again and compares them to the CIDs
+// recorded when they were handed off, hashed by "ait stage --partition" or
+// hashed for the last keyset generated, or to the entries of the given keyset. It exits with status 1 if a file doesn't
+// match.
+func VerifyRun(_ *cmd.Root, c *cmd.Sub) {
+	args := c.Args.(*VerifyArgs).Keyset
+	deprecatedJSON(c.Name, "--json", c.Flags.(*VerifyFlags).JSON)
+	if len(args) > 1 {
+		utils.FatalPrintln("Expected at most one keyset file.")
+	}
+	staged, err := utils.ReadStaged()
+	utils.CheckError(err)
+
+	var expected []VerifyResult
+	report := VerifyReport{}
+	if len(args) == 1 {
+		entries, err := utils.ReadKeysetEntries(args[0])
+		utils.CheckError(err)
+		expected = keysetExpectations(entries, staged)
+	} else {
+		handoff, err := utils.ReadHandoff()
+		utils.CheckError(err)
+		hashed := utils.OpenHashCache(utils.HashCachePath)
+		defer hashed.Close()
+		_ = staged.ForEach(func(path string) error {
+			if cid, ok := recordedCID(handoff, hashed, path); ok {
+				expected = append(expected, VerifyResult{Path: path, Name: utils.KeysetName(path), Expected: cid})
+			} else {
+				report.Unrecorded++
+			}
+			return nil
+		})
+	}
+	if len(expected) > 0 {
+		prettyIPFSInit()
+		report.Files = verifyFiles(expected)
+	}
+	report.Checked = len(report.Files)
+	for _, result := range report.Files {
+		if result.Status != verifyOK {
+			report.Failed++
+		}
+	}
+	if report.Files == nil {
+		report.Files = []VerifyResult{}
+	}
+	display.Out.Result(report, func(io.Writer) {
+		printVerifyReport(report, len(args) == 1)
+	})
+	if report.Failed > 0 {
+		utils.Exit(1)
+	}
+}
+
+// recordedCID returns the CID recorded for the staged file at path: the one
+// it was handed off or partitioned with, or else the one it was last hashed to
+// when a keyset was generated.
+func recordedCID(handoff map[string]utils.HandoffEntry, hashed *utils.HashCache, path string) (string, bool) {
+	if entry, ok := handoff[path]; ok {
+		return entry.CID, true
+	}
+	return hashed.Last(path)
+}
+
+// keysetExpectations returns the staged files to check against each entry of
+// a keyset, found by the name the keyset gives them. An entry no staged file
+// has the name of is missing.
+func keysetExpectations(entries []utils.KeysetEntry, staged types.StringSet) []VerifyResult {
+	byName := make(map[string][]string)
+	_ = staged.ForEach(func(path string) error {
+		name := utils.KeysetName(path)
+		byName[name] = append(byName[name], path)
+		return nil
+	})
+	var expected []VerifyResult
+	for _, entry := range entries {
+		paths := byName[entry.Name]
+		if len(paths) == 0 {
+			expected = append(expected, VerifyResult{Name: entry.Name, Expected: entry.CID, Status: verifyMissing})
+			continue
+		}
+		for _, path := range paths {
+			expected = append(expected, VerifyResult{Path: path, Name: entry.Name, Expected: entry.CID})
+		}
+	}
+	return expected
+}
+
+// verifyFiles hashes the files of the expected results that have no status
+// yet and sets it. Files sharing a keyset name only have to match one of them.
+func verifyFiles(expected []VerifyResult) []VerifyResult {
+	link, err := ipfs.LinkWorkdir()
+	utils.CheckError(err)
+	var total int64
+	count := 0
+	for _, result := range expected {
+		if result.Status == "" {
+			size, _ := utils.GetFileSize(result.Path)
+			total += size
+			count++
+		}
+	}
+	bar := display.NewFileProgress("Hashing", int64(count), total)
+	jobs := make(chan int)
+	wg := sync.WaitGroup{}
+	for w := 0; w < genNumWorkers(); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				result := &expected[i]
+				size, err := utils.GetFileSize(result.Path)
+				switch {
+				case os.IsNotExist(err):
+					result.Status = verifyMissing
+				case err != nil:
+					result.Status, result.Error = verifyError, err.Error()
+				default:
+					result.Actual, err = ipfs.Add(filepath.Join(link, result.Path), true)
+					if err != nil {
+						result.Status, result.Error = verifyError, err.Error()
+					} else if result.Actual == result.Expected {
+						result.Status = verifyOK
+					} else {
+						result.Status = verifyModified
+					}
+				}
+				bar.AddFile(size)
+			}
+		}()
+	}
+	for i, result := range expected {
+		if result.Status == "" {
+			jobs <- i
+		}
+	}
+	close(jobs)
+	wg.Wait()
+
+	// Staged files with the same name as a keyset entry only have to match
+	// it once, the others are different files.
+	matched := make(map[string]bool)
+	for _, result := range expected {
+		if result.Status == verifyOK {
+			matched[result.Name+" "+result.Expected] = true
+		}
+	}
+	var results []VerifyResult
+	reported := make(map[string]bool)
+	for _, result := range expected {
+		key := result.Name + " " + result.Expected
+		if result.Status != verifyOK && (matched[key] || reported[key]) {
+			continue
+		}
+		reported[key] = true
+		results = append(results, result)
+	}
+	sort.SliceStable(results, func(i, j int) bool { return results[i].Path < results[j].Path })
+	return results
+}
+
+// printVerifyReport lists the files that don't match their expected CID.
+func printVerifyReport(report VerifyReport, againstKeyset bool) {
+	for _, result := range report.Files {
+		switch result.Status {
+		case verifyModified:
+			fmt.Printf("modified:  %v\n\texpected %v, hashes to %v\n", result.Path, result.Expected, result.Actual)
+		case verifyMissing:
+			if result.Path == "" {
+				fmt.Printf("missing:   %v, no staged file has this name (%v)\n", result.Name, result.Expected)
+			} else {
+				fmt.Printf("missing:   %v\n", result.Path)
+			}
+		case verifyError:
+			fmt.Printf("error:     %v: %v\n", result.Path, result.Error)
+		}
+	}
+	fmt.Printf("%d of %d file(s) match their CID.\n", report.Checked-report.Failed, report.Checked)
+	if report.Unrecorded > 0 && !againstKeyset {
+		fmt.Printf("%d staged file(s) have no recorded CID, give a keyset to check them against it.\n",
+			report.Unrecorded)
+	}
+}
@@ -0,0 +1,250 @@
+package cli
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	aitgh "github.com/arken/ait/apis/github"
+	"github.com/arken/ait/apis/mirror"
+	"github.com/arken/ait/config"
+	"github.com/arken/ait/display"
+	"github.com/arken/ait/types"
+	"github.com/arken/ait/utils"
+
+	"github.com/DataDrake/cli-ng/v2/cmd"
+)
+
+// Watch keeps files mirrored from upstream HTTP servers up to date.
+var Watch = cmd.Sub{
+	Name:  "watch",
+	Short: "Mirror upstream HTTP files, staging and submitting them again when they change.",
+	Args:  &WatchArgs{},
+	Flags: &WatchFlags{},
+	Run:   WatchRun,
+}
+
+// WatchArgs handles the specific arguments for the watch command.
+type WatchArgs struct {
+	Args []string
+}
+
+// WatchFlags handles the specific flags for the watch command.
+type WatchFlags struct {
+	IsAdd    bool `short:"a" long:"add" desc:"Watch a URL, optionally giving where to keep it in the workspace"`
+	IsRm     bool `short:"d" long:"delete" desc:"Stop watching a URL"`
+	IsList   bool `short:"l" long:"list" desc:"List the watched URLs"`
+	Once     bool `short:"o" long:"once" desc:"Check the watched URLs once instead of every WatchPeriod"`
+	NoSubmit bool `short:"n" long:"no-submit" desc:"Only stage changed files, without submitting them"`
+	IsPR     bool `short:"p" long:"pull-request" desc:"Submit updates as pull requests"`
+}
+
+const watchUsage = `	ait watch --add/-a https://example.org/data.csv [data/data.csv]  # Mirrors a URL into the workspace
+	ait watch --delete/-d https://example.org/data.csv  # Stops mirroring a URL
+	ait watch --list/-l  # Lists the mirrored URLs
+	ait watch            # Keeps the mirrored URLs up to date`
+
+// WatchRun manages the watched URLs, or checks them for changes every
+// WatchPeriod until it's stopped.
+func WatchRun(_ *cmd.Root, c *cmd.Sub) {
+	flags := c.Flags.(*WatchFlags)
+	args := c.Args.(*WatchArgs).Args
+	watched, err := utils.ReadWatched()
+	utils.CheckError(err)
+	switch {
+	case flags.IsAdd && !flags.IsRm && !flags.IsList:
+		if len(args) < 1 {
+			utils.FatalPrintln("Expected a URL to watch:\n" + watchUsage)
+		}
+		watched = addWatched(watched, args)
+	case flags.IsRm && !flags.IsAdd && !flags.IsList:
+		if len(args) < 1 {
+			utils.FatalPrintln("Expected a URL to stop watching:\n" + watchUsage)
+		}
+		watched = removeWatched(watched, args[0])
+	case flags.IsList && !flags.IsAdd && !flags.IsRm:
+		listWatched(watched)
+		return
+	case !flags.IsAdd && !flags.IsRm && !flags.IsList:
+		watchUpstream(flags)
+		return
+	default:
+		utils.FatalPrintln("Only one of --add, --delete and --list can be used:\n" + watchUsage)
+	}
+	utils.CheckError(utils.WriteWatched(watched))
+}
+
+// addWatched adds the URL in args to watched, kept at the path also in args or
+// named after the URL.
+func addWatched(watched []utils.WatchedURL, args []string) []utils.WatchedURL {
+	u, err := url.Parse(args[0])
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		utils.FatalPrintf("%v isn't an HTTP URL.\n", args[0])
+	}
+	dest := path.Base(u.Path)
+	if len(args) > 1 {
+		dest = args[1]
+	}
+	if dest == "/" || dest == "." {
+		utils.FatalPrintf("Unable to name the file of %v, give where to keep it in the workspace.\n", args[0])
+	}
+	dest = filepath.Clean(dest)
+	withinRepo, err := utils.IsWithinRepo(dest)
+	utils.CheckError(err)
+	if !withinRepo {
+		utils.FatalPrintln("The file must be kept inside this AIT repo.")
+	}
+	for _, w := range watched {
+		if w.URL == args[0] {
+			utils.FatalPrintf("%v is already watched, it's kept at %v.\n", w.URL, w.Path)
+		}
+		if w.Path == dest {
+			utils.FatalPrintf("%v already mirrors %v.\n", dest, w.URL)
+		}
+	}
+	fmt.Printf("Watching %v, it will be kept at %v.\n", args[0], dest)
+	return append(watched, utils.WatchedURL{URL: args[0], Path: dest})
+}
+
+// removeWatched removes rawURL from watched, leaving its file in place.
+func removeWatched(watched []utils.WatchedURL, rawURL string) []utils.WatchedURL {
+	for i, w := range watched {
+		if w.URL == rawURL {
+			fmt.Printf("Stopped watching %v, %v was left in place.\n", w.URL, w.Path)
+			return append(watched[:i], watched[i+1:]...)
+		}
+	}
+	utils.FatalPrintf("%v isn't watched.\n", rawURL)
+	return watched
+}
+
+// listWatched prints the watched URLs.
+func listWatched(watched []utils.WatchedURL) {
+	if len(watched) == 0 {
+		fmt.Println("No URLs are watched.")
+		return
+	}
+	for _, w := range watched {
+		checked := "never checked"
+		if !w.Checked.IsZero() {
+			checked = "checked " + w.Checked.Local().Format("Jan 2 15:04")
+		}
+		fmt.Printf("%v -> %v (%v)\n", w.URL, w.Path, checked)
+	}
+}
+
+// watchUpstream checks the watched URLs every WatchPeriod, or once.
+func watchUpstream(flags *WatchFlags) {
+	period, err := time.ParseDuration(config.Global.Ingest.WatchPeriod)
+	if !flags.Once && (err != nil || period <= 0) {
+		utils.FatalPrintf("WatchPeriod setting %q is not a valid duration.\n", config.Global.Ingest.WatchPeriod)
+	}
+	var remote, keyset string
+	if !flags.NoSubmit {
+		remote, keyset = lastSubmission()
+	}
+	for {
+		if changed := checkWatched(); len(changed) > 0 && remote != "" {
+			submitWatched(remote, keyset, changed, flags.IsPR)
+		}
+		if flags.Once {
+			return
+		}
+		time.Sleep(period)
+	}
+}
+
+// checkWatched fetches the watched URLs that changed and stages them,
+// returning the URLs that did.
+func checkWatched() (changed []string) {
+	watched, err := utils.ReadWatched()
+	utils.CheckError(err)
+	if len(watched) == 0 {
+		utils.FatalPrintln("No URLs are watched:\n" + watchUsage)
+	}
+	for i, w := range watched {
+		utils.CheckError(os.MkdirAll(filepath.Dir(w.Path), os.ModePerm))
+		v, updated, err := mirror.FetchChanged(w.URL, w.Path,
+			mirror.Validators{ETag: w.ETag, LastModified: w.LastModified})
+		if err != nil {
+			fmt.Printf("[Unable to check %v: %v]\n", w.URL, err)
+			continue
+		}
+		watched[i].ETag, watched[i].LastModified = v.ETag, v.LastModified
+		watched[i].Checked = time.Now()
+		if updated {
+			fmt.Printf("%v changed, staged %v.\n", w.URL, w.Path)
+			stagePath(w.Path)
+			changed = append(changed, w.URL)
+		}
+	}
+	utils.CheckError(utils.WriteWatched(watched))
+	if len(changed) == 0 {
+		fmt.Printf("[%v: no watched URLs changed]\n", time.Now().Format("Jan 2 15:04"))
+	}
+	return changed
+}
+
+// lastSubmission returns the remote and keyset path of the workspace's latest
+// submission, which updates are submitted to. Both are empty when updates
+// can't be submitted unattended.
+func lastSubmission() (remote, keyset string) {
+	history, err := utils.ReadHistory()
+	utils.CheckError(err)
+	if len(history) == 0 {
+		fmt.Println("Changes will only be staged until the workspace is submitted once with \"ait submit\".")
+		return "", ""
+	}
+	if config.GitHubToken() == "" && !aitgh.UsingGitCredential() {
+		fmt.Println("Changes will only be staged, submitting them unattended needs a saved GitHub token.")
+		return "", ""
+	}
+	if config.Global.Git.Name == "" || config.Global.Git.Email == "" {
+		fmt.Println("Changes will only be staged, submitting them unattended needs your name and email.")
+		return "", ""
+	}
+	last := history[len(history)-1]
+	return last.Remote, last.Path
+}
+
+// submitWatched submits the staged files again, replacing the keyset at
+// keyset. The submission runs as its own process so a failure doesn't stop
+// the watch.
+func submitWatched(remote, keyset string, changed []string, isPR bool) {
+	category := filepath.Dir(keyset)
+	if category == "." {
+		category = ""
+	}
+	body := "Changed upstream:\n" + strings.Join(changed, "\n")
+	err := display.WriteApplication(&types.ApplicationContents{
+		Category: category,
+		KsName:   filepath.Base(keyset),
+		Title:    "Update mirrored files",
+		Commit:   "Update mirrored files\n\n" + body,
+		PRBody:   body,
+	})
+	if err != nil {
+		fmt.Printf("[Unable to prepare the submission: %v]\n", err)
+		return
+	}
+	executable, err := os.Executable()
+	if err != nil {
+		fmt.Printf("[Unable to submit the changes: %v]\n", err)
+		return
+	}
+	// The keyset already exists, it's overwritten with the staged files.
+	args := []string{"submit", remote, "--yes", "--ks-mode", "overwrite"}
+	if isPR {
+		args = append(args, "--pull-request")
+	}
+	child := exec.Command(executable, args...)
+	child.Stdout, child.Stderr = os.Stdout, os.Stderr
+	if err = child.Run(); err != nil {
+		fmt.Printf("[Unable to submit the changes, they stay staged: %v]\n", err)
+	}
+}
@@ -0,0 +1,483 @@
+package cli
+
+import (
+	"bytes"
+	_ "embed" // The dashboard page is embedded in the binary.
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/arken/ait/config"
+	"github.com/arken/ait/display"
+	"github.com/arken/ait/ipfs"
+	"github.com/arken/ait/types"
+	"github.com/arken/ait/utils"
+
+	"github.com/DataDrake/cli-ng/v2/cmd"
+)
+
+// Web serves a local dashboard for users who prefer a browser to the CLI.
+var Web = cmd.Sub{
+	Name:  "web",
+	Short: "Serve a local web dashboard of the workspace.",
+	Flags: &WebFlags{},
+	Run:   WebRun,
+}
+
+// WebFlags handles the specific flags for the web command.
+type WebFlags struct {
+	Address string `short:"a" long:"address" desc:"Address to serve the dashboard on, localhost:8421 by default"`
+}
+
+//go:embed web/index.html
+var dashboardPage []byte
+
+// webServer serves the dashboard page and the JSON API it's built on.
+type webServer struct {
+	// host and port are those the dashboard is served on, an empty or
+	// unspecified host serving it on every interface.
+	host string
+	port string
+	// staging serializes changes to the staged files.
+	staging sync.Mutex
+	submit  webSubmission
+}
+
+// webSubmission is a submission running in the background.
+type webSubmission struct {
+	lock    sync.Mutex
+	running bool
+	output  bytes.Buffer
+	err     string
+}
+
+// WebRun serves the dashboard until interrupted.
+func WebRun(_ *cmd.Root, c *cmd.Sub) {
+	flags := c.Flags.(*WebFlags)
+	address := flags.Address
+	if address == "" {
+		address = "localhost:8421"
+	}
+	listener, err := net.Listen("tcp", address)
+	utils.CheckError(err)
+	host, _, err := net.SplitHostPort(address)
+	utils.CheckError(err)
+	_, port, err := net.SplitHostPort(listener.Addr().String())
+	utils.CheckError(err)
+	server := &webServer{host: host, port: port}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", server.page)
+	mux.HandleFunc("/api/status", server.status)
+	mux.HandleFunc("/api/staged", server.staged)
+	mux.HandleFunc("/api/stage", server.stage)
+	mux.HandleFunc("/api/unstage", server.unstage)
+	mux.HandleFunc("/api/history", server.history)
+	mux.HandleFunc("/api/submit", server.submission)
+
+	if server.anyHost() {
+		host = "localhost"
+	}
+	fmt.Printf("Serving the dashboard at http://%v/, press Ctrl+C to stop.\n", net.JoinHostPort(host, port))
+	utils.CheckError(http.Serve(listener, server.guard(mux)))
+}
+
+// guard rejects requests that don't come from the dashboard: requests naming
+// another host, which protects against DNS rebinding, and changes that aren't
+// JSON, which browsers won't send cross-origin without permission.
+func (s *webServer) guard(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !s.allowedHost(r.Host) {
+			http.Error(w, "unexpected host", http.StatusForbidden)
+			return
+		}
+		if r.Method != http.MethodGet && r.Header.Get("Content-Type") != "application/json" {
+			http.Error(w, "changes must be sent as JSON", http.StatusUnsupportedMediaType)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// anyHost returns whether the dashboard is served on every interface.
+func (s *webServer) anyHost() bool {
+	ip := net.ParseIP(s.host)
+	return s.host == "" || ip != nil && ip.IsUnspecified()
+}
+
+// allowedHost returns whether a request naming host, with its port, is meant
+// for the dashboard: the host it's served on or, served on every interface,
+// localhost, an IP address or the name of this machine. Other names are only
+// seen once DNS was rebound to the dashboard.
+func (s *webServer) allowedHost(host string) bool {
+	name, port, err := net.SplitHostPort(host)
+	if err != nil || port != s.port {
+		return false
+	}
+	if !s.anyHost() {
+		return strings.EqualFold(name, s.host) || isLoopback(s.host) && isLoopback(name)
+	}
+	if net.ParseIP(name) != nil || isLoopback(name) {
+		return true
+	}
+	hostname, err := os.Hostname()
+	return err == nil && strings.EqualFold(name, hostname)
+}
+
+// isLoopback returns whether host names this machine through the loopback
+// interface.
+func isLoopback(host string) bool {
+	if strings.EqualFold(host, "localhost") {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+func (s *webServer) page(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/" {
+		http.NotFound(w, r)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Write(dashboardPage)
+}
+
+// webStatus summarizes the workspace.
+type webStatus struct {
+	Workspace   string            `json:"workspace"`
+	Staged      int               `json:"staged"`
+	StagedBytes int64             `json:"stagedBytes"`
+	Remotes     map[string]string `json:"remotes"`
+	Submissions int               `json:"submissions"`
+	AtRisk      int               `json:"atRisk"`
+	Uploaded    int64             `json:"uploaded"`
+	Downloaded  int64             `json:"downloaded"`
+}
+
+func (s *webServer) status(w http.ResponseWriter, r *http.Request) {
+	wd, _ := os.Getwd()
+	status := webStatus{Workspace: wd, Remotes: config.Global.Git.Remotes}
+	for _, file := range readStagedFiles() {
+		status.Staged++
+		status.StagedBytes += file.Size
+	}
+	history, err := utils.ReadHistory()
+	if err != nil {
+		writeWebError(w, err)
+		return
+	}
+	status.Submissions = len(history)
+	for _, submission := range history {
+		if submission.AtRisk {
+			status.AtRisk++
+		}
+	}
+	if transfers, err := ipfs.ReadTransfers(); err == nil {
+		for _, transfer := range transfers {
+			status.Uploaded += transfer.Uploaded
+			status.Downloaded += transfer.Downloaded
+		}
+	}
+	writeWebJSON(w, status)
+}
+
+// stagedFile is a staged path and its size.
+type stagedFile struct {
+	Path string `json:"path"`
+	Size int64  `json:"size"`
+}
+
+// readStagedFiles returns the staged files in order.
+func readStagedFiles() []stagedFile {
+	contents, err := utils.ReadStagedSorted()
+	if err != nil {
+		return []stagedFile{}
+	}
+	files := make([]stagedFile, 0, contents.Size())
+	contents.ForEach(func(path string) error {
+		size, _ := utils.GetFileSize(path)
+		files = append(files, stagedFile{Path: path, Size: size})
+		return nil
+	})
+	return files
+}
+
+func (s *webServer) staged(w http.ResponseWriter, r *http.Request) {
+	writeWebJSON(w, readStagedFiles())
+}
+
+// webPaths is a request to stage or unstage paths.
+type webPaths struct {
+	Paths []string `json:"paths"`
+	All   bool     `json:"all"`
+}
+
+func (s *webServer) stage(w http.ResponseWriter, r *http.Request) {
+	var req webPaths
+	if !readWebRequest(w, r, &req) {
+		return
+	}
+	s.staging.Lock()
+	defer s.staging.Unlock()
+	for _, userPath := range req.Paths {
+		userPath = filepath.Clean(userPath)
+		withinRepo, err := utils.IsWithinRepo(userPath)
+		if err != nil || !withinRepo {
+			http.Error(w, "will not stage files outside of the workspace: "+userPath, http.StatusBadRequest)
+			return
+		}
+		if inStateDir(userPath) {
+			http.Error(w, "will not stage the files ait keeps in .ait: "+userPath, http.StatusBadRequest)
+			return
+		}
+	}
+	changed, err := stagePaths(req.Paths)
+	if err != nil {
+		writeWebError(w, err)
+		return
+	}
+	writeWebJSON(w, map[string]int{"changed": changed})
+}
+
+// inStateDir returns whether path, within the workspace, is in the .ait
+// directory ait keeps its state in.
+func inStateDir(path string) bool {
+	wd, err := os.Getwd()
+	if err != nil {
+		return true
+	}
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return true
+	}
+	rel, err := filepath.Rel(wd, abs)
+	if err != nil {
+		return true
+	}
+	return strings.Split(filepath.ToSlash(rel), "/")[0] == ".ait"
+}
+
+// stagePaths stages the files and directories at paths, and returns how many
+// files weren't staged before.
+func stagePaths(paths []string) (int, error) {
+	added := types.NewThreadSafeStringSet()
+	for _, userPath := range paths {
+		addPath(filepath.Clean(userPath), added)
+	}
+	if err := preAddHook(added); err != nil {
+		return 0, err
+	}
+	changed := 0
+	err := utils.UpdateStaged(func(contents *types.ThreadSafeStringSet) error {
+		before := contents.Size()
+		_ = added.ForEach(func(path string) error {
+			contents.Add(path)
+			return nil
+		})
+		changed = contents.Size() - before
+		return nil
+	})
+	return changed, err
+}
+
+func (s *webServer) unstage(w http.ResponseWriter, r *http.Request) {
+	var req webPaths
+	if !readWebRequest(w, r, &req) {
+		return
+	}
+	s.staging.Lock()
+	defer s.staging.Unlock()
+	paths := req.Paths
+	if req.All {
+		paths = []string{"."}
+	}
+	changed, err := unstagePaths("ait web", paths)
+	if err != nil {
+		writeWebError(w, err)
+		return
+	}
+	writeWebJSON(w, map[string]int{"changed": changed})
+}
+
+// unstagePaths unstages the files at paths and those staged in the
+// directories at paths, recording reason with them so they can be restored.
+// It returns how many files were unstaged.
+func unstagePaths(reason string, paths []string) (int, error) {
+	changed := 0
+	err := utils.UpdateUnstaged(reason, func(contents *types.ThreadSafeStringSet) error {
+		var dropped []string
+		_ = contents.ForEach(func(addedPath string) error {
+			for _, userPath := range paths {
+				if utils.MatchesStaged(userPath, addedPath) {
+					dropped = append(dropped, addedPath)
+					break
+				}
+			}
+			return nil
+		})
+		for _, addedPath := range dropped {
+			contents.Delete(addedPath)
+		}
+		changed = len(dropped)
+		return nil
+	})
+	return changed, err
+}
+
+func (s *webServer) history(w http.ResponseWriter, r *http.Request) {
+	history, err := utils.ReadHistory()
+	if err != nil {
+		writeWebError(w, err)
+		return
+	}
+	writeWebJSON(w, history)
+}
+
+// webSubmitRequest is the application filled in through the submission
+// wizard.
+type webSubmitRequest struct {
+	Remote      string `json:"remote"`
+	Category    string `json:"category"`
+	Filename    string `json:"filename"`
+	Title       string `json:"title"`
+	Commit      string `json:"commit"`
+	PRBody      string `json:"pullRequestBody"`
+	PullRequest bool   `json:"pullRequest"`
+	Issue       bool   `json:"issue"`
+	ROCrate     bool   `json:"roCrate"`
+}
+
+// webSubmitState is the progress of the background submission.
+type webSubmitState struct {
+	Running bool   `json:"running"`
+	Output  string `json:"output"`
+	Error   string `json:"error,omitempty"`
+}
+
+// submission reports on the background submission, or starts one from the
+// wizard's application. Submissions run as a separate "ait submit" so a
+// failure can't stop the dashboard.
+func (s *webServer) submission(w http.ResponseWriter, r *http.Request) {
+	job := &s.submit
+	if r.Method == http.MethodGet {
+		job.lock.Lock()
+		defer job.lock.Unlock()
+		writeWebJSON(w, webSubmitState{Running: job.running, Output: job.output.String(), Error: job.err})
+		return
+	}
+	var req webSubmitRequest
+	if !readWebRequest(w, r, &req) {
+		return
+	}
+	if req.Remote == "" || strings.TrimSpace(req.Title) == "" || strings.TrimSpace(req.Commit) == "" {
+		http.Error(w, "a remote, title and commit message are required", http.StatusBadRequest)
+		return
+	}
+	job.lock.Lock()
+	defer job.lock.Unlock()
+	if job.running {
+		http.Error(w, "a submission is already running", http.StatusConflict)
+		return
+	}
+	child, err := submitCommand(req)
+	if err != nil {
+		writeWebError(w, err)
+		return
+	}
+	job.output.Reset()
+	job.err = ""
+	child.Stdout = &webOutput{job}
+	child.Stderr = &webOutput{job}
+	if err := child.Start(); err != nil {
+		writeWebError(w, err)
+		return
+	}
+	job.running = true
+	go func() {
+		err := child.Wait()
+		job.lock.Lock()
+		defer job.lock.Unlock()
+		// The application of a submission that failed isn't reused by the
+		// next one, the wizard asks for it again.
+		_ = os.Remove(webApplicationPath)
+		job.running = false
+		if err != nil {
+			job.err = err.Error()
+		}
+	}()
+	writeWebJSON(w, webSubmitState{Running: true})
+}
+
+// webApplicationPath is where the application of the wizard is saved for the
+// submission. The dashboard removes it once the submission is done.
+var webApplicationPath = filepath.Join(".ait", "commit")
+
+// submitCommand saves the application of req and returns the "ait submit"
+// submitting it, run with the global flags given.
+func submitCommand(req webSubmitRequest, globalFlags ...string) (*exec.Cmd, error) {
+	err := display.WriteApplication(&types.ApplicationContents{
+		Title:    req.Title,
+		Commit:   req.Commit,
+		PRBody:   req.PRBody,
+		Category: req.Category,
+		KsName:   req.Filename,
+	})
+	if err != nil {
+		return nil, err
+	}
+	executable, err := os.Executable()
+	if err != nil {
+		return nil, err
+	}
+	args := append(globalFlags, "submit", req.Remote)
+	if req.PullRequest {
+		args = append(args, "--pull-request")
+	}
+	if req.Issue {
+		args = append(args, "--issue")
+	}
+	if req.ROCrate {
+		args = append(args, "--ro-crate")
+	}
+	return exec.Command(executable, args...), nil
+}
+
+// webOutput collects the output of the background submission.
+type webOutput struct {
+	job *webSubmission
+}
+
+func (o *webOutput) Write(b []byte) (int, error) {
+	o.job.lock.Lock()
+	defer o.job.lock.Unlock()
+	return o.job.output.Write(b)
+}
+
+// readWebRequest decodes the JSON body of a POST request into v, answering
+// the request itself if it can't.
+func readWebRequest(w http.ResponseWriter, r *http.Request, v interface{}) bool {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return false
+	}
+	if err := json.NewDecoder(r.Body).Decode(v); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return false
+	}
+	return true
+}
+
+func writeWebJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeWebError(w http.ResponseWriter, err error) {
+	http.Error(w, err.Error(), http.StatusInternalServerError)
+}
@@ -0,0 +1,48 @@
+package cli
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestAllowedHost(t *testing.T) {
+	hostname, _ := os.Hostname()
+	for _, c := range []struct {
+		host, port, request string
+		allowed             bool
+	}{
+		{"localhost", "8421", "localhost:8421", true},
+		{"localhost", "8421", "127.0.0.1:8421", true},
+		{"localhost", "8421", "localhost:8080", false},
+		{"localhost", "8421", "evil.example.org:8421", false},
+		{"", "8421", "localhost:8421", true},
+		{"", "8421", "192.168.1.20:8421", true},
+		{"0.0.0.0", "8421", "[::1]:8421", true},
+		{"0.0.0.0", "8421", hostname + ":8421", true},
+		{"0.0.0.0", "8421", "evil.example.org:8421", false},
+		{"0.0.0.0", "8421", "localhost", false},
+		{"192.168.1.20", "8421", "192.168.1.20:8421", true},
+		{"192.168.1.20", "8421", "localhost:8421", false},
+	} {
+		s := &webServer{host: c.host, port: c.port}
+		if allowed := s.allowedHost(c.request); allowed != c.allowed {
+			t.Errorf("served on %q, expected %v for %v, got %v", c.host, c.allowed, c.request, allowed)
+		}
+	}
+}
+
+func TestInStateDir(t *testing.T) {
+	for path, expected := range map[string]bool{
+		".ait":                              true,
+		filepath.Join(".ait", "commit"):     true,
+		filepath.Join("data", "..", ".ait"): true,
+		".aitignore":                        false,
+		filepath.Join("data", ".ait"):       false,
+		filepath.Join("data", "survey.csv"): false,
+	} {
+		if inStateDir(path) != expected {
+			t.Errorf("expected %v for %v", expected, path)
+		}
+	}
+}
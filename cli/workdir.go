@@ -0,0 +1,51 @@
+package cli
+
+import (
+	"os"
+	"strings"
+
+	"github.com/arken/ait/config"
+	"github.com/arken/ait/utils"
+)
+
+// changeWorkdir handles the -C/--workdir global flag before anything looks at
+// the workspace, changing to the directory like "git -C". It returns args, the
+// command line without the program name, with the flag removed.
+func changeWorkdir(args []string) []string {
+	var rest []string
+	var dirs []string
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		switch {
+		case !strings.HasPrefix(arg, "-"):
+			// Only global flags before the command are ours.
+			rest = append(rest, args[i:]...)
+			i = len(args)
+		case arg == "-C" || arg == "--workdir":
+			if i+1 == len(args) {
+				utils.FatalPrintln(arg, "needs a directory.")
+			}
+			dirs = append(dirs, args[i+1])
+			i++
+		case strings.HasPrefix(arg, "--workdir="):
+			dirs = append(dirs, strings.TrimPrefix(arg, "--workdir="))
+		default:
+			rest = append(rest, arg)
+			if utils.IndexOf(valueFlags, arg) >= 0 && i+1 < len(args) {
+				rest = append(rest, args[i+1])
+				i++
+			}
+		}
+	}
+	// Like git, each directory is relative to the one before.
+	for _, dir := range dirs {
+		if err := os.Chdir(dir); err != nil {
+			utils.FatalPrintln("Unable to change to the workspace:", err)
+		}
+	}
+	if len(dirs) > 0 {
+		// The workspace may record its own IPFS profile.
+		utils.CheckError(config.SelectProfile())
+	}
+	return rest
+}
@@ -0,0 +1,31 @@
+package cli
+
+import (
+	"fmt"
+
+	"github.com/arken/ait/ipfs"
+)
+
+// checkWorkspaces repairs the links to the workspaces whose files the node
+// references and warns about workspaces or files that have gone missing, ie
+// after being renamed or on storage that isn't mounted. The IPFS subsystem
+// must be initialized.
+func checkWorkspaces() {
+	reports, err := ipfs.RefreshWorkspaces()
+	if err != nil {
+		fmt.Printf("[Unable to check the workspaces referenced by the node: %v]\n", err)
+		return
+	}
+	for _, report := range reports {
+		switch {
+		case report.Missing && report.Refs > 0:
+			fmt.Printf("The workspace %v is missing, %d referenced file(s) can't be provided "+
+				"until it is back.\n", report.Path, report.Refs)
+		case report.MissingFiles > 0:
+			fmt.Printf("%d of %d referenced file(s) in %v are missing.\n",
+				report.MissingFiles, report.Refs, report.Path)
+		case report.Relinked:
+			fmt.Printf("Repaired the link to the workspace %v.\n", report.Path)
+		}
+	}
+}
@@ -2,10 +2,11 @@ package config
 
 import (
 	"io/ioutil"
-	"log"
 	"os"
 	"os/user"
 	"path/filepath"
+	"strings"
+	"time"
 
 	"github.com/arken/ait/utils"
 
@@ -14,15 +15,64 @@ import (
 
 // Config defines the configuration struct for importing settings from TOML.
 type Config struct {
-	General general
-	Git     git
-	IPFS    ipfs
+	General   general
+	Git       git
+	IPFS      ipfs
+	DNSLink   dnslink
+	SMTP      smtp
+	Trust     trust
+	Scan      scan
+	Metadata  metadata
+	Community community
+	DOI       doi
+	Notify    notify
+	Ingest    ingest
+	Network   network
+	// Aliases are extra commands expanded to a command line, ie
+	// sp = "submit --pull-request".
+	Aliases map[string]string
+	// Defaults are flags added whenever a command is run, by command name,
+	// ie submit = "--pull-request". "%date" is replaced by today's date.
+	Defaults map[string]string
 }
 
 // general defines the substruct about general application settings.
 type general struct {
 	Version string
 	Editor  string
+	// Retention is the number of generated keysets and cloned keyset sources
+	// to keep for debugging. 0 deletes them as soon as they're not needed.
+	Retention int
+	// TransparencyLog is the URL of a Rekor server signed submission receipts
+	// are appended to, ie "https://rekor.sigstore.dev". Empty disables them.
+	TransparencyLog string
+	// Gateway is the public IPFS gateway submitted files are linked through
+	// after a submission, ie "https://ipfs.io".
+	Gateway string
+	// Contact is an email address or URL added to the User-Agent of every
+	// request, so data providers can reach you about your usage.
+	Contact string
+	// TempDir is where submissions generate their keysets, ie on a larger
+	// scratch disk. Empty uses the workspace's .ait directory.
+	TempDir string
+	// MinFreeSpace is how much space (ie "512MB") must be left on a disk
+	// after a submission or clone writes to it, or it isn't started.
+	MinFreeSpace string
+	// TrashPeriod is how long unstaged files can be staged again with
+	// "ait unstage --undo", ie "168h". "0" forgets them right away.
+	TrashPeriod string
+	// PromptTimeout is how long prompts wait for an answer, ie "30m", on
+	// unattended terminals. Empty waits forever.
+	PromptTimeout string
+	// PromptTimeoutAction is what happens once PromptTimeout passes:
+	// "abort" stops the command, "default" uses the prompt's default answer.
+	PromptTimeoutAction string
+	// Workers is how many files are hashed, added or uploaded at once. 0
+	// uses one less than the number of CPUs.
+	Workers int
+	// KeysetFormat is the format keysets are generated and submitted in:
+	// "ksv", the one repositories read, "json" or "csv".
+	KeysetFormat string
 }
 
 // git defines git specific config settings.
@@ -30,12 +80,254 @@ type git struct {
 	Name    string
 	Email   string
 	Remotes map[string]string
-	PAT     string
+	// RemoteOptions are the branch and provider of the remote aliases that
+	// don't use the defaults of their repository, by alias.
+	RemoteOptions map[string]RemoteOptions
+	PAT           string
+	// SSHKeyPath is the private key keyset repositories cloned over SSH are
+	// reached with. Empty tries the usual keys of ~/.ssh, then ssh-agent.
+	SSHKeyPath string
+	// EmailDomains are the domains the organizations discovered with "ait
+	// remote discover" expect the email addresses of their members at.
+	EmailDomains []string
+	// Tokens are the access tokens of GitLab and Gitea hosts, by host, ie
+	// "gitlab.mylab.org". GitHub uses PAT.
+	Tokens map[string]string
+	// SigningKey signs the commits of keysets, like git's user.signingKey:
+	// the ID of a GPG key, or the path of an SSH private key when
+	// SigningFormat is "ssh". Empty leaves them unsigned.
+	SigningKey string
+	// SigningFormat is how SigningKey signs, "openpgp" with gpg or "ssh"
+	// with ssh-keygen.
+	SigningFormat string
+	// SignKeysets commits a detached signature of each keyset next to it, ie
+	// survey.ks.asc, so its entries can be checked without the history.
+	SignKeysets bool
 }
 
 // ipfs defines the IPFS centric ait settings.
 type ipfs struct {
 	Path string
+	// RelayWarn is the amount of relayed traffic (ie "5GB") after which the
+	// user is warned that they are leaning heavily on the shared relay.
+	RelayWarn string
+	// RelayThrottle disconnects from the relay for the rest of the session
+	// once RelayWarn has been exceeded.
+	RelayThrottle bool
+	// Migrate decides what happens when the IPFS repository needs to be
+	// upgraded: "prompt", "auto" or "never".
+	Migrate string
+	// MigrateBackup copies the IPFS repository aside before migrating it.
+	MigrateBackup bool
+	// StorageMax is the largest size (ie "100TB") the repository may grow to.
+	StorageMax string
+	// StorageGCWatermark is the percentage of StorageMax at which unpinned
+	// blocks are garbage collected and the user is warned.
+	StorageGCWatermark int64
+	// StorageWarn is the size of the repository (ie "500GB") past which every
+	// command warns that "ait gc" should be run. Empty disables the warning.
+	StorageWarn string
+	// GCPeriod is how often long running nodes check the watermark (ie "1h").
+	GCPeriod string
+	// ScrubPeriod is how often long running nodes re-verify every block in
+	// the repository and repair what they can (ie "168h"). Empty disables it.
+	ScrubPeriod string
+	// ReprovideInterval is how long long running nodes take to announce
+	// every pinned root again, in batches spread over the interval with
+	// recent and at-risk submissions first (ie "12h"). Empty leaves it to
+	// IPFS's own reprovider, which announces everything at once every hour.
+	ReprovideInterval string
+	// Layout is the DAG layout files are added with: "balanced", suited to
+	// seeking in static files, or "trickle", suited to streaming data.
+	Layout string
+	// Inline stores files and blocks of at most InlineLimit bytes in their
+	// CID instead of a block, for datasets of millions of tiny files.
+	Inline      bool
+	InlineLimit int
+	// ProvideOnly runs a lightweight node that provides its own pinned data
+	// and peers with the cluster, but doesn't serve the DHT for others.
+	ProvideOnly bool
+	// DHTClient only queries the DHT, without serving it for others, while
+	// keeping the connections of a full node.
+	DHTClient bool
+	// ConnMgrLowWater and ConnMgrHighWater override the number of
+	// connections the node trims down to, and starts trimming at. 0 leaves
+	// them to IPFS's defaults, or those of ProvideOnly.
+	ConnMgrLowWater  int
+	ConnMgrHighWater int
+	// MaxUpload and MaxDownload cap the bandwidth of the node, in bytes per
+	// second shared by all its connections (ie "2MB"). Empty is unlimited.
+	// Limits only apply to TCP, QUIC is disabled while they're set.
+	MaxUpload   string
+	MaxDownload string
+	// DenyPeers are peer IDs the node never stays connected to.
+	DenyPeers []string
+	// DenyCIDRs are address ranges (ie "10.0.0.0/8") the node never dials
+	// or accepts connections from.
+	DenyCIDRs []string
+	// ClusterOnly drops every connection except to the Arken bootstrapper,
+	// the relay and AllowPeers, for networks with strict egress policies.
+	ClusterOnly bool
+	// AllowPeers are extra peer IDs, ie the institution's own cluster nodes,
+	// allowed when ClusterOnly is set.
+	AllowPeers []string
+	// BootstrapPeers and RelayPeers are the multiaddrs, ending in the peer ID,
+	// of the bootstrappers and circuit relays the node connects to, ie those
+	// of a private Arken cluster. Left to their defaults they follow the
+	// Network.Transport and the rotations of the Arken nodes' identities.
+	BootstrapPeers []string
+	RelayPeers     []string
+	// ReachabilityTimeout is the longest online commands wait at startup to
+	// find out whether the node can be reached, before using a relay
+	// (ie "30s"). It's usually known much sooner.
+	ReachabilityTimeout string
+	// Backend is the IPFS node ait works through: "embedded" runs one inside
+	// ait, "http" uses the daemon serving the HTTP API at APIAddr and "auto"
+	// uses that daemon whenever it's running, the embedded node otherwise.
+	Backend string
+	// APIAddr is the multiaddr of the external daemon's HTTP API.
+	APIAddr string
+	// Profiles maps profile names to separate IPFS repositories so that
+	// different workspaces don't share an identity or pinset.
+	Profiles map[string]string
+}
+
+// dnslink defines the optional DNSLink record updated after a submission.
+type dnslink struct {
+	// Domain is the name (ie "dataset.mylab.org") whose _dnslink TXT record
+	// should point at the latest submitted keyset. Empty disables DNSLink.
+	Domain string
+	// Provider is the DNS hosting service managing Domain. Only "cloudflare"
+	// is currently supported.
+	Provider string
+	// Zone is the provider's identifier for the DNS zone. It is looked up
+	// from Domain when left empty.
+	Zone string
+	// Token is an API token allowed to edit the zone's records.
+	Token string
+	// Key is an optional IPNS key name. When set the keyset is published
+	// under the key and the record points at its IPNS name instead.
+	Key string
+}
+
+// smtp defines the mail server used to submit to mailto: remotes.
+type smtp struct {
+	// Host is the server address including its port, ie "smtp.example.org:587".
+	Host     string
+	Username string
+	Password string
+}
+
+// trust defines the keys keyset repositories must be signed with.
+type trust struct {
+	// Keys maps a name to an armored PGP public key whose signatures on
+	// keyset repository commits are trusted.
+	Keys map[string]string
+	// Policy decides what happens when a keyset repository's latest commit
+	// isn't signed by a trusted key: "off", "warn" or "require".
+	Policy string
+	// Hosts pins provider hosts (ie "api.github.com") to the base64 SHA-256
+	// hashes of public keys one of their certificates must match.
+	Hosts map[string][]string
+	// Signers are the IPFS peer IDs of the technicians whose bundles ait
+	// review-bundle accepts without --signer.
+	Signers []string
+}
+
+// scan defines the optional scanner run on every file ait pull downloads.
+type scan struct {
+	// Command is the scanner to run, ie "clamscan --no-summary {}". "{}" is
+	// replaced by the downloaded file. Empty disables scanning.
+	Command string
+	// Quarantine is where files the scanner rejects are moved.
+	Quarantine string
+}
+
+// metadata defines the optional command collecting extra metadata on every
+// file a keyset is generated for.
+type metadata struct {
+	// Command is run on every staged file as its keyset entry is generated,
+	// ie "fitsmeta {}". "{}" is replaced by the file. It prints a JSON object
+	// of fields, ie FITS headers or a LIMS checksum, which are recorded with
+	// the submission and in its RO-Crate. Empty disables it.
+	Command string
+}
+
+// community defines the opt-in sharing of seeding statistics.
+type community struct {
+	// Share sends anonymized seeding statistics to Endpoint after uploads.
+	Share    bool
+	Endpoint string
+	// ID is the random identifier statistics are reported under. It is
+	// generated when sharing is first enabled.
+	ID string
+}
+
+// doi defines the optional registration of submitted datasets for a DOI.
+type doi struct {
+	// Registry is "datacite" or "zenodo". Empty disables minting DOIs.
+	Registry string
+	// Server overrides the registry's API, ie to use its test instance.
+	Server string
+	// Prefix is the DataCite DOI prefix, ie "10.12345".
+	Prefix string
+	// Username is the DataCite repository ID.
+	Username string
+	// Token is the DataCite repository password or the Zenodo access token.
+	Token string
+	// Publisher is recorded as the publisher of minted DOIs.
+	Publisher string
+}
+
+// notify defines where alerts are sent.
+type notify struct {
+	// Webhook is a URL alerts are posted to as JSON.
+	Webhook string
+	// Log is a file alerts are appended to.
+	Log string
+	// Desktop shows alerts as desktop notifications.
+	Desktop bool
+	// Operations shows a desktop notification when staging, submitting,
+	// pulling or uploading finishes or fails after at least OperationsAfter
+	// (ie "5m").
+	Operations      bool
+	OperationsAfter string
+	// AtRiskPeriod is how often long running nodes check whether datasets
+	// submitted from the workspace are still safely replicated (ie "1h").
+	AtRiskPeriod string
+	// AtRiskThreshold is the number of peers that must provide a file for it
+	// to be considered safely replicated.
+	AtRiskThreshold int
+}
+
+// ingest defines how data is fetched from institutional storage.
+type ingest struct {
+	// GlobusEndpoint is the ID of this machine's Globus Connect Personal
+	// endpoint, which Globus transfers are made to.
+	GlobusEndpoint string
+	// WatchPeriod is how often "ait watch" checks the watched URLs for
+	// changes, ie "6h".
+	WatchPeriod string
+}
+
+// network defines how ait gets out of restricted networks.
+type network struct {
+	// Transport is how the IPFS node connects to peers: "default", or
+	// "websocket" to reach the Arken nodes over WebSockets on port 443, or
+	// "socks5" to dial every peer through Proxy.
+	Transport string
+	// Proxy is a SOCKS5 proxy, ie "socks5://127.0.0.1:9050" for Tor, that
+	// every HTTP request is sent through. Empty uses the proxy environment
+	// variables.
+	Proxy string
+	// PeersURL publishes the current identities of the Arken bootstrapper
+	// and relay, as a chain of manifests each signed by the identities
+	// before it, so they can rotate without a new release. Empty only uses
+	// the identities ait was released with, or last fetched.
+	PeersURL string
+	// Retries is how many times requests to GitHub, GitLab and Gitea that
+	// failed for a transient reason are sent again, waiting longer each time.
+	Retries int
 }
 
 var (
@@ -44,40 +336,129 @@ var (
 	Path   string
 )
 
-// initialize the app config system. If a config doesn't exist, create one.
-// If the config is out of date read the current config and rebuild with new fields.
-func init() {
-	// Determine the current user to build expected file path.
-	user, err := user.Current()
-	utils.CheckError(err)
-	// Create expected config path.
-	Path = filepath.Join(user.HomeDir, ".ait", "ait.config")
-	readConf(&Global)
+// fileConf is the config file as Global was last read from or written to
+// it, which GenConf tells the settings changed since from.
+var fileConf *Config
+
+// HomeFlag is the global flag naming the directory ait keeps its config, IPFS
+// repository and cloned sources in instead of ~/.ait, ie on a scratch
+// filesystem when the home directory is read-only. It is read before the
+// command line is parsed, as the config is loaded first. The AIT_HOME
+// environment variable does the same.
+const HomeFlag = "--home"
+
+// StateDir returns the directory ait keeps its state in: the one given with
+// HomeFlag in args, or by AIT_HOME, or ~/.ait.
+func StateDir(args []string) (string, error) {
+	dir, ok := os.LookupEnv("AIT_HOME")
+	for i, arg := range args {
+		if arg == "--" {
+			break
+		}
+		if arg == HomeFlag && i+1 < len(args) {
+			dir, ok = args[i+1], true
+		} else if strings.HasPrefix(arg, HomeFlag+"=") {
+			dir, ok = strings.TrimPrefix(arg, HomeFlag+"="), true
+		}
+	}
+	if ok && dir != "" {
+		return filepath.Abs(utils.ExpandHome(dir))
+	}
+	usr, err := user.Current()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(usr.HomeDir, ".ait"), nil
+}
+
+// SourcePath returns where the keyset repository at url is cloned to.
+func SourcePath(url string) string {
+	return filepath.Join(filepath.Dir(Path), "sources", utils.GetRepoName(url))
+}
+
+// Load reads the config in the state directory home, ~/.ait or the one given
+// by AIT_HOME when empty, creating it or upgrading it to the current version
+// first, and applies it. With workspace, the overrides and IPFS profile of
+// the workspace in the working directory apply too. Programs embedding ait
+// load the config before using it, as the commands do when they start.
+func Load(home string, workspace bool) (err error) {
+	if home == "" {
+		if home, err = StateDir(nil); err != nil {
+			return err
+		}
+	}
+	Path = filepath.Join(home, "ait.config")
+	utils.RegistryPath = filepath.Join(home, "registry.jsonl")
+	Global = Config{}
+	fileConf = nil
+	if err = readConf(&Global); err != nil {
+		return err
+	}
 	// If the configuration version has changed update the config to the new
 	// format while keeping the user's preferences.
 	if Global.General.Version != defaultConf().General.Version {
-		reloadConf()
-		readConf(&Global)
+		if err = reloadConf(); err != nil {
+			return err
+		}
+		if err = readConf(&Global); err != nil {
+			return err
+		}
+	}
+	file, err := cloneConf(Global)
+	if err != nil {
+		return err
+	}
+	fileConf = &file
+	workspaceKeys = nil
+	if workspace {
+		if err = readWorkspaceConf(&Global); err != nil {
+			return err
+		}
 	}
 	ConsolidateEnvVars(&Global)
+	utils.Retention = Global.General.Retention
+	utils.TempDir = Global.General.TempDir
+	utils.MinFreeSpace, _ = utils.ParseByteSize(Global.General.MinFreeSpace)
+	utils.TrashPeriod, _ = time.ParseDuration(Global.General.TrashPeriod)
+	utils.PromptTimeout, _ = time.ParseDuration(Global.General.PromptTimeout)
+	utils.PromptDefault = Global.General.PromptTimeoutAction == "default"
+	utils.Workers = Global.General.Workers
+	utils.Retries = Global.Network.Retries
+	baseIPFSPath = Global.IPFS.Path
 
-	err = createSwarmKey()
+	if workspace {
+		err = SelectProfile()
+	} else {
+		err = UseProfile(os.Getenv("AIT_PROFILE"))
+	}
 	if err != nil {
-		log.Fatal(err)
+		return err
 	}
+	return createSwarmKey()
 }
 
 // Read the config or create a new one if it doesn't exist.
-func readConf(conf *Config) {
-	_, err := toml.DecodeFile(Path, &conf)
+func readConf(conf *Config) error {
+	data, err := ioutil.ReadFile(Path)
 	if os.IsNotExist(err) {
-		GenConf(defaultConf())
-		genApplication(defaultApplication())
-		readConf(conf)
+		if err = genConf(defaultConf()); err != nil {
+			return err
+		}
+		if err = genApplication(defaultApplication()); err != nil {
+			return err
+		}
+		return readConf(conf)
 	}
-	if err != nil && !os.IsNotExist(err) {
-		utils.FatalPrintln(err)
+	if err != nil {
+		return err
 	}
+	return decodeConf(data, conf)
+}
+
+// decodeConf decodes the TOML of a config into conf.
+func decodeConf(data []byte, conf *Config) error {
+	_, err := toml.Decode(string(data), conf)
+	return err
 }
 
 func createSwarmKey() (err error) {
@@ -93,8 +474,29 @@ func createSwarmKey() (err error) {
 // GetRemote takes a string and returns what should be a URL. If the string is
 // a key in Global.Git.Remotes, then its value will be returned. If it itself
 // a url, it will be returned untouched. This is to allow the arbitrary
-// substitution of real remote URLs and remote aliases.
+// substitution of real remote URLs and remote aliases. Either way the
+// insteadOf rules of the user's git config are applied, like git would.
 func GetRemote(remote string) string {
+	return utils.RewriteURL(lookupRemote(remote), false)
+}
+
+// GetPushRemote is GetRemote for a remote that will be submitted to, so the
+// pushInsteadOf rules of the user's git config are applied too. Submissions go
+// through the GitHub API, so pushInsteadOf rules to SSH URLs are ignored.
+func GetPushRemote(remote string) string {
+	url := lookupRemote(remote)
+	if pushed := utils.RewriteURL(url, true); strings.HasPrefix(pushed, "https://") {
+		return pushed
+	}
+	return utils.RewriteURL(url, false)
+}
+
+// lookupRemote returns the URL of a remote alias, or remote if it isn't one.
+// The aliases of the workspace take precedence over those of the config.
+func lookupRemote(remote string) string {
+	if url, ok := WorkspaceRemotes()[remote]; ok {
+		return url
+	}
 	url, ok := Global.Git.Remotes[remote]
 	if ok {
 		return url
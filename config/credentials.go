@@ -0,0 +1,83 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"sync"
+
+	"github.com/arken/ait/platform"
+)
+
+// githubAccount names the GitHub token in the credentials store.
+const githubAccount = "github-token"
+
+// Credentials is where secrets are kept, the system keychain unless it's
+// unavailable, ie on a headless Linux keeper without a Secret Service.
+var Credentials = platform.SystemKeychain()
+
+// plaintextWarning warns, once, that the token is read from the config file.
+var plaintextWarning sync.Once
+
+// GitHubToken returns the GitHub token: the one AIT_GIT_PAT gives, the one
+// saved in the credentials store or, as a last resort, PAT in the config file.
+// Nothing is saved, MigrateGitHubToken moves a token of the config file to the
+// credentials store.
+func GitHubToken() string {
+	if token := os.Getenv("AIT_GIT_PAT"); token != "" {
+		return token
+	}
+	token, err := Credentials.Get(githubAccount)
+	if err == nil && token != "" {
+		return token
+	}
+	if Global.Git.PAT == "" {
+		return ""
+	}
+	plaintextWarning.Do(func() {
+		if err == platform.ErrNoSecret {
+			fmt.Printf("Warning: the GitHub token is stored in plain text in %v, run \"ait setup\" to "+
+				"move it to the system keychain.\n", Path)
+			return
+		}
+		fmt.Printf("Warning: the GitHub token is stored in plain text in %v, the system keychain "+
+			"is unavailable (%v).\n", Path, err)
+	})
+	return Global.Git.PAT
+}
+
+// MigrateGitHubToken moves the GitHub token saved in plain text in the config
+// file, by an earlier version or while there was no keychain, to the
+// credentials store. It returns whether a token was moved. A token the
+// credentials store already holds isn't replaced.
+func MigrateGitHubToken() (bool, error) {
+	file, err := LoadConf(false)
+	if err != nil || file.Git.PAT == "" {
+		return false, err
+	}
+	if saved, err := Credentials.Get(githubAccount); err == nil && saved != "" && saved != file.Git.PAT {
+		return false, nil
+	}
+	if err = Credentials.Set(githubAccount, file.Git.PAT); err != nil {
+		return false, err
+	}
+	Global.Git.PAT = ""
+	return true, genConf(Global)
+}
+
+// SaveGitHubToken saves the GitHub token in the credentials store. If it's
+// unavailable the token is saved in plain text in the config file instead,
+// with a warning.
+func SaveGitHubToken(token string) {
+	err := Credentials.Set(githubAccount, token)
+	if err == nil {
+		if Global.Git.PAT != "" {
+			Global.Git.PAT = ""
+			GenConf(Global)
+		}
+		return
+	}
+	fmt.Printf("Warning: the system keychain is unavailable (%v), the GitHub token is stored in "+
+		"plain text in %v instead. Anyone able to read it can act on your GitHub account.\n", err, Path)
+	Global.Git.PAT = token
+	GenConf(Global)
+}
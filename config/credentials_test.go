@@ -0,0 +1,74 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/arken/ait/platform"
+)
+
+// memoryKeychain is a keychain holding its secrets in memory.
+type memoryKeychain map[string]string
+
+func (k memoryKeychain) Get(account string) (string, error) {
+	if secret, ok := k[account]; ok {
+		return secret, nil
+	}
+	return "", platform.ErrNoSecret
+}
+
+func (k memoryKeychain) Set(account, secret string) error {
+	k[account] = secret
+	return nil
+}
+
+func (k memoryKeychain) Delete(account string) error {
+	delete(k, account)
+	return nil
+}
+
+func TestGitHubToken(t *testing.T) {
+	defer func(path string, credentials platform.Keychain, global Config) {
+		Path, Credentials, Global = path, credentials, global
+	}(Path, Credentials, Global)
+	Path = filepath.Join(t.TempDir(), "ait.config")
+	keychain := memoryKeychain{}
+	Credentials = keychain
+	Global = defaultConf()
+	Global.Git.PAT = "from-file"
+	GenConf(Global)
+
+	// Reading the token leaves the keychain and the config alone.
+	if token := GitHubToken(); token != "from-file" {
+		t.Errorf("GitHubToken() = %q, expected the config's", token)
+	}
+	if len(keychain) != 0 {
+		t.Error("expected reading the token not to save it")
+	}
+
+	os.Setenv("AIT_GIT_PAT", "from-env")
+	defer os.Unsetenv("AIT_GIT_PAT")
+	keychain[githubAccount] = "from-keychain"
+	if token := GitHubToken(); token != "from-env" {
+		t.Errorf("GitHubToken() = %q, expected AIT_GIT_PAT to take precedence", token)
+	}
+	// A token given by the environment is never written to the config.
+	Global.Git.PAT = "from-env"
+	GenConf(Global)
+	if conf, _ := LoadConf(false); conf.Git.PAT != "from-file" {
+		t.Errorf("the config has PAT %q, expected the one it had", conf.Git.PAT)
+	}
+
+	delete(keychain, githubAccount)
+	moved, err := MigrateGitHubToken()
+	if err != nil || !moved {
+		t.Fatalf("MigrateGitHubToken() = %v, %v", moved, err)
+	}
+	if keychain[githubAccount] != "from-file" {
+		t.Errorf("the keychain holds %q, expected the config's token", keychain[githubAccount])
+	}
+	if conf, _ := LoadConf(false); conf.Git.PAT != "" {
+		t.Errorf("expected the token to be removed from the config, got %q", conf.Git.PAT)
+	}
+}
@@ -1,16 +1,25 @@
 package config
 
 import (
-	"bytes"
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"reflect"
 
 	"github.com/arken/ait/utils"
+)
 
-	"github.com/BurntSushi/toml"
+// The addresses of the Arken bootstrapper and relay new configs are written
+// with.
+const (
+	DefaultBootstrapPeer = "/dns4/link.arken.io/tcp/4001/p2p/12D3KooWSmosHZtDBbepxWwVgo8HyXSgNCUgs2GGD2qnQPbA3KhD"
+	DefaultRelayPeer     = "/dns4/relay.arken.io/tcp/4001/p2p/12D3KooWL7hvR7nfQxAWMowgoWXWQwKEkQA8QPZrhKjateRTgcDm"
 )
 
+// DefaultEditor is the editor applications are written in unless another is
+// configured.
+const DefaultEditor = "nano"
+
 // defaultConf defines the default values for AIT's configuration.
 func defaultConf() Config {
 	result := Config{
@@ -18,48 +27,221 @@ func defaultConf() Config {
 			// Configuration version number. If a field is added or changed
 			// in this default, the version must be changed to tell the app
 			// to rebuild the users config files.
-			Version: "0.1.0",
-			Editor:  "nano",
+			Version:             "0.1.45",
+			Editor:              DefaultEditor,
+			Retention:           0,
+			TransparencyLog:     "",
+			Gateway:             "https://ipfs.io",
+			Contact:             "",
+			TempDir:             "",
+			MinFreeSpace:        "512MB",
+			TrashPeriod:         "168h",
+			PromptTimeout:       "",
+			PromptTimeoutAction: "abort",
+			Workers:             0,
+			KeysetFormat:        "ksv",
 		},
 		Git: git{
-			Name:  "",
-			Email: "",
-			PAT:   "",
+			Name:          "",
+			Email:         "",
+			PAT:           "",
+			SigningFormat: "openpgp",
 		},
 		IPFS: ipfs{
-			Path: filepath.Join(filepath.Dir(Path), "ipfs"),
+			Path:                filepath.Join(filepath.Dir(Path), "ipfs"),
+			RelayWarn:           "5GB",
+			RelayThrottle:       false,
+			Migrate:             "prompt",
+			MigrateBackup:       false,
+			StorageMax:          "100TB",
+			GCPeriod:            "1h",
+			StorageGCWatermark:  90,
+			StorageWarn:         "",
+			ScrubPeriod:         "168h",
+			ReprovideInterval:   "12h",
+			Layout:              "balanced",
+			Inline:              false,
+			InlineLimit:         32,
+			ProvideOnly:         false,
+			DHTClient:           false,
+			ConnMgrLowWater:     0,
+			ConnMgrHighWater:    0,
+			MaxUpload:           "",
+			MaxDownload:         "",
+			DenyPeers:           []string{},
+			DenyCIDRs:           []string{},
+			ClusterOnly:         false,
+			AllowPeers:          []string{},
+			BootstrapPeers:      []string{DefaultBootstrapPeer},
+			RelayPeers:          []string{DefaultRelayPeer},
+			ReachabilityTimeout: "30s",
+			Backend:             "embedded",
+			APIAddr:             "/ip4/127.0.0.1/tcp/5001",
+		},
+		DNSLink: dnslink{
+			Domain:   "",
+			Provider: "cloudflare",
+			Zone:     "",
+			Token:    "",
+			Key:      "",
+		},
+		SMTP: smtp{
+			Host:     "",
+			Username: "",
+			Password: "",
+		},
+		Trust: trust{
+			Policy: "warn",
+		},
+		Scan: scan{
+			Command:    "",
+			Quarantine: filepath.Join(filepath.Dir(Path), "quarantine"),
+		},
+		Metadata: metadata{
+			Command: "",
 		},
+		Community: community{
+			Share:    false,
+			Endpoint: "https://stats.arken.io/v1",
+			ID:       "",
+		},
+		DOI: doi{
+			Registry:  "",
+			Server:    "",
+			Prefix:    "",
+			Username:  "",
+			Token:     "",
+			Publisher: "Arken",
+		},
+		Notify: notify{
+			Webhook:         "",
+			Log:             "",
+			Desktop:         false,
+			Operations:      false,
+			OperationsAfter: "5m",
+			AtRiskPeriod:    "1h",
+			AtRiskThreshold: 3,
+		},
+		Ingest: ingest{
+			GlobusEndpoint: "",
+			WatchPeriod:    "6h",
+		},
+		Network: network{
+			Transport: "default",
+			Proxy:     "",
+			PeersURL:  "https://arken.io/peers.json",
+			Retries:   3,
+		},
+		Aliases:  map[string]string{},
+		Defaults: map[string]string{},
 	}
 	return result
 }
 
-// GenConf encodes the values of the Config struct back into a TOML file.
+// GenConf encodes the values of the Config struct back into a TOML file. The
+// file is locked and replaced atomically so concurrent ait processes can't
+// clobber each other's writes. Settings overridden by the workspace are
+// written as the config file has them.
 func GenConf(conf Config) {
+	utils.CheckError(genConf(conf))
+}
+
+// genConf is GenConf returning the errors it runs into.
+func genConf(conf Config) error {
+	if len(workspaceKeys) > 0 {
+		conf = withoutOverrides(conf)
+	}
+	conf = withoutEnv(conf)
+	if ActiveProfile != "" {
+		// Never persist a profile's repository as the default one.
+		conf.IPFS.Path = baseIPFSPath
+	}
 	os.MkdirAll(filepath.Dir(Path), os.ModePerm)
-	buf := new(bytes.Buffer)
-	err := toml.NewEncoder(buf).Encode(conf)
-	utils.CheckError(err)
-	f, err := os.Create(Path)
-	utils.CheckError(err)
-	defer f.Close()
-	f.Write(buf.Bytes())
+	err := updateConf(func(file *Config) error {
+		if fileConf == nil {
+			*file = conf
+			return nil
+		}
+		// Only the settings changed since the config was read are written,
+		// over the file as it is now, so the ones another process saved
+		// in the meantime aren't reverted.
+		mergeChanges(reflect.ValueOf(file).Elem(), reflect.ValueOf(*fileConf), reflect.ValueOf(conf))
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if written, err := cloneConf(conf); err == nil {
+		fileConf = &written
+	}
+	return nil
+}
+
+// mergeChanges sets the settings of dst that changed from base to changed to
+// the ones of changed, table entries included.
+func mergeChanges(dst, base, changed reflect.Value) {
+	switch changed.Kind() {
+	case reflect.Struct:
+		for i := 0; i < changed.NumField(); i++ {
+			mergeChanges(dst.Field(i), base.Field(i), changed.Field(i))
+		}
+	case reflect.Map:
+		for _, k := range base.MapKeys() {
+			if !changed.MapIndex(k).IsValid() && !dst.IsNil() {
+				dst.SetMapIndex(k, reflect.Value{})
+			}
+		}
+		for _, k := range changed.MapKeys() {
+			old := base.MapIndex(k)
+			if !old.IsValid() {
+				old = reflect.Zero(changed.Type().Elem())
+			}
+			value := changed.MapIndex(k)
+			if sameSetting(old, value) {
+				continue
+			}
+			if dst.IsNil() {
+				dst.Set(reflect.MakeMap(dst.Type()))
+			}
+			entry := reflect.New(value.Type()).Elem()
+			if existing := dst.MapIndex(k); existing.IsValid() {
+				entry.Set(existing)
+			}
+			mergeChanges(entry, old, value)
+			dst.SetMapIndex(k, entry)
+		}
+	default:
+		if !sameSetting(base, changed) {
+			dst.Set(changed)
+		}
+	}
+}
+
+// sameSetting returns whether a and b hold the same setting, an empty list
+// being the same as none.
+func sameSetting(a, b reflect.Value) bool {
+	if a.Kind() == reflect.Slice && a.Len() == 0 && b.Len() == 0 {
+		return true
+	}
+	return reflect.DeepEqual(a.Interface(), b.Interface())
 }
 
 // genApplication creates a default application.md file in ~/.ait/ using the
 // default definition returned by defaultApplication
-func genApplication(appBytes []byte) {
+func genApplication(appBytes []byte) error {
 	appPath := filepath.Join(filepath.Dir(Path), "application.md")
-	err := ioutil.WriteFile(appPath, appBytes, 0644)
-	utils.CheckError(err)
+	return ioutil.WriteFile(appPath, appBytes, 0644)
 }
 
 // reloadConf imports the users config onto a default config and then rewrites
 // the configuration file.
-func reloadConf() {
+func reloadConf() error {
 	result := defaultConf()
-	readConf(&result)
+	if err := readConf(&result); err != nil {
+		return err
+	}
 	result.General.Version = defaultConf().General.Version
-	GenConf(result)
+	return genConf(result)
 }
 
 // defaultApplication defines the default file to be used as an application prompt
@@ -4,9 +4,45 @@ import (
 	"fmt"
 	"os"
 	"reflect"
+	"strconv"
 	"strings"
 )
 
+// envSections are the sections of the config the environment overrides, with
+// the prefix of their variables.
+func envSections(conf *Config) map[string]reflect.Value {
+	return map[string]reflect.Value{
+		"AIT_GIT_":     reflect.ValueOf(&conf.Git).Elem(),
+		"AIT_GENERAL_": reflect.ValueOf(&conf.General).Elem(),
+	}
+}
+
+// withoutEnv returns conf with the settings the environment still overrides
+// set back to the ones of the config file, so secrets and other values given
+// for a single run, ie AIT_GIT_PAT, are never written to it. Settings changed
+// since are kept.
+func withoutEnv(conf Config) Config {
+	var file *Config
+	for prefix, section := range envSections(&conf) {
+		for j := 0; j < section.NumField(); j++ {
+			name := section.Type().Field(j).Name
+			value, ok := os.LookupEnv(prefix + strings.ToUpper(name))
+			if !ok || fmt.Sprint(section.Field(j).Interface()) != value {
+				continue
+			}
+			if file == nil {
+				loaded, err := LoadConf(false)
+				if err != nil {
+					return conf
+				}
+				file = &loaded
+			}
+			section.Field(j).Set(envSections(file)[prefix].Field(j))
+		}
+	}
+	return conf
+}
+
 // ConsolidateEnvVars looks for discrepancies between environment variables and the
 // internal config struct, preferring the value set in the environment variable.
 // In this function, variables starting with "field" track values associated
@@ -26,13 +62,26 @@ func ConsolidateEnvVars(conf *Config) {
 		}
 		structType := iter.Type()
 		for j := 0; j < iter.NumField(); j++ {
-			fieldVal := iter.Field(j).String()
+			fieldVal := fmt.Sprint(iter.Field(j).Interface())
 			if fieldVal != "Version" {
 				fieldName := structType.Field(j).Name
 				evName := evPrefix + strings.ToUpper(fieldName)
 				evVal, evExists := os.LookupEnv(evName)
 				if evExists && evVal != fieldVal {
-					iter.FieldByName(fieldName).SetString(evVal)
+					field := iter.FieldByName(fieldName)
+					switch field.Kind() {
+					case reflect.String:
+						field.SetString(evVal)
+					case reflect.Int, reflect.Int64:
+						num, err := strconv.ParseInt(evVal, 10, 64)
+						if err != nil {
+							fmt.Printf("Env. var. \"%v\" is not a number, ignoring it.\n", evName)
+							continue
+						}
+						field.SetInt(num)
+					default:
+						continue
+					}
 					fmt.Printf("Env. var. \"%v\" does not match internal"+
 						" memory. Updating memory value to \"%v\".\n", evName, evVal)
 				}
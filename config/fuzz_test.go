@@ -0,0 +1,32 @@
+//go:build go1.18
+// +build go1.18
+
+package config
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/BurntSushi/toml"
+)
+
+func FuzzDecodeConf(f *testing.F) {
+	buf := &bytes.Buffer{}
+	if err := toml.NewEncoder(buf).Encode(defaultConf()); err != nil {
+		f.Fatal(err)
+	}
+	f.Add(buf.Bytes(), "origin")
+	f.Add([]byte("[Git.Remotes]\norigin = \"https://github.com/arken/ait\"\n"), "origin")
+	f.Add([]byte("[IPFS]\nStorageGCWatermark = -1\nDenyCIDRs = [\"10.0.0.0/8\"]\n"), "")
+	f.Fuzz(func(t *testing.T, data []byte, remote string) {
+		conf := Config{}
+		if decodeConf(data, &conf) != nil {
+			return
+		}
+		Validate(conf)
+		// Resolving a remote must not fail on the remotes of any config.
+		defer func(remotes map[string]string) { Global.Git.Remotes = remotes }(Global.Git.Remotes)
+		Global.Git.Remotes = conf.Git.Remotes
+		lookupRemote(remote)
+	})
+}
@@ -0,0 +1,321 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/arken/ait/utils"
+
+	ma "github.com/multiformats/go-multiaddr"
+)
+
+// A key names a setting as "section.field", ie "git.name" or "ipfs.path",
+// case-insensitively. The entries of tables are named after their key, ie
+// "git.remotes.core" or "git.remoteoptions.core.branch", and "remotes" is
+// short for "git.remotes". Lists are written comma separated.
+
+// keyShorthands expand the first part of a key.
+var keyShorthands = map[string]string{
+	"remotes": "git.remotes",
+}
+
+// readOnlyKeys are managed by ait.
+var readOnlyKeys = map[string]bool{
+	"General.Version": true,
+}
+
+// secretKeys are hidden by ListKeys, their value is only shown by asking
+// for it.
+var secretKeys = map[string]bool{
+	"Git.PAT":        true,
+	"Git.Tokens":     true,
+	"DNSLink.Token":  true,
+	"SMTP.Password":  true,
+	"DOI.Token":      true,
+	"Notify.Webhook": true,
+}
+
+// keyChecks validate the values of keys that Validate doesn't look at.
+var keyChecks = map[string]func(string) error{
+	"IPFS.APIAddr":        checkMultiaddr,
+	"IPFS.BootstrapPeers": checkMultiaddr,
+	"IPFS.RelayPeers":     checkMultiaddr,
+	"Git.Remotes":         checkRemoteURL,
+}
+
+// checkMultiaddr checks that addr is a multiaddr, ie "/ip4/127.0.0.1/tcp/5001".
+func checkMultiaddr(addr string) error {
+	_, err := ma.NewMultiaddr(addr)
+	return err
+}
+
+// checkRemoteURL checks that a remote is a single URL.
+func checkRemoteURL(url string) error {
+	if url == "" || strings.ContainsAny(url, " \t\n") {
+		return fmt.Errorf("%q isn't the URL of a repository", url)
+	}
+	return nil
+}
+
+// KeyValue is a setting and its value, as ListKeys lists them.
+type KeyValue struct {
+	Key   string `json:"key"`
+	Value string `json:"value"`
+}
+
+// resolvedKey is where a key leads in the config.
+type resolvedKey struct {
+	// path is the field names leading to the setting and the key of the
+	// table entry, if any, ie ["Git", "Remotes", "core"].
+	path []string
+	// field is the name of the setting among the fields, ie "Git.Remotes".
+	field string
+}
+
+// resolveKey finds the setting named by key in the type of Config.
+func resolveKey(key string) (resolvedKey, error) {
+	parts := strings.Split(key, ".")
+	if expanded, ok := keyShorthands[strings.ToLower(parts[0])]; ok {
+		parts = append(strings.Split(expanded, "."), parts[1:]...)
+	}
+	var resolved resolvedKey
+	var fields []string
+	t := reflect.TypeOf(Config{})
+	for i := 0; i < len(parts); i++ {
+		switch t.Kind() {
+		case reflect.Struct:
+			f, ok := t.FieldByNameFunc(func(name string) bool { return strings.EqualFold(name, parts[i]) })
+			if !ok {
+				return resolved, fmt.Errorf("unknown key %q", key)
+			}
+			resolved.path = append(resolved.path, f.Name)
+			fields = append(fields, f.Name)
+			t = f.Type
+		case reflect.Map:
+			// Table keys may hold dots, ie host names, only the last part
+			// names a field of tables of structs.
+			end := len(parts)
+			if t.Elem().Kind() == reflect.Struct {
+				end--
+			}
+			if end <= i {
+				return resolved, fmt.Errorf("%q needs the key of an entry and a field", key)
+			}
+			resolved.path = append(resolved.path, strings.Join(parts[i:end], "."))
+			i = end - 1
+			t = t.Elem()
+		default:
+			return resolved, fmt.Errorf("unknown key %q", key)
+		}
+	}
+	switch t.Kind() {
+	case reflect.Struct:
+		return resolved, fmt.Errorf("%q is a section, name one of its settings", key)
+	case reflect.Map:
+		return resolved, fmt.Errorf("%q is a table, name one of its entries as %v.<key>", key, key)
+	}
+	resolved.field = strings.Join(fields, ".")
+	return resolved, nil
+}
+
+// GetKey returns the value of the setting named by key in conf.
+func GetKey(conf Config, key string) (string, error) {
+	resolved, err := resolveKey(key)
+	if err != nil {
+		return "", err
+	}
+	v := reflect.ValueOf(conf)
+	for _, name := range resolved.path {
+		switch v.Kind() {
+		case reflect.Struct:
+			v = v.FieldByName(name)
+		case reflect.Map:
+			v = v.MapIndex(reflect.ValueOf(name))
+			if !v.IsValid() {
+				return "", fmt.Errorf("%q isn't set", key)
+			}
+		}
+	}
+	return formatValue(v), nil
+}
+
+// SetKey sets the setting named by key in conf to value, parsed as the type
+// of the setting, and returns the path of field names and table keys leading
+// to it along with the parsed value. The value is checked, but not conf as a
+// whole, which Validate does. conf may be changed even if the value is
+// rejected.
+func SetKey(conf *Config, key, value string) ([]string, interface{}, error) {
+	resolved, err := resolveKey(key)
+	if err != nil {
+		return nil, nil, err
+	}
+	if readOnlyKeys[resolved.field] {
+		return nil, nil, fmt.Errorf("%q is managed by ait", key)
+	}
+	parsed, err := setPath(reflect.ValueOf(conf).Elem(), resolved.path, value)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%v: %v", key, err)
+	}
+	if check, ok := keyChecks[resolved.field]; ok {
+		items, isList := parsed.([]string)
+		if !isList {
+			items = []string{value}
+		}
+		for _, item := range items {
+			if err := check(item); err != nil {
+				return nil, nil, fmt.Errorf("%v: %v", key, err)
+			}
+		}
+	}
+	return resolved.path, parsed, nil
+}
+
+// UnsetKey removes the table entry named by key from conf, or resets the
+// setting it names to its default.
+func UnsetKey(conf *Config, key string) error {
+	resolved, err := resolveKey(key)
+	if err != nil {
+		return err
+	}
+	if readOnlyKeys[resolved.field] {
+		return fmt.Errorf("%q is managed by ait", key)
+	}
+	def := defaultConf()
+	v, d := reflect.ValueOf(conf).Elem(), reflect.ValueOf(&def).Elem()
+	for i, name := range resolved.path {
+		if v.Kind() == reflect.Map {
+			k := reflect.ValueOf(name)
+			if i == len(resolved.path)-1 {
+				v.SetMapIndex(k, reflect.Value{})
+				return nil
+			}
+			// The field of a table entry: the entry is copied, reset and
+			// put back as map entries can't be set in place.
+			entry := reflect.New(v.Type().Elem()).Elem()
+			if existing := v.MapIndex(k); existing.IsValid() {
+				entry.Set(existing)
+			}
+			field := entry.FieldByName(resolved.path[i+1])
+			field.Set(reflect.Zero(field.Type()))
+			v.SetMapIndex(k, entry)
+			return nil
+		}
+		v, d = v.FieldByName(name), d.FieldByName(name)
+	}
+	v.Set(d)
+	return nil
+}
+
+// setPath sets the setting at path under v to value and returns the parsed
+// value.
+func setPath(v reflect.Value, path []string, value string) (interface{}, error) {
+	if len(path) == 0 {
+		return setValue(v, value)
+	}
+	if v.Kind() == reflect.Struct {
+		return setPath(v.FieldByName(path[0]), path[1:], value)
+	}
+	if v.IsNil() {
+		v.Set(reflect.MakeMap(v.Type()))
+	}
+	// Map entries can't be set in place, they're copied and put back.
+	k := reflect.ValueOf(path[0])
+	entry := reflect.New(v.Type().Elem()).Elem()
+	if existing := v.MapIndex(k); existing.IsValid() {
+		entry.Set(existing)
+	}
+	parsed, err := setPath(entry, path[1:], value)
+	if err == nil {
+		v.SetMapIndex(k, entry)
+	}
+	return parsed, err
+}
+
+// setValue parses value as the type of v and sets it.
+func setValue(v reflect.Value, value string) (interface{}, error) {
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(value)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return nil, fmt.Errorf("%q isn't true or false", value)
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int64:
+		n, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%q isn't a number", value)
+		}
+		v.SetInt(n)
+	case reflect.Slice:
+		v.Set(reflect.ValueOf(listItems(value)))
+	default:
+		return nil, fmt.Errorf("can't be set from the command line")
+	}
+	return v.Interface(), nil
+}
+
+// listItems splits a comma separated list, "" being the empty list.
+func listItems(value string) []string {
+	items := []string{}
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// formatValue returns a setting as it's written on the command line.
+func formatValue(v reflect.Value) string {
+	if v.Kind() == reflect.Slice {
+		items := make([]string, v.Len())
+		for i := range items {
+			items[i] = fmt.Sprint(v.Index(i).Interface())
+		}
+		return strings.Join(items, ",")
+	}
+	return fmt.Sprint(v.Interface())
+}
+
+// ListKeys returns every setting of conf with its value, sorted by key, the
+// entries of tables included. Secrets are redacted.
+func ListKeys(conf Config) []KeyValue {
+	var list []KeyValue
+	var walk func(v reflect.Value, key, field string)
+	walk = func(v reflect.Value, key, field string) {
+		switch v.Kind() {
+		case reflect.Struct:
+			for i := 0; i < v.NumField(); i++ {
+				name := v.Type().Field(i).Name
+				walk(v.Field(i), join(key, strings.ToLower(name)), join(field, name))
+			}
+		case reflect.Map:
+			keys := v.MapKeys()
+			for _, k := range keys {
+				walk(v.MapIndex(k), key+"."+k.String(), field)
+			}
+		default:
+			value := formatValue(v)
+			if secretKeys[field] && value != "" {
+				value = utils.Redacted
+			}
+			list = append(list, KeyValue{Key: key, Value: value})
+		}
+	}
+	walk(reflect.ValueOf(conf), "", "")
+	sort.Slice(list, func(i, j int) bool { return list[i].Key < list[j].Key })
+	return list
+}
+
+// join joins the parts of a key.
+func join(key, name string) string {
+	if key == "" {
+		return name
+	}
+	return key + "." + name
+}
@@ -0,0 +1,172 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSetKey(t *testing.T) {
+	conf := defaultConf()
+	for key, value := range map[string]string{
+		"git.name":                      "Alice",
+		"General.Workers":               "3",
+		"remotes.core":                  "https://github.com/arken/core-keyset",
+		"git.remoteoptions.core.branch": "main",
+		"ipfs.bootstrappeers":           "/ip4/127.0.0.1/tcp/4001, /ip4/127.0.0.2/tcp/4001",
+	} {
+		if _, _, err := SetKey(&conf, key, value); err != nil {
+			t.Fatal(err)
+		}
+		got, err := GetKey(conf, key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != strings.ReplaceAll(value, " ", "") {
+			t.Errorf("%v = %q, expected %q", key, got, value)
+		}
+	}
+	if conf.Git.RemoteOptions["core"].Branch != "main" {
+		t.Errorf("the branch of core wasn't set: %v", conf.Git.RemoteOptions)
+	}
+
+	for key, value := range map[string]string{
+		"git.nick":          "Alice",
+		"git":               "Alice",
+		"general.workers":   "many",
+		"ipfs.apiaddr":      "localhost:5001",
+		"general.version":   "0.0.1",
+		"git.remoteoptions": "main",
+	} {
+		if _, _, err := SetKey(&conf, key, value); err == nil {
+			t.Errorf("expected setting %v to %q to fail", key, value)
+		}
+	}
+
+	if err := UnsetKey(&conf, "remotes.core"); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := conf.Git.Remotes["core"]; ok {
+		t.Error("expected remotes.core to be removed")
+	}
+	if err := UnsetKey(&conf, "general.workers"); err != nil {
+		t.Fatal(err)
+	}
+	if conf.General.Workers != defaultConf().General.Workers {
+		t.Errorf("General.Workers = %v, expected the default", conf.General.Workers)
+	}
+}
+
+func TestWorkspaceConf(t *testing.T) {
+	dir := t.TempDir()
+	defer func(path, wsPath string, keys [][]string, file *Config) {
+		Path, WorkspaceConfigPath, workspaceKeys, fileConf = path, wsPath, keys, file
+	}(Path, WorkspaceConfigPath, workspaceKeys, fileConf)
+	Path = filepath.Join(dir, "ait.config")
+	WorkspaceConfigPath = filepath.Join(dir, ".ait", "config")
+	if err := SaveKey("git.name", "Alice", false); err == nil {
+		t.Error("expected setting a workspace key outside of a workspace to fail")
+	}
+	if err := os.Mkdir(filepath.Dir(WorkspaceConfigPath), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := SaveKey("git.name", "Bob", true); err != nil {
+		t.Fatal(err)
+	}
+	if err := SaveKey("git.email", "not an email", false); err == nil {
+		t.Error("expected an invalid email to be rejected")
+	}
+	for _, key := range []string{"git.pat", "dnslink.token", "smtp.password", "git.tokens.github.com"} {
+		if err := SaveKey(key, "secret", false); err == nil {
+			t.Errorf("expected the secret %v to be kept out of the workspace config", key)
+		}
+	}
+	for key, value := range map[string]string{"git.name": "Alice", "remotes.core": "https://github.com/arken/core-keyset"} {
+		if err := SaveKey(key, value, false); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	conf, err := LoadConf(false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	workspaceKeys = nil
+	if err = readWorkspaceConf(&conf); err != nil {
+		t.Fatal(err)
+	}
+	if conf.Git.Name != "Alice" || conf.Git.Remotes["core"] == "" {
+		t.Errorf("the workspace overrides weren't applied: %+v", conf.Git)
+	}
+	if !IsWorkspaceKey("git.name") || IsWorkspaceKey("git.email") {
+		t.Errorf("wrong workspace keys: %v", workspaceKeys)
+	}
+
+	// The overrides never end up in the config file.
+	conf.Git.Email = "alice@example.org"
+	GenConf(conf)
+	global, err := LoadConf(false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if global.Git.Name != "Bob" || global.Git.Email != "alice@example.org" {
+		t.Errorf("wrong config file settings: %+v", global.Git)
+	}
+	if _, ok := global.Git.Remotes["core"]; ok {
+		t.Error("the workspace's remote was written to the config file")
+	}
+	if conf.Git.Remotes["core"] == "" {
+		t.Error("writing the config file changed the config in use")
+	}
+
+	for _, key := range []string{"git.name", "remotes.core"} {
+		if err := RemoveKey(key, false); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := os.Stat(WorkspaceConfigPath); !os.IsNotExist(err) {
+		t.Errorf("expected the emptied workspace config to be removed, got %v", err)
+	}
+}
+
+func TestGenConfKeepsOtherWrites(t *testing.T) {
+	defer func(path string, file *Config) {
+		Path, fileConf = path, file
+	}(Path, fileConf)
+	Path = filepath.Join(t.TempDir(), "ait.config")
+	fileConf = nil
+	if err := genConf(defaultConf()); err != nil {
+		t.Fatal(err)
+	}
+	conf, err := LoadConf(false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	read, _ := cloneConf(conf)
+	fileConf = &read
+
+	// Another process saves settings while this one holds the config.
+	if err = SaveKey("git.email", "bob@example.org", true); err != nil {
+		t.Fatal(err)
+	}
+	if err = SaveKey("remotes.lab", "https://gitlab.com/arken/lab-keyset", true); err != nil {
+		t.Fatal(err)
+	}
+	conf.Git.Name = "Alice"
+	conf.Git.Remotes = map[string]string{}
+	conf.Git.Remotes["core"] = "https://github.com/arken/core-keyset"
+	if err = genConf(conf); err != nil {
+		t.Fatal(err)
+	}
+	file, err := LoadConf(false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if file.Git.Name != "Alice" || file.Git.Email != "bob@example.org" {
+		t.Errorf("wrong settings after the write: %+v", file.Git)
+	}
+	if file.Git.Remotes["core"] == "" || file.Git.Remotes["lab"] == "" {
+		t.Errorf("wrong remotes after the write: %v", file.Git.Remotes)
+	}
+}
@@ -0,0 +1,74 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	fslock "github.com/ipfs/go-fs-lock"
+)
+
+// lockTimeout is how long GenConf waits for another process to finish
+// writing the config before giving up.
+const lockTimeout = 10 * time.Second
+
+// confMutex serializes config writes within this process. The file lock
+// only guards against other processes.
+var confMutex sync.Mutex
+
+// configLock releases both the process and the file lock on Close.
+type configLock struct {
+	file io.Closer
+}
+
+func (l configLock) Close() error {
+	defer confMutex.Unlock()
+	return l.file.Close()
+}
+
+// lockConf takes an exclusive lock on the config file, waiting for other
+// ait processes (ie a daemon) that are currently writing it.
+func lockConf() (io.Closer, error) {
+	confMutex.Lock()
+	dir := filepath.Dir(Path)
+	name := filepath.Base(Path) + ".lock"
+	deadline := time.Now().Add(lockTimeout)
+	for {
+		lk, err := fslock.Lock(dir, name)
+		if err == nil {
+			return configLock{file: lk}, nil
+		}
+		var locked fslock.LockedError
+		if !errors.As(err, &locked) || time.Now().After(deadline) {
+			confMutex.Unlock()
+			return nil, fmt.Errorf("unable to lock %v: %v", Path, err)
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+}
+
+// writeAtomic writes data to a temporary file next to path and renames it
+// into place so readers never see a partially written file.
+func writeAtomic(path string, data []byte) error {
+	tmp := path + ".tmp"
+	file, err := os.OpenFile(tmp, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	_, err = file.Write(data)
+	if err == nil {
+		err = file.Sync()
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return os.Rename(tmp, path)
+}
@@ -0,0 +1,74 @@
+package config
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// WorkspaceProfilePath is the file in which a workspace records the IPFS
+// profile it should use.
+var WorkspaceProfilePath = filepath.Join(".ait", "profile")
+
+var (
+	// ActiveProfile is the name of the IPFS profile in use, empty for the
+	// default repository.
+	ActiveProfile string
+	// baseIPFSPath is the default repository path read from the config file.
+	// It is what gets written back by GenConf regardless of the profile.
+	baseIPFSPath string
+)
+
+// ProfilePath returns the IPFS repository path for the named profile. Profiles
+// without an explicit path live next to the default repository.
+func ProfilePath(name string) string {
+	if name == "" || name == "default" {
+		return baseIPFSPath
+	}
+	if path, ok := Global.IPFS.Profiles[name]; ok && path != "" {
+		return path
+	}
+	return filepath.Join(filepath.Dir(Path), "ipfs-"+name)
+}
+
+// ValidateProfileName makes sure a profile name can safely be used as part of
+// a directory name.
+func ValidateProfileName(name string) error {
+	if name == "" || name == "default" || strings.ContainsAny(name, `/\. `) {
+		return fmt.Errorf("invalid profile name %q, profile names may not be empty, "+
+			"\"default\", or contain spaces, dots or slashes", name)
+	}
+	return nil
+}
+
+// UseProfile switches Global.IPFS.Path to the repository of the named profile.
+// The name "default" (or an empty name) selects the default repository.
+func UseProfile(name string) error {
+	if name == "" || name == "default" {
+		ActiveProfile = ""
+		Global.IPFS.Path = ProfilePath(name)
+		return createSwarmKey()
+	}
+	if _, ok := Global.IPFS.Profiles[name]; !ok {
+		return fmt.Errorf("no IPFS profile named %q, create it with \"ait ipfs profile add %v\"", name, name)
+	}
+	ActiveProfile = name
+	Global.IPFS.Path = ProfilePath(name)
+	return createSwarmKey()
+}
+
+// SelectProfile picks the profile named by the AIT_PROFILE environment
+// variable or, failing that, the one recorded by the current workspace.
+func SelectProfile() error {
+	name, ok := os.LookupEnv("AIT_PROFILE")
+	if !ok {
+		data, err := ioutil.ReadFile(WorkspaceProfilePath)
+		if err != nil {
+			return nil
+		}
+		name = strings.TrimSpace(string(data))
+	}
+	return UseProfile(name)
+}
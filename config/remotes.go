@@ -0,0 +1,75 @@
+package config
+
+import (
+	"bufio"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// RemoteOptions are the settings of a remote alias other than its URL.
+type RemoteOptions struct {
+	// Branch is the branch submissions are made to and keysets are cloned
+	// from, the default branch of the repository when empty.
+	Branch string
+	// Provider is the forge hosting the repository: github, gitlab or gitea,
+	// detected from its host when empty.
+	Provider string
+}
+
+// RemoteOptionsOf returns the options of the saved remote alias of url, as
+// remotes are only known by their URL once their alias is resolved.
+func RemoteOptionsOf(url string) RemoteOptions {
+	for alias, options := range Global.Git.RemoteOptions {
+		target, ok := Global.Git.Remotes[alias]
+		if ok && (target == url || GetRemote(alias) == url || GetPushRemote(alias) == url) {
+			return options
+		}
+	}
+	return RemoteOptions{}
+}
+
+// WorkspaceRemotesPath is the file in which a workspace records its own remote
+// aliases, ie the one of the template it was initialized from, one "alias url"
+// pair per line.
+var WorkspaceRemotesPath = filepath.Join(".ait", "remotes")
+
+// WorkspaceRemotes returns the remote aliases of the current workspace.
+func WorkspaceRemotes() map[string]string {
+	remotes := make(map[string]string)
+	file, err := os.Open(WorkspaceRemotesPath)
+	if err != nil {
+		return remotes
+	}
+	defer file.Close()
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		if fields := strings.Fields(scanner.Text()); len(fields) == 2 {
+			remotes[fields[0]] = fields[1]
+		}
+	}
+	return remotes
+}
+
+// SaveWorkspaceRemote records alias as a remote alias of the current
+// workspace, replacing the alias of the same name.
+func SaveWorkspaceRemote(alias, url string) error {
+	if alias == "" || strings.ContainsAny(alias, " \t\n") {
+		return fmt.Errorf("invalid alias %q, aliases may not be empty or contain spaces", alias)
+	}
+	remotes := WorkspaceRemotes()
+	remotes[alias] = url
+	aliases := make([]string, 0, len(remotes))
+	for a := range remotes {
+		aliases = append(aliases, a)
+	}
+	sort.Strings(aliases)
+	var contents strings.Builder
+	for _, a := range aliases {
+		contents.WriteString(a + " " + remotes[a] + "\n")
+	}
+	return ioutil.WriteFile(WorkspaceRemotesPath, []byte(contents.String()), 0644)
+}
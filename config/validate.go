@@ -0,0 +1,133 @@
+package config
+
+import (
+	"fmt"
+	"path/filepath"
+	"time"
+
+	"github.com/arken/ait/utils"
+
+	ma "github.com/multiformats/go-multiaddr"
+)
+
+// maxInlineLimit is the largest block inlined in a CID, longer CIDs aren't
+// accepted by every IPFS implementation.
+const maxInlineLimit = 128
+
+// Validate checks the settings of conf that ait can't work with if they're
+// malformed, and returns the first problem found.
+func Validate(conf Config) error {
+	if conf.Git.Email != "" {
+		if _, err := utils.CleanEmail(conf.Git.Email, nil); err != nil {
+			return fmt.Errorf("Git.Email: %v", err)
+		}
+	}
+	if !filepath.IsAbs(conf.IPFS.Path) {
+		return fmt.Errorf("IPFS.Path %q must be an absolute path", conf.IPFS.Path)
+	}
+	if conf.General.TempDir != "" && !filepath.IsAbs(conf.General.TempDir) {
+		return fmt.Errorf("General.TempDir %q must be an absolute path", conf.General.TempDir)
+	}
+	sizes := map[string]string{
+		"General.MinFreeSpace": conf.General.MinFreeSpace,
+		"IPFS.StorageMax":      conf.IPFS.StorageMax,
+		"IPFS.StorageWarn":     conf.IPFS.StorageWarn,
+		"IPFS.RelayWarn":       conf.IPFS.RelayWarn,
+		"IPFS.MaxUpload":       conf.IPFS.MaxUpload,
+		"IPFS.MaxDownload":     conf.IPFS.MaxDownload,
+	}
+	for name, value := range sizes {
+		if _, err := utils.ParseByteSize(value); err != nil {
+			return fmt.Errorf("%v: %v", name, err)
+		}
+	}
+	durations := map[string]string{
+		"General.TrashPeriod":      conf.General.TrashPeriod,
+		"General.PromptTimeout":    conf.General.PromptTimeout,
+		"IPFS.GCPeriod":            conf.IPFS.GCPeriod,
+		"IPFS.ScrubPeriod":         conf.IPFS.ScrubPeriod,
+		"IPFS.ReprovideInterval":   conf.IPFS.ReprovideInterval,
+		"IPFS.ReachabilityTimeout": conf.IPFS.ReachabilityTimeout,
+		"Notify.OperationsAfter":   conf.Notify.OperationsAfter,
+		"Notify.AtRiskPeriod":      conf.Notify.AtRiskPeriod,
+	}
+	for name, value := range durations {
+		if value == "" {
+			continue
+		}
+		if _, err := time.ParseDuration(value); err != nil {
+			return fmt.Errorf("%v: %v", name, err)
+		}
+	}
+	if w := conf.IPFS.StorageGCWatermark; w < 0 || w > 100 {
+		return fmt.Errorf("IPFS.StorageGCWatermark %d isn't a percentage", w)
+	}
+	if conf.Notify.AtRiskThreshold < 1 {
+		return fmt.Errorf("Notify.AtRiskThreshold %d must be at least 1 peer", conf.Notify.AtRiskThreshold)
+	}
+	if conf.Network.Retries < 0 {
+		return fmt.Errorf("Network.Retries %d can't be negative", conf.Network.Retries)
+	}
+	if conf.General.Workers < 0 {
+		return fmt.Errorf("General.Workers %d can't be negative", conf.General.Workers)
+	}
+	switch conf.General.PromptTimeoutAction {
+	case "abort", "default":
+	default:
+		return fmt.Errorf("General.PromptTimeoutAction must be \"abort\" or \"default\", not %q",
+			conf.General.PromptTimeoutAction)
+	}
+	switch conf.General.KeysetFormat {
+	case "ksv", "json", "csv":
+	default:
+		return fmt.Errorf("General.KeysetFormat must be \"ksv\", \"json\" or \"csv\", not %q",
+			conf.General.KeysetFormat)
+	}
+	switch conf.Git.SigningFormat {
+	case utils.SignOpenPGP, utils.SignSSH:
+	default:
+		return fmt.Errorf("Git.SigningFormat must be \"openpgp\" or \"ssh\", not %q", conf.Git.SigningFormat)
+	}
+	if conf.Git.SignKeysets && conf.Git.SigningKey == "" {
+		return fmt.Errorf("Git.SignKeysets needs Git.SigningKey to sign the keysets with")
+	}
+	switch conf.IPFS.Migrate {
+	case "prompt", "auto", "never":
+	default:
+		return fmt.Errorf("IPFS.Migrate must be \"prompt\", \"auto\" or \"never\", not %q", conf.IPFS.Migrate)
+	}
+	switch conf.IPFS.Layout {
+	case "balanced", "trickle":
+	default:
+		return fmt.Errorf("IPFS.Layout must be \"balanced\" or \"trickle\", not %q", conf.IPFS.Layout)
+	}
+	low, high := conf.IPFS.ConnMgrLowWater, conf.IPFS.ConnMgrHighWater
+	if low < 0 || high < 0 {
+		return fmt.Errorf("IPFS.ConnMgrLowWater and IPFS.ConnMgrHighWater can't be negative")
+	}
+	if low > 0 && high > 0 && low > high {
+		return fmt.Errorf("IPFS.ConnMgrLowWater %d must be below IPFS.ConnMgrHighWater %d", low, high)
+	}
+	if (conf.IPFS.MaxUpload != "" || conf.IPFS.MaxDownload != "") && conf.Network.Transport == "websocket" {
+		return fmt.Errorf("IPFS.MaxUpload and IPFS.MaxDownload can't be enforced over the websocket Network.Transport")
+	}
+	switch conf.IPFS.Backend {
+	case "embedded", "http", "auto":
+	default:
+		return fmt.Errorf("IPFS.Backend must be \"embedded\", \"http\" or \"auto\", not %q", conf.IPFS.Backend)
+	}
+	if conf.IPFS.Backend != "embedded" {
+		if _, err := ma.NewMultiaddr(conf.IPFS.APIAddr); err != nil {
+			return fmt.Errorf("IPFS.APIAddr: %v", err)
+		}
+	}
+	if l := conf.IPFS.InlineLimit; conf.IPFS.Inline && (l < 1 || l > maxInlineLimit) {
+		return fmt.Errorf("IPFS.InlineLimit %d must be between 1 and %d bytes", l, maxInlineLimit)
+	}
+	switch conf.Trust.Policy {
+	case "off", "warn", "require":
+	default:
+		return fmt.Errorf("Trust.Policy must be \"off\", \"warn\" or \"require\", not %q", conf.Trust.Policy)
+	}
+	return nil
+}
@@ -0,0 +1,306 @@
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+)
+
+// WorkspaceConfigPath is the file in which a workspace overrides settings of
+// the config for itself, in the same format as the config.
+var WorkspaceConfigPath = filepath.Join(".ait", "config")
+
+// workspaceKeys are the paths of the settings the workspace config overrides,
+// which GenConf leaves out of the config file.
+var workspaceKeys [][]string
+
+// readWorkspaceConf applies the overrides of the current workspace to conf.
+func readWorkspaceConf(conf *Config) error {
+	data, err := ioutil.ReadFile(WorkspaceConfigPath)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	md, err := toml.Decode(string(data), conf)
+	if err != nil {
+		return fmt.Errorf("%v: %v", WorkspaceConfigPath, err)
+	}
+	workspaceKeys = nil
+	for _, key := range md.Keys() {
+		if md.Type(key...) == "Hash" {
+			continue
+		}
+		if resolved, err := resolveKey(strings.Join(key, ".")); err == nil {
+			workspaceKeys = append(workspaceKeys, resolved.path)
+		}
+	}
+	return nil
+}
+
+// IsWorkspaceKey returns whether the setting named by key is overridden by
+// the current workspace.
+func IsWorkspaceKey(key string) bool {
+	resolved, err := resolveKey(key)
+	if err != nil {
+		return false
+	}
+	for _, path := range workspaceKeys {
+		if reflect.DeepEqual(path, resolved.path) {
+			return true
+		}
+	}
+	return false
+}
+
+// LoadConf reads the config file onto the defaults and, with workspace, the
+// overrides of the current workspace on top of it. The environment isn't
+// looked at.
+func LoadConf(workspace bool) (Config, error) {
+	conf := defaultConf()
+	data, err := ioutil.ReadFile(Path)
+	if err == nil {
+		err = decodeConf(data, &conf)
+	}
+	if os.IsNotExist(err) {
+		err = nil
+	}
+	if err == nil && workspace {
+		_, err = toml.DecodeFile(WorkspaceConfigPath, &conf)
+		if os.IsNotExist(err) {
+			err = nil
+		}
+	}
+	return conf, err
+}
+
+// withoutOverrides returns a copy of conf whose settings overridden by the
+// workspace are the ones of the config file.
+func withoutOverrides(conf Config) Config {
+	file, err := LoadConf(false)
+	if err != nil {
+		return conf
+	}
+	clone, err := cloneConf(conf)
+	if err != nil {
+		return conf
+	}
+	for _, path := range workspaceKeys {
+		copyPath(reflect.ValueOf(&clone).Elem(), reflect.ValueOf(file), path)
+	}
+	return clone
+}
+
+// cloneConf returns a copy of conf made by decoding it, its tables are shared
+// otherwise.
+func cloneConf(conf Config) (Config, error) {
+	var clone Config
+	buf := new(bytes.Buffer)
+	if err := toml.NewEncoder(buf).Encode(conf); err != nil {
+		return clone, err
+	}
+	err := decodeConf(buf.Bytes(), &clone)
+	return clone, err
+}
+
+// copyPath sets the setting at path under dst to the one under src, removing
+// table entries src doesn't have.
+func copyPath(dst, src reflect.Value, path []string) {
+	if len(path) == 0 {
+		dst.Set(src)
+		return
+	}
+	if dst.Kind() == reflect.Struct {
+		copyPath(dst.FieldByName(path[0]), src.FieldByName(path[0]), path[1:])
+		return
+	}
+	k := reflect.ValueOf(path[0])
+	value := src.MapIndex(k)
+	if len(path) == 1 {
+		if dst.IsNil() && value.IsValid() {
+			dst.Set(reflect.MakeMap(dst.Type()))
+		}
+		if !dst.IsNil() {
+			dst.SetMapIndex(k, value)
+		}
+		return
+	}
+	if dst.IsNil() {
+		dst.Set(reflect.MakeMap(dst.Type()))
+	}
+	entry := reflect.New(dst.Type().Elem()).Elem()
+	if existing := dst.MapIndex(k); existing.IsValid() {
+		entry.Set(existing)
+	}
+	if !value.IsValid() {
+		value = reflect.New(src.Type().Elem()).Elem()
+	}
+	copyPath(entry, value, path[1:])
+	dst.SetMapIndex(k, entry)
+}
+
+// SaveKey sets the setting named by key to value in the config file, with
+// global, or in the workspace config. The config the setting ends up in must
+// pass Validate. Secrets are never saved in the workspace config, which is
+// kept in plain text next to the files and may be shared with them.
+func SaveKey(key, value string, global bool) error {
+	if global {
+		return updateConf(func(conf *Config) error {
+			if _, _, err := SetKey(conf, key, value); err != nil {
+				return err
+			}
+			return Validate(*conf)
+		})
+	}
+	if _, err := os.Stat(filepath.Dir(WorkspaceConfigPath)); err != nil {
+		return fmt.Errorf("not in an AIT workspace, set %v with --global instead", key)
+	}
+	if resolved, err := resolveKey(key); err == nil && secretKeys[resolved.field] {
+		return fmt.Errorf("%v is a secret, it can't be saved in the workspace config, set it with --global instead", key)
+	}
+	conf, err := LoadConf(true)
+	if err != nil {
+		return err
+	}
+	path, parsed, err := SetKey(&conf, key, value)
+	if err != nil {
+		return err
+	}
+	if err = Validate(conf); err != nil {
+		return err
+	}
+	tree, err := readWorkspaceTree()
+	if err != nil {
+		return err
+	}
+	setTree(tree, path, parsed)
+	return writeWorkspaceTree(tree)
+}
+
+// RemoveKey removes the setting named by key from the config file, with
+// global, resetting it to its default, or from the workspace config, the
+// config file's value applying again.
+func RemoveKey(key string, global bool) error {
+	if global {
+		return updateConf(func(conf *Config) error {
+			return UnsetKey(conf, key)
+		})
+	}
+	resolved, err := resolveKey(key)
+	if err != nil {
+		return err
+	}
+	tree, err := readWorkspaceTree()
+	if err != nil {
+		return err
+	}
+	if !removeTree(tree, resolved.path) {
+		return fmt.Errorf("%q isn't set by the workspace", key)
+	}
+	return writeWorkspaceTree(tree)
+}
+
+// updateConf reads the config file, lets update change it and writes it
+// back, all under the config lock so the changes other processes made in the
+// meantime are kept. Unlike GenConf, the IPFS profile and the workspace
+// overrides aren't looked at.
+func updateConf(update func(conf *Config) error) error {
+	lk, err := lockConf()
+	if err != nil {
+		return err
+	}
+	defer lk.Close()
+	conf, err := LoadConf(false)
+	if err != nil {
+		return err
+	}
+	if err = update(&conf); err != nil {
+		return err
+	}
+	buf := new(bytes.Buffer)
+	if err = toml.NewEncoder(buf).Encode(conf); err != nil {
+		return err
+	}
+	return writeAtomic(Path, buf.Bytes())
+}
+
+// readWorkspaceTree returns the tables of the workspace config, only holding
+// the settings it overrides.
+func readWorkspaceTree() (map[string]interface{}, error) {
+	tree := make(map[string]interface{})
+	_, err := toml.DecodeFile(WorkspaceConfigPath, &tree)
+	if os.IsNotExist(err) {
+		err = nil
+	}
+	return tree, err
+}
+
+// writeWorkspaceTree writes the tables of the workspace config, removing the
+// file once it overrides nothing.
+func writeWorkspaceTree(tree map[string]interface{}) error {
+	if len(tree) == 0 {
+		err := os.Remove(WorkspaceConfigPath)
+		if os.IsNotExist(err) {
+			err = nil
+		}
+		return err
+	}
+	buf := new(bytes.Buffer)
+	if err := toml.NewEncoder(buf).Encode(tree); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(WorkspaceConfigPath, buf.Bytes(), 0644)
+}
+
+// treeKey returns the key of tree matching name, which tables written by
+// hand may spell in another case.
+func treeKey(tree map[string]interface{}, name string) string {
+	for k := range tree {
+		if strings.EqualFold(k, name) {
+			return k
+		}
+	}
+	return name
+}
+
+// setTree sets the setting at path in tree to value, creating the tables
+// leading to it.
+func setTree(tree map[string]interface{}, path []string, value interface{}) {
+	k := treeKey(tree, path[0])
+	if len(path) == 1 {
+		tree[k] = value
+		return
+	}
+	sub, ok := tree[k].(map[string]interface{})
+	if !ok {
+		sub = make(map[string]interface{})
+		tree[k] = sub
+	}
+	setTree(sub, path[1:], value)
+}
+
+// removeTree removes the setting at path from tree along with the tables
+// left empty, and returns whether it was there.
+func removeTree(tree map[string]interface{}, path []string) bool {
+	k := treeKey(tree, path[0])
+	if len(path) == 1 {
+		_, ok := tree[k]
+		delete(tree, k)
+		return ok
+	}
+	sub, ok := tree[k].(map[string]interface{})
+	if !ok || !removeTree(sub, path[1:]) {
+		return false
+	}
+	if len(sub) == 0 {
+		delete(tree, k)
+	}
+	return true
+}
@@ -2,7 +2,11 @@ package display
 
 import (
 	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
 	"io"
+	"io/ioutil"
 	"os"
 	"os/exec"
 	"path/filepath"
@@ -17,24 +21,72 @@ import (
 
 var application *types.ApplicationContents
 
+// givenApplication is the application given on the command line, which
+// ShowApplication submits without opening the editor.
+type givenApplication struct {
+	// message is the contents of the message file, if any.
+	message     []byte
+	title, body string
+}
+
+var given *givenApplication
+
+// WorkspaceApplicationPath is the application template of the workspace, ie
+// from the template it was initialized from, used before any other.
+var WorkspaceApplicationPath = filepath.Join(".ait", "application.md")
+
+// KeysetExtension ends the keyset names read from applications, the extension
+// of the format the keyset is submitted in.
+var KeysetExtension = ".ks"
+
 // ShowApplication pulls up our template application, currently stored in the
 // string above.
 func ShowApplication() {
 	appPath := filepath.Join(".ait", "commit")
+	// A replayed session submits the application it recorded.
+	if session := utils.Replaying(); session != nil && session.Application != "" {
+		utils.CheckError(ioutil.WriteFile(appPath, []byte(session.Application), 0644))
+		return
+	}
 	// Don't overwrite the commit file if it already exists.
 	if s, _ := utils.GetFileSize(appPath); s == 0 {
 		//^ if the commit file is empty and/or does not exist, one must be
 		//fetched from the appropriate source
 		fetchApplicationTemplate(appPath)
 	}
-	execPath, err := exec.LookPath(config.Global.General.Editor)
+	// An application given on the command line is only submitted once, the
+	// editor opens if it's shown again, ie to rename the keyset.
+	if given != nil {
+		utils.CheckError(given.apply(appPath))
+		given = nil
+		if app := ReadApplication(); !app.IsValid() {
+			utils.FatalPrintf("The application given with --message-file, --title or --body has no %v.\n",
+				app.Missing())
+		}
+		recordApplication(appPath)
+		return
+	}
+	// Submissions run without a terminal, ie from "ait web", use the
+	// application as it was prepared.
+	if !utils.IsTerminal(os.Stdin) || utils.NonInteractive {
+		if app := ReadApplication(); app != nil && app.IsValid() {
+			recordApplication(appPath)
+			return
+		}
+	}
+	if utils.NonInteractive {
+		utils.FatalPrintf("The application in %v is incomplete, and it can't be edited in "+
+			"non-interactive mode. Fill it in beforehand or submit with --template.\n", appPath)
+	}
+	editor := editorCommand()
+	execPath, err := exec.LookPath(editor[0])
 	if err != nil {
 		utils.FatalPrintf("%v, your configured editor, could not be found. "+
 			"Please make sure it is installed and in your OS's PATH "+
-			"or change it in the ~/.ait/ait.config file.\n", config.Global.General.Editor)
+			"or change it in the ~/.ait/ait.config file.\n", editor[0])
 	}
 
-	cmd := exec.Command(execPath, appPath)
+	cmd := exec.Command(execPath, append(editor[1:], appPath)...)
 	cmd.Stdin = os.Stdin
 	cmd.Stdout = os.Stdout
 	// Display the editor to the user by running the command.
@@ -42,17 +94,134 @@ func ShowApplication() {
 	utils.CheckError(err)
 	now := time.Now()
 	_ = os.Chtimes(appPath, now, now)
+	recordApplication(appPath)
 	// Ignored because docs say that if this function an error, it's a PathError,
 	// and if appPath was bad, the program would have already crashed.
 }
 
+// editorCommand returns the editor applications are written in with its
+// arguments: General.Editor or, while it's left to its default, $VISUAL or
+// $EDITOR, as git does.
+func editorCommand() []string {
+	editor := config.Global.General.Editor
+	if editor == config.DefaultEditor {
+		for _, env := range []string{"VISUAL", "EDITOR"} {
+			if value := strings.TrimSpace(os.Getenv(env)); value != "" {
+				editor = value
+				break
+			}
+		}
+	}
+	fields := strings.Fields(editor)
+	if len(fields) == 0 {
+		return []string{config.DefaultEditor}
+	}
+	return fields
+}
+
+// GiveApplication gives the application on the command line rather than in
+// the editor. messageFile, "-" for the standard input, holds either a filled
+// application template or a message like a git commit message, whose first
+// line is the title and the rest the commit message. title and body replace
+// the title and commit message. The application is applied when it's shown,
+// once the repository's template is known, and must then be complete.
+func GiveApplication(messageFile, title, body string) error {
+	if messageFile == "" && title == "" && body == "" {
+		return nil
+	}
+	g := &givenApplication{title: strings.TrimSpace(title), body: strings.TrimSpace(body)}
+	if (title != "" && g.title == "") || (body != "" && g.body == "") {
+		return errors.New("--title and --body can't be blank")
+	}
+	if messageFile != "" {
+		var err error
+		if messageFile == "-" {
+			g.message, err = ioutil.ReadAll(os.Stdin)
+		} else {
+			g.message, err = ioutil.ReadFile(messageFile)
+		}
+		if err != nil {
+			return err
+		}
+		if len(bytes.TrimSpace(g.message)) == 0 {
+			return fmt.Errorf("the message file %v is empty", messageFile)
+		}
+	}
+	given = g
+	return nil
+}
+
+// apply writes the given application to the application at appPath, which
+// holds the template of the application.
+func (g *givenApplication) apply(appPath string) error {
+	title, body := g.title, g.body
+	if g.message != nil {
+		if validateTemplate(ioutil.NopCloser(bytes.NewReader(g.message))) {
+			if err := ioutil.WriteFile(appPath, g.message, 0644); err != nil {
+				return err
+			}
+		} else {
+			msgTitle, msgBody := splitMessage(string(g.message))
+			if title == "" {
+				title = msgTitle
+			}
+			if body == "" {
+				body = msgBody
+			}
+		}
+	}
+	if title == "" && body == "" {
+		return nil
+	}
+	app := *ReadApplication()
+	if title != "" {
+		app.Title = title
+	}
+	if body != "" {
+		app.Commit = body
+	}
+	// ReadApplication ends the name with the extension again.
+	app.KsName = strings.TrimSuffix(app.KsName, KeysetExtension)
+	return WriteApplication(&app)
+}
+
+// splitMessage splits a message like a git commit message in its title, the
+// first line, and its body, the lines after it. Lines starting with "#" are
+// comments.
+func splitMessage(message string) (string, string) {
+	var lines []string
+	for _, line := range strings.Split(message, "\n") {
+		if !strings.HasPrefix(line, "#") {
+			lines = append(lines, strings.TrimRight(line, " \t\r"))
+		}
+	}
+	text := strings.TrimSpace(strings.Join(lines, "\n"))
+	title, body := text, ""
+	if i := strings.Index(text, "\n"); i >= 0 {
+		title, body = text[:i], strings.TrimSpace(text[i+1:])
+	}
+	return strings.TrimSpace(title), body
+}
+
+// recordApplication keeps the application at appPath in the session being
+// recorded, if any.
+func recordApplication(appPath string) {
+	if data, err := ioutil.ReadFile(appPath); err == nil {
+		utils.RecordApplication(string(data))
+	}
+}
+
 // fetchApplicationTemplate fetches the prompt that will be shown to the user.
-// It will preferentially choose the cloned repository, but if there is none
-// there, the default application template that lives in ~/.ait/application.md
-// will be used instead. The appropriate template is deep-copied into
-// ./.ait/commit, so this function can cause the program to terminate if i/o
-// errors arise
+// It will preferentially choose the workspace's template, then the cloned
+// repository's, but if there is none there, the default application template
+// that lives in ~/.ait/application.md will be used instead. The appropriate
+// template is deep-copied into ./.ait/commit, so this function can cause the
+// program to terminate if i/o errors arise
 func fetchApplicationTemplate(destPath string) {
+	if fileIsValidTemplate(WorkspaceApplicationPath) {
+		utils.CheckError(utils.CopyFile(WorkspaceApplicationPath, destPath))
+		return
+	}
 	fromPath, err := aitgh.DownloadRepoAppTemplate()
 	// downloads the file into fromPath if it existed in the repo.
 	if err == nil && fileIsValidTemplate(fromPath) { // false if the file does not exist
@@ -79,6 +248,32 @@ In the future, please refrain from editing %v.
 	}
 }
 
+// FillApplicationTemplate returns the application template with the fields
+// of app that are set written under their labels, so they're filled in when
+// the application is shown.
+func FillApplicationTemplate(template []byte, app *types.ApplicationContents) []byte {
+	fields := map[string]string{
+		"# CATEGORY":     app.Category,
+		"# FILENAME":     app.KsName,
+		"# TITLE":        app.Title,
+		"# COMMIT":       app.Commit,
+		"# PULL REQUEST": app.PRBody,
+	}
+	var filled strings.Builder
+	for _, line := range strings.SplitAfter(string(template), "\n") {
+		filled.WriteString(line)
+		for label, value := range fields {
+			if value != "" && strings.HasPrefix(line, label) {
+				if !strings.HasSuffix(line, "\n") {
+					filled.WriteString("\n")
+				}
+				filled.WriteString(strings.TrimSpace(value) + "\n")
+			}
+		}
+	}
+	return []byte(filled.String())
+}
+
 func fileIsValidTemplate(path string) bool {
 	commitFile, err := os.OpenFile(path, os.O_RDONLY, 0644)
 	if err != nil {
@@ -170,13 +365,30 @@ func ReadApplication() *types.ApplicationContents {
 	}
 	application.TrimFields()
 	sanitizeCategory()
-	if !strings.HasSuffix(application.KsName, ".ks") {
-		application.KsName += ".ks"
+	if !strings.HasSuffix(application.KsName, KeysetExtension) {
+		application.KsName = strings.TrimSuffix(application.KsName, ".ks") + KeysetExtension
 	}
 	application.TimeFilled = time.Now()
 	return application
 }
 
+// WriteApplication saves app as the workspace's application, in the format
+// ReadApplication parses.
+func WriteApplication(app *types.ApplicationContents) error {
+	appPath := filepath.Join(".ait", "commit")
+	var contents strings.Builder
+	for _, field := range []struct{ label, value string }{
+		{"# CATEGORY", app.Category},
+		{"# FILENAME", app.KsName},
+		{"# TITLE", app.Title},
+		{"# COMMIT", app.Commit},
+		{"# PULL REQUEST", app.PRBody},
+	} {
+		contents.WriteString(field.label + "\n" + strings.TrimSpace(field.value) + "\n\n")
+	}
+	return ioutil.WriteFile(appPath, []byte(contents.String()), 0644)
+}
+
 func sanitizeCategory() {
 	category := &application.Category
 	if strings.HasPrefix(*category, string(filepath.Separator)) {
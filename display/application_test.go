@@ -43,6 +43,55 @@ func TestIsValidApplication(t *testing.T) {
 	os.Remove("temp")
 }
 
+func TestFillApplicationTemplate(t *testing.T) {
+	template := []byte("<!-- Describe the files -->\n# TITLE below\n\n# COMMIT below\n\n# PULL REQUEST below")
+	filled := FillApplicationTemplate(template, &types.ApplicationContents{
+		Title:  "Monthly climate readings",
+		PRBody: "Adds this month's readings.",
+	})
+	assert.Equal(t, "<!-- Describe the files -->\n# TITLE below\nMonthly climate readings\n\n"+
+		"# COMMIT below\n\n# PULL REQUEST below\nAdds this month's readings.\n", string(filled))
+}
+
+func TestSplitMessage(t *testing.T) {
+	title, body := splitMessage("# Comment\nMonthly readings  \n\nAdds this month's\nreadings.\n")
+	assert.Equal(t, "Monthly readings", title)
+	assert.Equal(t, "Adds this month's\nreadings.", body)
+	title, body = splitMessage("Only a title")
+	assert.Equal(t, "Only a title", title)
+	assert.Equal(t, "", body)
+}
+
+func TestGiveApplication(t *testing.T) {
+	wd, _ := os.Getwd()
+	defer os.Chdir(wd)
+	assert.NoError(t, os.Chdir(t.TempDir()))
+	assert.NoError(t, os.Mkdir(".ait", 0755))
+	appPath := filepath.Join(".ait", "commit")
+	template := "# CATEGORY\nclimate\n\n# FILENAME\nreadings\n\n# TITLE\n\n# COMMIT\n"
+	assert.NoError(t, ioutil.WriteFile(appPath, []byte(template), 0644))
+
+	assert.Error(t, GiveApplication("", " ", ""))
+	assert.NoError(t, ioutil.WriteFile("message.txt", []byte("Monthly readings\n\nFrom the roof station.\n"), 0644))
+	assert.NoError(t, GiveApplication("message.txt", "", "Adds this month's readings."))
+	assert.NoError(t, given.apply(appPath))
+	given = nil
+	app := ReadApplication()
+	assert.Equal(t, "Monthly readings", app.Title)
+	assert.Equal(t, "Adds this month's readings.", app.Commit)
+	assert.Equal(t, "climate/readings.ks", app.FullPath())
+
+	// A filled template replaces the application.
+	assert.NoError(t, ioutil.WriteFile("app.md", []byte("# TITLE\nOther\n# COMMIT\nMessage\n"), 0644))
+	assert.NoError(t, GiveApplication("app.md", "", ""))
+	assert.NoError(t, given.apply(appPath))
+	given = nil
+	application = nil
+	app = ReadApplication()
+	assert.Equal(t, "Other", app.Title)
+	assert.Equal(t, "Message", app.Commit)
+}
+
 func printApp(app *types.ApplicationContents) {
 	fmt.Print(app.Title, "\n\n", app.Commit, "\n\n", app.PRBody, "\n\n", app.KsName, "\n")
 }
@@ -0,0 +1,191 @@
+package display
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Record is a line of the JSON output of a command.
+type Record struct {
+	// Type is "message" for text the command printed, "result" for what it
+	// produced and "error" for why it failed.
+	Type    string      `json:"type"`
+	Command string      `json:"command"`
+	Text    string      `json:"text,omitempty"`
+	Data    interface{} `json:"data,omitempty"`
+	Time    time.Time   `json:"time"`
+}
+
+// syncMarker is written through a captured stdout to know when everything
+// printed before it has been emitted.
+const syncMarker = "\x00ait-sync"
+
+// Emitter renders the output of commands, as text or, in JSON mode, as
+// newline delimited JSON records other programs can parse. It is safe for
+// concurrent use.
+type Emitter struct {
+	lock    sync.Mutex
+	out     io.Writer
+	json    bool
+	command string
+	// stdout, pipe, synced and drained are set while os.Stdout is captured.
+	stdout  *os.File
+	pipe    *os.File
+	synced  chan struct{}
+	drained chan struct{}
+}
+
+// Out is the emitter commands print their output through.
+var Out = NewEmitter(os.Stdout)
+
+// NewEmitter returns an emitter writing text to w.
+func NewEmitter(w io.Writer) *Emitter {
+	return &Emitter{out: w}
+}
+
+// EnableJSON makes the emitter write JSON records from the command.
+func (e *Emitter) EnableJSON(command string) {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	e.json, e.command = true, command
+}
+
+// JSON returns whether the emitter writes JSON records.
+func (e *Emitter) JSON() bool {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	return e.json
+}
+
+// Printf writes the formatted text, as a message record per line in JSON mode.
+func (e *Emitter) Printf(format string, a ...interface{}) {
+	e.text(fmt.Sprintf(format, a...))
+}
+
+// Println writes the operands followed by a newline, as a message record in
+// JSON mode.
+func (e *Emitter) Println(a ...interface{}) {
+	e.text(fmt.Sprintln(a...))
+}
+
+// text writes text as is, or each of its lines as a message record.
+func (e *Emitter) text(text string) {
+	if !e.JSON() {
+		e.lock.Lock()
+		defer e.lock.Unlock()
+		io.WriteString(e.out, text)
+		return
+	}
+	e.sync()
+	for _, line := range strings.Split(text, "\n") {
+		e.record(Record{Type: "message", Text: line})
+	}
+}
+
+// Result writes what the command produced: data as a result record in JSON
+// mode, or else as human writes it.
+func (e *Emitter) Result(data interface{}, human func(w io.Writer)) {
+	if !e.JSON() {
+		e.lock.Lock()
+		defer e.lock.Unlock()
+		human(e.out)
+		return
+	}
+	e.sync()
+	e.record(Record{Type: "result", Data: data})
+}
+
+// Error writes why the command failed, as an error record in JSON mode.
+func (e *Emitter) Error(msg string) {
+	if !e.JSON() {
+		e.text(msg + "\n")
+		return
+	}
+	e.sync()
+	e.record(Record{Type: "error", Text: msg})
+}
+
+// record writes r as a line of JSON. Blank messages are left out.
+func (e *Emitter) record(r Record) {
+	if r.Type == "message" && strings.TrimSpace(r.Text) == "" {
+		return
+	}
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	r.Command, r.Time = e.command, time.Now()
+	line, err := json.Marshal(r)
+	if err != nil {
+		return
+	}
+	e.out.Write(append(line, '\n'))
+}
+
+// Capture redirects os.Stdout through the emitter until Release is called, so
+// the text commands print with fmt becomes message records in JSON mode.
+func (e *Emitter) Capture() error {
+	r, w, err := os.Pipe()
+	if err != nil {
+		return err
+	}
+	e.lock.Lock()
+	e.stdout, e.pipe = os.Stdout, w
+	e.synced, e.drained = make(chan struct{}), make(chan struct{})
+	synced, drained := e.synced, e.drained
+	e.lock.Unlock()
+	os.Stdout = w
+	go func() {
+		defer close(drained)
+		scanner := bufio.NewScanner(r)
+		scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+		for scanner.Scan() {
+			line := scanner.Text()
+			if line == syncMarker {
+				synced <- struct{}{}
+				continue
+			}
+			// Spinners and progress redraw the line after a carriage
+			// return, only what was drawn last is kept.
+			if i := strings.LastIndex(line, "\r"); i >= 0 {
+				line = line[i+1:]
+			}
+			e.record(Record{Type: "message", Text: line})
+		}
+		r.Close()
+	}()
+	return nil
+}
+
+// sync waits until everything printed to the captured stdout so far has been
+// emitted, so records keep the order things were printed in.
+func (e *Emitter) sync() {
+	e.lock.Lock()
+	pipe, synced := e.pipe, e.synced
+	e.lock.Unlock()
+	if pipe == nil {
+		return
+	}
+	// A partial line, ie a prompt, is ended so the marker is on its own.
+	if _, err := pipe.WriteString("\n" + syncMarker + "\n"); err == nil {
+		<-synced
+	}
+}
+
+// Release emits what's left of the captured stdout and restores it.
+func (e *Emitter) Release() {
+	e.lock.Lock()
+	pipe, drained, stdout := e.pipe, e.drained, e.stdout
+	e.pipe = nil
+	e.lock.Unlock()
+	if pipe == nil {
+		return
+	}
+	os.Stdout = stdout
+	pipe.Close()
+	<-drained
+}
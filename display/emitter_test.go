@@ -0,0 +1,49 @@
+package display
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEmitterText(t *testing.T) {
+	buf := new(bytes.Buffer)
+	e := NewEmitter(buf)
+	e.Println("Staging files")
+	e.Result(map[string]int{"added": 2}, func(w io.Writer) {
+		fmt.Fprintln(w, 2, "file(s) added")
+	})
+	e.Error("Unable to stage")
+	assert.Equal(t, "Staging files\n2 file(s) added\nUnable to stage\n", buf.String())
+}
+
+func TestEmitterJSON(t *testing.T) {
+	buf := new(bytes.Buffer)
+	e := NewEmitter(buf)
+	e.EnableJSON("stage")
+	e.Printf("Staging files\n\n")
+	e.Result(map[string]int{"added": 2}, func(w io.Writer) {
+		t.Error("the human output was written in JSON mode")
+	})
+	e.Error("Unable to stage")
+
+	var records []Record
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		var r Record
+		assert.NoError(t, json.Unmarshal([]byte(line), &r))
+		records = append(records, r)
+	}
+	if assert.Len(t, records, 3) {
+		assert.Equal(t, "message", records[0].Type)
+		assert.Equal(t, "Staging files", records[0].Text)
+		assert.Equal(t, "result", records[1].Type)
+		assert.Equal(t, map[string]interface{}{"added": float64(2)}, records[1].Data)
+		assert.Equal(t, "error", records[2].Type)
+		assert.Equal(t, "stage", records[2].Command)
+	}
+}
@@ -0,0 +1,70 @@
+package display
+
+import (
+	"encoding/json"
+	"io"
+	"net"
+	"sync"
+	"time"
+)
+
+// Event is a machine readable report of the progress of a long operation,
+// written as a line of JSON so other programs can render their own progress.
+type Event struct {
+	// Type is "start", "progress" or "finish".
+	Type      string `json:"type"`
+	Operation string `json:"operation"`
+	// Unit is "bytes" or "items".
+	Unit  string `json:"unit"`
+	Done  int64  `json:"done"`
+	Total int64  `json:"total"`
+	// Rate is the units of work done per second, averaged over the last
+	// thirty seconds.
+	Rate float64 `json:"rate"`
+	// Remaining is the estimated number of seconds left, omitted until
+	// there is a rate to estimate from.
+	Remaining *float64 `json:"remaining,omitempty"`
+	// FilesDone and FilesTotal count the files the bytes are spread over,
+	// for operations on many files.
+	FilesDone  int64     `json:"filesDone,omitempty"`
+	FilesTotal int64     `json:"filesTotal,omitempty"`
+	Time       time.Time `json:"time"`
+}
+
+var (
+	eventsLock sync.Mutex
+	events     io.Writer
+)
+
+// StreamEvents writes progress events to w as newline delimited JSON.
+func StreamEvents(w io.Writer) {
+	eventsLock.Lock()
+	defer eventsLock.Unlock()
+	events = w
+}
+
+// StreamEventsTo connects to the unix socket at path and streams progress
+// events to it.
+func StreamEventsTo(path string) error {
+	conn, err := net.Dial("unix", path)
+	if err != nil {
+		return err
+	}
+	StreamEvents(conn)
+	return nil
+}
+
+// emit writes the event to the stream, if there is one. Events that can't be
+// written are dropped so a closed listener doesn't interrupt the operation.
+func emit(e Event) {
+	eventsLock.Lock()
+	defer eventsLock.Unlock()
+	if events == nil {
+		return
+	}
+	line, err := json.Marshal(e)
+	if err != nil {
+		return
+	}
+	events.Write(append(line, '\n'))
+}
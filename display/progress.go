@@ -0,0 +1,287 @@
+package display
+
+import (
+	"fmt"
+	"io/ioutil"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/arken/ait/utils"
+	"github.com/schollz/progressbar/v3"
+)
+
+// rateWindow is how far back the throughput of an operation is averaged over,
+// long enough to smooth out files of very different sizes.
+const rateWindow = 30 * time.Second
+
+// sampleInterval bounds how many samples the window holds for operations
+// reporting many small pieces of work, like copied bytes.
+const sampleInterval = 250 * time.Millisecond
+
+// costHalfLife is how quickly the cost of files and bytes adapts to a change,
+// ie in the network's speed. It's longer than rateWindow so that the model
+// sees both small and large files of a mixed dataset.
+const costHalfLife = 5 * time.Minute
+
+// sample is the amount of work done by a point in time.
+type sample struct {
+	time  time.Time
+	done  int64
+	files int64
+}
+
+// Progress is a progress bar showing the throughput of an operation, averaged
+// over the last rateWindow, and the estimated time until it finishes. It is
+// safe for concurrent use.
+type Progress struct {
+	lock  sync.Mutex
+	bar   *progressbar.ProgressBar
+	label string
+	bytes bool
+	total int64
+	done  int64
+	// totalFiles is the number of files the bytes are spread over, or 0
+	// when files aren't counted.
+	totalFiles int64
+	filesDone  int64
+	cost       costModel
+	samples    []sample
+	// emitted is when the last progress event was written.
+	emitted  time.Time
+	finished bool
+}
+
+// NewProgress returns a progress bar for an operation with total units of
+// work, which are bytes if bytes is set and items otherwise.
+func NewProgress(label string, total int64, bytes bool) *Progress {
+	options := []progressbar.Option{
+		progressbar.OptionSetDescription(label),
+		progressbar.OptionSetPredictTime(false),
+		progressbar.OptionSetRenderBlankState(true),
+		progressbar.OptionThrottle(65 * time.Millisecond),
+		progressbar.OptionSpinnerType(14),
+		progressbar.OptionFullWidth(),
+		progressbar.OptionOnCompletion(func() { fmt.Println() }),
+	}
+	if !bytes {
+		options = append(options, progressbar.OptionShowCount())
+	}
+	// Progress is followed through events rather than bars in JSON mode.
+	if Out.JSON() {
+		options = append(options, progressbar.OptionSetWriter(ioutil.Discard),
+			progressbar.OptionOnCompletion(func() {}))
+	}
+	p := &Progress{
+		bar:     progressbar.NewOptions64(total, options...),
+		label:   label,
+		bytes:   bytes,
+		total:   total,
+		samples: []sample{{time.Now(), 0, 0}},
+	}
+	emit(p.event("start"))
+	return p
+}
+
+// NewFileProgress returns a progress bar for an operation on files holding
+// bytes in total. The estimate accounts for the time spent per file as well
+// as per byte, so it holds for a million tiny files as well as a few huge
+// ones. Files are marked as done with AddFile.
+func NewFileProgress(label string, files, bytes int64) *Progress {
+	p := NewProgress(label, bytes, true)
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	p.totalFiles = files
+	p.bar.Describe(p.describe())
+	return p
+}
+
+// Add records n more units of work as done.
+func (p *Progress) Add(n int64) {
+	p.add(0, n)
+}
+
+// AddFile records a file of size bytes as done. Bytes already reported with
+// Add or Write shouldn't be counted again, ie with AddFile(0).
+func (p *Progress) AddFile(size int64) {
+	p.add(1, size)
+}
+
+// add records files and n units of work as done.
+func (p *Progress) add(files, n int64) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	p.done += n
+	p.filesDone += files
+	now := time.Now()
+	p.record(now)
+	p.bar.Describe(p.describe())
+	p.bar.Add64(n)
+	switch {
+	case p.done >= p.total && !p.finished:
+		p.finished = true
+		emit(p.event("finish"))
+	case now.Sub(p.emitted) >= sampleInterval:
+		p.emitted = now
+		emit(p.event("progress"))
+	}
+}
+
+// Write records len(b) bytes as done, so data can be copied through the
+// progress bar.
+func (p *Progress) Write(b []byte) (int, error) {
+	p.Add(int64(len(b)))
+	return len(b), nil
+}
+
+// Refresh redraws the bar so the throughput and estimate stay current while
+// no work is finishing.
+func (p *Progress) Refresh() {
+	p.Add(0)
+}
+
+// Done returns the units of work done so far.
+func (p *Progress) Done() int64 {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	return p.done
+}
+
+// Finished returns true once all of the work is done.
+func (p *Progress) Finished() bool {
+	return p.Done() >= p.total
+}
+
+// record adds a sample of the work done at now, dropping samples older than
+// the window except the last of them so the average always spans it. Samples
+// are kept at least sampleInterval apart by replacing the latest one.
+func (p *Progress) record(now time.Time) {
+	n := len(p.samples)
+	if n > 1 && now.Sub(p.samples[n-2].time) < sampleInterval {
+		p.samples[n-1] = sample{now, p.done, p.filesDone}
+	} else {
+		// The latest sample won't be replaced anymore.
+		if n > 1 {
+			p.cost.add(p.samples[n-2], p.samples[n-1])
+		}
+		p.samples = append(p.samples, sample{now, p.done, p.filesDone})
+	}
+	start := 0
+	for start < len(p.samples)-1 && now.Sub(p.samples[start+1].time) >= rateWindow {
+		start++
+	}
+	p.samples = p.samples[start:]
+}
+
+// rate returns the units of work done per second over the window.
+func (p *Progress) rate() float64 {
+	first, last := p.samples[0], p.samples[len(p.samples)-1]
+	elapsed := last.time.Sub(first.time).Seconds()
+	if elapsed <= 0 {
+		return 0
+	}
+	return float64(last.done-first.done) / elapsed
+}
+
+// remaining returns the estimated time until the work is done, and false if
+// no work has been done within the window to estimate from.
+func (p *Progress) remaining() (time.Duration, bool) {
+	if p.totalFiles > 0 {
+		seconds, ok := p.cost.estimate(p.totalFiles-p.filesDone, p.total-p.done)
+		return time.Duration(seconds * float64(time.Second)).Round(time.Second), ok
+	}
+	rate := p.rate()
+	if rate <= 0 {
+		return 0, false
+	}
+	seconds := float64(p.total-p.done) / rate
+	return time.Duration(seconds * float64(time.Second)).Round(time.Second), true
+}
+
+// event returns an event of the given type with the current progress.
+func (p *Progress) event(kind string) Event {
+	e := Event{
+		Type:       kind,
+		Operation:  p.label,
+		Unit:       "items",
+		Done:       p.done,
+		Total:      p.total,
+		Rate:       p.rate(),
+		FilesDone:  p.filesDone,
+		FilesTotal: p.totalFiles,
+		Time:       time.Now(),
+	}
+	if p.bytes {
+		e.Unit = "bytes"
+	}
+	if left, ok := p.remaining(); ok {
+		seconds := left.Seconds()
+		e.Remaining = &seconds
+	}
+	return e
+}
+
+// describe returns the label followed by the throughput and estimate.
+func (p *Progress) describe() string {
+	rate := p.rate()
+	speed := fmt.Sprintf("%.1f/s", rate)
+	if p.bytes {
+		speed = utils.FormatByteSize(int64(rate)) + "/s"
+	} else if rate < 1 {
+		speed = fmt.Sprintf("%.1f/min", rate*60)
+	}
+	eta := "--"
+	if left, ok := p.remaining(); ok {
+		eta = left.String()
+	}
+	if p.totalFiles > 0 {
+		return fmt.Sprintf("%v %d/%d files %v, %v left", p.label, p.filesDone, p.totalFiles, speed, eta)
+	}
+	return fmt.Sprintf("%v %v, %v left", p.label, speed, eta)
+}
+
+// costModel fits the time work takes as a cost per file plus a cost per byte,
+// by least squares over the intervals between samples. Older intervals count
+// less, halving every costHalfLife.
+type costModel struct {
+	nn, bb, nb, tn, tb float64
+}
+
+// add records the work done between two samples.
+func (m *costModel) add(from, to sample) {
+	dt := to.time.Sub(from.time).Seconds()
+	dn, db := float64(to.files-from.files), float64(to.done-from.done)
+	decay := math.Pow(0.5, dt/costHalfLife.Seconds())
+	m.nn = m.nn*decay + dn*dn
+	m.bb = m.bb*decay + db*db
+	m.nb = m.nb*decay + dn*db
+	m.tn = m.tn*decay + dt*dn
+	m.tb = m.tb*decay + dt*db
+}
+
+// estimate returns the seconds needed for the given files and bytes, and
+// false if no work has been recorded to estimate from.
+func (m *costModel) estimate(files, bytes int64) (float64, bool) {
+	perFile, perByte := 0.0, 0.0
+	if m.nn > 0 {
+		perFile = m.tn / m.nn
+	}
+	if m.bb > 0 {
+		perByte = m.tb / m.bb
+	}
+	if perFile <= 0 && perByte <= 0 {
+		return 0, false
+	}
+	// Only when files of different sizes have been seen can the two costs
+	// be told apart, otherwise either one alone explains the time taken and
+	// the longer of their estimates is given.
+	det := m.nn*m.bb - m.nb*m.nb
+	if det > 1e-9*m.nn*m.bb {
+		a := (m.tn*m.bb - m.tb*m.nb) / det
+		b := (m.tb*m.nn - m.tn*m.nb) / det
+		if a >= 0 && b >= 0 {
+			return a*float64(files) + b*float64(bytes), true
+		}
+	}
+	return math.Max(perFile*float64(files), perByte*float64(bytes)), true
+}
@@ -0,0 +1,102 @@
+package display
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"testing"
+	"time"
+
+	"github.com/schollz/progressbar/v3"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProgressRate(t *testing.T) {
+	start := time.Now()
+	p := &Progress{
+		bar:     progressbar.NewOptions64(1400, progressbar.OptionSetWriter(ioutil.Discard)),
+		total:   1400,
+		samples: []sample{{start, 0, 0}},
+	}
+	_, ok := p.remaining()
+	assert.False(t, ok)
+
+	// 10 units a second for the first minute.
+	for i := 1; i <= 6; i++ {
+		p.done += 100
+		p.record(start.Add(time.Duration(i) * 10 * time.Second))
+	}
+	assert.InDelta(t, 10, p.rate(), 0.01)
+
+	// Only the last rateWindow counts, so the rate reflects the recent speed
+	// of 20 units a second.
+	for i := 7; i <= 9; i++ {
+		p.done += 200
+		p.record(start.Add(time.Duration(i) * 10 * time.Second))
+	}
+	assert.InDelta(t, 20, p.rate(), 0.01)
+	left, ok := p.remaining()
+	assert.True(t, ok)
+	assert.Equal(t, 10*time.Second, left)
+}
+
+func TestProgressEvents(t *testing.T) {
+	var buf bytes.Buffer
+	StreamEvents(&buf)
+	defer StreamEvents(nil)
+
+	p := NewProgress("Adding", 2, true)
+	p.Add(1)
+	p.Add(1)
+
+	var types []string
+	decoder := json.NewDecoder(&buf)
+	for decoder.More() {
+		var e Event
+		assert.NoError(t, decoder.Decode(&e))
+		assert.Equal(t, "Adding", e.Operation)
+		assert.Equal(t, "bytes", e.Unit)
+		types = append(types, e.Type)
+	}
+	assert.Equal(t, []string{"start", "progress", "finish"}, types)
+}
+
+func TestProgressFileCost(t *testing.T) {
+	// Each file costs 10ms and each byte 10ns, so a thousand 1 KB files take
+	// as long as a single 1 GB file although they hold a thousandth of the
+	// bytes.
+	cost := func(files, bytes int64) time.Duration {
+		return time.Duration(files)*10*time.Millisecond + time.Duration(bytes)*10*time.Nanosecond
+	}
+	start := time.Now()
+	p := &Progress{
+		bar:        progressbar.NewOptions64(1e9+4e6, progressbar.OptionSetWriter(ioutil.Discard)),
+		bytes:      true,
+		total:      1e9 + 4e6,
+		totalFiles: 4001,
+		samples:    []sample{{start, 0, 0}},
+	}
+	_, ok := p.remaining()
+	assert.False(t, ok)
+
+	now := start
+	work := func(files, size int64) {
+		for i := int64(0); i < files; i++ {
+			now = now.Add(cost(1, size))
+			p.done += size
+			p.filesDone++
+			p.record(now)
+		}
+	}
+	work(1000, 1000)
+	_, ok = p.remaining()
+	assert.True(t, ok)
+
+	// Once files of both sizes went through, the per file and per byte
+	// costs are told apart: the remaining tiny files are expected to take
+	// 20s, where their share of the bytes alone would suggest under a second.
+	work(1, 1e9)
+	work(1000, 1000)
+	left, _ := p.remaining()
+	assert.InDelta(t, cost(2000, 2e6).Seconds(), left.Seconds(), 1)
+}
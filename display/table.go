@@ -0,0 +1,327 @@
+package display
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/arken/ait/utils"
+)
+
+// Kinds of values a column holds, deciding how it is aligned, sorted and
+// filtered.
+const (
+	// Text columns hold strings, filtered with "=" by glob patterns.
+	Text = iota
+	// Size columns hold int64 byte counts, filtered by sizes like "1.5MB".
+	Size
+	// Time columns hold a time.Time, filtered by dates like "2021-03-01" or
+	// RFC 3339 times. The zero time is shown as "-".
+	Time
+	// Count columns hold ints, filtered by numbers. Negative counts aren't
+	// known and are shown as "-".
+	Count
+)
+
+// Column is a column of a Table.
+type Column struct {
+	Name string
+	Kind int
+}
+
+// Row is a row of a Table, holding a value for each column.
+type Row []interface{}
+
+// Table is rows of values rendered as aligned columns in a terminal, which
+// can be sorted, filtered and split in pages to review large sets.
+type Table struct {
+	Columns []Column
+	Rows    []Row
+}
+
+// column returns the index of the column named name, ignoring case.
+func (t *Table) column(name string) (int, error) {
+	names := make([]string, len(t.Columns))
+	for i, column := range t.Columns {
+		if strings.EqualFold(column.Name, strings.TrimSpace(name)) {
+			return i, nil
+		}
+		names[i] = strings.ToLower(column.Name)
+	}
+	return 0, fmt.Errorf("there is no column %q, expected one of %v", name, strings.Join(names, ", "))
+}
+
+// Sort sorts the rows by the named column, in descending order if the name is
+// prefixed with "-". Rows with equal values keep their order.
+func (t *Table) Sort(by string) error {
+	desc := strings.HasPrefix(by, "-")
+	i, err := t.column(strings.TrimPrefix(by, "-"))
+	if err != nil {
+		return err
+	}
+	sort.SliceStable(t.Rows, func(a, b int) bool {
+		if desc {
+			a, b = b, a
+		}
+		return compare(t.Rows[a][i], t.Rows[b][i]) < 0
+	})
+	return nil
+}
+
+// Filter keeps the rows matching every comma separated expression of filter.
+// An expression compares a column to a value with =, !=, <, <=, > or >=, ie
+// "size>1MB,name=*.pdf,added>=2021-03-01".
+func (t *Table) Filter(filter string) error {
+	var tests []func(Row) bool
+	for _, expr := range strings.Split(filter, ",") {
+		if strings.TrimSpace(expr) == "" {
+			continue
+		}
+		test, err := t.parseFilter(expr)
+		if err != nil {
+			return err
+		}
+		tests = append(tests, test)
+	}
+	rows := t.Rows[:0]
+	for _, row := range t.Rows {
+		matched := true
+		for _, test := range tests {
+			matched = matched && test(row)
+		}
+		if matched {
+			rows = append(rows, row)
+		}
+	}
+	t.Rows = rows
+	return nil
+}
+
+// filterOperators are the operators of filter expressions, two character ones
+// first so they aren't read as one character ones.
+var filterOperators = []string{"!=", "<=", ">=", "=", "<", ">"}
+
+// parseFilter returns a test of whether a row matches the expression.
+func (t *Table) parseFilter(expr string) (func(Row) bool, error) {
+	at := strings.IndexAny(expr, "!=<>")
+	if at < 0 {
+		return nil, fmt.Errorf("the filter %q has no operator, expected one of %v",
+			expr, strings.Join(filterOperators, " "))
+	}
+	var op string
+	for _, candidate := range filterOperators {
+		if strings.HasPrefix(expr[at:], candidate) {
+			op = candidate
+			break
+		}
+	}
+	if op == "" {
+		return nil, fmt.Errorf("the filter %q has no operator, expected one of %v",
+			expr, strings.Join(filterOperators, " "))
+	}
+	i, err := t.column(expr[:at])
+	if err != nil {
+		return nil, err
+	}
+	value := strings.TrimSpace(expr[at+len(op):])
+	cmp, err := comparer(t.Columns[i].Kind, value)
+	if err != nil {
+		return nil, fmt.Errorf("the filter %q: %v", expr, err)
+	}
+	return func(row Row) bool {
+		c := cmp(row[i])
+		switch op {
+		case "=":
+			return c == 0
+		case "!=":
+			return c != 0
+		case "<":
+			return c < 0
+		case "<=":
+			return c <= 0
+		case ">":
+			return c > 0
+		default:
+			return c >= 0
+		}
+	}, nil
+}
+
+// comparer parses value as a value of a column of kind, and returns a function
+// comparing the column's values to it.
+func comparer(kind int, value string) (func(interface{}) int, error) {
+	switch kind {
+	case Size:
+		size, err := utils.ParseByteSize(value)
+		if err != nil {
+			return nil, err
+		}
+		return func(v interface{}) int { return compare(v, size) }, nil
+	case Count:
+		count, err := strconv.Atoi(value)
+		if err != nil {
+			return nil, fmt.Errorf("expected a number, got %q", value)
+		}
+		return func(v interface{}) int { return compare(v, count) }, nil
+	case Time:
+		// A date matches the whole day.
+		precision := 24 * time.Hour
+		at, err := time.ParseInLocation("2006-01-02", value, time.Local)
+		if err != nil {
+			precision = time.Second
+			if at, err = time.Parse(time.RFC3339, value); err != nil {
+				return nil, fmt.Errorf("expected a date like 2006-01-02 or a time like 2006-01-02T15:04:05Z, got %q", value)
+			}
+		}
+		return func(v interface{}) int {
+			switch t := v.(time.Time); {
+			case t.Before(at):
+				return -1
+			case t.Before(at.Add(precision)):
+				return 0
+			default:
+				return 1
+			}
+		}, nil
+	default:
+		if _, err := filepath.Match(value, ""); err != nil {
+			return nil, fmt.Errorf("invalid pattern %q", value)
+		}
+		return func(v interface{}) int {
+			if matched, _ := filepath.Match(value, v.(string)); matched {
+				return 0
+			}
+			return compare(v, value)
+		}, nil
+	}
+}
+
+// compare returns -1, 0 or 1 as a is less than, equal to or greater than b,
+// which hold the same kind of value.
+func compare(a, b interface{}) int {
+	switch a := a.(type) {
+	case int64:
+		switch b := b.(int64); {
+		case a < b:
+			return -1
+		case a > b:
+			return 1
+		}
+		return 0
+	case int:
+		switch b := b.(int); {
+		case a < b:
+			return -1
+		case a > b:
+			return 1
+		}
+		return 0
+	case time.Time:
+		switch b := b.(time.Time); {
+		case a.Before(b):
+			return -1
+		case a.After(b):
+			return 1
+		}
+		return 0
+	default:
+		return strings.Compare(fmt.Sprint(a), fmt.Sprint(b))
+	}
+}
+
+// Pages returns the number of pages of size rows the table spans, at least 1.
+// A size of 0 or less puts every row on one page.
+func (t *Table) Pages(size int) int {
+	if size <= 0 || len(t.Rows) == 0 {
+		return 1
+	}
+	return (len(t.Rows) + size - 1) / size
+}
+
+// Render writes the page of size rows of the table, numbered from 1, to w as
+// aligned columns under a header. Only the rows of the page are measured, so
+// rendering a page of a huge table is quick.
+func (t *Table) Render(w io.Writer, page, size int) error {
+	if page < 1 || page > t.Pages(size) {
+		return fmt.Errorf("page %d doesn't exist, there are %d", page, t.Pages(size))
+	}
+	rows := t.Rows
+	if size > 0 && len(rows) > 0 {
+		end := page * size
+		if end > len(rows) {
+			end = len(rows)
+		}
+		rows = rows[(page-1)*size : end]
+	}
+
+	cells := make([][]string, 0, len(rows)+1)
+	header := make([]string, len(t.Columns))
+	for i, column := range t.Columns {
+		header[i] = strings.ToUpper(column.Name)
+	}
+	cells = append(cells, header)
+	for _, row := range rows {
+		line := make([]string, len(t.Columns))
+		for i, value := range row {
+			line[i] = formatCell(value)
+		}
+		cells = append(cells, line)
+	}
+	widths := make([]int, len(t.Columns))
+	for _, line := range cells {
+		for i, cell := range line {
+			if len(cell) > widths[i] {
+				widths[i] = len(cell)
+			}
+		}
+	}
+	for _, line := range cells {
+		var b strings.Builder
+		for i, cell := range line {
+			if i > 0 {
+				b.WriteString("  ")
+			}
+			pad := strings.Repeat(" ", widths[i]-len(cell))
+			switch {
+			case t.Columns[i].Kind == Size || t.Columns[i].Kind == Count:
+				b.WriteString(pad + cell)
+			case i == len(line)-1:
+				b.WriteString(cell)
+			default:
+				b.WriteString(cell + pad)
+			}
+		}
+		if _, err := fmt.Fprintln(w, b.String()); err != nil {
+			return err
+		}
+	}
+	if t.Pages(size) > 1 {
+		_, err := fmt.Fprintf(w, "Page %d of %d, %d rows in total.\n", page, t.Pages(size), len(t.Rows))
+		return err
+	}
+	return nil
+}
+
+// formatCell returns how a value is shown in the table.
+func formatCell(value interface{}) string {
+	switch value := value.(type) {
+	case int64:
+		return utils.FormatByteSize(value)
+	case int:
+		if value < 0 {
+			return "-"
+		}
+		return strconv.Itoa(value)
+	case time.Time:
+		if value.IsZero() {
+			return "-"
+		}
+		return value.Local().Format("2006-01-02 15:04")
+	default:
+		return fmt.Sprint(value)
+	}
+}
@@ -0,0 +1,80 @@
+package display
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func testTable() *Table {
+	day := time.Date(2021, 3, 1, 12, 0, 0, 0, time.Local)
+	return &Table{
+		Columns: []Column{{"Name", Text}, {"Size", Size}, {"Added", Time}, {"Peers", Count}},
+		Rows: []Row{
+			{"b.pdf", int64(2048), day, 12},
+			{"a.txt", int64(10), day.Add(48 * time.Hour), 3},
+			{"c.pdf", int64(5 << 20), time.Time{}, -1},
+		},
+	}
+}
+
+func names(t *Table) (names []string) {
+	for _, row := range t.Rows {
+		names = append(names, row[0].(string))
+	}
+	return names
+}
+
+func TestTableSort(t *testing.T) {
+	table := testTable()
+	assert.NoError(t, table.Sort("name"))
+	assert.Equal(t, []string{"a.txt", "b.pdf", "c.pdf"}, names(table))
+	assert.NoError(t, table.Sort("-SIZE"))
+	assert.Equal(t, []string{"c.pdf", "b.pdf", "a.txt"}, names(table))
+	assert.NoError(t, table.Sort("added"))
+	assert.Equal(t, []string{"c.pdf", "b.pdf", "a.txt"}, names(table))
+	assert.NoError(t, table.Sort("-peers"))
+	assert.Equal(t, []string{"b.pdf", "a.txt", "c.pdf"}, names(table))
+	assert.Error(t, table.Sort("cid"))
+}
+
+func TestTableFilter(t *testing.T) {
+	for filter, want := range map[string][]string{
+		"name=*.pdf":                 {"b.pdf", "c.pdf"},
+		"name!=*.pdf":                {"a.txt"},
+		"size>1KB":                   {"b.pdf", "c.pdf"},
+		"size>1KB, name=b*":          {"b.pdf"},
+		"added=2021-03-01":           {"b.pdf"},
+		"added>=2021-03-02":          {"a.txt"},
+		"added<2021-03-01":           {"c.pdf"},
+		"size<=2048,size>=2048":      {"b.pdf"},
+		"added>2021-03-01T00:00:00Z": {"b.pdf", "a.txt"},
+		"peers>=3":                   {"b.pdf", "a.txt"},
+		"peers<10":                   {"a.txt", "c.pdf"},
+	} {
+		table := testTable()
+		assert.NoError(t, table.Filter(filter), filter)
+		assert.Equal(t, want, names(table), filter)
+	}
+	for _, bad := range []string{"name", "cid=x", "size>big", "added>yesterday", "name=[", "name!x", "peers>few"} {
+		assert.Error(t, testTable().Filter(bad), bad)
+	}
+}
+
+func TestTableRender(t *testing.T) {
+	table := testTable()
+	out := &bytes.Buffer{}
+	assert.NoError(t, table.Render(out, 1, 0))
+	assert.Equal(t, "NAME    SIZE  ADDED             PEERS\n"+
+		"b.pdf  2.0KB  2021-03-01 12:00     12\n"+
+		"a.txt    10B  2021-03-03 12:00      3\n"+
+		"c.pdf  5.0MB  -                     -\n", out.String())
+
+	out.Reset()
+	assert.Equal(t, 2, table.Pages(2))
+	assert.NoError(t, table.Render(out, 2, 2))
+	assert.Equal(t, "NAME    SIZE  ADDED  PEERS\nc.pdf  5.0MB  -          -\nPage 2 of 2, 3 rows in total.\n", out.String())
+	assert.Error(t, table.Render(out, 3, 2))
+}
@@ -0,0 +1,257 @@
+package display
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/arken/ait/utils"
+	"github.com/schollz/progressbar/v3"
+)
+
+// minShownTransfer is the size of the smallest upload shown and of the
+// smallest transfer summarized once done, smaller ones take no time at all.
+const minShownTransfer = 256 * 1024
+
+// Transfer shows the progress of a clone, fetch or upload: the bytes moved
+// and their speed, averaged over the last rateWindow, the estimated time left
+// when the size is known, and what the remote reports on git's sideband, ie
+// "Counting objects:  45% (450/1000)". It is safe for concurrent use.
+type Transfer struct {
+	lock  sync.Mutex
+	bar   *progressbar.ProgressBar
+	label string
+	// total is the size of the transfer, or 0 when it isn't known.
+	total   int64
+	done    int64
+	remote  string
+	started time.Time
+	samples []sample
+	// emitted is when the last progress event was written.
+	emitted  time.Time
+	finished bool
+}
+
+// NewTransfer returns the progress of a transfer of total bytes, or of an
+// unknown size if total is 0.
+func NewTransfer(label string, total int64) *Transfer {
+	max := total
+	if max <= 0 {
+		max = -1
+	}
+	options := []progressbar.Option{
+		progressbar.OptionSetDescription(label),
+		progressbar.OptionSetPredictTime(false),
+		progressbar.OptionThrottle(65 * time.Millisecond),
+		progressbar.OptionSpinnerType(14),
+		progressbar.OptionFullWidth(),
+		progressbar.OptionClearOnFinish(),
+	}
+	if Out.JSON() {
+		options = append(options, progressbar.OptionSetWriter(ioutil.Discard))
+	}
+	now := time.Now()
+	t := &Transfer{
+		bar:     progressbar.NewOptions64(max, options...),
+		label:   label,
+		total:   total,
+		started: now,
+		samples: []sample{{now, 0, 0}},
+	}
+	emit(t.event("start"))
+	return t
+}
+
+// Add records n more bytes as transferred.
+func (t *Transfer) Add(n int64) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	if t.finished {
+		return
+	}
+	t.done += n
+	now := time.Now()
+	t.record(now)
+	t.bar.Describe(t.describe())
+	t.bar.Add64(n)
+	if now.Sub(t.emitted) >= sampleInterval {
+		t.emitted = now
+		emit(t.event("progress"))
+	}
+}
+
+// Write receives the progress messages of the remote, so it can be given as
+// the Progress of go-git's options. Only the latest message is shown.
+func (t *Transfer) Write(b []byte) (int, error) {
+	lines := strings.FieldsFunc(string(b), func(r rune) bool { return r == '\r' || r == '\n' })
+	for i := len(lines) - 1; i >= 0; i-- {
+		if line := strings.TrimSpace(lines[i]); line != "" {
+			t.lock.Lock()
+			t.remote = strings.TrimSuffix(line, ", done.")
+			t.lock.Unlock()
+			break
+		}
+	}
+	t.Add(0)
+	return len(b), nil
+}
+
+// Finish clears the bar and, unless little was transferred, prints how much
+// and how fast. It can be called more than once.
+func (t *Transfer) Finish() {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	if t.finished {
+		return
+	}
+	t.finished = true
+	_ = t.bar.Finish()
+	emit(t.event("finish"))
+	if t.done < minShownTransfer || Out.JSON() {
+		return
+	}
+	elapsed := time.Since(t.started)
+	speed := float64(t.done) / elapsed.Seconds()
+	fmt.Printf("%v: %v in %v, %v/s\n", t.label, utils.FormatByteSize(t.done),
+		elapsed.Round(100*time.Millisecond), utils.FormatByteSize(int64(speed)))
+}
+
+// record adds a sample of the bytes transferred at now, keeping those within
+// the window as Progress does.
+func (t *Transfer) record(now time.Time) {
+	n := len(t.samples)
+	if n > 1 && now.Sub(t.samples[n-2].time) < sampleInterval {
+		t.samples[n-1] = sample{now, t.done, 0}
+	} else {
+		t.samples = append(t.samples, sample{now, t.done, 0})
+	}
+	start := 0
+	for start < len(t.samples)-1 && now.Sub(t.samples[start+1].time) >= rateWindow {
+		start++
+	}
+	t.samples = t.samples[start:]
+}
+
+// rate returns the bytes transferred per second over the window.
+func (t *Transfer) rate() float64 {
+	first, last := t.samples[0], t.samples[len(t.samples)-1]
+	elapsed := last.time.Sub(first.time).Seconds()
+	if elapsed <= 0 {
+		return 0
+	}
+	return float64(last.done-first.done) / elapsed
+}
+
+// remaining returns the estimated time until the transfer is done, and false
+// if its size isn't known or nothing was transferred within the window.
+func (t *Transfer) remaining() (time.Duration, bool) {
+	rate := t.rate()
+	if t.total <= 0 || rate <= 0 {
+		return 0, false
+	}
+	seconds := float64(t.total-t.done) / rate
+	return time.Duration(seconds * float64(time.Second)).Round(time.Second), true
+}
+
+// event returns an event of the given type with the current progress.
+func (t *Transfer) event(kind string) Event {
+	e := Event{
+		Type:      kind,
+		Operation: t.label,
+		Unit:      "bytes",
+		Done:      t.done,
+		Total:     t.total,
+		Rate:      t.rate(),
+		Time:      time.Now(),
+	}
+	if left, ok := t.remaining(); ok {
+		seconds := left.Seconds()
+		e.Remaining = &seconds
+	}
+	return e
+}
+
+// describe returns the label followed by the remote's latest message, the
+// bytes transferred, their speed and the estimate.
+func (t *Transfer) describe() string {
+	var b strings.Builder
+	b.WriteString(t.label)
+	if t.remote != "" {
+		fmt.Fprintf(&b, " (%v)", t.remote)
+	}
+	fmt.Fprintf(&b, " %v at %v/s", utils.FormatByteSize(t.done), utils.FormatByteSize(int64(t.rate())))
+	if left, ok := t.remaining(); ok {
+		fmt.Fprintf(&b, ", %v left", left)
+	}
+	return b.String()
+}
+
+// Transport returns base counting the bytes of every request and response
+// body it carries as part of the transfer.
+func (t *Transfer) Transport(base http.RoundTripper) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return transferTransport{base: base, transfer: t}
+}
+
+// transferTransport counts the bodies of requests and responses into a
+// transfer.
+type transferTransport struct {
+	base     http.RoundTripper
+	transfer *Transfer
+}
+
+func (tt transferTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Body != nil && req.Body != http.NoBody {
+		req = req.Clone(req.Context())
+		req.Body = countingBody{req.Body, tt.transfer}
+	}
+	resp, err := tt.base.RoundTrip(req)
+	if err == nil {
+		resp.Body = countingBody{resp.Body, tt.transfer}
+	}
+	return resp, err
+}
+
+// countingBody adds the bytes read from a body to a transfer.
+type countingBody struct {
+	io.ReadCloser
+	transfer *Transfer
+}
+
+func (c countingBody) Read(p []byte) (int, error) {
+	n, err := c.ReadCloser.Read(p)
+	c.transfer.Add(int64(n))
+	return n, err
+}
+
+// UploadTransport returns base showing the progress of the requests whose
+// body is large enough to take a while to send, ie keysets pushed through
+// the API of a forge.
+func UploadTransport(base http.RoundTripper) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return uploadTransport{base}
+}
+
+// uploadTransport shows the upload of large request bodies.
+type uploadTransport struct {
+	base http.RoundTripper
+}
+
+func (ut uploadTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Body == nil || req.ContentLength < minShownTransfer {
+		return ut.base.RoundTrip(req)
+	}
+	transfer := NewTransfer("Uploading to "+req.URL.Host, req.ContentLength)
+	defer transfer.Finish()
+	req = req.Clone(req.Context())
+	req.Body = countingBody{req.Body, transfer}
+	return ut.base.RoundTrip(req)
+}
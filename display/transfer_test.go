@@ -0,0 +1,68 @@
+package display
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/schollz/progressbar/v3"
+	"github.com/stretchr/testify/assert"
+)
+
+// quietTransfer returns a transfer drawing nothing.
+func quietTransfer(total int64) *Transfer {
+	now := time.Now()
+	return &Transfer{
+		bar:     progressbar.NewOptions64(-1, progressbar.OptionSetWriter(ioutil.Discard)),
+		label:   "Cloning",
+		total:   total,
+		started: now,
+		samples: []sample{{now, 0, 0}},
+	}
+}
+
+func TestTransferSideband(t *testing.T) {
+	tr := quietTransfer(0)
+	_, err := tr.Write([]byte("Enumerating objects: 5, done.\nCounting objects:  20% (1/5)\rCounting objects: 100% (5/5)"))
+	assert.NoError(t, err)
+	assert.Equal(t, "Counting objects: 100% (5/5)", tr.remote)
+	tr.Write([]byte("Compressing objects: 100% (3/3), done.\n\n"))
+	assert.Equal(t, "Compressing objects: 100% (3/3)", tr.remote)
+	assert.True(t, strings.HasPrefix(tr.describe(), "Cloning (Compressing objects: 100% (3/3)) 0B at 0B/s"))
+
+	// Without a size, no time left is estimated.
+	tr.done = 1000
+	tr.samples = []sample{{tr.started, 0, 0}, {tr.started.Add(time.Second), 1000, 0}}
+	_, ok := tr.remaining()
+	assert.False(t, ok)
+	tr.total = 3000
+	left, ok := tr.remaining()
+	assert.True(t, ok)
+	assert.Equal(t, 2*time.Second, left)
+}
+
+func TestTransferTransport(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		w.Write(bytes.Repeat([]byte("x"), 2*len(body)))
+	}))
+	defer server.Close()
+	tr := quietTransfer(0)
+	client := &http.Client{Transport: tr.Transport(nil)}
+	resp, err := client.Post(server.URL, "text/plain", strings.NewReader("0123456789"))
+	assert.NoError(t, err)
+	body, _ := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	assert.Len(t, body, 20)
+	assert.Equal(t, int64(30), tr.done)
+
+	// Small requests go through as they are.
+	client = &http.Client{Transport: UploadTransport(nil)}
+	resp, err = client.Post(server.URL, "text/plain", strings.NewReader("small"))
+	assert.NoError(t, err)
+	resp.Body.Close()
+}
@@ -0,0 +1,403 @@
+package display
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/arken/ait/types"
+	"github.com/arken/ait/utils"
+
+	"github.com/dustin/go-humanize"
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+// SubmitForm is the application filled in on the workbench, with the remote
+// it's submitted to.
+type SubmitForm struct {
+	Remote      string
+	Category    string
+	Filename    string
+	Title       string
+	Commit      string
+	PRBody      string
+	PullRequest bool
+	Issue       bool
+}
+
+// Workbench is an interactive terminal UI to browse the workspace, toggle
+// files in and out of the staged files, fill in the application and follow
+// its submission. What it does to the workspace is left to its actions.
+type Workbench struct {
+	// Staged returns the staged paths.
+	Staged func() ([]string, error)
+	// Stage stages path, a file or a directory, and returns how many files
+	// were staged.
+	Stage func(path string) (int, error)
+	// Unstage unstages path and every file staged under it, and returns how
+	// many files were unstaged.
+	Unstage func(path string) (int, error)
+	// Submit submits the form, writing what the submission prints, progress
+	// events included, to output. It returns once it's finished.
+	Submit func(form SubmitForm, output *WorkbenchOutput) error
+
+	app      *tview.Application
+	tree     *tview.TreeView
+	form     *tview.Form
+	output   *tview.TextView
+	progress *tview.TextView
+	status   *tview.TextView
+
+	// lock guards staged and busy, which actions running in the background
+	// change.
+	lock   sync.Mutex
+	staged map[string]bool
+	busy   bool
+}
+
+// workbenchEntry is the file or directory a node of the tree shows.
+type workbenchEntry struct {
+	path   string
+	name   string
+	dir    bool
+	loaded bool
+}
+
+const workbenchHelp = "Space: stage/unstage  Enter: open directory  Tab: switch panes  q: quit"
+
+// NewWorkbench returns a workbench submitting to one of remotes, with the
+// form filled in from app if it isn't nil.
+func NewWorkbench(remotes []string, app *types.ApplicationContents) *Workbench {
+	wb := &Workbench{app: tview.NewApplication(), staged: make(map[string]bool)}
+
+	root := tview.NewTreeNode(".").SetReference(&workbenchEntry{path: ".", name: ".", dir: true})
+	wb.tree = tview.NewTreeView().SetRoot(root).SetCurrentNode(root)
+	wb.tree.SetBorder(true).SetTitle(" Workspace ")
+	wb.tree.SetSelectedFunc(wb.open)
+	wb.tree.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		switch {
+		case event.Key() == tcell.KeyRune && event.Rune() == ' ':
+			wb.toggle(wb.tree.GetCurrentNode())
+			return nil
+		case event.Key() == tcell.KeyRune && event.Rune() == 'q':
+			wb.app.Stop()
+			return nil
+		}
+		return event
+	})
+
+	if app == nil {
+		app = &types.ApplicationContents{}
+	}
+	if len(remotes) == 0 {
+		remotes = []string{""}
+	}
+	wb.form = tview.NewForm().
+		AddDropDown("Remote", remotes, 0, nil).
+		AddInputField("Category", app.Category, 0, nil, nil).
+		AddInputField("Filename", app.KsName, 0, nil, nil).
+		AddInputField("Title", app.Title, 0, nil, nil).
+		AddInputField("Commit message", app.Commit, 0, nil, nil).
+		AddInputField("Pull request body", app.PRBody, 0, nil, nil).
+		AddCheckbox("Open a pull request", false, nil).
+		AddCheckbox("Open an issue", false, nil).
+		AddButton("Submit", wb.submit)
+	wb.form.SetBorder(true).SetTitle(" Application ")
+	wb.form.SetCancelFunc(func() { wb.app.SetFocus(wb.tree) })
+
+	wb.progress = tview.NewTextView()
+	wb.output = tview.NewTextView().SetScrollable(true)
+	wb.output.SetChangedFunc(func() { wb.app.Draw() })
+	submission := tview.NewFlex().SetDirection(tview.FlexRow).
+		AddItem(wb.progress, 1, 0, false).
+		AddItem(wb.output, 0, 1, false)
+	submission.SetBorder(true).SetTitle(" Submission ")
+
+	wb.status = tview.NewTextView().SetText(workbenchHelp)
+	panes := tview.NewFlex().
+		AddItem(wb.tree, 0, 1, true).
+		AddItem(tview.NewFlex().SetDirection(tview.FlexRow).
+			AddItem(wb.form, 0, 2, false).
+			AddItem(submission, 0, 1, false), 0, 1, false)
+	layout := tview.NewFlex().SetDirection(tview.FlexRow).
+		AddItem(panes, 0, 1, true).
+		AddItem(wb.status, 1, 0, false)
+
+	wb.tree.SetDoneFunc(func(key tcell.Key) {
+		if key == tcell.KeyTab {
+			wb.app.SetFocus(wb.form)
+		}
+	})
+	wb.app.SetRoot(layout, true).SetFocus(wb.tree)
+	return wb
+}
+
+// Run shows the workbench until it's quit.
+func (wb *Workbench) Run() error {
+	if err := wb.refresh(); err != nil {
+		return err
+	}
+	root := wb.tree.GetRoot()
+	wb.load(root)
+	wb.label(root)
+	return wb.app.Run()
+}
+
+// refresh reads the staged files again.
+func (wb *Workbench) refresh() error {
+	paths, err := wb.Staged()
+	if err != nil {
+		return err
+	}
+	staged := make(map[string]bool, len(paths))
+	for _, path := range paths {
+		staged[path] = true
+	}
+	wb.lock.Lock()
+	wb.staged = staged
+	wb.lock.Unlock()
+	return nil
+}
+
+// open expands or collapses a directory, reading its entries the first time.
+func (wb *Workbench) open(node *tview.TreeNode) {
+	entry := node.GetReference().(*workbenchEntry)
+	if !entry.dir {
+		return
+	}
+	if !entry.loaded {
+		wb.load(node)
+		wb.label(node)
+		node.Expand()
+		return
+	}
+	node.SetExpanded(!node.IsExpanded())
+}
+
+// load adds the entries of the directory of node as its children,
+// directories first.
+func (wb *Workbench) load(node *tview.TreeNode) {
+	entry := node.GetReference().(*workbenchEntry)
+	entry.loaded = true
+	infos, err := ioutil.ReadDir(entry.path)
+	if err != nil {
+		wb.setStatus("[red]" + tview.Escape(err.Error()))
+		return
+	}
+	sort.SliceStable(infos, func(i, j int) bool { return infos[i].IsDir() && !infos[j].IsDir() })
+	for _, info := range infos {
+		if info.Name() == ".ait" {
+			continue
+		}
+		child := &workbenchEntry{path: filepath.Join(entry.path, info.Name()), name: info.Name(), dir: info.IsDir()}
+		node.AddChild(tview.NewTreeNode("").SetReference(child).SetExpanded(false))
+	}
+}
+
+// label sets the text of node and the loaded nodes under it from the staged
+// files: staged files are marked and directories count theirs.
+func (wb *Workbench) label(node *tview.TreeNode) {
+	wb.lock.Lock()
+	defer wb.lock.Unlock()
+	node.Walk(func(node, _ *tview.TreeNode) bool {
+		entry := node.GetReference().(*workbenchEntry)
+		if !entry.dir {
+			if wb.staged[entry.path] {
+				node.SetText(tview.Escape("[x] " + entry.name)).SetColor(tcell.ColorGreen)
+			} else {
+				node.SetText(tview.Escape("[ ] " + entry.name)).SetColor(tcell.ColorWhite)
+			}
+			return true
+		}
+		count := 0
+		for path := range wb.staged {
+			if utils.MatchesStaged(entry.path, path) {
+				count++
+			}
+		}
+		text := entry.name + string(filepath.Separator)
+		if count > 0 {
+			text += fmt.Sprintf(" (%d staged)", count)
+			node.SetColor(tcell.ColorGreen)
+		} else {
+			node.SetColor(tcell.ColorTeal)
+		}
+		node.SetText(tview.Escape(text))
+		return true
+	})
+}
+
+// toggle stages the file or directory of node, or unstages it if anything
+// under it is staged. Staging a directory can take a while, so it's done in
+// the background.
+func (wb *Workbench) toggle(node *tview.TreeNode) {
+	entry := node.GetReference().(*workbenchEntry)
+	wb.lock.Lock()
+	if wb.busy {
+		wb.lock.Unlock()
+		return
+	}
+	staged := false
+	for path := range wb.staged {
+		staged = staged || utils.MatchesStaged(entry.path, path)
+	}
+	wb.busy = true
+	wb.lock.Unlock()
+
+	action, verb := wb.Stage, "staged"
+	if staged {
+		action, verb = wb.Unstage, "unstaged"
+	}
+	wb.setStatus(fmt.Sprintf("Updating %v...", tview.Escape(entry.path)))
+	go func() {
+		changed, err := action(entry.path)
+		if err == nil {
+			err = wb.refresh()
+		}
+		wb.app.QueueUpdateDraw(func() {
+			wb.lock.Lock()
+			wb.busy = false
+			wb.lock.Unlock()
+			if err != nil {
+				wb.setStatus("[red]" + tview.Escape(err.Error()))
+				return
+			}
+			wb.label(wb.tree.GetRoot())
+			wb.setStatus(fmt.Sprintf("%d file(s) %v  |  %v", changed, verb, workbenchHelp))
+		})
+	}()
+}
+
+// submit submits the form in the background, showing its output as it runs.
+func (wb *Workbench) submit() {
+	_, remote := wb.form.GetFormItemByLabel("Remote").(*tview.DropDown).GetCurrentOption()
+	form := SubmitForm{
+		Remote:      remote,
+		Category:    wb.text("Category"),
+		Filename:    wb.text("Filename"),
+		Title:       wb.text("Title"),
+		Commit:      wb.text("Commit message"),
+		PRBody:      wb.text("Pull request body"),
+		PullRequest: wb.form.GetFormItemByLabel("Open a pull request").(*tview.Checkbox).IsChecked(),
+		Issue:       wb.form.GetFormItemByLabel("Open an issue").(*tview.Checkbox).IsChecked(),
+	}
+	if form.Remote == "" || strings.TrimSpace(form.Title) == "" || strings.TrimSpace(form.Commit) == "" {
+		wb.setStatus("[red]A remote, title and commit message are required, \"ait remote add\" adds remotes")
+		return
+	}
+	wb.lock.Lock()
+	if wb.busy {
+		wb.lock.Unlock()
+		wb.setStatus("[red]Wait for the staged files to be updated, or the submission to finish")
+		return
+	}
+	wb.busy = true
+	wb.lock.Unlock()
+
+	wb.output.Clear()
+	wb.progress.Clear()
+	wb.setStatus("Submitting to " + tview.Escape(form.Remote) + "...")
+	output := &WorkbenchOutput{wb: wb}
+	go func() {
+		err := wb.Submit(form, output)
+		output.flush()
+		if err == nil {
+			err = wb.refresh()
+		}
+		wb.app.QueueUpdateDraw(func() {
+			wb.lock.Lock()
+			wb.busy = false
+			wb.lock.Unlock()
+			if err != nil {
+				wb.setStatus("[red]Submission failed: " + tview.Escape(err.Error()))
+				return
+			}
+			wb.label(wb.tree.GetRoot())
+			wb.setStatus("Submission finished  |  " + workbenchHelp)
+		})
+	}()
+}
+
+// text returns the text of the input field of the form labeled label.
+func (wb *Workbench) text(label string) string {
+	return wb.form.GetFormItemByLabel(label).(*tview.InputField).GetText()
+}
+
+// setStatus shows text, which may hold color tags, in the status line.
+func (wb *Workbench) setStatus(text string) {
+	wb.status.SetDynamicColors(true).SetText(text)
+}
+
+// WorkbenchOutput is written what a submission prints. Lines that are
+// progress events update the progress line of the workbench, others are
+// added to its output.
+type WorkbenchOutput struct {
+	wb      *Workbench
+	lock    sync.Mutex
+	partial []byte
+}
+
+func (o *WorkbenchOutput) Write(b []byte) (int, error) {
+	o.lock.Lock()
+	defer o.lock.Unlock()
+	o.partial = append(o.partial, b...)
+	for {
+		i := bytes.IndexByte(o.partial, '\n')
+		if i < 0 {
+			return len(b), nil
+		}
+		o.line(string(o.partial[:i]))
+		o.partial = o.partial[i+1:]
+	}
+}
+
+// flush writes the last line if it wasn't terminated.
+func (o *WorkbenchOutput) flush() {
+	o.lock.Lock()
+	defer o.lock.Unlock()
+	if len(o.partial) > 0 {
+		o.line(string(o.partial))
+		o.partial = nil
+	}
+}
+
+// line shows a line of output. Progress bars redraw themselves after a
+// carriage return, only what they drew last is kept.
+func (o *WorkbenchOutput) line(line string) {
+	if i := strings.LastIndexByte(line, '\r'); i >= 0 {
+		line = line[i+1:]
+	}
+	var event Event
+	if err := json.Unmarshal([]byte(line), &event); err == nil && event.Type != "" {
+		o.wb.app.QueueUpdateDraw(func() { o.wb.progress.SetText(describeEvent(event)) })
+		return
+	}
+	fmt.Fprintln(o.wb.output, line)
+}
+
+// describeEvent returns the progress line of a progress event.
+func describeEvent(e Event) string {
+	amount := func(n int64) string {
+		if e.Unit == "bytes" {
+			return humanize.Bytes(uint64(n))
+		}
+		return humanize.Comma(n)
+	}
+	text := fmt.Sprintf("%v: %v of %v", e.Operation, amount(e.Done), amount(e.Total))
+	if e.FilesTotal > 0 {
+		text += fmt.Sprintf(", %d of %d file(s)", e.FilesDone, e.FilesTotal)
+	}
+	switch {
+	case e.Type == "finish":
+		text += ", done"
+	case e.Remaining != nil:
+		text += fmt.Sprintf(", %v/s, %vs left", amount(int64(e.Rate)), int64(*e.Remaining))
+	}
+	return text
+}
@@ -0,0 +1,21 @@
+package display
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDescribeEvent(t *testing.T) {
+	remaining := 42.0
+	assert.Equal(t, "add: 1.0 MB of 4.0 MB, 2 of 8 file(s), 500 kB/s, 42s left", describeEvent(Event{
+		Type: "progress", Operation: "add", Unit: "bytes", Done: 1e6, Total: 4e6,
+		Rate: 5e5, Remaining: &remaining, FilesDone: 2, FilesTotal: 8,
+	}))
+	assert.Equal(t, "announce: 1,200 of 1,200, done", describeEvent(Event{
+		Type: "finish", Operation: "announce", Unit: "items", Done: 1200, Total: 1200,
+	}))
+	assert.Equal(t, "pull: 3 of 10", describeEvent(Event{
+		Type: "start", Operation: "pull", Unit: "items", Done: 3, Total: 10,
+	}))
+}
@@ -1,10 +1,18 @@
 package ipfs
 
 import (
+	"fmt"
 	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
 
+	"github.com/arken/ait/config"
+	"github.com/arken/ait/utils"
 	"github.com/ipfs/interface-go-ipfs-core/options"
 
+	gocid "github.com/ipfs/go-cid"
 	files "github.com/ipfs/go-ipfs-files"
 	icorepath "github.com/ipfs/interface-go-ipfs-core/path"
 )
@@ -22,6 +30,11 @@ func Pin(hash string) (err error) {
 
 // Add imports a file to IPFS and returns the file identifier to ait.
 func Add(path string, onlyHash bool) (cid string, err error) {
+	if onlyHash {
+		defer utils.TimePhase("hashing")()
+	} else {
+		defer utils.TimePhase("datastore writes")()
+	}
 	file, err := getUnixfsNode(path)
 	if err != nil {
 		if file != nil {
@@ -29,12 +42,20 @@ func Add(path string, onlyHash bool) (cid string, err error) {
 		}
 		return cid, err
 	}
+	params, err := workspaceAddParams(path)
+	if err != nil {
+		file.Close()
+		return cid, err
+	}
 	output, err := ipfs.Unixfs().Add(ctx, file, func(input *options.UnixfsAddSettings) error {
 		input.Pin = true
-		input.NoCopy = true
+		// An external daemon can't reference files through ait's
+		// workspace links, it stores their blocks instead.
+		input.NoCopy = node != nil
 		input.CidVersion = 1
 		input.OnlyHash = onlyHash
-		return nil
+		applyAddParams(input, params)
+		return applyLayout(input)
 	})
 	if err != nil {
 		return cid, err
@@ -44,6 +65,277 @@ func Add(path string, onlyHash bool) (cid string, err error) {
 	return cid, nil
 }
 
+// Hash returns the identifier the file at path has when it's added like the
+// one identified by like, without adding it: with the same CID version and
+// hash function, and raw leaves if they can be what like was made with. Files
+// added by others than ait, ie with CIDv0, are compared to their CID this way.
+// Unlike Add it isn't referenced by the filestore, so the file doesn't have to
+// be reached through a workspace link.
+func Hash(path, like string) (cid string, err error) {
+	defer utils.TimePhase("hashing")()
+	expected, err := gocid.Decode(like)
+	if err != nil {
+		return "", err
+	}
+	prefix := expected.Prefix()
+	// CIDv0 files never have raw leaves, and CIDv1 files without one block
+	// are only raw. Others have them, as with ipfs add --cid-version 1, or
+	// not.
+	rawLeaves := []bool{prefix.Version != 0}
+	if prefix.Version != 0 && prefix.Codec != gocid.Raw {
+		rawLeaves = append(rawLeaves, false)
+	}
+	for _, raw := range rawLeaves {
+		if cid, err = hashWith(path, int(prefix.Version), prefix.MhType, raw); err != nil || cid == like {
+			return cid, err
+		}
+	}
+	return cid, nil
+}
+
+// hashWith returns the identifier the file at path is added with using the
+// given CID version, hash function and leaves.
+func hashWith(path string, version int, mhType uint64, rawLeaves bool) (string, error) {
+	file, err := getUnixfsNode(path)
+	if err != nil {
+		if file != nil {
+			file.Close()
+		}
+		return "", err
+	}
+	defer file.Close()
+	output, err := ipfs.Unixfs().Add(ctx, file, func(input *options.UnixfsAddSettings) error {
+		input.CidVersion = version
+		input.MhType = mhType
+		input.OnlyHash = true
+		input.RawLeaves, input.RawLeavesSet = rawLeaves, true
+		return applyLayout(input)
+	})
+	if err != nil {
+		return "", err
+	}
+	return output.Cid().String(), nil
+}
+
+// AddBytes imports data held in memory into the repository, pins it, and
+// returns its identifier.
+func AddBytes(data []byte) (cid string, err error) {
+	output, err := ipfs.Unixfs().Add(ctx, files.NewBytesFile(data), func(input *options.UnixfsAddSettings) error {
+		input.Pin = true
+		input.CidVersion = 1
+		return applyLayout(input)
+	})
+	if err != nil {
+		return cid, err
+	}
+	return output.Cid().String(), nil
+}
+
+// DAG layouts accepted by the IPFS.Layout config setting.
+const (
+	LayoutBalanced = "balanced"
+	LayoutTrickle  = "trickle"
+)
+
+// applyLayout sets the configured DAG layout and block inlining of an add.
+// They change the CIDs of the files, so submitting and uploading must always
+// use the same settings.
+func applyLayout(input *options.UnixfsAddSettings) error {
+	switch config.Global.IPFS.Layout {
+	case LayoutBalanced, "":
+		input.Layout = options.BalancedLayout
+	case LayoutTrickle:
+		input.Layout = options.TrickleLayout
+	default:
+		return fmt.Errorf("unknown DAG layout %q", config.Global.IPFS.Layout)
+	}
+	input.Inline = config.Global.IPFS.Inline
+	if config.Global.IPFS.InlineLimit > 0 {
+		input.InlineLimit = config.Global.IPFS.InlineLimit
+	}
+	return nil
+}
+
+// applyAddParams sets the parameters a file was staged with. The filestore
+// only references raw leaves, so files staged without them are copied into
+// the repository instead.
+func applyAddParams(input *options.UnixfsAddSettings, params utils.AddParams) {
+	if params.CidVersion != nil {
+		input.CidVersion = *params.CidVersion
+	}
+	if params.Chunker != "" {
+		input.Chunker = params.Chunker
+	}
+	if params.RawLeaves != nil {
+		input.RawLeaves, input.RawLeavesSet = *params.RawLeaves, true
+		input.NoCopy = input.NoCopy && *params.RawLeaves
+	}
+}
+
+// addParamsFile is a parameters file of a workspace as it was last read.
+type addParamsFile struct {
+	modTime time.Time
+	params  map[string]utils.AddParams
+}
+
+// addParamsCache keeps the parameters files read by Add, until they change.
+var addParamsCache = struct {
+	sync.Mutex
+	files map[string]addParamsFile
+}{files: make(map[string]addParamsFile)}
+
+// workspaceAddParams returns the parameters the file at path was staged with
+// in the workspace it's reached through. Files that aren't reached through a
+// workspace link keep the defaults.
+func workspaceAddParams(path string) (utils.AddParams, error) {
+	link, inWorkspace, err := workspaceOf(path)
+	if err != nil || link == "" {
+		return utils.AddParams{}, err
+	}
+	// The parameters file is read through the link like the file itself.
+	paramsPath := filepath.Join(workspacesDir(), link, utils.AddParamsPath)
+	info, err := os.Stat(paramsPath)
+	if os.IsNotExist(err) {
+		return utils.AddParams{}, nil
+	}
+	if err != nil {
+		return utils.AddParams{}, err
+	}
+	addParamsCache.Lock()
+	defer addParamsCache.Unlock()
+	cached, ok := addParamsCache.files[paramsPath]
+	if !ok || !cached.modTime.Equal(info.ModTime()) {
+		params, err := utils.ReadAddParams(paramsPath)
+		if err != nil {
+			return utils.AddParams{}, fmt.Errorf("unable to read the IPFS add parameters: %w", err)
+		}
+		cached = addParamsFile{modTime: info.ModTime(), params: params}
+		addParamsCache.files[paramsPath] = cached
+	}
+	return utils.AddParamsFor(cached.params, inWorkspace), nil
+}
+
+// workspaceOf returns the workspace link the file at path is reached through
+// and its path within the workspace, or an empty link if it isn't reached
+// through one.
+func workspaceOf(path string) (link, rel string, err error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return "", "", err
+	}
+	root, err := filepath.Abs(filestoreRoot())
+	if err != nil {
+		return "", "", err
+	}
+	if rel, err = filepath.Rel(root, abs); err != nil {
+		return "", "", nil
+	}
+	link, rel = splitWorkspacePath(rel)
+	return link, rel, nil
+}
+
+// hashCaches are the hash caches of the workspaces files were hashed from,
+// by link.
+var hashCaches = struct {
+	sync.Mutex
+	caches map[string]*utils.HashCache
+}{caches: make(map[string]*utils.HashCache)}
+
+// HashCached returns the CID the file at path, reached through a workspace
+// link, is added with, like Add only hashing it. The CID is kept in the hash
+// cache of the workspace and the file isn't hashed again until it changes or
+// it's staged with other parameters.
+func HashCached(path string) (string, error) {
+	cache, rel, info, fingerprint, err := hashCacheOf(path)
+	if err != nil {
+		return "", err
+	}
+	if cache == nil {
+		return Add(path, true)
+	}
+	if cid, ok := cache.Get(rel, info, fingerprint); ok {
+		return cid, nil
+	}
+	cid, err := Add(path, true)
+	if err != nil {
+		return cid, err
+	}
+	if err = cache.Put(rel, info, fingerprint, cid); err != nil {
+		fmt.Printf("\n[Unable to cache the CID of %v: %v]\n", rel, err)
+	}
+	return cid, nil
+}
+
+// CachedCID returns the CID HashCached would return for the file at path
+// without hashing it, if it's in the hash cache of the workspace and
+// unchanged since.
+func CachedCID(path string) (string, bool) {
+	cache, rel, info, fingerprint, err := hashCacheOf(path)
+	if err != nil || cache == nil {
+		return "", false
+	}
+	return cache.Get(rel, info, fingerprint)
+}
+
+// hashCacheOf returns the hash cache of the workspace the file at path is
+// reached through, the path of the file within the workspace, its info and
+// the fingerprint of the settings changing its CID. The cache is nil if path
+// isn't reached through a workspace link.
+func hashCacheOf(path string) (cache *utils.HashCache, rel string, info os.FileInfo, fingerprint string,
+	err error) {
+	link, rel, err := workspaceOf(path)
+	if err != nil || link == "" {
+		return nil, "", nil, "", nil
+	}
+	if info, err = os.Stat(path); err != nil {
+		return nil, "", nil, "", err
+	}
+	params, err := workspaceAddParams(path)
+	if err != nil {
+		return nil, "", nil, "", err
+	}
+	// Any setting changing the CID of the file invalidates it.
+	fingerprint = fmt.Sprintf("%v|%v|%v|%v", config.Global.IPFS.Layout, config.Global.IPFS.Inline,
+		config.Global.IPFS.InlineLimit, params)
+	return workspaceHashCache(link), rel, info, fingerprint, nil
+}
+
+// workspaceHashCache returns the hash cache of the workspace at link.
+func workspaceHashCache(link string) *utils.HashCache {
+	hashCaches.Lock()
+	defer hashCaches.Unlock()
+	cache, ok := hashCaches.caches[link]
+	if !ok {
+		cache = utils.OpenHashCache(filepath.Join(workspacesDir(), link, utils.HashCachePath))
+		hashCaches.caches[link] = cache
+	}
+	return cache
+}
+
+// LastCID returns the CID the file at path, reached through a workspace link,
+// was last hashed to by HashCached, even if it no longer exists.
+func LastCID(path string) (string, bool) {
+	link, rel, err := workspaceOf(path)
+	if err != nil || link == "" {
+		return "", false
+	}
+	return workspaceHashCache(link).Last(rel)
+}
+
+// Unpin releases a pin so the content can be garbage collected. Content
+// protected for a submission that isn't merged and replicated yet is kept.
+func Unpin(hash string) error {
+	owners, err := Protected(hash)
+	if err != nil {
+		return err
+	}
+	if len(owners) > 0 {
+		return fmt.Errorf("%v is protected until submission %v is merged and replicated",
+			hash, strings.Join(owners, ", "))
+	}
+	return ipfs.Pin().Rm(ctx, icorepath.New("/ipfs/"+hash))
+}
+
 func getUnixfsNode(path string) (files.Node, error) {
 	st, err := os.Stat(path)
 	if err != nil {
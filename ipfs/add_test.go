@@ -4,6 +4,11 @@ import (
 	"io/ioutil"
 	"os"
 	"testing"
+
+	aitConf "github.com/arken/ait/config"
+	"github.com/arken/ait/utils"
+
+	"github.com/ipfs/interface-go-ipfs-core/options"
 )
 
 func TestAdd(t *testing.T) {
@@ -25,3 +30,35 @@ func TestAdd(t *testing.T) {
 		t.Error(err)
 	}
 }
+
+func TestApplyLayout(t *testing.T) {
+	conf := aitConf.Global.IPFS
+	defer func() { aitConf.Global.IPFS = conf }()
+
+	aitConf.Global.IPFS.Layout = LayoutTrickle
+	aitConf.Global.IPFS.Inline = true
+	aitConf.Global.IPFS.InlineLimit = 64
+	input := &options.UnixfsAddSettings{}
+	if err := applyLayout(input); err != nil {
+		t.Fatal(err)
+	}
+	if input.Layout != options.TrickleLayout || !input.Inline || input.InlineLimit != 64 {
+		t.Errorf("unexpected settings %+v", input)
+	}
+	aitConf.Global.IPFS.Layout = "sideways"
+	if err := applyLayout(input); err == nil {
+		t.Error("an unknown layout was accepted")
+	}
+}
+
+func TestApplyAddParams(t *testing.T) {
+	version, raw := 0, false
+	input := &options.UnixfsAddSettings{CidVersion: 1, NoCopy: true}
+	applyAddParams(input, utils.AddParams{CidVersion: &version, Chunker: "buzhash", RawLeaves: &raw})
+	if input.CidVersion != 0 || input.Chunker != "buzhash" || input.RawLeaves || !input.RawLeavesSet {
+		t.Errorf("unexpected settings %+v", input)
+	}
+	if input.NoCopy {
+		t.Error("a file without raw leaves would be referenced by the filestore")
+	}
+}
@@ -0,0 +1,313 @@
+package ipfs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	aitConf "github.com/arken/ait/config"
+
+	bitswap "github.com/ipfs/go-bitswap"
+	httpapi "github.com/ipfs/go-ipfs-http-client"
+	"github.com/ipfs/go-ipfs/core/corerepo"
+	icore "github.com/ipfs/interface-go-ipfs-core"
+	"github.com/libp2p/go-libp2p-core/network"
+	"github.com/libp2p/go-libp2p-core/peer"
+	ma "github.com/multiformats/go-multiaddr"
+)
+
+// The backends ait can work through, as IPFS.Backend names them.
+const (
+	embeddedBackendName = "embedded"
+	httpBackendName     = "http"
+	autoBackendName     = "auto"
+)
+
+// probeTimeout bounds how long "auto" waits for an external daemon to answer
+// before running the embedded node.
+const probeTimeout = 2 * time.Second
+
+// nodeBackend is the IPFS node ait works through once the IPFS subsystem is
+// started: the node embedded in ait, or an external daemon reached over its
+// HTTP API. Both are driven through the CoreAPI start returns, the methods
+// cover what the CoreAPI doesn't.
+type nodeBackend interface {
+	name() string
+	// start brings the node up and returns its CoreAPI. Online nodes check
+	// whether they can be reached and use a relay when they can't.
+	start(online bool) (icore.CoreAPI, error)
+	id() string
+	peerCount() int
+	// connection returns whether the node is connected to p and the latency
+	// to it, 0 when it isn't known.
+	connection(p peer.ID) (bool, time.Duration)
+	// routingPeers returns the number of peers in the DHT routing tables.
+	routingPeers() int
+	storageUsed() (uint64, error)
+	// dataSent returns the bytes bitswap has sent to other peers.
+	dataSent() (uint64, error)
+	collectGarbage() error
+	// enforceStorage keeps the repository below its GC watermark until ctx
+	// is canceled.
+	enforceStorage(ctx context.Context) error
+	close() error
+}
+
+// current is the backend the IPFS subsystem was started with.
+var current nodeBackend
+
+// selectBackend returns the backend IPFS.Backend asks for. "auto" uses the
+// external daemon if it answers at IPFS.APIAddr.
+func selectBackend() (nodeBackend, error) {
+	conf := aitConf.Global.IPFS
+	switch conf.Backend {
+	case httpBackendName:
+		return newHTTPBackend(conf.APIAddr)
+	case autoBackendName:
+		b, err := newHTTPBackend(conf.APIAddr)
+		if err != nil {
+			return nil, err
+		}
+		if b.probe() == nil {
+			return b, nil
+		}
+	}
+	return &embeddedBackend{}, nil
+}
+
+// BackendName returns the name of the backend the IPFS subsystem runs on,
+// "embedded" or "http".
+func BackendName() string {
+	if current == nil {
+		return ""
+	}
+	return current.name()
+}
+
+// requireEmbedded returns an error naming what can only be done by the
+// embedded node, which has direct access to its repository, when ait works
+// through an external daemon.
+func requireEmbedded(what string) error {
+	if node != nil {
+		return nil
+	}
+	return fmt.Errorf("%v needs the embedded IPFS node, the external daemon at %v manages its own "+
+		"repository (set IPFS.Backend to \"embedded\" to use ait's)", what, aitConf.Global.IPFS.APIAddr)
+}
+
+// externalDaemon returns the daemon ait works through, without starting the
+// IPFS subsystem: the one at IPFS.APIAddr when IPFS.Backend is "http", or is
+// "auto" and it answers. It returns nil when ait uses its own repository.
+func externalDaemon() (*httpBackend, error) {
+	if current != nil {
+		b, _ := current.(*httpBackend)
+		return b, nil
+	}
+	conf := aitConf.Global.IPFS
+	switch conf.Backend {
+	case httpBackendName:
+		return newHTTPBackend(conf.APIAddr)
+	case autoBackendName:
+		if b, err := newHTTPBackend(conf.APIAddr); err == nil && b.probe() == nil {
+			return b, nil
+		}
+	}
+	return nil, nil
+}
+
+// requireOwnRepository is requireEmbedded for what reads or writes ait's IPFS
+// repository without starting a node, ie its keystore and identity.
+func requireOwnRepository(what string) error {
+	if b, err := externalDaemon(); b == nil && err == nil {
+		return nil
+	}
+	return fmt.Errorf("%v needs ait's own IPFS repository, the external daemon at %v keeps its own keys "+
+		"and identity (manage them with \"ipfs key\", or set IPFS.Backend to \"embedded\" to use ait's)",
+		what, aitConf.Global.IPFS.APIAddr)
+}
+
+// embeddedBackend runs an IPFS node inside ait, on the repository at
+// IPFS.Path.
+type embeddedBackend struct{}
+
+func (*embeddedBackend) name() string { return embeddedBackendName }
+
+func (*embeddedBackend) start(online bool) (icore.CoreAPI, error) {
+	var api icore.CoreAPI
+	var err error
+	ctx, api, err = spawnNode(aitConf.Global.IPFS.Path, online)
+	if err != nil {
+		return nil, err
+	}
+	cfg, err := node.Repo.Config()
+	if err != nil {
+		return nil, err
+	}
+	cfg.Experimental.FilestoreEnabled = true
+	// Keep the connection manager from trimming the Arken nodes, which
+	// provide-only nodes rely on with their few connections.
+	for _, addr := range arkenPeers() {
+		id, _ := addrID(addr)
+		node.PeerHost.ConnManager().Protect(id, "arken")
+	}
+	return api, nil
+}
+
+func (*embeddedBackend) id() string { return node.Identity.Pretty() }
+
+func (*embeddedBackend) peerCount() int { return len(node.PeerHost.Network().Peers()) }
+
+func (*embeddedBackend) connection(p peer.ID) (bool, time.Duration) {
+	if node.PeerHost.Network().Connectedness(p) != network.Connected {
+		return false, 0
+	}
+	return true, node.Peerstore.LatencyEWMA(p)
+}
+
+func (*embeddedBackend) routingPeers() (n int) {
+	if node.DHT == nil {
+		return 0
+	}
+	if node.DHT.WAN != nil {
+		n += node.DHT.WAN.RoutingTable().Size()
+	}
+	if node.DHT.LAN != nil {
+		n += node.DHT.LAN.RoutingTable().Size()
+	}
+	return n
+}
+
+func (*embeddedBackend) storageUsed() (uint64, error) { return node.Repo.GetStorageUsage() }
+
+func (*embeddedBackend) dataSent() (uint64, error) {
+	bs, ok := node.Exchange.(*bitswap.Bitswap)
+	if !ok {
+		return 0, errors.New("the node doesn't exchange blocks over bitswap")
+	}
+	stat, err := bs.Stat()
+	if err != nil {
+		return 0, err
+	}
+	return stat.DataSent, nil
+}
+
+func (*embeddedBackend) collectGarbage() error { return corerepo.GarbageCollect(node, ctx) }
+
+func (*embeddedBackend) enforceStorage(c context.Context) error {
+	if err := corerepo.ConditionalGC(c, node, 0); err != nil {
+		return err
+	}
+	return corerepo.PeriodicGC(c, node)
+}
+
+func (*embeddedBackend) close() error { return node.Close() }
+
+// httpBackend works through an external IPFS daemon, ie one the machine
+// already runs, reached over its HTTP API. The daemon keeps its own
+// repository, identity and network settings: ait's IPFS settings beyond
+// APIAddr don't apply to it.
+type httpBackend struct {
+	addr   string
+	api    *httpapi.HttpApi
+	peerID string
+}
+
+// newHTTPBackend returns the backend of the daemon whose API listens on addr,
+// ie "/ip4/127.0.0.1/tcp/5001".
+func newHTTPBackend(addr string) (*httpBackend, error) {
+	maddr, err := ma.NewMultiaddr(addr)
+	if err != nil {
+		return nil, fmt.Errorf("IPFS.APIAddr: %v", err)
+	}
+	api, err := httpapi.NewApi(maddr)
+	if err != nil {
+		return nil, err
+	}
+	return &httpBackend{addr: addr, api: api}, nil
+}
+
+// probe asks the daemon for its peer ID, failing if no daemon answers within
+// probeTimeout.
+func (b *httpBackend) probe() error {
+	c, cancl := context.WithTimeout(context.Background(), probeTimeout)
+	defer cancl()
+	self, err := b.api.Key().Self(c)
+	if err != nil {
+		return fmt.Errorf("no IPFS daemon answers at %v: %v", b.addr, err)
+	}
+	b.peerID = self.ID().Pretty()
+	return nil
+}
+
+func (*httpBackend) name() string { return httpBackendName }
+
+// start checks that the daemon answers. Its reachability is the daemon's
+// own business, online only reports which daemon is used.
+func (b *httpBackend) start(online bool) (icore.CoreAPI, error) {
+	if err := b.probe(); err != nil {
+		return nil, err
+	}
+	if online {
+		fmt.Printf("[Using the IPFS daemon at %v]\n", b.addr)
+	}
+	return b.api, nil
+}
+
+func (b *httpBackend) id() string { return b.peerID }
+
+func (b *httpBackend) peerCount() int {
+	peers, err := b.api.Swarm().Peers(ctx)
+	if err != nil {
+		return 0
+	}
+	return len(peers)
+}
+
+func (b *httpBackend) connection(p peer.ID) (bool, time.Duration) {
+	peers, err := b.api.Swarm().Peers(ctx)
+	if err != nil {
+		return false, 0
+	}
+	for _, conn := range peers {
+		if conn.ID() == p {
+			latency, _ := conn.Latency()
+			return true, latency
+		}
+	}
+	return false, 0
+}
+
+// routingPeers counts the daemon's connected peers, its API doesn't show
+// its routing tables.
+func (b *httpBackend) routingPeers() int { return b.peerCount() }
+
+func (b *httpBackend) storageUsed() (uint64, error) {
+	var stat struct {
+		RepoSize uint64
+	}
+	err := b.api.Request("repo/stat").Option("size-only", true).Exec(ctx, &stat)
+	return stat.RepoSize, err
+}
+
+func (b *httpBackend) dataSent() (uint64, error) {
+	var stat struct {
+		DataSent uint64
+	}
+	err := b.api.Request("bitswap/stat").Exec(ctx, &stat)
+	return stat.DataSent, err
+}
+
+func (b *httpBackend) collectGarbage() error {
+	// The daemon streams the removed blocks, which are of no use here.
+	return b.api.Request("repo/gc").Option("quiet", true).Exec(ctx, nil)
+}
+
+// enforceStorage leaves garbage collection to the daemon, which does it
+// when started with --enable-gc following its own Datastore settings.
+func (b *httpBackend) enforceStorage(c context.Context) error {
+	<-c.Done()
+	return nil
+}
+
+func (*httpBackend) close() error { return nil }
@@ -0,0 +1,94 @@
+package ipfs
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	aitConf "github.com/arken/ait/config"
+)
+
+// testPeerID is the ID the fake daemon answers with.
+const testPeerID = "QmNnooDu7bfjPFoTZYxMNLWUQJyrVwtbZg5gBMjTezGAJN"
+
+func TestSelectBackend(t *testing.T) {
+	daemon := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/v0/id":
+			fmt.Fprintf(w, `{"ID": %q}`, testPeerID)
+		case "/api/v0/repo/stat":
+			fmt.Fprint(w, `{"RepoSize": 4096}`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer daemon.Close()
+	_, port, _ := net.SplitHostPort(daemon.Listener.Addr().String())
+	running := "/ip4/127.0.0.1/tcp/" + port
+
+	// Nothing listens on the port of a closed listener.
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, port, _ = net.SplitHostPort(l.Addr().String())
+	l.Close()
+	stopped := "/ip4/127.0.0.1/tcp/" + port
+
+	conf := aitConf.Global.IPFS
+	defer func() { aitConf.Global.IPFS = conf }()
+	for _, test := range []struct {
+		backend, addr, expected string
+	}{
+		{"embedded", running, embeddedBackendName},
+		{"auto", running, httpBackendName},
+		{"auto", stopped, embeddedBackendName},
+		{"http", stopped, httpBackendName},
+	} {
+		aitConf.Global.IPFS.Backend, aitConf.Global.IPFS.APIAddr = test.backend, test.addr
+		b, err := selectBackend()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if b.name() != test.expected {
+			t.Errorf("%v at %v selected %v, expected %v", test.backend, test.addr, b.name(), test.expected)
+		}
+	}
+
+	aitConf.Global.IPFS.Backend, aitConf.Global.IPFS.APIAddr = "http", stopped
+	b, _ := selectBackend()
+	if _, err := b.start(false); err == nil {
+		t.Error("expected an error without a daemon")
+	}
+
+	ctx = context.Background()
+	b, _ = newHTTPBackend(running)
+	if _, err := b.start(false); err != nil {
+		t.Fatal(err)
+	}
+	if b.id() != testPeerID {
+		t.Errorf("id() = %v, expected %v", b.id(), testPeerID)
+	}
+	if used, err := b.storageUsed(); err != nil || used != 4096 {
+		t.Errorf("storageUsed() = %v, %v, expected 4096", used, err)
+	}
+	if err := requireEmbedded("Checking the repository"); err == nil {
+		t.Error("expected the repository checks to need the embedded node")
+	}
+
+	// Without starting a node, the keys and identity are the daemon's.
+	aitConf.Global.IPFS.Backend, aitConf.Global.IPFS.APIAddr = "auto", running
+	if id, err := NodeID(); err != nil || id != testPeerID {
+		t.Errorf("NodeID() = %v, %v, expected the daemon's %v", id, err, testPeerID)
+	}
+	if _, err := GenerateKey("site"); err == nil {
+		t.Error("expected keys not to be made in ait's keystore with a daemon")
+	}
+	aitConf.Global.IPFS.Backend = "embedded"
+	if err := requireOwnRepository("Managing IPNS keys"); err != nil {
+		t.Error("expected the embedded backend to use ait's repository:", err)
+	}
+}
@@ -0,0 +1,115 @@
+package ipfs
+
+import (
+	"context"
+
+	aitConf "github.com/arken/ait/config"
+	"github.com/arken/ait/utils"
+
+	manet "github.com/multiformats/go-multiaddr/net"
+	"golang.org/x/time/rate"
+)
+
+// bandwidth holds the limiters every connection of the node shares, nil for
+// a direction that isn't limited.
+type bandwidth struct {
+	up, down *rate.Limiter
+}
+
+// bandwidthLimits returns the limits of the configured MaxUpload and
+// MaxDownload, or nil if neither is set.
+func bandwidthLimits() (*bandwidth, error) {
+	up, err := utils.ParseByteSize(aitConf.Global.IPFS.MaxUpload)
+	if err != nil {
+		return nil, err
+	}
+	down, err := utils.ParseByteSize(aitConf.Global.IPFS.MaxDownload)
+	if err != nil {
+		return nil, err
+	}
+	if up <= 0 && down <= 0 {
+		return nil, nil
+	}
+	return &bandwidth{up: newLimiter(up), down: newLimiter(down)}, nil
+}
+
+// newLimiter returns a limiter of perSecond bytes, allowing bursts of a
+// second's worth, or nil when perSecond is 0.
+func newLimiter(perSecond int64) *rate.Limiter {
+	if perSecond <= 0 {
+		return nil
+	}
+	return rate.NewLimiter(rate.Limit(perSecond), int(perSecond))
+}
+
+// wait blocks until n bytes can go through the limiter, waiting for them a
+// burst at a time.
+func wait(l *rate.Limiter, n int) {
+	for n > 0 {
+		chunk := n
+		if chunk > l.Burst() {
+			chunk = l.Burst()
+		}
+		_ = l.WaitN(context.Background(), chunk)
+		n -= chunk
+	}
+}
+
+// limit returns conn with its reads and writes held to the limits.
+func (b *bandwidth) limit(conn manet.Conn) manet.Conn {
+	if b == nil {
+		return conn
+	}
+	return &limitedConn{Conn: conn, bandwidth: b}
+}
+
+// limitedConn is a connection sharing the node's bandwidth limits.
+type limitedConn struct {
+	manet.Conn
+	*bandwidth
+}
+
+// Read reads from the connection, then waits for the bytes read so the
+// connection isn't read faster than the download limit on average.
+func (c *limitedConn) Read(p []byte) (int, error) {
+	n, err := c.Conn.Read(p)
+	if c.down != nil {
+		wait(c.down, n)
+	}
+	return n, err
+}
+
+// Write waits for the upload limit before each burst it writes.
+func (c *limitedConn) Write(p []byte) (int, error) {
+	if c.up == nil {
+		return c.Conn.Write(p)
+	}
+	written := 0
+	for written < len(p) {
+		chunk := len(p) - written
+		if chunk > c.up.Burst() {
+			chunk = c.up.Burst()
+		}
+		wait(c.up, chunk)
+		n, err := c.Conn.Write(p[written : written+chunk])
+		written += n
+		if err != nil {
+			return written, err
+		}
+	}
+	return written, nil
+}
+
+// limitedListener accepts connections sharing the node's bandwidth limits.
+type limitedListener struct {
+	manet.Listener
+	*bandwidth
+}
+
+func (l *limitedListener) Accept() (manet.Conn, error) {
+	conn, err := l.Listener.Accept()
+	if err != nil {
+		return nil, err
+	}
+	return l.limit(conn), nil
+}
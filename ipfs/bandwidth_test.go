@@ -0,0 +1,60 @@
+package ipfs
+
+import (
+	"io"
+	"io/ioutil"
+	"testing"
+	"time"
+
+	aitConf "github.com/arken/ait/config"
+
+	ma "github.com/multiformats/go-multiaddr"
+	manet "github.com/multiformats/go-multiaddr/net"
+)
+
+func TestBandwidthLimits(t *testing.T) {
+	defer func(conf aitConf.Config) { aitConf.Global = conf }(aitConf.Global)
+	aitConf.Global.IPFS.MaxUpload, aitConf.Global.IPFS.MaxDownload = "", ""
+	if limits, err := bandwidthLimits(); err != nil || limits != nil {
+		t.Fatalf("bandwidthLimits() = %v, %v without limits", limits, err)
+	}
+	aitConf.Global.IPFS.MaxUpload = "1MB"
+	limits, err := bandwidthLimits()
+	if err != nil || limits.up == nil || limits.down != nil {
+		t.Fatalf("bandwidthLimits() = %v, %v with an upload limit", limits, err)
+	}
+
+	list, err := manet.Listen(ma.StringCast("/ip4/127.0.0.1/tcp/0"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer list.Close()
+	received := make(chan int64)
+	go func() {
+		conn, err := list.Accept()
+		if err != nil {
+			received <- 0
+			return
+		}
+		n, _ := io.Copy(ioutil.Discard, conn)
+		conn.Close()
+		received <- n
+	}()
+	conn, err := manet.Dial(list.Multiaddr())
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The first megabyte is a burst, the next half takes half a second.
+	size := limits.up.Burst() * 3 / 2
+	started := time.Now()
+	if n, err := limits.limit(conn).Write(make([]byte, size)); err != nil || n != size {
+		t.Fatalf("wrote %d bytes of %d: %v", n, size, err)
+	}
+	conn.Close()
+	if elapsed := time.Since(started); elapsed < 400*time.Millisecond {
+		t.Fatalf("wrote %d bytes in %v, over the limit", size, elapsed)
+	}
+	if n := <-received; n != int64(size) {
+		t.Fatalf("received %d bytes of %d", n, size)
+	}
+}
@@ -0,0 +1,112 @@
+package ipfs
+
+import (
+	"fmt"
+	"net"
+
+	aitConf "github.com/arken/ait/config"
+
+	"github.com/ipfs/go-ipfs/repo/fsrepo"
+	migrate "github.com/ipfs/go-ipfs/repo/fsrepo/migrations"
+	ma "github.com/multiformats/go-multiaddr"
+)
+
+// CheckRepo checks the IPFS repository without starting a node: that its
+// version is the one the embedded node expects, that its config can be read
+// and that the swarm ports it listens on are free. With an external daemon,
+// it checks that the daemon answers instead.
+func CheckRepo() []Check {
+	conf := aitConf.Global.IPFS
+	if conf.Backend == httpBackendName || conf.Backend == autoBackendName {
+		check := Check{Name: "IPFS daemon (" + conf.APIAddr + ")"}
+		b, err := newHTTPBackend(conf.APIAddr)
+		if err == nil {
+			err = b.probe()
+		}
+		switch {
+		case err == nil:
+			check.OK, check.Detail = true, "peer "+b.peerID
+			return []Check{check}
+		case conf.Backend == httpBackendName:
+			check.Detail = err.Error()
+			check.Fix = "start the daemon (ipfs daemon), or set IPFS.Backend to \"embedded\""
+			return []Check{check}
+		}
+	}
+
+	path := conf.Path
+	repo := Check{Name: "IPFS repository (" + path + ")"}
+	if !fsrepo.IsInitialized(path) {
+		repo.OK, repo.Detail = true, "not created yet, it's created the first time the node starts"
+		return []Check{repo}
+	}
+	version, err := migrate.RepoPath(path).Version()
+	switch {
+	case err != nil:
+		repo.Detail = err.Error()
+		repo.Fix = "run \"ait ipfs fsck\", or restore the repository from a backup"
+	case version < fsrepo.RepoVersion:
+		repo.Detail = fmt.Sprintf("version %d, version %d is required", version, fsrepo.RepoVersion)
+		repo.Fix = "rerun any command with --auto-migrate to upgrade it"
+	case version > fsrepo.RepoVersion:
+		repo.Detail = fmt.Sprintf("version %d, newer than the version %d this ait supports", version,
+			fsrepo.RepoVersion)
+		repo.Fix = "update ait with \"ait update\""
+	default:
+		repo.OK, repo.Detail = true, fmt.Sprintf("version %d", version)
+	}
+	checks := []Check{repo}
+
+	cfg, err := fsrepo.ConfigAt(path)
+	if err != nil {
+		return append(checks, Check{Name: "IPFS config", Detail: err.Error(),
+			Fix: "fix or remove " + path + "/config, removing it gives the node a new identity"})
+	}
+	locked, err := fsrepo.LockedByOtherProcess(path)
+	if err != nil {
+		return append(checks, Check{Name: "IPFS repository lock", Detail: err.Error()})
+	}
+	if locked {
+		// The ports are the ones of the running node then.
+		return append(checks, Check{Name: "IPFS repository lock", OK: true,
+			Detail: "in use by another ait process, ie ait daemon, its swarm ports weren't checked"})
+	}
+	for _, addr := range cfg.Addresses.Swarm {
+		if check, ok := checkSwarmPort(addr); ok {
+			checks = append(checks, check)
+		}
+	}
+	return checks
+}
+
+// checkSwarmPort checks that the TCP port of the swarm address addr can be
+// listened on. Addresses of other transports, or on a random port, aren't
+// checked.
+func checkSwarmPort(addr string) (Check, bool) {
+	m, err := ma.NewMultiaddr(addr)
+	if err != nil {
+		return Check{Name: "Swarm address " + addr, Detail: err.Error(),
+			Fix: "correct Addresses.Swarm in " + aitConf.Global.IPFS.Path + "/config"}, true
+	}
+	port, err := m.ValueForProtocol(ma.P_TCP)
+	if err != nil || port == "0" {
+		return Check{}, false
+	}
+	host, err := m.ValueForProtocol(ma.P_IP4)
+	if err != nil {
+		if host, err = m.ValueForProtocol(ma.P_IP6); err != nil {
+			return Check{}, false
+		}
+	}
+	check := Check{Name: "Swarm port " + addr}
+	l, err := net.Listen("tcp", net.JoinHostPort(host, port))
+	if err != nil {
+		check.Detail = err.Error()
+		check.Fix = "stop the program using port " + port + ", or change Addresses.Swarm in " +
+			aitConf.Global.IPFS.Path + "/config"
+		return check, true
+	}
+	l.Close()
+	check.OK, check.Detail = true, "free"
+	return check, true
+}
@@ -0,0 +1,32 @@
+package ipfs
+
+import (
+	"net"
+	"strconv"
+	"testing"
+)
+
+func TestCheckSwarmPort(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	port := strconv.Itoa(l.Addr().(*net.TCPAddr).Port)
+	check, ok := checkSwarmPort("/ip4/127.0.0.1/tcp/" + port)
+	if !ok || check.OK || check.Fix == "" {
+		t.Errorf("checkSwarmPort of a port in use = %+v, %v", check, ok)
+	}
+	l.Close()
+	if check, ok = checkSwarmPort("/ip4/127.0.0.1/tcp/" + port); !ok || !check.OK {
+		t.Errorf("checkSwarmPort of a free port = %+v, %v", check, ok)
+	}
+
+	for _, addr := range []string{"/ip4/0.0.0.0/tcp/0", "/ip4/0.0.0.0/udp/4001/quic"} {
+		if _, ok = checkSwarmPort(addr); ok {
+			t.Errorf("expected %v not to be checked", addr)
+		}
+	}
+	if check, ok = checkSwarmPort("/ip4/not-an-ip/tcp/4001"); !ok || check.OK {
+		t.Errorf("checkSwarmPort of an invalid address = %+v, %v", check, ok)
+	}
+}
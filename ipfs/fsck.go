@@ -0,0 +1,138 @@
+package ipfs
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	blockservice "github.com/ipfs/go-blockservice"
+	cid "github.com/ipfs/go-cid"
+	filestore "github.com/ipfs/go-filestore"
+	offline "github.com/ipfs/go-ipfs-exchange-offline"
+	ipld "github.com/ipfs/go-ipld-format"
+	merkledag "github.com/ipfs/go-merkledag"
+)
+
+// FsckReport summarizes the problems found while checking the repository.
+type FsckReport struct {
+	// BlocksChecked is the number of blocks that were re-hashed.
+	BlocksChecked int
+	// CorruptBlocks are blocks whose contents no longer match their CID.
+	CorruptBlocks []string
+	// MissingBlocks maps each pinned root to the blocks of its DAG that are
+	// not in the local repository.
+	MissingBlocks map[string][]string
+	// BrokenRefs are filestore references whose backing file changed or
+	// disappeared, mapped to the file path they reference.
+	BrokenRefs map[string]string
+}
+
+// IsHealthy returns true if no problems were found.
+func (report *FsckReport) IsHealthy() bool {
+	return len(report.CorruptBlocks) == 0 && len(report.MissingBlocks) == 0 &&
+		len(report.BrokenRefs) == 0
+}
+
+// Fsck verifies the node's datastore. Every stored block is re-hashed and
+// compared against its CID, every filestore reference is checked against its
+// backing file, and the DAG of every recursive pin is walked to find blocks
+// missing from the local repository.
+func Fsck() (report FsckReport, err error) {
+	report.MissingBlocks = make(map[string][]string)
+	report.BrokenRefs = make(map[string]string)
+	if err = requireEmbedded("Checking the repository"); err != nil {
+		return report, err
+	}
+
+	keys, err := node.Blockstore.AllKeysChan(ctx)
+	if err != nil {
+		return report, err
+	}
+	for c := range keys {
+		report.BlocksChecked++
+		block, err := node.Blockstore.Get(c)
+		if err != nil {
+			report.CorruptBlocks = append(report.CorruptBlocks, c.String())
+			continue
+		}
+		sum, err := c.Prefix().Sum(block.RawData())
+		if err != nil || !sum.Equals(c) {
+			report.CorruptBlocks = append(report.CorruptBlocks, c.String())
+		}
+	}
+
+	if node.Filestore != nil {
+		next, err := filestore.VerifyAll(node.Filestore, false)
+		if err != nil {
+			return report, err
+		}
+		for res := next(); res != nil; res = next() {
+			if res.Status != filestore.StatusOk {
+				report.BrokenRefs[res.Key.String()] = res.FilePath
+			}
+		}
+	}
+
+	pins, err := node.Pinning.RecursiveKeys(ctx)
+	if err != nil {
+		return report, err
+	}
+	for _, root := range pins {
+		missing, err := missingBlocks(root)
+		if err != nil {
+			return report, err
+		}
+		if len(missing) > 0 {
+			report.MissingBlocks[root.String()] = missing
+		}
+	}
+	return report, nil
+}
+
+// missingBlocks walks the DAG under root using only the local blockstore and
+// returns the blocks that could not be found.
+func missingBlocks(root cid.Cid) (missing []string, err error) {
+	localDAG := merkledag.NewDAGService(blockservice.New(node.Blockstore, offline.Exchange(node.Blockstore)))
+	seen := cid.NewSet()
+	err = merkledag.Walk(ctx, merkledag.GetLinksWithDAG(localDAG), root, seen.Visit,
+		merkledag.OnError(func(c cid.Cid, err error) error {
+			if err == ipld.ErrNotFound {
+				missing = append(missing, c.String())
+				return nil
+			}
+			return err
+		}))
+	return missing, err
+}
+
+// Refetch repairs a pinned DAG by fetching its missing blocks from the
+// network, giving up after the timeout.
+func Refetch(root string, timeout time.Duration) error {
+	c, err := cid.Decode(root)
+	if err != nil {
+		return err
+	}
+	if err = requireEmbedded("Repairing the repository"); err != nil {
+		return err
+	}
+	fetchCtx, cancelFetch := context.WithTimeout(ctx, timeout)
+	defer cancelFetch()
+	err = merkledag.FetchGraph(fetchCtx, c, node.DAG)
+	if err != nil {
+		return fmt.Errorf("unable to fetch %v from the network: %v", root, err)
+	}
+	return nil
+}
+
+// RemoveBlock deletes a single block from the local blockstore so that it can
+// be fetched or re-added cleanly.
+func RemoveBlock(hash string) error {
+	c, err := cid.Decode(hash)
+	if err != nil {
+		return err
+	}
+	if err = requireEmbedded("Removing blocks"); err != nil {
+		return err
+	}
+	return node.Blockstore.DeleteBlock(c)
+}
@@ -0,0 +1,124 @@
+package ipfs
+
+import (
+	"fmt"
+	"net"
+	"strings"
+
+	aitConf "github.com/arken/ait/config"
+
+	config "github.com/ipfs/go-ipfs-config"
+	"github.com/libp2p/go-libp2p-core/network"
+	"github.com/libp2p/go-libp2p-core/peer"
+	ma "github.com/multiformats/go-multiaddr"
+)
+
+// peerGate decides which peers the node may stay connected to.
+type peerGate struct {
+	deny  map[peer.ID]bool
+	allow map[peer.ID]bool
+	// clusterOnly rejects every peer that isn't in allow.
+	clusterOnly bool
+}
+
+// newPeerGate builds the gate from the ait config. The Arken bootstrapper and
+// relay are always allowed unless they're explicitly denied.
+func newPeerGate() (*peerGate, error) {
+	gate := &peerGate{
+		deny:        map[peer.ID]bool{},
+		allow:       map[peer.ID]bool{},
+		clusterOnly: aitConf.Global.IPFS.ClusterOnly,
+	}
+	for _, id := range aitConf.Global.IPFS.DenyPeers {
+		p, err := peer.Decode(id)
+		if err != nil {
+			return nil, fmt.Errorf("invalid peer ID %q in the IPFS DenyPeers setting", id)
+		}
+		gate.deny[p] = true
+	}
+	for _, id := range aitConf.Global.IPFS.AllowPeers {
+		p, err := peer.Decode(id)
+		if err != nil {
+			return nil, fmt.Errorf("invalid peer ID %q in the IPFS AllowPeers setting", id)
+		}
+		gate.allow[p] = true
+	}
+	for _, addr := range arkenPeers() {
+		id, err := addrID(addr)
+		if err != nil {
+			return nil, err
+		}
+		gate.allow[id] = true
+	}
+	return gate, nil
+}
+
+// allowed returns whether the node may be connected to p.
+func (g *peerGate) allowed(p peer.ID) bool {
+	if g.deny[p] {
+		return false
+	}
+	return !g.clusterOnly || g.allow[p]
+}
+
+// Connected closes connections to peers the gate doesn't allow as soon as
+// they're made, whichever side dialed.
+func (g *peerGate) Connected(_ network.Network, c network.Conn) {
+	if !g.allowed(c.RemotePeer()) {
+		go c.Close()
+	}
+}
+
+func (g *peerGate) Listen(network.Network, ma.Multiaddr)         {}
+func (g *peerGate) ListenClose(network.Network, ma.Multiaddr)    {}
+func (g *peerGate) Disconnected(network.Network, network.Conn)   {}
+func (g *peerGate) OpenedStream(network.Network, network.Stream) {}
+func (g *peerGate) ClosedStream(network.Network, network.Stream) {}
+
+// gatePeers starts enforcing the peer settings on the running node.
+func gatePeers() error {
+	gate, err := newPeerGate()
+	if err != nil {
+		return err
+	}
+	if len(gate.deny) == 0 && !gate.clusterOnly {
+		return nil
+	}
+	node.PeerHost.Network().Notify(gate)
+	for _, c := range node.PeerHost.Network().Conns() {
+		gate.Connected(node.PeerHost.Network(), c)
+	}
+	return nil
+}
+
+// applyGateConfig turns the denied address ranges into IPFS swarm address
+// filters, so those addresses are never dialed or accepted.
+func applyGateConfig(cfg *config.Config) error {
+	filters := []string{}
+	for _, cidr := range aitConf.Global.IPFS.DenyCIDRs {
+		mask, err := cidrMask(cidr)
+		if err != nil {
+			return err
+		}
+		filters = append(filters, mask)
+	}
+	cfg.Swarm.AddrFilters = filters
+	return nil
+}
+
+// cidrMask converts a CIDR (ie "10.0.0.0/8") into the multiaddr mask IPFS
+// filters on (ie "/ip4/10.0.0.0/ipcidr/8"). Masks are returned unchanged.
+func cidrMask(cidr string) (string, error) {
+	if strings.HasPrefix(cidr, "/") {
+		return cidr, nil
+	}
+	_, ipnet, err := net.ParseCIDR(cidr)
+	if err != nil {
+		return "", fmt.Errorf("invalid CIDR %q in the IPFS DenyCIDRs setting", cidr)
+	}
+	ones, _ := ipnet.Mask.Size()
+	if ip := ipnet.IP.To4(); ip != nil {
+		return fmt.Sprintf("/ip4/%v/ipcidr/%d", ip, ones), nil
+	}
+	return fmt.Sprintf("/ip6/%v/ipcidr/%d", ipnet.IP, ones), nil
+}
@@ -0,0 +1,62 @@
+package ipfs
+
+import (
+	"testing"
+
+	aitConf "github.com/arken/ait/config"
+
+	"github.com/libp2p/go-libp2p-core/peer"
+)
+
+func TestCIDRMask(t *testing.T) {
+	cases := map[string]string{
+		"10.0.0.0/8":             "/ip4/10.0.0.0/ipcidr/8",
+		"192.168.1.7/24":         "/ip4/192.168.1.0/ipcidr/24",
+		"fd00::/8":               "/ip6/fd00::/ipcidr/8",
+		"/ip4/1.2.3.0/ipcidr/24": "/ip4/1.2.3.0/ipcidr/24",
+	}
+	for cidr, want := range cases {
+		got, err := cidrMask(cidr)
+		if err != nil || got != want {
+			t.Errorf("cidrMask(%q) = %q, %v, want %q", cidr, got, err, want)
+		}
+	}
+	if _, err := cidrMask("10.0.0.0"); err == nil {
+		t.Error("expected an error for an address without a prefix length")
+	}
+}
+
+func TestPeerGate(t *testing.T) {
+	conf := aitConf.Global.IPFS
+	defer func() { aitConf.Global.IPFS = conf }()
+
+	relay, _ := peer.Decode(arkenRelayID)
+	denied, _ := peer.Decode("QmNnooDu7bfjPFoTZYxMNLWUQJyrVwtbZg5gBMjTezGAJN")
+	allowed, _ := peer.Decode("QmcZf59bWwK5XFi76CZX8cbJ4BhTzzA3gU1ZjYZcYW3dwt")
+	other, _ := peer.Decode("QmaCpDMGvV2BGHeYERUEnRQAwe3N8SzbUtfsmvsqQLuvuJ")
+
+	aitConf.Global.IPFS.DenyPeers = []string{denied.String()}
+	aitConf.Global.IPFS.AllowPeers = []string{allowed.String()}
+	aitConf.Global.IPFS.ClusterOnly = false
+	gate, err := newPeerGate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if gate.allowed(denied) || !gate.allowed(other) || !gate.allowed(relay) {
+		t.Error("expected only the denied peer to be rejected")
+	}
+
+	aitConf.Global.IPFS.ClusterOnly = true
+	gate, err = newPeerGate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if gate.allowed(denied) || gate.allowed(other) || !gate.allowed(allowed) || !gate.allowed(relay) {
+		t.Error("expected only the cluster and allowed peers to be accepted")
+	}
+
+	aitConf.Global.IPFS.DenyPeers = []string{"not-a-peer"}
+	if _, err = newPeerGate(); err == nil {
+		t.Error("expected an error for an invalid peer ID")
+	}
+}
@@ -0,0 +1,33 @@
+package ipfs
+
+import (
+	"github.com/ipfs/interface-go-ipfs-core/options"
+	icorepath "github.com/ipfs/interface-go-ipfs-core/path"
+)
+
+// BuildRoot creates a directory linking to each file (name -> CID) so that a
+// whole dataset can be referenced by a single CID. Names may be slash
+// separated paths, in which case the subdirectories are created. The directory is pinned
+// and its CID returned. The files must already be in the
+// repository, and the workspace link must exist if they were added through it.
+func BuildRoot(entries map[string]string) (cid string, err error) {
+	root, err := ipfs.Object().New(ctx, options.Object.Type("unixfs-dir"))
+	if err != nil {
+		return cid, err
+	}
+	var base icorepath.Resolved = icorepath.IpfsPath(root.Cid())
+	for name, hash := range entries {
+		base, err = ipfs.Object().AddLink(ctx, base, name, icorepath.New("/ipfs/"+hash),
+			options.Object.Create(true))
+		if err != nil {
+			return cid, err
+		}
+	}
+	// The files are pinned on their own, so only the directory node itself
+	// needs to be kept.
+	err = ipfs.Pin().Add(ctx, base, options.Pin.Recursive(false))
+	if err != nil {
+		return cid, err
+	}
+	return base.Cid().String(), nil
+}
@@ -0,0 +1,137 @@
+package ipfs
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+
+	aitConf "github.com/arken/ait/config"
+
+	config "github.com/ipfs/go-ipfs-config"
+	serialize "github.com/ipfs/go-ipfs-config/serialize"
+	"github.com/ipfs/go-ipfs/repo/fsrepo"
+	crypto "github.com/libp2p/go-libp2p-core/crypto"
+	"github.com/libp2p/go-libp2p-core/peer"
+)
+
+// identityConfig returns the config of the IPFS repository, which holds the
+// identity of the node. An external daemon has an identity of its own, so
+// ait's isn't used with one.
+func identityConfig() (*config.Config, error) {
+	if err := requireOwnRepository("Managing the node's identity"); err != nil {
+		return nil, err
+	}
+	if !fsrepo.IsInitialized(aitConf.Global.IPFS.Path) {
+		return nil, errors.New("the IPFS repository has no identity yet, " +
+			"run \"ait ipfs fsck\" to create one")
+	}
+	return fsrepo.ConfigAt(aitConf.Global.IPFS.Path)
+}
+
+// NodeID returns the peer ID of the IPFS repository without starting a node,
+// the one GetID returns once it's started. With an external daemon, it's the
+// daemon's.
+func NodeID() (string, error) {
+	b, err := externalDaemon()
+	if err != nil {
+		return "", err
+	}
+	if b != nil {
+		if b.peerID == "" {
+			if err = b.probe(); err != nil {
+				return "", err
+			}
+		}
+		return b.peerID, nil
+	}
+	cfg, err := identityConfig()
+	if err != nil {
+		return "", err
+	}
+	return cfg.Identity.PeerID, nil
+}
+
+// ExportIdentity returns the private key of the node's identity in the libp2p
+// protobuf encoding, as ExportKey does for IPNS keys.
+func ExportIdentity() ([]byte, error) {
+	cfg, err := identityConfig()
+	if err != nil {
+		return nil, err
+	}
+	priv, err := cfg.Identity.DecodePrivateKey("")
+	if err != nil {
+		return nil, err
+	}
+	return crypto.MarshalPrivateKey(priv)
+}
+
+// ImportIdentity makes the private key exported by ExportIdentity the node's
+// identity. The identity it replaces is kept in the keystore under keepAs.
+// It returns the peer IDs of the replaced identity and of the new one.
+func ImportIdentity(data []byte, keepAs string) (oldID, newID string, err error) {
+	priv, err := crypto.UnmarshalPrivateKey(data)
+	if err != nil {
+		return "", "", fmt.Errorf("not an exported identity: %v", err)
+	}
+	return replaceIdentity(priv, keepAs)
+}
+
+// RotateIdentity gives the node a new ed25519 identity. The identity it
+// replaces is kept in the keystore under keepAs. It returns the peer IDs of
+// the replaced identity and of the new one.
+func RotateIdentity(keepAs string) (oldID, newID string, err error) {
+	priv, _, err := crypto.GenerateEd25519Key(rand.Reader)
+	if err != nil {
+		return "", "", err
+	}
+	return replaceIdentity(priv, keepAs)
+}
+
+// replaceIdentity writes priv as the identity in the config of the IPFS
+// repository, once the one it replaces is saved to the keystore under keepAs.
+// The repository can't be in use, a running node would keep its identity.
+func replaceIdentity(priv crypto.PrivKey, keepAs string) (oldID, newID string, err error) {
+	path := aitConf.Global.IPFS.Path
+	cfg, err := identityConfig()
+	if err != nil {
+		return "", "", err
+	}
+	if locked, err := fsrepo.LockedByOtherProcess(path); err != nil {
+		return "", "", err
+	} else if locked {
+		return "", "", errors.New("the IPFS repository is in use, stop \"ait daemon\" " +
+			"and other ait commands first")
+	}
+	id, err := peer.IDFromPrivateKey(priv)
+	if err != nil {
+		return "", "", err
+	}
+	oldID, newID = cfg.Identity.PeerID, id.Pretty()
+	if oldID == newID {
+		return oldID, newID, errors.New("the node already has this identity")
+	}
+	old, err := cfg.Identity.DecodePrivateKey("")
+	if err != nil {
+		return "", "", err
+	}
+	ks, err := openKeystore()
+	if err != nil {
+		return "", "", err
+	}
+	if err = ks.Put(keepAs, old); err != nil {
+		return "", "", err
+	}
+
+	encoded, err := crypto.MarshalPrivateKey(priv)
+	if err != nil {
+		return "", "", err
+	}
+	cfg.Identity.PeerID = newID
+	cfg.Identity.PrivKey = base64.StdEncoding.EncodeToString(encoded)
+	configFilename, err := config.Filename(path)
+	if err != nil {
+		return "", "", err
+	}
+	return oldID, newID, serialize.WriteConfigFile(configFilename, cfg)
+}
@@ -0,0 +1,76 @@
+package ipfs
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	aitConf "github.com/arken/ait/config"
+
+	config "github.com/ipfs/go-ipfs-config"
+	serialize "github.com/ipfs/go-ipfs-config/serialize"
+)
+
+func TestReplaceIdentity(t *testing.T) {
+	root, err := ioutil.TempDir("", "ait-identity")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+	repoPath := aitConf.Global.IPFS.Path
+	aitConf.Global.IPFS.Path = filepath.Join(root, "ipfs")
+	defer func() { aitConf.Global.IPFS.Path = repoPath }()
+	if _, err := NodeID(); err == nil {
+		t.Fatal("expected an error without a repository")
+	}
+
+	cfg, err := config.Init(ioutil.Discard, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(aitConf.Global.IPFS.Path, os.ModePerm); err != nil {
+		t.Fatal(err)
+	}
+	if err := serialize.WriteConfigFile(filepath.Join(aitConf.Global.IPFS.Path, "config"), cfg); err != nil {
+		t.Fatal(err)
+	}
+	first, err := NodeID()
+	if err != nil || first != cfg.Identity.PeerID {
+		t.Fatalf("NodeID() = %v, %v, expected %v", first, err, cfg.Identity.PeerID)
+	}
+	exported, err := ExportIdentity()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	previous, rotated, err := RotateIdentity("identity-old")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if previous != first || rotated == first {
+		t.Fatalf("rotated from %v to %v, expected from %v to a new ID", previous, rotated, first)
+	}
+	if id, _ := NodeID(); id != rotated {
+		t.Fatalf("the node is %v after rotating to %v", id, rotated)
+	}
+	keys, err := ListKeys()
+	if err != nil || len(keys) != 1 || keys[0].Name != "identity-old" || keys[0].ID != first {
+		t.Fatalf("the replaced identity wasn't kept: %v, %v", keys, err)
+	}
+
+	// The exported identity comes back, the rotated one is kept in turn.
+	previous, imported, err := ImportIdentity(exported, "identity-rotated")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if previous != rotated || imported != first {
+		t.Fatalf("imported %v over %v, expected %v over %v", imported, previous, first, rotated)
+	}
+	if _, _, err := ImportIdentity(exported, "identity-again"); err == nil {
+		t.Fatal("expected an error importing the current identity")
+	}
+	if _, _, err := ImportIdentity([]byte("not a key"), "identity-bad"); err == nil {
+		t.Fatal("expected an error importing garbage")
+	}
+}
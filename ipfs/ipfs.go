@@ -7,10 +7,8 @@ import (
 	"log"
 	"os"
 	"path/filepath"
-	"strconv"
 	"strings"
 	"sync"
-	"time"
 
 	aitConf "github.com/arken/ait/config"
 
@@ -18,7 +16,6 @@ import (
 	serialize "github.com/ipfs/go-ipfs-config/serialize"
 	libp2p "github.com/ipfs/go-ipfs/core/node/libp2p"
 	"github.com/ipfs/go-ipfs/peering"
-	migrate "github.com/ipfs/go-ipfs/repo/fsrepo/migrations"
 	icore "github.com/ipfs/interface-go-ipfs-core"
 	peerstore "github.com/libp2p/go-libp2p-peerstore"
 	ma "github.com/multiformats/go-multiaddr"
@@ -41,28 +38,44 @@ var (
 	cancel           context.CancelFunc
 )
 
-// Init starts the IPFS subsystem.
+// The connection manager limits of provide-only nodes, which only need the
+// Arken nodes and the peers fetching their data.
+const (
+	provideOnlyLowWater  = 20
+	provideOnlyHighWater = 50
+)
+
+// arkenPeers returns the addresses of the bootstrappers and relays the node
+// connects to.
+func arkenPeers() []string {
+	return append(append([]string{}, bootstrapPeers()...), relayPeers()...)
+}
+
+// Init starts the IPFS subsystem on the backend IPFS.Backend selects.
 func Init(online bool) {
+	if err := Start(online); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// Start is Init returning the error starting the IPFS subsystem failed with,
+// for programs embedding ait.
+func Start(online bool) error {
 	var err error
 	ctx, cancel = context.WithCancel(context.Background())
 
-	ctx, ipfs, err = spawnNode(aitConf.Global.IPFS.Path, online)
+	current, err = selectBackend()
 	if err != nil {
-		log.Fatal(err)
+		return err
 	}
-
-	cfg, err := node.Repo.Config()
+	ipfs, err = current.start(online)
 	if err != nil {
-		log.Fatal(err)
-	}
-	cfg.Experimental.FilestoreEnabled = true
-	peers := []string{
-		// Arken Bootstrapper node.
-		"/dns4/link.arken.io/tcp/4001/ipfs/12D3KooWSmosHZtDBbepxWwVgo8HyXSgNCUgs2GGD2qnQPbA3KhD",
-		"/dns4/relay.arken.io/tcp/4001/ipfs/12D3KooWL7hvR7nfQxAWMowgoWXWQwKEkQA8QPZrhKjateRTgcDm",
+		return err
 	}
-	go connectToPeers(ctx, ipfs, peers)
-
+	AtRiskThreshhold = aitConf.Global.Notify.AtRiskThreshold
+	go connectToPeers(ctx, ipfs, arkenPeers())
+	checkStorage()
+	return nil
 }
 
 // spawnNode creates and tests and IPFS node for public reachability.
@@ -70,6 +83,10 @@ func spawnNode(path string, online bool) (ctx context.Context, api icore.CoreAPI
 	// Create IPFS node
 	ctx, cancel = context.WithCancel(context.Background())
 
+	loadArkenPeers()
+	if err = applyPeersConfig(); err != nil {
+		return ctx, api, err
+	}
 	err = setRelay(false, path)
 	if err != nil && err.Error() != "ipfs not initialized, please run 'ipfs init'" {
 		return ctx, api, err
@@ -80,24 +97,24 @@ func spawnNode(path string, online bool) (ctx context.Context, api icore.CoreAPI
 	}
 
 	if online {
-		// Wait 30s before testing reachability
 		fmt.Printf("[Checking Node Reachability on Arken Network]\n")
-		time.Sleep(30 * time.Second)
-		public, err := checkReachability(api)
+		public, err := waitReachability(api, reachabilityTimeout())
 		if err != nil {
 			return ctx, api, err
 		}
 		// If the node isn't publicly reachable switch to relay system.
 		if !public {
-			cancel()
 			fmt.Printf("[Node unable to be reached by network.]\n")
 			fmt.Printf("[Recreating using Circuit Relay System.]\n")
+			// Closing the node waits for its ports and repository to be
+			// released, so it can be recreated right away.
+			if err = node.Close(); err != nil {
+				return ctx, api, err
+			}
+			cancel()
 
 			setRelay(true, path)
 
-			// Wait for port to free
-			time.Sleep(30 * time.Second)
-
 			// Recreate IPFS Node
 			ctx, cancel = context.WithCancel(context.Background())
 			api, err = createNode(ctx, path)
@@ -107,13 +124,18 @@ func spawnNode(path string, online bool) (ctx context.Context, api icore.CoreAPI
 			fmt.Printf("[Node Re-Created Sucessfully]\n")
 
 			ps = peering.NewPeeringService(node.PeerHost)
-			addr, err := ma.NewMultiaddr("/dns4/relay.arken.io/tcp/4001/ipfs/12D3KooWL7hvR7nfQxAWMowgoWXWQwKEkQA8QPZrhKjateRTgcDm")
-			if err != nil {
-				log.Fatal(err)
+			for _, addr := range relayPeers() {
+				info, err := peer.AddrInfoFromP2pAddr(ma.StringCast(addr))
+				if err != nil {
+					log.Fatal(err)
+				}
+				ps.AddPeer(*info)
 			}
-			ps.AddPeer(peer.AddrInfo{ID: "12D3KooWL7hvR7nfQxAWMowgoWXWQwKEkQA8QPZrhKjateRTgcDm", Addrs: []ma.Multiaddr{addr}})
 			ps.Start()
 
+			relayed = true
+			go monitorRelay(ctx)
+
 		} else {
 			fmt.Printf("[Arken Node is Publicly Reachable with NAT]\n")
 		}
@@ -127,14 +149,27 @@ func setRelay(relay bool, path string) (err error) {
 	if err != nil {
 		return err
 	}
+	cfg.Addresses.Announce = []string{}
 	if relay {
-		cfg.Addresses.Announce = []string{
-			"/dns4/relay.arken.io/tcp/4001/p2p/12D3KooWL7hvR7nfQxAWMowgoWXWQwKEkQA8QPZrhKjateRTgcDm/p2p-circuit/p2p/" + cfg.Identity.PeerID,
+		for _, addr := range relayPeers() {
+			cfg.Addresses.Announce = append(cfg.Addresses.Announce,
+				addr+"/p2p-circuit/p2p/"+cfg.Identity.PeerID)
 		}
-	} else {
-		cfg.Addresses.Announce = []string{}
 	}
-	cfg.Routing.Type = "dhtserver"
+	applyRoutingConfig(cfg)
+	applyReprovideConfig(cfg)
+	err = applyStorageConfig(cfg)
+	if err != nil {
+		return err
+	}
+	err = applyGateConfig(cfg)
+	if err != nil {
+		return err
+	}
+	err = applyTransportConfig(cfg)
+	if err != nil {
+		return err
+	}
 
 	configFilename, err := config.Filename(path)
 	if err != nil {
@@ -146,6 +181,48 @@ func setRelay(relay bool, path string) (err error) {
 	return nil
 }
 
+// applyRoutingConfig makes the node a full DHT server, or in provide-only
+// mode a DHT client keeping few connections besides the Arken nodes. DHTClient
+// only gives up serving the DHT, and the configured watermarks override those
+// of either mode.
+func applyRoutingConfig(cfg *config.Config) {
+	conf := aitConf.Global.IPFS
+	cfg.Routing.Type = "dhtserver"
+	cfg.Swarm.ConnMgr.LowWater = config.DefaultConnMgrLowWater
+	cfg.Swarm.ConnMgr.HighWater = config.DefaultConnMgrHighWater
+	if conf.ProvideOnly {
+		cfg.Routing.Type = "dhtclient"
+		cfg.Swarm.ConnMgr.LowWater = provideOnlyLowWater
+		cfg.Swarm.ConnMgr.HighWater = provideOnlyHighWater
+	} else if conf.DHTClient {
+		cfg.Routing.Type = "dhtclient"
+	}
+	if conf.ConnMgrLowWater > 0 {
+		cfg.Swarm.ConnMgr.LowWater = conf.ConnMgrLowWater
+	}
+	if conf.ConnMgrHighWater > 0 {
+		cfg.Swarm.ConnMgr.HighWater = conf.ConnMgrHighWater
+	}
+	// Only one of them may be set, above the default of the other.
+	if cfg.Swarm.ConnMgr.LowWater > cfg.Swarm.ConnMgr.HighWater {
+		if conf.ConnMgrHighWater > 0 {
+			cfg.Swarm.ConnMgr.LowWater = cfg.Swarm.ConnMgr.HighWater
+		} else {
+			cfg.Swarm.ConnMgr.HighWater = cfg.Swarm.ConnMgr.LowWater
+		}
+	}
+}
+
+// routingOption returns the DHT routing matching applyRoutingConfig.
+func routingOption() libp2p.RoutingOption {
+	if aitConf.Global.IPFS.ProvideOnly || aitConf.Global.IPFS.DHTClient {
+		// Only fetch and publish DHT records, never store them for others.
+		return libp2p.DHTClientOption
+	}
+	// A full DHT node both fetching and storing DHT records.
+	return libp2p.DHTOption
+}
+
 // checkReachability tests if the IPFS node is reachable by the network
 // and opts to use a relay if it is not.
 func checkReachability(api icore.CoreAPI) (public bool, err error) {
@@ -175,7 +252,25 @@ func checkReachability(api icore.CoreAPI) (public bool, err error) {
 
 // GetID returns the identifier of the node.
 func GetID() (result string) {
-	return node.Identity.Pretty()
+	return current.id()
+}
+
+// PeerCount returns the number of peers the node is connected to.
+func PeerCount() int {
+	return current.peerCount()
+}
+
+// Close stops the node and releases its repository, so another ait process
+// can open it. An external daemon is left running.
+func Close() error {
+	if current == nil {
+		return nil
+	}
+	err := current.close()
+	if cancel != nil {
+		cancel()
+	}
+	return err
 }
 
 // setupPlugins loads an initializes any external plugins
@@ -222,12 +317,7 @@ func createNode(ctx context.Context, repoPath string) (icore.CoreAPI, error) {
 	repo, err := fsrepo.Open(repoPath)
 	if err != nil {
 		if err == fsrepo.ErrNeedMigration {
-			err = migrate.RunMigration(fsrepo.RepoVersion)
-			if err != nil {
-				return nil, err
-			}
-			version := strconv.Itoa(fsrepo.RepoVersion)
-			err := ioutil.WriteFile(filepath.Join(repoPath, migrate.VersionFile), []byte(version), 0644)
+			err = migrateRepo(repoPath)
 			if err != nil {
 				return nil, err
 			}
@@ -244,8 +334,11 @@ func createNode(ctx context.Context, repoPath string) (icore.CoreAPI, error) {
 	nodeOptions := &core.BuildCfg{
 		Permanent: true,
 		Online:    true,
-		Routing:   libp2p.DHTOption, // This option sets the node to be a full DHT node (both fetching and storing DHT Records)
+		Routing:   routingOption(),
+		Host:      hostOption(),
 		Repo:      repo,
+		// Keepers report the progress of pin plans over pubsub.
+		ExtraOpts: map[string]bool{"pubsub": true},
 	}
 
 	node, err = core.NewNode(ctx, nodeOptions)
@@ -255,6 +348,11 @@ func createNode(ctx context.Context, repoPath string) (icore.CoreAPI, error) {
 
 	node.IsDaemon = true
 
+	// Drop connections to the peers the config doesn't allow.
+	if err = gatePeers(); err != nil {
+		return nil, err
+	}
+
 	// Attach the Core API to the constructed node
 	return coreapi.NewCoreAPI(node)
 }
@@ -306,16 +404,23 @@ func createRepo(ctx context.Context, path string) (string, error) {
 		return "", err
 	}
 
-	cfg.Datastore.StorageMax = "100TB"
+	err = applyStorageConfig(cfg)
+	if err != nil {
+		return "", err
+	}
+	err = applyGateConfig(cfg)
+	if err != nil {
+		return "", err
+	}
+	err = applyTransportConfig(cfg)
+	if err != nil {
+		return "", err
+	}
 	cfg.Reprovider.Strategy = "roots"
-	cfg.Reprovider.Interval = "1h"
-	cfg.Routing.Type = "dhtserver"
+	applyReprovideConfig(cfg)
+	applyRoutingConfig(cfg)
 	cfg.Experimental.FilestoreEnabled = true
-	bootstrapNodes := []string{
-		// Arken Bootstrapper node.
-		"/dns4/link.arken.io/tcp/4001/ipfs/12D3KooWSmosHZtDBbepxWwVgo8HyXSgNCUgs2GGD2qnQPbA3KhD",
-	}
-	cfg.Bootstrap = bootstrapNodes
+	cfg.Bootstrap = bootstrapPeers()
 
 	// Create the repo with the config
 	err = fsrepo.Init(path, cfg)
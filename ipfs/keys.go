@@ -0,0 +1,135 @@
+package ipfs
+
+import (
+	"crypto/rand"
+	"fmt"
+	"path/filepath"
+	"sort"
+
+	aitConf "github.com/arken/ait/config"
+
+	"github.com/ipfs/go-ipfs/keystore"
+	"github.com/ipfs/interface-go-ipfs-core/options"
+	icorepath "github.com/ipfs/interface-go-ipfs-core/path"
+	crypto "github.com/libp2p/go-libp2p-core/crypto"
+	"github.com/libp2p/go-libp2p-core/peer"
+)
+
+// Key is a named IPNS key stored in the repository's keystore.
+type Key struct {
+	Name string
+	ID   string
+}
+
+// openKeystore opens the keystore of the configured repository without
+// starting a node. An external daemon publishes with the keys of its own
+// keystore, so ait's isn't used with one.
+func openKeystore() (*keystore.FSKeystore, error) {
+	if err := requireOwnRepository("Managing IPNS keys"); err != nil {
+		return nil, err
+	}
+	return keystore.NewFSKeystore(filepath.Join(aitConf.Global.IPFS.Path, "keystore"))
+}
+
+// GenerateKey creates a new ed25519 IPNS key with the given name and returns
+// its identifier.
+func GenerateKey(name string) (key Key, err error) {
+	if name == "" || name == "self" {
+		return key, fmt.Errorf("invalid key name %q", name)
+	}
+	ks, err := openKeystore()
+	if err != nil {
+		return key, err
+	}
+	priv, _, err := crypto.GenerateEd25519Key(rand.Reader)
+	if err != nil {
+		return key, err
+	}
+	if err = ks.Put(name, priv); err != nil {
+		return key, err
+	}
+	return keyFromPrivate(name, priv)
+}
+
+// ListKeys returns the keys stored in the keystore sorted by name.
+func ListKeys() (keys []Key, err error) {
+	ks, err := openKeystore()
+	if err != nil {
+		return keys, err
+	}
+	names, err := ks.List()
+	if err != nil {
+		return keys, err
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		priv, err := ks.Get(name)
+		if err != nil {
+			return keys, err
+		}
+		key, err := keyFromPrivate(name, priv)
+		if err != nil {
+			return keys, err
+		}
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
+
+// ExportKey returns the named private key in the libp2p protobuf encoding
+// used by go-ipfs for key export.
+func ExportKey(name string) ([]byte, error) {
+	ks, err := openKeystore()
+	if err != nil {
+		return nil, err
+	}
+	priv, err := ks.Get(name)
+	if err != nil {
+		return nil, err
+	}
+	return crypto.MarshalPrivateKey(priv)
+}
+
+// ImportKey stores a private key previously produced by ExportKey under name.
+func ImportKey(name string, data []byte) (key Key, err error) {
+	ks, err := openKeystore()
+	if err != nil {
+		return key, err
+	}
+	priv, err := crypto.UnmarshalPrivateKey(data)
+	if err != nil {
+		return key, fmt.Errorf("not an exported IPNS key: %v", err)
+	}
+	if err = ks.Put(name, priv); err != nil {
+		return key, err
+	}
+	return keyFromPrivate(name, priv)
+}
+
+// RemoveKey deletes the named key from the keystore.
+func RemoveKey(name string) error {
+	ks, err := openKeystore()
+	if err != nil {
+		return err
+	}
+	return ks.Delete(name)
+}
+
+// Publish points the IPNS name of the given key at a CID and returns the
+// published name. The IPFS subsystem must be initialized.
+func Publish(keyName, hash string) (string, error) {
+	entry, err := ipfs.Name().Publish(ctx, icorepath.New("/ipfs/"+hash), options.Name.Key(keyName))
+	if err != nil {
+		return "", err
+	}
+	return entry.Name(), nil
+}
+
+// keyFromPrivate builds a Key from a name and its private key.
+func keyFromPrivate(name string, priv crypto.PrivKey) (key Key, err error) {
+	id, err := peer.IDFromPrivateKey(priv)
+	if err != nil {
+		return key, err
+	}
+	return Key{Name: name, ID: id.Pretty()}, nil
+}
@@ -0,0 +1,126 @@
+package ipfs
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	aitConf "github.com/arken/ait/config"
+	"github.com/arken/ait/utils"
+
+	"github.com/ipfs/go-ipfs/repo/fsrepo"
+	migrate "github.com/ipfs/go-ipfs/repo/fsrepo/migrations"
+)
+
+// Migration policies accepted by the IPFS.Migrate config setting.
+const (
+	MigratePrompt = "prompt"
+	MigrateAuto   = "auto"
+	MigrateNever  = "never"
+)
+
+// migrateRepo upgrades the repository at repoPath to the version expected by
+// the embedded node, following the configured migration policy.
+func migrateRepo(repoPath string) error {
+	current, err := migrate.RepoPath(repoPath).Version()
+	if err != nil {
+		return err
+	}
+	target := fsrepo.RepoVersion
+	backup := aitConf.Global.IPFS.MigrateBackup
+
+	switch aitConf.Global.IPFS.Migrate {
+	case MigrateNever:
+		return fmt.Errorf("the IPFS repository at %v is version %d but version %d is required. "+
+			"Rerun with --auto-migrate to upgrade it", repoPath, current, target)
+	case MigrateAuto:
+	default:
+		fmt.Printf("\nThe IPFS repository at %v needs to be migrated from version %d to %d.\n"+
+			"This can take a long time for large repositories. Migrate now? (y/[n]) ",
+			repoPath, current, target)
+		if !promptYes(false) {
+			return fmt.Errorf("IPFS repository migration declined")
+		}
+		if !backup {
+			fmt.Print("Back up the repository before migrating? (y/[n]) ")
+			backup = promptYes(false)
+		}
+	}
+
+	if backup {
+		dest := fmt.Sprintf("%v.backup-v%d", strings.TrimSuffix(repoPath, string(filepath.Separator)), current)
+		fmt.Printf("[Backing up IPFS repository to %v]\n", dest)
+		if err := backupRepo(repoPath, dest); err != nil {
+			return fmt.Errorf("repository backup failed, not migrating: %v", err)
+		}
+	}
+
+	// fs-repo-migrations locates the repository through the environment.
+	os.Setenv("IPFS_PATH", repoPath)
+	fmt.Printf("[Migrating IPFS repository from version %d to %d]\n", current, target)
+	start := time.Now()
+	if err := migrate.RunMigration(target); err != nil {
+		return err
+	}
+	if err := migrate.RepoPath(repoPath).WriteVersion(target); err != nil {
+		return err
+	}
+	fmt.Printf("[Migration finished in %v]\n", time.Since(start).Round(time.Second))
+	return nil
+}
+
+// backupRepo recursively copies the repository at src to dest, streaming each
+// file so large datastores are not read into memory.
+func backupRepo(src, dest string) error {
+	if _, err := os.Stat(dest); err == nil {
+		return fmt.Errorf("%v already exists", dest)
+	}
+	return filepath.Walk(src, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dest, rel)
+		switch {
+		case info.IsDir():
+			return os.MkdirAll(target, info.Mode())
+		case info.Mode()&os.ModeSymlink != 0:
+			link, err := os.Readlink(path)
+			if err != nil {
+				return err
+			}
+			return os.Symlink(link, target)
+		case rel == "repo.lock":
+			return nil
+		}
+		in, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer in.Close()
+		out, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, info.Mode())
+		if err != nil {
+			return err
+		}
+		if _, err = io.Copy(out, in); err != nil {
+			out.Close()
+			return err
+		}
+		return out.Close()
+	})
+}
+
+// promptYes reads a yes/no answer from stdin, returning def on an empty line.
+func promptYes(def bool) bool {
+	input := strings.ToLower(utils.ReadAnswer())
+	if input == "" {
+		return def
+	}
+	return input == "y"
+}
@@ -0,0 +1,121 @@
+package ipfs
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+)
+
+// PeerDial is the result of dialing one of the Arken peers over TCP.
+type PeerDial struct {
+	Name string
+	// Host is the host and port dialed.
+	Host    string
+	Latency time.Duration
+	Err     error
+}
+
+// DialArkenPeers checks that the Arken bootstrapper and relay accept TCP
+// connections, without starting a node, and measures how long connecting
+// took.
+func DialArkenPeers(timeout time.Duration) []PeerDial {
+	loadArkenPeers()
+	if err := applyPeersConfig(); err != nil {
+		return []PeerDial{{Name: "configured peers", Err: err}}
+	}
+	bootstrappers := len(bootstrapPeers())
+	peers := arkenPeers()
+	dials := make([]PeerDial, 0, len(peers))
+	for i, addr := range peers {
+		parts := strings.Split(addr, "/")
+		if len(parts) < 5 {
+			continue
+		}
+		name := "bootstrapper"
+		if i >= bootstrappers {
+			name = "relay"
+		}
+		dial := PeerDial{Name: name, Host: net.JoinHostPort(parts[2], parts[4])}
+		start := time.Now()
+		conn, err := dialTCP(dial.Host, timeout)
+		dial.Latency, dial.Err = time.Since(start), err
+		if err == nil {
+			conn.Close()
+		}
+		dials = append(dials, dial)
+	}
+	return dials
+}
+
+// Check is the outcome of a single network check.
+type Check struct {
+	Name   string
+	OK     bool
+	Detail string
+	// Fix is what can be done about the check failing, if anything.
+	Fix string
+}
+
+// CheckNode checks the running node's view of the Arken network: whether it
+// connected to the bootstrapper and relay, whether its DHT has bootstrapped
+// and whether it's reachable from outside its NAT. Connections are waited
+// for up to timeout. The IPFS subsystem must be initialized.
+func CheckNode(timeout time.Duration) []Check {
+	deadline := time.Now().Add(timeout)
+	for time.Now().Before(deadline) && !(BootstrapperConnected() && dhtPeers() > 0) {
+		time.Sleep(time.Second)
+	}
+
+	var checks []Check
+	for _, p := range []struct {
+		name string
+		id   string
+	}{{"Bootstrapper connection", arkenBootstrapID}, {"Relay connection", arkenRelayID}} {
+		check := Check{Name: p.name}
+		if connected, latency := current.connection(mustDecodeID(p.id)); connected {
+			check.OK = true
+			check.Detail = "connected"
+			if latency > 0 {
+				check.Detail = fmt.Sprintf("connected, %v round trip", latency.Round(time.Millisecond))
+			}
+		} else {
+			check.Detail = "not connected after " + timeout.String()
+		}
+		checks = append(checks, check)
+	}
+
+	check := Check{Name: "DHT bootstrap"}
+	if n := dhtPeers(); n > 0 {
+		check.OK = true
+		check.Detail = fmt.Sprintf("%d peers in the routing table", n)
+		c, cancel := context.WithTimeout(ctx, timeout)
+		defer cancel()
+		// Looking the relay up exercises a real DHT query.
+		if _, err := ipfs.Dht().FindPeer(c, mustDecodeID(arkenRelayID)); err != nil {
+			check.OK = false
+			check.Detail += fmt.Sprintf(", but looking up the relay failed: %v", err)
+		}
+	} else {
+		check.Detail = "the routing table is empty"
+	}
+	checks = append(checks, check)
+
+	check = Check{Name: "NAT"}
+	if public, err := checkReachability(ipfs); err != nil {
+		check.Detail = err.Error()
+	} else if public {
+		check.OK = true
+		check.Detail = "the node has a public address"
+	} else {
+		check.Detail = "only private addresses, uploads will go through the relay"
+	}
+	return append(checks, check)
+}
+
+// dhtPeers returns the number of peers in the routing tables of the node's
+// DHTs.
+func dhtPeers() int {
+	return current.routingPeers()
+}
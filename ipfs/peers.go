@@ -0,0 +1,237 @@
+package ipfs
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	aitConf "github.com/arken/ait/config"
+	"github.com/arken/ait/utils"
+
+	"github.com/libp2p/go-libp2p-core/peer"
+	ma "github.com/multiformats/go-multiaddr"
+)
+
+// The identities the Arken nodes were released with, trusted until a signed
+// peer manifest names others.
+const (
+	builtinBootstrapID = "12D3KooWSmosHZtDBbepxWwVgo8HyXSgNCUgs2GGD2qnQPbA3KhD"
+	builtinRelayID     = "12D3KooWL7hvR7nfQxAWMowgoWXWQwKEkQA8QPZrhKjateRTgcDm"
+)
+
+var (
+	// arkenBootstrapID is the peer identity of the Arken bootstrapper node.
+	arkenBootstrapID = builtinBootstrapID
+	// arkenRelayID is the peer identity of the shared Arken circuit relay.
+	arkenRelayID = builtinRelayID
+	peersLoaded  sync.Once
+)
+
+// peersFile caches the signed peer manifests, next to the config.
+const peersFile = "arken_peers.json"
+
+// peersRefresh is how long the cached peer manifests are used before they're
+// fetched again, and peersTimeout how long fetching them may take.
+const (
+	peersRefresh = 24 * time.Hour
+	peersTimeout = 5 * time.Second
+)
+
+// PeerManifest names the current identities of the Arken nodes. Its version
+// grows with every rotation, so an older manifest never replaces a newer one.
+type PeerManifest struct {
+	Version   int       `json:"version"`
+	Bootstrap string    `json:"bootstrap"`
+	Relay     string    `json:"relay"`
+	Issued    time.Time `json:"issued"`
+}
+
+// SignedPeerManifest is a manifest, as JSON, signed with the private key of
+// Signer, the bootstrapper or relay identity trusted before it.
+type SignedPeerManifest struct {
+	Manifest  []byte `json:"manifest"`
+	Signer    string `json:"signer"`
+	Signature []byte `json:"signature"`
+}
+
+// ApplyPeerManifests applies the chain of signed manifests, oldest first, to
+// the trusted manifest current. Each manifest must be newer than the one it
+// follows and signed by one of the identities that one names, so a chain can
+// only be extended by the holder of a trusted key. Manifests already applied
+// are skipped, and the chain stops at the first one that isn't valid, which is
+// returned as an error along with the manifest applied until then.
+func ApplyPeerManifests(current PeerManifest, chain []SignedPeerManifest) (PeerManifest, error) {
+	for i, signed := range chain {
+		var next PeerManifest
+		if err := json.Unmarshal(signed.Manifest, &next); err != nil {
+			return current, fmt.Errorf("manifest %d is malformed: %v", i+1, err)
+		}
+		if next.Version <= current.Version {
+			continue
+		}
+		if signed.Signer != current.Bootstrap && signed.Signer != current.Relay {
+			return current, fmt.Errorf("version %d is signed by %v, which version %d doesn't trust",
+				next.Version, signed.Signer, current.Version)
+		}
+		if err := verifyPeerSignature(signed); err != nil {
+			return current, fmt.Errorf("version %d: %v", next.Version, err)
+		}
+		for _, id := range []string{next.Bootstrap, next.Relay} {
+			if _, err := peer.Decode(id); err != nil {
+				return current, fmt.Errorf("version %d names an invalid peer ID %q", next.Version, id)
+			}
+		}
+		current = next
+	}
+	return current, nil
+}
+
+// verifyPeerSignature checks the manifest was signed by its signer, whose
+// public key is part of its peer ID.
+func verifyPeerSignature(signed SignedPeerManifest) error {
+	id, err := peer.Decode(signed.Signer)
+	if err != nil {
+		return err
+	}
+	key, err := id.ExtractPublicKey()
+	if err != nil {
+		return fmt.Errorf("the public key of %v can't be read from its ID: %v", signed.Signer, err)
+	}
+	ok, err := key.Verify(signed.Manifest, signed.Signature)
+	if err != nil || !ok {
+		return fmt.Errorf("the signature of %v doesn't match", signed.Signer)
+	}
+	return nil
+}
+
+// loadArkenPeers sets the identities of the Arken nodes from the cached peer
+// manifests, fetching them again from the configured URL once they're older
+// than peersRefresh. Manifests that can't be fetched or verified are
+// reported and the identities trusted so far kept, so ait never stops
+// working because of them.
+func loadArkenPeers() {
+	peersLoaded.Do(func() {
+		builtin := PeerManifest{Bootstrap: builtinBootstrapID, Relay: builtinRelayID}
+		path := filepath.Join(filepath.Dir(aitConf.Path), peersFile)
+		current := builtin
+		if chain, err := readPeerChain(path); err == nil {
+			if current, err = ApplyPeerManifests(builtin, chain); err != nil {
+				fmt.Fprintln(os.Stderr, "Ignoring part of the cached Arken peer manifests:", err)
+			}
+		}
+		url := aitConf.Global.Network.PeersURL
+		if info, err := os.Stat(path); url != "" && (err != nil || time.Since(info.ModTime()) > peersRefresh) {
+			chain, data, err := fetchPeerChain(url)
+			var fetched PeerManifest
+			if err == nil {
+				fetched, err = ApplyPeerManifests(builtin, chain)
+			}
+			switch {
+			case err != nil:
+				fmt.Fprintln(os.Stderr, "Unable to update the Arken peer identities:", err)
+				// Tried again after peersRefresh, rather than slowing every
+				// run while offline.
+				if now := time.Now(); os.Chtimes(path, now, now) != nil {
+					_ = ioutil.WriteFile(path, []byte("[]"), 0644)
+				}
+			case fetched.Version >= current.Version:
+				current = fetched
+				_ = ioutil.WriteFile(path, data, 0644)
+			}
+		}
+		arkenBootstrapID, arkenRelayID = current.Bootstrap, current.Relay
+	})
+}
+
+// readPeerChain reads the chain of signed manifests cached at path.
+func readPeerChain(path string) ([]SignedPeerManifest, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var chain []SignedPeerManifest
+	return chain, json.Unmarshal(data, &chain)
+}
+
+// fetchPeerChain fetches the chain of signed manifests published at url,
+// returning it along with its JSON.
+func fetchPeerChain(url string) ([]SignedPeerManifest, []byte, error) {
+	resp, err := utils.NewClient(peersTimeout).Get(url)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, nil, fmt.Errorf("%v answered %v", url, resp.Status)
+	}
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, nil, err
+	}
+	var chain []SignedPeerManifest
+	if err = json.Unmarshal(data, &chain); err != nil {
+		return nil, nil, fmt.Errorf("%v isn't a chain of peer manifests: %v", url, err)
+	}
+	return chain, data, nil
+}
+
+// bootstrapPeers returns the addresses of the bootstrappers the node connects
+// to, the Arken bootstrapper unless IPFS.BootstrapPeers names others.
+func bootstrapPeers() []string {
+	return configuredPeers(aitConf.Global.IPFS.BootstrapPeers, aitConf.DefaultBootstrapPeer,
+		"link.arken.io", arkenBootstrapID)
+}
+
+// relayPeers returns the addresses of the circuit relays the node uses when
+// it can't be reached, the Arken relay unless IPFS.RelayPeers names others.
+func relayPeers() []string {
+	return configuredPeers(aitConf.Global.IPFS.RelayPeers, aitConf.DefaultRelayPeer,
+		"relay.arken.io", arkenRelayID)
+}
+
+// configuredPeers returns the configured addresses, or the address of the
+// Arken node at host when they're left to the default, so it follows the
+// transport and the rotations of the node's identity.
+func configuredPeers(addrs []string, def, host, id string) []string {
+	if len(addrs) == 0 || len(addrs) == 1 && addrs[0] == def {
+		return []string{arkenAddr(host, id)}
+	}
+	return addrs
+}
+
+// applyPeersConfig checks the configured bootstrappers and relays, and makes
+// the first of each the one the network checks and relay accounting follow.
+func applyPeersConfig() error {
+	for _, setting := range []struct {
+		name  string
+		addrs []string
+	}{{"BootstrapPeers", bootstrapPeers()}, {"RelayPeers", relayPeers()}} {
+		for _, addr := range setting.addrs {
+			if _, err := addrID(addr); err != nil {
+				return fmt.Errorf("invalid address %q in the IPFS %v setting: %v", addr, setting.name, err)
+			}
+		}
+	}
+	bootstrap, _ := addrID(bootstrapPeers()[0])
+	relay, _ := addrID(relayPeers()[0])
+	arkenBootstrapID, arkenRelayID = bootstrap.String(), relay.String()
+	return nil
+}
+
+// addrID returns the peer identity the address of a node ends with.
+func addrID(addr string) (peer.ID, error) {
+	parsed, err := ma.NewMultiaddr(addr)
+	if err != nil {
+		return "", err
+	}
+	info, err := peer.AddrInfoFromP2pAddr(parsed)
+	if err != nil {
+		return "", err
+	}
+	return info.ID, nil
+}
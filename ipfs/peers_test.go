@@ -0,0 +1,110 @@
+package ipfs
+
+import (
+	"encoding/json"
+	"testing"
+
+	aitConf "github.com/arken/ait/config"
+
+	"github.com/libp2p/go-libp2p-core/crypto"
+	"github.com/libp2p/go-libp2p-core/peer"
+)
+
+// testIdentity returns a new identity and its private key.
+func testIdentity(t *testing.T) (string, crypto.PrivKey) {
+	key, _, err := crypto.GenerateEd25519Key(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	id, err := peer.IDFromPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return id.String(), key
+}
+
+// signManifest signs the manifest m with key as the identity signer.
+func signManifest(t *testing.T, m PeerManifest, signer string, key crypto.PrivKey) SignedPeerManifest {
+	data, err := json.Marshal(m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sig, err := key.Sign(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return SignedPeerManifest{Manifest: data, Signer: signer, Signature: sig}
+}
+
+func TestApplyPeerManifests(t *testing.T) {
+	boot1, bootKey1 := testIdentity(t)
+	relay1, _ := testIdentity(t)
+	boot2, bootKey2 := testIdentity(t)
+	relay2, relayKey2 := testIdentity(t)
+	boot3, _ := testIdentity(t)
+	root := PeerManifest{Bootstrap: boot1, Relay: relay1}
+
+	v1 := signManifest(t, PeerManifest{Version: 1, Bootstrap: boot2, Relay: relay2}, boot1, bootKey1)
+	v2 := signManifest(t, PeerManifest{Version: 2, Bootstrap: boot3, Relay: relay2}, relay2, relayKey2)
+	current, err := ApplyPeerManifests(root, []SignedPeerManifest{v1, v2})
+	if err != nil || current.Version != 2 || current.Bootstrap != boot3 || current.Relay != relay2 {
+		t.Fatalf("expected version 2, got %+v, %v", current, err)
+	}
+	if again, err := ApplyPeerManifests(current, []SignedPeerManifest{v1, v2}); err != nil || again != current {
+		t.Error("applied manifests should be skipped, got", again, err)
+	}
+
+	// A manifest must be signed by an identity the one before it names.
+	if current, err = ApplyPeerManifests(root, []SignedPeerManifest{v2}); err == nil || current != root {
+		t.Error("skipping a rotation should be refused, got", current)
+	}
+	forged := signManifest(t, PeerManifest{Version: 3, Bootstrap: boot3, Relay: boot3}, boot2, bootKey1)
+	if current, err = ApplyPeerManifests(root, []SignedPeerManifest{v1, forged}); err == nil || current.Version != 1 {
+		t.Error("a forged signature should stop the chain at version 1, got", current)
+	}
+	tampered := v1
+	tampered.Manifest = []byte(`{"version": 1, "bootstrap": "` + boot3 + `", "relay": "` + relay2 + `"}`)
+	if _, err = ApplyPeerManifests(root, []SignedPeerManifest{tampered}); err == nil {
+		t.Error("a tampered manifest should be refused")
+	}
+	invalid := signManifest(t, PeerManifest{Version: 3, Bootstrap: "nope", Relay: relay2}, boot2, bootKey2)
+	if current, err = ApplyPeerManifests(root, []SignedPeerManifest{v1, invalid}); err == nil || current.Version != 1 {
+		t.Error("an invalid peer ID should be refused, got", current)
+	}
+}
+
+func TestConfiguredPeers(t *testing.T) {
+	conf := aitConf.Global
+	defer func() { aitConf.Global = conf }()
+	aitConf.Global.Network.Transport = TransportWebSocket
+
+	// Left to the defaults, the Arken nodes follow the transport.
+	aitConf.Global.IPFS.BootstrapPeers = []string{aitConf.DefaultBootstrapPeer}
+	aitConf.Global.IPFS.RelayPeers = nil
+	if err := applyPeersConfig(); err != nil {
+		t.Fatal(err)
+	}
+	if peers := arkenPeers(); len(peers) != 2 || peers[0] != arkenAddr("link.arken.io", builtinBootstrapID) ||
+		peers[1] != arkenAddr("relay.arken.io", builtinRelayID) {
+		t.Error("expected the Arken nodes over WebSocket, got", peers)
+	}
+
+	boot, _ := testIdentity(t)
+	relay1, _ := testIdentity(t)
+	relay2, _ := testIdentity(t)
+	aitConf.Global.IPFS.BootstrapPeers = []string{"/ip4/10.0.0.1/tcp/4001/p2p/" + boot}
+	aitConf.Global.IPFS.RelayPeers = []string{"/dns4/relay1.example.org/tcp/4001/p2p/" + relay1,
+		"/dns4/relay2.example.org/tcp/4001/p2p/" + relay2}
+	if err := applyPeersConfig(); err != nil {
+		t.Fatal(err)
+	}
+	if len(arkenPeers()) != 3 || arkenBootstrapID != boot || arkenRelayID != relay1 {
+		t.Error("expected the configured peers, got", arkenPeers(), arkenBootstrapID, arkenRelayID)
+	}
+
+	aitConf.Global.IPFS.RelayPeers = []string{"/dns4/relay.example.org/tcp/4001"}
+	if err := applyPeersConfig(); err == nil {
+		t.Error("expected an error for an address without a peer ID")
+	}
+	arkenBootstrapID, arkenRelayID = builtinBootstrapID, builtinRelayID
+}
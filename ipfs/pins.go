@@ -0,0 +1,62 @@
+package ipfs
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+
+	aitConf "github.com/arken/ait/config"
+	"github.com/arken/ait/utils"
+)
+
+// pinsFile is where the CIDs ait pinned outside of a submission are kept, with
+// why they were pinned, so gc keeps them.
+const pinsFile = "pins.json"
+
+// The reasons ait pins CIDs outside of a submission.
+const (
+	PinPublished = "publish"
+	PinUploaded  = "upload"
+	PinRefetched = "refetch"
+)
+
+// pinsLock serializes the updates of the pins file, staged files are
+// refetched by several workers at once.
+var pinsLock sync.Mutex
+
+// ReadPins returns the CIDs ait pinned outside of a submission, each with the
+// reasons it was pinned for.
+func ReadPins() (pins map[string][]string, err error) {
+	pins = map[string][]string{}
+	data, err := ioutil.ReadFile(filepath.Join(aitConf.Global.IPFS.Path, pinsFile))
+	if os.IsNotExist(err) {
+		return pins, nil
+	}
+	if err != nil {
+		return pins, err
+	}
+	err = json.Unmarshal(data, &pins)
+	return pins, err
+}
+
+// RecordPin records that ait pinned cid for reason.
+func RecordPin(cid, reason string) error {
+	pinsLock.Lock()
+	defer pinsLock.Unlock()
+	pins, err := ReadPins()
+	if err != nil {
+		return err
+	}
+	cid = NormalizeCID(cid)
+	if utils.IndexOf(pins[cid], reason) >= 0 {
+		return nil
+	}
+	pins[cid] = append(pins[cid], reason)
+	data, err := json.MarshalIndent(pins, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(filepath.Join(aitConf.Global.IPFS.Path, pinsFile), data, 0644)
+}
@@ -0,0 +1,53 @@
+package ipfs
+
+import (
+	"reflect"
+	"testing"
+
+	aitConf "github.com/arken/ait/config"
+)
+
+func TestRecordPin(t *testing.T) {
+	repoPath := aitConf.Global.IPFS.Path
+	aitConf.Global.IPFS.Path = t.TempDir()
+	defer func() { aitConf.Global.IPFS.Path = repoPath }()
+
+	v0 := "QmYwAPJzv5CZsnA625s3Xf2nemtYgPpHdWEz79ojWnPbdG"
+	for _, reason := range []string{PinPublished, PinUploaded, PinPublished} {
+		if err := RecordPin(v0, reason); err != nil {
+			t.Fatal(err)
+		}
+	}
+	pins, err := ReadPins()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string][]string{NormalizeCID(v0): {PinPublished, PinUploaded}}
+	if !reflect.DeepEqual(pins, expected) {
+		t.Errorf("ReadPins() = %v, expected %v", pins, expected)
+	}
+}
+
+func TestNormalizeCID(t *testing.T) {
+	v0 := "QmYwAPJzv5CZsnA625s3Xf2nemtYgPpHdWEz79ojWnPbdG"
+	v1 := NormalizeCID(v0)
+	if v1 == v0 || v1[0] != 'b' {
+		t.Errorf("NormalizeCID(%q) = %q, expected a CIDv1", v0, v1)
+	}
+	if again := NormalizeCID(v1); again != v1 {
+		t.Errorf("NormalizeCID(%q) = %q, expected a CIDv1 to be kept", v1, again)
+	}
+	if hash := NormalizeCID("not-a-cid"); hash != "not-a-cid" {
+		t.Errorf("NormalizeCID() = %q, expected what isn't a CID to be kept", hash)
+	}
+}
+
+func TestRecursivePins(t *testing.T) {
+	// gc lists the pins of ait's own repository, never those of a daemon.
+	if node != nil {
+		t.Skip("the embedded node is running")
+	}
+	if pins, err := RecursivePins(); err == nil {
+		t.Errorf("RecursivePins() = %v, expected an error without the embedded node", pins)
+	}
+}
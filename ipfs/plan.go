@@ -0,0 +1,36 @@
+package ipfs
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	aitConf "github.com/arken/ait/config"
+)
+
+// planFile is where the CIDs pinned by the last applied pin plan are kept, so
+// applying the next one unpins what it no longer lists.
+const planFile = "plan.json"
+
+// ReadPlanPins returns the CIDs pinned by the last applied pin plan.
+func ReadPlanPins() (cids []string, err error) {
+	data, err := ioutil.ReadFile(filepath.Join(aitConf.Global.IPFS.Path, planFile))
+	if os.IsNotExist(err) {
+		return cids, nil
+	}
+	if err != nil {
+		return cids, err
+	}
+	err = json.Unmarshal(data, &cids)
+	return cids, err
+}
+
+// WritePlanPins records the CIDs pinned by the pin plan being applied.
+func WritePlanPins(cids []string) error {
+	data, err := json.MarshalIndent(cids, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(filepath.Join(aitConf.Global.IPFS.Path, planFile), data, 0644)
+}
@@ -0,0 +1,120 @@
+package ipfs
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	aitConf "github.com/arken/ait/config"
+	"github.com/arken/ait/utils"
+
+	icorepath "github.com/ipfs/interface-go-ipfs-core/path"
+)
+
+// protectedFile is where the CIDs protected from garbage collection are kept.
+// It lives in the IPFS repository since every workspace using it shares the
+// garbage collector.
+const protectedFile = "protected.json"
+
+// ReadProtected returns the protected CIDs, each with the IDs of the
+// submissions holding it.
+func ReadProtected() (protected map[string][]string, err error) {
+	protected = map[string][]string{}
+	data, err := ioutil.ReadFile(filepath.Join(aitConf.Global.IPFS.Path, protectedFile))
+	if os.IsNotExist(err) {
+		return protected, nil
+	}
+	if err != nil {
+		return protected, err
+	}
+	err = json.Unmarshal(data, &protected)
+	return protected, err
+}
+
+// writeProtected persists the protected CIDs to the IPFS repository.
+func writeProtected(protected map[string][]string) error {
+	data, err := json.MarshalIndent(protected, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(filepath.Join(aitConf.Global.IPFS.Path, protectedFile), data, 0644)
+}
+
+// Protect records the submission owner as holding the CIDs, so they can't be
+// unpinned and are pinned again before garbage collection until Release is
+// called for it.
+func Protect(owner string, cids []string) error {
+	protected, err := ReadProtected()
+	if err != nil {
+		return err
+	}
+	for _, cid := range cids {
+		if utils.IndexOf(protected[cid], owner) < 0 {
+			protected[cid] = append(protected[cid], owner)
+		}
+	}
+	return writeProtected(protected)
+}
+
+// Release drops the hold of the submission owner. CIDs no longer held by any
+// submission stay pinned, but are no longer protected.
+func Release(owner string) error {
+	protected, err := ReadProtected()
+	if err != nil {
+		return err
+	}
+	for cid, owners := range protected {
+		kept := owners[:0]
+		for _, o := range owners {
+			if o != owner {
+				kept = append(kept, o)
+			}
+		}
+		if len(kept) == 0 {
+			delete(protected, cid)
+		} else {
+			protected[cid] = kept
+		}
+	}
+	return writeProtected(protected)
+}
+
+// Protected returns the IDs of the submissions holding cid, sorted, or nil if
+// it isn't protected.
+func Protected(cid string) ([]string, error) {
+	protected, err := ReadProtected()
+	if err != nil {
+		return nil, err
+	}
+	owners := protected[cid]
+	sort.Strings(owners)
+	return owners, nil
+}
+
+// repinProtected pins the protected CIDs that lost their pin, ie through an
+// external IPFS client, so the garbage collector keeps them. Each CID is given
+// a minute to be pinned from the local blocks or the network.
+func repinProtected(ctx context.Context) error {
+	protected, err := ReadProtected()
+	if err != nil {
+		return err
+	}
+	for cid := range protected {
+		path := icorepath.New("/ipfs/" + cid)
+		if _, pinned, err := ipfs.Pin().IsPinned(ctx, path); err == nil && pinned {
+			continue
+		}
+		c, cancel := context.WithTimeout(ctx, time.Minute)
+		err := ipfs.Pin().Add(c, path)
+		cancel()
+		if err != nil {
+			fmt.Printf("[Unable to pin protected %v again: %v]\n", cid, err)
+		}
+	}
+	return nil
+}
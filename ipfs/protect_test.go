@@ -0,0 +1,42 @@
+package ipfs
+
+import (
+	"reflect"
+	"testing"
+
+	aitConf "github.com/arken/ait/config"
+)
+
+func TestProtect(t *testing.T) {
+	repoPath := aitConf.Global.IPFS.Path
+	aitConf.Global.IPFS.Path = t.TempDir()
+	defer func() { aitConf.Global.IPFS.Path = repoPath }()
+
+	if err := Protect("20210601-120000", []string{"bafya", "bafyb"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := Protect("20210602-120000", []string{"bafyb"}); err != nil {
+		t.Fatal(err)
+	}
+	owners, err := Protected("bafyb")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(owners, []string{"20210601-120000", "20210602-120000"}) {
+		t.Errorf("unexpected owners of bafyb: %v", owners)
+	}
+
+	if err = Release("20210601-120000"); err != nil {
+		t.Fatal(err)
+	}
+	protected, err := ReadProtected()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(protected, map[string][]string{"bafyb": {"20210602-120000"}}) {
+		t.Errorf("unexpected protection after a release: %v", protected)
+	}
+	if owners, _ = Protected("bafya"); owners != nil {
+		t.Errorf("expected bafya to be released, held by %v", owners)
+	}
+}
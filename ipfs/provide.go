@@ -0,0 +1,48 @@
+package ipfs
+
+import (
+	"context"
+	"time"
+
+	"github.com/ipfs/go-cid"
+	"github.com/ipfs/interface-go-ipfs-core/options"
+	icorepath "github.com/ipfs/interface-go-ipfs-core/path"
+)
+
+// provideTimeout bounds how long a single DHT announcement may take.
+const provideTimeout = time.Minute
+
+// Provide announces to the DHT that this node hosts the given CID. The
+// content must already have been added to the local repository.
+func Provide(hash string) error {
+	contxt, cancl := context.WithTimeout(ctx, provideTimeout)
+	defer cancl()
+	return ipfs.Dht().Provide(contxt, icorepath.New("/ipfs/"+hash))
+}
+
+// Holds returns whether the node holds the root block of the given CID, ie
+// because its file was uploaded before, without fetching it from the network.
+// Only the CIDs the node holds can be provided.
+func Holds(hash string) bool {
+	c, err := cid.Decode(hash)
+	if err != nil {
+		return false
+	}
+	offline, err := ipfs.WithOptions(options.Api.Offline(true))
+	if err != nil {
+		return false
+	}
+	contxt, cancl := context.WithTimeout(ctx, provideTimeout)
+	defer cancl()
+	_, err = offline.Block().Stat(contxt, icorepath.IpfsPath(c))
+	return err == nil
+}
+
+// BootstrapperConnected returns whether the node is connected to the Arken
+// bootstrapper. The bootstrapper is one of the closest peers for every key on
+// the private Arken network, so a successful Provide while connected means it
+// has received the provider record.
+func BootstrapperConnected() bool {
+	connected, _ := current.connection(mustDecodeID(arkenBootstrapID))
+	return connected
+}
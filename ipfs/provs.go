@@ -5,6 +5,7 @@ import (
 	"time"
 
 	"github.com/ipfs/interface-go-ipfs-core/options"
+	"github.com/libp2p/go-libp2p-core/peer"
 
 	icorepath "github.com/ipfs/interface-go-ipfs-core/path"
 )
@@ -31,3 +32,26 @@ func FindProvs(hash string, maxPeers int) (replications int, err error) {
 	cancl()
 	return replications, nil
 }
+
+// ProvidedBy queries the IPFS network for whether the peer id is among the
+// providers of a given file, giving up after timeout.
+func ProvidedBy(hash string, id peer.ID, timeout time.Duration) (bool, error) {
+	path := icorepath.New("/ipfs/" + hash)
+	contxt, cancl := context.WithTimeout(ctx, timeout)
+	defer cancl()
+
+	output, err := ipfs.Dht().FindProviders(contxt, path, func(input *options.DhtFindProvidersSettings) error {
+		// Popular files have many providers, the peer may not be the first.
+		input.NumProviders = 100
+		return nil
+	})
+	if err != nil {
+		return false, err
+	}
+	for provider := range output {
+		if provider.ID == id {
+			return true, nil
+		}
+	}
+	return false, nil
+}
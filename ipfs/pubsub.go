@@ -0,0 +1,30 @@
+package ipfs
+
+import (
+	"context"
+)
+
+// PublishMessage sends data to the peers subscribed to topic.
+func PublishMessage(topic string, data []byte) error {
+	return ipfs.PubSub().Publish(ctx, topic, data)
+}
+
+// Subscribe calls fn with the sender and data of each message published to
+// topic until c is done.
+func Subscribe(c context.Context, topic string, fn func(from string, data []byte)) error {
+	sub, err := ipfs.PubSub().Subscribe(c, topic)
+	if err != nil {
+		return err
+	}
+	defer sub.Close()
+	for {
+		msg, err := sub.Next(c)
+		if err != nil {
+			if c.Err() != nil {
+				return nil
+			}
+			return err
+		}
+		fn(msg.From().Pretty(), msg.Data())
+	}
+}
@@ -1,7 +1,11 @@
 package ipfs
 
 import (
+	"context"
 	"errors"
+	"io"
+	"io/ioutil"
+	"time"
 
 	files "github.com/ipfs/go-ipfs-files"
 	icorepath "github.com/ipfs/interface-go-ipfs-core/path"
@@ -19,3 +23,45 @@ func Pull(cid string) (output files.Node, err error) {
 
 	return output, nil
 }
+
+// Cat fetches a single file from the IPFS network and returns its contents.
+func Cat(cid string) ([]byte, error) {
+	output, err := Pull(cid)
+	if err != nil {
+		return nil, err
+	}
+	defer output.Close()
+	file, ok := output.(files.File)
+	if !ok {
+		return nil, errors.New("CID " + cid + " is a directory, not a file")
+	}
+	return ioutil.ReadAll(file)
+}
+
+// Size returns the size of the file or directory with the given CID, giving
+// up if it can't be found within timeout.
+func Size(cid string, timeout time.Duration) (int64, error) {
+	c, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	output, err := ipfs.Unixfs().Get(c, icorepath.New("/ipfs/"+cid))
+	if err != nil {
+		return 0, err
+	}
+	defer output.Close()
+	return output.Size()
+}
+
+// Head returns at most the first n bytes of the file with the given CID, read
+// from the repository or fetched from the network.
+func Head(cid string, n int64) ([]byte, error) {
+	output, err := Pull(cid)
+	if err != nil {
+		return nil, err
+	}
+	defer output.Close()
+	file, ok := output.(files.File)
+	if !ok {
+		return nil, errors.New("CID " + cid + " is a directory, not a file")
+	}
+	return ioutil.ReadAll(io.LimitReader(file, n))
+}
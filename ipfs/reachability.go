@@ -0,0 +1,69 @@
+package ipfs
+
+import (
+	"time"
+
+	aitConf "github.com/arken/ait/config"
+
+	icore "github.com/ipfs/interface-go-ipfs-core"
+	"github.com/libp2p/go-libp2p-core/event"
+	"github.com/libp2p/go-libp2p-core/network"
+)
+
+// The addresses of the node are checked again after reachabilityBackoff,
+// doubling up to reachabilityMaxBackoff, while its reachability isn't known.
+const (
+	reachabilityBackoff    = 250 * time.Millisecond
+	reachabilityMaxBackoff = 4 * time.Second
+	// defaultReachabilityTimeout is used when IPFS.ReachabilityTimeout isn't
+	// a duration.
+	defaultReachabilityTimeout = 30 * time.Second
+)
+
+// reachabilityTimeout returns how long the node waits to find out whether
+// it's reachable before falling back to the relay.
+func reachabilityTimeout() time.Duration {
+	timeout, err := time.ParseDuration(aitConf.Global.IPFS.ReachabilityTimeout)
+	if err != nil || timeout < 0 {
+		return defaultReachabilityTimeout
+	}
+	return timeout
+}
+
+// waitReachability returns whether the node can be reached from outside its
+// NAT as soon as that's known: a public address shows it, whether it's
+// listened on or mapped through UPnP, and AutoNAT reports it once peers have
+// dialed the node back. The addresses are checked with exponential backoff
+// until one of them answers, or for at most maxWait, after which the node is
+// taken to be unreachable.
+func waitReachability(api icore.CoreAPI, maxWait time.Duration) (bool, error) {
+	sub, err := node.PeerHost.EventBus().Subscribe(new(event.EvtLocalReachabilityChanged))
+	if err != nil {
+		return false, err
+	}
+	defer sub.Close()
+	deadline := time.NewTimer(maxWait)
+	defer deadline.Stop()
+	backoff := reachabilityBackoff
+	for {
+		public, err := checkReachability(api)
+		if err != nil || public {
+			return public, err
+		}
+		select {
+		case e := <-sub.Out():
+			switch e.(event.EvtLocalReachabilityChanged).Reachability {
+			case network.ReachabilityPublic:
+				return true, nil
+			case network.ReachabilityPrivate:
+				return false, nil
+			}
+		case <-time.After(backoff):
+			if backoff *= 2; backoff > reachabilityMaxBackoff {
+				backoff = reachabilityMaxBackoff
+			}
+		case <-deadline.C:
+			return false, nil
+		}
+	}
+}
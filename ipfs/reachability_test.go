@@ -0,0 +1,25 @@
+package ipfs
+
+import (
+	"testing"
+	"time"
+
+	aitConf "github.com/arken/ait/config"
+)
+
+func TestReachabilityTimeout(t *testing.T) {
+	conf := aitConf.Global.IPFS
+	defer func() { aitConf.Global.IPFS = conf }()
+	for value, expected := range map[string]time.Duration{
+		"10s":  10 * time.Second,
+		"0s":   0,
+		"":     defaultReachabilityTimeout,
+		"soon": defaultReachabilityTimeout,
+		"-5s":  defaultReachabilityTimeout,
+	} {
+		aitConf.Global.IPFS.ReachabilityTimeout = value
+		if timeout := reachabilityTimeout(); timeout != expected {
+			t.Errorf("expected %v for %q, got %v", expected, value, timeout)
+		}
+	}
+}
@@ -0,0 +1,135 @@
+package ipfs
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	aitConf "github.com/arken/ait/config"
+	"github.com/arken/ait/utils"
+
+	"github.com/libp2p/go-libp2p-core/peer"
+)
+
+// relayStatsFile is where relay usage is persisted between ait runs.
+const relayStatsFile = "relay_stats.json"
+
+// RelayStats records how much traffic this node has pushed through the shared
+// Arken relay.
+type RelayStats struct {
+	TotalIn     int64
+	TotalOut    int64
+	SessionIn   int64
+	SessionOut  int64
+	LastRelayed time.Time
+	Throttled   bool
+}
+
+// relayed is true when the running node is only reachable through the relay.
+var relayed bool
+
+// IsRelayed returns whether the running node is routing through the relay.
+func IsRelayed() bool {
+	return relayed
+}
+
+// ReadRelayStats loads the persisted relay usage from the IPFS repository.
+// A zero value RelayStats is returned if nothing has been recorded yet.
+func ReadRelayStats() (stats RelayStats, err error) {
+	data, err := ioutil.ReadFile(filepath.Join(aitConf.Global.IPFS.Path, relayStatsFile))
+	if os.IsNotExist(err) {
+		return stats, nil
+	}
+	if err != nil {
+		return stats, err
+	}
+	err = json.Unmarshal(data, &stats)
+	return stats, err
+}
+
+// writeRelayStats persists the relay usage to the IPFS repository.
+func writeRelayStats(stats RelayStats) error {
+	data, err := json.MarshalIndent(stats, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(filepath.Join(aitConf.Global.IPFS.Path, relayStatsFile), data, 0644)
+}
+
+// monitorRelay periodically samples the bandwidth used with the relay peer,
+// adds it to the persisted totals and warns the user once the configured
+// threshold has been crossed. If throttling is enabled the relay connection
+// is dropped for the remainder of the session.
+func monitorRelay(ctx context.Context) {
+	limit, err := utils.ParseByteSize(aitConf.Global.IPFS.RelayWarn)
+	if err != nil {
+		fmt.Printf("[Ignoring RelayWarn setting: %v]\n", err)
+	}
+	stats, err := ReadRelayStats()
+	if err != nil {
+		fmt.Printf("[Unable to read relay usage: %v]\n", err)
+	}
+	stats.SessionIn, stats.SessionOut, stats.Throttled = 0, 0, false
+	baseIn, baseOut := stats.TotalIn, stats.TotalOut
+	warned := false
+
+	ticker := time.NewTicker(10 * time.Second)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		if node == nil || node.Reporter == nil {
+			continue
+		}
+		bw := node.Reporter.GetBandwidthForPeer(mustDecodeID(arkenRelayID))
+		stats.SessionIn, stats.SessionOut = bw.TotalIn, bw.TotalOut
+		stats.TotalIn, stats.TotalOut = baseIn+bw.TotalIn, baseOut+bw.TotalOut
+		stats.LastRelayed = time.Now()
+
+		if limit > 0 && !warned && stats.TotalIn+stats.TotalOut > limit {
+			warned = true
+			fmt.Printf("\n[Warning: %s has been routed through the shared Arken relay, "+
+				"exceeding the %s threshold.]\n",
+				utils.FormatByteSize(stats.TotalIn+stats.TotalOut), aitConf.Global.IPFS.RelayWarn)
+			if aitConf.Global.IPFS.RelayThrottle {
+				throttleRelay()
+				stats.Throttled = true
+			} else {
+				fmt.Printf("[Consider opening port 4001 so your node can be reached directly.]\n")
+			}
+		}
+		if err := writeRelayStats(stats); err != nil {
+			fmt.Printf("[Unable to record relay usage: %v]\n", err)
+		}
+	}
+}
+
+// throttleRelay stops the peering service from reconnecting to the relay and
+// closes the current relay connection.
+func throttleRelay() {
+	for _, addr := range relayPeers() {
+		id, _ := addrID(addr)
+		if ps != nil {
+			ps.RemovePeer(id)
+		}
+		_ = node.PeerHost.Network().ClosePeer(id)
+	}
+	fmt.Printf("[Relay throttling enabled: disconnected from the Arken relay for this session.]\n")
+}
+
+// mustDecodeID decodes the identity of an Arken node, panicking if it is
+// malformed.
+func mustDecodeID(id string) peer.ID {
+	decoded, err := peer.Decode(id)
+	if err != nil {
+		panic(err)
+	}
+	return decoded
+}
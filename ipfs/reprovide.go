@@ -0,0 +1,150 @@
+package ipfs
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	aitConf "github.com/arken/ait/config"
+
+	config "github.com/ipfs/go-ipfs-config"
+	"github.com/ipfs/interface-go-ipfs-core/options"
+)
+
+// minReprovideGap is the shortest time between two batches of reprovides, so
+// small pinsets are announced a few CIDs at a time rather than in a burst.
+const minReprovideGap = 30 * time.Second
+
+// reprovideWorkers is the number of CIDs of a batch announced at once.
+const reprovideWorkers = 8
+
+// applyReprovideConfig turns IPFS's own reprovider off when ait schedules the
+// reprovides itself, ScheduleReprovides taking over in long running nodes.
+func applyReprovideConfig(cfg *config.Config) {
+	if aitConf.Global.IPFS.ReprovideInterval != "" {
+		cfg.Reprovider.Interval = "0"
+		return
+	}
+	cfg.Reprovider.Interval = "1h"
+}
+
+// ScheduleReprovides announces every pinned root again each interval until c
+// is canceled. The CIDs returned by priority, ie those of recent or at-risk
+// submissions, go first; the rest are spread in batches evenly over the
+// interval so a large pinset doesn't flood the DHT, and the batches grow with
+// the pinset so a round still fits in the interval. It is meant for long
+// running nodes.
+func ScheduleReprovides(c context.Context, interval time.Duration, priority func() []string) {
+	for {
+		start := time.Now()
+		hashes, err := pinnedRoots(c)
+		if err != nil {
+			fmt.Printf("\n[Unable to list the pinned roots to reprovide: %v]\n", err)
+		}
+		order := reprovideOrder(hashes, priority())
+		size, gap := reprovideBatches(len(order), interval)
+		for i := 0; i < len(order); i += size {
+			end := i + size
+			if end > len(order) {
+				end = len(order)
+			}
+			provideBatch(order[i:end])
+			// Each batch has its slot, a slow batch eats into the next
+			// gaps rather than pushing the round past the interval.
+			next := start.Add(time.Duration(i/size+1) * gap)
+			select {
+			case <-c.Done():
+				return
+			case <-time.After(time.Until(next)):
+			}
+		}
+		if took := time.Since(start); took > interval {
+			fmt.Printf("\n[Reproviding %d pinned roots took %v, longer than ReprovideInterval %v]\n",
+				len(order), took.Round(time.Second), interval)
+		}
+		select {
+		case <-c.Done():
+			return
+		case <-time.After(time.Until(start.Add(interval))):
+		}
+	}
+}
+
+// pinnedRoots returns the CIDs pinned recursively, as they were pinned.
+func pinnedRoots(c context.Context) ([]string, error) {
+	pins, err := ipfs.Pin().Ls(c, options.Pin.Ls.Recursive())
+	if err != nil {
+		return nil, err
+	}
+	var roots []string
+	for pin := range pins {
+		if err := pin.Err(); err != nil {
+			return roots, err
+		}
+		roots = append(roots, pin.Path().Cid().String())
+	}
+	return roots, nil
+}
+
+// reprovideOrder returns the roots with those in priority first, in the order
+// given, and the rest after. Priority CIDs that aren't pinned are skipped.
+func reprovideOrder(roots, priority []string) []string {
+	pinned := make(map[string]bool, len(roots))
+	for _, root := range roots {
+		pinned[root] = true
+	}
+	order := make([]string, 0, len(roots))
+	first := make(map[string]bool, len(priority))
+	for _, hash := range priority {
+		if pinned[hash] && !first[hash] {
+			first[hash] = true
+			order = append(order, hash)
+		}
+	}
+	for _, root := range roots {
+		if !first[root] {
+			order = append(order, root)
+		}
+	}
+	return order
+}
+
+// reprovideBatches splits n reprovides over interval into batches of size
+// announced every gap, at most one batch every minReprovideGap.
+func reprovideBatches(n int, interval time.Duration) (size int, gap time.Duration) {
+	batches := int(interval / minReprovideGap)
+	if batches < 1 {
+		batches = 1
+	}
+	if n < batches {
+		batches = n
+	}
+	if batches == 0 {
+		return 1, interval
+	}
+	size = (n + batches - 1) / batches
+	batches = (n + size - 1) / size
+	return size, interval / time.Duration(batches)
+}
+
+// provideBatch announces the CIDs, reprovideWorkers at a time. Failures are
+// retried by the next round.
+func provideBatch(hashes []string) {
+	jobs := make(chan string)
+	wg := sync.WaitGroup{}
+	for w := 0; w < reprovideWorkers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for hash := range jobs {
+				_ = Provide(hash)
+			}
+		}()
+	}
+	for _, hash := range hashes {
+		jobs <- hash
+	}
+	close(jobs)
+	wg.Wait()
+}
@@ -0,0 +1,44 @@
+package ipfs
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestReprovideBatches(t *testing.T) {
+	cases := []struct {
+		n        int
+		interval time.Duration
+		size     int
+		gap      time.Duration
+	}{
+		// Fewer CIDs than slots, one at a time spread over the interval.
+		{4, time.Hour, 1, 15 * time.Minute},
+		// One batch per minReprovideGap, growing with the pinset.
+		{1200, time.Hour, 10, 30 * time.Second},
+		{1201, time.Hour, 11, time.Hour / 110},
+		// Intervals shorter than a gap announce everything at once.
+		{50, 10 * time.Second, 50, 10 * time.Second},
+		{0, time.Hour, 1, time.Hour},
+	}
+	for _, c := range cases {
+		size, gap := reprovideBatches(c.n, c.interval)
+		if size != c.size || gap != c.gap {
+			t.Errorf("reprovideBatches(%d, %v) = %d, %v, expected %d, %v",
+				c.n, c.interval, size, gap, c.size, c.gap)
+		}
+		if c.n > 0 && time.Duration((c.n+size-1)/size)*gap > c.interval {
+			t.Errorf("reprovideBatches(%d, %v) doesn't fit in the interval", c.n, c.interval)
+		}
+	}
+}
+
+func TestReprovideOrder(t *testing.T) {
+	roots := []string{"a", "b", "c", "d"}
+	got := reprovideOrder(roots, []string{"c", "x", "a", "c"})
+	expected := []string{"c", "a", "b", "d"}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("reprovideOrder = %v, expected %v", got, expected)
+	}
+}
@@ -0,0 +1,50 @@
+package ipfs
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	aitConf "github.com/arken/ait/config"
+)
+
+// scrubStatsFile is where the result of the last scrub is persisted.
+const scrubStatsFile = "scrub_stats.json"
+
+// ScrubStats records the last periodic integrity check of the repository.
+type ScrubStats struct {
+	Last          time.Time
+	BlocksChecked int
+	Problems      int
+	Unrepaired    int
+}
+
+// ReadScrubStats loads the result of the last scrub from the IPFS repository.
+// A zero value ScrubStats is returned if the repository was never scrubbed.
+func ReadScrubStats() (stats ScrubStats, err error) {
+	data, err := ioutil.ReadFile(filepath.Join(aitConf.Global.IPFS.Path, scrubStatsFile))
+	if os.IsNotExist(err) {
+		return stats, nil
+	}
+	if err != nil {
+		return stats, err
+	}
+	err = json.Unmarshal(data, &stats)
+	return stats, err
+}
+
+// WriteScrubStats persists the result of a scrub to the IPFS repository.
+func WriteScrubStats(stats ScrubStats) error {
+	data, err := json.MarshalIndent(stats, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(filepath.Join(aitConf.Global.IPFS.Path, scrubStatsFile), data, 0644)
+}
+
+// Problems returns the number of problems found in the report.
+func (report *FsckReport) Problems() int {
+	return len(report.CorruptBlocks) + len(report.MissingBlocks) + len(report.BrokenRefs)
+}
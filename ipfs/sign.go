@@ -0,0 +1,52 @@
+package ipfs
+
+import (
+	"errors"
+
+	crypto "github.com/libp2p/go-libp2p-core/crypto"
+	"github.com/libp2p/go-libp2p-core/peer"
+)
+
+// Sign signs data with the identity key of the IPFS repository, without
+// starting a node. It returns the signature, the marshaled public key, and
+// the peer ID identifying the signer.
+func Sign(data []byte) (sig, pubKey []byte, id string, err error) {
+	cfg, err := identityConfig()
+	if err != nil {
+		return nil, nil, "", err
+	}
+	priv, err := cfg.Identity.DecodePrivateKey("")
+	if err != nil {
+		return nil, nil, "", err
+	}
+	sig, err = priv.Sign(data)
+	if err != nil {
+		return nil, nil, "", err
+	}
+	pubKey, err = crypto.MarshalPublicKey(priv.GetPublic())
+	if err != nil {
+		return nil, nil, "", err
+	}
+	return sig, pubKey, cfg.Identity.PeerID, nil
+}
+
+// Verify checks a signature produced by Sign and returns the peer ID of the
+// signer.
+func Verify(data, sig, pubKey []byte) (string, error) {
+	pub, err := crypto.UnmarshalPublicKey(pubKey)
+	if err != nil {
+		return "", err
+	}
+	ok, err := pub.Verify(data, sig)
+	if err != nil {
+		return "", err
+	}
+	if !ok {
+		return "", errors.New("signature does not match")
+	}
+	id, err := peer.IDFromPublicKey(pub)
+	if err != nil {
+		return "", err
+	}
+	return id.Pretty(), nil
+}
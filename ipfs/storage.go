@@ -0,0 +1,122 @@
+package ipfs
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	aitConf "github.com/arken/ait/config"
+	"github.com/arken/ait/utils"
+
+	humanize "github.com/dustin/go-humanize"
+	"github.com/ipfs/go-cid"
+	config "github.com/ipfs/go-ipfs-config"
+	"github.com/ipfs/interface-go-ipfs-core/options"
+)
+
+// applyStorageConfig copies AIT's storage settings into the IPFS config.
+func applyStorageConfig(cfg *config.Config) error {
+	max, err := utils.ParseByteSize(aitConf.Global.IPFS.StorageMax)
+	if err != nil {
+		return fmt.Errorf("invalid IPFS StorageMax %q in the ait config", aitConf.Global.IPFS.StorageMax)
+	}
+	watermark := aitConf.Global.IPFS.StorageGCWatermark
+	if watermark <= 0 || watermark > 100 {
+		return fmt.Errorf("IPFS StorageGCWatermark must be a percentage between 1 and 100, got %d", watermark)
+	}
+	// IPFS reads units as powers of 1000, the size is given in bytes so it's
+	// the same as ait's other sizes.
+	cfg.Datastore.StorageMax = strconv.FormatInt(max, 10)
+	cfg.Datastore.StorageGCWatermark = watermark
+	cfg.Datastore.GCPeriod = aitConf.Global.IPFS.GCPeriod
+	return nil
+}
+
+// StorageUsage returns the number of bytes used by the repository and the
+// configured maximum.
+func StorageUsage() (used, max uint64, err error) {
+	used, err = current.storageUsed()
+	if err != nil {
+		return used, max, err
+	}
+	size, err := utils.ParseByteSize(aitConf.Global.IPFS.StorageMax)
+	return used, uint64(size), err
+}
+
+// checkStorage warns the user once the repository grows past the GC
+// watermark of its configured maximum size.
+func checkStorage() {
+	used, max, err := StorageUsage()
+	if err != nil || max == 0 {
+		return
+	}
+	watermark := max / 100 * uint64(aitConf.Global.IPFS.StorageGCWatermark)
+	quota, _ := utils.ParseByteSize(aitConf.Global.IPFS.StorageWarn)
+	if used >= max {
+		fmt.Printf("[Warning: the IPFS repository is using %v, exceeding its %v limit.]\n",
+			humanize.Bytes(used), aitConf.Global.IPFS.StorageMax)
+	} else if quota > 0 && used >= uint64(quota) {
+		fmt.Printf("[Warning: the IPFS repository is using %v, past its %v quota. \"ait gc\" removes "+
+			"the files no staged file or submission references.]\n", humanize.Bytes(used),
+			aitConf.Global.IPFS.StorageWarn)
+	} else if used >= watermark {
+		fmt.Printf("[Warning: the IPFS repository is using %v of its %v limit.]\n",
+			humanize.Bytes(used), aitConf.Global.IPFS.StorageMax)
+	}
+}
+
+// EnforceStorage garbage collects unpinned blocks whenever the repository
+// passes its GC watermark, checking every GCPeriod until ctx is canceled.
+// Protected CIDs are pinned again first. It is meant for long running nodes,
+// an external daemon collects its garbage itself.
+func EnforceStorage(ctx context.Context) error {
+	if err := repinProtected(ctx); err != nil {
+		return err
+	}
+	return current.enforceStorage(ctx)
+}
+
+// CollectGarbage removes every unpinned block from the repository now.
+// Protected CIDs are pinned again first. An external daemon's repository holds
+// more than ait's data, so it's left to the daemon.
+func CollectGarbage() error {
+	if err := requireEmbedded("Garbage collection"); err != nil {
+		return err
+	}
+	if err := repinProtected(ctx); err != nil {
+		return err
+	}
+	return current.collectGarbage()
+}
+
+// RecursivePins returns the CIDs pinned with everything they link to, as
+// version 1 CIDs. The pins of an external daemon aren't all ait's, so they
+// aren't listed.
+func RecursivePins() ([]string, error) {
+	if err := requireEmbedded("Garbage collection"); err != nil {
+		return nil, err
+	}
+	pins, err := ipfs.Pin().Ls(ctx, options.Pin.Ls.Recursive())
+	if err != nil {
+		return nil, err
+	}
+	var cids []string
+	for pin := range pins {
+		if err := pin.Err(); err != nil {
+			return cids, err
+		}
+		cids = append(cids, NormalizeCID(pin.Path().Cid().String()))
+	}
+	return cids, nil
+}
+
+// NormalizeCID returns hash as a version 1 CID, so CIDs of the same content
+// compare equal whichever version they're written in. Hashes that aren't CIDs
+// are returned as is.
+func NormalizeCID(hash string) string {
+	c, err := cid.Decode(hash)
+	if err != nil {
+		return hash
+	}
+	return cid.NewCidV1(c.Type(), c.Hash()).String()
+}
@@ -0,0 +1,133 @@
+package ipfs
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	aitConf "github.com/arken/ait/config"
+)
+
+// transferStatsFile is where per dataset transfer totals are persisted.
+const transferStatsFile = "transfer_stats.json"
+
+// transferDay is the layout of the date each daily total is filed under.
+const transferDay = "2006-01-02"
+
+// Transfer is the amount of data moved for a single dataset, in total and
+// per day.
+type Transfer struct {
+	Uploaded   int64
+	Downloaded int64
+	LastActive time.Time
+	Days       map[string]*TransferDay
+}
+
+// TransferDay is the amount of data moved for a dataset on one day.
+type TransferDay struct {
+	Uploaded   int64
+	Downloaded int64
+}
+
+// transferLock serializes updates made by concurrent goroutines.
+var transferLock sync.Mutex
+
+// ReadTransfers loads the persisted transfer totals keyed by dataset. An
+// empty map is returned if nothing has been recorded yet.
+func ReadTransfers() (map[string]*Transfer, error) {
+	stats := make(map[string]*Transfer)
+	data, err := ioutil.ReadFile(filepath.Join(aitConf.Global.IPFS.Path, transferStatsFile))
+	if os.IsNotExist(err) {
+		return stats, nil
+	}
+	if err != nil {
+		return stats, err
+	}
+	err = json.Unmarshal(data, &stats)
+	return stats, err
+}
+
+// RecordTransfer adds the given amounts of uploaded and downloaded bytes to
+// the dataset's totals and today's totals.
+func RecordTransfer(dataset string, uploaded, downloaded int64) error {
+	if uploaded == 0 && downloaded == 0 {
+		return nil
+	}
+	transferLock.Lock()
+	defer transferLock.Unlock()
+	stats, err := ReadTransfers()
+	if err != nil {
+		return err
+	}
+	t, ok := stats[dataset]
+	if !ok {
+		t = &Transfer{}
+		stats[dataset] = t
+	}
+	if t.Days == nil {
+		t.Days = make(map[string]*TransferDay)
+	}
+	now := time.Now()
+	day, ok := t.Days[now.Format(transferDay)]
+	if !ok {
+		day = &TransferDay{}
+		t.Days[now.Format(transferDay)] = day
+	}
+	t.Uploaded += uploaded
+	t.Downloaded += downloaded
+	day.Uploaded += uploaded
+	day.Downloaded += downloaded
+	t.LastActive = now
+
+	data, err := json.MarshalIndent(stats, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(filepath.Join(aitConf.Global.IPFS.Path, transferStatsFile), data, 0644)
+}
+
+// Since returns the amounts moved for the dataset on the days after t.
+func (t *Transfer) Since(since time.Time) (uploaded, downloaded int64) {
+	for date, day := range t.Days {
+		when, err := time.ParseInLocation(transferDay, date, time.Local)
+		if err != nil || when.Before(since) {
+			continue
+		}
+		uploaded += day.Uploaded
+		downloaded += day.Downloaded
+	}
+	return uploaded, downloaded
+}
+
+// MonitorUploads periodically samples the data bitswap has sent to other
+// peers and attributes it to the dataset being seeded. Bitswap doesn't report
+// what was sent per block, so the node should only seed one dataset while it
+// is monitored.
+func MonitorUploads(ctx context.Context, dataset string) {
+	sent, err := current.dataSent()
+	if err != nil {
+		return
+	}
+	ticker := time.NewTicker(10 * time.Second)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		total, err := current.dataSent()
+		if err != nil {
+			continue
+		}
+		if err := RecordTransfer(dataset, int64(total-sent), 0); err != nil {
+			fmt.Printf("\n[Unable to record transfer usage: %v]\n", err)
+		}
+		sent = total
+	}
+}
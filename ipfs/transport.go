@@ -0,0 +1,192 @@
+package ipfs
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/url"
+	"time"
+
+	aitConf "github.com/arken/ait/config"
+
+	config "github.com/ipfs/go-ipfs-config"
+	ipfsp2p "github.com/ipfs/go-ipfs/core/node/libp2p"
+	p2p "github.com/libp2p/go-libp2p"
+	"github.com/libp2p/go-libp2p-core/host"
+	"github.com/libp2p/go-libp2p-core/peer"
+	"github.com/libp2p/go-libp2p-core/peerstore"
+	"github.com/libp2p/go-libp2p-core/transport"
+	tptu "github.com/libp2p/go-libp2p-transport-upgrader"
+	tcp "github.com/libp2p/go-tcp-transport"
+	ma "github.com/multiformats/go-multiaddr"
+	manet "github.com/multiformats/go-multiaddr/net"
+	"golang.org/x/net/proxy"
+)
+
+const (
+	// TransportDefault connects to peers over TCP and QUIC.
+	TransportDefault = "default"
+	// TransportWebSocket reaches the Arken nodes over WebSockets on port 443,
+	// for firewalls that only let web traffic out.
+	TransportWebSocket = "websocket"
+	// TransportSOCKS5 dials every peer over TCP through the configured
+	// SOCKS5 proxy, ie Tor.
+	TransportSOCKS5 = "socks5"
+)
+
+// arkenAddr returns the address of the Arken node at host with the given
+// identity over the configured transport.
+func arkenAddr(host, id string) string {
+	if aitConf.Global.Network.Transport == TransportWebSocket {
+		return fmt.Sprintf("/dns4/%v/tcp/443/ws/p2p/%v", host, id)
+	}
+	return fmt.Sprintf("/dns4/%v/tcp/4001/p2p/%v", host, id)
+}
+
+// applyTransportConfig enables the IPFS transports the configured transport
+// needs. Through a SOCKS5 proxy, or with bandwidth limits, only the TCP
+// transport added by hostOption is used, so no connection bypasses the proxy
+// or the limits.
+func applyTransportConfig(cfg *config.Config) error {
+	network := &cfg.Swarm.Transports.Network
+	limits, err := bandwidthLimits()
+	if err != nil {
+		return err
+	}
+	switch aitConf.Global.Network.Transport {
+	case "", TransportDefault, TransportWebSocket:
+		network.TCP, network.Websocket, network.QUIC = config.Default, config.Default, config.Default
+		if limits != nil {
+			network.TCP, network.Websocket, network.QUIC = config.False, config.False, config.False
+		}
+	case TransportSOCKS5:
+		if _, err := socksDialer(); err != nil {
+			return err
+		}
+		network.TCP, network.Websocket, network.QUIC = config.False, config.False, config.False
+	default:
+		return fmt.Errorf("unknown Network Transport %q in the ait config, expected %q, %q or %q",
+			aitConf.Global.Network.Transport, TransportDefault, TransportWebSocket, TransportSOCKS5)
+	}
+	return nil
+}
+
+// hostOption builds the libp2p host, adding the TCP transport dialing through
+// the SOCKS5 proxy or holding connections to the bandwidth limits when either
+// is configured.
+func hostOption() ipfsp2p.HostOption {
+	limits, _ := bandwidthLimits()
+	socks := aitConf.Global.Network.Transport == TransportSOCKS5
+	if !socks && limits == nil {
+		return ipfsp2p.DefaultHostOption
+	}
+	return func(ctx context.Context, id peer.ID, ps peerstore.Peerstore, options ...p2p.Option) (host.Host, error) {
+		var dialer proxy.ContextDialer
+		if socks {
+			var err error
+			if dialer, err = socksDialer(); err != nil {
+				return nil, err
+			}
+		}
+		options = append(options, p2p.Transport(func(upgrader *tptu.Upgrader) *tcpTransport {
+			return &tcpTransport{TcpTransport: tcp.NewTCPTransport(upgrader), dialer: dialer, limits: limits}
+		}))
+		return ipfsp2p.DefaultHostOption(ctx, id, ps, options...)
+	}
+}
+
+// socksDialer returns a dialer connecting through the configured proxy.
+func socksDialer() (proxy.ContextDialer, error) {
+	u, err := url.Parse(aitConf.Global.Network.Proxy)
+	if err != nil || u.Scheme != "socks5" || u.Host == "" {
+		return nil, fmt.Errorf("the socks5 Network Transport needs a Proxy such as \"socks5://127.0.0.1:9050\" in the ait config, got %q",
+			aitConf.Global.Network.Proxy)
+	}
+	var auth *proxy.Auth
+	if u.User != nil {
+		auth = &proxy.Auth{User: u.User.Username()}
+		auth.Password, _ = u.User.Password()
+	}
+	dialer, err := proxy.SOCKS5("tcp", u.Host, auth, proxy.Direct)
+	if err != nil {
+		return nil, err
+	}
+	return dialer.(proxy.ContextDialer), nil
+}
+
+// dialTCP connects to addr, through the SOCKS5 proxy when it's configured.
+func dialTCP(addr string, timeout time.Duration) (net.Conn, error) {
+	if aitConf.Global.Network.Transport != TransportSOCKS5 {
+		return net.DialTimeout("tcp", addr, timeout)
+	}
+	dialer, err := socksDialer()
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	return dialer.DialContext(ctx, "tcp", addr)
+}
+
+// tcpTransport is the TCP transport, except that it dials through a SOCKS5
+// proxy when dialer is set, and that its connections share the bandwidth
+// limits when they're set.
+type tcpTransport struct {
+	*tcp.TcpTransport
+	dialer proxy.ContextDialer
+	limits *bandwidth
+}
+
+var _ transport.Transport = &tcpTransport{}
+
+// Dial connects to the peer at raddr, through the proxy if there's one.
+func (t *tcpTransport) Dial(ctx context.Context, raddr ma.Multiaddr, p peer.ID) (transport.CapableConn, error) {
+	var conn manet.Conn
+	if t.dialer != nil {
+		_, addr, err := manet.DialArgs(raddr)
+		if err != nil {
+			return nil, err
+		}
+		proxied, err := t.dialer.DialContext(ctx, "tcp", addr)
+		if err != nil {
+			return nil, err
+		}
+		conn = &proxiedConn{Conn: proxied, local: ma.StringCast("/ip4/0.0.0.0/tcp/0"), remote: raddr}
+	} else {
+		var err error
+		if conn, err = (&manet.Dialer{}).DialContext(ctx, raddr); err != nil {
+			return nil, err
+		}
+	}
+	return t.Upgrader.UpgradeOutbound(ctx, t, t.limits.limit(conn), p)
+}
+
+// Listen accepts connections on laddr, holding them to the bandwidth limits.
+// Connections through a proxy are only dialed, listening is left to TCP.
+func (t *tcpTransport) Listen(laddr ma.Multiaddr) (transport.Listener, error) {
+	if t.limits == nil {
+		return t.TcpTransport.Listen(laddr)
+	}
+	list, err := manet.Listen(laddr)
+	if err != nil {
+		return nil, err
+	}
+	return t.Upgrader.UpgradeListener(t, &limitedListener{list, t.limits}), nil
+}
+
+func (t *tcpTransport) String() string {
+	if t.dialer != nil {
+		return "SOCKS5"
+	}
+	return "TCP"
+}
+
+// proxiedConn is a connection made through a proxy, addressed by the peer it
+// reaches rather than the proxy.
+type proxiedConn struct {
+	net.Conn
+	local, remote ma.Multiaddr
+}
+
+func (c *proxiedConn) LocalMultiaddr() ma.Multiaddr  { return c.local }
+func (c *proxiedConn) RemoteMultiaddr() ma.Multiaddr { return c.remote }
@@ -0,0 +1,43 @@
+package ipfs
+
+import (
+	"testing"
+
+	aitConf "github.com/arken/ait/config"
+
+	config "github.com/ipfs/go-ipfs-config"
+)
+
+func TestTransportConfig(t *testing.T) {
+	conf := aitConf.Global.Network
+	defer func() { aitConf.Global.Network = conf }()
+
+	aitConf.Global.Network.Transport = TransportWebSocket
+	if got := arkenAddr("relay.arken.io", arkenRelayID); got != "/dns4/relay.arken.io/tcp/443/ws/p2p/"+arkenRelayID {
+		t.Errorf("unexpected WebSocket address %v", got)
+	}
+	aitConf.Global.Network.Transport = TransportDefault
+	if got := arkenAddr("relay.arken.io", arkenRelayID); got != "/dns4/relay.arken.io/tcp/4001/p2p/"+arkenRelayID {
+		t.Errorf("unexpected TCP address %v", got)
+	}
+
+	cfg := &config.Config{}
+	aitConf.Global.Network.Transport = TransportSOCKS5
+	aitConf.Global.Network.Proxy = ""
+	if err := applyTransportConfig(cfg); err == nil {
+		t.Error("expected the socks5 transport to need a proxy")
+	}
+	aitConf.Global.Network.Proxy = "socks5://127.0.0.1:9050"
+	if err := applyTransportConfig(cfg); err != nil {
+		t.Fatal(err)
+	}
+	network := cfg.Swarm.Transports.Network
+	if network.TCP.WithDefault(true) || network.QUIC.WithDefault(true) || network.Websocket.WithDefault(true) {
+		t.Error("expected every unproxied transport to be disabled")
+	}
+
+	aitConf.Global.Network.Transport = "carrier-pigeon"
+	if err := applyTransportConfig(cfg); err == nil {
+		t.Error("expected an unknown transport to be rejected")
+	}
+}
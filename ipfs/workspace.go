@@ -0,0 +1,256 @@
+package ipfs
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	aitConf "github.com/arken/ait/config"
+
+	filestore "github.com/ipfs/go-filestore"
+)
+
+// Workspace is a directory whose files the filestore references through a
+// link kept next to the IPFS repository, so the references keep resolving no
+// matter which workspace was used last.
+type Workspace struct {
+	// Link is the name of the workspace's link in the workspaces directory.
+	Link string `json:"link"`
+	// Path is the absolute path the link points at.
+	Path   string    `json:"path"`
+	Linked time.Time `json:"linked"`
+}
+
+// WorkspaceReport is the state of a workspace's filestore references.
+type WorkspaceReport struct {
+	Workspace
+	// Refs is the number of files referenced through the workspace, and
+	// MissingFiles those of them that no longer exist.
+	Refs         int
+	MissingFiles int
+	// Missing is set when the workspace directory itself is gone, ie
+	// renamed or on storage that isn't mounted.
+	Missing bool
+	// Relinked is set when the link had to be repaired.
+	Relinked bool
+}
+
+// filestoreRoot is the directory filestore references are relative to.
+func filestoreRoot() string {
+	return filepath.Dir(aitConf.Global.IPFS.Path)
+}
+
+// workspacesDir holds a link to each workspace.
+func workspacesDir() string {
+	return filepath.Join(filestoreRoot(), "workspaces")
+}
+
+// LinkPath returns the path of the link to the workspace, which the files of
+// the workspace are added through.
+func (w Workspace) LinkPath() string {
+	return filepath.Join(workspacesDir(), w.Link)
+}
+
+// workspacesFile records the absolute path behind each workspace link.
+func workspacesFile() string {
+	return filepath.Join(filestoreRoot(), "workspaces.json")
+}
+
+// workspaceLink returns the name of the link to the workspace at path.
+func workspaceLink(path string) string {
+	sum := sha256.Sum256([]byte(path))
+	return hex.EncodeToString(sum[:8])
+}
+
+// ReadWorkspaces returns the recorded workspaces by link name.
+func ReadWorkspaces() (map[string]*Workspace, error) {
+	workspaces := make(map[string]*Workspace)
+	data, err := ioutil.ReadFile(workspacesFile())
+	if os.IsNotExist(err) {
+		return workspaces, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return workspaces, json.Unmarshal(data, &workspaces)
+}
+
+// WriteWorkspaces records the workspaces.
+func WriteWorkspaces(workspaces map[string]*Workspace) error {
+	data, err := json.MarshalIndent(workspaces, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(workspacesFile(), data, 0644)
+}
+
+// LinkWorkdir points the current workspace's link next to the IPFS repository
+// at the current working directory and returns the link's path. Files added
+// through the link are referenced by the filestore without copying them into
+// the repository. The link is kept so the references stay valid.
+func LinkWorkdir() (string, error) {
+	wd, err := os.Getwd()
+	if err != nil {
+		return "", err
+	}
+	return LinkWorkspace(wd)
+}
+
+// linkLock serializes the changes to the recorded workspaces within this
+// process, ie of several workspaces a program embedding ait links at once.
+var linkLock sync.Mutex
+
+// LinkWorkspace is LinkWorkdir for the workspace at dir.
+func LinkWorkspace(dir string) (string, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return "", err
+	}
+	linkLock.Lock()
+	defer linkLock.Unlock()
+	workspaces, err := ReadWorkspaces()
+	if err != nil {
+		return "", err
+	}
+	// A relocated workspace keeps the link its files are referenced by.
+	name := workspaceLink(dir)
+	for _, w := range workspaces {
+		if w.Path == dir {
+			name = w.Link
+		}
+	}
+	link := filepath.Join(workspacesDir(), name)
+	if err = setLink(link, dir); err != nil {
+		return link, err
+	}
+	if w, ok := workspaces[name]; !ok || w.Path != dir {
+		workspaces[name] = &Workspace{Link: name, Path: dir, Linked: time.Now()}
+		err = WriteWorkspaces(workspaces)
+	}
+	return link, err
+}
+
+// RelocateWorkspaces points the links of the workspaces at or under oldPath to
+// the same place under newPath, so data that moved, ie to another mount
+// point, is referenced again without adding it anew. Every new location must
+// exist before anything is changed. It returns the workspaces that were moved,
+// with their new paths.
+func RelocateWorkspaces(oldPath, newPath string) ([]Workspace, error) {
+	workspaces, err := ReadWorkspaces()
+	if err != nil {
+		return nil, err
+	}
+	targets := make(map[string]string)
+	for name, w := range workspaces {
+		rel, err := filepath.Rel(oldPath, w.Path)
+		if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			continue
+		}
+		target := filepath.Join(newPath, rel)
+		if info, err := os.Stat(target); err != nil || !info.IsDir() {
+			return nil, fmt.Errorf("%v would move to %v, which isn't a directory", w.Path, target)
+		}
+		targets[name] = target
+	}
+	var moved []Workspace
+	for name, target := range targets {
+		if err := setLink(filepath.Join(workspacesDir(), name), target); err != nil {
+			return moved, err
+		}
+		w := workspaces[name]
+		w.Path, w.Linked = target, time.Now()
+		moved = append(moved, *w)
+	}
+	if len(moved) > 0 {
+		err = WriteWorkspaces(workspaces)
+	}
+	return moved, err
+}
+
+// setLink makes link a symlink to target, replacing whatever is there if it
+// points elsewhere.
+func setLink(link, target string) error {
+	if current, err := os.Readlink(link); err == nil && current == target {
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(link), os.ModePerm); err != nil {
+		return err
+	}
+	os.Remove(link)
+	return os.Symlink(target, link)
+}
+
+// RefreshWorkspaces checks every recorded workspace when a long running node
+// starts: links are repaired, and the files referenced through each are
+// counted and checked to still exist, so moved or unmounted data is noticed
+// instead of silently no longer being provided. The IPFS subsystem must be
+// initialized.
+func RefreshWorkspaces() ([]WorkspaceReport, error) {
+	workspaces, err := ReadWorkspaces()
+	if err != nil {
+		return nil, err
+	}
+	reports := make(map[string]*WorkspaceReport, len(workspaces))
+	for name, w := range workspaces {
+		report := &WorkspaceReport{Workspace: *w}
+		reports[name] = report
+		if _, err := os.Stat(w.Path); err != nil {
+			report.Missing = true
+			continue
+		}
+		link := filepath.Join(workspacesDir(), name)
+		if current, err := os.Readlink(link); err != nil || current != w.Path {
+			if err := setLink(link, w.Path); err != nil {
+				return nil, err
+			}
+			report.Relinked = true
+		}
+	}
+
+	if node != nil && node.Filestore != nil {
+		next, err := filestore.ListAll(node.Filestore, false)
+		if err != nil {
+			return nil, err
+		}
+		checked := make(map[string]bool)
+		for res := next(); res != nil; res = next() {
+			if _, seen := checked[res.FilePath]; seen || res.FilePath == "" {
+				continue
+			}
+			name, rel := splitWorkspacePath(res.FilePath)
+			report, ok := reports[name]
+			if !ok {
+				continue
+			}
+			report.Refs++
+			_, err := os.Stat(filepath.Join(report.Path, rel))
+			checked[res.FilePath] = err == nil
+			if err != nil && !report.Missing {
+				report.MissingFiles++
+			}
+		}
+	}
+
+	result := make([]WorkspaceReport, 0, len(reports))
+	for _, report := range reports {
+		result = append(result, *report)
+	}
+	return result, nil
+}
+
+// splitWorkspacePath splits a filestore path into the workspace link it goes
+// through and the path within the workspace.
+func splitWorkspacePath(path string) (link, rel string) {
+	parts := strings.SplitN(filepath.ToSlash(path), "/", 3)
+	if len(parts) < 3 || parts[0] != "workspaces" {
+		return "", ""
+	}
+	return parts[1], filepath.FromSlash(parts[2])
+}
@@ -0,0 +1,58 @@
+package ipfs
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	aitConf "github.com/arken/ait/config"
+)
+
+func TestRelocateWorkspaces(t *testing.T) {
+	root, err := ioutil.TempDir("", "ait-workspaces")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+	repoPath := aitConf.Global.IPFS.Path
+	aitConf.Global.IPFS.Path = filepath.Join(root, "ait", "ipfs")
+	defer func() { aitConf.Global.IPFS.Path = repoPath }()
+
+	oldDisk := filepath.Join(root, "old", "project")
+	newDisk := filepath.Join(root, "new", "project")
+	for _, dir := range []string{oldDisk, newDisk, filepath.Dir(aitConf.Global.IPFS.Path)} {
+		if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+			t.Fatal(err)
+		}
+	}
+	wd, _ := os.Getwd()
+	defer os.Chdir(wd)
+	os.Chdir(oldDisk)
+	link, err := LinkWorkdir()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	moved, err := RelocateWorkspaces(filepath.Join(root, "old"), filepath.Join(root, "new"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(moved) != 1 || moved[0].Path != newDisk {
+		t.Fatalf("expected the workspace to move to %v, got %v", newDisk, moved)
+	}
+	if target, _ := os.Readlink(link); target != newDisk {
+		t.Errorf("the link points at %v instead of %v", target, newDisk)
+	}
+
+	// The relocated workspace keeps its link.
+	os.Chdir(newDisk)
+	if relinked, err := LinkWorkdir(); err != nil || relinked != link {
+		t.Errorf("expected the link %v, got %v (%v)", link, relinked, err)
+	}
+
+	// Nothing changes if a new location is missing.
+	if _, err := RelocateWorkspaces(filepath.Join(root, "new"), filepath.Join(root, "gone")); err == nil {
+		t.Error("expected relocating to a missing directory to fail")
+	}
+}
@@ -0,0 +1,56 @@
+package keysets
+
+import (
+	"path/filepath"
+	"sync"
+
+	"github.com/arken/ait/display"
+	"github.com/arken/ait/utils"
+)
+
+// addResult is the CID a staged file was added with, or why it wasn't.
+type addResult struct {
+	cid string
+	err error
+	// skipped is set when the deadline passed before the file was added.
+	skipped bool
+}
+
+// addStagedFiles adds the staged files at paths, in the workspace at dir, with
+// utils.NumWorkers files at once and returns their results in the order of
+// paths, so keysets are written the same however the work was split. Files
+// left once the context of deadline is done are skipped and counted by
+// deadline. bar, if not nil, advances as each file is done.
+func addStagedFiles(dir string, handoff map[string]utils.HandoffEntry, link string, paths []string,
+	deadline *deadlineTracker, bar *display.Progress) []addResult {
+	results := make([]addResult, len(paths))
+	jobs := make(chan int)
+	var lock sync.Mutex
+	var wg sync.WaitGroup
+	for w := 0; w < utils.NumWorkers(); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				lock.Lock()
+				expired := deadline.expired()
+				lock.Unlock()
+				if expired {
+					results[i].skipped = true
+					continue
+				}
+				results[i].cid, results[i].err = addStaged(dir, handoff, link, paths[i])
+				if bar != nil {
+					size, _ := utils.GetFileSize(filepath.Join(dir, paths[i]))
+					bar.AddFile(size)
+				}
+			}
+		}()
+	}
+	for i := range paths {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+	return results
+}
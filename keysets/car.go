@@ -0,0 +1,151 @@
+package keysets
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	cid "github.com/ipfs/go-cid"
+	cbor "github.com/ipfs/go-ipld-cbor"
+	ipld "github.com/ipfs/go-ipld-format"
+	merkledag "github.com/ipfs/go-merkledag"
+	unixfs "github.com/ipfs/go-unixfs"
+)
+
+// carWriter writes keysets as a CAR file whose root is a UnixFS directory
+// linking to every entry by name. Only the directory is in it, a manifest
+// of the keyset: IPFS tools importing it (ie "ipfs dag import") fetch the
+// files through it.
+type carWriter struct{}
+
+func (carWriter) Extension() string {
+	return ".car"
+}
+
+func (carWriter) Write(w io.Writer, entries []Entry) error {
+	root := &carDir{}
+	for _, entry := range entries {
+		c, err := cid.Decode(entry.CID)
+		if err != nil {
+			return fmt.Errorf("%v: %v", entry.Name, err)
+		}
+		var parts []string
+		for _, part := range strings.Split(entry.Name, "/") {
+			if part != "" && part != "." && part != ".." {
+				parts = append(parts, part)
+			}
+		}
+		if len(parts) > 0 {
+			root.add(parts, c)
+		}
+	}
+	var nodes []*merkledag.ProtoNode
+	node, err := root.build(&nodes)
+	if err != nil {
+		return err
+	}
+	header, err := cbor.DumpObject(map[string]interface{}{
+		"roots":   []cid.Cid{node.Cid()},
+		"version": 1,
+	})
+	if err != nil {
+		return err
+	}
+	if err = writeCarSection(w, header); err != nil {
+		return err
+	}
+	// The root comes first, then the directories it holds.
+	for i := len(nodes) - 1; i >= 0; i-- {
+		if err = writeCarSection(w, nodes[i].Cid().Bytes(), nodes[i].RawData()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeCarSection writes the parts as one section of a CAR file, prefixed by
+// their total length.
+func writeCarSection(w io.Writer, parts ...[]byte) error {
+	length := 0
+	for _, part := range parts {
+		length += len(part)
+	}
+	prefix := make([]byte, binary.MaxVarintLen64)
+	if _, err := w.Write(prefix[:binary.PutUvarint(prefix, uint64(length))]); err != nil {
+		return err
+	}
+	for _, part := range parts {
+		if _, err := w.Write(part); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// carDir is a directory of the manifest, names listed more than once keep
+// their first CID.
+type carDir struct {
+	files map[string]cid.Cid
+	dirs  map[string]*carDir
+}
+
+// add links the file at the path given by parts to c.
+func (d *carDir) add(parts []string, c cid.Cid) {
+	name := parts[0]
+	if _, taken := d.files[name]; taken {
+		return
+	}
+	if len(parts) == 1 {
+		if _, taken := d.dirs[name]; !taken {
+			if d.files == nil {
+				d.files = make(map[string]cid.Cid)
+			}
+			d.files[name] = c
+		}
+		return
+	}
+	if d.dirs == nil {
+		d.dirs = make(map[string]*carDir)
+	}
+	if d.dirs[name] == nil {
+		d.dirs[name] = &carDir{}
+	}
+	d.dirs[name].add(parts[1:], c)
+}
+
+// build returns the node of the directory, appending it to nodes after the
+// nodes of the directories it holds. Links are sorted by name, so the same
+// keyset always gets the same root.
+func (d *carDir) build(nodes *[]*merkledag.ProtoNode) (*merkledag.ProtoNode, error) {
+	names := make([]string, 0, len(d.files)+len(d.dirs))
+	for name := range d.files {
+		names = append(names, name)
+	}
+	for name := range d.dirs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	node := merkledag.NodeWithData(unixfs.FolderPBData())
+	for _, name := range names {
+		// The size of the files isn't in the keyset, their links leave it
+		// out.
+		link := &ipld.Link{Cid: d.files[name]}
+		if dir, ok := d.dirs[name]; ok {
+			child, err := dir.build(nodes)
+			if err != nil {
+				return nil, err
+			}
+			if link.Size, err = child.Size(); err != nil {
+				return nil, err
+			}
+			link.Cid = child.Cid()
+		}
+		if err := node.AddRawLink(name, link); err != nil {
+			return nil, err
+		}
+	}
+	*nodes = append(*nodes, node)
+	return node, nil
+}
@@ -4,13 +4,34 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 
+	"github.com/arken/ait/config"
+	"github.com/arken/ait/display"
 	"github.com/arken/ait/utils"
 	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	"github.com/go-git/go-git/v5/plumbing/transport/client"
+	githttp "github.com/go-git/go-git/v5/plumbing/transport/http"
 )
 
-// Clone pulls a remote repository to the local instance of AIT.
+// Clone pulls a remote repository to the local instance of AIT, showing the
+// objects counted by the remote and the bytes received as it goes.
 func Clone(url, path string) (*git.Repository, error) {
+	defer utils.TimePhase("clone")()
+	var transfer *display.Transfer
+	if utils.FileExists(path) {
+		transfer = display.NewTransfer("Fetching "+url, 0)
+	} else {
+		transfer = display.NewTransfer("Cloning "+url, 0)
+	}
+	defer transfer.Finish()
+	// Bytes are counted as they're read from the https transport, the only
+	// one reporting them. The ssh one only has the remote's messages.
+	https := utils.PinnedClient(config.Global.Trust.Hosts)
+	https.Transport = transfer.Transport(https.Transport)
+	client.InstallProtocol("https", githttp.NewClient(https))
 	dir := filepath.Dir(path)
 	if !utils.FileExists(dir) {
 		err := os.MkdirAll(dir, os.ModePerm)
@@ -21,10 +42,26 @@ func Clone(url, path string) (*git.Repository, error) {
 
 	r, err := git.PlainOpen(path)
 	if err != nil && err.Error() == "repository does not exist" {
-		r, err = git.PlainClone(path, false, &git.CloneOptions{
-			URL:               url,
+		// The size of a clone isn't known beforehand, at least keep the
+		// configured space free for it.
+		if err = utils.CheckFreeSpace(dir, 0, "cloning "+url); err != nil {
+			return nil, err
+		}
+		options := &git.CloneOptions{
 			RecurseSubmodules: git.DefaultSubmoduleRecursionDepth,
-		})
+			Progress:          transfer,
+		}
+		if branch := config.RemoteOptionsOf(url).Branch; branch != "" {
+			options.ReferenceName = plumbing.NewBranchReferenceName(branch)
+		}
+		options.URL, options.Auth = gitTransport(url)
+		r, err = git.PlainClone(path, false, options)
+		if needsAuth(err) {
+			if options.Auth = gitAuth(options.URL); options.Auth != nil {
+				os.RemoveAll(path)
+				r, err = git.PlainClone(path, false, options)
+			}
+		}
 
 		if err != nil {
 			return r, err
@@ -39,7 +76,18 @@ func Clone(url, path string) (*git.Repository, error) {
 		if err != nil {
 			return r, err
 		}
-		err = w.Pull(&git.PullOptions{RemoteName: "origin"})
+		options := &git.PullOptions{RemoteName: "origin", Progress: transfer}
+		origin := url
+		if remote, err := r.Remote("origin"); err == nil && len(remote.Config().URLs) > 0 {
+			origin = remote.Config().URLs[0]
+		}
+		_, options.Auth = gitTransport(origin)
+		err = w.Pull(options)
+		if needsAuth(err) {
+			if options.Auth = gitAuth(origin); options.Auth != nil {
+				err = w.Pull(options)
+			}
+		}
 		if err != nil && err.Error() != "already up-to-date" {
 			return r, err
 		}
@@ -47,3 +95,28 @@ func Clone(url, path string) (*git.Repository, error) {
 
 	return r, nil
 }
+
+// needsAuth returns true if err means the remote wants credentials.
+func needsAuth(err error) bool {
+	return err == transport.ErrAuthenticationRequired || err == transport.ErrAuthorizationFailed
+}
+
+// gitAuth returns the credentials git's helpers have for url, so private
+// keysets clone the same way they do with git, or the saved access token for
+// GitHub repositories, or nil if there are none.
+func gitAuth(url string) transport.AuthMethod {
+	username, password, ok := utils.GitCredential(url)
+	if !ok && strings.HasPrefix(url, "https://github.com/") {
+		if token := config.GitHubToken(); token != "" {
+			username, password, ok = "", token, true
+		}
+	}
+	if !ok {
+		return nil
+	}
+	if username == "" {
+		// Token only helpers are accepted by GitHub with any username.
+		username = "git"
+	}
+	return &githttp.BasicAuth{Username: username, Password: password}
+}
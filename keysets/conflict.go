@@ -0,0 +1,160 @@
+package keysets
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"github.com/arken/ait/utils"
+)
+
+// The ways a conflict is resolved when amending a keyset.
+const (
+	// ConflictOurs replaces the entry of the keyset with the staged file.
+	ConflictOurs = "ours"
+	// ConflictTheirs keeps the entry of the keyset and leaves the staged
+	// file out.
+	ConflictTheirs = "theirs"
+	// ConflictAbort leaves the keyset as it is and fails with a
+	// *ConflictError.
+	ConflictAbort = "abort"
+)
+
+// OnConflict is how amending a keyset resolves conflicts: ConflictOurs,
+// ConflictTheirs or ConflictAbort, or "" to ask ResolveConflict about each.
+var OnConflict string
+
+// ResolveConflict returns how the conflict is resolved when OnConflict is
+// empty, ie by asking the user. When it's nil, amending aborts.
+var ResolveConflict func(Conflict) string
+
+// Conflict is a staged file named like an entry of the keyset being amended,
+// with other contents.
+type Conflict struct {
+	Name string `json:"name"`
+	// Ours is the CID of the staged file, Theirs the one in the keyset.
+	Ours   string `json:"ours"`
+	Theirs string `json:"theirs"`
+}
+
+// ConflictError is returned when amending a keyset is aborted on conflicts.
+// The keyset is left as it was.
+type ConflictError struct {
+	Conflicts []Conflict
+}
+
+func (e *ConflictError) Error() string {
+	return fmt.Sprintf("%d staged file(s) are named like entries of the keyset with other contents",
+		len(e.Conflicts))
+}
+
+// entryName returns the name an entry is written under, as getKeySetLine
+// writes it.
+func entryName(name string) string {
+	return strings.Join(strings.Fields(name), "-")
+}
+
+// amendEntries adds entries to the keyset at ksPath. Those whose CID is
+// already in the keyset are skipped, those named like an entry with another
+// CID are resolved as OnConflict says and the others are appended, with
+// their metadata.
+func amendEntries(ksPath string, entries []Entry) error {
+	data, err := ioutil.ReadFile(ksPath)
+	if err != nil {
+		return err
+	}
+	lines := strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
+	existing := make(map[string]bool)
+	names := make(map[string]string)
+	for _, line := range lines {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		pair := strings.Fields(line)
+		if len(pair) != 2 {
+			return fmt.Errorf("malformed KeySet file detected: %v", ksPath)
+		}
+		existing[pair[0]] = true
+		names[pair[1]] = pair[0]
+	}
+
+	var added []Entry
+	var conflicts []Conflict
+	replaced := make(map[string]Entry)
+	for _, entry := range entries {
+		if existing[entry.CID] {
+			continue
+		}
+		existing[entry.CID] = true
+		theirs, ok := names[entryName(entry.Name)]
+		if !ok {
+			added = append(added, entry)
+			continue
+		}
+		conflict := Conflict{Name: entryName(entry.Name), Ours: entry.CID, Theirs: theirs}
+		conflicts = append(conflicts, conflict)
+		resolution := OnConflict
+		if resolution == "" {
+			resolution = ConflictAbort
+			if ResolveConflict != nil {
+				resolution = ResolveConflict(conflict)
+			}
+		}
+		switch resolution {
+		case ConflictOurs:
+			replaced[conflict.Name] = entry
+		case ConflictTheirs:
+		default:
+			return &ConflictError{Conflicts: conflicts}
+		}
+	}
+
+	if len(replaced) == 0 {
+		keySetFile, err := os.OpenFile(ksPath, os.O_WRONLY|os.O_APPEND, 0644)
+		if err != nil {
+			return err
+		}
+		defer keySetFile.Close()
+		_, err = keySetFile.WriteString(entryLines(added))
+		return err
+	}
+	// Replaced entries are rewritten in place, their metadata along with them.
+	var out strings.Builder
+	skipping := false
+	for _, line := range lines {
+		if skipping && utils.IsKeysetMetadataLine(line) {
+			continue
+		}
+		skipping = false
+		pair := strings.Fields(line)
+		if entry, ok := replaced[pairName(pair)]; ok && !strings.HasPrefix(line, "#") {
+			out.WriteString(entryLines([]Entry{entry}))
+			skipping = true
+			continue
+		}
+		out.WriteString(line + "\n")
+	}
+	out.WriteString(entryLines(added))
+	return ioutil.WriteFile(ksPath, []byte(out.String()), 0644)
+}
+
+// pairName returns the name of the entry of a keyset line split in fields,
+// or "" if the line isn't an entry.
+func pairName(pair []string) string {
+	if len(pair) != 2 {
+		return ""
+	}
+	return pair[1]
+}
+
+// entryLines returns the lines of entries in a keyset, with their metadata.
+func entryLines(entries []Entry) string {
+	var lines strings.Builder
+	for _, entry := range entries {
+		lines.WriteString(getKeySetLine(entry.Name, entry.CID) + "\n")
+		writeMetadataLines(&lines, entry.Metadata)
+	}
+	return lines.String()
+}
@@ -0,0 +1,60 @@
+package keysets
+
+import (
+	"errors"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAmendEntries(t *testing.T) {
+	const upstream = "bafyone  one.txt\n#  license: CC0\nbafytwo  two.txt\n#  license: MIT\nbafythree  three.txt\n"
+	ksPath := filepath.Join(t.TempDir(), "upstream.ks")
+	write := func() {
+		assert.NoError(t, ioutil.WriteFile(ksPath, []byte(upstream), 0644))
+	}
+	read := func() string {
+		data, err := ioutil.ReadFile(ksPath)
+		assert.NoError(t, err)
+		return string(data)
+	}
+	defer func() { OnConflict, ResolveConflict = "", nil }()
+	staged := []Entry{
+		{Name: "one.txt", CID: "bafyone"},
+		{Name: "two.txt", CID: "bafynewtwo", Metadata: map[string]string{"license": "CC-BY-4.0"}},
+		{Name: "four.txt", CID: "bafyfour"},
+	}
+
+	// Exact duplicates are skipped, conflicts abort by default.
+	write()
+	err := amendEntries(ksPath, staged)
+	var conflictErr *ConflictError
+	assert.True(t, errors.As(err, &conflictErr))
+	assert.Equal(t, []Conflict{{Name: "two.txt", Ours: "bafynewtwo", Theirs: "bafytwo"}}, conflictErr.Conflicts)
+	assert.Equal(t, upstream, read())
+
+	OnConflict = ConflictTheirs
+	assert.NoError(t, amendEntries(ksPath, staged))
+	assert.Equal(t, upstream+"bafyfour  four.txt\n", read())
+
+	// Ours replaces the entry in place, with its metadata.
+	write()
+	OnConflict = ConflictOurs
+	assert.NoError(t, amendEntries(ksPath, staged))
+	assert.Equal(t, "bafyone  one.txt\n#  license: CC0\nbafynewtwo  two.txt\n#  license: CC-BY-4.0\n"+
+		"bafythree  three.txt\nbafyfour  four.txt\n", read())
+
+	// Without a policy, each conflict is asked about.
+	write()
+	OnConflict = ""
+	var asked []Conflict
+	ResolveConflict = func(c Conflict) string {
+		asked = append(asked, c)
+		return ConflictTheirs
+	}
+	assert.NoError(t, amendEntries(ksPath, staged))
+	assert.Len(t, asked, 1)
+	assert.Equal(t, upstream+"bafyfour  four.txt\n", read())
+}
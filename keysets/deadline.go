@@ -0,0 +1,59 @@
+package keysets
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Deadline is when Generate stops adding staged files. The zero time is no
+// deadline.
+var Deadline time.Time
+
+// DeadlineError is returned by Generate when Deadline, or the deadline of the
+// context given to GenerateIn, passed before every staged file was added. No
+// keyset is written, but the CIDs of the files that were added are in the hash
+// cache of the workspace, so generating the keyset again picks up where it
+// stopped as long as they're unchanged.
+type DeadlineError struct {
+	Added     int
+	Remaining int
+}
+
+func (e *DeadlineError) Error() string {
+	return fmt.Sprintf("the deadline passed with %d of %d staged file(s) added", e.Added, e.Added+e.Remaining)
+}
+
+// deadlineTracker counts the staged files added before ctx is done.
+type deadlineTracker struct {
+	ctx       context.Context
+	added     int
+	remaining int
+}
+
+// expired returns whether ctx is done, counting a file as remaining if so.
+func (t *deadlineTracker) expired() bool {
+	if t.ctx.Err() == nil {
+		return false
+	}
+	t.remaining++
+	return true
+}
+
+// done counts a staged file as added.
+func (t *deadlineTracker) done() {
+	t.added++
+}
+
+// err returns a *DeadlineError if files remain because the deadline passed,
+// or the error of ctx if it was cancelled.
+func (t *deadlineTracker) err() error {
+	if t.remaining == 0 {
+		return nil
+	}
+	if !errors.Is(t.ctx.Err(), context.DeadlineExceeded) {
+		return t.ctx.Err()
+	}
+	return &DeadlineError{Added: t.added, Remaining: t.remaining}
+}
@@ -0,0 +1,38 @@
+//go:build go1.18
+// +build go1.18
+
+package keysets
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func FuzzSearch(f *testing.F) {
+	f.Add([]byte("bafyone  book1.pdf\nbafytwo  book2.pdf\n"), "library/book*.pdf")
+	f.Add([]byte("too many fields here\n\x00\xff  \x1b]0;title\x07\n"), "library/*")
+	f.Add([]byte("bafyone  book1.pdf"), "library/[")
+	f.Add([]byte("bafyone  book1.pdf"), "library")
+	f.Fuzz(func(t *testing.T, keyset []byte, filePath string) {
+		dir := t.TempDir()
+		if err := ioutil.WriteFile(filepath.Join(dir, "library.ks"), keyset, 0644); err != nil {
+			t.Fatal(err)
+		}
+		// Cut the keyset in shards through its lines.
+		defer func(size int64) { shardSize = size }(shardSize)
+		shardSize = 7
+
+		hashes, err := Search(dir, filePath)
+		if err != nil {
+			return
+		}
+		pattern := filePath[strings.Index(filePath, "/")+1:]
+		for name, cids := range hashes {
+			if matched, _ := filepath.Match(pattern, name); !matched || len(cids) == 0 {
+				t.Fatalf("%q matched %q with %q", filePath, name, cids)
+			}
+		}
+	})
+}
@@ -1,116 +1,198 @@
 package keysets
 
 import (
-	"bufio"
+	"context"
 	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 	"sync"
 
-	"github.com/arken/ait/config"
+	"github.com/arken/ait/display"
 	"github.com/arken/ait/ipfs"
 	"github.com/arken/ait/types"
 	"github.com/arken/ait/utils"
-
-	"github.com/schollz/progressbar/v3"
 )
 
 const delimiter = "  "
 
+// AddFailure is a staged file that couldn't be added to IPFS.
+type AddFailure struct {
+	Path  string `json:"path"`
+	Error string `json:"error"`
+}
+
+// AddError is returned by Generate when some staged files couldn't be added.
+// The keyset is still written, without them.
+type AddError struct {
+	Failures []AddFailure
+}
+
+func (e *AddError) Error() string {
+	return fmt.Sprintf("%d staged file(s) couldn't be added", len(e.Failures))
+}
+
+// addError returns an *AddError of failures, or nil if there are none.
+func addError(failures []AddFailure) error {
+	if len(failures) == 0 {
+		return nil
+	}
+	return &AddError{Failures: failures}
+}
+
 // Generate is the public facing function for the creation of a keyset file.
 // Depending on the value of overwrite, the keyset file is either generated from
-// scratch or added to.
+// scratch or added to. A file that can't be added, ie because it can't be read
+// anymore, doesn't stop the others: they are reported in an *AddError.
 func Generate(path string, overwrite bool) error {
-	if overwrite {
-		return createNew(path)
+	ctx, cancel := deadlineContext()
+	defer cancel()
+	return GenerateIn(ctx, ".", path, overwrite)
+}
+
+// GenerateIn is Generate for the staged files of the workspace at dir. Files
+// left once ctx is done aren't added: a *DeadlineError is returned if its
+// deadline passed, its error if it was cancelled.
+func GenerateIn(ctx context.Context, dir, path string, overwrite bool) error {
+	if !overwrite {
+		return amendExisting(ctx, dir, path)
 	}
-	return amendExisting(path)
+	contents, err := utils.ReadStagedSortedIn(dir)
+	if err != nil {
+		return err
+	}
+	return createNew(ctx, dir, path, contents)
+}
+
+// GenerateFiles creates the keyset file at path from the staged files at
+// paths only, ie a part of a split submission, leaving the staged files as
+// they are.
+func GenerateFiles(path string, paths []string) error {
+	ctx, cancel := deadlineContext()
+	defer cancel()
+	contents := types.NewSortedStringSet()
+	for _, p := range paths {
+		contents.Add(p)
+	}
+	return createNew(ctx, ".", path, contents)
 }
 
-// createNew creates a keyset file with the given path. Path should not be the
-// desired directory, rather it should be a full path to a file which does not
-// exist yet (will be truncated if it does exist), and the file should end in
-// ".ks" The resultant keyset files contains the name (not path) of the file and
-// an IPFS cid hash, separated by a space.
-func createNew(path string) error {
+// deadlineContext returns the context Generate adds staged files with, done
+// at Deadline if there is one.
+func deadlineContext() (context.Context, context.CancelFunc) {
+	if Deadline.IsZero() {
+		return context.WithCancel(context.Background())
+	}
+	return context.WithDeadline(context.Background(), Deadline)
+}
+
+// createNew creates a keyset file with the given path from the staged files
+// in contents. Path should not be the desired directory, rather it should be a
+// full path to a file which does not exist yet (will be truncated if it does
+// exist), and the file should end in ".ks" The resultant keyset files contains
+// the name (not path) of the file and an IPFS cid hash, separated by a space.
+func createNew(ctx context.Context, dir, path string, contents *types.SortedStringSet) error {
 	_ = os.MkdirAll(filepath.Dir(path), os.ModePerm)
 
 	keySetFile, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
 	if err != nil {
 		return err
 	}
-	addedFiles, err := os.OpenFile(utils.AddedFilesPath, os.O_RDONLY, 0644)
-	if err != nil {
-		return err
-	}
 
-	// In order to not copy files to ~/.ait/ipfs/ we need to create a workdir symlink
-	// in .ait
-	wd, err := os.Getwd()
+	// In order to not copy files to ~/.ait/ipfs/ they are added through the
+	// workspace's link.
+	link, err := ipfs.LinkWorkspace(dir)
 	if err != nil {
+		cleanup(keySetFile)
 		return err
 	}
-	link := filepath.Join(filepath.Dir(config.Global.IPFS.Path), "workdir")
-	err = os.Symlink(wd, link)
-	if err != nil {
-		if strings.HasSuffix(err.Error(), "file exists") {
-			os.Remove(link)
-			err = os.Symlink(wd, link)
-			if err != nil {
-				return err
-			}
-		} else {
-			return err
-		}
-	}
-
-	contents := types.NewSortedStringSet()
-	utils.FillSet(contents, addedFiles)
-	addedFiles.Close()
 
 	// For large Datasets display a loading bar.
-	var ipfsBar *progressbar.ProgressBar
-	barPresent := false
+	var ipfsBar *display.Progress
 	if contents.Size() > 30 {
 		fmt.Println("Adding Files to Embedded IPFS Node:")
-		ipfsBar = progressbar.Default(int64(contents.Size()))
-		ipfsBar.RenderBlank()
-		barPresent = true
+		ipfsBar = display.NewFileProgress("Adding", int64(contents.Size()), stagedSize(dir, contents))
 	}
 
-	var output strings.Builder
-	output.Grow(contents.Size())
+	// Files handed off from another machine have already been hashed.
+	handoff, err := utils.ReadHandoffIn(dir)
+	if err != nil {
+		cleanup(keySetFile)
+		return err
+	}
 
-	err = contents.ForEach(func(filePath string) error {
-		linkPath := filepath.Join(link, filePath)
-		fmt.Fprintf(&output, "%s\n", getKeySetLineFromPath(linkPath))
-		if barPresent {
-			ipfsBar.Add(1)
-		}
+	meta, err := newMetadataCollector(dir, false)
+	if err != nil {
+		cleanup(keySetFile)
+		return err
+	}
+	fileMeta, err := utils.ReadFileMetadataIn(dir)
+	if err != nil {
+		cleanup(keySetFile)
+		return err
+	}
+	var failures []AddFailure
+	var entries []Entry
+	deadline := deadlineTracker{ctx: ctx}
+	paths := make([]string, 0, contents.Size())
+	contents.ForEach(func(filePath string) error {
+		paths = append(paths, filePath)
 		return nil
 	})
-	_, err = keySetFile.WriteString(output.String())
-	if err != nil {
+	for i, result := range addStagedFiles(dir, handoff, link, paths, &deadline, ipfsBar) {
+		switch {
+		case result.skipped:
+		case result.err != nil:
+			failures = append(failures, AddFailure{Path: paths[i], Error: result.err.Error()})
+		default:
+			collected := meta.collect(paths[i], result.cid)
+			entries = append(entries, Entry{Name: filepath.Base(paths[i]), CID: result.cid,
+				Metadata: withCollected(utils.MetadataFor(fileMeta, paths[i]), collected)})
+			deadline.done()
+		}
+	}
+	if err = deadline.err(); err != nil {
 		cleanup(keySetFile)
-		os.Remove(link)
 		return err
 	}
-	err = os.Remove(link)
+	// The keyset is amended and merged later on, so it's always written as
+	// repositories read it.
+	err = Formats[DefaultFormat].Write(keySetFile, entries)
 	if err != nil {
+		cleanup(keySetFile)
+		return err
+	}
+	if err = meta.save(); err != nil {
+		cleanup(keySetFile)
 		return err
 	}
 	err = keySetFile.Close()
 	if err != nil {
 		return err
 	}
-	return err
+	return addError(failures)
+}
+
+// addStaged returns the CID of the staged file at filePath in the workspace at
+// dir: the one it was handed off with, or the one it gets added to IPFS through
+// the workspace's link with. Files deleted since they were staged are fetched
+// back.
+func addStaged(dir string, handoff map[string]utils.HandoffEntry, link, filePath string) (string, error) {
+	if cid, ok := utils.HandoffCIDIn(dir, handoff, filePath); ok {
+		return cid, nil
+	}
+	if isGone(filepath.Join(dir, filePath)) {
+		return refetchMissing(dir, link, filePath)
+	}
+	return ipfs.HashCached(filepath.Join(link, filePath))
 }
 
 // amendExisting looks at current files in added_files and adds any that aren't
 // already in the keyset file to the keyset files. The keyset file in question
-// should be at path.
-func amendExisting(ksPath string) error {
+// should be at path. Staged files named like an entry of the keyset with other
+// contents are resolved as OnConflict says.
+func amendExisting(ctx context.Context, dir, ksPath string) error {
 	doneChan := make(chan int, 1)
 	wg := sync.WaitGroup{}
 	wg.Add(1)
@@ -118,69 +200,92 @@ func amendExisting(ksPath string) error {
 	// Display Spinner on amend.
 	go utils.SpinnerWait(doneChan, "Reading Previous Keyset File...", &wg)
 
-	keySetFile, err := os.OpenFile(ksPath, os.O_RDWR, 0644)
+	staged, err := utils.ReadStagedSortedIn(dir)
 	if err != nil {
+		doneChan <- 0
+		wg.Wait()
 		return err
 	}
-	defer keySetFile.Close()
-	addedFiles, err := os.OpenFile(utils.AddedFilesPath, os.O_RDONLY, 0644)
-	if err != nil {
-		return err
-	}
-	defer addedFiles.Close()
+	var paths []string
+	_ = staged.ForEach(func(path string) error {
+		paths = append(paths, path)
+		return nil
+	})
 	addedFilesContents := make(map[string]string)
 	// ^ map of cid -> filePATH
-	fillMapWithCID(addedFilesContents, addedFiles)
-	ksContents := make(map[string]string)
-	// ^ map of cid -> fileNAME
-	fillMapWithCID(ksContents, keySetFile)
-	newFiles := make(map[string]string)
+	collected := make(map[string]map[string]string)
+	failures, err := fillMapWithStagedCIDs(ctx, dir, addedFilesContents, collected, paths)
 
 	doneChan <- 0
 	wg.Wait()
+	if err != nil {
+		return err
+	}
 
 	// For large Datasets display a loading bar.
-	var namesBar *progressbar.ProgressBar
+	var namesBar *display.Progress
 	barPresent := false
 	if len(addedFilesContents) > 30 {
 		fmt.Println("Reading File Names:")
-		namesBar = progressbar.Default(int64(len(addedFilesContents)))
-		namesBar.RenderBlank()
+		namesBar = display.NewProgress("Reading", int64(len(addedFilesContents)), false)
 		barPresent = true
 	}
 
-	// ^ paths of the files which will be added
+	fileMeta, err := utils.ReadFileMetadataIn(dir)
+	if err != nil {
+		return err
+	}
+	entries := make([]Entry, 0, len(addedFilesContents))
 	for cid, path := range addedFilesContents {
-		if _, contains := ksContents[cid]; !contains {
-			filename := strings.Join(strings.Fields(filepath.Base(path)), "-")
-			newFiles[cid] = filename
-		} else {
-			delete(ksContents, cid)
-		}
+		entries = append(entries, Entry{Name: utils.KeysetName(path), CID: cid,
+			Metadata: withCollected(utils.MetadataFor(fileMeta, path), collected[path])})
 		if barPresent {
 			namesBar.Add(1)
-			namesBar.RenderBlank()
 		}
 	}
-
-	var ipfsBar *progressbar.ProgressBar
-	if barPresent {
-		fmt.Println("Adding Files to Embedded IPFS Node:")
-		ipfsBar = progressbar.Default(int64(len(newFiles)))
-		ipfsBar.RenderBlank()
+	// Conflicts are resolved, and new entries appended, in a stable order.
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Name < entries[j].Name })
+	if err = amendEntries(ksPath, entries); err != nil {
+		return err
 	}
+	return addError(failures)
+}
 
-	for cid, filename := range newFiles {
-		line := getKeySetLine(filename, cid)
-		_, err := keySetFile.WriteString(line + "\n")
-		if err != nil {
-			return err
-		}
-		if barPresent {
-			ipfsBar.Add(1)
-		}
+// Merge appends the entries of the keyset at from that aren't already in the
+// keyset at ksPath to it, along with their metadata. Entries named like one of
+// the keyset with another CID are resolved as OnConflict says.
+func Merge(ksPath, from string) error {
+	entries, err := utils.ReadKeysetEntries(from)
+	if err != nil {
+		return err
 	}
-	return nil
+	fromFile, err := os.Open(from)
+	if err != nil {
+		return err
+	}
+	metadata, err := utils.ParseKeysetMetadata(fromFile)
+	fromFile.Close()
+	if err != nil {
+		return err
+	}
+	merged := make([]Entry, len(entries))
+	for i, entry := range entries {
+		merged[i] = Entry{Name: entry.Name, CID: entry.CID, Metadata: metadata[entry.Name]}
+	}
+	return amendEntries(ksPath, merged)
+}
+
+// stagedSize returns the total size in bytes of the staged files of the
+// workspace at dir, so progress adding them reflects how much data is left
+// rather than how many files.
+func stagedSize(dir string, contents *types.SortedStringSet) int64 {
+	var total int64
+	contents.ForEach(func(filePath string) error {
+		size, _ := utils.GetFileSize(filepath.Join(dir, filePath))
+		total += size
+		return nil
+	})
+	return total
 }
 
 // cleanup closes and deletes the given file.
@@ -190,16 +295,6 @@ func cleanup(file *os.File) {
 	_ = os.Remove(path)
 }
 
-// getKeySetLine returns a properly formed line for a KeySet file given a path
-// to a file. No newline at the end.
-func getKeySetLineFromPath(filePath string) string {
-	// Scrub filename for spaces and replace with dashes.
-	cid, err := ipfs.Add(filePath, true)
-	utils.CheckErrorWithCleanup(err, utils.SubmissionCleanup)
-	filename := strings.Join(strings.Fields(filepath.Base(filePath)), "-")
-	return getKeySetLine(filename, cid)
-}
-
 // getKeySetLine returns a properly formed line for a KeySet file. It expects a
 // fileNAME (not path) and an IPFS cid. No newline at the end.
 func getKeySetLine(filename, cid string) string {
@@ -208,57 +303,49 @@ func getKeySetLine(filename, cid string) string {
 	return cid + delimiter + filename
 }
 
-// fillMapWithCID will fill the given map with IPFS cid hashes as the key and
-// either the filename or filepath as the value. This function ONLY be used for
-// files that are standard keyset files or files that are just newline separated
-// paths. Returns the length of the longest fileNAME, not path. If the file was
-// a keyset file, the values are filenames. If the file was just file paths, the
-// the values will be file paths.
-func fillMapWithCID(contents map[string]string, file *os.File) {
-	// In order to not copy files to ~/.ait/ipfs/ we need to create a workdir symlink
-	// in .ait
-	wd, err := os.Getwd()
+// writeMetadataLines writes the metadata lines of the entry written last.
+func writeMetadataLines(w *strings.Builder, fields map[string]string) {
+	for _, line := range utils.KeysetMetadataLines(fields) {
+		w.WriteString(line + "\n")
+	}
+}
+
+// fillMapWithStagedCIDs adds the staged files at paths, in the workspace at
+// dir, and fills the given map with their CIDs as the key and their paths as
+// the value. The files that couldn't be added are returned, along with a
+// *DeadlineError if the deadline of ctx passed before every file was added.
+// The metadata of those files is collected along the way, into collected by
+// path.
+func fillMapWithStagedCIDs(ctx context.Context, dir string, contents map[string]string,
+	collected map[string]map[string]string, paths []string) (failures []AddFailure, err error) {
+	// In order to not copy files to ~/.ait/ipfs/ they are added through the
+	// workspace's link.
+	link, err := ipfs.LinkWorkspace(dir)
 	if err != nil {
-		utils.FatalWithCleanup(utils.SubmissionCleanup, err.Error())
+		return nil, err
 	}
-	link := filepath.Join(filepath.Dir(config.Global.IPFS.Path), "workdir")
-	err = os.Symlink(wd, link)
+	handoff, err := utils.ReadHandoffIn(dir)
 	if err != nil {
-		if strings.HasSuffix(err.Error(), "file exists") {
-			os.Remove(link)
-			err = os.Symlink(wd, link)
-			if err != nil {
-				utils.FatalWithCleanup(utils.SubmissionCleanup, err.Error())
-			}
-		} else {
-			utils.FatalWithCleanup(utils.SubmissionCleanup, err.Error())
-		}
+		return nil, err
 	}
-
-	scanner := bufio.NewScanner(file)
-	scanner.Split(bufio.ScanLines)
-	if filepath.Ext(file.Name()) == ".ks" {
-		for scanner.Scan() {
-			line := strings.TrimSpace(scanner.Text())
-			if len(line) > 0 {
-				pair := strings.Fields(line)
-				if len(pair) != 2 {
-					utils.FatalWithCleanup(utils.SubmissionCleanup,
-						"Malformed KeySet file detected:", file.Name())
-				}
-				contents[pair[0]] = pair[1]
-			}
-		}
-	} else {
-		for scanner.Scan() {
-			line := strings.TrimSpace(scanner.Text())
-			if len(line) > 0 {
-				filename := filepath.Base(line)
-				cid, err := ipfs.Add(filepath.Join(link, line), true)
-				utils.CheckErrorWithCleanup(err, utils.SubmissionCleanup)
-				contents[cid] = filename
-			}
+	meta, err := newMetadataCollector(dir, true)
+	if err != nil {
+		return nil, err
+	}
+	deadline := deadlineTracker{ctx: ctx}
+	for i, result := range addStagedFiles(dir, handoff, link, paths, &deadline, nil) {
+		switch {
+		case result.skipped:
+		case result.err != nil:
+			failures = append(failures, AddFailure{Path: paths[i], Error: result.err.Error()})
+		default:
+			contents[result.cid] = paths[i]
+			deadline.done()
+			collected[paths[i]] = meta.collect(paths[i], result.cid)
 		}
 	}
-	os.Remove(link)
+	if err = deadline.err(); err != nil {
+		return failures, err
+	}
+	return failures, meta.save()
 }
@@ -1,9 +1,14 @@
 package keysets
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"os"
 	"testing"
+	"time"
+
+	"github.com/arken/ait/utils"
 )
 
 func TestGenerate(t *testing.T) {
@@ -14,3 +19,85 @@ func TestGenerate(t *testing.T) {
 	}
 	os.Remove("test.ks")
 }
+
+func TestAddError(t *testing.T) {
+	if addError(nil) != nil {
+		t.Error("no failures should be no error")
+	}
+	err := addError([]AddFailure{{Path: "a.txt", Error: "permission denied"}, {Path: "b.txt", Error: "gone"}})
+	var addErr *AddError
+	if !errors.As(err, &addErr) || len(addErr.Failures) != 2 {
+		t.Fatalf("expected an *AddError with both failures, got %v", err)
+	}
+	if err.Error() != "2 staged file(s) couldn't be added" {
+		t.Error("unexpected message:", err)
+	}
+}
+
+func TestWithCollected(t *testing.T) {
+	given := map[string]string{"license": "CC-BY-4.0"}
+	if fields := withCollected(given, nil); len(fields) != 1 || fields["license"] != "CC-BY-4.0" {
+		t.Error("without collected fields the given ones should be kept, got", fields)
+	}
+	fields := withCollected(given, map[string]string{"license": "unknown", "rows": "1200"})
+	if fields["license"] != "CC-BY-4.0" || fields["rows"] != "1200" {
+		t.Error("the collected fields should be added, under those given with ait meta, got", fields)
+	}
+}
+
+func TestDeadlineTracker(t *testing.T) {
+	tracker := deadlineTracker{ctx: context.Background()}
+	if tracker.expired() || tracker.err() != nil {
+		t.Fatal("no deadline should never expire")
+	}
+	tracker.done()
+	ctx, cancel := context.WithDeadline(context.Background(), time.Now().Add(-time.Second))
+	defer cancel()
+	tracker.ctx = ctx
+	if !tracker.expired() || !tracker.expired() {
+		t.Fatal("a past deadline should have expired")
+	}
+	var deadlineErr *DeadlineError
+	if err := tracker.err(); !errors.As(err, &deadlineErr) || deadlineErr.Added != 1 || deadlineErr.Remaining != 2 {
+		t.Fatalf("expected 1 added and 2 remaining, got %v", err)
+	}
+
+	ctx, cancel = context.WithCancel(context.Background())
+	cancel()
+	tracker = deadlineTracker{ctx: ctx}
+	if !tracker.expired() || !errors.Is(tracker.err(), context.Canceled) {
+		t.Error("a cancelled context should stop the files with its error")
+	}
+}
+
+func TestAddStagedFiles(t *testing.T) {
+	defer func(workers int) { utils.Workers = workers }(utils.Workers)
+	utils.Workers = 4
+	// Handed off files that aren't on this machine keep their CID without
+	// being added to IPFS.
+	handoff := make(map[string]utils.HandoffEntry)
+	var paths []string
+	for i := 0; i < 50; i++ {
+		path := fmt.Sprintf("handed-off/%02d.csv", i)
+		handoff[path] = utils.HandoffEntry{Path: path, CID: fmt.Sprintf("cid%02d", i), Size: 1}
+		paths = append(paths, path)
+	}
+	deadline := deadlineTracker{ctx: context.Background()}
+	for i, result := range addStagedFiles(".", handoff, "", paths, &deadline, nil) {
+		if result.err != nil || result.skipped || result.cid != fmt.Sprintf("cid%02d", i) {
+			t.Fatalf("expected cid%02d for %v, got %+v", i, paths[i], result)
+		}
+	}
+
+	ctx, cancel := context.WithDeadline(context.Background(), time.Now().Add(-time.Minute))
+	defer cancel()
+	deadline.ctx = ctx
+	for _, result := range addStagedFiles(".", handoff, "", paths, &deadline, nil) {
+		if !result.skipped {
+			t.Fatal("files should be skipped once the deadline passed, got", result)
+		}
+	}
+	if deadline.remaining != len(paths) {
+		t.Errorf("expected %d remaining files, got %d", len(paths), deadline.remaining)
+	}
+}
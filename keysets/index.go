@@ -0,0 +1,141 @@
+package keysets
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/arken/ait/utils"
+
+	"github.com/go-git/go-billy/v5"
+	"github.com/go-git/go-git/v5"
+)
+
+// IndexEntry summarizes a keyset file for a repository's table of contents.
+type IndexEntry struct {
+	Path    string
+	Entries int
+	// Size is the total size of the entries that could be looked up, and
+	// Unknown the number of entries that couldn't.
+	Size    int64
+	Unknown int
+	// Updated is when the keyset was last changed.
+	Updated time.Time
+}
+
+// BuildIndex summarizes every keyset in the worktree of a keyset repository,
+// in order of their paths. sizeOf returns the size of an entry from its CID.
+func BuildIndex(r *git.Repository, sizeOf func(cid string) (int64, error)) ([]IndexEntry, error) {
+	w, err := r.Worktree()
+	if err != nil {
+		return nil, err
+	}
+	paths, err := findKeysets(w.Filesystem, "")
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(paths)
+	index := make([]IndexEntry, 0, len(paths))
+	for _, p := range paths {
+		file, err := w.Filesystem.Open(p)
+		if err != nil {
+			return nil, err
+		}
+		// Entries are looked up as they're read, rather than all loaded first.
+		entry := IndexEntry{Path: p}
+		err = utils.ScanKeysetEntries(file, func(e utils.KeysetEntry) error {
+			size, err := sizeOf(e.CID)
+			if err != nil {
+				entry.Unknown++
+			}
+			entry.Size += size
+			entry.Entries++
+			return nil
+		})
+		file.Close()
+		if err != nil {
+			return nil, err
+		}
+		if entry.Updated, err = lastChanged(r, p); err != nil {
+			return nil, err
+		}
+		index = append(index, entry)
+	}
+	return index, nil
+}
+
+// findKeysets returns the paths of the keyset files under dir, skipping
+// hidden directories such as .git.
+func findKeysets(fs billy.Filesystem, dir string) ([]string, error) {
+	infos, err := fs.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var paths []string
+	for _, info := range infos {
+		p := path.Join(dir, info.Name())
+		if strings.HasPrefix(info.Name(), ".") {
+			continue
+		}
+		if info.IsDir() {
+			found, err := findKeysets(fs, p)
+			if err != nil {
+				return nil, err
+			}
+			paths = append(paths, found...)
+		} else if strings.HasSuffix(p, ".ks") {
+			paths = append(paths, p)
+		}
+	}
+	return paths, nil
+}
+
+// lastChanged returns when the file at p was last committed.
+func lastChanged(r *git.Repository, p string) (time.Time, error) {
+	commits, err := r.Log(&git.LogOptions{FileName: &p})
+	if err != nil {
+		return time.Time{}, err
+	}
+	defer commits.Close()
+	commit, err := commits.Next()
+	if err != nil {
+		return time.Time{}, nil
+	}
+	return commit.Committer.When, nil
+}
+
+// FormatIndex renders the index as a Markdown table, with the totals of
+// every keyset at the end.
+func FormatIndex(index []IndexEntry) string {
+	var b strings.Builder
+	b.WriteString("# Keyset Index\n\n")
+	b.WriteString("This file is generated by `ait index`, do not edit it by hand.\n\n")
+	b.WriteString("| Keyset | Entries | Size | Last Update |\n")
+	b.WriteString("|--------|--------:|-----:|-------------|\n")
+	var total IndexEntry
+	for _, entry := range index {
+		updated := "-"
+		if !entry.Updated.IsZero() {
+			updated = entry.Updated.UTC().Format("2006-01-02")
+		}
+		fmt.Fprintf(&b, "| [%v](%v) | %d | %v | %v |\n", entry.Path, entry.Path,
+			entry.Entries, formatIndexSize(entry), updated)
+		total.Entries += entry.Entries
+		total.Size += entry.Size
+		total.Unknown += entry.Unknown
+	}
+	fmt.Fprintf(&b, "| **Total** | %d | %v | |\n", total.Entries, formatIndexSize(total))
+	return b.String()
+}
+
+// formatIndexSize formats the size of an entry, noting how many of its
+// entries' sizes are unknown.
+func formatIndexSize(entry IndexEntry) string {
+	size := utils.FormatByteSize(entry.Size)
+	if entry.Unknown > 0 {
+		size += fmt.Sprintf(" (%d unknown)", entry.Unknown)
+	}
+	return size
+}
@@ -0,0 +1,59 @@
+package keysets
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/go-git/go-billy/v5/memfs"
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/storage/memory"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBuildIndex(t *testing.T) {
+	fs := memfs.New()
+	r, err := git.Init(memory.NewStorage(), fs)
+	assert.NoError(t, err)
+	w, err := r.Worktree()
+	assert.NoError(t, err)
+	files := map[string]string{
+		"library/books.ks": "bafyone  book.pdf\nbafytwo  other.pdf\n",
+		"science.ks":       "bafylost  data.csv\n",
+		"README.md":        "Not a keyset\n",
+	}
+	for name, contents := range files {
+		f, err := fs.Create(name)
+		assert.NoError(t, err)
+		f.Write([]byte(contents))
+		f.Close()
+		_, err = w.Add(name)
+		assert.NoError(t, err)
+	}
+	when := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+	sig := &object.Signature{Name: "Keyset Maintainer", Email: "maintainer@example.org", When: when}
+	_, err = w.Commit("Add keysets", &git.CommitOptions{Author: sig, Committer: sig})
+	assert.NoError(t, err)
+
+	sizes := map[string]int64{"bafyone": 1024, "bafytwo": 512}
+	index, err := BuildIndex(r, func(cid string) (int64, error) {
+		if size, ok := sizes[cid]; ok {
+			return size, nil
+		}
+		return 0, errors.New("not found")
+	})
+	assert.NoError(t, err)
+	assert.Len(t, index, 2)
+	assert.Equal(t, IndexEntry{Path: "library/books.ks", Entries: 2, Size: 1536, Updated: index[0].Updated}, index[0])
+	assert.True(t, when.Equal(index[0].Updated))
+	assert.Equal(t, 1, index[1].Unknown)
+
+	assert.Equal(t, "# Keyset Index\n\n"+
+		"This file is generated by `ait index`, do not edit it by hand.\n\n"+
+		"| Keyset | Entries | Size | Last Update |\n"+
+		"|--------|--------:|-----:|-------------|\n"+
+		"| [library/books.ks](library/books.ks) | 2 | 1.5KB | 2026-10-01 |\n"+
+		"| [science.ks](science.ks) | 1 | 0B (1 unknown) | 2026-10-01 |\n"+
+		"| **Total** | 3 | 1.5KB (1 unknown) | |\n", FormatIndex(index))
+}
@@ -0,0 +1,85 @@
+package keysets
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/arken/ait/config"
+	"github.com/arken/ait/utils"
+)
+
+// metadataCollector runs the configured metadata command on the staged files
+// of the workspace at dir keyset entries are generated for.
+type metadataCollector struct {
+	dir      string
+	command  string
+	metadata map[string]utils.EntryMetadata
+	failures []AddFailure
+}
+
+// newMetadataCollector returns a collector of the metadata of the staged
+// files, adding to the metadata collected before when amending.
+func newMetadataCollector(dir string, amend bool) (*metadataCollector, error) {
+	c := &metadataCollector{dir: dir, command: config.Global.Metadata.Command}
+	if c.command == "" {
+		if !amend {
+			_ = os.Remove(filepath.Join(dir, utils.EntryMetadataPath))
+		}
+		return c, nil
+	}
+	c.metadata = make(map[string]utils.EntryMetadata)
+	if amend {
+		existing, err := utils.ReadEntryMetadataIn(dir)
+		if err != nil {
+			return nil, err
+		}
+		c.metadata = existing
+	}
+	return c, nil
+}
+
+// collect runs the metadata command on the staged file at path, added with
+// cid, and returns the fields it printed. Files that aren't on this machine,
+// ie handed off, are skipped.
+func (c *metadataCollector) collect(path, cid string) map[string]string {
+	if c.command == "" || !utils.FileExists(filepath.Join(c.dir, path)) {
+		return nil
+	}
+	fields, err := utils.RunMetadataCommand(c.command, filepath.Join(c.dir, path))
+	if err != nil {
+		c.failures = append(c.failures, AddFailure{Path: path, Error: err.Error()})
+		return nil
+	}
+	c.metadata[path] = utils.EntryMetadata{CID: cid, Fields: fields}
+	return fields
+}
+
+// withCollected returns the metadata of an entry: the fields given to its file
+// with ait meta, and those the metadata command collected for it. The fields
+// given with ait meta take precedence.
+func withCollected(given, collected map[string]string) map[string]string {
+	if len(collected) == 0 {
+		return given
+	}
+	fields := make(map[string]string, len(given)+len(collected))
+	for key, value := range collected {
+		fields[key] = value
+	}
+	for key, value := range given {
+		fields[key] = value
+	}
+	return fields
+}
+
+// save records the collected metadata and reports the files it couldn't be
+// collected for, which are still in the keyset.
+func (c *metadataCollector) save() error {
+	if c.command == "" {
+		return nil
+	}
+	for _, failure := range c.failures {
+		fmt.Printf("Unable to collect the metadata of %v: %v\n", failure.Path, failure.Error)
+	}
+	return utils.WriteEntryMetadataIn(c.dir, c.metadata)
+}
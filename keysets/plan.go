@@ -0,0 +1,85 @@
+package keysets
+
+import (
+	"sort"
+)
+
+// PlanFile is a file of a keyset to be replicated across a team.
+type PlanFile struct {
+	CID  string
+	Size int64
+	// Holders are the indexes of the peers already providing the file, which
+	// are preferred so nothing is downloaded again.
+	Holders []int
+}
+
+// PlanPeer is a member of the team with the space it offers, in bytes.
+type PlanPeer struct {
+	ID     string
+	Budget int64
+}
+
+// Plan is an assignment of files to the peers of a team.
+type Plan struct {
+	// Pins are the CIDs each peer should pin, by the peer's index.
+	Pins [][]string
+	// Used is how much of its budget each peer's pins take.
+	Used []int64
+	// Short are the files that couldn't be given to enough peers within
+	// their budgets, with the number of replicas they got.
+	Short map[string]int
+}
+
+// MakePlan assigns every file to replicas distinct peers without exceeding
+// their budgets. Larger files are placed first, each on the peers already
+// holding it and then on those with the most space left, which spreads the
+// data evenly. Files that don't fit replicas times are listed in Short.
+func MakePlan(files []PlanFile, peers []PlanPeer, replicas int) *Plan {
+	plan := &Plan{
+		Pins:  make([][]string, len(peers)),
+		Used:  make([]int64, len(peers)),
+		Short: make(map[string]int),
+	}
+	order := make([]int, len(files))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		return files[order[a]].Size > files[order[b]].Size
+	})
+
+	for _, i := range order {
+		file := files[i]
+		holds := make(map[int]bool, len(file.Holders))
+		for _, p := range file.Holders {
+			holds[p] = true
+		}
+		candidates := make([]int, len(peers))
+		for p := range candidates {
+			candidates[p] = p
+		}
+		sort.SliceStable(candidates, func(a, b int) bool {
+			pa, pb := candidates[a], candidates[b]
+			if holds[pa] != holds[pb] {
+				return holds[pa]
+			}
+			return peers[pa].Budget-plan.Used[pa] > peers[pb].Budget-plan.Used[pb]
+		})
+		placed := 0
+		for _, p := range candidates {
+			if placed == replicas {
+				break
+			}
+			if plan.Used[p]+file.Size > peers[p].Budget {
+				continue
+			}
+			plan.Pins[p] = append(plan.Pins[p], file.CID)
+			plan.Used[p] += file.Size
+			placed++
+		}
+		if placed < replicas {
+			plan.Short[file.CID] = placed
+		}
+	}
+	return plan
+}
@@ -0,0 +1,28 @@
+package keysets
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMakePlan(t *testing.T) {
+	peers := []PlanPeer{{"alice", 100}, {"bob", 60}, {"carol", 70}}
+	files := []PlanFile{
+		{CID: "small", Size: 10},
+		{CID: "large", Size: 50},
+		{CID: "held", Size: 20, Holders: []int{2}},
+		{CID: "huge", Size: 80},
+	}
+	plan := MakePlan(files, peers, 2)
+
+	assert.Equal(t, []string{"huge", "held"}, plan.Pins[0])
+	assert.Equal(t, []string{"large", "small"}, plan.Pins[1])
+	assert.Equal(t, []string{"large", "held"}, plan.Pins[2])
+	assert.Equal(t, []int64{100, 60, 70}, plan.Used)
+	for p, used := range plan.Used {
+		assert.LessOrEqual(t, used, peers[p].Budget)
+	}
+	// Only alice can hold the huge file, and small didn't fit twice.
+	assert.Equal(t, map[string]int{"huge": 1, "small": 1}, plan.Short)
+}
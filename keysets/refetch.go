@@ -0,0 +1,66 @@
+package keysets
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/arken/ait/ipfs"
+	"github.com/arken/ait/utils"
+)
+
+// refetchTimeout is how long the blocks of a staged file whose original is
+// gone are fetched from the network for.
+const refetchTimeout = 5 * time.Minute
+
+// isGone returns whether the file at filePath no longer exists.
+func isGone(filePath string) bool {
+	_, err := os.Stat(filePath)
+	return os.IsNotExist(err)
+}
+
+// refetchMissing returns the CID of the staged file at filePath in the
+// workspace at dir, reached through link, which no longer exists: the one it
+// was last hashed to, if a submission of the workspace holds it.
+// Its blocks the garbage collector removed are fetched back from the peers
+// replicating it and pinned, so it can be submitted again. It fails if the
+// file was never submitted or no peer provides it anymore.
+func refetchMissing(dir, link, filePath string) (string, error) {
+	cid, ok := ipfs.LastCID(filepath.Join(link, filePath))
+	if ok {
+		submitted, err := wasSubmitted(dir, cid)
+		if err != nil {
+			return "", err
+		}
+		ok = submitted
+	}
+	if !ok {
+		return "", fmt.Errorf("%v no longer exists and was never submitted, there is nothing to fetch it from", filePath)
+	}
+	fmt.Printf("%v no longer exists, fetching %v from the network...\n", filePath, cid)
+	if err := ipfs.Refetch(cid, refetchTimeout); err != nil {
+		return "", fmt.Errorf("%v no longer exists and %v", filePath, err)
+	}
+	if err := ipfs.Pin(cid); err != nil {
+		return "", err
+	}
+	return cid, ipfs.RecordPin(cid, ipfs.PinRefetched)
+}
+
+// wasSubmitted returns whether a submission recorded in the history of the
+// workspace at dir holds cid.
+func wasSubmitted(dir, cid string) (bool, error) {
+	history, err := utils.ReadHistoryIn(filepath.Join(dir, utils.HistoryPath))
+	if err != nil {
+		return false, err
+	}
+	for _, s := range history {
+		for _, entry := range s.Entries {
+			if entry.CID == cid {
+				return true, nil
+			}
+		}
+	}
+	return false, nil
+}
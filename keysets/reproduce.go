@@ -0,0 +1,166 @@
+package keysets
+
+import (
+	"bytes"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/arken/ait/config"
+	"github.com/arken/ait/ipfs"
+	"github.com/arken/ait/utils"
+)
+
+// Reproduce regenerates the keyset of the files at paths, relative to the
+// working directory, like Generate does from scratch: one entry per file in
+// the order of their paths, with the metadata given to it with ait meta and
+// collected by the metadata command. The files are only hashed, nothing is
+// written to the repository. Files that can't be hashed are left out and
+// returned.
+func Reproduce(paths []string) (keyset []byte, failures []AddFailure, err error) {
+	link, err := ipfs.LinkWorkdir()
+	if err != nil {
+		return nil, nil, err
+	}
+	fileMeta, err := utils.ReadFileMetadata()
+	if err != nil {
+		return nil, nil, err
+	}
+	// The collected metadata isn't saved, it belongs to the staged files.
+	meta := &metadataCollector{dir: ".", command: config.Global.Metadata.Command,
+		metadata: make(map[string]utils.EntryMetadata)}
+	keyset, failures, err = writeKeyset(paths, func(path string) (string, error) {
+		return ipfs.Add(filepath.Join(link, path), true)
+	}, func(path, cid string) map[string]string {
+		return withCollected(utils.MetadataFor(fileMeta, path), meta.collect(path, cid))
+	})
+	for _, failure := range meta.failures {
+		fmt.Printf("Unable to collect the metadata of %v: %v\n", failure.Path, failure.Error)
+	}
+	return keyset, failures, err
+}
+
+// writeKeyset returns the keyset of the files at paths, sorted like the
+// staged files are, with the CIDs cidOf returns and the metadata metadataOf
+// returns.
+func writeKeyset(paths []string, cidOf func(path string) (string, error),
+	metadataOf func(path, cid string) map[string]string) ([]byte, []AddFailure, error) {
+	sorted := append([]string(nil), paths...)
+	sort.Strings(sorted)
+	var entries []Entry
+	var failures []AddFailure
+	for _, path := range sorted {
+		cid, err := cidOf(path)
+		if err != nil {
+			failures = append(failures, AddFailure{Path: path, Error: err.Error()})
+			continue
+		}
+		entries = append(entries, Entry{Name: filepath.Base(path), CID: cid, Metadata: metadataOf(path, cid)})
+	}
+	var output bytes.Buffer
+	if err := Formats[DefaultFormat].Write(&output, entries); err != nil {
+		return nil, nil, err
+	}
+	return output.Bytes(), failures, nil
+}
+
+// Divergence is how a reproduced keyset differs from the submitted one.
+type Divergence struct {
+	// Line is the first line the keysets differ on, counted from 1, or 0 if
+	// they are byte-for-byte identical.
+	Line int
+	// Changed are the files listed in both under another CID.
+	Changed []ChangedEntry
+	// Missing are the entries of the submitted keyset that weren't
+	// reproduced, and Extra those only the reproduced keyset has.
+	Missing []utils.KeysetEntry
+	Extra   []utils.KeysetEntry
+}
+
+// ChangedEntry is a file whose CID wasn't reproduced.
+type ChangedEntry struct {
+	Name       string
+	Submitted  string
+	Reproduced string
+}
+
+// Identical returns whether the keysets are byte-for-byte identical.
+func (d *Divergence) Identical() bool {
+	return d.Line == 0
+}
+
+// Reordered returns whether the keysets list the same entries, only in
+// another order or with other spacing, ie when the submitted keyset was
+// amended.
+func (d *Divergence) Reordered() bool {
+	return !d.Identical() && len(d.Changed) == 0 && len(d.Missing) == 0 && len(d.Extra) == 0
+}
+
+// Compare returns how the reproduced keyset differs from the submitted one.
+func Compare(submitted, reproduced []byte) (*Divergence, error) {
+	d := &Divergence{Line: firstDifference(submitted, reproduced)}
+	if d.Identical() {
+		return d, nil
+	}
+	want, err := utils.ParseKeysetEntries(bytes.NewReader(submitted))
+	if err != nil {
+		return nil, err
+	}
+	got, err := utils.ParseKeysetEntries(bytes.NewReader(reproduced))
+	if err != nil {
+		return nil, err
+	}
+	// Entries are matched first by their whole line, then by name, so files
+	// sharing a name are told apart as long as their CIDs were reproduced.
+	unmatched := make(map[utils.KeysetEntry]int, len(got))
+	for _, entry := range got {
+		unmatched[entry]++
+	}
+	var missing []utils.KeysetEntry
+	for _, entry := range want {
+		if unmatched[entry] > 0 {
+			unmatched[entry]--
+		} else {
+			missing = append(missing, entry)
+		}
+	}
+	extraByName := make(map[string][]utils.KeysetEntry)
+	for _, entry := range got {
+		if unmatched[entry] > 0 {
+			unmatched[entry]--
+			extraByName[entry.Name] = append(extraByName[entry.Name], entry)
+		}
+	}
+	for _, entry := range missing {
+		if extras := extraByName[entry.Name]; len(extras) > 0 {
+			d.Changed = append(d.Changed, ChangedEntry{Name: entry.Name, Submitted: entry.CID, Reproduced: extras[0].CID})
+			extraByName[entry.Name] = extras[1:]
+		} else {
+			d.Missing = append(d.Missing, entry)
+		}
+	}
+	for _, entry := range got {
+		if extras := extraByName[entry.Name]; len(extras) > 0 && extras[0] == entry {
+			d.Extra = append(d.Extra, entry)
+			extraByName[entry.Name] = extras[1:]
+		}
+	}
+	return d, nil
+}
+
+// firstDifference returns the first line a and b differ on, counted from 1,
+// or 0 if they are identical.
+func firstDifference(a, b []byte) int {
+	if bytes.Equal(a, b) {
+		return 0
+	}
+	linesA := strings.SplitAfter(string(a), "\n")
+	linesB := strings.SplitAfter(string(b), "\n")
+	for i := range linesA {
+		if i >= len(linesB) || linesA[i] != linesB[i] {
+			return i + 1
+		}
+	}
+	return len(linesA) + 1
+}
@@ -0,0 +1,65 @@
+package keysets
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/arken/ait/utils"
+)
+
+func TestWriteKeyset(t *testing.T) {
+	cids := map[string]string{"b/data 1.csv": "bafyb", "a/z.txt": "bafya"}
+	fileMeta := map[string]map[string]string{"a/z.txt": {"license": "CC-BY-4.0"}}
+	keyset, failures, err := writeKeyset([]string{"b/data 1.csv", "gone.txt", "a/z.txt"},
+		func(path string) (string, error) {
+			if cid, ok := cids[path]; ok {
+				return cid, nil
+			}
+			return "", errors.New("no such file")
+		}, func(path, _ string) map[string]string {
+			return utils.MetadataFor(fileMeta, path)
+		})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "bafya  z.txt\n#  license: CC-BY-4.0\nbafyb  data-1.csv\n"; string(keyset) != want {
+		t.Errorf("expected %q, got %q", want, keyset)
+	}
+	if len(failures) != 1 || failures[0].Path != "gone.txt" {
+		t.Error("expected gone.txt to fail, got", failures)
+	}
+}
+
+func TestCompare(t *testing.T) {
+	submitted := []byte("bafya  a.txt\nbafyb  b.txt\nbafyc  c.txt\n")
+	d, err := Compare(submitted, submitted)
+	if err != nil || !d.Identical() || d.Reordered() {
+		t.Fatal("identical keysets should match, got", d, err)
+	}
+
+	d, err = Compare(submitted, []byte("bafyc  c.txt\nbafya  a.txt\nbafyb  b.txt\n"))
+	if err != nil || d.Identical() || !d.Reordered() || d.Line != 1 {
+		t.Fatal("expected the same entries in another order, got", d, err)
+	}
+
+	d, err = Compare(submitted, []byte("bafya  a.txt\nbafyx  b.txt\nbafyd  d.txt\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d.Line != 2 || d.Reordered() {
+		t.Error("expected a divergence from line 2, got", d.Line)
+	}
+	if len(d.Changed) != 1 || d.Changed[0] != (ChangedEntry{Name: "b.txt", Submitted: "bafyb", Reproduced: "bafyx"}) {
+		t.Error("expected b.txt to have changed, got", d.Changed)
+	}
+	if len(d.Missing) != 1 || d.Missing[0].Name != "c.txt" {
+		t.Error("expected c.txt to be missing, got", d.Missing)
+	}
+	if len(d.Extra) != 1 || d.Extra[0].Name != "d.txt" {
+		t.Error("expected d.txt to be extra, got", d.Extra)
+	}
+
+	if d, _ = Compare(submitted, submitted[:len(submitted)-1]); d.Line != 3 {
+		t.Error("a missing trailing newline should diverge on the last line, got", d.Line)
+	}
+}
@@ -0,0 +1,108 @@
+package keysets
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"runtime"
+	"sync"
+)
+
+// shardSize is the size of the pieces large keyset files are split into so
+// they can be parsed in parallel.
+var shardSize int64 = 64 << 20
+
+// shard is a range of whole lines of a keyset file.
+type shard struct {
+	path   string
+	offset int64
+	length int64
+}
+
+// shardKeysets splits the keyset files into shards of about shardSize,
+// breaking large files at line boundaries. Shards are in the order of paths
+// and of their offsets.
+func shardKeysets(paths []string) ([]shard, error) {
+	var shards []shard
+	for _, path := range paths {
+		file, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+		info, err := file.Stat()
+		if err != nil {
+			file.Close()
+			return nil, err
+		}
+		start := int64(0)
+		for start < info.Size() {
+			end, err := lineBoundary(file, start+shardSize, info.Size())
+			if err != nil {
+				file.Close()
+				return nil, err
+			}
+			shards = append(shards, shard{path, start, end - start})
+			start = end
+		}
+		file.Close()
+	}
+	return shards, nil
+}
+
+// lineBoundary returns the offset of the line following the one offset falls
+// in, or size if it's past the end of the file.
+func lineBoundary(file *os.File, offset, size int64) (int64, error) {
+	buf := make([]byte, 4096)
+	for offset < size {
+		n, err := file.ReadAt(buf, offset)
+		if i := bytes.IndexByte(buf[:n], '\n'); i >= 0 {
+			return offset + int64(i) + 1, nil
+		}
+		offset += int64(n)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return 0, err
+		}
+	}
+	return size, nil
+}
+
+// scanShards calls parse with the index and a reader of each shard, from a
+// worker per CPU. parse must be safe for concurrent use, ie by only storing
+// its results at the shard's index. The first error is returned.
+func scanShards(shards []shard, parse func(i int, r io.Reader) error) error {
+	jobs := make(chan int)
+	errs := make(chan error, len(shards))
+	wg := sync.WaitGroup{}
+	workers := runtime.NumCPU()
+	if workers > len(shards) {
+		workers = len(shards)
+	}
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				file, err := os.Open(shards[i].path)
+				if err != nil {
+					errs <- err
+					continue
+				}
+				err = parse(i, io.NewSectionReader(file, shards[i].offset, shards[i].length))
+				file.Close()
+				if err != nil {
+					errs <- err
+				}
+			}
+		}()
+	}
+	for i := range shards {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+	close(errs)
+	return <-errs
+}
@@ -1,44 +1,61 @@
 package keysets
 
 import (
-	"bufio"
+	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 	"strings"
+
+	"github.com/arken/ait/utils"
 )
 
 // Search checks for the existance of a file in a keyset and returns
-// its' coorisponding CID hash if found.
+// its' coorisponding CID hash if found. Keysets are streamed and large ones
+// parsed in parallel shards, so memory only grows with the matches. The CIDs
+// of a name are in the order they appear in the keysets.
 func Search(keysetPath, filePath string) (hashes map[string][]string, err error) {
-	hashes = make(map[string][]string)
-	filedata := strings.Split(filePath, "/")
-	category := filedata[0]
-
+	filedata := strings.SplitN(filePath, "/", 2)
+	if len(filedata) < 2 {
+		return nil, fmt.Errorf("expected <category>/<file>, got %q", filePath)
+	}
+	category, pattern := filedata[0], filedata[1]
+	if _, err = filepath.Match(pattern, ""); err != nil {
+		return nil, err
+	}
+
+	var paths []string
 	err = filepath.Walk(keysetPath, func(path string, info os.FileInfo, err error) error {
 		if strings.HasSuffix(path, category+".ks") {
-			file, err := os.Open(path)
-			if err != nil {
-				return err
-			}
-
-			scanner := bufio.NewScanner(file)
-
-			// Scan through the lines in the file.
-			for scanner.Scan() {
-				// Split data on white space.
-				data := strings.Fields(scanner.Text())
-
-				if matched, _ := filepath.Match(filedata[1], data[1]); matched {
-					if hashes[data[1]] == nil {
-						hashes[data[1]] = []string{}
-					}
-					hashes[data[1]] = append(hashes[data[1]], data[0])
-				}
-			}
-
+			paths = append(paths, path)
 		}
 		return nil
 	})
-
+	if err != nil {
+		return nil, err
+	}
+	shards, err := shardKeysets(paths)
+	if err != nil {
+		return nil, err
+	}
+
+	matches := make([][]utils.KeysetEntry, len(shards))
+	err = scanShards(shards, func(i int, r io.Reader) error {
+		return utils.ScanKeysetEntries(r, func(entry utils.KeysetEntry) error {
+			if matched, _ := filepath.Match(pattern, entry.Name); matched {
+				matches[i] = append(matches[i], entry)
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	hashes = make(map[string][]string)
+	for _, found := range matches {
+		for _, entry := range found {
+			hashes[entry.Name] = append(hashes[entry.Name], entry.CID)
+		}
+	}
 	return hashes, nil
 }
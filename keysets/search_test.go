@@ -0,0 +1,45 @@
+package keysets
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSearch(t *testing.T) {
+	dir := t.TempDir()
+	var library strings.Builder
+	for i := 0; i < 1000; i++ {
+		fmt.Fprintf(&library, "bafy%04d  book%04d.pdf\n", i, i%500)
+	}
+	library.WriteString("malformed line with too many fields\n")
+	assert.NoError(t, os.MkdirAll(filepath.Join(dir, "archive"), os.ModePerm))
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, "library.ks"), []byte(library.String()), 0644))
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, "archive", "library.ks"),
+		[]byte("bafyold  book0001.pdf"), 0644))
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, "science.ks"),
+		[]byte("bafydata  book0001.pdf\n"), 0644))
+
+	// Split the keysets in many shards, cutting through lines.
+	defer func(size int64) { shardSize = size }(shardSize)
+	shardSize = 100
+
+	hashes, err := Search(dir, "library/book000[12].pdf")
+	assert.NoError(t, err)
+	assert.Equal(t, map[string][]string{
+		"book0001.pdf": {"bafyold", "bafy0001", "bafy0501"},
+		"book0002.pdf": {"bafy0002", "bafy0502"},
+	}, hashes)
+
+	hashes, err = Search(dir, "library/*")
+	assert.NoError(t, err)
+	assert.Len(t, hashes, 500)
+
+	_, err = Search(dir, "library")
+	assert.Error(t, err)
+}
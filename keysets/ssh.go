@@ -0,0 +1,75 @@
+package keysets
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/arken/ait/config"
+	"github.com/arken/ait/utils"
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	gitssh "github.com/go-git/go-git/v5/plumbing/transport/ssh"
+)
+
+// sshKeyNames are the private keys looked for in ~/.ssh, in the order ssh
+// tries them.
+var sshKeyNames = []string{"id_ed25519", "id_ecdsa", "id_rsa"}
+
+// gitTransport returns the URL to reach the repository at url with, and the
+// SSH key to authenticate with when it's reached over SSH. Without a usable
+// key ssh-agent is used if it runs, otherwise GitHub repositories are reached
+// over HTTPS instead, with the credentials gitAuth finds.
+func gitTransport(url string) (string, transport.AuthMethod) {
+	endpoint, err := transport.NewEndpoint(url)
+	if err != nil || endpoint.Protocol != "ssh" {
+		return url, nil
+	}
+	user := endpoint.User
+	if user == "" {
+		user = "git"
+	}
+	if auth := sshKeyAuth(user); auth != nil {
+		return url, auth
+	}
+	if os.Getenv("SSH_AUTH_SOCK") != "" {
+		return url, nil
+	}
+	if endpoint.Host != "github.com" {
+		return url, nil
+	}
+	https := "https://github.com/" + strings.TrimPrefix(endpoint.Path, "/")
+	fmt.Printf("No SSH key is available for %v, using %v instead.\n", url, https)
+	return https, nil
+}
+
+// sshKeyAuth returns the SSH key configured with Git.SSHKeyPath, or the first
+// one of ~/.ssh that can be read, or nil if there's none. Keys protected by
+// a passphrase are left to ssh-agent.
+func sshKeyAuth(user string) transport.AuthMethod {
+	var paths []string
+	if config.Global.Git.SSHKeyPath != "" {
+		paths = []string{utils.ExpandHome(config.Global.Git.SSHKeyPath)}
+	} else if home, err := os.UserHomeDir(); err == nil {
+		for _, name := range sshKeyNames {
+			paths = append(paths, filepath.Join(home, ".ssh", name))
+		}
+	}
+	for _, path := range paths {
+		if !utils.FileExists(path) {
+			if config.Global.Git.SSHKeyPath != "" {
+				fmt.Printf("The SSH key %v doesn't exist.\n", path)
+			}
+			continue
+		}
+		auth, err := gitssh.NewPublicKeysFromFile(user, path, "")
+		if err != nil {
+			if config.Global.Git.SSHKeyPath != "" {
+				fmt.Printf("Unable to use the SSH key %v: %v\n", path, err)
+			}
+			continue
+		}
+		return auth
+	}
+	return nil
+}
@@ -0,0 +1,52 @@
+package keysets
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/arken/ait/config"
+)
+
+func TestGitTransport(t *testing.T) {
+	home := t.TempDir()
+	defer os.Setenv("HOME", os.Getenv("HOME"))
+	defer os.Setenv("SSH_AUTH_SOCK", os.Getenv("SSH_AUTH_SOCK"))
+	os.Setenv("HOME", home)
+	os.Unsetenv("SSH_AUTH_SOCK")
+
+	https := "https://github.com/arken/core-keyset.git"
+	if url, auth := gitTransport(https); url != https || auth != nil {
+		t.Error("HTTPS URLs should be left alone, got", url, auth)
+	}
+	if url, auth := gitTransport("git@github.com:arken/core-keyset.git"); url != https || auth != nil {
+		t.Error("without a key, GitHub should be reached over HTTPS, got", url, auth)
+	}
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(home, ".ssh", "id_rsa")
+	if err = os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		t.Fatal(err)
+	}
+	encoded := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})
+	if err = ioutil.WriteFile(path, encoded, 0600); err != nil {
+		t.Fatal(err)
+	}
+	ssh := "git@github.com:arken/core-keyset.git"
+	if url, auth := gitTransport(ssh); url != ssh || auth == nil {
+		t.Error("the key of ~/.ssh should be used, got", url, auth)
+	}
+	config.Global.Git.SSHKeyPath = filepath.Join(home, "missing")
+	defer func() { config.Global.Git.SSHKeyPath = "" }()
+	if _, auth := gitTransport(ssh); auth != nil {
+		t.Error("only the configured key should be used, got", auth)
+	}
+}
@@ -0,0 +1,152 @@
+package keysets
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/arken/ait/utils"
+)
+
+// UpdateResult counts the changes Update made to a keyset.
+type UpdateResult struct {
+	Added   int
+	Removed int
+	// Kept is the number of entries left as they were because they weren't
+	// submitted from this workspace, ie those of other contributors.
+	Kept int
+	// Annotated is the number of entries still staged whose metadata changed.
+	Annotated int
+}
+
+// Changed returns whether any entry was added, removed or annotated.
+func (r UpdateResult) Changed() bool {
+	return r.Added > 0 || r.Removed > 0 || r.Annotated > 0
+}
+
+// Update writes the keyset at existing to w, updated to match the keyset of the
+// staged files at staged: the entries in submitted, the CIDs this workspace
+// submitted to it before, that aren't staged anymore are removed, and staged
+// files it misses are appended. Staged entries carry their metadata from the
+// keyset at staged. Every other line is kept as it was, so the entries of
+// other contributors and any comments are preserved.
+func Update(existing, staged string, submitted map[string]bool, w io.Writer) (UpdateResult, error) {
+	var result UpdateResult
+	entries, err := utils.ReadKeysetEntries(staged)
+	if err != nil {
+		return result, err
+	}
+	stagedMeta, err := readKeysetMetadata(staged)
+	if err != nil {
+		return result, err
+	}
+	existingMeta, err := readKeysetMetadata(existing)
+	if err != nil {
+		return result, err
+	}
+	stagedNames := make(map[string]string, len(entries))
+	for _, entry := range entries {
+		stagedNames[entry.CID] = entry.Name
+	}
+
+	file, err := os.Open(existing)
+	if err != nil {
+		return result, err
+	}
+	defer file.Close()
+	present := make(map[string]bool)
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 4096), utils.MaxKeysetLine)
+	// replaced is set while skipping the metadata lines of an entry that was
+	// removed or written with its staged metadata.
+	replaced := false
+	for scanner.Scan() {
+		line := scanner.Text()
+		if replaced && utils.IsKeysetMetadataLine(line) {
+			continue
+		}
+		replaced = false
+		fields := strings.Fields(line)
+		if len(fields) != 2 || strings.HasPrefix(line, "#") {
+			if _, err = fmt.Fprintln(w, line); err != nil {
+				return result, err
+			}
+			continue
+		}
+		cid := fields[0]
+		name, isStaged := stagedNames[cid]
+		if submitted[cid] && !isStaged {
+			result.Removed++
+			replaced = true
+			continue
+		}
+		if !submitted[cid] && !isStaged {
+			result.Kept++
+		}
+		present[cid] = true
+		if _, err = fmt.Fprintln(w, line); err != nil {
+			return result, err
+		}
+		if isStaged {
+			if !sameMetadata(existingMeta[fields[1]], stagedMeta[name]) {
+				result.Annotated++
+			}
+			if err = writeMetadata(w, stagedMeta[name]); err != nil {
+				return result, err
+			}
+			replaced = true
+		}
+	}
+	if err = scanner.Err(); err != nil {
+		return result, err
+	}
+	for _, entry := range entries {
+		if present[entry.CID] {
+			continue
+		}
+		present[entry.CID] = true
+		result.Added++
+		if _, err = fmt.Fprintln(w, getKeySetLine(entry.Name, entry.CID)); err != nil {
+			return result, err
+		}
+		if err = writeMetadata(w, stagedMeta[entry.Name]); err != nil {
+			return result, err
+		}
+	}
+	return result, nil
+}
+
+// readKeysetMetadata reads the metadata of the entries of the keyset at path.
+func readKeysetMetadata(path string) (map[string]map[string]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return utils.ParseKeysetMetadata(file)
+}
+
+// writeMetadata writes the metadata lines of the entry written last to w.
+func writeMetadata(w io.Writer, fields map[string]string) error {
+	for _, line := range utils.KeysetMetadataLines(fields) {
+		if _, err := fmt.Fprintln(w, line); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// sameMetadata returns whether a and b hold the same fields.
+func sameMetadata(a, b map[string]string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for key, value := range a {
+		if other, ok := b[key]; !ok || other != value {
+			return false
+		}
+	}
+	return true
+}
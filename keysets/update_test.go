@@ -0,0 +1,85 @@
+package keysets
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestUpdate(t *testing.T) {
+	dir := t.TempDir()
+	existing := filepath.Join(dir, "existing.ks")
+	staged := filepath.Join(dir, "staged.ks")
+	err := ioutil.WriteFile(existing, []byte("# Survey data\n"+
+		"QmOther  theirs.csv\n"+
+		"QmKept  kept.csv\n"+
+		"QmGone  deleted.csv\n"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = ioutil.WriteFile(staged, []byte("QmKept  kept.csv\nQmNew  new.csv\n"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var out strings.Builder
+	submitted := map[string]bool{"QmKept": true, "QmGone": true}
+	result, err := Update(existing, staged, submitted, &out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result != (UpdateResult{Added: 1, Removed: 1, Kept: 1}) || !result.Changed() {
+		t.Errorf("expected 1 added, 1 removed and 1 kept, got %+v", result)
+	}
+	expected := "# Survey data\nQmOther  theirs.csv\nQmKept  kept.csv\nQmNew" + delimiter + "new.csv\n"
+	if out.String() != expected {
+		t.Errorf("expected\n%v\ngot\n%v", expected, out.String())
+	}
+
+	// Entries of other contributors are kept even if they aren't staged, and
+	// nothing changes when the keyset already matches.
+	out.Reset()
+	if err = ioutil.WriteFile(existing, []byte(expected), 0644); err != nil {
+		t.Fatal(err)
+	}
+	result, err = Update(existing, staged, map[string]bool{"QmKept": true, "QmNew": true}, &out)
+	if err != nil || result.Changed() || out.String() != expected {
+		t.Errorf("expected no change, got %+v, %v:\n%v", result, err, out.String())
+	}
+}
+
+func TestUpdateMetadata(t *testing.T) {
+	dir := t.TempDir()
+	existing := filepath.Join(dir, "existing.ks")
+	staged := filepath.Join(dir, "staged.ks")
+	err := ioutil.WriteFile(existing, []byte("QmOther  theirs.csv\n"+
+		"#  license: MIT\n"+
+		"QmKept  kept.csv\n"+
+		"#  license: MIT\n"+
+		"QmGone  deleted.csv\n"+
+		"#  tags: [old]\n"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = ioutil.WriteFile(staged, []byte("QmKept  kept.csv\n#  license: CC-BY-4.0\n"+
+		"QmNew  new.csv\n#  tags: [survey, raw]\n"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var out strings.Builder
+	submitted := map[string]bool{"QmKept": true, "QmGone": true}
+	result, err := Update(existing, staged, submitted, &out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result != (UpdateResult{Added: 1, Removed: 1, Kept: 1, Annotated: 1}) {
+		t.Errorf("expected 1 added, 1 removed, 1 kept and 1 annotated, got %+v", result)
+	}
+	expected := "QmOther  theirs.csv\n#  license: MIT\nQmKept  kept.csv\n#  license: CC-BY-4.0\n" +
+		"QmNew" + delimiter + "new.csv\n#  tags: [survey, raw]\n"
+	if out.String() != expected {
+		t.Errorf("expected\n%v\ngot\n%v", expected, out.String())
+	}
+}
@@ -0,0 +1,188 @@
+package keysets
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/arken/ait/ipfs"
+	"github.com/arken/ait/utils"
+
+	"github.com/go-git/go-billy/v5/osfs"
+	cid "github.com/ipfs/go-cid"
+)
+
+// ValidateOptions selects the checks Validate runs besides those of the
+// syntax of each entry, duplicates and file name collisions.
+type ValidateOptions struct {
+	// AllowV0 accepts CIDv0 entries, only CIDv1 ones are otherwise, as ait
+	// adds files with.
+	AllowV0 bool
+	// MaxSize is the largest an entry may be, in bytes, sizes aren't checked
+	// when it's 0. SizeOf returns the size of an entry from its CID.
+	MaxSize int64
+	SizeOf  func(cid string) (int64, error)
+	// Providers returns how many peers provide a CID, entries must have at
+	// least one when it's set.
+	Providers func(cid string) (int, error)
+}
+
+// Problem is an issue found at a line of a keyset.
+type Problem struct {
+	Path    string `json:"path"`
+	Line    int    `json:"line"`
+	Message string `json:"message"`
+}
+
+func (p Problem) String() string {
+	return fmt.Sprintf("%v:%d: %v", p.Path, p.Line, p.Message)
+}
+
+// Validation is the outcome of validating keysets.
+type Validation struct {
+	Keysets  int       `json:"keysets"`
+	Entries  int       `json:"entries"`
+	Problems []Problem `json:"problems"`
+}
+
+// location is where an entry was first read.
+type location struct {
+	path string
+	line int
+	name string
+	cid  string
+}
+
+// FindKeysetFiles returns the paths of the keyset files in the directory dir,
+// a keyset repository, in order and relative to it.
+func FindKeysetFiles(dir string) ([]string, error) {
+	paths, err := findKeysets(osfs.New(dir), "")
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(paths)
+	return paths, nil
+}
+
+// Validate checks every entry of the keysets at paths, which are reported
+// relative to dir. A CID listed twice is a duplicate even across keysets,
+// while file names only collide within a keyset, as they're pulled to a
+// directory of their own.
+func Validate(dir string, paths []string, opts ValidateOptions) (*Validation, error) {
+	v := &Validation{Keysets: len(paths), Problems: []Problem{}}
+	seen := make(map[string]location)
+	for _, path := range paths {
+		if err := v.validateKeyset(dir, path, seen, opts); err != nil {
+			return nil, err
+		}
+	}
+	return v, nil
+}
+
+// validateKeyset checks the entries of a keyset, recording the CIDs it lists
+// in seen.
+func (v *Validation) validateKeyset(dir, path string, seen map[string]location, opts ValidateOptions) error {
+	file, err := os.Open(filepath.Join(dir, path))
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	report := func(line int, format string, args ...interface{}) {
+		v.Problems = append(v.Problems, Problem{Path: path, Line: line, Message: fmt.Sprintf(format, args...)})
+	}
+	names := make(map[string]location)
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), utils.MaxKeysetLine)
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := scanner.Text()
+		fields := strings.Fields(text)
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+		if len(fields) != 2 {
+			report(line, "expected a CID and a file name, found %d field(s)", len(fields))
+			continue
+		}
+		v.Entries++
+		entry := location{path: path, line: line, cid: fields[0], name: fields[1]}
+		if msg := checkCID(entry.cid, opts.AllowV0); msg != "" {
+			report(line, "%v", msg)
+			continue
+		}
+		if msg := checkName(entry.name); msg != "" {
+			report(line, "%v", msg)
+		}
+
+		key := ipfs.NormalizeCID(entry.cid)
+		if first, ok := seen[key]; ok {
+			if first.path == path {
+				report(line, "duplicate entry, %v is already listed at line %d", entry.cid, first.line)
+			} else {
+				report(line, "duplicate entry, %v is already listed in %v at line %d", entry.cid, first.path, first.line)
+			}
+		} else {
+			seen[key] = entry
+		}
+		folded := strings.ToLower(entry.name)
+		if first, ok := names[folded]; ok {
+			switch {
+			case first.name != entry.name:
+				report(line, "file name %v collides with %v at line %d on case-insensitive file systems",
+					entry.name, first.name, first.line)
+			case ipfs.NormalizeCID(first.cid) != key:
+				report(line, "file name %v is already used at line %d for another CID", entry.name, first.line)
+			}
+		} else {
+			names[folded] = entry
+		}
+
+		if opts.MaxSize > 0 && opts.SizeOf != nil {
+			size, err := opts.SizeOf(entry.cid)
+			if err != nil {
+				report(line, "unable to look up the size of %v: %v", entry.name, err)
+			} else if size > opts.MaxSize {
+				report(line, "%v is %v, over the limit of %v", entry.name,
+					utils.FormatByteSize(size), utils.FormatByteSize(opts.MaxSize))
+			}
+		}
+		if opts.Providers != nil {
+			if count, err := opts.Providers(entry.cid); err != nil || count == 0 {
+				report(line, "no provider was found for %v (%v)", entry.name, entry.cid)
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		report(line+1, "unable to read the keyset further: %v", err)
+	}
+	return nil
+}
+
+// checkCID returns what's wrong with the CID of an entry, if anything.
+func checkCID(hash string, allowV0 bool) string {
+	c, err := cid.Decode(hash)
+	if err != nil {
+		return fmt.Sprintf("invalid CID %q: %v", hash, err)
+	}
+	if c.Version() == 0 && !allowV0 {
+		return fmt.Sprintf("%v is a CIDv0, entries are expected to be CIDv1", hash)
+	}
+	return ""
+}
+
+// checkName returns what's wrong with the file name of an entry, if anything.
+// Entries are pulled by name, so it can't lead out of their directory.
+func checkName(name string) string {
+	if strings.IndexFunc(name, unicode.IsControl) >= 0 {
+		return fmt.Sprintf("file name %q holds control characters", name)
+	}
+	if name == "." || name == ".." || strings.ContainsAny(name, `/\`) {
+		return fmt.Sprintf("file name %v isn't a plain file name", name)
+	}
+	return ""
+}
@@ -0,0 +1,72 @@
+package keysets
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	cid "github.com/ipfs/go-cid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidate(t *testing.T) {
+	dir := t.TempDir()
+	survey, err := cid.Decode(surveyCID)
+	assert.NoError(t, err)
+	surveyV1 := cid.NewCidV1(survey.Type(), survey.Hash()).String()
+	sum := func(data string) string {
+		c, err := cid.Prefix{Version: 1, Codec: cid.Raw, MhType: 0x12, MhLength: -1}.Sum([]byte(data))
+		assert.NoError(t, err)
+		return c.String()
+	}
+	assert.NoError(t, os.MkdirAll(filepath.Join(dir, "library"), 0755))
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, "library", "books.ks"), []byte(
+		readsCID+"  reads.fastq\n#  license: CC-BY-4.0\n\n"+
+			"not-a-cid  broken.txt\n"+
+			surveyCID+"  survey.csv\n"+
+			surveyV1+"  copy.csv\n"+
+			sum("a")+"  READS.fastq\n"+
+			sum("b")+"  ..\n"+
+			"too many fields\n"), 0644))
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, "other.ks"), []byte(readsCID+"  reads.fastq\n"), 0644))
+	assert.NoError(t, os.MkdirAll(filepath.Join(dir, ".git"), 0755))
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, ".git", "ignored.ks"), []byte("x\n"), 0644))
+
+	paths, err := FindKeysetFiles(dir)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"library/books.ks", "other.ks"}, paths)
+
+	v, err := Validate(dir, paths, ValidateOptions{AllowV0: true})
+	assert.NoError(t, err)
+	assert.Equal(t, 2, v.Keysets)
+	assert.Equal(t, 7, v.Entries)
+	lines := make(map[string]int)
+	for _, p := range v.Problems {
+		lines[fmt.Sprintf("%v:%d", p.Path, p.Line)]++
+	}
+	assert.Equal(t, map[string]int{
+		"library/books.ks:4": 1, // invalid CID
+		"library/books.ks:6": 1, // the CIDv0 of line 5 in version 1
+		"library/books.ks:7": 1, // case-insensitive collision
+		"library/books.ks:8": 1, // not a plain file name
+		"library/books.ks:9": 1, // too many fields
+		"other.ks:1":         1, // listed in books.ks already
+	}, lines)
+
+	v, err = Validate(dir, []string{"other.ks"}, ValidateOptions{
+		MaxSize: 10,
+		SizeOf:  func(string) (int64, error) { return 11, nil },
+		Providers: func(string) (int, error) {
+			return 0, errors.New("routing: not found")
+		},
+	})
+	assert.NoError(t, err)
+	assert.Len(t, v.Problems, 2)
+
+	v, err = Validate(dir, []string{filepath.Join("library", "books.ks")}, ValidateOptions{})
+	assert.NoError(t, err)
+	assert.Contains(t, v.Problems[1].Message, "CIDv0")
+}
@@ -0,0 +1,72 @@
+package keysets
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/go-git/go-git/v5"
+	"golang.org/x/crypto/openpgp"
+)
+
+// Verification policies for keyset repositories.
+const (
+	// VerifyOff never checks signatures.
+	VerifyOff = "off"
+	// VerifyWarn reports unsigned or untrusted keysets but still uses them.
+	VerifyWarn = "warn"
+	// VerifyRequire refuses to use unsigned or untrusted keysets.
+	VerifyRequire = "require"
+)
+
+// VerifyHead checks that the commit at the HEAD of the repository is signed by
+// one of the keys in the given armored keyrings and returns the identity of
+// the signer. A signed HEAD vouches for every keyset file it contains.
+func VerifyHead(r *git.Repository, keyrings []string) (string, error) {
+	if len(keyrings) == 0 {
+		return "", errors.New("no trusted keys are configured")
+	}
+	ref, err := r.Head()
+	if err != nil {
+		return "", err
+	}
+	commit, err := r.CommitObject(ref.Hash())
+	if err != nil {
+		return "", err
+	}
+	if commit.PGPSignature == "" {
+		return "", fmt.Errorf("commit %v is not signed", ref.Hash().String()[:8])
+	}
+	// Each keyring is a separate armored block, which the openpgp reader
+	// can't parse as one.
+	for _, keyring := range keyrings {
+		entity, err := commit.Verify(keyring)
+		if err != nil {
+			continue
+		}
+		return identity(entity), nil
+	}
+	return "", fmt.Errorf("commit %v is not signed by a trusted key", ref.Hash().String()[:8])
+}
+
+// KeyIdentity parses an armored PGP public key and returns the identity it
+// was issued to, so that keys can be checked before they're trusted.
+func KeyIdentity(armored string) (string, error) {
+	entities, err := openpgp.ReadArmoredKeyRing(strings.NewReader(armored))
+	if err != nil {
+		return "", err
+	}
+	if len(entities) == 0 {
+		return "", errors.New("no public key found")
+	}
+	return identity(entities[0]), nil
+}
+
+// identity names a key by one of its user IDs, or its fingerprint if it has
+// none.
+func identity(entity *openpgp.Entity) string {
+	for name := range entity.Identities {
+		return name
+	}
+	return fmt.Sprintf("%X", entity.PrimaryKey.Fingerprint)
+}
@@ -0,0 +1,60 @@
+package keysets
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/go-git/go-billy/v5/memfs"
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/storage/memory"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/openpgp"
+	"golang.org/x/crypto/openpgp/armor"
+)
+
+func armoredPublicKey(t *testing.T, entity *openpgp.Entity) string {
+	buf := new(bytes.Buffer)
+	w, err := armor.Encode(buf, openpgp.PublicKeyType, nil)
+	assert.NoError(t, err)
+	assert.NoError(t, entity.Serialize(w))
+	assert.NoError(t, w.Close())
+	return buf.String()
+}
+
+func TestVerifyHead(t *testing.T) {
+	maintainer, err := openpgp.NewEntity("Keyset Maintainer", "", "maintainer@example.org", nil)
+	assert.NoError(t, err)
+	stranger, err := openpgp.NewEntity("Stranger", "", "stranger@example.org", nil)
+	assert.NoError(t, err)
+
+	fs := memfs.New()
+	r, err := git.Init(memory.NewStorage(), fs)
+	assert.NoError(t, err)
+	w, err := r.Worktree()
+	assert.NoError(t, err)
+	f, err := fs.Create("library.ks")
+	assert.NoError(t, err)
+	f.Write([]byte("bafyone  book.pdf\n"))
+	f.Close()
+	_, err = w.Add("library.ks")
+	assert.NoError(t, err)
+	sig := &object.Signature{Name: "Keyset Maintainer", Email: "maintainer@example.org", When: time.Now()}
+	_, err = w.Commit("unsigned", &git.CommitOptions{Author: sig})
+	assert.NoError(t, err)
+
+	_, err = VerifyHead(r, []string{armoredPublicKey(t, maintainer)})
+	assert.Error(t, err)
+
+	_, err = w.Commit("signed", &git.CommitOptions{Author: sig, SignKey: maintainer})
+	assert.NoError(t, err)
+	signer, err := VerifyHead(r, []string{armoredPublicKey(t, stranger), armoredPublicKey(t, maintainer)})
+	assert.NoError(t, err)
+	assert.Contains(t, signer, "maintainer@example.org")
+
+	_, err = VerifyHead(r, []string{armoredPublicKey(t, stranger)})
+	assert.Error(t, err)
+	_, err = VerifyHead(r, nil)
+	assert.Error(t, err)
+}
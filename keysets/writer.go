@@ -0,0 +1,173 @@
+package keysets
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/arken/ait/utils"
+)
+
+// DefaultFormat is the format keyset repositories read, a CID and a name per
+// line with the metadata of the entry commented out after it.
+const DefaultFormat = "ksv"
+
+// Entry is a file listed in a keyset, by name and CID, with its metadata.
+type Entry struct {
+	Name     string            `json:"name"`
+	CID      string            `json:"cid"`
+	Metadata map[string]string `json:"metadata,omitempty"`
+}
+
+// Writer writes the entries of a keyset in one format.
+type Writer interface {
+	// Extension is the extension of the files written, ie ".ks".
+	Extension() string
+	// Write writes the entries to w, in the order given.
+	Write(w io.Writer, entries []Entry) error
+}
+
+// Formats are the keyset writers, by the name they're selected with.
+var Formats = map[string]Writer{
+	"ksv":  ksvWriter{},
+	"json": jsonWriter{},
+	"csv":  csvWriter{},
+	"car":  carWriter{},
+}
+
+// FormatWriter returns the writer of the named format.
+func FormatWriter(format string) (Writer, error) {
+	if w, ok := Formats[format]; ok {
+		return w, nil
+	}
+	names := make([]string, 0, len(Formats))
+	for name := range Formats {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return nil, fmt.Errorf("unknown keyset format %q, expected one of %v", format, strings.Join(names, ", "))
+}
+
+// WithExtension returns the keyset name given with the extension of w rather
+// than the one of keysets, ie "survey.json" for "survey.ks".
+func WithExtension(name string, w Writer) string {
+	return strings.TrimSuffix(name, Formats[DefaultFormat].Extension()) + w.Extension()
+}
+
+// ReadEntries returns the entries of the keyset at ksPath with their metadata.
+func ReadEntries(ksPath string) ([]Entry, error) {
+	listed, err := utils.ReadKeysetEntries(ksPath)
+	if err != nil {
+		return nil, err
+	}
+	file, err := os.Open(ksPath)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	metadata, err := utils.ParseKeysetMetadata(file)
+	if err != nil {
+		return nil, err
+	}
+	entries := make([]Entry, len(listed))
+	for i, entry := range listed {
+		entries[i] = Entry{Name: entry.Name, CID: entry.CID, Metadata: metadata[entry.Name]}
+	}
+	return entries, nil
+}
+
+// Export writes the keyset at ksPath to dest with w. The keyset is left as it
+// is, so it can still be amended.
+func Export(ksPath, dest string, w Writer) error {
+	entries, err := ReadEntries(ksPath)
+	if err != nil {
+		return err
+	}
+	if err = os.MkdirAll(filepath.Dir(dest), os.ModePerm); err != nil {
+		return err
+	}
+	file, err := os.Create(dest)
+	if err != nil {
+		return err
+	}
+	if err = w.Write(file, entries); err != nil {
+		cleanup(file)
+		return err
+	}
+	return file.Close()
+}
+
+// ksvWriter writes keysets as repositories read them.
+type ksvWriter struct{}
+
+func (ksvWriter) Extension() string {
+	return ".ks"
+}
+
+func (ksvWriter) Write(w io.Writer, entries []Entry) error {
+	var output strings.Builder
+	for _, entry := range entries {
+		output.WriteString(getKeySetLine(entry.Name, entry.CID) + "\n")
+		writeMetadataLines(&output, entry.Metadata)
+	}
+	_, err := io.WriteString(w, output.String())
+	return err
+}
+
+// jsonWriter writes keysets as an array of entries.
+type jsonWriter struct{}
+
+func (jsonWriter) Extension() string {
+	return ".json"
+}
+
+func (jsonWriter) Write(w io.Writer, entries []Entry) error {
+	if entries == nil {
+		entries = []Entry{}
+	}
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(entries)
+}
+
+// csvWriter writes keysets as a table with a header, the metadata keys of
+// every entry being columns after the CID and the name.
+type csvWriter struct{}
+
+func (csvWriter) Extension() string {
+	return ".csv"
+}
+
+func (csvWriter) Write(w io.Writer, entries []Entry) error {
+	seen := make(map[string]bool)
+	var keys []string
+	for _, entry := range entries {
+		for key := range entry.Metadata {
+			if !seen[key] {
+				seen[key] = true
+				keys = append(keys, key)
+			}
+		}
+	}
+	sort.Strings(keys)
+	out := csv.NewWriter(w)
+	if err := out.Write(append([]string{"cid", "name"}, keys...)); err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		record := []string{entry.CID, entry.Name}
+		for _, key := range keys {
+			record = append(record, entry.Metadata[key])
+		}
+		if err := out.Write(record); err != nil {
+			return err
+		}
+	}
+	out.Flush()
+	return out.Error()
+}
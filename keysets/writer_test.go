@@ -0,0 +1,105 @@
+package keysets
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/csv"
+	"encoding/json"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	cid "github.com/ipfs/go-cid"
+	merkledag "github.com/ipfs/go-merkledag"
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	surveyCID = "QmbFMke1KXqnYyBBWxB74N4c5SBnJMVAiMNRcGu6x1AwQH"
+	readsCID  = "bafkreihdwdcefgh4dqkjv67uzcmw7ojee6xedzdetojuzjevtenxquvyku"
+)
+
+func TestExport(t *testing.T) {
+	dir := t.TempDir()
+	ksPath := filepath.Join(dir, "survey.ks")
+	keyset := surveyCID + "  survey.csv\n#  license: CC-BY-4.0\n#  tags: [genomics, rna]\n" +
+		readsCID + "  reads.fastq\n"
+	assert.NoError(t, ioutil.WriteFile(ksPath, []byte(keyset), 0644))
+
+	// The keyset comes out as it went in.
+	assert.NoError(t, Export(ksPath, filepath.Join(dir, "copy.ks"), Formats["ksv"]))
+	exported, err := ioutil.ReadFile(filepath.Join(dir, "copy.ks"))
+	assert.NoError(t, err)
+	assert.Equal(t, keyset, string(exported))
+
+	assert.NoError(t, Export(ksPath, filepath.Join(dir, "survey.json"), Formats["json"]))
+	exported, err = ioutil.ReadFile(filepath.Join(dir, "survey.json"))
+	assert.NoError(t, err)
+	var entries []Entry
+	assert.NoError(t, json.Unmarshal(exported, &entries))
+	assert.Equal(t, []Entry{
+		{Name: "survey.csv", CID: surveyCID, Metadata: map[string]string{"license": "CC-BY-4.0", "tags": "genomics,rna"}},
+		{Name: "reads.fastq", CID: readsCID},
+	}, entries)
+
+	assert.NoError(t, Export(ksPath, filepath.Join(dir, "survey.csv"), Formats["csv"]))
+	exported, err = ioutil.ReadFile(filepath.Join(dir, "survey.csv"))
+	assert.NoError(t, err)
+	records, err := csv.NewReader(bytes.NewReader(exported)).ReadAll()
+	assert.NoError(t, err)
+	assert.Equal(t, [][]string{
+		{"cid", "name", "license", "tags"},
+		{surveyCID, "survey.csv", "CC-BY-4.0", "genomics,rna"},
+		{readsCID, "reads.fastq", "", ""},
+	}, records)
+}
+
+func TestCarWriter(t *testing.T) {
+	var buf bytes.Buffer
+	entries := []Entry{{Name: "survey.csv", CID: surveyCID}, {Name: "raw/reads.fastq", CID: readsCID},
+		{Name: "survey.csv", CID: readsCID}}
+	assert.NoError(t, carWriter{}.Write(&buf, entries))
+
+	// The header, then the root and the directory it holds.
+	var sections [][]byte
+	data := buf.Bytes()
+	for len(data) > 0 {
+		length, n := binary.Uvarint(data)
+		assert.True(t, n > 0 && n+int(length) <= len(data))
+		sections = append(sections, data[n:n+int(length)])
+		data = data[n+int(length):]
+	}
+	assert.Len(t, sections, 3)
+	nodes := make([]*merkledag.ProtoNode, 0, 2)
+	for _, section := range sections[1:] {
+		n, c, err := cid.CidFromBytes(section)
+		assert.NoError(t, err)
+		node, err := merkledag.DecodeProtobuf(section[n:])
+		assert.NoError(t, err)
+		assert.Equal(t, c, node.Cid())
+		nodes = append(nodes, node)
+	}
+	assert.Contains(t, string(sections[0]), string(nodes[0].Cid().Bytes()))
+	links := nodes[0].Links()
+	assert.Len(t, links, 2)
+	assert.Equal(t, "raw", links[0].Name)
+	assert.Equal(t, nodes[1].Cid(), links[0].Cid)
+	assert.Equal(t, "survey.csv", links[1].Name)
+	assert.Equal(t, surveyCID, links[1].Cid.String())
+	assert.Equal(t, "reads.fastq", nodes[1].Links()[0].Name)
+
+	// The same keyset always gets the same root.
+	var again bytes.Buffer
+	assert.NoError(t, carWriter{}.Write(&again, entries))
+	assert.Equal(t, buf.Bytes(), again.Bytes())
+	assert.Error(t, carWriter{}.Write(&again, []Entry{{Name: "bad", CID: "not-a-cid"}}))
+}
+
+func TestFormatWriter(t *testing.T) {
+	w, err := FormatWriter("csv")
+	assert.NoError(t, err)
+	assert.Equal(t, ".csv", w.Extension())
+	assert.Equal(t, "survey.csv", WithExtension("survey.ks", w))
+	_, err = FormatWriter("xml")
+	assert.EqualError(t, err, `unknown keyset format "xml", expected one of car, csv, json, ksv`)
+}
@@ -0,0 +1,82 @@
+package notify
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/arken/ait/config"
+	"github.com/arken/ait/platform"
+	"github.com/arken/ait/utils"
+)
+
+// Notification is a message sent to the user through the configured
+// channels.
+type Notification struct {
+	Title   string    `json:"title"`
+	Message string    `json:"message"`
+	Time    time.Time `json:"time"`
+}
+
+// Send delivers the notification through every configured channel: the
+// webhook, the log file and the desktop. Every channel is attempted and the
+// first error is returned.
+func Send(title, message string) error {
+	n := Notification{Title: title, Message: message, Time: time.Now()}
+	conf := config.Global.Notify
+	var errs []error
+	if conf.Webhook != "" {
+		errs = append(errs, webhook(conf.Webhook, n))
+	}
+	if conf.Log != "" {
+		errs = append(errs, appendLog(conf.Log, n))
+	}
+	if conf.Desktop {
+		errs = append(errs, Desktop(title, message))
+	}
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// webhook posts the notification as JSON to url.
+func webhook(url string, n Notification) error {
+	body, err := json.Marshal(n)
+	if err != nil {
+		return err
+	}
+	client := utils.NewClient(20 * time.Second)
+	resp, err := client.Post(url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("notification webhook returned %v", resp.Status)
+	}
+	return nil
+}
+
+// appendLog adds the notification as a line to the log file at path.
+func appendLog(path string, n Notification) error {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	_, err = fmt.Fprintf(file, "%v  %v: %v\n", n.Time.Format(time.RFC3339), n.Title,
+		strings.ReplaceAll(n.Message, "\n", " "))
+	return err
+}
+
+// Desktop shows a notification on the user's desktop with notify-send on
+// Linux, osascript on macOS and a PowerShell toast on Windows.
+func Desktop(title, message string) error {
+	return platform.Notify(title, message)
+}
@@ -0,0 +1,37 @@
+package notify
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWebhookAndLog(t *testing.T) {
+	var received Notification
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&received))
+	}))
+	defer server.Close()
+	n := Notification{Title: "Dataset at risk", Message: "Only 1 of 2\nfile(s)", Time: time.Now()}
+	assert.NoError(t, webhook(server.URL, n))
+	assert.Equal(t, "Dataset at risk", received.Title)
+
+	dir, err := ioutil.TempDir("", "ait-notify")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "alerts.log")
+	assert.NoError(t, appendLog(path, n))
+	assert.NoError(t, appendLog(path, n))
+	data, err := ioutil.ReadFile(path)
+	assert.NoError(t, err)
+	assert.Contains(t, string(data), "Dataset at risk: Only 1 of 2 file(s)\n")
+	assert.Equal(t, 2, strings.Count(string(data), "\n"))
+}
@@ -0,0 +1,11 @@
+//go:build !darwin && !freebsd && !linux && !windows
+// +build !darwin,!freebsd,!linux,!windows
+
+package platform
+
+const diskStats = ""
+
+// FreeSpace can't read the free space of a disk on this system.
+func FreeSpace(path string) (int64, error) {
+	return 0, ErrUnsupported
+}
@@ -0,0 +1,18 @@
+//go:build darwin || freebsd || linux
+// +build darwin freebsd linux
+
+package platform
+
+import "syscall"
+
+const diskStats = "statfs"
+
+// FreeSpace returns the number of bytes available to unprivileged users on
+// the disk holding path, which must exist.
+func FreeSpace(path string) (int64, error) {
+	var stat syscall.Statfs_t
+	if err := syscall.Statfs(path, &stat); err != nil {
+		return 0, err
+	}
+	return int64(stat.Bavail) * int64(stat.Bsize), nil
+}
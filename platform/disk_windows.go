@@ -0,0 +1,22 @@
+//go:build windows
+// +build windows
+
+package platform
+
+import "golang.org/x/sys/windows"
+
+const diskStats = "GetDiskFreeSpaceEx"
+
+// FreeSpace returns the number of bytes available to the user on the disk
+// holding path, which must exist.
+func FreeSpace(path string) (int64, error) {
+	name, err := windows.UTF16PtrFromString(path)
+	if err != nil {
+		return 0, err
+	}
+	var free uint64
+	if err = windows.GetDiskFreeSpaceEx(name, &free, nil, nil); err != nil {
+		return 0, err
+	}
+	return int64(free), nil
+}
@@ -0,0 +1,12 @@
+//go:build !darwin && !freebsd && !linux
+// +build !darwin,!freebsd,!linux
+
+package platform
+
+import "os"
+
+// FileID can't read the identity of a file on this system, files are told
+// apart by their size and modification time alone.
+func FileID(info os.FileInfo) uint64 {
+	return 0
+}
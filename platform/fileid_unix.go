@@ -0,0 +1,18 @@
+//go:build darwin || freebsd || linux
+// +build darwin freebsd linux
+
+package platform
+
+import (
+	"os"
+	"syscall"
+)
+
+// FileID returns the inode of the file described by info, which changes when
+// the file is replaced rather than written in place.
+func FileID(info os.FileInfo) uint64 {
+	if stat, ok := info.Sys().(*syscall.Stat_t); ok {
+		return uint64(stat.Ino)
+	}
+	return 0
+}
@@ -0,0 +1,28 @@
+package platform
+
+import "errors"
+
+// keychainService names the entries ait keeps in the system keychain.
+const keychainService = "ait"
+
+// ErrNoSecret is returned by Keychain.Get when no secret is stored for the
+// account.
+var ErrNoSecret = errors.New("no secret is stored for this account")
+
+// Keychain stores secrets, ie access tokens, by account, outside of any file
+// other programs could read.
+type Keychain interface {
+	// Get returns the secret stored for account, or ErrNoSecret.
+	Get(account string) (string, error)
+	// Set stores secret for account, replacing the one stored before.
+	Set(account, secret string) error
+	// Delete removes the secret of account, if there is one.
+	Delete(account string) error
+}
+
+// SystemKeychain returns the keychain of the system: the login keychain on
+// macOS, the Credential Manager on Windows and the Secret Service, ie GNOME
+// Keyring or KWallet, through secret-tool on Linux and the BSDs.
+func SystemKeychain() Keychain {
+	return systemKeychain{}
+}
@@ -0,0 +1,63 @@
+//go:build darwin
+// +build darwin
+
+package platform
+
+import (
+	"bytes"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+const keychainBackend = "macOS Keychain"
+
+// systemKeychain keeps secrets in the login keychain with the security tool.
+type systemKeychain struct{}
+
+// errItemNotFound is the status security exits with when there's no such item.
+const errItemNotFound = 44
+
+// Get returns the secret stored for account.
+func (systemKeychain) Get(account string) (string, error) {
+	out, err := exec.Command("security", "find-generic-password",
+		"-s", keychainService, "-a", account, "-w").Output()
+	if exit, ok := err.(*exec.ExitError); ok && exit.ExitCode() == errItemNotFound {
+		return "", ErrNoSecret
+	}
+	if err != nil {
+		return "", fmt.Errorf("unable to read the keychain: %v", err)
+	}
+	return strings.TrimSuffix(string(out), "\n"), nil
+}
+
+// Set stores secret for account. The command is given on stdin, so the secret
+// never shows in the arguments of a process.
+func (systemKeychain) Set(account, secret string) error {
+	cmd := exec.Command("security", "-i")
+	cmd.Stdin = strings.NewReader(fmt.Sprintf("add-generic-password -U -s %v -a %v -w %v\n",
+		securityQuote(keychainService), securityQuote(account), securityQuote(secret)))
+	if out, err := cmd.CombinedOutput(); err != nil || len(bytes.TrimSpace(out)) > 0 {
+		return fmt.Errorf("unable to write to the keychain: %v %s", err, bytes.TrimSpace(out))
+	}
+	return nil
+}
+
+// Delete removes the secret of account.
+func (systemKeychain) Delete(account string) error {
+	err := exec.Command("security", "delete-generic-password",
+		"-s", keychainService, "-a", account).Run()
+	if exit, ok := err.(*exec.ExitError); ok && exit.ExitCode() == errItemNotFound {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("unable to delete from the keychain: %v", err)
+	}
+	return nil
+}
+
+// securityQuote quotes s as a single argument of an interactive security
+// command.
+func securityQuote(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+}
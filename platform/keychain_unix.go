@@ -0,0 +1,59 @@
+//go:build !darwin && !windows
+// +build !darwin,!windows
+
+package platform
+
+import (
+	"bytes"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+const keychainBackend = "Secret Service (secret-tool)"
+
+// systemKeychain keeps secrets with the Secret Service through secret-tool.
+type systemKeychain struct{}
+
+// attributes returns the attributes identifying the secret of account.
+func (systemKeychain) attributes(account string) []string {
+	return []string{"service", keychainService, "account", account}
+}
+
+// Get returns the secret stored for account. secret-tool exits with status 1
+// both when there's no such secret and when it fails, the latter is told
+// apart by what it printed.
+func (k systemKeychain) Get(account string) (string, error) {
+	cmd := exec.Command("secret-tool", append([]string{"lookup"}, k.attributes(account)...)...)
+	stderr := new(bytes.Buffer)
+	cmd.Stderr = stderr
+	out, err := cmd.Output()
+	if _, ok := err.(*exec.ExitError); ok && stderr.Len() == 0 {
+		return "", ErrNoSecret
+	}
+	if err != nil {
+		return "", fmt.Errorf("unable to read the Secret Service: %v %s", err, bytes.TrimSpace(stderr.Bytes()))
+	}
+	return string(out), nil
+}
+
+// Set stores secret for account. secret-tool reads it from stdin, so it never
+// shows in the arguments of a process.
+func (k systemKeychain) Set(account, secret string) error {
+	args := append([]string{"store", "--label=ait " + account}, k.attributes(account)...)
+	cmd := exec.Command("secret-tool", args...)
+	cmd.Stdin = strings.NewReader(secret)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("unable to write to the Secret Service: %v %s", err, bytes.TrimSpace(out))
+	}
+	return nil
+}
+
+// Delete removes the secret of account.
+func (k systemKeychain) Delete(account string) error {
+	cmd := exec.Command("secret-tool", append([]string{"clear"}, k.attributes(account)...)...)
+	if out, err := cmd.CombinedOutput(); err != nil && len(bytes.TrimSpace(out)) > 0 {
+		return fmt.Errorf("unable to delete from the Secret Service: %v %s", err, bytes.TrimSpace(out))
+	}
+	return nil
+}
@@ -0,0 +1,114 @@
+//go:build windows
+// +build windows
+
+package platform
+
+import (
+	"fmt"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+const keychainBackend = "Windows Credential Manager"
+
+const (
+	credTypeGeneric         = 1
+	credPersistLocalMachine = 2
+)
+
+var (
+	advapi32      = windows.NewLazySystemDLL("advapi32.dll")
+	procCredRead  = advapi32.NewProc("CredReadW")
+	procCredWrite = advapi32.NewProc("CredWriteW")
+	procCredDel   = advapi32.NewProc("CredDeleteW")
+	procCredFree  = advapi32.NewProc("CredFree")
+)
+
+// credential is the CREDENTIALW structure of the Credential Manager.
+type credential struct {
+	Flags              uint32
+	Type               uint32
+	TargetName         *uint16
+	Comment            *uint16
+	LastWritten        windows.Filetime
+	CredentialBlobSize uint32
+	CredentialBlob     *byte
+	Persist            uint32
+	AttributeCount     uint32
+	Attributes         uintptr
+	TargetAlias        *uint16
+	UserName           *uint16
+}
+
+// systemKeychain keeps secrets as generic credentials of the Credential
+// Manager, named "ait:<account>".
+type systemKeychain struct{}
+
+// target returns the name of the credential of account.
+func (systemKeychain) target(account string) (*uint16, error) {
+	return windows.UTF16PtrFromString(keychainService + ":" + account)
+}
+
+// Get returns the secret stored for account.
+func (k systemKeychain) Get(account string) (string, error) {
+	target, err := k.target(account)
+	if err != nil {
+		return "", err
+	}
+	var cred *credential
+	ret, _, err := procCredRead.Call(uintptr(unsafe.Pointer(target)), credTypeGeneric, 0,
+		uintptr(unsafe.Pointer(&cred)))
+	if ret == 0 && err == windows.ERROR_NOT_FOUND {
+		return "", ErrNoSecret
+	}
+	if ret == 0 {
+		return "", fmt.Errorf("unable to read the Credential Manager: %v", err)
+	}
+	defer procCredFree.Call(uintptr(unsafe.Pointer(cred)))
+	if cred.CredentialBlobSize == 0 {
+		return "", nil
+	}
+	blob := (*[1 << 20]byte)(unsafe.Pointer(cred.CredentialBlob))[:cred.CredentialBlobSize:cred.CredentialBlobSize]
+	return string(blob), nil
+}
+
+// Set stores secret for account.
+func (k systemKeychain) Set(account, secret string) error {
+	target, err := k.target(account)
+	if err != nil {
+		return err
+	}
+	user, err := windows.UTF16PtrFromString(account)
+	if err != nil {
+		return err
+	}
+	cred := credential{
+		Type:               credTypeGeneric,
+		TargetName:         target,
+		CredentialBlobSize: uint32(len(secret)),
+		Persist:            credPersistLocalMachine,
+		UserName:           user,
+	}
+	if len(secret) > 0 {
+		blob := []byte(secret)
+		cred.CredentialBlob = &blob[0]
+	}
+	if ret, _, err := procCredWrite.Call(uintptr(unsafe.Pointer(&cred)), 0); ret == 0 {
+		return fmt.Errorf("unable to write to the Credential Manager: %v", err)
+	}
+	return nil
+}
+
+// Delete removes the secret of account.
+func (k systemKeychain) Delete(account string) error {
+	target, err := k.target(account)
+	if err != nil {
+		return err
+	}
+	ret, _, err := procCredDel.Call(uintptr(unsafe.Pointer(target)), credTypeGeneric, 0)
+	if ret == 0 && err != windows.ERROR_NOT_FOUND {
+		return fmt.Errorf("unable to delete from the Credential Manager: %v", err)
+	}
+	return nil
+}
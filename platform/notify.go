@@ -0,0 +1,19 @@
+package platform
+
+import (
+	"bytes"
+	"fmt"
+)
+
+// Notify shows a notification on the user's desktop with notify-send on Linux
+// and the BSDs, osascript on macOS and a PowerShell toast on Windows.
+func Notify(title, message string) error {
+	cmd, err := notifyCommand(title, message)
+	if err != nil {
+		return fmt.Errorf("unable to show a desktop notification: %v", err)
+	}
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("unable to show a desktop notification: %v %s", err, bytes.TrimSpace(out))
+	}
+	return nil
+}
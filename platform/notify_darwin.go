@@ -0,0 +1,17 @@
+//go:build darwin && !nodesktop
+// +build darwin,!nodesktop
+
+package platform
+
+import (
+	"fmt"
+	"os/exec"
+)
+
+const desktopNotifier = "osascript"
+
+// notifyCommand returns the command showing a desktop notification.
+func notifyCommand(title, message string) (*exec.Cmd, error) {
+	script := fmt.Sprintf("display notification %q with title %q", message, title)
+	return exec.Command("osascript", "-e", script), nil
+}
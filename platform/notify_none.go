@@ -0,0 +1,13 @@
+//go:build nodesktop
+// +build nodesktop
+
+package platform
+
+import "os/exec"
+
+const desktopNotifier = ""
+
+// notifyCommand fails, desktop notifications were left out of this build.
+func notifyCommand(title, message string) (*exec.Cmd, error) {
+	return nil, ErrUnsupported
+}
@@ -0,0 +1,13 @@
+//go:build !darwin && !windows && !nodesktop
+// +build !darwin,!windows,!nodesktop
+
+package platform
+
+import "os/exec"
+
+const desktopNotifier = "notify-send"
+
+// notifyCommand returns the command showing a desktop notification.
+func notifyCommand(title, message string) (*exec.Cmd, error) {
+	return exec.Command("notify-send", "--app-name=ait", title, message), nil
+}
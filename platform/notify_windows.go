@@ -0,0 +1,29 @@
+//go:build windows && !nodesktop
+// +build windows,!nodesktop
+
+package platform
+
+import (
+	"os/exec"
+	"strings"
+)
+
+const desktopNotifier = "PowerShell toast"
+
+// notifyCommand returns the command showing a desktop notification.
+func notifyCommand(title, message string) (*exec.Cmd, error) {
+	return exec.Command("powershell", "-NoProfile", "-Command", windowsToast(title, message)), nil
+}
+
+// windowsToast returns a PowerShell script showing a toast notification.
+func windowsToast(title, message string) string {
+	escape := func(s string) string {
+		return strings.NewReplacer("'", "''", "<", "&lt;", ">", "&gt;", "&", "&amp;").Replace(s)
+	}
+	return `[Windows.UI.Notifications.ToastNotificationManager, Windows.UI.Notifications, ContentType = WindowsRuntime] > $null
+$xml = [Windows.UI.Notifications.ToastNotificationManager]::GetTemplateContent([Windows.UI.Notifications.ToastTemplateType]::ToastText02)
+$text = $xml.GetElementsByTagName('text')
+$text.Item(0).AppendChild($xml.CreateTextNode('` + escape(title) + `')) > $null
+$text.Item(1).AppendChild($xml.CreateTextNode('` + escape(message) + `')) > $null
+[Windows.UI.Notifications.ToastNotificationManager]::CreateToastNotifier('ait').Show([Windows.UI.Notifications.ToastNotification]::new($xml))`
+}
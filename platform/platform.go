@@ -0,0 +1,50 @@
+// Package platform holds the code that differs between the systems ait runs
+// on: desktop notifications, detecting terminals, waiting for input, reading
+// free disk space and keeping secrets in the system keychain.
+// Each is implemented in files selected by build tags, so every release
+// artifact, ie for ARM keepers on a Raspberry Pi or an ARM Mac, gets all that
+// its system supports. Building with the nodesktop tag leaves desktop
+// notifications out, for headless keepers.
+package platform
+
+import (
+	"errors"
+	"fmt"
+	"runtime"
+)
+
+// ErrUnsupported is returned by features this build doesn't support.
+var ErrUnsupported = errors.New("not supported on " + runtime.GOOS + "/" + runtime.GOARCH)
+
+// Name returns the system and architecture ait was built for, ie
+// "linux/arm64".
+func Name() string {
+	return runtime.GOOS + "/" + runtime.GOARCH
+}
+
+// ReleaseAsset returns the name of the release artifact of version for this
+// system and architecture.
+func ReleaseAsset(version string) string {
+	name := "ait-" + version + "-" + runtime.GOOS + "-" + runtime.GOARCH
+	if runtime.GOOS == "windows" {
+		name += ".exe"
+	}
+	return name
+}
+
+// Features describes how each platform specific feature is provided by this
+// build, one per line.
+func Features() []string {
+	describe := func(how string) string {
+		if how == "" {
+			return "unsupported"
+		}
+		return how
+	}
+	return []string{
+		fmt.Sprintf("desktop notifications: %v", describe(desktopNotifier)),
+		fmt.Sprintf("terminal detection: %v", describe(terminalCheck)),
+		fmt.Sprintf("free space: %v", describe(diskStats)),
+		fmt.Sprintf("credentials: %v", describe(keychainBackend)),
+	}
+}
@@ -0,0 +1,14 @@
+//go:build !darwin && !freebsd && !linux && !netbsd && !openbsd && !windows
+// +build !darwin,!freebsd,!linux,!netbsd,!openbsd,!windows
+
+package platform
+
+import (
+	"os"
+	"time"
+)
+
+// WaitReadable isn't supported on this system.
+func WaitReadable(file *os.File, timeout time.Duration) (bool, error) {
+	return false, ErrUnsupported
+}
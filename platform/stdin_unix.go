@@ -0,0 +1,26 @@
+//go:build darwin || freebsd || linux || netbsd || openbsd
+// +build darwin freebsd linux netbsd openbsd
+
+package platform
+
+import (
+	"os"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+// WaitReadable waits until the file has input to read, returning false if
+// timeout passes first. Nothing is read, so a prompt that stops waiting
+// leaves the input to whatever reads it next.
+func WaitReadable(file *os.File, timeout time.Duration) (bool, error) {
+	deadline := time.Now().Add(timeout)
+	for {
+		fds := []unix.PollFd{{Fd: int32(file.Fd()), Events: unix.POLLIN}}
+		n, err := unix.Poll(fds, int(time.Until(deadline).Milliseconds()))
+		if err == unix.EINTR {
+			continue
+		}
+		return n > 0, err
+	}
+}
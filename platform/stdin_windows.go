@@ -0,0 +1,22 @@
+//go:build windows
+// +build windows
+
+package platform
+
+import (
+	"os"
+	"time"
+
+	"golang.org/x/sys/windows"
+)
+
+// WaitReadable waits until the file has input to read, returning false if
+// timeout passes first. Consoles are signaled by any input event, pipes and
+// files are always readable.
+func WaitReadable(file *os.File, timeout time.Duration) (bool, error) {
+	event, err := windows.WaitForSingleObject(windows.Handle(file.Fd()), uint32(timeout.Milliseconds()))
+	if err != nil {
+		return false, err
+	}
+	return event != uint32(windows.WAIT_TIMEOUT), nil
+}
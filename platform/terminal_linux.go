@@ -0,0 +1,19 @@
+//go:build linux
+// +build linux
+
+package platform
+
+import (
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+const terminalCheck = "termios"
+
+// IsTerminal returns true if the file is a terminal, rather than a pipe, a
+// regular file or another character device like /dev/null.
+func IsTerminal(file *os.File) bool {
+	_, err := unix.IoctlGetTermios(int(file.Fd()), unix.TCGETS)
+	return err == nil
+}
@@ -0,0 +1,15 @@
+//go:build !linux && !darwin && !freebsd && !netbsd && !openbsd && !windows
+// +build !linux,!darwin,!freebsd,!netbsd,!openbsd,!windows
+
+package platform
+
+import "os"
+
+const terminalCheck = "character device"
+
+// IsTerminal returns true if the file is a character device, the closest to
+// a terminal this system can tell.
+func IsTerminal(file *os.File) bool {
+	info, err := file.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
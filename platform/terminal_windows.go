@@ -0,0 +1,19 @@
+//go:build windows
+// +build windows
+
+package platform
+
+import (
+	"os"
+
+	"golang.org/x/sys/windows"
+)
+
+const terminalCheck = "console mode"
+
+// IsTerminal returns true if the file is a console, rather than a pipe, a
+// regular file or the NUL device.
+func IsTerminal(file *os.File) bool {
+	var mode uint32
+	return windows.GetConsoleMode(windows.Handle(file.Fd()), &mode) == nil
+}
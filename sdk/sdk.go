@@ -0,0 +1,233 @@
+// Package sdk runs ait's core operations from other Go programs, ie web
+// portals and ingest pipelines embedding ait: staging files, generating their
+// keyset and submitting it. Unlike the commands, its functions never prompt
+// or exit the program, they return errors instead.
+//
+// The functions work on the state of the workspace they're given, without
+// changing the working directory, so workspaces can be worked on at the same
+// time. The settings are the ones of the config file (~/.ait/ait.config, or
+// the one under AIT_HOME) loaded when the first workspace is opened, the
+// overrides in a workspace's .ait/config don't apply.
+package sdk
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/arken/ait/config"
+	"github.com/arken/ait/ipfs"
+	"github.com/arken/ait/keysets"
+	"github.com/arken/ait/types"
+	"github.com/arken/ait/utils"
+)
+
+var (
+	// loadConfig loads the config once, loadErr is the error it failed with.
+	loadConfig sync.Once
+	loadErr    error
+	// lock guards the IPFS node, started is set once it's running.
+	lock    sync.Mutex
+	started bool
+)
+
+// Workspace is an AIT workspace, a directory ait init was run in.
+type Workspace struct {
+	dir string
+}
+
+// Open returns the workspace at dir. The config is loaded the first time.
+func Open(dir string) (*Workspace, error) {
+	loadConfig.Do(func() { loadErr = config.Load("", false) })
+	if loadErr != nil {
+		return nil, loadErr
+	}
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+	if info, err := os.Stat(filepath.Join(abs, ".ait")); err != nil || !info.IsDir() {
+		return nil, fmt.Errorf("%v isn't an AIT workspace, run ait init in it first", abs)
+	}
+	return &Workspace{dir: abs}, nil
+}
+
+// Dir returns the directory of the workspace.
+func (w *Workspace) Dir() string {
+	return w.dir
+}
+
+// startIPFS starts the IPFS node, which keeps running for the next calls
+// until Close.
+func startIPFS() error {
+	lock.Lock()
+	defer lock.Unlock()
+	if started {
+		return nil
+	}
+	if err := ipfs.Start(false); err != nil {
+		return err
+	}
+	started = true
+	return nil
+}
+
+// Close stops the IPFS node the functions of the package started, if any.
+func Close() error {
+	lock.Lock()
+	defer lock.Unlock()
+	if !started {
+		return nil
+	}
+	started = false
+	return ipfs.Close()
+}
+
+// StageOptions are the options of Stage.
+type StageOptions struct {
+	// Extensions stages the files of the workspace with one of these
+	// extensions, ie ".csv", besides the paths given.
+	Extensions []string
+	// NoIgnore stages the files .aitignore files exclude too.
+	NoIgnore bool
+}
+
+// Stage stages the files at paths, relative to the workspace or absolute
+// within it, and the files of the directories among them, as ait stage does.
+// It returns how many files weren't staged yet.
+func Stage(ctx context.Context, w *Workspace, paths []string, opts StageOptions) (int, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	ignore := utils.NewIgnoreIn(w.dir)
+	if opts.NoIgnore {
+		ignore = nil
+	}
+	contents := types.NewThreadSafeStringSet()
+	for _, p := range paths {
+		path, err := utils.RelativePath(w.dir, p)
+		if err != nil {
+			return 0, fmt.Errorf("%v isn't in the workspace", p)
+		}
+		info, err := os.Stat(filepath.Join(w.dir, path))
+		if err != nil {
+			return 0, err
+		}
+		if !info.IsDir() {
+			contents.Add(path)
+		} else if errs := utils.WalkFilesIn(w.dir, path, nil, ignore, contents); len(errs) > 0 {
+			return 0, errs[0]
+		}
+	}
+	if len(opts.Extensions) > 0 {
+		exts := types.NewBasicStringSet()
+		for _, ext := range opts.Extensions {
+			exts.Add("." + strings.TrimPrefix(strings.TrimSpace(ext), "."))
+		}
+		if errs := utils.WalkFilesIn(w.dir, ".", exts, ignore, contents); len(errs) > 0 {
+			return 0, errs[0]
+		}
+	}
+	if contents.Size() == 0 {
+		return 0, nil
+	}
+	var sorted []string
+	_ = contents.ForEach(func(path string) error {
+		sorted = append(sorted, path)
+		return nil
+	})
+	sort.Strings(sorted)
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	if err := utils.RunHookIn(w.dir, utils.HookPreAdd, sorted, ""); err != nil {
+		return 0, err
+	}
+	added := 0
+	err := utils.UpdateStagedIn(w.dir, func(staged *types.ThreadSafeStringSet) error {
+		before := staged.Size()
+		for _, path := range sorted {
+			staged.Add(path)
+		}
+		added = staged.Size() - before
+		return nil
+	})
+	return added, err
+}
+
+// KeysetOptions are the options of GenerateKeyset.
+type KeysetOptions struct {
+	// Format is the format the keyset is written in: ksv, json, csv or car,
+	// General.KeysetFormat by default.
+	Format string
+}
+
+// GenerateKeyset adds the staged files to IPFS and writes the keyset listing
+// them to out. Files that can't be added are left out and reported in a
+// *keysets.AddError once the keyset is written. When the deadline of ctx
+// passes first, nothing is written and the error is a
+// *keysets.DeadlineError, the files added so far aren't hashed again by the
+// next call.
+func GenerateKeyset(ctx context.Context, w *Workspace, out io.Writer, opts KeysetOptions) error {
+	format := opts.Format
+	if format == "" {
+		format = config.Global.General.KeysetFormat
+	}
+	writer, err := keysets.FormatWriter(format)
+	if err != nil {
+		return err
+	}
+	ksPath, genErr := w.generate(ctx)
+	if ksPath != "" {
+		defer os.RemoveAll(filepath.Dir(ksPath))
+	}
+	var addErr *keysets.AddError
+	if genErr != nil && !errors.As(genErr, &addErr) {
+		return genErr
+	}
+	entries, err := keysets.ReadEntries(ksPath)
+	if err != nil {
+		return err
+	}
+	if err = writer.Write(out, entries); err != nil {
+		return err
+	}
+	return genErr
+}
+
+// staged returns the paths of the staged files of the workspace, sorted.
+func (w *Workspace) staged() ([]string, error) {
+	contents, err := utils.ReadStagedSortedIn(w.dir)
+	if err != nil {
+		return nil, err
+	}
+	staged := make([]string, 0, contents.Size())
+	_ = contents.ForEach(func(path string) error {
+		staged = append(staged, path)
+		return nil
+	})
+	return staged, nil
+}
+
+// generate starts the IPFS node and generates the keyset of the staged files
+// in a temporary directory, stopping once ctx is done. Unless the path is
+// empty, the directory is left to the caller to remove, even when an error is
+// returned.
+func (w *Workspace) generate(ctx context.Context) (string, error) {
+	if err := startIPFS(); err != nil {
+		return "", err
+	}
+	dir, err := ioutil.TempDir("", "ait-keyset")
+	if err != nil {
+		return "", err
+	}
+	ksPath := filepath.Join(dir, "keyset.ks")
+	return ksPath, keysets.GenerateIn(ctx, w.dir, ksPath, true)
+}
@@ -0,0 +1,260 @@
+package sdk
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/arken/ait/apis/forge"
+	aitgh "github.com/arken/ait/apis/github"
+	"github.com/arken/ait/config"
+	"github.com/arken/ait/ipfs"
+	"github.com/arken/ait/types"
+	"github.com/arken/ait/utils"
+)
+
+// Auth authenticates submissions to the forge hosting the repository.
+type Auth struct {
+	// Token is an access token of the user: a GitHub token able to push to
+	// public repositories, or a GitLab or Gitea one.
+	Token string
+}
+
+// SubmitOptions are the options of Submit.
+type SubmitOptions struct {
+	// Title and Message are the title of the submission and the message
+	// it's committed with, both required.
+	Title   string
+	Message string
+	// Category and Name are where the keyset goes in the repository, ie
+	// "science/biology" and "datasets" for science/biology/datasets.ks.
+	Category string
+	Name     string
+	// PullRequest proposes the keyset in a pull request from the user's fork
+	// rather than committing it to the repository, which users who can't
+	// push to it must do.
+	PullRequest bool
+	// Overwrite replaces the keyset if the repository has one under the same
+	// name, rather than failing.
+	Overwrite bool
+	// Provider is the forge hosting the repository, github, gitlab or gitea,
+	// detected from its host when empty.
+	Provider string
+}
+
+// Submit generates the keyset of the staged files and submits it to remote,
+// a repository URL or a remote alias of the config, as ait submit does: the
+// pre-submit and post-submit hooks of the workspace run before and after it,
+// the keyset is signed when Git.SignKeysets is set, and the submitted files
+// are protected from garbage collection until the submission is merged and
+// replicated. The submission is recorded in the history of the workspace and
+// returned, along with an error if its files couldn't be protected or the
+// post-submit hook failed. The
+// staged files are served by the node once it's running online, ie by ait
+// daemon or ait upload.
+func Submit(ctx context.Context, w *Workspace, remote string, auth Auth, opts SubmitOptions) (*utils.Submission, error) {
+	if strings.TrimSpace(opts.Title) == "" || strings.TrimSpace(opts.Message) == "" {
+		return nil, errors.New("a submission needs a title and a message")
+	}
+	if strings.TrimSpace(opts.Name) == "" {
+		return nil, errors.New("a submission needs the name of its keyset")
+	}
+	url := config.GetPushRemote(remote)
+	kind, err := forge.Detect(url, opts.Provider)
+	if err != nil {
+		return nil, err
+	}
+	app := &types.ApplicationContents{
+		Title:    strings.TrimSpace(opts.Title),
+		Commit:   strings.TrimSpace(opts.Message),
+		Category: strings.Trim(opts.Category, "/"),
+		KsName:   strings.TrimSuffix(opts.Name, ".ks") + ".ks",
+	}
+	if strings.Contains(app.Category, "..") {
+		return nil, errors.New("path backtracking (\"..\") is not allowed in the category")
+	}
+	staged, err := w.staged()
+	if err != nil {
+		return nil, err
+	}
+	// The hook runs before the keyset is generated, so the metadata it
+	// attaches to files is submitted with them.
+	err = utils.RunHookIn(w.dir, utils.HookPreSubmit, staged, app.FullPath(), "AIT_REMOTE="+url)
+	if err != nil {
+		return nil, err
+	}
+	ksPath, err := w.generate(ctx)
+	if ksPath != "" {
+		// Unlike SubmissionCleanup, the application being written in the
+		// workspace is left alone.
+		defer os.RemoveAll(filepath.Dir(ksPath))
+	}
+	if err != nil {
+		return nil, err
+	}
+	files, err := keysetFiles(ksPath, app.FullPath())
+	if err != nil {
+		return nil, err
+	}
+	var commit, prURL string
+	var prNumber int
+	if kind == forge.GitHub {
+		commit, prURL, prNumber, err = submitGitHub(ctx, url, files, app, auth, opts)
+	} else {
+		commit, prURL, prNumber, err = submitForge(ctx, kind, url, files, app, auth, opts)
+	}
+	if err != nil {
+		return nil, err
+	}
+	entries, err := utils.ReadKeysetEntries(ksPath)
+	if err != nil {
+		return nil, err
+	}
+	submission := utils.NewSubmission(url, app.FullPath(), entries)
+	submission.Commit = commit
+	submission.PullRequest, submission.PRNumber = prURL, prNumber
+	cids := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		cids = append(cids, entry.CID)
+	}
+	protectErr := ipfs.Protect(submission.ID, cids)
+	submission.Protected = protectErr == nil
+	if err = submission.SaveIn(filepath.Join(w.dir, utils.HistoryPath)); err != nil {
+		return nil, err
+	}
+	if protectErr != nil {
+		return submission, fmt.Errorf("the submitted files couldn't be protected from garbage collection: %w",
+			protectErr)
+	}
+	return submission, utils.RunHookIn(w.dir, utils.HookPostSubmit, staged, submission.Path,
+		"AIT_REMOTE="+submission.Remote,
+		"AIT_SUBMISSION="+submission.ID,
+		"AIT_COMMIT="+submission.Commit,
+		"AIT_PULL_REQUEST="+submission.PullRequest,
+		"AIT_ISSUE=")
+}
+
+// keysetFiles returns the files committed for the keyset at ksPath, by their
+// path in the repository: the keyset at repoPath and, when Git.SignKeysets is
+// set, its detached signature next to it.
+func keysetFiles(ksPath, repoPath string) (map[string]string, error) {
+	files := map[string]string{repoPath: ksPath}
+	if !config.Global.Git.SignKeysets {
+		return files, nil
+	}
+	contents, err := ioutil.ReadFile(ksPath)
+	if err != nil {
+		return nil, err
+	}
+	format := config.Global.Git.SigningFormat
+	signature, err := utils.Sign(contents, format, config.Global.Git.SigningKey, utils.NamespaceFile)
+	if err != nil {
+		return nil, err
+	}
+	ext := utils.SignatureExtension(format)
+	if err = ioutil.WriteFile(ksPath+ext, []byte(signature), 0644); err != nil {
+		return nil, err
+	}
+	files[repoPath+ext] = ksPath + ext
+	return files, nil
+}
+
+// githubLock runs the GitHub submissions one at a time, as the github package
+// keeps the repository it submits to.
+var githubLock sync.Mutex
+
+// submitGitHub commits files, the keyset at app.FullPath() and its signature
+// if any, to the GitHub repository at url, returning the SHA of the commit and
+// the pull request opened, if any.
+func submitGitHub(ctx context.Context, url string, files map[string]string, app *types.ApplicationContents,
+	auth Auth, opts SubmitOptions) (string, string, int, error) {
+	githubLock.Lock()
+	defer githubLock.Unlock()
+	canPush, err := aitgh.InitWithToken(ctx, url, auth.Token, opts.PullRequest)
+	if err != nil {
+		return "", "", 0, err
+	}
+	if !canPush {
+		return "", "", 0, fmt.Errorf("the token can't push to %v, submit in a pull request", url)
+	}
+	repoPath := app.FullPath()
+	if opts.PullRequest {
+		if err = aitgh.CreateFork(); err != nil {
+			return "", "", 0, err
+		}
+		if err = aitgh.UsePullRequestBranch(repoPath); err != nil {
+			return "", "", 0, err
+		}
+	}
+	exists, err := aitgh.KeysetExists(repoPath, opts.PullRequest)
+	if err != nil {
+		return "", "", 0, err
+	}
+	if exists && !opts.Overwrite {
+		return "", "", 0, fmt.Errorf("%v is already in the repository", repoPath)
+	}
+	var commit string
+	if len(files) > 1 {
+		// The keyset and its signature are committed at once, so they're
+		// never out of step.
+		commit, err = aitgh.CommitFiles(files, app.Commit, nil, "", opts.PullRequest, "")
+	} else {
+		commit, err = aitgh.CommitFile(files[repoPath], repoPath, app.Commit, opts.PullRequest)
+	}
+	if err != nil || !opts.PullRequest {
+		return commit, "", 0, err
+	}
+	prURL, prNumber, err := aitgh.CreatePullRequest(app.Title, app.Commit, repoPath)
+	return commit, prURL, prNumber, err
+}
+
+// submitForge commits files, the keyset at app.FullPath() and its signature
+// if any, to the repository at url hosted on a GitLab or Gitea forge,
+// returning the SHA of the commit and the pull request opened, if any.
+func submitForge(ctx context.Context, kind, url string, files map[string]string, app *types.ApplicationContents,
+	auth Auth, opts SubmitOptions) (string, string, int, error) {
+	provider, err := forge.NewWithToken(ctx, kind, url, auth.Token)
+	if err != nil {
+		return "", "", 0, err
+	}
+	if opts.PullRequest {
+		err = provider.Fork()
+	} else if canPush, accessErr := provider.CheckAccess(); accessErr != nil {
+		err = accessErr
+	} else if !canPush {
+		err = fmt.Errorf("the token can't push to %v, submit in a pull request", url)
+	}
+	if err != nil {
+		return "", "", 0, err
+	}
+	repoPath := app.FullPath()
+	exists, err := provider.Exists(repoPath)
+	if err != nil {
+		return "", "", 0, err
+	}
+	if exists && !opts.Overwrite {
+		return "", "", 0, fmt.Errorf("%v is already in the repository", repoPath)
+	}
+	commit, err := provider.CommitFile(files[repoPath], repoPath, app.Commit, opts.PullRequest)
+	if err != nil {
+		return "", "", 0, err
+	}
+	// The forges commit a file at a time, the signature follows the keyset.
+	for path, localPath := range files {
+		if path != repoPath {
+			if _, err = provider.CommitFile(localPath, path, app.Commit, opts.PullRequest); err != nil {
+				return "", "", 0, err
+			}
+		}
+	}
+	if !opts.PullRequest {
+		return commit, "", 0, nil
+	}
+	prURL, prNumber, err := provider.OpenPR(app.Title, app.Commit)
+	return commit, prURL, prNumber, err
+}
@@ -46,6 +46,20 @@ func (app *ApplicationContents) IsValid() bool {
 	return len(app.Title) != 0 && len(app.Commit) != 0
 }
 
+// Missing names what IsValid needs that the application lacks, "" if it's
+// valid.
+func (app *ApplicationContents) Missing() string {
+	switch {
+	case len(app.Title) == 0 && len(app.Commit) == 0:
+		return "title or commit message"
+	case len(app.Title) == 0:
+		return "title"
+	case len(app.Commit) == 0:
+		return "commit message"
+	}
+	return ""
+}
+
 // FullPath returns the full path of the keyset **in the repo.
 func (app *ApplicationContents) FullPath() string {
 	// TODO: This won't work on windows because it'll replace / with \
@@ -0,0 +1,150 @@
+package utils
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+)
+
+// AddParamsPath holds the parameters the staged files are added to IPFS with,
+// by path, for the files and directories staged with other than the defaults.
+var AddParamsPath = filepath.Join(".ait", "add_params.json")
+
+// maxChunkSize is the largest block IPFS peers accept, chunks can't be larger.
+const maxChunkSize = 1 << 20
+
+// chunkerPattern is what the chunkers of IPFS look like: "size-<bytes>",
+// "rabin", "rabin-<avg>", "rabin-<min>-<avg>-<max>" or "buzhash".
+var chunkerPattern = regexp.MustCompile(`^(size-(\d+)|rabin(-\d+|-\d+-\d+-\d+)?|buzhash)$`)
+
+// AddParams are how a file is chunked and hashed when it's added to IPFS.
+// They change its CID, so they're set to match the CIDs other IPFS tools gave
+// the same data. Unset fields keep the defaults of ait.
+type AddParams struct {
+	// CidVersion is 0 or 1, 1 by default.
+	CidVersion *int `json:"cid_version,omitempty"`
+	// Chunker splits the file in blocks, "size-262144" by default.
+	Chunker string `json:"chunker,omitempty"`
+	// RawLeaves stores the data of the file as is in the leaves of its DAG,
+	// which it is by default.
+	RawLeaves *bool `json:"raw_leaves,omitempty"`
+}
+
+// IsZero returns whether the parameters keep every default.
+func (p AddParams) IsZero() bool {
+	return p.CidVersion == nil && p.Chunker == "" && p.RawLeaves == nil
+}
+
+// String describes the parameters as the flags of "ait stage" setting them.
+func (p AddParams) String() string {
+	s := ""
+	if p.CidVersion != nil {
+		s += fmt.Sprintf(" --cid-version %d", *p.CidVersion)
+	}
+	if p.Chunker != "" {
+		s += " --chunker " + p.Chunker
+	}
+	if p.RawLeaves != nil {
+		s += fmt.Sprintf(" --raw-leaves %v", *p.RawLeaves)
+	}
+	if s == "" {
+		return "defaults"
+	}
+	return s[1:]
+}
+
+// ParseAddParams reads the parameters given as flags, those left empty keep
+// their default.
+func ParseAddParams(cidVersion, chunker, rawLeaves string) (AddParams, error) {
+	var params AddParams
+	if cidVersion != "" {
+		version, err := strconv.Atoi(cidVersion)
+		if err != nil || version != 0 && version != 1 {
+			return params, fmt.Errorf("the CID version is 0 or 1, not %q", cidVersion)
+		}
+		params.CidVersion = &version
+	}
+	if chunker != "" {
+		match := chunkerPattern.FindStringSubmatch(chunker)
+		if match == nil {
+			return params, fmt.Errorf("unknown chunker %q, expected size-<bytes>, "+
+				"rabin-<min>-<avg>-<max> or buzhash", chunker)
+		}
+		if size, err := strconv.Atoi(match[2]); match[2] != "" && (err != nil || size <= 0 || size > maxChunkSize) {
+			return params, fmt.Errorf("chunks are between 1 byte and %d bytes, not %v", maxChunkSize, match[2])
+		}
+		params.Chunker = chunker
+	}
+	if rawLeaves != "" {
+		raw, err := strconv.ParseBool(rawLeaves)
+		if err != nil {
+			return params, fmt.Errorf("raw leaves are true or false, not %q", rawLeaves)
+		}
+		params.RawLeaves = &raw
+	}
+	return params, nil
+}
+
+// ReadAddParams returns the parameters recorded in the file at path, by path
+// within its workspace.
+func ReadAddParams(path string) (map[string]AddParams, error) {
+	params := make(map[string]AddParams)
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return params, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return params, json.Unmarshal(data, &params)
+}
+
+// WriteAddParams records the parameters of the staged files of the workspace.
+// Paths left with the defaults are dropped.
+func WriteAddParams(params map[string]AddParams) error {
+	for path, p := range params {
+		if p.IsZero() {
+			delete(params, path)
+		}
+	}
+	data, err := json.MarshalIndent(params, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(AddParamsPath, data, 0644)
+}
+
+// AddParamsFor returns the parameters the file at path is added with: those
+// set for it and for the directories holding it, those closest to the file
+// taking precedence.
+func AddParamsFor(params map[string]AddParams, path string) AddParams {
+	var result AddParams
+	if len(params) == 0 {
+		return result
+	}
+	path = SlashPath(path)
+	var owners []string
+	for p := path; ; p = filepath.ToSlash(filepath.Dir(p)) {
+		owners = append(owners, p)
+		if p == "." || p == "/" || filepath.Dir(p) == p {
+			break
+		}
+	}
+	for i := len(owners) - 1; i >= 0; i-- {
+		p := params[filepath.FromSlash(owners[i])]
+		if p.CidVersion != nil {
+			result.CidVersion = p.CidVersion
+		}
+		if p.Chunker != "" {
+			result.Chunker = p.Chunker
+		}
+		if p.RawLeaves != nil {
+			result.RawLeaves = p.RawLeaves
+		}
+	}
+	return result
+}
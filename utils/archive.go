@@ -0,0 +1,141 @@
+package utils
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// ArchiveEntry maps a file or directory on disk to its name within an
+// archive created by CreateArchive.
+type ArchiveEntry struct {
+	Path string
+	Name string
+}
+
+// CreateArchive writes the given entries to a gzipped tarball at out.
+// Directories are added recursively and entries that don't exist are skipped.
+func CreateArchive(out string, entries []ArchiveEntry) (err error) {
+	file, err := os.OpenFile(out, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if closeErr := file.Close(); err == nil {
+			err = closeErr
+		}
+	}()
+	gz := gzip.NewWriter(file)
+	tw := tar.NewWriter(gz)
+	for _, entry := range entries {
+		if !FileExists(entry.Path) {
+			continue
+		}
+		err = filepath.Walk(entry.Path, func(filePath string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			if !info.Mode().IsRegular() && !info.IsDir() {
+				return nil
+			}
+			rel, err := filepath.Rel(entry.Path, filePath)
+			if err != nil {
+				return err
+			}
+			header, err := tar.FileInfoHeader(info, "")
+			if err != nil {
+				return err
+			}
+			header.Name = path.Join(entry.Name, filepath.ToSlash(rel))
+			if info.IsDir() {
+				header.Name += "/"
+			}
+			if err = tw.WriteHeader(header); err != nil {
+				return err
+			}
+			if info.IsDir() {
+				return nil
+			}
+			src, err := os.Open(filePath)
+			if err != nil {
+				return err
+			}
+			defer src.Close()
+			_, err = io.Copy(tw, src)
+			return err
+		})
+		if err != nil {
+			return err
+		}
+	}
+	if err = tw.Close(); err != nil {
+		return err
+	}
+	return gz.Close()
+}
+
+// ExtractArchive unpacks a tarball created by CreateArchive. The first
+// element of each entry's name selects its destination directory from roots;
+// entries without a root are ignored. Existing files are only replaced when
+// overwrite is set. The paths of the extracted files are returned.
+func ExtractArchive(in string, roots map[string]string, overwrite bool) (extracted []string, err error) {
+	file, err := os.Open(in)
+	if err != nil {
+		return extracted, err
+	}
+	defer file.Close()
+	gz, err := gzip.NewReader(file)
+	if err != nil {
+		return extracted, fmt.Errorf("%v is not an ait backup: %v", in, err)
+	}
+	tr := tar.NewReader(gz)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return extracted, nil
+		}
+		if err != nil {
+			return extracted, err
+		}
+		name := path.Clean(header.Name)
+		parts := strings.SplitN(name, "/", 2)
+		root, ok := roots[parts[0]]
+		if !ok || strings.HasPrefix(name, "../") || path.IsAbs(name) {
+			continue
+		}
+		dest := root
+		if len(parts) > 1 {
+			dest = filepath.Join(root, filepath.FromSlash(parts[1]))
+		}
+		if header.Typeflag == tar.TypeDir {
+			if err = os.MkdirAll(dest, os.ModePerm); err != nil {
+				return extracted, err
+			}
+			continue
+		}
+		if header.Typeflag != tar.TypeReg {
+			continue
+		}
+		if !overwrite && FileExists(dest) {
+			return extracted, fmt.Errorf("%v already exists", dest)
+		}
+		if err = os.MkdirAll(filepath.Dir(dest), os.ModePerm); err != nil {
+			return extracted, err
+		}
+		out, err := os.OpenFile(dest, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, os.FileMode(header.Mode).Perm())
+		if err != nil {
+			return extracted, err
+		}
+		_, err = io.Copy(out, tr)
+		out.Close()
+		if err != nil {
+			return extracted, err
+		}
+		extracted = append(extracted, dest)
+	}
+}
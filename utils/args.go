@@ -0,0 +1,86 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"strings"
+)
+
+// SplitArgs splits a command line into args on whitespace like a shell,
+// keeping quoted text together and honoring backslash escapes outside of
+// single quotes.
+func SplitArgs(line string) ([]string, error) {
+	var args []string
+	var current strings.Builder
+	inArg, escaped := false, false
+	var quote rune
+	for _, r := range line {
+		switch {
+		case escaped:
+			current.WriteRune(r)
+			escaped = false
+		case r == '\\' && quote != '\'':
+			escaped, inArg = true, true
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			} else {
+				current.WriteRune(r)
+			}
+		case r == '"' || r == '\'':
+			quote, inArg = r, true
+		case r == ' ' || r == '\t' || r == '\n':
+			if inArg {
+				args = append(args, current.String())
+				current.Reset()
+				inArg = false
+			}
+		default:
+			current.WriteRune(r)
+			inArg = true
+		}
+	}
+	if quote != 0 || escaped {
+		return nil, errors.New("unterminated quote or escape")
+	}
+	if inArg {
+		args = append(args, current.String())
+	}
+	return args, nil
+}
+
+// ParseFileMappings parses a comma separated list of local files, each
+// optionally followed by "=" and the path in the repository it's uploaded to,
+// ie "manifest.json=meta/manifest.json,INDEX.md". The result maps repository
+// paths to local paths. Every local file must exist.
+func ParseFileMappings(spec string) (map[string]string, error) {
+	files := make(map[string]string)
+	for _, item := range strings.Split(spec, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		localPath, repoPath := item, item
+		if i := strings.Index(item, "="); i >= 0 {
+			localPath, repoPath = item[:i], item[i+1:]
+		}
+		repoPath = SlashPath(repoPath)
+		if repoPath == "." || path.IsAbs(repoPath) || repoPath == ".." || strings.HasPrefix(repoPath, "../") {
+			return nil, fmt.Errorf("%q isn't a path within the repository", repoPath)
+		}
+		if _, ok := files[repoPath]; ok {
+			return nil, fmt.Errorf("more than one file is uploaded to %v", repoPath)
+		}
+		info, err := os.Stat(localPath)
+		if err != nil {
+			return nil, err
+		}
+		if !info.Mode().IsRegular() {
+			return nil, fmt.Errorf("%v isn't a file", localPath)
+		}
+		files[repoPath] = localPath
+	}
+	return files, nil
+}
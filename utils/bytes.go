@@ -0,0 +1,55 @@
+package utils
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// byteUnits maps the suffixes accepted by ParseByteSize to their size in bytes.
+// Units are powers of 1024.
+var byteUnits = []struct {
+	suffix string
+	size   int64
+}{
+	{"TB", 1 << 40},
+	{"GB", 1 << 30},
+	{"MB", 1 << 20},
+	{"KB", 1 << 10},
+	{"B", 1},
+}
+
+// ParseByteSize converts a human readable size such as "100TB", "5 GB" or
+// "512" into a number of bytes. An empty string is parsed as 0.
+func ParseByteSize(size string) (int64, error) {
+	size = strings.ToUpper(strings.TrimSpace(size))
+	if size == "" {
+		return 0, nil
+	}
+	for _, unit := range byteUnits {
+		if strings.HasSuffix(size, unit.suffix) {
+			num := strings.TrimSpace(strings.TrimSuffix(size, unit.suffix))
+			value, err := strconv.ParseFloat(num, 64)
+			if err != nil || value < 0 {
+				return 0, fmt.Errorf("invalid size %q", size)
+			}
+			return int64(value * float64(unit.size)), nil
+		}
+	}
+	value, err := strconv.ParseInt(size, 10, 64)
+	if err != nil || value < 0 {
+		return 0, fmt.Errorf("invalid size %q", size)
+	}
+	return value, nil
+}
+
+// FormatByteSize returns the given number of bytes as a short human readable
+// string, ie 1536 -> "1.5KB".
+func FormatByteSize(size int64) string {
+	for _, unit := range byteUnits {
+		if size >= unit.size && unit.size > 1 {
+			return strconv.FormatFloat(float64(size)/float64(unit.size), 'f', 1, 64) + unit.suffix
+		}
+	}
+	return strconv.FormatInt(size, 10) + "B"
+}
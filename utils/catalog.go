@@ -0,0 +1,173 @@
+package utils
+
+import (
+	"crypto/sha256"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// CatalogPath holds the records imported from a lab's existing data catalog.
+var CatalogPath = filepath.Join(".ait", "catalog.json")
+
+// CatalogRecord is a file listed in a data catalog.
+type CatalogRecord struct {
+	Path   string `json:"path"`
+	SHA256 string `json:"sha256"`
+	// ID is the file's identifier in the catalog, if it has one.
+	ID       string            `json:"id,omitempty"`
+	Metadata map[string]string `json:"metadata,omitempty"`
+	// Verified is set once the file on disk was found to match SHA256.
+	Verified bool `json:"verified,omitempty"`
+}
+
+// CatalogMismatch is a staged file whose checksum differs from the catalog.
+type CatalogMismatch struct {
+	Path     string
+	Expected string
+	Actual   string
+}
+
+// CatalogReport is the result of reconciling the staged files with the
+// catalog.
+type CatalogReport struct {
+	Matched    []string
+	Mismatched []CatalogMismatch
+	// Uncataloged are staged files the catalog doesn't list, and Unstaged
+	// files it lists that aren't staged.
+	Uncataloged []string
+	Unstaged    []string
+}
+
+// ParseCatalogCSV reads catalog records from a CSV file with a header row. The
+// "path" and "sha256" columns are required, an "id" column is taken as the
+// catalog's identifier and every other column is kept as metadata. Paths are
+// relative to the workspace.
+func ParseCatalogCSV(r io.Reader) ([]CatalogRecord, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("unable to read the catalog's header: %v", err)
+	}
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	pathCol, hasPath := columns["path"]
+	sumCol, hasSum := columns["sha256"]
+	if !hasPath || !hasSum {
+		return nil, errors.New("the catalog needs \"path\" and \"sha256\" columns")
+	}
+	idCol, hasID := columns["id"]
+	var records []CatalogRecord
+	for {
+		row, err := reader.Read()
+		if err == io.EOF {
+			return records, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		record := CatalogRecord{
+			Path:   filepath.Clean(filepath.FromSlash(strings.TrimSpace(row[pathCol]))),
+			SHA256: strings.ToLower(strings.TrimSpace(row[sumCol])),
+		}
+		if _, err := hex.DecodeString(record.SHA256); err != nil || len(record.SHA256) != 64 {
+			return nil, fmt.Errorf("%v: invalid sha256 %q", record.Path, row[sumCol])
+		}
+		if hasID {
+			record.ID = strings.TrimSpace(row[idCol])
+		}
+		for i, value := range row {
+			if i == pathCol || i == sumCol || hasID && i == idCol || value == "" {
+				continue
+			}
+			if record.Metadata == nil {
+				record.Metadata = make(map[string]string)
+			}
+			record.Metadata[strings.TrimSpace(header[i])] = value
+		}
+		records = append(records, record)
+	}
+}
+
+// ReadCatalog returns the imported catalog records keyed by path. A missing
+// catalog is not an error.
+func ReadCatalog() (map[string]CatalogRecord, error) {
+	catalog := make(map[string]CatalogRecord)
+	data, err := ioutil.ReadFile(CatalogPath)
+	if os.IsNotExist(err) {
+		return catalog, nil
+	}
+	if err != nil {
+		return catalog, err
+	}
+	return catalog, json.Unmarshal(data, &catalog)
+}
+
+// WriteCatalog saves the catalog records.
+func WriteCatalog(catalog map[string]CatalogRecord) error {
+	data, err := json.MarshalIndent(catalog, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(CatalogPath, data, 0644)
+}
+
+// ReconcileCatalog compares the staged files with the catalog, checking the
+// checksum of each cataloged file with hash and marking the records that
+// matched as verified.
+func ReconcileCatalog(catalog map[string]CatalogRecord, staged []string,
+	hash func(path string) (string, error)) (report CatalogReport, err error) {
+	isStaged := make(map[string]bool, len(staged))
+	for _, path := range staged {
+		isStaged[path] = true
+		record, ok := catalog[path]
+		if !ok {
+			report.Uncataloged = append(report.Uncataloged, path)
+			continue
+		}
+		sum, err := hash(path)
+		if err != nil {
+			return report, err
+		}
+		record.Verified = sum == record.SHA256
+		catalog[path] = record
+		if record.Verified {
+			report.Matched = append(report.Matched, path)
+		} else {
+			report.Mismatched = append(report.Mismatched,
+				CatalogMismatch{Path: path, Expected: record.SHA256, Actual: sum})
+		}
+	}
+	for path := range catalog {
+		if !isStaged[path] {
+			report.Unstaged = append(report.Unstaged, path)
+		}
+	}
+	sort.Strings(report.Unstaged)
+	return report, nil
+}
+
+// FileSHA256 returns the hex encoded SHA-256 checksum of the file at path.
+func FileSHA256(path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+	sum := sha256.New()
+	if _, err = io.Copy(sum, file); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(sum.Sum(nil)), nil
+}
@@ -0,0 +1,51 @@
+package utils
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/arken/ait/platform"
+)
+
+// MinFreeSpace is the number of bytes that must be left free on a disk after
+// ait writes temporary files or data to it. It is set from the ait config.
+var MinFreeSpace int64
+
+// FreeSpace returns the number of bytes available to ait on the disk holding
+// path. Path doesn't need to exist yet, the closest existing parent is used.
+func FreeSpace(path string) (int64, error) {
+	path, err := filepath.Abs(path)
+	if err != nil {
+		return 0, err
+	}
+	for {
+		_, err = os.Stat(path)
+		if err == nil || !os.IsNotExist(err) || filepath.Dir(path) == path {
+			break
+		}
+		path = filepath.Dir(path)
+	}
+	if err != nil {
+		return 0, err
+	}
+	return platform.FreeSpace(path)
+}
+
+// CheckFreeSpace returns an error explaining the shortfall if writing need
+// bytes to dir would leave less than MinFreeSpace free on its disk. what names
+// the data for the error. Systems where free space can't be read always pass.
+func CheckFreeSpace(dir string, need int64, what string) error {
+	free, err := FreeSpace(dir)
+	if err == platform.ErrUnsupported {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if free-need >= MinFreeSpace {
+		return nil
+	}
+	return fmt.Errorf("not enough free space in %v for %v: %v needed and %v kept free, but only %v is free",
+		dir, what, FormatByteSize(need), FormatByteSize(MinFreeSpace), FormatByteSize(free))
+}
@@ -0,0 +1,245 @@
+package utils
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// FileMetadataPath holds the metadata users attached to files and directories
+// of the workspace, ie their license, by path.
+var FileMetadataPath = filepath.Join(".ait", "file_metadata.json")
+
+// TagsKey is the metadata key holding the tags of a file, separated by commas
+// and written as a list in keysets.
+const TagsKey = "tags"
+
+// metadataKey is what metadata keys look like, so they read the same in every
+// keyset.
+var metadataKey = regexp.MustCompile(`^[a-z][a-z0-9_-]*$`)
+
+// keysetMetadataPrefix starts the metadata lines following an entry of a
+// keyset. As comments of at least three fields they're skipped by readers of
+// the entries alone.
+const keysetMetadataPrefix = "#  "
+
+// ReadFileMetadata returns the metadata attached to the files and directories
+// of the workspace, by path.
+func ReadFileMetadata() (map[string]map[string]string, error) {
+	return ReadFileMetadataIn(".")
+}
+
+// ReadFileMetadataIn returns the metadata attached to the files and
+// directories of the workspace at dir, by path.
+func ReadFileMetadataIn(dir string) (map[string]map[string]string, error) {
+	metadata := make(map[string]map[string]string)
+	data, err := ioutil.ReadFile(inWorkspace(dir, FileMetadataPath))
+	if os.IsNotExist(err) {
+		return metadata, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return metadata, json.Unmarshal(data, &metadata)
+}
+
+// WriteFileMetadata records the metadata attached to the files and directories
+// of the workspace. Paths left without metadata are dropped.
+func WriteFileMetadata(metadata map[string]map[string]string) error {
+	for path, fields := range metadata {
+		if len(fields) == 0 {
+			delete(metadata, path)
+		}
+	}
+	data, err := json.MarshalIndent(metadata, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(FileMetadataPath, data, 0644)
+}
+
+// ParseMetadataAssignment splits a "key=value" argument. Tags are listed
+// without the spaces around their commas.
+func ParseMetadataAssignment(arg string) (key, value string, err error) {
+	parts := strings.SplitN(arg, "=", 2)
+	if len(parts) < 2 {
+		return "", "", fmt.Errorf("expected key=value, not %q", arg)
+	}
+	key, value = parts[0], strings.TrimSpace(parts[1])
+	if !metadataKey.MatchString(key) {
+		return "", "", fmt.Errorf("invalid key %q, keys are lowercase letters, digits, - and _", key)
+	}
+	if key == TagsKey {
+		value = joinTags(strings.Split(value, ","))
+	}
+	return key, value, nil
+}
+
+// joinTags joins the tags that aren't blank with commas.
+func joinTags(tags []string) string {
+	var kept []string
+	for _, tag := range tags {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			kept = append(kept, tag)
+		}
+	}
+	return strings.Join(kept, ",")
+}
+
+// MetadataFor returns the metadata of the file at path: the fields attached
+// to it and to the directories holding it, those closest to the file taking
+// precedence.
+func MetadataFor(metadata map[string]map[string]string, path string) map[string]string {
+	path = SlashPath(path)
+	var owners []string
+	for p := path; ; p = filepath.ToSlash(filepath.Dir(p)) {
+		owners = append(owners, p)
+		if p == "." || p == "/" || filepath.Dir(p) == p {
+			break
+		}
+	}
+	var fields map[string]string
+	for i := len(owners) - 1; i >= 0; i-- {
+		for key, value := range metadata[filepath.FromSlash(owners[i])] {
+			if fields == nil {
+				fields = make(map[string]string)
+			}
+			fields[key] = value
+		}
+	}
+	return fields
+}
+
+// KeysetMetadataLines returns the lines recording fields after an entry of a
+// keyset, as a YAML block commented out, sorted by key:
+//
+//	#  license: CC-BY-4.0
+//	#  tags: [genomics, rna]
+func KeysetMetadataLines(fields map[string]string) []string {
+	keys := make([]string, 0, len(fields))
+	for key, value := range fields {
+		// Empty values would leave lines of two fields, read as entries.
+		if value != "" && metadataKey.MatchString(key) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	lines := make([]string, 0, len(keys))
+	for _, key := range keys {
+		value := yamlScalar(fields[key])
+		if key == TagsKey {
+			tags := strings.Split(fields[key], ",")
+			for i := range tags {
+				tags[i] = yamlScalar(tags[i])
+			}
+			value = "[" + strings.Join(tags, ", ") + "]"
+		}
+		lines = append(lines, keysetMetadataPrefix+key+": "+value)
+	}
+	return lines
+}
+
+// yamlScalar returns s as a plain YAML scalar, or double quoted if it would
+// be read otherwise.
+func yamlScalar(s string) string {
+	if s == "" || strings.TrimSpace(s) != s || strings.ContainsAny(s[:1], "-?:,[]{}#&*!|>'\"%@`") ||
+		strings.Contains(s, ": ") || strings.Contains(s, " #") || strings.ContainsAny(s, ",[]{}") ||
+		strings.IndexFunc(s, func(r rune) bool { return r < ' ' }) >= 0 {
+		return strconv.Quote(s)
+	}
+	return s
+}
+
+// ParseKeysetMetadata reads the metadata recorded after the entries of a
+// keyset, by entry name. Comments that aren't right after an entry are left
+// alone.
+func ParseKeysetMetadata(r io.Reader) (map[string]map[string]string, error) {
+	metadata := make(map[string]map[string]string)
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), MaxKeysetLine)
+	entry := ""
+	for scanner.Scan() {
+		line := scanner.Text()
+		fields := strings.Fields(line)
+		if len(fields) == 2 && !strings.HasPrefix(line, "#") {
+			entry = fields[1]
+			continue
+		}
+		key, value, ok := parseKeysetMetadataLine(line)
+		if !ok || entry == "" {
+			entry = ""
+			continue
+		}
+		if metadata[entry] == nil {
+			metadata[entry] = make(map[string]string)
+		}
+		metadata[entry][key] = value
+	}
+	return metadata, scanner.Err()
+}
+
+// IsKeysetMetadataLine returns whether the line of a keyset records metadata
+// of the entry before it.
+func IsKeysetMetadataLine(line string) bool {
+	_, _, ok := parseKeysetMetadataLine(line)
+	return ok
+}
+
+// parseKeysetMetadataLine reads a line written by KeysetMetadataLines.
+func parseKeysetMetadataLine(line string) (key, value string, ok bool) {
+	if !strings.HasPrefix(line, keysetMetadataPrefix) {
+		return "", "", false
+	}
+	parts := strings.SplitN(strings.TrimPrefix(line, keysetMetadataPrefix), ": ", 2)
+	if len(parts) < 2 || !metadataKey.MatchString(parts[0]) {
+		return "", "", false
+	}
+	key, value = parts[0], strings.TrimSpace(parts[1])
+	if strings.HasPrefix(value, "[") && strings.HasSuffix(value, "]") {
+		var items []string
+		for _, item := range splitYAMLList(value[1 : len(value)-1]) {
+			items = append(items, unquoteYAML(item))
+		}
+		return key, joinTags(items), true
+	}
+	return key, unquoteYAML(value), true
+}
+
+// splitYAMLList splits the items of a YAML flow list at the commas outside of
+// double quotes.
+func splitYAMLList(s string) []string {
+	var items []string
+	quoted, escaped, start := false, false, 0
+	for i, r := range s {
+		switch {
+		case escaped:
+			escaped = false
+		case r == '\\' && quoted:
+			escaped = true
+		case r == '"':
+			quoted = !quoted
+		case r == ',' && !quoted:
+			items = append(items, strings.TrimSpace(s[start:i]))
+			start = i + 1
+		}
+	}
+	return append(items, strings.TrimSpace(s[start:]))
+}
+
+// unquoteYAML returns the value of a YAML scalar written by yamlScalar.
+func unquoteYAML(s string) string {
+	if strings.HasPrefix(s, `"`) {
+		if unquoted, err := strconv.Unquote(s); err == nil {
+			return unquoted
+		}
+	}
+	return s
+}
@@ -0,0 +1,48 @@
+//go:build go1.18
+// +build go1.18
+
+package utils
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	format "github.com/go-git/go-git/v5/plumbing/format/config"
+)
+
+func FuzzParseKeysetEntries(f *testing.F) {
+	f.Add([]byte("bafyone  a.txt\nbafytwo  b.txt\n"))
+	f.Add([]byte("malformed line with too many fields\n\n  \nbafy  \x1b[2Jname\n"))
+	f.Add([]byte(strings.Repeat("x", MaxKeysetLine+1) + "\nbafy  after.txt"))
+	f.Fuzz(func(t *testing.T, data []byte) {
+		entries, err := ParseKeysetEntries(bytes.NewReader(data))
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, entry := range entries {
+			if entry.CID == "" || entry.Name == "" || hasControl(entry.CID) || hasControl(entry.Name) ||
+				strings.ContainsAny(entry.CID+entry.Name, " \t\n") {
+				t.Fatalf("malformed entry %q", entry)
+			}
+		}
+	})
+}
+
+func FuzzRemoteURL(f *testing.F) {
+	f.Add("https://github.com/arken/ait.git", "[url \"https://github.com/\"]\n\tinsteadOf = gh:\n")
+	f.Add("gh:arken/ait", "[url \"https://github.com/\"]\n\tpushInsteadOf = gh:\n")
+	f.Add(".git", "")
+	f.Fuzz(func(t *testing.T, url, gitconfig string) {
+		raw := format.New()
+		if err := format.NewDecoder(strings.NewReader(gitconfig)).Decode(raw); err == nil {
+			url = rewriteURL(urlRules(raw), url, true)
+		}
+		if name := GetRepoName(url); strings.Contains(name, "/") {
+			t.Fatalf("the name of %q is %q", url, name)
+		}
+		if owner := GetRepoOwner(url); strings.Contains(owner, "/") {
+			t.Fatalf("the owner of %q is %q", url, owner)
+		}
+	})
+}
@@ -0,0 +1,117 @@
+package utils
+
+import (
+	"bufio"
+	"bytes"
+	"os"
+	"os/exec"
+	"strings"
+
+	gitconfig "github.com/go-git/go-git/v5/config"
+	format "github.com/go-git/go-git/v5/plumbing/format/config"
+)
+
+// urlRule is a url.<base>.insteadOf or pushInsteadOf rule from git config.
+type urlRule struct {
+	base   string
+	prefix string
+	push   bool
+}
+
+// readURLRules reads the URL rewrite rules from the system and global git
+// config.
+func readURLRules() []urlRule {
+	var rules []urlRule
+	for _, scope := range []gitconfig.Scope{gitconfig.SystemScope, gitconfig.GlobalScope} {
+		paths, err := gitconfig.Paths(scope)
+		if err != nil {
+			continue
+		}
+		for _, path := range paths {
+			file, err := os.Open(path)
+			if err != nil {
+				continue
+			}
+			raw := format.New()
+			err = format.NewDecoder(file).Decode(raw)
+			file.Close()
+			if err != nil {
+				continue
+			}
+			rules = append(rules, urlRules(raw)...)
+		}
+	}
+	return rules
+}
+
+// urlRules returns the URL rewrite rules in a git config.
+func urlRules(raw *format.Config) (rules []urlRule) {
+	for _, sub := range raw.Section("url").Subsections {
+		for _, prefix := range sub.OptionAll("insteadOf") {
+			rules = append(rules, urlRule{base: sub.Name, prefix: prefix})
+		}
+		for _, prefix := range sub.OptionAll("pushInsteadOf") {
+			rules = append(rules, urlRule{base: sub.Name, prefix: prefix, push: true})
+		}
+	}
+	return rules
+}
+
+// rewriteURL applies the rule with the longest matching prefix to url, like
+// git. For pushes, pushInsteadOf rules take precedence over insteadOf.
+func rewriteURL(rules []urlRule, url string, push bool) string {
+	var best *urlRule
+	for i, rule := range rules {
+		if rule.push && !push || !strings.HasPrefix(url, rule.prefix) {
+			continue
+		}
+		if best == nil || rule.push && !best.push ||
+			rule.push == best.push && len(rule.prefix) > len(best.prefix) {
+			best = &rules[i]
+		}
+	}
+	if best == nil {
+		return url
+	}
+	return best.base + strings.TrimPrefix(url, best.prefix)
+}
+
+// RewriteURL applies the url.<base>.insteadOf rules of the user's git config
+// to url, and the pushInsteadOf rules when it will be pushed to, so remotes
+// resolve the same way they do for git.
+func RewriteURL(url string, push bool) string {
+	return rewriteURL(readURLRules(), url, push)
+}
+
+// GitCredential asks git's credential helpers for the username and password
+// of url, so per-host credential.<url> sections and helpers are honored. It
+// never prompts, and returns false if git has no credential for url.
+func GitCredential(url string) (username, password string, ok bool) {
+	cmd := exec.Command("git", "credential", "fill")
+	cmd.Env = append(os.Environ(), "GIT_TERMINAL_PROMPT=0", "GIT_ASKPASS=", "SSH_ASKPASS=")
+	cmd.Stdin = strings.NewReader("url=" + url + "\n\n")
+	out, err := cmd.Output()
+	if err != nil {
+		return "", "", false
+	}
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	for scanner.Scan() {
+		key, value := splitKeyValue(scanner.Text())
+		switch key {
+		case "username":
+			username = value
+		case "password":
+			password = value
+		}
+	}
+	return username, password, password != ""
+}
+
+// splitKeyValue splits a "key=value" line at the first "=".
+func splitKeyValue(line string) (key, value string) {
+	parts := strings.SplitN(line, "=", 2)
+	if len(parts) < 2 {
+		return parts[0], ""
+	}
+	return parts[0], parts[1]
+}
@@ -0,0 +1,96 @@
+package utils
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+// HandoffPath holds the CIDs of staged files received through ait handoff so
+// they don't have to be hashed again.
+var HandoffPath = filepath.Join(".ait", "handoff")
+
+// HandoffEntry is a staged file along with the CID and size it had on the
+// machine that handed it off.
+type HandoffEntry struct {
+	Path string `json:"path"`
+	CID  string `json:"cid"`
+	Size int64  `json:"size"`
+}
+
+// ReadHandoff returns the received entries keyed by path. A missing file is
+// not an error.
+func ReadHandoff() (map[string]HandoffEntry, error) {
+	return ReadHandoffIn(".")
+}
+
+// ReadHandoffIn returns the received entries of the workspace at dir keyed by
+// path.
+func ReadHandoffIn(dir string) (map[string]HandoffEntry, error) {
+	result := make(map[string]HandoffEntry)
+	data, err := ioutil.ReadFile(inWorkspace(dir, HandoffPath))
+	if os.IsNotExist(err) {
+		return result, nil
+	}
+	if err != nil {
+		return result, err
+	}
+	var entries []HandoffEntry
+	if err = json.Unmarshal(data, &entries); err != nil {
+		return result, err
+	}
+	for _, entry := range entries {
+		result[entry.Path] = entry
+	}
+	return result, nil
+}
+
+// WriteHandoff merges entries into the received entries.
+func WriteHandoff(entries []HandoffEntry) error {
+	return WriteHandoffIn(".", entries)
+}
+
+// WriteHandoffIn merges entries into the received entries of the workspace at
+// dir.
+func WriteHandoffIn(dir string, entries []HandoffEntry) error {
+	known, err := ReadHandoffIn(dir)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		known[entry.Path] = entry
+	}
+	merged := make([]HandoffEntry, 0, len(known))
+	for _, entry := range known {
+		merged = append(merged, entry)
+	}
+	data, err := json.Marshal(merged)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(inWorkspace(dir, HandoffPath), data, 0644)
+}
+
+// HandoffCID returns the handed off CID of the file at path if the file still
+// has the size it had on the sending machine. Files that don't exist locally,
+// ie when submitting a reviewed bundle, are taken on trust.
+func HandoffCID(entries map[string]HandoffEntry, path string) (string, bool) {
+	return HandoffCIDIn(".", entries, path)
+}
+
+// HandoffCIDIn is HandoffCID for the file at path in the workspace at dir.
+func HandoffCIDIn(dir string, entries map[string]HandoffEntry, path string) (string, bool) {
+	entry, ok := entries[path]
+	if !ok {
+		return "", false
+	}
+	size, err := GetFileSize(inWorkspace(dir, path))
+	if os.IsNotExist(err) {
+		return entry.CID, true
+	}
+	if err != nil || size != entry.Size {
+		return "", false
+	}
+	return entry.CID, true
+}
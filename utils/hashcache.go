@@ -0,0 +1,145 @@
+package utils
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/arken/ait/platform"
+)
+
+// HashCachePath holds the CIDs the files of the workspace were hashed to, so
+// the files that didn't change since aren't hashed again. It's a log of JSON
+// lines, the last line of a path holding its CID.
+var HashCachePath = filepath.Join(".ait", "cache", "cids.jsonl")
+
+// Rehash makes hash caches ignore the CIDs they hold, every file is hashed
+// again and the CIDs it gets recorded.
+var Rehash bool
+
+// hashCacheEntry is the CID a file of the workspace was hashed to, as it was
+// then.
+type hashCacheEntry struct {
+	Path    string `json:"path"`
+	Size    int64  `json:"size"`
+	ModTime int64  `json:"mtime"`
+	Inode   uint64 `json:"inode,omitempty"`
+	// Params are the parameters the file was hashed with, the CID only holds
+	// for the same.
+	Params string `json:"params"`
+	CID    string `json:"cid"`
+}
+
+// HashCache maps the files of a workspace to the CIDs they were hashed to,
+// for as long as their size, modification time and inode are unchanged. It's
+// safe for concurrent use.
+type HashCache struct {
+	lock    sync.Mutex
+	path    string
+	entries map[string]hashCacheEntry
+	log     *os.File
+}
+
+// OpenHashCache reads the hash cache at path. A cache that can't be read is
+// started over, it only saves time.
+func OpenHashCache(path string) *HashCache {
+	c := &HashCache{path: path, entries: make(map[string]hashCacheEntry)}
+	file, err := os.Open(path)
+	if err != nil {
+		return c
+	}
+	defer file.Close()
+	lines := 0
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var entry hashCacheEntry
+		if json.Unmarshal(scanner.Bytes(), &entry) == nil && entry.Path != "" {
+			c.entries[entry.Path] = entry
+			lines++
+		}
+	}
+	// The log is compacted once most of its lines were superseded.
+	if lines > 2*len(c.entries)+1000 {
+		_ = c.compact()
+	}
+	return c
+}
+
+// Get returns the CID the file at rel, within the workspace, was hashed to
+// with params, if it's unchanged since. info describes the file as it is now.
+func (c *HashCache) Get(rel string, info os.FileInfo, params string) (string, bool) {
+	if Rehash {
+		return "", false
+	}
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	entry, ok := c.entries[rel]
+	if !ok || entry.Params != params || entry.Size != info.Size() ||
+		entry.ModTime != info.ModTime().UnixNano() || entry.Inode != platform.FileID(info) {
+		return "", false
+	}
+	return entry.CID, true
+}
+
+// Last returns the CID the file at rel, within the workspace, was last hashed
+// to, whatever it's like now, ie once it's gone.
+func (c *HashCache) Last(rel string) (string, bool) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	entry, ok := c.entries[rel]
+	return entry.CID, ok
+}
+
+// Put records that the file at rel, described by info, was hashed to cid with
+// params.
+func (c *HashCache) Put(rel string, info os.FileInfo, params, cid string) error {
+	entry := hashCacheEntry{Path: rel, Size: info.Size(), ModTime: info.ModTime().UnixNano(),
+		Inode: platform.FileID(info), Params: params, CID: cid}
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.entries[rel] = entry
+	if c.log == nil {
+		if err = os.MkdirAll(filepath.Dir(c.path), os.ModePerm); err != nil {
+			return err
+		}
+		c.log, err = os.OpenFile(c.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+		if err != nil {
+			return err
+		}
+	}
+	_, err = c.log.Write(append(line, '\n'))
+	return err
+}
+
+// compact rewrites the log with the last line of each path only.
+func (c *HashCache) compact() error {
+	var buf bytes.Buffer
+	for _, entry := range c.entries {
+		line, err := json.Marshal(entry)
+		if err != nil {
+			return err
+		}
+		buf.Write(append(line, '\n'))
+	}
+	return writeAtomic(c.path, buf.Bytes())
+}
+
+// Close closes the log of the cache.
+func (c *HashCache) Close() error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if c.log == nil {
+		return nil
+	}
+	err := c.log.Close()
+	c.log = nil
+	return err
+}
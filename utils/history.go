@@ -0,0 +1,225 @@
+package utils
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// HistoryPath is the directory holding a record of each submission made from
+// the workspace.
+var HistoryPath = filepath.Join(".ait", "history")
+
+// KeysetEntry is a single line of a keyset file.
+type KeysetEntry struct {
+	CID  string `json:"cid"`
+	Name string `json:"name"`
+}
+
+// Submission records where and what was submitted.
+type Submission struct {
+	ID          string        `json:"id"`
+	Time        time.Time     `json:"time"`
+	Remote      string        `json:"remote"`
+	Path        string        `json:"path"`
+	Commit      string        `json:"commit,omitempty"`
+	PullRequest string        `json:"pullRequest,omitempty"`
+	PRNumber    int           `json:"prNumber,omitempty"`
+	Issue       string        `json:"issue,omitempty"`
+	Entries     []KeysetEntry `json:"entries"`
+	// Replicated is set once the replication of every entry has been
+	// reported on the pull request.
+	Replicated bool `json:"replicated,omitempty"`
+	// Safe is set once every entry has reached the replication target, and
+	// AtRisk while the user has been alerted that it no longer does.
+	Safe   bool `json:"safe,omitempty"`
+	AtRisk bool `json:"atRisk,omitempty"`
+	// Receipt is set when the submission was recorded in a transparency log.
+	Receipt *Receipt `json:"receipt,omitempty"`
+	// Root is the CID of a directory holding every submitted file.
+	Root string `json:"root,omitempty"`
+	// ROCrate is set when Root is packaged as an RO-Crate.
+	ROCrate bool `json:"roCrate,omitempty"`
+	// Links are public gateway URLs of the dataset root and each file.
+	Links []GatewayLink `json:"links,omitempty"`
+	// DOI is set when a DOI was minted for the dataset.
+	DOI string `json:"doi,omitempty"`
+	// Replication is how many entries had reached the replication target
+	// each time it was checked, oldest first.
+	Replication []ReplicationSample `json:"replication,omitempty"`
+	// Catalog maps submitted paths to their IDs in the lab's data catalog,
+	// for files whose checksum matched it.
+	Catalog map[string]string `json:"catalog,omitempty"`
+	// Metadata maps submitted paths to the fields the metadata command
+	// collected for them.
+	Metadata map[string]map[string]string `json:"metadata,omitempty"`
+	// Protected is set while the entries are protected from garbage
+	// collection, until the submission is merged and replicated.
+	Protected bool `json:"protected,omitempty"`
+}
+
+// ReplicationSample is the number of a submission's entries that had reached
+// the replication target at a point in time.
+type ReplicationSample struct {
+	Time       time.Time `json:"time"`
+	Replicated int       `json:"replicated"`
+}
+
+// maxReplicationSamples keeps a month of hourly checks.
+const maxReplicationSamples = 720
+
+// GatewayLink is a public gateway URL for a submitted file, or the whole
+// dataset when Name is empty.
+type GatewayLink struct {
+	Name string `json:"name,omitempty"`
+	URL  string `json:"url"`
+}
+
+// Receipt is a signed statement of a submission and where it was logged.
+type Receipt struct {
+	Statement []byte `json:"statement"`
+	Signature []byte `json:"signature"`
+	Submitter string `json:"submitter"`
+	LogIndex  int64  `json:"logIndex"`
+	LogEntry  string `json:"logEntry"`
+}
+
+// NewSubmission creates a record of a submission made now.
+func NewSubmission(remote, path string, entries []KeysetEntry) *Submission {
+	now := time.Now()
+	return &Submission{
+		ID:      now.Format("20060102-150405"),
+		Time:    now,
+		Remote:  remote,
+		Path:    path,
+		Entries: entries,
+	}
+}
+
+// Save writes the submission to the history.
+func (s *Submission) Save() error {
+	return s.SaveIn(HistoryPath)
+}
+
+// SaveIn writes the submission to the history directory dir, ie of another
+// workspace.
+func (s *Submission) SaveIn(dir string) error {
+	err := os.MkdirAll(dir, os.ModePerm)
+	if err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(filepath.Join(dir, s.ID+".json"), data, 0644)
+}
+
+// RecordReplication adds a sample of how many entries are replicated now,
+// dropping the oldest samples beyond maxReplicationSamples.
+func (s *Submission) RecordReplication(replicated int) {
+	s.Replication = append(s.Replication, ReplicationSample{Time: time.Now(), Replicated: replicated})
+	if extra := len(s.Replication) - maxReplicationSamples; extra > 0 {
+		s.Replication = append([]ReplicationSample(nil), s.Replication[extra:]...)
+	}
+}
+
+// ReadHistory returns the recorded submissions, oldest first.
+func ReadHistory() (history []*Submission, err error) {
+	return ReadHistoryIn(HistoryPath)
+}
+
+// ReadHistoryIn returns the submissions recorded in the history directory
+// dir, ie of another workspace, oldest first.
+func ReadHistoryIn(dir string) (history []*Submission, err error) {
+	files, err := ioutil.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return history, nil
+	}
+	if err != nil {
+		return history, err
+	}
+	for _, info := range files {
+		if filepath.Ext(info.Name()) != ".json" {
+			continue
+		}
+		data, err := ioutil.ReadFile(filepath.Join(dir, info.Name()))
+		if err != nil {
+			return history, err
+		}
+		s := &Submission{}
+		if err = json.Unmarshal(data, s); err != nil {
+			return history, err
+		}
+		history = append(history, s)
+	}
+	sort.Slice(history, func(i, j int) bool {
+		return history[i].Time.Before(history[j].Time)
+	})
+	return history, nil
+}
+
+// ReadKeysetEntries parses the keyset file at path.
+func ReadKeysetEntries(path string) (entries []KeysetEntry, err error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return entries, err
+	}
+	defer file.Close()
+	return ParseKeysetEntries(file)
+}
+
+// ParseKeysetEntries parses the entries of a keyset file.
+func ParseKeysetEntries(r io.Reader) (entries []KeysetEntry, err error) {
+	err = ScanKeysetEntries(r, func(entry KeysetEntry) error {
+		entries = append(entries, entry)
+		return nil
+	})
+	return entries, err
+}
+
+// MaxKeysetLine bounds the memory held for a single line of a keyset.
+const MaxKeysetLine = 64 * 1024
+
+// ScanKeysetEntries calls fn with each entry read from r, one line at a time,
+// so memory use doesn't grow with the size of the keyset. Keysets come from
+// upstream repositories, so malformed lines are skipped rather than trusted:
+// lines without exactly a CID and a name, lines longer than MaxKeysetLine and
+// entries holding control characters, which could rewrite the terminal when
+// printed.
+func ScanKeysetEntries(r io.Reader, fn func(KeysetEntry) error) error {
+	reader := bufio.NewReaderSize(r, MaxKeysetLine)
+	for {
+		line, err := reader.ReadSlice('\n')
+		if err == bufio.ErrBufferFull {
+			for err == bufio.ErrBufferFull {
+				_, err = reader.ReadSlice('\n')
+			}
+			line = nil
+		}
+		if err != nil && err != io.EOF {
+			return err
+		}
+		fields := strings.Fields(string(line))
+		if len(fields) == 2 && !hasControl(fields[0]) && !hasControl(fields[1]) {
+			if fnErr := fn(KeysetEntry{CID: fields[0], Name: fields[1]}); fnErr != nil {
+				return fnErr
+			}
+		}
+		if err == io.EOF {
+			return nil
+		}
+	}
+}
+
+// hasControl returns whether s holds a control character.
+func hasControl(s string) bool {
+	return strings.IndexFunc(s, unicode.IsControl) >= 0
+}
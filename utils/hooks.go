@@ -0,0 +1,102 @@
+package utils
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// HooksPath is the directory of the workspace's hooks, programs run at the
+// steps of staging and submitting files like git hooks.
+var HooksPath = filepath.Join(".ait", "hooks")
+
+// The hooks run, each named after the step it runs at.
+const (
+	// HookPreAdd runs before files are staged, which it can prevent.
+	HookPreAdd = "pre-add"
+	// HookPreSubmit runs before the keyset of a submission is generated,
+	// which it can prevent.
+	HookPreSubmit = "pre-submit"
+	// HookPostSubmit runs once a submission succeeded.
+	HookPostSubmit = "post-submit"
+)
+
+// HookOutput is where hooks print to, the terminal unless ait draws on it.
+var HookOutput io.Writer = os.Stdout
+
+// hookExtensions are the extensions hooks may have on Windows, which runs
+// programs by their extension.
+var hookExtensions = []string{"", ".exe", ".bat", ".cmd"}
+
+// hookPath returns the path of the hook named name, or nothing if the
+// workspace at dir doesn't have it.
+func hookPath(dir, name string) string {
+	extensions := hookExtensions[:1]
+	if runtime.GOOS == "windows" {
+		extensions = hookExtensions
+	}
+	for _, ext := range extensions {
+		path := filepath.Join(inWorkspace(dir, HooksPath), name+ext)
+		if info, err := os.Stat(path); err == nil && !info.IsDir() {
+			if runtime.GOOS != "windows" && info.Mode()&0111 == 0 {
+				fmt.Fprintf(HookOutput, "[The %v hook was ignored because it isn't executable, "+
+					"chmod +x %v to run it.]\n", name, path)
+				return ""
+			}
+			return path
+		}
+	}
+	return ""
+}
+
+// RunHook runs the hook named name, if the workspace has it, with paths on
+// its standard input, one per line, and the keyset path as its argument when
+// it's given. env holds the other KEY=value pairs it's run with. A hook
+// exiting with an error fails the step it runs at, the error telling what it
+// printed last.
+func RunHook(name string, paths []string, keyset string, env ...string) error {
+	return RunHookIn(".", name, paths, keyset, env...)
+}
+
+// RunHookIn runs the hook named name of the workspace at dir like RunHook, in
+// that directory.
+func RunHookIn(dir, name string, paths []string, keyset string, env ...string) error {
+	path := hookPath(dir, name)
+	if path == "" {
+		return nil
+	}
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return err
+	}
+	var args []string
+	if keyset != "" {
+		args = append(args, keyset)
+	}
+	cmd := exec.Command(abs, args...)
+	cmd.Dir = dir
+	cmd.Stdin = strings.NewReader(strings.Join(paths, "\n") + "\n")
+	cmd.Env = append(os.Environ(), "AIT_HOOK="+name, "AIT_KEYSET="+keyset)
+	cmd.Env = append(cmd.Env, env...)
+	var stderr bytes.Buffer
+	cmd.Stdout = HookOutput
+	cmd.Stderr = io.MultiWriter(HookOutput, &stderr)
+	if err = cmd.Run(); err != nil {
+		if msg := lastLine(stderr.String()); msg != "" {
+			return fmt.Errorf("the %v hook failed: %v: %v", name, err, msg)
+		}
+		return fmt.Errorf("the %v hook failed: %v", name, err)
+	}
+	return nil
+}
+
+// lastLine returns the last line of text that isn't blank.
+func lastLine(text string) string {
+	lines := strings.Split(strings.TrimSpace(text), "\n")
+	return strings.TrimSpace(lines[len(lines)-1])
+}
@@ -0,0 +1,73 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// CleanName returns the name without surrounding spaces and with its inner
+// spaces collapsed, or an error if it can't sign a commit. Any script is
+// accepted, only the characters git uses to delimit the name are refused.
+func CleanName(name string) (string, error) {
+	name = strings.Join(strings.Fields(name), " ")
+	if name == "" {
+		return "", errors.New("the name is empty")
+	}
+	for _, r := range name {
+		if unicode.IsControl(r) || r == '<' || r == '>' {
+			return "", fmt.Errorf("the name can't contain %q", r)
+		}
+	}
+	return name, nil
+}
+
+// CleanEmail returns the email address without surrounding spaces, or an error
+// if it isn't one. Addresses in any script are accepted, ie "josé@bücher.de".
+// An address without a domain is completed with the preset domain when
+// exactly one is given.
+func CleanEmail(email string, domains []string) (string, error) {
+	email = strings.TrimSpace(email)
+	if !strings.Contains(email, "@") && email != "" && len(domains) == 1 {
+		email += "@" + domains[0]
+	}
+	at := strings.LastIndex(email, "@")
+	if at < 1 || at == len(email)-1 {
+		return "", fmt.Errorf("%q isn't an email address, ie jane@example.org", email)
+	}
+	local, domain := email[:at], email[at+1:]
+	for _, r := range local {
+		if unicode.IsSpace(r) || unicode.IsControl(r) || strings.ContainsRune(`"(),:;<>@[\]`, r) {
+			return "", fmt.Errorf("%q can't be in the name of an email address", r)
+		}
+	}
+	labels := strings.Split(domain, ".")
+	if len(labels) < 2 {
+		return "", fmt.Errorf("%v isn't a domain, ie example.org", domain)
+	}
+	for _, label := range labels {
+		if label == "" || strings.HasPrefix(label, "-") || strings.HasSuffix(label, "-") {
+			return "", fmt.Errorf("%v isn't a domain, ie example.org", domain)
+		}
+		for _, r := range label {
+			if !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '-' {
+				return "", fmt.Errorf("%q can't be in the domain of an email address", r)
+			}
+		}
+	}
+	return email, nil
+}
+
+// InEmailDomains returns whether the email address is at one of the domains,
+// or any of their subdomains.
+func InEmailDomains(email string, domains []string) bool {
+	domain := strings.ToLower(email[strings.LastIndex(email, "@")+1:])
+	for _, d := range domains {
+		d = strings.ToLower(d)
+		if domain == d || strings.HasSuffix(domain, "."+d) {
+			return true
+		}
+	}
+	return false
+}
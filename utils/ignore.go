@@ -0,0 +1,176 @@
+package utils
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// IgnoreFileName is the name of the files listing, in gitignore syntax, the
+// paths directory walks skip when staging. Each applies to the directory it's
+// in, and those of deeper directories take precedence.
+const IgnoreFileName = ".aitignore"
+
+// ignoreRule is a single pattern of an ignore file.
+type ignoreRule struct {
+	re *regexp.Regexp
+	// negate re-includes the paths the pattern matches, for "!" patterns.
+	negate bool
+	// dirOnly only matches directories, for patterns ending with "/".
+	dirOnly bool
+}
+
+// Ignore decides which paths of the workspace its ignore files exclude. The
+// ignore file of each directory is read once. It's safe for concurrent use,
+// and a nil *Ignore excludes nothing.
+type Ignore struct {
+	lock  sync.Mutex
+	rules map[string][]ignoreRule
+	// root is the directory of the workspace.
+	root string
+}
+
+// NewIgnore returns an Ignore reading the ignore files of the workspace as
+// they're needed.
+func NewIgnore() *Ignore {
+	return NewIgnoreIn(".")
+}
+
+// NewIgnoreIn returns an Ignore reading the ignore files of the workspace at
+// root.
+func NewIgnoreIn(root string) *Ignore {
+	return &Ignore{rules: make(map[string][]ignoreRule), root: root}
+}
+
+// Ignored returns whether the path, relative to the root of the workspace, is
+// excluded by the ignore files of its directory and those above it. As with
+// gitignore the last matching pattern decides, and a negated pattern can't
+// re-include a path whose directory is excluded, since it's never walked.
+func (ig *Ignore) Ignored(path string, isDir bool) bool {
+	if ig == nil {
+		return false
+	}
+	path = filepath.ToSlash(filepath.Clean(path))
+	ignored := false
+	dir := "."
+	rel := path
+	for {
+		for _, rule := range ig.load(dir) {
+			if (!rule.dirOnly || isDir) && rule.re.MatchString(rel) {
+				ignored = !rule.negate
+			}
+		}
+		slash := strings.Index(rel, "/")
+		if slash < 0 {
+			return ignored
+		}
+		if dir == "." {
+			dir = rel[:slash]
+		} else {
+			dir += "/" + rel[:slash]
+		}
+		rel = rel[slash+1:]
+	}
+}
+
+// load returns the rules of the ignore file of dir, reading it the first time.
+func (ig *Ignore) load(dir string) []ignoreRule {
+	ig.lock.Lock()
+	defer ig.lock.Unlock()
+	if rules, ok := ig.rules[dir]; ok {
+		return rules
+	}
+	var rules []ignoreRule
+	if file, err := os.Open(filepath.Join(inWorkspace(ig.root, filepath.FromSlash(dir)), IgnoreFileName)); err == nil {
+		rules = parseIgnore(file)
+		file.Close()
+	}
+	ig.rules[dir] = rules
+	return rules
+}
+
+// parseIgnore reads the patterns of an ignore file, skipping blank lines,
+// comments and patterns that can't be used.
+func parseIgnore(file *os.File) []ignoreRule {
+	var rules []ignoreRule
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		if rule, ok := parseIgnoreLine(scanner.Text()); ok {
+			rules = append(rules, rule)
+		}
+	}
+	return rules
+}
+
+// parseIgnoreLine converts a gitignore pattern to a rule matching paths
+// relative to the directory of the ignore file.
+func parseIgnoreLine(line string) (ignoreRule, bool) {
+	var rule ignoreRule
+	line = strings.TrimRight(line, " \t\r")
+	if line == "" || strings.HasPrefix(line, "#") {
+		return rule, false
+	}
+	if strings.HasPrefix(line, "!") {
+		rule.negate = true
+		line = line[1:]
+	} else if strings.HasPrefix(line, `\!`) || strings.HasPrefix(line, `\#`) {
+		line = line[1:]
+	}
+	if strings.HasSuffix(line, "/") {
+		rule.dirOnly = true
+		line = strings.TrimRight(line, "/")
+	}
+	if line == "" {
+		return rule, false
+	}
+	// Patterns with a slash other than a trailing one are relative to the
+	// directory of the ignore file, others match a name at any depth.
+	anchored := strings.Contains(line, "/")
+	line = strings.TrimPrefix(line, "/")
+	var expr strings.Builder
+	expr.WriteString("^")
+	if !anchored {
+		expr.WriteString("(.*/)?")
+	}
+	for i := 0; i < len(line); i++ {
+		switch {
+		case strings.HasPrefix(line[i:], "**/"):
+			expr.WriteString("(.*/)?")
+			i += 2
+		case strings.HasPrefix(line[i:], "**"):
+			expr.WriteString(".*")
+			i++
+		case line[i] == '*':
+			expr.WriteString("[^/]*")
+		case line[i] == '?':
+			expr.WriteString("[^/]")
+		case line[i] == '[':
+			end := strings.Index(line[i+1:], "]")
+			if end < 0 {
+				expr.WriteString(`\[`)
+				continue
+			}
+			class := line[i+1 : i+1+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			expr.WriteString("[" + strings.ReplaceAll(class, `\`, `\\`) + "]")
+			i += end + 1
+		case line[i] == '\\' && i+1 < len(line):
+			i++
+			expr.WriteString(regexp.QuoteMeta(line[i : i+1]))
+		default:
+			expr.WriteString(regexp.QuoteMeta(line[i : i+1]))
+		}
+	}
+	expr.WriteString("$")
+	re, err := regexp.Compile(expr.String())
+	if err != nil {
+		return rule, false
+	}
+	rule.re = re
+	return rule, true
+}
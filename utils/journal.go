@@ -0,0 +1,161 @@
+package utils
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/arken/ait/types"
+
+	fslock "github.com/ipfs/go-fs-lock"
+)
+
+// journalSuffix is appended to a file's path to get the path of its
+// write-ahead journal.
+const journalSuffix = ".journal"
+
+// journalCommit prefixes the last line of a complete journal. It is followed
+// by the number of entries the journal holds.
+const journalCommit = "#commit "
+
+// WriteStaged replaces the staged files with the given set, ie to restore
+// them. Changes to the staged files go through UpdateStaged instead, so they
+// don't undo those of other ait processes.
+func WriteStaged(contents types.StringSet) error {
+	lk, err := LockStaged()
+	if err != nil {
+		return err
+	}
+	defer lk.Close()
+	return writeStaged(".", contents)
+}
+
+// writeStaged replaces the staged files of the workspace at dir with the given
+// set, with the lock held. The new contents are first written to a journal which is replayed by
+// RecoverStaged if ait is interrupted before the staging file has been
+// replaced. When each file was staged is recorded alongside.
+func writeStaged(dir string, contents types.StringSet) error {
+	if err := writeJournaled(inWorkspace(dir, AddedFilesPath), contents); err != nil {
+		return err
+	}
+	return updateStagedTimes(dir, contents)
+}
+
+// RecoverStaged completes a staging operation that was interrupted by a crash.
+// It returns true if an operation had to be recovered.
+func RecoverStaged() (bool, error) {
+	// Only the journal of a process that's gone is recovered, not that of
+	// one writing it right now.
+	lk, err := lockStaged(".", 0)
+	var locked fslock.LockedError
+	if errors.As(err, &locked) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	defer lk.Close()
+	return recoverJournal(AddedFilesPath)
+}
+
+// writeJournaled writes contents to a journal next to path, syncs it, and
+// then atomically swaps it in place of path.
+func writeJournaled(path string, contents types.StringSet) error {
+	buf := new(bytes.Buffer)
+	_ = contents.ForEach(func(line string) error {
+		buf.WriteString(line)
+		buf.WriteByte('\n')
+		return nil
+	})
+	fmt.Fprintf(buf, "%s%d\n", journalCommit, contents.Size())
+	journal := path + journalSuffix
+	if err := writeSynced(journal, buf.Bytes()); err != nil {
+		return err
+	}
+	return replayJournal(path, buf.Bytes())
+}
+
+// recoverJournal replays the journal of path if it was completely written and
+// discards it otherwise, leaving path as it was before the operation.
+func recoverJournal(path string) (bool, error) {
+	journal := path + journalSuffix
+	data, err := ioutil.ReadFile(journal)
+	if os.IsNotExist(err) {
+		_ = os.Remove(path + ".tmp")
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	if !journalComplete(data) {
+		return true, os.Remove(journal)
+	}
+	return true, replayJournal(path, data)
+}
+
+// replayJournal writes the entries of a complete journal over path and removes
+// the journal once path is safely on disk.
+func replayJournal(path string, journal []byte) error {
+	end := bytes.LastIndex(bytes.TrimSuffix(journal, []byte("\n")), []byte("\n"))
+	entries := journal[:end+1]
+	tmp := path + ".tmp"
+	if err := writeSynced(tmp, entries); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return err
+	}
+	return os.Remove(path + journalSuffix)
+}
+
+// journalComplete checks that the journal ends with a commit line matching the
+// number of entries written before it.
+func journalComplete(journal []byte) bool {
+	scanner := bufio.NewScanner(bytes.NewReader(journal))
+	var lines []string
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+	if len(lines) == 0 || !bytes.HasSuffix(journal, []byte("\n")) {
+		return false
+	}
+	last := lines[len(lines)-1]
+	if !strings.HasPrefix(last, journalCommit) {
+		return false
+	}
+	count, err := strconv.Atoi(strings.TrimPrefix(last, journalCommit))
+	return err == nil && count == len(lines)-1
+}
+
+// writeSynced writes data to path and flushes it to disk before returning.
+func writeSynced(path string, data []byte) error {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err = file.Write(data); err != nil {
+		file.Close()
+		return err
+	}
+	if err = file.Sync(); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// writeAtomic writes data to a synced temporary file next to path and renames
+// it into place, so path is never read half written.
+func writeAtomic(path string, data []byte) error {
+	tmp := path + ".tmp"
+	if err := writeSynced(tmp, data); err != nil {
+		_ = os.Remove(tmp)
+		return err
+	}
+	return os.Rename(tmp, path)
+}
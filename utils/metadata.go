@@ -0,0 +1,116 @@
+package utils
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// EntryMetadataPath holds the extra metadata the metadata command returned for
+// the staged files, as their keyset entries were generated.
+var EntryMetadataPath = filepath.Join(".ait", "entry_metadata.json")
+
+// EntryMetadata is the extra metadata of a staged file, along with the CID it
+// was collected for, so it's only attached to that content.
+type EntryMetadata struct {
+	CID    string            `json:"cid"`
+	Fields map[string]string `json:"fields"`
+}
+
+// RunMetadataCommand runs the metadata command template on path, like
+// ScanFile, and returns the fields of the JSON object it prints. Values that
+// aren't strings are kept as JSON.
+func RunMetadataCommand(template, path string) (map[string]string, error) {
+	fields := commandFields(template, path)
+	if len(fields) == 0 {
+		return nil, errors.New("the metadata command is empty")
+	}
+	var stdout, stderr bytes.Buffer
+	cmd := exec.Command(fields[0], fields[1:]...)
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("%v: %v", err, msg)
+		}
+		return nil, err
+	}
+	return ParseMetadataFields(stdout.Bytes())
+}
+
+// ParseMetadataFields reads the fields of a JSON object. Values that aren't
+// strings are kept as JSON.
+func ParseMetadataFields(data []byte) (map[string]string, error) {
+	raw := make(map[string]json.RawMessage)
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("expected a JSON object of fields: %v", err)
+	}
+	fields := make(map[string]string, len(raw))
+	for name, value := range raw {
+		var s string
+		if json.Unmarshal(value, &s) != nil {
+			s = string(value)
+		}
+		fields[name] = s
+	}
+	return fields, nil
+}
+
+// ReadEntryMetadata returns the metadata collected for the staged files, by
+// path.
+func ReadEntryMetadata() (map[string]EntryMetadata, error) {
+	return ReadEntryMetadataIn(".")
+}
+
+// ReadEntryMetadataIn returns the metadata collected for the staged files of
+// the workspace at dir, by path.
+func ReadEntryMetadataIn(dir string) (map[string]EntryMetadata, error) {
+	metadata := make(map[string]EntryMetadata)
+	data, err := ioutil.ReadFile(inWorkspace(dir, EntryMetadataPath))
+	if os.IsNotExist(err) {
+		return metadata, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return metadata, json.Unmarshal(data, &metadata)
+}
+
+// WriteEntryMetadata records the metadata collected for the staged files.
+func WriteEntryMetadata(metadata map[string]EntryMetadata) error {
+	return WriteEntryMetadataIn(".", metadata)
+}
+
+// WriteEntryMetadataIn records the metadata collected for the staged files of
+// the workspace at dir.
+func WriteEntryMetadataIn(dir string, metadata map[string]EntryMetadata) error {
+	data, err := json.MarshalIndent(metadata, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(inWorkspace(dir, EntryMetadataPath), data, 0644)
+}
+
+// SubmittedMetadata returns the metadata fields of the submitted files, by
+// path, keeping only those collected for the CID they were submitted with.
+func SubmittedMetadata(metadata map[string]EntryMetadata, entries []KeysetEntry) map[string]map[string]string {
+	submitted := make(map[string]bool, len(entries))
+	for _, entry := range entries {
+		submitted[entry.CID] = true
+	}
+	fields := make(map[string]map[string]string)
+	for path, m := range metadata {
+		if submitted[m.CID] && len(m.Fields) > 0 {
+			fields[path] = m.Fields
+		}
+	}
+	if len(fields) == 0 {
+		return nil
+	}
+	return fields
+}
@@ -0,0 +1,90 @@
+package utils
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// ExpandHome replaces a leading "~" in p with the user's home directory. Paths
+// starting with "~user" are left as they are.
+func ExpandHome(p string) string {
+	if p != "~" && !strings.HasPrefix(p, "~/") && !strings.HasPrefix(p, "~"+string(filepath.Separator)) {
+		return p
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return p
+	}
+	return filepath.Join(home, p[1:])
+}
+
+// inWorkspace returns where the file at path, relative to the root of a
+// workspace like the state files of ait, is in the workspace at dir. Absolute
+// paths are kept.
+func inWorkspace(dir, path string) string {
+	if filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(dir, path)
+}
+
+// WorkspacePath returns p in the form paths of the workspace are staged in:
+// relative to the working directory, clean and with the platform's separators.
+// "~" is expanded and absolute paths are accepted, but paths leading out of the
+// working directory are refused.
+func WorkspacePath(p string) (string, error) {
+	wd, err := os.Getwd()
+	if err != nil {
+		return "", err
+	}
+	return RelativePath(wd, p)
+}
+
+// FindWorkspace returns the root of the workspace holding dir: dir itself or
+// the closest of its parents with a .ait directory holding a staging file,
+// like git finds its repository. The .ait directory at stateDir, where ait
+// keeps its own state, ie ~/.ait, isn't a workspace.
+func FindWorkspace(dir, stateDir string) (string, bool) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return "", false
+	}
+	for {
+		if filepath.Join(dir, ".ait") != filepath.Clean(stateDir) && FileExists(filepath.Join(dir, AddedFilesPath)) {
+			return dir, true
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", false
+		}
+		dir = parent
+	}
+}
+
+// RelativePath is WorkspacePath with root in place of the working directory.
+func RelativePath(root, p string) (string, error) {
+	p = filepath.FromSlash(ExpandHome(p))
+	if !filepath.IsAbs(p) {
+		p = filepath.Join(root, p)
+	}
+	root, p = filepath.Clean(root), filepath.Clean(p)
+	if !IsWithin(root, p) {
+		return "", fmt.Errorf("%v isn't within %v", p, root)
+	}
+	return filepath.Rel(root, p)
+}
+
+// SlashPath returns p clean and with forward slashes, the form paths take in
+// keysets, repositories and URLs whatever the platform.
+func SlashPath(p string) string {
+	return path.Clean(filepath.ToSlash(p))
+}
+
+// KeysetName returns the name a file at p is listed under in a keyset: its base
+// name with every run of whitespace replaced with a dash.
+func KeysetName(p string) string {
+	return strings.Join(strings.Fields(path.Base(SlashPath(p))), "-")
+}
@@ -0,0 +1,70 @@
+package utils
+
+import (
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// CertificatePin returns the pin of a certificate: the base64 encoded SHA-256
+// hash of its public key, the same value HPKP and curl's --pinnedpubkey use.
+func CertificatePin(cert *x509.Certificate) string {
+	sum := sha256.Sum256(cert.RawSubjectPublicKeyInfo)
+	return base64.StdEncoding.EncodeToString(sum[:])
+}
+
+// FetchPins connects to the host and returns the certificate chain it
+// presents, leaf first, so that one of them can be pinned.
+func FetchPins(host string) ([]*x509.Certificate, error) {
+	addr := host
+	if _, _, err := net.SplitHostPort(host); err != nil {
+		addr = net.JoinHostPort(host, "443")
+	}
+	dialer := &net.Dialer{Timeout: 10 * time.Second}
+	conn, err := tls.DialWithDialer(dialer, "tcp", addr, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	return conn.ConnectionState().PeerCertificates, nil
+}
+
+// PinnedClient returns an HTTP client that refuses to talk to any of the
+// hosts in pins unless one of the certificates they present matches one of
+// their pins. Hosts without pins are verified as usual. Requests are sent with
+// UserAgent.
+func PinnedClient(pins map[string][]string) *http.Client {
+	if len(pins) == 0 {
+		return NewClient(0)
+	}
+	transport := newTransport()
+	transport.TLSClientConfig = &tls.Config{
+		VerifyConnection: func(cs tls.ConnectionState) error {
+			return verifyPins(pins, cs.ServerName, cs.PeerCertificates)
+		},
+	}
+	return &http.Client{Transport: identifyingTransport{transport}}
+}
+
+// verifyPins checks the presented chain against the pins of host.
+func verifyPins(pins map[string][]string, host string, chain []*x509.Certificate) error {
+	expected, ok := pins[strings.ToLower(host)]
+	if !ok || len(expected) == 0 {
+		return nil
+	}
+	for _, cert := range chain {
+		pin := CertificatePin(cert)
+		for _, want := range expected {
+			if pin == want {
+				return nil
+			}
+		}
+	}
+	return fmt.Errorf("the certificate of %v does not match its pinned keys, refusing to connect", host)
+}
@@ -0,0 +1,46 @@
+package utils
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+)
+
+// Proxy is the proxy every outbound HTTP request is sent through. Empty uses
+// the proxy environment variables.
+var Proxy string
+
+// ParseProxy checks that proxy is a SOCKS5 or HTTP proxy URL, ie
+// "socks5://127.0.0.1:9050".
+func ParseProxy(proxy string) (*url.URL, error) {
+	u, err := url.Parse(proxy)
+	if err != nil {
+		return nil, fmt.Errorf("invalid proxy %q: %v", proxy, err)
+	}
+	switch u.Scheme {
+	case "socks5", "socks5h", "http", "https":
+	default:
+		return nil, fmt.Errorf("invalid proxy %q, expected a socks5:// or http:// URL", proxy)
+	}
+	if u.Host == "" {
+		return nil, fmt.Errorf("invalid proxy %q, it has no host", proxy)
+	}
+	return u, nil
+}
+
+// newTransport returns a copy of the default HTTP transport going through
+// Proxy.
+func newTransport() *http.Transport {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = proxyFor
+	return transport
+}
+
+// proxyFor returns the proxy req should go through. Proxy is looked up for
+// every request, so clients created before the config was loaded use it too.
+func proxyFor(req *http.Request) (*url.URL, error) {
+	if Proxy == "" {
+		return http.ProxyFromEnvironment(req)
+	}
+	return ParseProxy(Proxy)
+}
@@ -0,0 +1,94 @@
+package utils
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// QueuePath holds the submissions prepared while GitHub couldn't be reached.
+var QueuePath = filepath.Join(".ait", "queue")
+
+// QueuedSubmission is a prepared submission waiting to be pushed. Its keyset
+// is kept beside it.
+type QueuedSubmission struct {
+	ID     string    `json:"id"`
+	Time   time.Time `json:"time"`
+	Remote string    `json:"remote"`
+	// PullRequest, Issue, ROCrate, Also and Provider are the flags the
+	// submission was made with.
+	PullRequest bool   `json:"pullRequest,omitempty"`
+	Issue       bool   `json:"issue,omitempty"`
+	ROCrate     bool   `json:"roCrate,omitempty"`
+	Also        string `json:"also,omitempty"`
+	Provider    string `json:"provider,omitempty"`
+	// The rest are the fields of its application.
+	Title    string `json:"title"`
+	Commit   string `json:"commit"`
+	PRBody   string `json:"prBody,omitempty"`
+	Category string `json:"category,omitempty"`
+	Filename string `json:"filename"`
+}
+
+// NewQueuedSubmission creates a queued submission to remote prepared now.
+func NewQueuedSubmission(remote string) *QueuedSubmission {
+	now := time.Now()
+	return &QueuedSubmission{ID: now.Format("20060102-150405"), Time: now, Remote: remote}
+}
+
+// KeysetPath is where the submission's keyset is kept.
+func (q *QueuedSubmission) KeysetPath() string {
+	return filepath.Join(QueuePath, q.ID+".ks")
+}
+
+// Save writes the submission to the queue.
+func (q *QueuedSubmission) Save() error {
+	if err := os.MkdirAll(QueuePath, os.ModePerm); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(q, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(filepath.Join(QueuePath, q.ID+".json"), data, 0644)
+}
+
+// Remove deletes the submission and its keyset from the queue.
+func (q *QueuedSubmission) Remove() error {
+	if err := os.Remove(q.KeysetPath()); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return os.Remove(filepath.Join(QueuePath, q.ID+".json"))
+}
+
+// ReadQueue returns the queued submissions, oldest first.
+func ReadQueue() (queue []*QueuedSubmission, err error) {
+	files, err := ioutil.ReadDir(QueuePath)
+	if os.IsNotExist(err) {
+		return queue, nil
+	}
+	if err != nil {
+		return queue, err
+	}
+	for _, info := range files {
+		if filepath.Ext(info.Name()) != ".json" {
+			continue
+		}
+		data, err := ioutil.ReadFile(filepath.Join(QueuePath, info.Name()))
+		if err != nil {
+			return queue, err
+		}
+		q := &QueuedSubmission{}
+		if err = json.Unmarshal(data, q); err != nil {
+			return queue, err
+		}
+		queue = append(queue, q)
+	}
+	sort.Slice(queue, func(i, j int) bool {
+		return queue[i].Time.Before(queue[j].Time)
+	})
+	return queue, nil
+}
@@ -0,0 +1,129 @@
+package utils
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// RegistryPath is the registry of every CID submitted from any workspace, in
+// the directory ait keeps its state in. It is set along with the config.
+var RegistryPath string
+
+// RegistryEntry records that a CID was submitted.
+type RegistryEntry struct {
+	CID        string    `json:"cid"`
+	Name       string    `json:"name"`
+	Remote     string    `json:"remote"`
+	Keyset     string    `json:"keyset"`
+	Workspace  string    `json:"workspace"`
+	Submission string    `json:"submission"`
+	Time       time.Time `json:"time"`
+}
+
+// registryEntries returns the registry entries of the submission s made from
+// the workspace.
+func registryEntries(s *Submission, workspace string) []RegistryEntry {
+	entries := make([]RegistryEntry, 0, len(s.Entries))
+	for _, entry := range s.Entries {
+		entries = append(entries, RegistryEntry{
+			CID:        entry.CID,
+			Name:       entry.Name,
+			Remote:     s.Remote,
+			Keyset:     s.Path,
+			Workspace:  workspace,
+			Submission: s.ID,
+			Time:       s.Time,
+		})
+	}
+	return entries
+}
+
+// RegisterSubmission adds the entries of the submission s, made from the
+// workspace, to the registry. It is only appended to, one JSON entry per
+// line, so workspaces submitting at once don't overwrite each other.
+func RegisterSubmission(s *Submission, workspace string) error {
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	for _, entry := range registryEntries(s, workspace) {
+		if err := encoder.Encode(entry); err != nil {
+			return err
+		}
+	}
+	if err := os.MkdirAll(filepath.Dir(RegistryPath), os.ModePerm); err != nil {
+		return err
+	}
+	file, err := os.OpenFile(RegistryPath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err = file.Write(buf.Bytes()); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// ReadRegistry returns the registry entries by CID, oldest first.
+func ReadRegistry() (map[string][]RegistryEntry, error) {
+	registry := make(map[string][]RegistryEntry)
+	file, err := os.Open(RegistryPath)
+	if os.IsNotExist(err) {
+		return registry, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		if len(bytes.TrimSpace(scanner.Bytes())) == 0 {
+			continue
+		}
+		var entry RegistryEntry
+		if err = json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			return nil, fmt.Errorf("line %d of %v is malformed: %v", line, RegistryPath, err)
+		}
+		registry[entry.CID] = append(registry[entry.CID], entry)
+	}
+	if err = scanner.Err(); err != nil {
+		return nil, err
+	}
+	for _, entries := range registry {
+		sort.SliceStable(entries, func(i, j int) bool { return entries[i].Time.Before(entries[j].Time) })
+	}
+	return registry, nil
+}
+
+// RebuildRegistry replaces the registry with the submissions recorded in the
+// history of each workspace, returning how many submissions it holds.
+// Workspaces that no longer exist are skipped.
+func RebuildRegistry(workspaces []string) (int, error) {
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	count := 0
+	for _, workspace := range workspaces {
+		history, err := ReadHistoryIn(filepath.Join(workspace, HistoryPath))
+		if err != nil {
+			return count, fmt.Errorf("%v: %v", workspace, err)
+		}
+		for _, s := range history {
+			for _, entry := range registryEntries(s, workspace) {
+				if err = encoder.Encode(entry); err != nil {
+					return count, err
+				}
+			}
+			count++
+		}
+	}
+	if err := os.MkdirAll(filepath.Dir(RegistryPath), os.ModePerm); err != nil {
+		return count, err
+	}
+	return count, writeSynced(RegistryPath, buf.Bytes())
+}
@@ -0,0 +1,61 @@
+package utils
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// KeysetArchivePath is where generated keysets are kept after a submission
+// when a retention policy is configured.
+var KeysetArchivePath = filepath.Join(".ait", "archive")
+
+// Retention is the number of generated keysets and cloned sources kept
+// around for debugging failed submissions. It is set from the ait config.
+var Retention int
+
+// archiveKeyset moves the generated keyset into the archive under a timestamped
+// name and prunes the archive down to the retention limit.
+func archiveKeyset() {
+	generated := GeneratedKeysetPath()
+	if !FileExists(generated) {
+		return
+	}
+	if err := os.MkdirAll(KeysetArchivePath, os.ModePerm); err != nil {
+		return
+	}
+	name := time.Now().Format("20060102-150405") + ".ks"
+	if err := moveFile(generated, filepath.Join(KeysetArchivePath, name)); err != nil {
+		return
+	}
+	_, _ = PruneDir(KeysetArchivePath, Retention)
+}
+
+// PruneDir deletes all but the keep most recently modified entries of dir and
+// returns the names of the removed entries. A missing dir is not an error.
+func PruneDir(dir string, keep int) (removed []string, err error) {
+	entries, err := ioutil.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return removed, nil
+	}
+	if err != nil {
+		return removed, err
+	}
+	if keep < 0 {
+		keep = 0
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].ModTime().After(entries[j].ModTime())
+	})
+	for i := keep; i < len(entries); i++ {
+		err = os.RemoveAll(filepath.Join(dir, entries[i].Name()))
+		if err != nil {
+			return removed, fmt.Errorf("unable to remove %v: %v", entries[i].Name(), err)
+		}
+		removed = append(removed, entries[i].Name())
+	}
+	return removed, nil
+}
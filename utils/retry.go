@@ -0,0 +1,145 @@
+package utils
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+)
+
+// Retries is how many times a request that failed for a transient reason, ie
+// a dropped connection or a 503, is sent again before giving up.
+var Retries = 3
+
+// retryBackoff is how long the first retry waits, doubling for every retry
+// after it up to maxRetryBackoff.
+var retryBackoff = time.Second
+
+// maxRetryBackoff bounds the wait between retries. A server asking to wait
+// longer isn't retried.
+const maxRetryBackoff = 30 * time.Second
+
+// retryingTransport sends requests again, with exponential backoff, when they
+// fail for a reason that's likely to pass.
+type retryingTransport struct {
+	base http.RoundTripper
+}
+
+func (t retryingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	backoff := retryBackoff
+	// A body that can't be read again can't be sent again either.
+	rewindable := req.Body == nil || req.Body == http.NoBody || req.GetBody != nil
+	for attempt := 0; ; attempt++ {
+		if attempt > 0 && req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			req = req.Clone(req.Context())
+			req.Body = body
+		}
+		resp, err := t.base.RoundTrip(req)
+		wait, reason, retry := shouldRetry(req, resp, err)
+		if !retry || !rewindable || attempt >= Retries {
+			return resp, err
+		}
+		if wait == 0 {
+			wait = backoff + time.Duration(rand.Int63n(int64(backoff)/2+1))
+			if backoff *= 2; backoff > maxRetryBackoff {
+				backoff = maxRetryBackoff
+			}
+		}
+		if resp != nil {
+			_, _ = io.Copy(ioutil.Discard, io.LimitReader(resp.Body, 64*1024))
+			resp.Body.Close()
+		}
+		fmt.Fprintf(os.Stderr, "[%v %v: %v, retrying in %v]\n", req.Method, req.URL.Host, reason,
+			wait.Round(time.Second/10))
+		select {
+		case <-time.After(wait):
+		case <-req.Context().Done():
+			return nil, req.Context().Err()
+		}
+	}
+}
+
+// shouldRetry returns whether the request that got resp or err is sent again,
+// why, and how long to wait first if the server said so.
+func shouldRetry(req *http.Request, resp *http.Response, err error) (time.Duration, string, bool) {
+	if err != nil {
+		if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+			return 0, "", false
+		}
+		// Requests that weren't sent can always be sent again, others only
+		// if sending them twice does no harm.
+		var opErr *net.OpError
+		var dnsErr *net.DNSError
+		sent := !(errors.As(err, &opErr) && opErr.Op == "dial" || errors.As(err, &dnsErr))
+		if sent && !idempotent(req.Method) {
+			return 0, "", false
+		}
+		return 0, err.Error(), true
+	}
+	switch resp.StatusCode {
+	case http.StatusTooManyRequests:
+	case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		// A gateway may have passed the request on before failing, others
+		// are only sent again when the server asks to retry later.
+		if !idempotent(req.Method) && resp.Header.Get("Retry-After") == "" {
+			return 0, "", false
+		}
+	case http.StatusForbidden:
+		// GitHub's secondary rate limits answer 403 with a Retry-After.
+		if resp.Header.Get("Retry-After") == "" {
+			return 0, "", false
+		}
+	default:
+		return 0, "", false
+	}
+	wait := time.Duration(0)
+	if seconds, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && seconds >= 0 {
+		wait = time.Duration(seconds) * time.Second
+		if wait > maxRetryBackoff {
+			return 0, "", false
+		}
+	}
+	return wait, resp.Status, true
+}
+
+// idempotent returns whether requests of method can be sent twice with the
+// same effect as once.
+func idempotent(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	return false
+}
+
+// ShowUploads, when set, wraps the transport of the API clients to show the
+// progress of large uploads. The CLI sets it, the API clients can't import
+// the package drawing progress.
+var ShowUploads func(http.RoundTripper) http.RoundTripper
+
+// WithRetries returns client sending the requests that fail for a transient
+// reason again, up to Retries times with exponential backoff. Each attempt of
+// a large upload shows its progress if ShowUploads is set.
+func WithRetries(client *http.Client) *http.Client {
+	base := client.Transport
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	if ShowUploads != nil {
+		base = ShowUploads(base)
+	}
+	retrying := *client
+	retrying.Transport = retryingTransport{base}
+	return &retrying
+}
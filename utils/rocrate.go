@@ -0,0 +1,102 @@
+package utils
+
+import (
+	"encoding/json"
+	"path"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ROCrateMetadataFile is the name RO-Crate requires for the metadata
+// descriptor at the root of a crate.
+const ROCrateMetadataFile = "ro-crate-metadata.json"
+
+// ROCrateDataset is the metadata describing the root dataset of a crate.
+type ROCrateDataset struct {
+	Name        string
+	Description string
+	Author      string
+	Email       string
+	Published   time.Time
+	// Catalog holds the data catalog records of files, by path. The catalog
+	// ID and metadata of verified records are added to their files.
+	Catalog map[string]CatalogRecord
+	// Metadata holds the fields the metadata command collected for files, by
+	// path. They are added to the files they were collected for the CID of.
+	Metadata map[string]EntryMetadata
+}
+
+// ROCrateMetadata returns the RO-Crate 1.1 metadata descriptor of a crate
+// holding the given files. Each file's CID is recorded as its identifier so
+// the crate can be checked against the keyset.
+func ROCrateMetadata(dataset ROCrateDataset, files []HandoffEntry) ([]byte, error) {
+	sorted := append([]HandoffEntry(nil), files...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Path < sorted[j].Path })
+
+	parts := make([]map[string]string, 0, len(sorted))
+	graph := []interface{}{
+		map[string]interface{}{
+			"@id":        ROCrateMetadataFile,
+			"@type":      "CreativeWork",
+			"conformsTo": map[string]string{"@id": "https://w3id.org/ro/crate/1.1"},
+			"about":      map[string]string{"@id": "./"},
+		},
+	}
+	for _, file := range sorted {
+		parts = append(parts, map[string]string{"@id": SlashPath(file.Path)})
+	}
+	root := map[string]interface{}{
+		"@id":           "./",
+		"@type":         "Dataset",
+		"name":          dataset.Name,
+		"datePublished": dataset.Published.Format("2006-01-02"),
+		"hasPart":       parts,
+	}
+	if dataset.Description != "" {
+		root["description"] = dataset.Description
+	}
+	if dataset.Author != "" {
+		root["author"] = map[string]string{"@id": "#author"}
+	}
+	graph = append(graph, root)
+	if dataset.Author != "" {
+		author := map[string]string{"@id": "#author", "@type": "Person", "name": dataset.Author}
+		if dataset.Email != "" {
+			author["email"] = dataset.Email
+		}
+		graph = append(graph, author)
+	}
+	for _, file := range sorted {
+		entity := map[string]interface{}{
+			"@id":         SlashPath(file.Path),
+			"@type":       "File",
+			"name":        path.Base(SlashPath(file.Path)),
+			"contentSize": file.Size,
+			"identifier":  "ipfs://" + file.CID,
+		}
+		if m, ok := dataset.Metadata[file.Path]; ok && m.CID == file.CID {
+			for key, value := range m.Fields {
+				if _, reserved := entity[key]; !reserved && !strings.HasPrefix(key, "@") {
+					entity[key] = value
+				}
+			}
+		}
+		if record, ok := dataset.Catalog[file.Path]; ok && record.Verified {
+			for key, value := range record.Metadata {
+				if _, reserved := entity[key]; !reserved && !strings.HasPrefix(key, "@") {
+					entity[key] = value
+				}
+			}
+			entity["sha256"] = record.SHA256
+			if record.ID != "" {
+				entity["identifier"] = []string{"ipfs://" + file.CID, record.ID}
+			}
+		}
+		graph = append(graph, entity)
+	}
+	return json.MarshalIndent(map[string]interface{}{
+		"@context": "https://w3id.org/ro/crate/1.1/context",
+		"@graph":   graph,
+	}, "", "  ")
+}
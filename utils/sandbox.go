@@ -0,0 +1,61 @@
+package utils
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// SandboxPath joins name, a path read from a keyset, to root. Keysets come
+// from upstream repositories, so names that would place a file outside of root
+// are refused: absolute paths, paths with ".." components and paths through a
+// symlink leading out of root, including a symlink at the path itself, which
+// creating the file would follow.
+func SandboxPath(root, name string) (string, error) {
+	if filepath.IsAbs(name) || filepath.VolumeName(name) != "" || strings.HasPrefix(name, "/") ||
+		strings.HasPrefix(name, "\\") {
+		return "", fmt.Errorf("%q is an absolute path", name)
+	}
+	parts := strings.FieldsFunc(name, func(r rune) bool { return r == '/' || r == '\\' })
+	for _, part := range parts {
+		if part == ".." {
+			return "", fmt.Errorf("%q leads out of %v", name, root)
+		}
+	}
+	path := filepath.Join(root, filepath.Join(parts...))
+	if path == filepath.Clean(root) {
+		return "", fmt.Errorf("%q isn't a file name", name)
+	}
+
+	realRoot, err := filepath.EvalSymlinks(root)
+	if err != nil {
+		return "", err
+	}
+	current := root
+	for _, part := range parts {
+		current = filepath.Join(current, part)
+		info, err := os.Lstat(current)
+		if os.IsNotExist(err) {
+			break
+		}
+		if err != nil {
+			return "", err
+		}
+		if info.Mode()&os.ModeSymlink == 0 {
+			continue
+		}
+		target, err := filepath.EvalSymlinks(current)
+		if err != nil || !IsWithin(realRoot, target) {
+			return "", fmt.Errorf("%q goes through a symlink leading out of %v", name, root)
+		}
+	}
+	return path, nil
+}
+
+// IsWithin returns whether path is root or a path under it. Both must be
+// clean and absolute, or both relative to the same directory.
+func IsWithin(root, path string) bool {
+	rel, err := filepath.Rel(root, path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
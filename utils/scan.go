@@ -0,0 +1,64 @@
+package utils
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// ScanFile runs the scanner command template on path. Each "{}" in the
+// template is replaced by the path, which is appended when there is none. Any
+// non-zero exit is reported as a failure along with the scanner's output.
+func ScanFile(template, path string) (output string, err error) {
+	fields := commandFields(template, path)
+	if len(fields) == 0 {
+		return "", errors.New("the scan command is empty")
+	}
+	out, err := exec.Command(fields[0], fields[1:]...).CombinedOutput()
+	return strings.TrimSpace(string(out)), err
+}
+
+// commandFields splits a command template run on path into its fields, with
+// each "{}" replaced by path, which is appended when there is none.
+func commandFields(template, path string) []string {
+	fields := strings.Fields(template)
+	if len(fields) == 0 {
+		return nil
+	}
+	substituted := false
+	for i, field := range fields {
+		if strings.Contains(field, "{}") {
+			fields[i] = strings.ReplaceAll(field, "{}", path)
+			substituted = true
+		}
+	}
+	if !substituted {
+		fields = append(fields, path)
+	}
+	return fields
+}
+
+// QuarantineFile moves a file that failed scanning into dir, keeping its name
+// and adding a number if the name is already taken. It returns the new path.
+func QuarantineFile(path, dir string) (string, error) {
+	err := os.MkdirAll(dir, 0700)
+	if err != nil {
+		return "", err
+	}
+	base := filepath.Base(path)
+	dest := filepath.Join(dir, base)
+	for i := 1; FileExists(dest); i++ {
+		dest = filepath.Join(dir, base+"."+strconv.Itoa(i))
+	}
+	// Renaming fails across filesystems, so fall back to copying.
+	if err = os.Rename(path, dest); err == nil {
+		return dest, nil
+	}
+	if err = CopyFile(path, dest); err != nil {
+		return "", err
+	}
+	return dest, os.Remove(path)
+}
@@ -0,0 +1,62 @@
+package utils
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base32"
+	"errors"
+	"strings"
+)
+
+// codeEncoding is used for secrets that people read out or paste to each
+// other, so it avoids padding and mixed case.
+var codeEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// Seal encrypts data with a freshly generated secret and returns both. The
+// secret is high entropy, so the AES-256-GCM key is its SHA-256 hash, without
+// a slow key derivation.
+func Seal(data []byte) (sealed []byte, secret string, err error) {
+	raw := make([]byte, 20)
+	if _, err = rand.Read(raw); err != nil {
+		return nil, "", err
+	}
+	secret = strings.ToLower(codeEncoding.EncodeToString(raw))
+	gcm, err := newGCM(secret)
+	if err != nil {
+		return nil, "", err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err = rand.Read(nonce); err != nil {
+		return nil, "", err
+	}
+	return gcm.Seal(nonce, nonce, data, nil), secret, nil
+}
+
+// Unseal decrypts data produced by Seal with the matching secret.
+func Unseal(sealed []byte, secret string) ([]byte, error) {
+	gcm, err := newGCM(secret)
+	if err != nil {
+		return nil, err
+	}
+	if len(sealed) < gcm.NonceSize() {
+		return nil, errors.New("sealed data is truncated")
+	}
+	nonce, data := sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():]
+	plain, err := gcm.Open(nil, nonce, data, nil)
+	if err != nil {
+		return nil, errors.New("wrong code or corrupted data")
+	}
+	return plain, nil
+}
+
+// newGCM derives the AES key from a secret.
+func newGCM(secret string) (cipher.AEAD, error) {
+	key := sha256.Sum256([]byte(strings.ToLower(strings.TrimSpace(secret))))
+	block, err := aes.NewCipher(key[:])
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,31 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"golang.org/x/term"
+)
+
+// ReadSecret reads a password or token typed at a prompt without echoing it,
+// on every OS. Secrets are never recorded in or replayed from a session. A
+// secret piped in, when stdin isn't a terminal, is read as a line.
+func ReadSecret() (string, error) {
+	if NonInteractive {
+		return "", errors.New("unable to ask for a secret in non-interactive mode, " +
+			"give it with an environment variable or the config")
+	}
+	fd := int(os.Stdin.Fd())
+	if !term.IsTerminal(fd) {
+		text, err := stdin.ReadString('\n')
+		if err != nil && text == "" {
+			return "", err
+		}
+		return strings.TrimRight(text, "\r\n"), nil
+	}
+	secret, err := term.ReadPassword(fd)
+	fmt.Println()
+	return string(secret), err
+}
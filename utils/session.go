@@ -0,0 +1,188 @@
+package utils
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/arken/ait/platform"
+)
+
+// Session is a recorded run of an interactive command: its arguments, flags,
+// application and every answer given to its prompts, so the same run can be
+// replayed later or on another machine.
+type Session struct {
+	Time    time.Time       `json:"time"`
+	Command string          `json:"command"`
+	Args    []string        `json:"args"`
+	Flags   json.RawMessage `json:"flags"`
+	// Staged are the files that were staged, which must match on replay.
+	Staged      []string `json:"staged"`
+	Application string   `json:"application,omitempty"`
+	Answers     []string `json:"answers"`
+}
+
+// stdin reads the answers to prompts from stdinFile. A single reader is shared
+// so answers piped in several lines at once aren't lost to another reader's
+// buffer.
+var (
+	stdinFile = os.Stdin
+	stdin     = bufio.NewReader(stdinFile)
+)
+
+// PromptTimeout is how long prompts wait for an answer, forever when zero.
+// Once it passes the prompt's default answer is used if PromptDefault is set,
+// otherwise the command is aborted. Both are set along with the config.
+var (
+	PromptTimeout time.Duration
+	PromptDefault bool
+)
+
+// NonInteractive is set when the command must not prompt, ie in CI pipelines.
+// Every choice then comes from flags, the environment and the config, and a
+// prompt that can't be avoided fails the command.
+var NonInteractive bool
+
+// promptLine is a line read from stdin.
+type promptLine struct {
+	text string
+	err  error
+}
+
+// recording and replaying are the sessions being recorded and replayed.
+var recording, replaying *Session
+
+// RecordSession starts recording the answers and application of s.
+func RecordSession(s *Session) {
+	recording = s
+}
+
+// ReplaySession answers prompts and the application from s instead of asking.
+func ReplaySession(s *Session) {
+	replaying = s
+}
+
+// Replaying returns the session being replayed, or nil.
+func Replaying() *Session {
+	return replaying
+}
+
+// ReadAnswer reads the answer to a prompt, without surrounding spaces. When a
+// session is replayed the recorded answer is used, and echoed. An empty
+// answer, the prompt's default, is returned once PromptTimeout passes if
+// PromptDefault is set.
+func ReadAnswer() string {
+	answer, _ := readAnswer(false)
+	return answer
+}
+
+// ReadRequiredAnswer reads the answer to a prompt that has no default, ie a
+// name. The command is aborted once PromptTimeout passes or input ends.
+func ReadRequiredAnswer() string {
+	answer, _ := readAnswer(true)
+	return answer
+}
+
+// TryReadAnswer reads the answer to a prompt like ReadAnswer, returning false
+// when input has ended without one.
+func TryReadAnswer() (string, bool) {
+	return readAnswer(false)
+}
+
+// readAnswer reads the answer to a prompt, which can't fall back to its
+// default when required.
+func readAnswer(required bool) (string, bool) {
+	if replaying != nil {
+		if len(replaying.Answers) == 0 {
+			FatalPrintln("\nThe replayed session has no answer for this prompt, the run differs from the recorded one.")
+		}
+		answer := replaying.Answers[0]
+		replaying.Answers = replaying.Answers[1:]
+		fmt.Println(answer)
+		return answer, true
+	}
+	if NonInteractive {
+		FatalPrintln("\nUnable to ask in non-interactive mode, give the answer with a flag, " +
+			"an environment variable or the config.")
+	}
+	line, ok := readLine()
+	if !ok {
+		if required || !PromptDefault {
+			FatalPrintf("\nNo answer within %v, aborting.\n", PromptTimeout)
+		}
+		fmt.Printf("\nNo answer within %v, using the default.\n", PromptTimeout)
+	}
+	answer := strings.TrimSpace(line.text)
+	if required && line.err != nil && answer == "" {
+		FatalPrintln("\nInput ended without an answer, aborting.")
+	}
+	if recording != nil {
+		recording.Answers = append(recording.Answers, answer)
+	}
+	return answer, line.err == nil || answer != ""
+}
+
+// readLine waits for the next line of stdin, returning false if PromptTimeout
+// passes first. Stdin is only read once it has input, so a prompt that timed
+// out doesn't leave a read behind to take the input meant for what follows,
+// ie a secret or the editor. Where waiting for input isn't supported prompts
+// wait for an answer however long it takes.
+func readLine() (promptLine, bool) {
+	deadline := time.Now().Add(PromptTimeout)
+	for PromptTimeout > 0 && !lineBuffered() {
+		ready, err := platform.WaitReadable(stdinFile, time.Until(deadline))
+		if err != nil {
+			break
+		}
+		if !ready {
+			return promptLine{}, false
+		}
+		// Stdin has input, it's buffered without waiting for more.
+		if _, err = stdin.Peek(stdin.Buffered() + 1); err != nil {
+			break
+		}
+	}
+	text, err := stdin.ReadString('\n')
+	return promptLine{text: text, err: err}, true
+}
+
+// lineBuffered returns whether a whole line of stdin is buffered already.
+func lineBuffered() bool {
+	buffered, _ := stdin.Peek(stdin.Buffered())
+	return bytes.IndexByte(buffered, '\n') >= 0
+}
+
+// RecordApplication keeps the application the user wrote in the session
+// being recorded.
+func RecordApplication(application string) {
+	if recording != nil {
+		recording.Application = application
+	}
+}
+
+// ReadSession reads the session recorded at path.
+func ReadSession(path string) (*Session, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	s := &Session{}
+	if err = json.Unmarshal(data, s); err != nil {
+		return nil, fmt.Errorf("%v isn't a recorded session: %v", path, err)
+	}
+	return s, nil
+}
+
+// Save writes the session to path.
+func (s *Session) Save(path string) error {
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, data, 0644)
+}
@@ -0,0 +1,126 @@
+package utils
+
+import (
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// StagingShardsPath holds the staging indexes written by the parts of a
+// partitioned "ait stage", until they are merged.
+var StagingShardsPath = filepath.Join(".ait", "staging")
+
+// StagingShard is the staged files one part of a partitioned staging hashed.
+type StagingShard struct {
+	Part    int            `json:"part"`
+	Of      int            `json:"of"`
+	Entries []HandoffEntry `json:"entries"`
+}
+
+// ParsePartition parses a partition like "3/8", the third of eight parts,
+// counted from 1.
+func ParsePartition(partition string) (part, of int, err error) {
+	fields := strings.SplitN(partition, "/", 2)
+	if len(fields) == 2 {
+		part, err = strconv.Atoi(strings.TrimSpace(fields[0]))
+		if err == nil {
+			of, err = strconv.Atoi(strings.TrimSpace(fields[1]))
+		}
+	}
+	if len(fields) != 2 || err != nil || of < 1 || part < 1 || part > of {
+		return 0, 0, fmt.Errorf("expected a partition like 3/8, got %q", partition)
+	}
+	return part, of, nil
+}
+
+// InPartition returns whether the file at path belongs to the part of of
+// parts. Files are spread by a hash of their path, so every part of the same
+// tree gets the same files whichever machine walks it.
+func InPartition(path string, part, of int) bool {
+	h := fnv.New32a()
+	h.Write([]byte(SlashPath(path)))
+	return int(h.Sum32()%uint32(of)) == part-1
+}
+
+// shardPath returns where the shard of the part of of parts is written.
+func shardPath(part, of int) string {
+	return filepath.Join(StagingShardsPath, fmt.Sprintf("part-%d-of-%d.json", part, of))
+}
+
+// WriteStagingShard writes the shard, replacing the one written by an earlier
+// run of the same part.
+func WriteStagingShard(shard StagingShard) (string, error) {
+	if err := os.MkdirAll(StagingShardsPath, os.ModePerm); err != nil {
+		return "", err
+	}
+	data, err := json.Marshal(shard)
+	if err != nil {
+		return "", err
+	}
+	path := shardPath(shard.Part, shard.Of)
+	return path, writeSynced(path, data)
+}
+
+// ReadStagingShards returns the shards written so far, in order.
+func ReadStagingShards() ([]StagingShard, error) {
+	files, err := ioutil.ReadDir(StagingShardsPath)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var shards []StagingShard
+	for _, file := range files {
+		if file.IsDir() || filepath.Ext(file.Name()) != ".json" {
+			continue
+		}
+		data, err := ioutil.ReadFile(filepath.Join(StagingShardsPath, file.Name()))
+		if err != nil {
+			return nil, err
+		}
+		var shard StagingShard
+		if err = json.Unmarshal(data, &shard); err != nil {
+			return nil, fmt.Errorf("%v: %v", file.Name(), err)
+		}
+		shards = append(shards, shard)
+	}
+	sort.Slice(shards, func(a, b int) bool {
+		if shards[a].Of != shards[b].Of {
+			return shards[a].Of < shards[b].Of
+		}
+		return shards[a].Part < shards[b].Part
+	})
+	return shards, nil
+}
+
+// MergeStagingShards returns the entries of every shard. Shards must all split
+// the tree in the same number of parts, and the parts that have no shard yet
+// are returned as missing.
+func MergeStagingShards(shards []StagingShard) (entries []HandoffEntry, missing []int, err error) {
+	if len(shards) == 0 {
+		return nil, nil, nil
+	}
+	of := shards[0].Of
+	seen := make(map[int]bool, of)
+	for _, shard := range shards {
+		if shard.Of != of {
+			return nil, nil, fmt.Errorf("some parts split the files in %d and others in %d, "+
+				"remove the shards of the wrong partitioning from %v", of, shard.Of, StagingShardsPath)
+		}
+		seen[shard.Part] = true
+		entries = append(entries, shard.Entries...)
+	}
+	for part := 1; part <= of; part++ {
+		if !seen[part] {
+			missing = append(missing, part)
+		}
+	}
+	return entries, missing, nil
+}
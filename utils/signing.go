@@ -0,0 +1,88 @@
+package utils
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// Signing formats, as git's gpg.format.
+const (
+	// SignOpenPGP signs with gpg and a key ID.
+	SignOpenPGP = "openpgp"
+	// SignSSH signs with ssh-keygen and the path of an SSH private key.
+	SignSSH = "ssh"
+)
+
+// Signature namespaces of SSH signatures, so a signature made for one use
+// can't be passed off as another.
+const (
+	// NamespaceGit is the namespace git signs commits in.
+	NamespaceGit = "git"
+	// NamespaceFile is the namespace of detached signatures of files.
+	NamespaceFile = "file"
+)
+
+// SignatureExtension returns the extension of the detached signatures made
+// in format, ie ".asc" for survey.ks.asc.
+func SignatureExtension(format string) string {
+	if format == SignSSH {
+		return ".sig"
+	}
+	return ".asc"
+}
+
+// Sign returns the armored detached signature of data made with key in
+// format, as git signs commits: gpg is given the key ID, ssh-keygen the path
+// of the private key and the namespace. Either runs their agent, and may
+// prompt for a passphrase.
+func Sign(data []byte, format, key, namespace string) (string, error) {
+	var cmd *exec.Cmd
+	switch format {
+	case SignOpenPGP, "":
+		cmd = exec.Command("gpg", "--batch", "--yes", "--armor", "--detach-sign", "--local-user", key)
+		cmd.Stdin = bytes.NewReader(data)
+	case SignSSH:
+		// ssh-keygen signs files, written next to the data as <file>.sig.
+		file, err := ioutil.TempFile(TempDir, "ait-sign")
+		if err != nil {
+			return "", err
+		}
+		defer os.Remove(file.Name())
+		defer os.Remove(file.Name() + ".sig")
+		_, err = file.Write(data)
+		if closeErr := file.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			return "", err
+		}
+		cmd = exec.Command("ssh-keygen", "-Y", "sign", "-f", ExpandHome(key), "-n", namespace, file.Name())
+		var stderr bytes.Buffer
+		cmd.Stderr = &stderr
+		if err = cmd.Run(); err != nil {
+			return "", signError(err, stderr.String())
+		}
+		signature, err := ioutil.ReadFile(file.Name() + ".sig")
+		return string(signature), err
+	default:
+		return "", fmt.Errorf("unknown signing format %q, expected %v or %v", format, SignOpenPGP, SignSSH)
+	}
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	if err := cmd.Run(); err != nil {
+		return "", signError(err, stderr.String())
+	}
+	return stdout.String(), nil
+}
+
+// signError describes a signing command that failed with what it printed.
+func signError(err error, stderr string) error {
+	if msg := strings.TrimSpace(stderr); msg != "" {
+		return fmt.Errorf("unable to sign: %v: %v", err, msg)
+	}
+	return fmt.Errorf("unable to sign: %v", err)
+}
@@ -0,0 +1,25 @@
+package utils
+
+// SplitFiles partitions paths, in order, into parts of at most maxFiles files
+// and maxSize bytes as reported by size. A limit of 0 is no limit. A file
+// larger than maxSize gets a part of its own.
+func SplitFiles(paths []string, size func(string) int64, maxFiles int, maxSize int64) [][]string {
+	var parts [][]string
+	var current []string
+	var currentSize int64
+	for _, path := range paths {
+		s := size(path)
+		full := maxFiles > 0 && len(current) >= maxFiles ||
+			maxSize > 0 && currentSize+s > maxSize
+		if full && len(current) > 0 {
+			parts = append(parts, current)
+			current, currentSize = nil, 0
+		}
+		current = append(current, path)
+		currentSize += s
+	}
+	if len(current) > 0 {
+		parts = append(parts, current)
+	}
+	return parts
+}
@@ -0,0 +1,71 @@
+package utils
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/arken/ait/types"
+)
+
+// StagedTimesPath records when each staged file was staged.
+var StagedTimesPath = filepath.Join(".ait", "staged_times.json")
+
+// ReadStagedTimes returns when each staged file was staged. Files staged
+// before times were recorded are missing.
+func ReadStagedTimes() (map[string]time.Time, error) {
+	return ReadStagedTimesIn(".")
+}
+
+// ReadStagedTimesIn returns when each staged file of the workspace at dir was
+// staged.
+func ReadStagedTimesIn(dir string) (map[string]time.Time, error) {
+	times := make(map[string]time.Time)
+	data, err := ioutil.ReadFile(inWorkspace(dir, StagedTimesPath))
+	if os.IsNotExist(err) {
+		return times, nil
+	}
+	if err != nil {
+		return times, err
+	}
+	return times, json.Unmarshal(data, &times)
+}
+
+// updateStagedTimes records the current time for the newly staged files of
+// contents, in the workspace at dir, and forgets the files that aren't staged
+// anymore.
+func updateStagedTimes(dir string, contents types.StringSet) error {
+	old, err := ReadStagedTimesIn(dir)
+	if err != nil {
+		old = make(map[string]time.Time)
+	}
+	now := time.Now().UTC().Truncate(time.Second)
+	times := make(map[string]time.Time, contents.Size())
+	_ = contents.ForEach(func(path string) error {
+		if t, ok := old[path]; ok {
+			times[path] = t
+		} else {
+			times[path] = now
+		}
+		return nil
+	})
+	return writeStagedTimes(dir, times)
+}
+
+// WriteStagedTimes replaces the record of when each staged file was staged,
+// ie to restore it after the staged files were temporarily replaced.
+func WriteStagedTimes(times map[string]time.Time) error {
+	return writeStagedTimes(".", times)
+}
+
+// writeStagedTimes replaces the record of when each staged file of the
+// workspace at dir was staged.
+func writeStagedTimes(dir string, times map[string]time.Time) error {
+	data, err := json.Marshal(times)
+	if err != nil {
+		return err
+	}
+	return writeAtomic(inWorkspace(dir, StagedTimesPath), data)
+}
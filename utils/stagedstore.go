@@ -0,0 +1,132 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/arken/ait/types"
+
+	fslock "github.com/ipfs/go-fs-lock"
+)
+
+// stagedLockName is the lock file guarding the staged files of a workspace
+// against concurrent ait processes.
+const stagedLockName = "added_files.lock"
+
+// StagedLockTimeout is how long changes to the staged files wait for another
+// ait process changing them before giving up.
+var StagedLockTimeout = 30 * time.Second
+
+// stagedMutex serializes changes to the staged files within this process. The
+// file lock only guards against other processes.
+var stagedMutex sync.Mutex
+
+// stagedLock releases both the process and the file lock on Close.
+type stagedLock struct {
+	file io.Closer
+}
+
+func (l stagedLock) Close() error {
+	defer stagedMutex.Unlock()
+	return l.file.Close()
+}
+
+// LockStaged takes an exclusive lock on the staged files of the workspace,
+// waiting for another ait process changing them, ie "ait watch" staging the
+// files that changed.
+func LockStaged() (io.Closer, error) {
+	return lockStaged(".", StagedLockTimeout)
+}
+
+// lockStaged locks the staged files of the workspace at dir like LockStaged,
+// giving up after timeout with an fslock.LockedError if another process still
+// holds the lock.
+func lockStaged(dir string, timeout time.Duration) (io.Closer, error) {
+	stagedMutex.Lock()
+	dir = filepath.Dir(inWorkspace(dir, AddedFilesPath))
+	deadline := time.Now().Add(timeout)
+	for {
+		lk, err := fslock.Lock(dir, stagedLockName)
+		if err == nil {
+			return stagedLock{file: lk}, nil
+		}
+		var locked fslock.LockedError
+		if !errors.As(err, &locked) || time.Now().After(deadline) {
+			stagedMutex.Unlock()
+			return nil, fmt.Errorf("unable to lock the staged files, is another ait command "+
+				"changing them? %w", err)
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+}
+
+// ReadStaged returns the staged files. The staged files are replaced whole
+// when written, so they're read without taking the lock.
+func ReadStaged() (*types.ThreadSafeStringSet, error) {
+	return ReadStagedIn(".")
+}
+
+// ReadStagedIn returns the staged files of the workspace at dir, ie for a
+// program embedding ait, relative to its root like ReadStaged returns them.
+func ReadStagedIn(dir string) (*types.ThreadSafeStringSet, error) {
+	contents := types.NewThreadSafeStringSet()
+	file, err := os.Open(inWorkspace(dir, AddedFilesPath))
+	if os.IsNotExist(err) {
+		return contents, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	FillSet(contents, file)
+	return contents, nil
+}
+
+// ReadStagedSorted returns the staged files in order.
+func ReadStagedSorted() (*types.SortedStringSet, error) {
+	return ReadStagedSortedIn(".")
+}
+
+// ReadStagedSortedIn returns the staged files of the workspace at dir in
+// order.
+func ReadStagedSortedIn(dir string) (*types.SortedStringSet, error) {
+	staged, err := ReadStagedIn(dir)
+	if err != nil {
+		return nil, err
+	}
+	sorted := types.NewSortedStringSet()
+	_ = staged.ForEach(func(path string) error {
+		sorted.Add(path)
+		return nil
+	})
+	return sorted, nil
+}
+
+// UpdateStaged calls fn with the staged files and writes back what it leaves
+// in them, holding the lock throughout so the changes other ait processes
+// make meanwhile aren't lost. Nothing is written if fn returns an error.
+func UpdateStaged(fn func(contents *types.ThreadSafeStringSet) error) error {
+	return UpdateStagedIn(".", fn)
+}
+
+// UpdateStagedIn is UpdateStaged for the staged files of the workspace at dir.
+func UpdateStagedIn(dir string, fn func(contents *types.ThreadSafeStringSet) error) error {
+	lk, err := lockStaged(dir, StagedLockTimeout)
+	if err != nil {
+		return err
+	}
+	defer lk.Close()
+	contents, err := ReadStagedIn(dir)
+	if err != nil {
+		return err
+	}
+	if err = fn(contents); err != nil {
+		return err
+	}
+	return writeStaged(dir, contents)
+}
@@ -0,0 +1,148 @@
+package utils
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// JournalPath holds the journals of submissions that haven't completed, one
+// directory per submission.
+var JournalPath = filepath.Join(".ait", "submissions")
+
+// The steps of a submission, in the order they complete. The commit is made
+// through the API, so creating it also pushes it.
+const (
+	StepGenerated   = "generated"
+	StepAnnounced   = "announced"
+	StepCommitted   = "committed"
+	StepPullRequest = "pull-request"
+)
+
+// SubmissionJournal records the steps of a submission as they complete, with
+// what's needed to pick it up from the last one. Its keyset is kept beside it.
+type SubmissionJournal struct {
+	ID          string    `json:"id"`
+	Time        time.Time `json:"time"`
+	Remote      string    `json:"remote"`
+	Provider    string    `json:"provider,omitempty"`
+	PullRequest bool      `json:"pull_request,omitempty"`
+	Issue       bool      `json:"issue,omitempty"`
+	ROCrate     bool      `json:"ro_crate,omitempty"`
+	Also        string    `json:"also,omitempty"`
+	Title       string    `json:"title"`
+	Commit      string    `json:"commit"`
+	PRBody      string    `json:"pr_body,omitempty"`
+	Category    string    `json:"category"`
+	Filename    string    `json:"filename"`
+	// Overwrite and Exists are how the keyset was found in the repository,
+	// so it's committed the same way when resumed.
+	Overwrite bool     `json:"overwrite"`
+	Exists    bool     `json:"exists,omitempty"`
+	Steps     []string `json:"steps"`
+	CommitSHA string   `json:"commit_sha,omitempty"`
+	// DOI is kept once minted, so resuming doesn't mint another.
+	DOI      string `json:"doi,omitempty"`
+	PRURL    string `json:"pull_request_url,omitempty"`
+	PRNumber int    `json:"pull_request_number,omitempty"`
+}
+
+// NewJournal returns the journal of a new submission to the remote.
+func NewJournal(remote string) *SubmissionJournal {
+	now := time.Now()
+	return &SubmissionJournal{
+		ID:     now.Format("20060102-150405"),
+		Time:   now,
+		Remote: remote,
+	}
+}
+
+// Dir returns the directory the journal and its keyset are kept in.
+func (j *SubmissionJournal) Dir() string {
+	return filepath.Join(JournalPath, j.ID)
+}
+
+// KeysetPath returns where the keyset generated for the submission is kept.
+func (j *SubmissionJournal) KeysetPath() string {
+	return filepath.Join(j.Dir(), "keyset.ks")
+}
+
+// Done returns whether the step has completed.
+func (j *SubmissionJournal) Done(step string) bool {
+	for _, s := range j.Steps {
+		if s == step {
+			return true
+		}
+	}
+	return false
+}
+
+// Record marks the step as completed and saves the journal.
+func (j *SubmissionJournal) Record(step string) error {
+	if !j.Done(step) {
+		j.Steps = append(j.Steps, step)
+	}
+	return j.Save()
+}
+
+// LastStep returns the last step that completed, or "" if none has.
+func (j *SubmissionJournal) LastStep() string {
+	if len(j.Steps) == 0 {
+		return ""
+	}
+	return j.Steps[len(j.Steps)-1]
+}
+
+// Save writes the journal to its directory.
+func (j *SubmissionJournal) Save() error {
+	if err := os.MkdirAll(j.Dir(), os.ModePerm); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(j, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(filepath.Join(j.Dir(), "journal.json"), data, 0644)
+}
+
+// Remove deletes the journal and its keyset.
+func (j *SubmissionJournal) Remove() error {
+	return os.RemoveAll(j.Dir())
+}
+
+// LatestJournal returns the journal of the most recent submission that
+// didn't complete, or nil if there's none.
+func LatestJournal() (*SubmissionJournal, error) {
+	dirs, err := ioutil.ReadDir(JournalPath)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var journals []*SubmissionJournal
+	for _, info := range dirs {
+		data, err := ioutil.ReadFile(filepath.Join(JournalPath, info.Name(), "journal.json"))
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		j := &SubmissionJournal{}
+		if err = json.Unmarshal(data, j); err != nil {
+			return nil, err
+		}
+		journals = append(journals, j)
+	}
+	if len(journals) == 0 {
+		return nil, nil
+	}
+	sort.Slice(journals, func(a, b int) bool {
+		return journals[a].Time.Before(journals[b].Time)
+	})
+	return journals[len(journals)-1], nil
+}
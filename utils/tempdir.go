@@ -0,0 +1,58 @@
+package utils
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// TempDir is the directory submissions generate their keysets in, ie on a
+// larger scratch disk. Empty uses the workspace's .ait directory. It is set
+// from the ait config.
+var TempDir string
+
+// KeysetsDir returns the directory keysets are generated in during a
+// submission. Each workspace gets its own directory under TempDir so they
+// can share it.
+func KeysetsDir() string {
+	if TempDir == "" {
+		return filepath.Join(".ait", "keysets")
+	}
+	wd, _ := os.Getwd()
+	sum := sha256.Sum256([]byte(wd))
+	return filepath.Join(TempDir, "ait-keysets-"+hex.EncodeToString(sum[:6]))
+}
+
+// GeneratedKeysetPath returns the path of the keyset generated for a
+// submission.
+func GeneratedKeysetPath() string {
+	return filepath.Join(KeysetsDir(), "generated.ks")
+}
+
+// moveFile renames src to dst, copying it when they are on different disks.
+func moveFile(src, dst string) error {
+	if os.Rename(src, dst) == nil {
+		return nil
+	}
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(out, in)
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(dst)
+		return err
+	}
+	in.Close()
+	return os.Remove(src)
+}
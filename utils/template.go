@@ -0,0 +1,122 @@
+package utils
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+)
+
+// SubmitTemplate is a named submission, saved to submit recurring datasets
+// again without deciding everything anew: where to, with which flags and with
+// which application (its category, keyset file name, title and messages).
+type SubmitTemplate struct {
+	Name   string    `json:"name"`
+	Saved  time.Time `json:"saved"`
+	Remote string    `json:"remote"`
+	// Flags are the flags of the submission, those given on the command line
+	// take precedence.
+	Flags       json.RawMessage `json:"flags,omitempty"`
+	Application string          `json:"application,omitempty"`
+}
+
+// CheckTemplateName returns an error if name can't name a template.
+func CheckTemplateName(name string) error {
+	if name == "" || strings.HasPrefix(name, ".") || strings.ContainsAny(name, `/\`) {
+		return fmt.Errorf("%q can't name a template, use a name like monthly-climate", name)
+	}
+	return nil
+}
+
+// ReadTemplate reads the template called name from dir.
+func ReadTemplate(dir, name string) (*SubmitTemplate, error) {
+	if err := CheckTemplateName(name); err != nil {
+		return nil, err
+	}
+	data, err := ioutil.ReadFile(filepath.Join(dir, name+".json"))
+	if os.IsNotExist(err) {
+		return nil, fmt.Errorf("there is no template called %q", name)
+	}
+	if err != nil {
+		return nil, err
+	}
+	t := &SubmitTemplate{}
+	if err = json.Unmarshal(data, t); err != nil {
+		return nil, fmt.Errorf("the template %q is malformed: %v", name, err)
+	}
+	t.Name = name
+	return t, nil
+}
+
+// ListTemplates returns the templates saved in dir, by name.
+func ListTemplates(dir string) ([]*SubmitTemplate, error) {
+	files, err := ioutil.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var templates []*SubmitTemplate
+	for _, file := range files {
+		if file.IsDir() || filepath.Ext(file.Name()) != ".json" {
+			continue
+		}
+		t, err := ReadTemplate(dir, strings.TrimSuffix(file.Name(), ".json"))
+		if err != nil {
+			return nil, err
+		}
+		templates = append(templates, t)
+	}
+	sort.Slice(templates, func(i, j int) bool { return templates[i].Name < templates[j].Name })
+	return templates, nil
+}
+
+// Save writes the template to dir, replacing the one of the same name.
+func (t *SubmitTemplate) Save(dir string) error {
+	if err := CheckTemplateName(t.Name); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(t, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(filepath.Join(dir, t.Name+".json"), data, 0644)
+}
+
+// MergeFlags fills flags, a pointer to a command's flags, with the saved
+// flags, keeping those already set to something else than their zero value.
+func MergeFlags(saved json.RawMessage, flags interface{}) error {
+	if len(saved) == 0 {
+		return nil
+	}
+	merged := make(map[string]interface{})
+	if err := json.Unmarshal(saved, &merged); err != nil {
+		return err
+	}
+	data, err := json.Marshal(flags)
+	if err != nil {
+		return err
+	}
+	given := make(map[string]interface{})
+	if err = json.Unmarshal(data, &given); err != nil {
+		return err
+	}
+	for name, value := range given {
+		if value != nil && !reflect.ValueOf(value).IsZero() {
+			merged[name] = value
+		}
+	}
+	if data, err = json.Marshal(merged); err != nil {
+		return err
+	}
+	return json.Unmarshal(data, flags)
+}
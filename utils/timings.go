@@ -0,0 +1,107 @@
+package utils
+
+import (
+	"fmt"
+	"io"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// phaseTiming is the time spent in a phase of a command.
+type phaseTiming struct {
+	name   string
+	active int
+	since  time.Time
+	// wall is how long the phase was running, busy the sum of the time of
+	// every concurrent run, ie of the workers hashing files.
+	wall, busy time.Duration
+	count      int
+}
+
+var (
+	timingsEnabled int32
+	timingsLock    sync.Mutex
+	timings        []*phaseTiming
+)
+
+// EnableTimings starts recording where commands spend their time. Nothing is
+// recorded, or sent anywhere, unless it's called.
+func EnableTimings() {
+	atomic.StoreInt32(&timingsEnabled, 1)
+}
+
+// TimePhase starts timing a phase of the command, ie "hashing", and returns
+// the function ending it. Phases can be timed from many goroutines at once.
+func TimePhase(name string) func() {
+	if atomic.LoadInt32(&timingsEnabled) == 0 {
+		return func() {}
+	}
+	start := time.Now()
+	timingsLock.Lock()
+	var phase *phaseTiming
+	for _, p := range timings {
+		if p.name == name {
+			phase = p
+		}
+	}
+	if phase == nil {
+		phase = &phaseTiming{name: name}
+		timings = append(timings, phase)
+	}
+	if phase.active == 0 {
+		phase.since = start
+	}
+	phase.active++
+	phase.count++
+	timingsLock.Unlock()
+
+	return func() {
+		now := time.Now()
+		timingsLock.Lock()
+		defer timingsLock.Unlock()
+		phase.busy += now.Sub(start)
+		phase.active--
+		if phase.active == 0 {
+			phase.wall += now.Sub(phase.since)
+		}
+	}
+}
+
+// WriteTimings writes the breakdown of the phases timed so far to w, with
+// their share of the total time the command took. Phases still running are
+// counted up to now.
+func WriteTimings(w io.Writer, total time.Duration) {
+	if atomic.LoadInt32(&timingsEnabled) == 0 {
+		return
+	}
+	timingsLock.Lock()
+	defer timingsLock.Unlock()
+	now := time.Now()
+	fmt.Fprintf(w, "Timings, %v in total:\n", total.Round(time.Millisecond))
+	var timed time.Duration
+	for _, p := range timings {
+		wall := p.wall
+		if p.active > 0 {
+			wall += now.Sub(p.since)
+		}
+		timed += wall
+		fmt.Fprintf(w, "  %-18v %10v %5.1f%%  %d time(s)", p.name, wall.Round(time.Millisecond),
+			percent(wall, total), p.count)
+		if p.busy > wall+wall/10 {
+			fmt.Fprintf(w, ", %.1fx parallel", float64(p.busy)/float64(wall))
+		}
+		fmt.Fprintln(w)
+	}
+	if other := total - timed; other > 0 {
+		fmt.Fprintf(w, "  %-18v %10v %5.1f%%\n", "other", other.Round(time.Millisecond), percent(other, total))
+	}
+}
+
+// percent returns part as a percentage of total.
+func percent(part, total time.Duration) float64 {
+	if total <= 0 {
+		return 0
+	}
+	return 100 * float64(part) / float64(total)
+}
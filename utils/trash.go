@@ -0,0 +1,204 @@
+package utils
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/arken/ait/types"
+)
+
+// TrashPath records the files recently dropped from the staged files.
+var TrashPath = filepath.Join(".ait", "trash.json")
+
+// TrashPeriod is how long unstaged files can be restored for. It is set from
+// the ait config, 0 keeps nothing.
+var TrashPeriod time.Duration
+
+// TrashRecord is a set of files dropped from the staged files at once.
+type TrashRecord struct {
+	ID     int       `json:"id"`
+	Time   time.Time `json:"time"`
+	Reason string    `json:"reason"`
+	// Files maps each dropped file to when it had been staged.
+	Files map[string]time.Time `json:"files"`
+}
+
+// WriteUnstaged replaces the staged files with contents like WriteStaged, and
+// records the files it drops in the trash so "ait unstage --undo" can stage
+// them again without walking their directories.
+func WriteUnstaged(contents types.StringSet, reason string) error {
+	return UpdateUnstaged(reason, func(staged *types.ThreadSafeStringSet) error {
+		var dropped []string
+		_ = staged.ForEach(func(path string) error {
+			if !contents.Contains(path) {
+				dropped = append(dropped, path)
+			}
+			return nil
+		})
+		for _, path := range dropped {
+			staged.Delete(path)
+		}
+		return contents.ForEach(func(path string) error {
+			staged.Add(path)
+			return nil
+		})
+	})
+}
+
+// UpdateUnstaged changes the staged files like UpdateStaged, and records the
+// files fn drops from them in the trash.
+func UpdateUnstaged(reason string, fn func(contents *types.ThreadSafeStringSet) error) error {
+	lk, err := LockStaged()
+	if err != nil {
+		return err
+	}
+	defer lk.Close()
+	contents, err := ReadStaged()
+	if err != nil {
+		return err
+	}
+	var before []string
+	_ = contents.ForEach(func(path string) error {
+		before = append(before, path)
+		return nil
+	})
+	if err = fn(contents); err != nil {
+		return err
+	}
+	times, _ := ReadStagedTimes()
+	record := TrashRecord{Reason: reason, Files: make(map[string]time.Time)}
+	for _, path := range before {
+		if !contents.Contains(path) {
+			record.Files[path] = times[path]
+		}
+	}
+	if err := writeStaged(".", contents); err != nil {
+		return err
+	}
+	if len(record.Files) == 0 || TrashPeriod <= 0 {
+		return nil
+	}
+	records, err := ReadTrash()
+	if err != nil {
+		return err
+	}
+	record.Time = time.Now().UTC().Truncate(time.Second)
+	for _, r := range records {
+		if r.ID >= record.ID {
+			record.ID = r.ID + 1
+		}
+	}
+	return WriteTrash(append(records, record))
+}
+
+// ReadTrash returns the trash records that haven't expired, oldest first.
+func ReadTrash() ([]TrashRecord, error) {
+	var records []TrashRecord
+	data, err := ioutil.ReadFile(TrashPath)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err = json.Unmarshal(data, &records); err != nil {
+		return nil, err
+	}
+	kept := records[:0]
+	for _, r := range records {
+		if time.Since(r.Time) < TrashPeriod && len(r.Files) > 0 {
+			kept = append(kept, r)
+		}
+	}
+	sort.SliceStable(kept, func(a, b int) bool { return kept[a].ID < kept[b].ID })
+	return kept, nil
+}
+
+// WriteTrash replaces the trash records.
+func WriteTrash(records []TrashRecord) error {
+	if len(records) == 0 {
+		err := os.Remove(TrashPath)
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	data, err := json.MarshalIndent(records, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeSynced(TrashPath, data)
+}
+
+// RestoreTrash stages the files of the trash records again, keeping when they
+// were first staged, and removes them from the trash. Only the files match
+// selects are restored. It returns the restored files and those that no longer
+// exist, which are left in the trash.
+func RestoreTrash(records []TrashRecord, match func(path string) bool) (restored, missing []string, err error) {
+	lk, err := LockStaged()
+	if err != nil {
+		return nil, nil, err
+	}
+	defer lk.Close()
+	staged, err := ReadStaged()
+	if err != nil {
+		return nil, nil, err
+	}
+	all, err := ReadTrash()
+	if err != nil {
+		return nil, nil, err
+	}
+	selected := make(map[int]bool, len(records))
+	for _, r := range records {
+		selected[r.ID] = true
+	}
+	recovered := make(map[string]time.Time)
+	for _, r := range all {
+		if !selected[r.ID] {
+			continue
+		}
+		for path, at := range r.Files {
+			if !match(path) {
+				continue
+			}
+			if !FileExists(path) {
+				missing = append(missing, path)
+				continue
+			}
+			delete(r.Files, path)
+			if !staged.Contains(path) {
+				staged.Add(path)
+				restored = append(restored, path)
+			}
+			if !at.IsZero() {
+				recovered[path] = at
+			}
+		}
+	}
+	if err = writeStaged(".", staged); err != nil {
+		return nil, nil, err
+	}
+	times, err := ReadStagedTimes()
+	if err != nil {
+		return nil, nil, err
+	}
+	for path, at := range recovered {
+		times[path] = at
+	}
+	if err = WriteStagedTimes(times); err != nil {
+		return nil, nil, err
+	}
+	kept := all[:0]
+	for _, r := range all {
+		if len(r.Files) > 0 {
+			kept = append(kept, r)
+		}
+	}
+	sort.Strings(restored)
+	sort.Strings(missing)
+	return restored, missing, WriteTrash(kept)
+}
@@ -0,0 +1,46 @@
+package utils
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// UserAgent identifies ait in every outbound HTTP request, as the usage
+// policies of many data providers ask.
+var UserAgent = FormatUserAgent("", "")
+
+// FormatUserAgent returns the User-Agent of the given version of ait, with
+// contact, an email address or URL, for providers to reach its user.
+func FormatUserAgent(version, contact string) string {
+	if version == "" {
+		version = "dev"
+	}
+	info := []string{"+https://github.com/arken/ait"}
+	if contact = strings.TrimSpace(contact); contact != "" {
+		if strings.Contains(contact, "@") && !strings.Contains(contact, ":") {
+			contact = "mailto:" + contact
+		}
+		info = append(info, contact)
+	}
+	return fmt.Sprintf("ait/%v (%v)", strings.TrimPrefix(version, "v"), strings.Join(info, "; "))
+}
+
+// identifyingTransport sends every request with UserAgent, replacing the
+// User-Agent of the libraries making it.
+type identifyingTransport struct {
+	base http.RoundTripper
+}
+
+func (t identifyingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	req.Header.Set("User-Agent", UserAgent)
+	return t.base.RoundTrip(req)
+}
+
+// NewClient returns an HTTP client that identifies itself with UserAgent and
+// gives up on requests after timeout, or never when it's 0.
+func NewClient(timeout time.Duration) *http.Client {
+	return &http.Client{Timeout: timeout, Transport: identifyingTransport{newTransport()}}
+}
@@ -10,11 +10,12 @@ import (
 	"strings"
 	"time"
 
+	"github.com/arken/ait/platform"
 	"github.com/arken/ait/types"
 )
 
 // AddedFilesPath is the location of the ait working memory file.
-const AddedFilesPath string = ".ait/added_files" //can later be put somewhere more central
+var AddedFilesPath = filepath.Join(".ait", "added_files")
 
 // IsAITRepo is a trivial check to see if the program's working dir is an ait repo.
 func IsAITRepo() bool {
@@ -46,6 +47,25 @@ func IsInSubDir(dir, pathToCheck string) bool {
 	return strings.HasPrefix(dir, pathToCheck)
 }
 
+// MatchesStaged returns whether the staged path is matched by pattern: the
+// path itself, a directory it's in, or a glob pattern like "data/*.csv"
+// matching either. The pattern "." matches every path.
+func MatchesStaged(pattern, path string) bool {
+	pattern = filepath.Clean(pattern)
+	if pattern == "." {
+		return true
+	}
+	if !strings.ContainsAny(pattern, "*?[") {
+		return path == pattern || strings.HasPrefix(path, pattern+string(filepath.Separator))
+	}
+	for dir := path; dir != "." && dir != string(filepath.Separator); dir = filepath.Dir(dir) {
+		if matched, _ := filepath.Match(pattern, dir); matched {
+			return true
+		}
+	}
+	return false
+}
+
 // FillSet splits the given file by newline and adds each line to the given set.
 func FillSet(contents types.StringSet, file *os.File) {
 	scanner := bufio.NewScanner(file)
@@ -118,22 +138,58 @@ func GetFileModTime(path string) (time.Time, error) {
 	return info.ModTime(), nil
 }
 
+// IsTerminal returns true if the file is a terminal, rather than a pipe or a
+// regular file.
+func IsTerminal(file *os.File) bool {
+	return platform.IsTerminal(file)
+}
+
+// BeforeFatal, when set, is called with the message of a fatal error right
+// before the program exits.
+var BeforeFatal func(msg string)
+
+// FatalOutput, when set, reports the message of a fatal error instead of it
+// being printed, ie as a JSON record.
+var FatalOutput func(msg string)
+
+// BeforeExit, when set, is called right before the program exits early, ie to
+// emit the output that's still buffered.
+var BeforeExit func()
+
+// Exit runs BeforeExit and exits with the given code.
+func Exit(code int) {
+	if BeforeExit != nil {
+		BeforeExit()
+	}
+	os.Exit(code)
+}
+
 // FatalPrintln Println's the given arguments and then exits with exit code 1.
 func FatalPrintln(a ...interface{}) {
-	if a != nil {
+	if FatalOutput != nil {
+		FatalOutput(strings.TrimSpace(fmt.Sprintln(a...)))
+	} else if a != nil {
 		fmt.Println(a...)
 	}
-	os.Exit(1)
+	if BeforeFatal != nil {
+		BeforeFatal(strings.TrimSpace(fmt.Sprintln(a...)))
+	}
+	Exit(1)
 }
 
 // FatalPrintf Printf's the given arguments and then exits with exit code 1.
 func FatalPrintf(format string, a ...interface{}) {
-	if a != nil {
+	if FatalOutput != nil {
+		FatalOutput(strings.TrimSpace(fmt.Sprintf(format, a...)))
+	} else if a != nil {
 		fmt.Printf(format, a...)
 	} else {
 		fmt.Printf(format)
 	}
-	os.Exit(1)
+	if BeforeFatal != nil {
+		BeforeFatal(strings.TrimSpace(fmt.Sprintf(format, a...)))
+	}
+	Exit(1)
 }
 
 // CheckError checks if the given error is nil, and if not it FatalPrintln's the
@@ -179,10 +235,14 @@ func FatalWithCleanup(cleanup func(), a ...interface{}) {
 }
 
 // SubmissionCleanup attempts to delete the sources and commit file. Nothing
-// is done if either of those operations is unsuccessful
+// is done if either of those operations is unsuccessful. When Retention is
+// set the generated keyset is archived instead of deleted.
 func SubmissionCleanup() {
-	_ = os.RemoveAll(filepath.Join(".ait", "keysets"))
-	_ = os.Remove(".ait/commit")
+	if Retention > 0 {
+		archiveKeyset()
+	}
+	_ = os.RemoveAll(KeysetsDir())
+	_ = os.Remove(filepath.Join(".ait", "commit"))
 }
 
 // IsWithinRepo tests if the given path is within this current repo.
@@ -197,7 +257,7 @@ func IsWithinRepo(path string) (bool, error) {
 	if err != nil {
 		return false, err
 	}
-	return strings.HasPrefix(path, wd), nil
+	return IsWithin(wd, path), nil
 }
 
 // IndexOf returns the index of key in slice, or -1 if it doesn't exist
@@ -236,7 +296,8 @@ func IsGithubRemote(url string) (bool, string) {
 		msg += "The URL is not complete because it does not start with \"https://\"\n"
 	}
 	if strings.HasPrefix(url, "git@") {
-		msg += "The URL is for the SSH protocol which AIT not support.\n"
+		msg += "The URL is for the SSH protocol, which keysets can be pulled with but not submitted " +
+			"with, submissions go through the GitHub API.\n"
 	}
 	if strings.HasSuffix(msg, "\n") {
 		msg = msg[0 : len(msg)-1] //cut off the newline.
@@ -0,0 +1,56 @@
+package utils
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"sync"
+
+	"github.com/arken/ait/types"
+)
+
+// WalkFiles adds the regular files under dir to contents, walking its
+// directories concurrently and skipping .ait and the paths ignore excludes.
+// With exts, only the files with one of those extensions are added. The
+// directories that can't be read are skipped and their errors returned.
+func WalkFiles(dir string, exts *types.BasicStringSet, ignore *Ignore, contents *types.ThreadSafeStringSet) []error {
+	return WalkFilesIn(".", dir, exts, ignore, contents)
+}
+
+// WalkFilesIn is WalkFiles for the directory dir of the workspace at root,
+// adding the paths of the files relative to root.
+func WalkFilesIn(root, dir string, exts *types.BasicStringSet, ignore *Ignore,
+	contents *types.ThreadSafeStringSet) []error {
+	var errs []error
+	var lock sync.Mutex
+	var wg sync.WaitGroup
+	var walk func(dir string)
+	walk = func(dir string) {
+		defer wg.Done()
+		if dir == ".ait" {
+			return
+		}
+		files, err := ioutil.ReadDir(inWorkspace(root, dir))
+		if err != nil {
+			lock.Lock()
+			errs = append(errs, err)
+			lock.Unlock()
+			return
+		}
+		for _, info := range files {
+			path := filepath.Join(dir, info.Name())
+			if ignore.Ignored(path, info.IsDir()) {
+				continue
+			}
+			if info.IsDir() {
+				wg.Add(1)
+				go walk(path)
+			} else if exts == nil || exts.Size() == 0 || exts.Contains(filepath.Ext(info.Name())) {
+				contents.Add(path)
+			}
+		}
+	}
+	wg.Add(1)
+	walk(dir)
+	wg.Wait()
+	return errs
+}
@@ -0,0 +1,44 @@
+package utils
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// WatchPath holds the upstream HTTP files the workspace mirrors.
+var WatchPath = filepath.Join(".ait", "watch.json")
+
+// WatchedURL is an upstream file kept up to date in the workspace.
+type WatchedURL struct {
+	URL  string `json:"url"`
+	Path string `json:"path"`
+	// ETag and LastModified identify the version of the file at Path.
+	ETag         string    `json:"etag,omitempty"`
+	LastModified string    `json:"lastModified,omitempty"`
+	Checked      time.Time `json:"checked,omitempty"`
+}
+
+// ReadWatched returns the watched URLs. A missing list is not an error.
+func ReadWatched() ([]WatchedURL, error) {
+	var watched []WatchedURL
+	data, err := ioutil.ReadFile(WatchPath)
+	if os.IsNotExist(err) {
+		return watched, nil
+	}
+	if err != nil {
+		return watched, err
+	}
+	return watched, json.Unmarshal(data, &watched)
+}
+
+// WriteWatched saves the watched URLs.
+func WriteWatched(watched []WatchedURL) error {
+	data, err := json.MarshalIndent(watched, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(WatchPath, data, 0644)
+}
@@ -0,0 +1,19 @@
+package utils
+
+import "runtime"
+
+// Workers is how many files are hashed, added or uploaded at once. 0 picks a
+// number from the CPUs available. It is set from the ait config.
+var Workers int
+
+// NumWorkers returns how many workers to run: Workers if it's set, otherwise
+// one less than the number of CPUs to leave one for the main thread.
+func NumWorkers() int {
+	if Workers > 0 {
+		return Workers
+	}
+	if runtime.NumCPU() > 2 {
+		return runtime.NumCPU() - 1
+	}
+	return 1
+}